@@ -15,6 +15,7 @@ import (
 	"github.com/fslongjin/liteboxd/backend/internal/k8s"
 	"github.com/fslongjin/liteboxd/backend/internal/lifecycle"
 	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/metrics"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
 	"github.com/gin-gonic/gin"
 )
@@ -66,7 +67,13 @@ func main() {
 
 	// Create gateway service
 	drainState := lifecycle.NewDrainManager()
-	svc := gateway.NewService(k8sClient, store.NewSandboxStore(), config, drainState)
+	sandboxStore := store.NewSandboxStore()
+	k8sClient.SetNamespaceResolver(sandboxStore.GetClusterNamespace)
+	svc := gateway.NewService(k8sClient, sandboxStore, store.NewSandboxPreviewLinkStore(), config, drainState)
+	trafficMeter := gateway.NewTrafficMeter(store.NewSandboxTrafficStore())
+	svc.SetTrafficMeter(trafficMeter)
+	trafficMeter.Start(10 * time.Second)
+	metrics.RegisterWebSocketSessions(drainState)
 
 	// Set Gin mode
 	if logger.Enabled(context.Background(), slog.LevelDebug) {
@@ -80,8 +87,9 @@ func main() {
 	r.Use(gin.Recovery())
 	r.Use(logx.RequestIDMiddleware())
 	r.Use(logx.AccessLogMiddleware("gateway_http"))
+	r.Use(metrics.Middleware())
 	r.Use(func(c *gin.Context) {
-		if drainState.IsDraining() && c.Request.URL.Path != "/health" && c.Request.URL.Path != "/readyz" {
+		if drainState.IsDraining() && c.Request.URL.Path != "/health" && c.Request.URL.Path != "/readyz" && c.Request.URL.Path != "/metrics" {
 			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "service is draining"})
 			return
 		}
@@ -90,6 +98,7 @@ func main() {
 
 	// Register routes
 	svc.RegisterRoutes(r)
+	r.GET("/metrics", metrics.Handler(os.Getenv("METRICS_TOKEN")))
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -130,5 +139,9 @@ func main() {
 		log.Printf("Gateway drained with timeout, remaining active websockets: %d", drainState.ActiveWebSockets())
 	}
 
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	trafficMeter.Flush(flushCtx)
+
 	log.Println("Gateway server stopped")
 }
@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/fslongjin/liteboxd/backend/internal/k8s"
 	"github.com/fslongjin/liteboxd/backend/internal/lifecycle"
 	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/metrics"
 	"github.com/fslongjin/liteboxd/backend/internal/security"
 	"github.com/fslongjin/liteboxd/backend/internal/service"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
@@ -132,6 +134,15 @@ func runServer() {
 	}
 	slog.Info("sandbox namespace ensured", "component", "k8s", "namespace", k8sClient.SandboxNamespace())
 
+	// Sandboxes of projects run in the namespaces of their projects
+	sandboxStore := store.NewSandboxStore()
+	k8sClient.SetNamespaceResolver(sandboxStore.GetClusterNamespace)
+	projectStore := store.NewProjectStore()
+	projectSvc := service.NewProjectService(k8sClient, projectStore, sandboxStore)
+	if err := projectSvc.EnsureNamespaces(ctx); err != nil {
+		log.Fatalf("Failed to ensure project namespaces: %v", err)
+	}
+
 	// Ensure network policies are applied
 	netPolicyMgr := k8s.NewNetworkPolicyManager(k8sClient)
 	if err := netPolicyMgr.EnsureDefaultPolicies(ctx); err != nil {
@@ -149,11 +160,57 @@ func runServer() {
 	if err != nil {
 		log.Fatalf("Failed to initialize token cipher: %v", err)
 	}
-	sandboxStore := store.NewSandboxStore()
 	sandboxSvc := service.NewSandboxService(k8sClient, sandboxStore, tokenCipher)
+	if _, err := sandboxSvc.ReencryptAccessTokens(context.Background()); err != nil {
+		slog.Warn("failed to re-encrypt some sandbox access tokens", "component", "token_key_rotation", "error", err)
+	}
 	deletionSvc := service.NewSandboxDeletionService(k8sClient, sandboxStore)
 	reconcileSvc := service.NewSandboxReconcileService(k8sClient, sandboxStore)
+	processSvc := service.NewSandboxProcessService(k8sClient, sandboxStore, store.NewSandboxProcessStore())
+	snapshotSvc := service.NewSandboxSnapshotService(k8sClient, sandboxStore, store.NewSandboxSnapshotStore(), filepath.Join(dataDir, "snapshots"))
+	poolSvc := service.NewSandboxPoolService(k8sClient, sandboxStore, store.NewSandboxPoolStore(), templateSvc)
+	var networkEventSource service.NetworkEventSource
+	if path := os.Getenv("NETWORK_AUDIT_HUBBLE_EXPORT_FILE"); path != "" {
+		hubbleSource := service.NewHubbleFileSource(path, k8sClient.SandboxNamespace())
+		hubbleSource.SetNamespaceFilter(k8sClient.IsSandboxNamespace)
+		networkEventSource = hubbleSource
+	}
+	previewSvc := service.NewSandboxPreviewService(sandboxStore, store.NewSandboxPreviewLinkStore())
+	trafficSvc := service.NewSandboxTrafficService(sandboxStore, store.NewSandboxTrafficStore())
+	networkAuditSvc := service.NewSandboxNetworkAuditService(sandboxStore, store.NewSandboxNetworkEventStore(), networkEventSource)
 	sandboxSvc.SetTemplateService(templateSvc)
+	sandboxSvc.SetSnapshotService(snapshotSvc)
+	sandboxSvc.SetPoolService(poolSvc)
+	sandboxSvc.SetProjectService(projectSvc)
+	quotaStore := store.NewQuotaStore()
+	if err := quotaStore.DeleteAllReservations(context.Background()); err != nil {
+		slog.Warn("failed to clear quota reservations", "error", err)
+	}
+	quotaSvc := service.NewQuotaService(quotaStore, sandboxStore, authStore, projectStore)
+	sandboxSvc.SetQuotaService(quotaSvc)
+	reconcileSvc.SetPoolService(poolSvc)
+	sandboxSvc.SetCheckpointDir(filepath.Join(dataDir, "checkpoints"))
+	if v := os.Getenv("FILE_TRANSFER_MAX_BYTES"); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil && parsed >= 0 {
+			sandboxSvc.SetMaxFileTransferSize(parsed)
+		} else {
+			slog.Warn("invalid FILE_TRANSFER_MAX_BYTES, file transfers are not size limited", "value", v)
+		}
+	}
+	if v := os.Getenv("SANDBOX_MAX_TTL_SECONDS"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
+			sandboxSvc.SetMaxTTL(time.Duration(parsed) * time.Second)
+		} else {
+			slog.Warn("invalid SANDBOX_MAX_TTL_SECONDS, using default", "value", v, "default", service.DefaultMaxTTL)
+		}
+	}
+	if v := os.Getenv("SANDBOX_ALLOW_NETWORK_WIDENING"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			sandboxSvc.SetAllowNetworkWidening(parsed)
+		} else {
+			slog.Warn("invalid SANDBOX_ALLOW_NETWORK_WIDENING, network overrides may only narrow", "value", v)
+		}
+	}
 	templateSvc.SetPrepullService(prepullSvc)
 
 	sandboxSvc.StartTTLCleaner(30 * time.Second)
@@ -176,6 +233,10 @@ func runServer() {
 	slog.Info("sandbox reconciler started", "component", "sandbox_reconciler", "interval", "1m")
 	deletionSvc.Start(10 * time.Second)
 	slog.Info("sandbox deletion reconciler started", "component", "sandbox_deletion", "interval", "10s")
+	if networkEventSource != nil {
+		networkAuditSvc.Start(10 * time.Second)
+		slog.Info("network audit collector started", "component", "sandbox_network_audit", "interval", "10s")
+	}
 
 	go func() {
 		if _, err := reconcileSvc.Run(context.Background(), "startup"); err != nil {
@@ -198,31 +259,56 @@ func runServer() {
 	}()
 
 	drainState := lifecycle.NewDrainManager()
+	metrics.RegisterWebSocketSessions(drainState)
+	metrics.RegisterSandboxCounts(sandboxStore)
 
 	// Create handlers
-	authHandler := handler.NewAuthHandler(authStore, sessionMaxAge, nil)
+	authHandler := handler.NewAuthHandler(authStore, projectStore, sessionMaxAge, nil)
+	userHandler := handler.NewUserHandler(authStore)
+	projectHandler := handler.NewProjectHandler(projectSvc)
+	quotaHandler := handler.NewQuotaHandler(quotaSvc)
 	sandboxHandler := handler.NewSandboxHandler(sandboxSvc, reconcileSvc, drainState)
+	processHandler := handler.NewProcessHandler(processSvc)
+	snapshotHandler := handler.NewSnapshotHandler(snapshotSvc)
+	poolHandler := handler.NewPoolHandler(poolSvc)
+	networkEventHandler := handler.NewNetworkEventHandler(networkAuditSvc)
+	previewLinkHandler := handler.NewPreviewLinkHandler(previewSvc)
+	trafficHandler := handler.NewTrafficHandler(trafficSvc)
 	templateHandler := handler.NewTemplateHandler(templateSvc)
 	prepullHandler := handler.NewPrepullHandler(prepullSvc, templateSvc)
 	importExportHandler := handler.NewImportExportHandler(importExportSvc)
 
 	r := gin.New()
+	// Client addresses are taken from X-Forwarded-For only when the request comes
+	// from a trusted proxy, so that API key source restrictions can't be spoofed
+	var trustedProxies []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		for _, proxy := range strings.Split(v, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				trustedProxies = append(trustedProxies, proxy)
+			}
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(gin.Recovery())
 	r.Use(logx.RequestIDMiddleware())
 	r.Use(logx.AccessLogMiddleware("api_http"))
+	r.Use(metrics.Middleware())
 
 	r.Use(cors.New(cors.Config{
 		AllowOriginFunc: func(origin string) bool {
 			return true // Allow all origins; cookie SameSite provides CSRF protection
 		},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Upgrade", "Connection", "Sec-WebSocket-Key", "Sec-WebSocket-Version", "Sec-WebSocket-Extensions", "Sec-WebSocket-Protocol"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 	r.Use(func(c *gin.Context) {
-		if drainState.IsDraining() && c.Request.URL.Path != "/health" && c.Request.URL.Path != "/readyz" {
+		if drainState.IsDraining() && c.Request.URL.Path != "/health" && c.Request.URL.Path != "/readyz" && c.Request.URL.Path != "/metrics" {
 			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "service is draining"})
 			return
 		}
@@ -239,6 +325,7 @@ func runServer() {
 		}
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+	r.GET("/metrics", metrics.Handler(os.Getenv("METRICS_TOKEN")))
 
 	// Auth middleware
 	authMiddleware := auth.AuthMiddleware(authStore)
@@ -249,8 +336,17 @@ func runServer() {
 
 	// Protected API routes
 	api := r.Group("/api/v1")
-	api.Use(authMiddleware)
+	api.Use(authMiddleware, auth.PermissionMiddleware(), auth.SandboxOwnerMiddleware(sandboxStore))
+	userHandler.RegisterRoutes(api)
+	projectHandler.RegisterRoutes(api)
+	quotaHandler.RegisterRoutes(api)
 	sandboxHandler.RegisterRoutes(api)
+	processHandler.RegisterRoutes(api)
+	snapshotHandler.RegisterRoutes(api)
+	poolHandler.RegisterRoutes(api)
+	networkEventHandler.RegisterRoutes(api)
+	previewLinkHandler.RegisterRoutes(api)
+	trafficHandler.RegisterRoutes(api)
 	templateHandler.RegisterRoutes(api)
 	prepullHandler.RegisterRoutes(api)
 	importExportHandler.RegisterRoutes(api)
@@ -379,7 +475,16 @@ func runNetworkController() {
 		}
 	}
 
-	reconciler := service.NewSandboxNetworkPolicyReconciler(k8sClient, store.NewSandboxStore(), store.NewTemplateStore())
+	// Pick up the namespaces of projects, including those the API server creates later
+	sandboxStore := store.NewSandboxStore()
+	k8sClient.SetNamespaceResolver(sandboxStore.GetClusterNamespace)
+	projectSvc := service.NewProjectService(k8sClient, store.NewProjectStore(), sandboxStore)
+	if err := projectSvc.RegisterNamespaces(ctx); err != nil {
+		slog.Warn("failed to register project namespaces", "component", "project", "error", err)
+	}
+	projectSvc.StartNamespaceSync(interval)
+
+	reconciler := service.NewSandboxNetworkPolicyReconciler(k8sClient, sandboxStore, store.NewTemplateStore())
 	if err := reconciler.RunOnce(ctx); err != nil {
 		slog.Warn("startup network policy reconcile failed", "component", "sandbox_network_policy_reconciler", "error", err)
 	}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/fslongjin/liteboxd/backend/internal/model"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -20,7 +21,7 @@ const (
 	bcryptCost              = 12
 )
 
-// EnsureAdmin checks if an admin user exists. If not, creates one from env vars.
+// EnsureAdmin checks if any user exists. If not, creates an admin from env vars.
 // If admin exists and ADMIN_PASSWORD is set to a non-default value, updates the password.
 func EnsureAdmin(ctx context.Context, authStore *store.AuthStore) error {
 	username := os.Getenv(AdminUsernameEnv)
@@ -30,7 +31,7 @@ func EnsureAdmin(ctx context.Context, authStore *store.AuthStore) error {
 	password := os.Getenv(AdminPasswordEnv)
 	initialPassword := os.Getenv(AdminInitialPasswordEnv)
 
-	count, err := authStore.AdminCount(ctx)
+	count, err := authStore.UserCount(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check admin users: %w", err)
 	}
@@ -48,12 +49,13 @@ func EnsureAdmin(ctx context.Context, authStore *store.AuthStore) error {
 		if err != nil {
 			return fmt.Errorf("failed to hash admin password: %w", err)
 		}
-		admin := &store.AdminUserRecord{
+		admin := &store.UserRecord{
 			ID:           uuid.NewString(),
 			Username:     username,
 			PasswordHash: string(hash),
+			Role:         string(model.RoleAdmin),
 		}
-		if err := authStore.CreateAdmin(ctx, admin); err != nil {
+		if err := authStore.CreateUser(ctx, admin); err != nil {
 			return fmt.Errorf("failed to create admin user: %w", err)
 		}
 		slog.Info("admin user created", "username", username)
@@ -62,7 +64,7 @@ func EnsureAdmin(ctx context.Context, authStore *store.AuthStore) error {
 
 	// Admin exists — if password env var is set and non-default, update the password
 	if password != "" && password != defaultAdminPass {
-		existing, err := authStore.GetAdminByUsername(ctx, username)
+		existing, err := authStore.GetUserByUsername(ctx, username)
 		if err != nil {
 			return fmt.Errorf("failed to query admin user: %w", err)
 		}
@@ -74,7 +76,7 @@ func EnsureAdmin(ctx context.Context, authStore *store.AuthStore) error {
 		if err != nil {
 			return fmt.Errorf("failed to hash admin password: %w", err)
 		}
-		if err := authStore.UpdateAdminPassword(ctx, existing.ID, string(hash)); err != nil {
+		if err := authStore.UpdateUserPassword(ctx, existing.ID, string(hash)); err != nil {
 			return fmt.Errorf("failed to update admin password: %w", err)
 		}
 		slog.Info("admin password updated from environment", "username", username)
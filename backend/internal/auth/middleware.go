@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/fslongjin/liteboxd/backend/internal/model"
 	"github.com/fslongjin/liteboxd/backend/internal/security"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,7 @@ const (
 	APIKeyPrefix         = "lbxk_"
 	ContextKeyUserID     = "auth_user_id"
 	ContextKeyAuthMethod = "auth_method"
+	ContextKeyRole       = "auth_role"
 
 	AuthMethodSession = "session"
 	AuthMethodAPIKey  = "api_key"
@@ -30,13 +32,20 @@ func AuthMiddleware(authStore *store.AuthStore) gin.HandlerFunc {
 			if strings.HasPrefix(authHeader, "Bearer ") {
 				token := strings.TrimPrefix(authHeader, "Bearer ")
 				if token != "" {
-					if authenticateAPIKey(c, authStore, token) {
-						c.Next()
+					apiKey := authenticateAPIKey(c, authStore, token)
+					if apiKey == nil {
+						c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+							"error": "invalid or expired API key",
+						})
 						return
 					}
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-						"error": "invalid or expired API key",
-					})
+					if err := checkAPIKeyRestrictions(c, apiKey); err != nil {
+						c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+							"error": err.Error(),
+						})
+						return
+					}
+					c.Next()
 					return
 				}
 			}
@@ -71,25 +80,25 @@ func SessionOnlyMiddleware() gin.HandlerFunc {
 	}
 }
 
-func authenticateAPIKey(c *gin.Context, authStore *store.AuthStore, token string) bool {
+// authenticateAPIKey returns the API key of token, or nil when it doesn't authenticate.
+func authenticateAPIKey(c *gin.Context, authStore *store.AuthStore, token string) *store.APIKeyRecord {
 	keyHash := security.HashToken(token)
 	apiKey, err := authStore.GetAPIKeyByHash(c.Request.Context(), keyHash)
 	if err != nil || apiKey == nil {
-		return false
+		return nil
 	}
 	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now()) {
-		return false
+		return nil
+	}
+	if !setUser(c, authStore, apiKey.UserID, apiKey) {
+		return nil
 	}
-	// Note: API Key auth intentionally does not set ContextKeyUserID.
-	// The api_keys table has no user association, and in the single-admin model
-	// there is no need to look up the admin user for API key requests.
-	// Only Me() reads ContextKeyUserID, and it already handles the missing case.
 	c.Set(ContextKeyAuthMethod, AuthMethodAPIKey)
 	// Update last_used_at asynchronously
 	go func() {
 		_ = authStore.UpdateAPIKeyLastUsed(context.Background(), apiKey.ID, time.Now())
 	}()
-	return true
+	return apiKey
 }
 
 func authenticateSession(c *gin.Context, authStore *store.AuthStore, token string) bool {
@@ -104,7 +113,38 @@ func authenticateSession(c *gin.Context, authStore *store.AuthStore, token strin
 		}()
 		return false
 	}
-	c.Set(ContextKeyUserID, session.UserID)
+	if !setUser(c, authStore, session.UserID, nil) {
+		return false
+	}
 	c.Set(ContextKeyAuthMethod, AuthMethodSession)
 	return true
 }
+
+// setUser stores the ID and role of an enabled user in the context, and the request
+// principal in the request context. API keys and sessions of disabled or deleted users
+// don't authenticate. apiKey is nil for sessions.
+func setUser(c *gin.Context, authStore *store.AuthStore, userID string, apiKey *store.APIKeyRecord) bool {
+	if userID == "" {
+		return false
+	}
+	user, err := authStore.GetUserByID(c.Request.Context(), userID)
+	if err != nil || user == nil || user.Disabled {
+		return false
+	}
+	c.Set(ContextKeyUserID, user.ID)
+	c.Set(ContextKeyRole, model.Role(user.Role))
+	p := &Principal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     model.Role(user.Role),
+	}
+	if apiKey != nil {
+		p.APIKeyID = apiKey.ID
+		p.Project = apiKey.Project
+		p.Scopes = apiKey.Scopes
+		p.Templates = apiKey.Templates
+		p.AllowedCIDRs = apiKey.AllowedCIDRs
+	}
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+	return true
+}
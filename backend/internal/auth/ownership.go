@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+const sandboxRoutePrefix = "/api/v1/sandboxes/:id"
+
+// SandboxOwnerMiddleware hides sandboxes from users other than their owner on every
+// route of a single sandbox. Admins can reach all sandboxes. API keys scoped to a
+// project, including those of admins, only reach the sandboxes of the project, and
+// API keys limited to templates only reach sandboxes made from those templates. Must
+// be used after AuthMiddleware.
+func SandboxOwnerMiddleware(sandboxStore *store.SandboxStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route != sandboxRoutePrefix && !strings.HasPrefix(route, sandboxRoutePrefix+"/") {
+			c.Next()
+			return
+		}
+		p := PrincipalFromContext(c.Request.Context())
+		if p == nil || (p.IsAdmin() && p.Project == "" && len(p.Templates) == 0) {
+			c.Next()
+			return
+		}
+
+		rec, err := sandboxStore.GetByID(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// Report other users' sandboxes as missing rather than forbidden, so their IDs
+		// can't be probed
+		if rec != nil && ((!p.IsAdmin() && rec.OwnerID != p.UserID) || (p.Project != "" && rec.Project != p.Project) ||
+			!p.AllowsTemplate(rec.TemplateName)) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "sandbox not found"})
+			return
+		}
+		c.Next()
+	}
+}
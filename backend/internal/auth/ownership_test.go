@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSandboxOwnerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := store.InitDB(filepath.Join(t.TempDir(), "liteboxd.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.CloseDB() })
+
+	sandboxStore := store.NewSandboxStore()
+	now := time.Now().UTC()
+	if err := sandboxStore.Create(context.Background(), &store.SandboxRecord{
+		ID:              "sbx-1",
+		TemplateName:    "python",
+		Image:           "python:3.11",
+		EnvJSON:         `{}`,
+		DesiredState:    store.DesiredStateActive,
+		LifecycleStatus: "running",
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(time.Hour),
+		UpdatedAt:       now,
+		OwnerID:         "u-alice",
+		OwnerName:       "alice",
+		Project:         "team-a",
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	newRouter := func(p *Principal) *gin.Engine {
+		r := gin.New()
+		api := r.Group("/api/v1")
+		api.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+		}, SandboxOwnerMiddleware(sandboxStore))
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		api.GET("/sandboxes", ok)
+		api.GET("/sandboxes/:id", ok)
+		api.POST("/sandboxes/:id/exec", ok)
+		return r
+	}
+
+	alice := &Principal{UserID: "u-alice", Username: "alice", Role: model.RoleDeveloper}
+	bob := &Principal{UserID: "u-bob", Username: "bob", Role: model.RoleDeveloper}
+	admin := &Principal{UserID: "u-admin", Username: "admin", Role: model.RoleAdmin}
+	aliceTeamA := &Principal{UserID: "u-alice", Username: "alice", Role: model.RoleDeveloper, Project: "team-a"}
+	adminTeamB := &Principal{UserID: "u-admin", Username: "admin", Role: model.RoleAdmin, Project: "team-b"}
+	alicePython := &Principal{UserID: "u-alice", Username: "alice", Role: model.RoleDeveloper, Templates: []string{"python"}}
+	aliceNode := &Principal{UserID: "u-alice", Username: "alice", Role: model.RoleDeveloper, Templates: []string{"node"}}
+	adminNode := &Principal{UserID: "u-admin", Username: "admin", Role: model.RoleAdmin, Templates: []string{"node"}}
+
+	tests := []struct {
+		principal *Principal
+		method    string
+		path      string
+		want      int
+	}{
+		{alice, http.MethodGet, "/api/v1/sandboxes/sbx-1", http.StatusOK},
+		{alice, http.MethodPost, "/api/v1/sandboxes/sbx-1/exec", http.StatusOK},
+		{bob, http.MethodGet, "/api/v1/sandboxes/sbx-1", http.StatusNotFound},
+		{bob, http.MethodPost, "/api/v1/sandboxes/sbx-1/exec", http.StatusNotFound},
+		{bob, http.MethodGet, "/api/v1/sandboxes", http.StatusOK},
+		{admin, http.MethodPost, "/api/v1/sandboxes/sbx-1/exec", http.StatusOK},
+		{aliceTeamA, http.MethodGet, "/api/v1/sandboxes/sbx-1", http.StatusOK},
+		{adminTeamB, http.MethodGet, "/api/v1/sandboxes/sbx-1", http.StatusNotFound},
+		{alicePython, http.MethodPost, "/api/v1/sandboxes/sbx-1/exec", http.StatusOK},
+		{aliceNode, http.MethodPost, "/api/v1/sandboxes/sbx-1/exec", http.StatusNotFound},
+		{adminNode, http.MethodGet, "/api/v1/sandboxes/sbx-1", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		newRouter(tt.principal).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s as %s = %d, want %d", tt.method, tt.path, tt.principal.Username, w.Code, tt.want)
+		}
+	}
+}
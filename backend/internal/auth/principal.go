@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request: a user, and the API key when
+// the request was made with one.
+type Principal struct {
+	UserID   string
+	Username string
+	Role     model.Role
+	APIKeyID string
+	// Project is set when the API key is scoped to a project
+	Project string
+	// Scopes, Templates and AllowedCIDRs are set when the API key is limited to some
+	// operations, templates or source addresses
+	Scopes       []string
+	Templates    []string
+	AllowedCIDRs []string
+}
+
+// IsAdmin reports whether the principal has the admin role.
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Role == model.RoleAdmin
+}
+
+// AllowsTemplate reports whether the principal may use the named template: callers
+// without a template allowlist may use any.
+func (p *Principal) AllowsTemplate(name string) bool {
+	if p == nil || len(p.Templates) == 0 {
+		return true
+	}
+	for _, t := range p.Templates {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a context carrying the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal of a request, or nil for work that
+// doesn't run on behalf of a caller, such as background jobs.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+var roleRank = map[model.Role]int{
+	model.RoleViewer:    1,
+	model.RoleDeveloper: 2,
+	model.RoleAdmin:     3,
+}
+
+// developerReadRoutes are read-only by method but open a shell in a sandbox or
+// download its files, so viewers may not use them.
+var developerReadRoutes = map[string]bool{
+	"/api/v1/sandboxes/:id/exec/interactive": true,
+	"/api/v1/sandboxes/:id/files":            true,
+}
+
+// adminRoutes list the sandboxes and volumes of every user, so only admins may use
+// them.
+var adminRoutes = map[string]bool{
+	"/api/v1/sandboxes/pvcs":               true,
+	"/api/v1/sandboxes/reconcile":          true,
+	"/api/v1/sandboxes/reconcile/runs":     true,
+	"/api/v1/sandboxes/reconcile/runs/:id": true,
+}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role model.Role) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleFromContext returns the role of the authenticated user.
+func RoleFromContext(c *gin.Context) model.Role {
+	role, _ := c.Get(ContextKeyRole)
+	r, _ := role.(model.Role)
+	return r
+}
+
+// UserIDFromContext returns the ID of the authenticated user.
+func UserIDFromContext(c *gin.Context) string {
+	return c.GetString(ContextKeyUserID)
+}
+
+// HasRole reports whether the authenticated user has at least the required role.
+func HasRole(c *gin.Context, required model.Role) bool {
+	return roleRank[RoleFromContext(c)] >= roleRank[required]
+}
+
+// HasRole reports whether the principal has at least the required role.
+func (p *Principal) HasRole(required model.Role) bool {
+	return p != nil && roleRank[p.Role] >= roleRank[required]
+}
+
+// RequireRole rejects requests of users below the required role. Must be used after
+// AuthMiddleware.
+func RequireRole(required model.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRole(c, required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "this operation requires the " + string(required) + " role",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// PermissionMiddleware enforces the role needed for each API route: viewers may
+// only read, everything that changes state or runs commands needs a developer, and
+// views across all users need an admin.
+// Must be used after AuthMiddleware.
+func PermissionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		RequireRole(requiredRole(c.Request.Method, c.FullPath()))(c)
+	}
+}
+
+func requiredRole(method, route string) model.Role {
+	if adminRoutes[route] {
+		return model.RoleAdmin
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if developerReadRoutes[route] {
+			return model.RoleDeveloper
+		}
+		return model.RoleViewer
+	default:
+		return model.RoleDeveloper
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(role model.Role) *gin.Engine {
+		r := gin.New()
+		api := r.Group("/api/v1")
+		api.Use(func(c *gin.Context) { c.Set(ContextKeyRole, role) }, PermissionMiddleware())
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		api.GET("/sandboxes", ok)
+		api.POST("/sandboxes/:id/exec", ok)
+		api.GET("/sandboxes/:id/exec/interactive", ok)
+		api.GET("/sandboxes/:id/files", ok)
+		api.GET("/sandboxes/:id/fs", ok)
+		api.DELETE("/sandboxes/:id", ok)
+		api.GET("/sandboxes/pvcs", ok)
+		api.GET("/sandboxes/reconcile/runs/:id", ok)
+		users := api.Group("/users")
+		users.Use(RequireRole(model.RoleAdmin))
+		users.GET("", ok)
+		return r
+	}
+
+	tests := []struct {
+		role   model.Role
+		method string
+		path   string
+		want   int
+	}{
+		{model.RoleViewer, http.MethodGet, "/api/v1/sandboxes", http.StatusOK},
+		{model.RoleViewer, http.MethodPost, "/api/v1/sandboxes/abc/exec", http.StatusForbidden},
+		{model.RoleViewer, http.MethodGet, "/api/v1/sandboxes/abc/exec/interactive", http.StatusForbidden},
+		{model.RoleViewer, http.MethodDelete, "/api/v1/sandboxes/abc", http.StatusForbidden},
+		{model.RoleViewer, http.MethodGet, "/api/v1/sandboxes/abc/files?path=/etc/passwd", http.StatusForbidden},
+		{model.RoleViewer, http.MethodGet, "/api/v1/sandboxes/abc/files?path=/workspace&archive=true", http.StatusForbidden},
+		{model.RoleViewer, http.MethodGet, "/api/v1/sandboxes/abc/fs", http.StatusOK},
+		{model.RoleDeveloper, http.MethodGet, "/api/v1/sandboxes/abc/files?path=/etc/passwd", http.StatusOK},
+		{model.RoleDeveloper, http.MethodPost, "/api/v1/sandboxes/abc/exec", http.StatusOK},
+		{model.RoleDeveloper, http.MethodGet, "/api/v1/sandboxes/abc/exec/interactive", http.StatusOK},
+		{model.RoleDeveloper, http.MethodGet, "/api/v1/users", http.StatusForbidden},
+		{model.RoleAdmin, http.MethodGet, "/api/v1/users", http.StatusOK},
+		{model.RoleViewer, http.MethodGet, "/api/v1/sandboxes/pvcs", http.StatusForbidden},
+		{model.RoleDeveloper, http.MethodGet, "/api/v1/sandboxes/pvcs", http.StatusForbidden},
+		{model.RoleDeveloper, http.MethodGet, "/api/v1/sandboxes/reconcile/runs/run-1", http.StatusForbidden},
+		{model.RoleAdmin, http.MethodGet, "/api/v1/sandboxes/pvcs", http.StatusOK},
+		{model.RoleAdmin, http.MethodGet, "/api/v1/sandboxes/reconcile/runs/run-1", http.StatusOK},
+		{"", http.MethodGet, "/api/v1/sandboxes", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		newRouter(tt.role).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s as %q = %d, want %d", tt.method, tt.path, tt.role, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+// API key scopes. A key without scopes may do everything its user may; a key with
+// scopes may only make the requests they cover, on top of the user's role.
+const (
+	ScopeSandboxRead   = "sandbox:read"
+	ScopeSandboxCreate = "sandbox:create"
+	ScopeSandboxWrite  = "sandbox:write"
+	ScopeSandboxExec   = "sandbox:exec"
+	ScopeTemplateRead  = "template:read"
+	ScopeTemplateWrite = "template:write"
+)
+
+var validScopes = map[string]bool{
+	ScopeSandboxRead:   true,
+	ScopeSandboxCreate: true,
+	ScopeSandboxWrite:  true,
+	ScopeSandboxExec:   true,
+	ScopeTemplateRead:  true,
+	ScopeTemplateWrite: true,
+}
+
+// execRoutes run commands in a sandbox and need the sandbox:exec scope.
+var execRoutes = map[string]bool{
+	"POST /api/v1/sandboxes/:id/exec":                        true,
+	"POST /api/v1/sandboxes/:id/exec/stream":                 true,
+	"GET /api/v1/sandboxes/:id/exec/interactive":             true,
+	"POST /api/v1/sandboxes/:id/processes":                   true,
+	"POST /api/v1/sandboxes/:id/processes/:processId/signal": true,
+	"POST /api/v1/sandboxes/:id/processes/:processId/kill":   true,
+}
+
+// unscopedRoutes may be used by every API key, whatever its scopes.
+var unscopedRoutes = map[string]bool{
+	"/api/v1/auth/me": true,
+	"/api/v1/quotas":  true,
+}
+
+// ValidScope reports whether scope is a known API key scope.
+func ValidScope(scope string) bool {
+	return validScopes[scope]
+}
+
+// requiredScope returns the scope a request needs, or "" when no scope allows it.
+func requiredScope(method, route string) string {
+	read := method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	switch {
+	case strings.HasPrefix(route, "/api/v1/sandboxes"), strings.HasPrefix(route, "/api/v1/snapshots"):
+		switch {
+		case execRoutes[method+" "+route]:
+			return ScopeSandboxExec
+		case route == "/api/v1/sandboxes" && method == http.MethodPost:
+			return ScopeSandboxCreate
+		case read:
+			return ScopeSandboxRead
+		default:
+			return ScopeSandboxWrite
+		}
+	case strings.HasPrefix(route, "/api/v1/templates"), strings.HasPrefix(route, "/api/v1/pools"),
+		strings.HasPrefix(route, "/api/v1/images"):
+		if read {
+			return ScopeTemplateRead
+		}
+		return ScopeTemplateWrite
+	default:
+		return ""
+	}
+}
+
+// checkAPIKeyRestrictions checks the request against the source address allowlist,
+// scopes and template allowlist of an API key.
+func checkAPIKeyRestrictions(c *gin.Context, apiKey *store.APIKeyRecord) error {
+	if len(apiKey.AllowedCIDRs) > 0 && !ipAllowed(c.ClientIP(), apiKey.AllowedCIDRs) {
+		return fmt.Errorf("API key may not be used from %s", c.ClientIP())
+	}
+
+	route := c.FullPath()
+	if len(apiKey.Scopes) > 0 && !unscopedRoutes[route] {
+		scope := requiredScope(c.Request.Method, route)
+		if scope == "" {
+			return fmt.Errorf("API key scopes don't allow this operation")
+		}
+		if !hasScope(apiKey.Scopes, scope) {
+			return fmt.Errorf("this operation requires the %s scope", scope)
+		}
+	}
+
+	if len(apiKey.Templates) > 0 {
+		var name string
+		switch {
+		case strings.HasPrefix(route, "/api/v1/templates/:name"):
+			name = c.Param("name")
+		case strings.HasPrefix(route, "/api/v1/pools/:template"):
+			name = c.Param("template")
+		}
+		if name != "" && !PrincipalFromContext(c.Request.Context()).AllowsTemplate(name) {
+			return fmt.Errorf("API key may not use template %s", name)
+		}
+	}
+	return nil
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeCIDR parses a CIDR or a single IP address, which becomes a /32 or /128.
+func NormalizeCIDR(s string) (string, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid CIDR %q", s)
+	}
+	return network.String(), nil
+}
+
+// ipAllowed reports whether ip is in one of cidrs.
+func ipAllowed(ip string, cidrs []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
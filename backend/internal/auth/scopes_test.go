@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddlewareEnforcesAPIKeyRestrictions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := store.InitDB(filepath.Join(t.TempDir(), "liteboxd.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.CloseDB() })
+
+	ctx := context.Background()
+	authStore := store.NewAuthStore()
+	now := time.Now().UTC()
+	if err := authStore.CreateUser(ctx, &store.UserRecord{
+		ID: "u-ci", Username: "ci", PasswordHash: "x", Role: string(model.RoleDeveloper), CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	keys := map[string]*store.APIKeyRecord{
+		"lbxk_full": {},
+		"lbxk_ci": {
+			Scopes:    []string{ScopeSandboxCreate, ScopeSandboxExec},
+			Templates: []string{"python", "node"},
+		},
+		"lbxk_office": {AllowedCIDRs: []string{"10.0.0.0/8"}},
+	}
+	for token, rec := range keys {
+		rec.ID, rec.UserID, rec.Name, rec.Prefix = token, "u-ci", token, token
+		rec.KeyHash = security.HashToken(token)
+		if err := authStore.CreateAPIKey(ctx, rec); err != nil {
+			t.Fatalf("CreateAPIKey() error = %v", err)
+		}
+	}
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(AuthMiddleware(authStore))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/auth/me", ok)
+	api.POST("/sandboxes", ok)
+	api.GET("/sandboxes/:id", ok)
+	api.DELETE("/sandboxes/:id", ok)
+	api.POST("/sandboxes/:id/exec", ok)
+	api.GET("/templates/:name", ok)
+	api.GET("/pools/:template", ok)
+	api.GET("/users", ok)
+
+	tests := []struct {
+		token  string
+		method string
+		path   string
+		remote string
+		want   int
+	}{
+		{"lbxk_full", http.MethodDelete, "/api/v1/sandboxes/sbx-1", "", http.StatusOK},
+		{"lbxk_full", http.MethodGet, "/api/v1/users", "", http.StatusOK},
+		{"lbxk_ci", http.MethodPost, "/api/v1/sandboxes", "", http.StatusOK},
+		{"lbxk_ci", http.MethodPost, "/api/v1/sandboxes/sbx-1/exec", "", http.StatusOK},
+		{"lbxk_ci", http.MethodGet, "/api/v1/auth/me", "", http.StatusOK},
+		{"lbxk_ci", http.MethodGet, "/api/v1/sandboxes/sbx-1", "", http.StatusForbidden},
+		{"lbxk_ci", http.MethodDelete, "/api/v1/sandboxes/sbx-1", "", http.StatusForbidden},
+		{"lbxk_ci", http.MethodGet, "/api/v1/users", "", http.StatusForbidden},
+		{"lbxk_office", http.MethodGet, "/api/v1/sandboxes/sbx-1", "10.1.2.3:5000", http.StatusOK},
+		{"lbxk_office", http.MethodGet, "/api/v1/sandboxes/sbx-1", "192.0.2.1:5000", http.StatusForbidden},
+		{"lbxk_unknown", http.MethodGet, "/api/v1/sandboxes/sbx-1", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		if tt.remote != "" {
+			req.RemoteAddr = tt.remote
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s with %s = %d, want %d", tt.method, tt.path, tt.token, w.Code, tt.want)
+		}
+	}
+}
+
+func TestAuthMiddlewareEnforcesTemplateAllowlist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := store.InitDB(filepath.Join(t.TempDir(), "liteboxd.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.CloseDB() })
+
+	ctx := context.Background()
+	authStore := store.NewAuthStore()
+	now := time.Now().UTC()
+	if err := authStore.CreateUser(ctx, &store.UserRecord{
+		ID: "u-ci", Username: "ci", PasswordHash: "x", Role: string(model.RoleDeveloper), CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := authStore.CreateAPIKey(ctx, &store.APIKeyRecord{
+		ID: "k-ci", UserID: "u-ci", Name: "ci", Prefix: "lbxk_ci", KeyHash: security.HashToken("lbxk_ci"),
+		Templates: []string{"python"},
+	}); err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(AuthMiddleware(authStore))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/templates/:name", ok)
+	api.GET("/pools/:template", ok)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/v1/templates/python", http.StatusOK},
+		{"/api/v1/templates/node", http.StatusForbidden},
+		{"/api/v1/pools/python", http.StatusOK},
+		{"/api/v1/pools/node", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer lbxk_ci")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
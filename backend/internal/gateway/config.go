@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"github.com/fslongjin/liteboxd/backend/internal/k8s"
@@ -24,6 +25,9 @@ type Config struct {
 	// UseK8sProxy enables using K8s API server proxy instead of direct pod connection
 	// This is useful for local development with remote cluster
 	UseK8sProxy bool
+	// BaseDomain enables host routing: {port}-{id}.<BaseDomain> proxies the whole
+	// request path to the sandbox port. Empty disables it.
+	BaseDomain string
 }
 
 // LoadConfig loads configuration from environment variables with defaults
@@ -51,6 +55,8 @@ func LoadConfig() *Config {
 		}
 	}
 
+	baseDomain := strings.Trim(strings.ToLower(os.Getenv("GATEWAY_BASE_DOMAIN")), ".")
+
 	return &Config{
 		Port:             port,
 		KubeconfigPath:   kubeconfigPath,
@@ -59,5 +65,6 @@ func LoadConfig() *Config {
 		RequestTimeout:   5 * time.Minute,
 		ShutdownTimeout:  shutdownTimeout,
 		UseK8sProxy:      useK8sProxy,
+		BaseDomain:       baseDomain,
 	}
 }
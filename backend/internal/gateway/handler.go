@@ -13,22 +13,36 @@ import (
 type Service struct {
 	k8sClient    *k8s.Client
 	sandboxStore *store.SandboxStore
+	previewStore *store.SandboxPreviewLinkStore
 	config       *Config
 	drainState   *lifecycle.DrainManager
+	traffic      *TrafficMeter
 }
 
 // NewService creates a new gateway service
-func NewService(k8sClient *k8s.Client, sandboxStore *store.SandboxStore, config *Config, drainState *lifecycle.DrainManager) *Service {
+func NewService(k8sClient *k8s.Client, sandboxStore *store.SandboxStore, previewStore *store.SandboxPreviewLinkStore, config *Config, drainState *lifecycle.DrainManager) *Service {
 	return &Service{
 		k8sClient:    k8sClient,
 		sandboxStore: sandboxStore,
+		previewStore: previewStore,
 		config:       config,
 		drainState:   drainState,
 	}
 }
 
+// SetTrafficMeter sets the meter that counts the traffic relayed per sandbox.
+func (s *Service) SetTrafficMeter(m *TrafficMeter) {
+	s.traffic = m
+}
+
 // RegisterRoutes registers all gateway routes
 func (s *Service) RegisterRoutes(r *gin.Engine) {
+	// Host routes take precedence over every path route, so the middleware must be
+	// registered first
+	if s.config.BaseDomain != "" {
+		r.Use(s.HostRoutingMiddleware())
+	}
+
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "gateway"})
@@ -54,4 +68,12 @@ func (s *Service) RegisterRoutes(r *gin.Engine) {
 	proxyGroup.PUT("/sandbox/:sandbox/port/:port/*action", s.ProxyHandler)
 	proxyGroup.DELETE("/sandbox/:sandbox/port/:port/*action", s.ProxyHandler)
 	proxyGroup.PATCH("/sandbox/:sandbox/port/:port/*action", s.ProxyHandler)
+
+	// TCP tunnel route - only the sandbox access token opens a tunnel
+	// Format: /api/v1/sandbox/:sandbox/tcp/:port
+	tunnelGroup := r.Group("/api/v1")
+	tunnelGroup.Use(ExtractPortMiddleware())
+	tunnelGroup.Use(RequireAccessTokenMiddleware())
+	tunnelGroup.Use(s.AuthMiddleware())
+	tunnelGroup.GET("/sandbox/:sandbox/tcp/:port", s.TunnelHandler)
 }
@@ -2,10 +2,18 @@ package gateway
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
 	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,63 +21,267 @@ const (
 	authorizationHeader = "X-Access-Token"
 	sandboxIDParam      = "sandbox"
 	portParam           = "port"
+	// hostRoutedKey marks requests routed by host rather than by path
+	hostRoutedKey = "hostRouted"
+	// targetPathKey holds the cleaned path of requests authorized by a preview link,
+	// which is forwarded instead of the raw one
+	targetPathKey = "targetPath"
 )
 
 // AuthMiddleware creates authentication middleware for the gateway
 func (s *Service) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		logger := logx.LoggerWithRequestID(c.Request.Context()).With("component", "gateway_auth")
-
-		// Extract sandbox ID from URL path
-		sandboxID := c.Param(sandboxIDParam)
-		if sandboxID == "" {
-			logger.Warn("missing sandbox id")
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"error": "sandbox ID is required",
-			})
+		if !s.authorize(c) {
 			return
 		}
+		// Token is valid, proceed to next handler
+		c.Next()
+	}
+}
 
-		// Extract access token from header
-		token := c.GetHeader(authorizationHeader)
-		if token == "" {
-			logger.Warn("missing access token", "sandbox_id", sandboxID)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "missing access token",
-			})
-			return
+// authorize checks the access token of a request for the sandbox in its params. It
+// aborts the request and returns false when access is denied.
+func (s *Service) authorize(c *gin.Context) bool {
+	logger := logx.LoggerWithRequestID(c.Request.Context()).With("component", "gateway_auth")
+
+	// Extract sandbox ID from URL path
+	sandboxID := c.Param(sandboxIDParam)
+	if sandboxID == "" {
+		logger.Warn("missing sandbox id")
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "sandbox ID is required",
+		})
+		return false
+	}
+
+	// Extract access token from header. Without one, a preview token from a query
+	// parameter or cookie is accepted instead.
+	token := c.GetHeader(authorizationHeader)
+	var link *store.SandboxPreviewLinkRecord
+	if token == "" {
+		var ok bool
+		if link, ok = s.authorizePreview(c, sandboxID); !ok {
+			return false
 		}
+	}
+
+	// Verify token against sandbox metadata in DB (source of truth).
+	record, err := s.sandboxStore.GetByID(c.Request.Context(), sandboxID)
+	if err != nil {
+		logger.Error("failed to query sandbox metadata during auth", "sandbox_id", sandboxID, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to verify access token",
+		})
+		return false
+	}
+	if record == nil || record.DesiredState != "active" || record.LifecycleStatus == "deleted" || record.LifecycleStatus == "lost" {
+		logger.Warn("sandbox not found during auth", "sandbox_id", sandboxID)
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "sandbox not found",
+		})
+		return false
+	}
+
+	// Compare hashed token.
+	if link == nil && !accessTokenMatches(record, token, time.Now().UTC()) {
+		logger.Warn("invalid access token", "sandbox_id", sandboxID)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid access token",
+		})
+		return false
+	}
 
-		// Verify token against sandbox metadata in DB (source of truth).
-		record, err := s.sandboxStore.GetByID(c.Request.Context(), sandboxID)
+	if record.LifecycleStatus == "paused" {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "sandbox is paused",
+		})
+		return false
+	}
+
+	// Inbound traffic keeps sandboxes with an idle TTL alive.
+	if err := s.sandboxStore.TouchIdleTTL(c.Request.Context(), record, time.Now().UTC()); err != nil {
+		logger.Warn("failed to extend idle ttl", "sandbox_id", sandboxID, "error", err)
+	}
+
+	logger.Debug("gateway auth success", "sandbox_id", sandboxID)
+	return true
+}
+
+// accessTokenMatches reports whether token is the access token of a sandbox, or the
+// token replaced by its last rotation while that is still in its grace period.
+func accessTokenMatches(record *store.SandboxRecord, token string, now time.Time) bool {
+	hash := security.HashToken(token)
+	if hash == record.AccessTokenSHA256 {
+		return true
+	}
+	return record.PreviousAccessTokenSHA256 != "" && hash == record.PreviousAccessTokenSHA256 &&
+		record.PreviousAccessTokenExpiresAt != nil && now.Before(*record.PreviousAccessTokenExpiresAt)
+}
+
+// authorizePreview checks the preview token of a request without an access token. A
+// token passed as a query parameter is moved into a cookie, so the pages and assets
+// loaded from the first response are authorized too. The token is removed from the
+// request before it is proxied.
+func (s *Service) authorizePreview(c *gin.Context, sandboxID string) (*store.SandboxPreviewLinkRecord, bool) {
+	logger := logx.LoggerWithRequestID(c.Request.Context()).With("component", "gateway_auth")
+
+	token := c.Query(model.PreviewTokenParam)
+	fromQuery := token != ""
+	if !fromQuery {
+		if cookie, err := c.Request.Cookie(model.PreviewTokenParam); err == nil {
+			token = cookie.Value
+		}
+	}
+	if token == "" {
+		logger.Warn("missing access token", "sandbox_id", sandboxID)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "missing access token",
+		})
+		return nil, false
+	}
+
+	link, err := s.previewStore.GetByTokenHash(c.Request.Context(), security.HashToken(token))
+	if err != nil {
+		logger.Error("failed to query preview link during auth", "sandbox_id", sandboxID, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to verify preview token",
+		})
+		return nil, false
+	}
+	port, _ := strconv.Atoi(c.GetString("port"))
+	now := time.Now().UTC()
+	if link == nil || link.SandboxID != sandboxID || link.Port != port || !now.Before(link.ExpiresAt) {
+		logger.Warn("invalid preview token", "sandbox_id", sandboxID)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid preview token",
+		})
+		return nil, false
+	}
+	target, ok := cleanPreviewPath(targetPath(c))
+	if !ok {
+		logger.Warn("dot segment in preview path", "sandbox_id", sandboxID, "link_id", link.ID)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "path must not contain . or .. segments",
+		})
+		return nil, false
+	}
+	if !pathWithinPrefix(target, link.PathPrefix) {
+		logger.Warn("path outside preview link", "sandbox_id", sandboxID, "link_id", link.ID)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "path is not covered by the preview link",
+		})
+		return nil, false
+	}
+
+	if fromQuery {
+		cookiePath := fmt.Sprintf("/api/v1/sandbox/%s/port/%d", sandboxID, port)
+		if c.GetBool(hostRoutedKey) {
+			cookiePath = "/"
+		}
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     model.PreviewTokenParam,
+			Value:    token,
+			Path:     cookiePath,
+			Expires:  link.ExpiresAt,
+			HttpOnly: true,
+			Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	c.Set(targetPathKey, target)
+	stripPreviewToken(c.Request)
+	return link, true
+}
+
+// cleanPreviewPath returns p without repeated slashes, keeping a trailing slash. Paths
+// with . or .. segments, also percent-encoded, are refused: the sandbox would resolve
+// them to paths outside the prefix of the preview link.
+func cleanPreviewPath(p string) (string, bool) {
+	for _, segment := range strings.Split(p, "/") {
+		decoded, err := url.PathUnescape(segment)
 		if err != nil {
-			logger.Error("failed to query sandbox metadata during auth", "sandbox_id", sandboxID, "error", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"error": "failed to verify access token",
-			})
-			return
+			decoded = segment
 		}
-		if record == nil || record.DesiredState != "active" || record.LifecycleStatus == "deleted" || record.LifecycleStatus == "lost" {
-			logger.Warn("sandbox not found during auth", "sandbox_id", sandboxID)
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-				"error": "sandbox not found",
-			})
-			return
+		if segment == "." || segment == ".." || decoded == "." || decoded == ".." {
+			return "", false
 		}
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned, true
+}
 
-		// Compare hashed token.
-		if security.HashToken(token) != record.AccessTokenSHA256 {
-			logger.Warn("invalid access token", "sandbox_id", sandboxID)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "invalid access token",
-			})
+// pathWithinPrefix reports whether p is prefix or a path below it. An empty prefix
+// covers every path.
+func pathWithinPrefix(p, prefix string) bool {
+	if prefix == "" || p == prefix {
+		return true
+	}
+	return strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// stripPreviewToken removes the preview token query parameter and cookie from a request.
+func stripPreviewToken(req *http.Request) {
+	query := req.URL.Query()
+	if query.Has(model.PreviewTokenParam) {
+		query.Del(model.PreviewTokenParam)
+		req.URL.RawQuery = query.Encode()
+	}
+	if _, err := req.Cookie(model.PreviewTokenParam); err == nil {
+		cookies := req.Cookies()
+		req.Header.Del("Cookie")
+		for _, cookie := range cookies {
+			if cookie.Name != model.PreviewTokenParam {
+				req.AddCookie(cookie)
+			}
+		}
+	}
+}
+
+// HostRoutingMiddleware serves requests for {port}-{id}.<base domain> hosts. The
+// request path is passed to the sandbox unchanged, so apps that use absolute paths work.
+// Requests for other hosts go on to the path routes.
+func (s *Service) HostRoutingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sandboxID, port, ok := parseSandboxHost(c.Request.Host, s.config.BaseDomain)
+		if !ok {
+			c.Next()
 			return
 		}
+		c.Params = gin.Params{
+			{Key: sandboxIDParam, Value: sandboxID},
+			{Key: portParam, Value: port},
+		}
+		c.Set("port", port)
+		c.Set(hostRoutedKey, true)
+		if !s.authorize(c) {
+			return
+		}
+		s.ProxyHandler(c)
+		c.Abort()
+	}
+}
 
-		logger.Debug("gateway auth success", "sandbox_id", sandboxID)
-		// Token is valid, proceed to next handler
-		c.Next()
+// parseSandboxHost extracts the sandbox ID and port from a {port}-{id}.<baseDomain>
+// host.
+func parseSandboxHost(host, baseDomain string) (sandboxID, port string, ok bool) {
+	if baseDomain == "" {
+		return "", "", false
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label, found := strings.CutSuffix(strings.TrimSuffix(strings.ToLower(host), "."), "."+baseDomain)
+	if !found || strings.Contains(label, ".") {
+		return "", "", false
+	}
+	port, sandboxID, ok = strings.Cut(label, "-")
+	if n, err := strconv.Atoi(port); !ok || sandboxID == "" || err != nil || n < 1 || n > 65535 {
+		return "", "", false
 	}
+	return sandboxID, port, true
 }
 
 // CORSMiddleware adds CORS headers for WebSocket support
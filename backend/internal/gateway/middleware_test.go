@@ -4,13 +4,18 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
 	"github.com/fslongjin/liteboxd/backend/internal/security"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
 	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func initGatewayTestDB(t *testing.T) *store.SandboxStore {
@@ -115,3 +120,221 @@ func TestAuthMiddlewareRejectsInvalidOrDeletedSandbox(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSandboxHost(t *testing.T) {
+	cases := []struct {
+		host, id, port string
+		ok             bool
+	}{
+		{host: "8080-abc12345.sandbox.example.com", id: "abc12345", port: "8080", ok: true},
+		{host: "8080-ABC12345.Sandbox.Example.com.:443", id: "abc12345", port: "8080", ok: true},
+		{host: "sandbox.example.com"},
+		{host: "8080-abc12345.other.example.com"},
+		{host: "x.8080-abc12345.sandbox.example.com"},
+		{host: "abc12345.sandbox.example.com"},
+		{host: "80x-abc12345.sandbox.example.com"},
+		{host: "70000-abc12345.sandbox.example.com"},
+		{host: "8080-.sandbox.example.com"},
+	}
+	for _, tc := range cases {
+		id, port, ok := parseSandboxHost(tc.host, "sandbox.example.com")
+		if id != tc.id || port != tc.port || ok != tc.ok {
+			t.Fatalf("parseSandboxHost(%q) = %q, %q, %v", tc.host, id, port, ok)
+		}
+	}
+	if _, _, ok := parseSandboxHost("8080-abc12345.sandbox.example.com", ""); ok {
+		t.Fatalf("host routing matched without a base domain")
+	}
+}
+
+func TestHostRoutingProxiesWholePath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sandboxStore := initGatewayTestDB(t)
+	seedSandbox(t, sandboxStore, "abc12345", "secret-token", "running")
+
+	var gotPath string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+
+	k8sClient := k8s.NewClientForTest(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sandbox-abc12345", Namespace: k8s.DefaultSandboxNamespace},
+		Status:     corev1.PodStatus{PodIP: backendURL.Hostname()},
+	})
+	svc := NewService(k8sClient, sandboxStore, store.NewSandboxPreviewLinkStore(), &Config{BaseDomain: "sandbox.example.com"}, nil)
+	r := gin.New()
+	svc.RegisterRoutes(r)
+	gateway := httptest.NewServer(r)
+	defer gateway.Close()
+
+	get := func(host, path, token string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, gateway.URL+path, nil)
+		req.Host = host
+		if token != "" {
+			req.Header.Set(authorizationHeader, token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s%s error = %v", host, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	sandboxHost := backendURL.Port() + "-abc12345.sandbox.example.com"
+
+	// Paths that collide with gateway routes still go to the sandbox
+	for _, path := range []string{"/@vite/client", "/health"} {
+		if status := get(sandboxHost, path, "secret-token"); status != http.StatusTeapot || gotPath != path {
+			t.Fatalf("GET %s: status %d, backend path %q", path, status, gotPath)
+		}
+	}
+	if status := get(sandboxHost, "/", ""); status != http.StatusUnauthorized {
+		t.Fatalf("request without token: status %d, want 401", status)
+	}
+	if status := get("gateway.example.com", "/health", ""); status != http.StatusOK {
+		t.Fatalf("gateway health on its own host: status %d", status)
+	}
+	if status := get("gateway.example.com", "/api/v1/sandbox/abc12345/port/"+backendURL.Port()+"/x/y", "secret-token"); status != http.StatusTeapot || gotPath != "/x/y" {
+		t.Fatalf("path route: status %d, backend path %q", status, gotPath)
+	}
+}
+
+func TestPreviewTokenAuthorizesBrowserRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sandboxStore := initGatewayTestDB(t)
+	seedSandbox(t, sandboxStore, "abc12345", "secret-token", "running")
+
+	var gotPath, gotQuery, gotCookie string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotQuery = r.URL.RawQuery
+		gotCookie = r.Header.Get("Cookie")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	previewStore := store.NewSandboxPreviewLinkStore()
+	now := time.Now().UTC()
+	for _, rec := range []store.SandboxPreviewLinkRecord{
+		{ID: "prv-1", TokenSHA256: security.HashToken("preview-token"), Port: port, PathPrefix: "/docs", ExpiresAt: now.Add(time.Hour)},
+		{ID: "prv-2", TokenSHA256: security.HashToken("expired-token"), Port: port, ExpiresAt: now.Add(-time.Minute)},
+		{ID: "prv-3", TokenSHA256: security.HashToken("other-port-token"), Port: port + 1, ExpiresAt: now.Add(time.Hour)},
+	} {
+		rec.SandboxID = "abc12345"
+		rec.CreatedAt = now
+		if err := previewStore.Create(context.Background(), &rec); err != nil {
+			t.Fatalf("seed preview link error: %v", err)
+		}
+	}
+
+	k8sClient := k8s.NewClientForTest(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sandbox-abc12345", Namespace: k8s.DefaultSandboxNamespace},
+		Status:     corev1.PodStatus{PodIP: backendURL.Hostname()},
+	})
+	svc := NewService(k8sClient, sandboxStore, previewStore, &Config{}, nil)
+	r := gin.New()
+	svc.RegisterRoutes(r)
+	gateway := httptest.NewServer(r)
+	defer gateway.Close()
+
+	base := gateway.URL + "/api/v1/sandbox/abc12345/port/" + backendURL.Port()
+	get := func(rawURL string, cookie *http.Cookie) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", rawURL, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get(base+"/docs/index.html?page=2&liteboxd_preview_token=preview-token", nil)
+	if resp.StatusCode != http.StatusTeapot || gotQuery != "page=2" {
+		t.Fatalf("query token: status %d, backend query %q", resp.StatusCode, gotQuery)
+	}
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Value != "preview-token" || cookies[0].Path != "/api/v1/sandbox/abc12345/port/"+backendURL.Port() {
+		t.Fatalf("unexpected preview cookie: %+v", cookies)
+	}
+
+	if resp := get(base+"/docs/app.js", cookies[0]); resp.StatusCode != http.StatusTeapot || gotCookie != "" {
+		t.Fatalf("cookie token: status %d, backend cookie %q", resp.StatusCode, gotCookie)
+	}
+	if resp := get(base+"//docs//guide/", cookies[0]); resp.StatusCode != http.StatusTeapot || gotPath != "/docs/guide/" {
+		t.Fatalf("unclean path: status %d, backend path %q", resp.StatusCode, gotPath)
+	}
+	cases := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{name: "outside path prefix", url: base + "/admin?liteboxd_preview_token=preview-token", status: http.StatusForbidden},
+		{name: "prefix is matched by segment", url: base + "/docsx?liteboxd_preview_token=preview-token", status: http.StatusForbidden},
+		{name: "dot dot segment", url: base + "/docs/../admin?liteboxd_preview_token=preview-token", status: http.StatusBadRequest},
+		{name: "dot segment", url: base + "/docs/./index.html?liteboxd_preview_token=preview-token", status: http.StatusBadRequest},
+		{name: "encoded dot dot segment", url: base + "/docs/%2e%2e/admin?liteboxd_preview_token=preview-token", status: http.StatusBadRequest},
+		{name: "encoded slash and dot dot", url: base + "/docs%2F..%2Fadmin?liteboxd_preview_token=preview-token", status: http.StatusBadRequest},
+		{name: "double encoded dot dot segment", url: base + "/docs/%252e%252e/admin?liteboxd_preview_token=preview-token", status: http.StatusBadRequest},
+		{name: "expired", url: base + "/?liteboxd_preview_token=expired-token", status: http.StatusUnauthorized},
+		{name: "other port", url: base + "/?liteboxd_preview_token=other-port-token", status: http.StatusUnauthorized},
+		{name: "unknown token", url: base + "/?liteboxd_preview_token=nope", status: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		if resp := get(tc.url, nil); resp.StatusCode != tc.status {
+			t.Fatalf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.status)
+		}
+	}
+}
+
+func TestAuthMiddlewareAcceptsPreviousTokenDuringGrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sandboxStore := initGatewayTestDB(t)
+	seedSandbox(t, sandboxStore, "rot12345", "old-token", "running")
+	seedSandbox(t, sandboxStore, "exp12345", "old-token-2", "running")
+	ctx := context.Background()
+	now := time.Now().UTC()
+	graceEnd := now.Add(time.Minute)
+	if _, err := sandboxStore.RotateAccessToken(ctx, "rot12345", "c", "n", "v1", security.HashToken("new-token"), &graceEnd, now); err != nil {
+		t.Fatalf("RotateAccessToken() error = %v", err)
+	}
+	graceEnded := now.Add(-time.Second)
+	if _, err := sandboxStore.RotateAccessToken(ctx, "exp12345", "c", "n", "v1", security.HashToken("new-token-2"), &graceEnded, now); err != nil {
+		t.Fatalf("RotateAccessToken() error = %v", err)
+	}
+
+	svc := &Service{sandboxStore: sandboxStore}
+	r := gin.New()
+	r.Use(svc.AuthMiddleware())
+	r.GET("/api/v1/sandbox/:sandbox/port/:port/*action", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		sandbox, token string
+		status         int
+	}{
+		{sandbox: "rot12345", token: "new-token", status: http.StatusOK},
+		{sandbox: "rot12345", token: "old-token", status: http.StatusOK},
+		{sandbox: "exp12345", token: "new-token-2", status: http.StatusOK},
+		{sandbox: "exp12345", token: "old-token-2", status: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/sandbox/"+tc.sandbox+"/port/8080/ping", nil)
+		req.Header.Set(authorizationHeader, tc.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s with %s: status %d, want %d", tc.sandbox, tc.token, w.Code, tc.status)
+		}
+	}
+}
@@ -115,6 +115,7 @@ func (s *Service) ProxyHandler(c *gin.Context) {
 
 	sandboxID := c.Param(sandboxIDParam)
 	port := c.GetString("port")
+	realPath := targetPath(c)
 	logger = logger.With("sandbox_id", sandboxID, "port", port)
 
 	var targetURL *url.URL
@@ -149,26 +150,9 @@ func (s *Service) ProxyHandler(c *gin.Context) {
 			// Construct proxy path
 			// Target: /api/v1/namespaces/liteboxd/pods/sandbox-{id}:{port}/proxy/{path}
 
-			// Extract path after /port/{port}
-			realPath := ""
-			parts := strings.Split(req.URL.Path, "/")
-			portIndex := -1
-			for i, p := range parts {
-				if p == "port" && i+1 < len(parts) {
-					portIndex = i + 2
-					break
-				}
-			}
-			if portIndex > 0 && portIndex < len(parts) {
-				realPath = "/" + strings.Join(parts[portIndex:], "/")
-			}
-			if realPath == "" {
-				realPath = "/"
-			}
-
 			// K8s API Proxy path
 			k8sProxyPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s:%s/proxy%s",
-				s.k8sClient.SandboxNamespace(), podName, port, realPath)
+				s.k8sClient.SandboxNamespaceOf(req.Context(), sandboxID), podName, port, realPath)
 
 			req.URL.Path = k8sProxyPath
 
@@ -225,21 +209,7 @@ func (s *Service) ProxyHandler(c *gin.Context) {
 			// Update the request path to remove the gateway prefix
 			// Original path: /api/v1/sandbox/{id}/port/{port}/...
 			// Target path: /...
-			parts := strings.Split(req.URL.Path, "/")
-			if len(parts) >= 6 {
-				// parts[0]="", parts[1]="api", parts[2]="v1", parts[3]="sandbox", parts[4]={id}, parts[5]="port", parts[6]={port}, parts[7+]=...
-				// Find the index after "port"
-				portIndex := -1
-				for i, p := range parts {
-					if p == "port" && i+1 < len(parts) {
-						portIndex = i + 2
-						break
-					}
-				}
-				if portIndex > 0 && portIndex < len(parts) {
-					req.URL.Path = "/" + strings.Join(parts[portIndex:], "/")
-				}
-			}
+			req.URL.Path = realPath
 
 			// Update host to target
 			req.Host = targetURL.Host
@@ -268,8 +238,19 @@ func (s *Service) ProxyHandler(c *gin.Context) {
 	}
 
 	logger.Debug("proxying http request", "target", targetURL.String())
+	var body *countingReadCloser
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		body = &countingReadCloser{ReadCloser: c.Request.Body}
+		c.Request.Body = body
+	}
 	// Serve the proxied request
 	proxy.ServeHTTP(c.Writer, c.Request)
+
+	var in int64
+	if body != nil {
+		in = body.n
+	}
+	s.traffic.Add(sandboxID, in, int64(max(c.Writer.Size(), 0)))
 }
 
 // handleWebSocketUpgrade handles WebSocket upgrade requests
@@ -293,7 +274,7 @@ func (s *Service) handleWebSocketUpgrade(c *gin.Context, target *url.URL) {
 
 	sandboxID := c.Param(sandboxIDParam)
 	port := c.GetString("port")
-	realPath := extractTargetPath(c.Request.URL.Path)
+	realPath := targetPath(c)
 	var backendURL *url.URL
 	var header http.Header
 	var dialer *websocket.Dialer
@@ -301,7 +282,7 @@ func (s *Service) handleWebSocketUpgrade(c *gin.Context, target *url.URL) {
 	if s.config.UseK8sProxy {
 		podName := fmt.Sprintf("sandbox-%s", sandboxID)
 		k8sProxyPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s:%s/proxy%s",
-			s.k8sClient.SandboxNamespace(), podName, port, realPath)
+			s.k8sClient.SandboxNamespaceOf(c.Request.Context(), sandboxID), podName, port, realPath)
 		backendURL = &url.URL{
 			Scheme:   target.Scheme,
 			Host:     target.Host,
@@ -375,7 +356,7 @@ func (s *Service) handleWebSocketUpgrade(c *gin.Context, target *url.URL) {
 		"subprotocol", backendConn.Subprotocol(),
 	)
 
-	runWebSocketProxySession(c.Request.Context(), logger, sessionID, clientConn, backendConn)
+	runWebSocketProxySession(c.Request.Context(), logger, sessionID, clientConn, backendConn, s.recordTraffic(sandboxID))
 	logger.Info("websocket proxy disconnected")
 }
 
@@ -395,6 +376,19 @@ func wsSchemeFromTarget(target *url.URL) string {
 	return "ws"
 }
 
+// targetPath is the path requested from the sandbox: the cleaned path of requests
+// authorized by a preview link, else the part after /port/{port} for path routes, or
+// the whole path for host routes.
+func targetPath(c *gin.Context) string {
+	if p := c.GetString(targetPathKey); p != "" {
+		return p
+	}
+	if c.GetBool(hostRoutedKey) {
+		return c.Request.URL.Path
+	}
+	return extractTargetPath(c.Request.URL.Path)
+}
+
 func extractTargetPath(path string) string {
 	parts := strings.Split(path, "/")
 	portIndex := -1
@@ -479,7 +473,10 @@ func cloneHeader(src http.Header) http.Header {
 	return dst
 }
 
-func runWebSocketProxySession(ctx context.Context, logger *slog.Logger, sessionID string, clientConn, backendConn *websocket.Conn) {
+// runWebSocketProxySession relays messages between a client and a backend WebSocket
+// until either closes. The size of every relayed message is passed to record, which
+// may be nil.
+func runWebSocketProxySession(ctx context.Context, logger *slog.Logger, sessionID string, clientConn, backendConn *websocket.Conn, record trafficRecorder) {
 	client := newSafeWSConn("client", clientConn)
 	backend := newSafeWSConn("backend", backendConn)
 	defer func() {
@@ -489,10 +486,10 @@ func runWebSocketProxySession(ctx context.Context, logger *slog.Logger, sessionI
 
 	errCh := make(chan wsRelayResult, 2)
 	go func() {
-		errCh <- relayWebSocketMessages(backend, clientConn, "client_to_backend")
+		errCh <- relayWebSocketMessages(backend, clientConn, "client_to_backend", func(n int) { record.add(int64(n), 0) })
 	}()
 	go func() {
-		errCh <- relayWebSocketMessages(client, backendConn, "backend_to_client")
+		errCh <- relayWebSocketMessages(client, backendConn, "backend_to_client", func(n int) { record.add(0, int64(n)) })
 	}()
 
 	var first wsRelayResult
@@ -520,7 +517,7 @@ func runWebSocketProxySession(ctx context.Context, logger *slog.Logger, sessionI
 	}
 }
 
-func relayWebSocketMessages(dst *safeWSConn, src *websocket.Conn, direction string) wsRelayResult {
+func relayWebSocketMessages(dst *safeWSConn, src *websocket.Conn, direction string, relayed func(n int)) wsRelayResult {
 	srcName := "backend"
 	if direction == "client_to_backend" {
 		srcName = "client"
@@ -534,6 +531,7 @@ func relayWebSocketMessages(dst *safeWSConn, src *websocket.Conn, direction stri
 		if err := dst.WriteMessage(messageType, message); err != nil {
 			return newWSRelayResult(direction, srcName, dst.name, "write", err)
 		}
+		relayed(len(message))
 	}
 }
 
@@ -78,7 +78,7 @@ func TestRunWebSocketProxySessionPropagatesBackendClose(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		runWebSocketProxySession(context.Background(), logger, "test-backend-close", front.server, back.client)
+		runWebSocketProxySession(context.Background(), logger, "test-backend-close", front.server, back.client, nil)
 	}()
 
 	if err := back.server.WriteControl(
@@ -110,7 +110,7 @@ func TestRunWebSocketProxySessionPropagatesClientClose(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		runWebSocketProxySession(context.Background(), logger, "test-client-close", front.server, back.client)
+		runWebSocketProxySession(context.Background(), logger, "test-client-close", front.server, back.client, nil)
 	}()
 
 	if err := front.client.WriteControl(
@@ -142,7 +142,7 @@ func TestRunWebSocketProxySessionConvertsBackendDropToSyntheticClose(t *testing.
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		runWebSocketProxySession(context.Background(), logger, "test-backend-drop", front.server, back.client)
+		runWebSocketProxySession(context.Background(), logger, "test-backend-drop", front.server, back.client, nil)
 	}()
 
 	if err := back.client.NetConn().Close(); err != nil {
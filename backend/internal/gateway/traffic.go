@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/metrics"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+// TrafficMeter counts the bytes the gateway relays per sandbox and periodically adds
+// them to the sandbox traffic counters in the store.
+type TrafficMeter struct {
+	store   *store.SandboxTrafficStore
+	mu      sync.Mutex
+	pending map[string]*trafficDelta
+}
+
+type trafficDelta struct {
+	in, out int64
+}
+
+// NewTrafficMeter creates a new TrafficMeter.
+func NewTrafficMeter(trafficStore *store.SandboxTrafficStore) *TrafficMeter {
+	return &TrafficMeter{
+		store:   trafficStore,
+		pending: make(map[string]*trafficDelta),
+	}
+}
+
+// Add records bytes sent to (in) and received from (out) a sandbox. It is safe to call
+// on a nil meter.
+func (m *TrafficMeter) Add(sandboxID string, in, out int64) {
+	if m == nil || (in == 0 && out == 0) {
+		return
+	}
+	metrics.GatewayTrafficBytes.WithLabelValues("in").Add(float64(in))
+	metrics.GatewayTrafficBytes.WithLabelValues("out").Add(float64(out))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delta := m.pending[sandboxID]
+	if delta == nil {
+		delta = &trafficDelta{}
+		m.pending[sandboxID] = delta
+	}
+	delta.in += in
+	delta.out += out
+}
+
+// Flush writes the traffic recorded since the last flush to the store. Traffic that
+// fails to be written is kept for the next flush.
+func (m *TrafficMeter) Flush(ctx context.Context) {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[string]*trafficDelta)
+	m.mu.Unlock()
+
+	now := time.Now().UTC()
+	for sandboxID, delta := range pending {
+		if err := m.store.Add(ctx, sandboxID, delta.in, delta.out, now); err != nil {
+			slog.Warn("failed to flush sandbox traffic", "component", "gateway_traffic", "sandbox_id", sandboxID, "error", err)
+			m.mu.Lock()
+			if current := m.pending[sandboxID]; current != nil {
+				current.in += delta.in
+				current.out += delta.out
+			} else {
+				m.pending[sandboxID] = delta
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// Start flushes the meter every interval.
+func (m *TrafficMeter) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			m.Flush(context.Background())
+		}
+	}()
+}
+
+// trafficRecorder records bytes sent to (in) and received from (out) one sandbox.
+type trafficRecorder func(in, out int64)
+
+func (r trafficRecorder) add(in, out int64) {
+	if r != nil {
+		r(in, out)
+	}
+}
+
+// recordTraffic returns the traffic recorder of a sandbox.
+func (s *Service) recordTraffic(sandboxID string) trafficRecorder {
+	return func(in, out int64) {
+		s.traffic.Add(sandboxID, in, out)
+	}
+}
+
+// countingReadCloser counts the bytes read from a request body.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	tunnelDialTimeout = 10 * time.Second
+	tunnelBufferSize  = 32 * 1024
+)
+
+// RequireAccessTokenMiddleware rejects requests without an access token header, so
+// routes behind it can't be opened with a preview token.
+func RequireAccessTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(authorizationHeader) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "access token is required",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// TunnelHandler relays a raw TCP connection to a sandbox port over a WebSocket. Each
+// binary message carries a chunk of the stream in either direction, and the tunnel
+// ends when either the WebSocket or the TCP connection closes.
+func (s *Service) TunnelHandler(c *gin.Context) {
+	logger := logx.LoggerWithRequestID(c.Request.Context()).With("component", "gateway_tunnel")
+
+	if s.drainState != nil && s.drainState.IsDraining() {
+		logger.Warn("tunnel request rejected while draining")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "service is draining",
+		})
+		return
+	}
+	if !isWebSocketUpgrade(c.Request) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "tcp tunnel requires a websocket upgrade",
+		})
+		return
+	}
+
+	sandboxID := c.Param(sandboxIDParam)
+	port := c.GetString("port")
+	logger = logger.With("sandbox_id", sandboxID, "port", port)
+
+	backend, err := s.dialSandboxPort(c.Request.Context(), sandboxID, port)
+	if err != nil {
+		logger.Warn("failed to connect sandbox port", "error", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error": "failed to connect to sandbox port: " + err.Error(),
+		})
+		return
+	}
+	defer backend.Close()
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin:     func(r *http.Request) bool { return true },
+		ReadBufferSize:  tunnelBufferSize,
+		WriteBufferSize: tunnelBufferSize,
+	}
+	clientConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Warn("failed to upgrade client websocket", "error", err)
+		return
+	}
+
+	release := func() {}
+	if s.drainState != nil {
+		release = s.drainState.TrackWebSocket()
+	}
+	defer release()
+	logger.Info("tcp tunnel connected", "client_remote_addr", c.Request.RemoteAddr, "use_k8s_proxy", s.config.UseK8sProxy)
+
+	relayTunnel(clientConn, backend, s.recordTraffic(sandboxID))
+	logger.Info("tcp tunnel disconnected")
+}
+
+// dialSandboxPort opens a TCP connection to a port of a sandbox, either directly to
+// the pod IP or through the apiserver when the gateway runs in k8s proxy mode.
+func (s *Service) dialSandboxPort(ctx context.Context, sandboxID, port string) (io.ReadWriteCloser, error) {
+	if s.config.UseK8sProxy {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, err
+		}
+		return s.k8sClient.DialPort(ctx, sandboxID, p)
+	}
+
+	podIP, err := s.k8sClient.GetPodIP(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	dialer := net.Dialer{Timeout: tunnelDialTimeout}
+	return dialer.DialContext(ctx, "tcp", net.JoinHostPort(podIP, port))
+}
+
+// relayTunnel copies data between a tunnel WebSocket and its TCP connection until
+// either side ends, then closes both. The relayed bytes are passed to record, which
+// may be nil.
+func relayTunnel(ws *websocket.Conn, backend io.ReadWriteCloser, record trafficRecorder) {
+	done := make(chan struct{}, 2)
+
+	go func() {
+		defer func() { done <- struct{}{} }()
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			if _, err := backend.Write(data); err != nil {
+				return
+			}
+			record.add(int64(len(data)), 0)
+		}
+	}()
+
+	go func() {
+		defer func() { done <- struct{}{} }()
+		buf := make([]byte, tunnelBufferSize)
+		for {
+			n, err := backend.Read(buf)
+			if n > 0 {
+				if werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+				record.add(0, int64(n))
+			}
+			if err != nil {
+				_ = ws.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+					time.Now().Add(wsProxyCloseWriteTimeout))
+				return
+			}
+		}
+	}()
+
+	<-done
+	_ = ws.Close()
+	_ = backend.Close()
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTunnelRelaysTCPStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sandboxStore := initGatewayTestDB(t)
+	seedSandbox(t, sandboxStore, "abc12345", "secret-token", "running")
+
+	// The backend greets, then echoes every line back
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = conn.Write([]byte("hello\n"))
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	port := strconv.Itoa(backend.Addr().(*net.TCPAddr).Port)
+
+	k8sClient := k8s.NewClientForTest(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sandbox-abc12345", Namespace: k8s.DefaultSandboxNamespace},
+		Status:     corev1.PodStatus{PodIP: "127.0.0.1"},
+	})
+	svc := NewService(k8sClient, sandboxStore, store.NewSandboxPreviewLinkStore(), &Config{}, nil)
+	trafficStore := store.NewSandboxTrafficStore()
+	meter := NewTrafficMeter(trafficStore)
+	svc.SetTrafficMeter(meter)
+	r := gin.New()
+	svc.RegisterRoutes(r)
+	gateway := httptest.NewServer(r)
+	defer gateway.Close()
+
+	tunnelURL := "ws" + strings.TrimPrefix(gateway.URL, "http") + "/api/v1/sandbox/abc12345/tcp/" + port
+
+	// Preview tokens and bad access tokens don't open tunnels
+	for _, header := range []http.Header{nil, {authorizationHeader: []string{"wrong"}}} {
+		if _, resp, err := websocket.DefaultDialer.Dial(tunnelURL, header); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("dial with header %v: resp %v, err %v, want 401", header, resp, err)
+		}
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(tunnelURL, http.Header{authorizationHeader: []string{"secret-token"}})
+	if err != nil {
+		t.Fatalf("dial tunnel: %v", err)
+	}
+	defer conn.Close()
+
+	var received strings.Builder
+	read := func(want string) {
+		t.Helper()
+		for received.Len() < len(want) {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("read tunnel: %v", err)
+			}
+			if messageType != websocket.BinaryMessage {
+				t.Fatalf("message type = %d, want binary", messageType)
+			}
+			received.Write(data)
+		}
+		if received.String() != want {
+			t.Fatalf("received %q, want %q", received.String(), want)
+		}
+		received.Reset()
+	}
+
+	read("hello\n")
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("ping\n")); err != nil {
+		t.Fatalf("write tunnel: %v", err)
+	}
+	read("ping\n")
+	conn.Close()
+
+	// The relayed bytes are counted for the sandbox
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		meter.Flush(context.Background())
+		rec, err := trafficStore.Get(context.Background(), "abc12345")
+		if err != nil {
+			t.Fatalf("Get traffic: %v", err)
+		}
+		if rec != nil && rec.BytesIn == 5 && rec.BytesOut == 11 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("traffic = %+v, want 5 bytes in and 11 out", rec)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
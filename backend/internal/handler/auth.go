@@ -17,14 +17,16 @@ import (
 // AuthHandler handles authentication-related HTTP requests.
 type AuthHandler struct {
 	authStore     *store.AuthStore
+	projectStore  *store.ProjectStore
 	sessionMaxAge time.Duration
 	cookieSecure  *bool // nil = auto-detect from request
 }
 
 // NewAuthHandler creates a new AuthHandler.
-func NewAuthHandler(authStore *store.AuthStore, sessionMaxAge time.Duration, cookieSecure *bool) *AuthHandler {
+func NewAuthHandler(authStore *store.AuthStore, projectStore *store.ProjectStore, sessionMaxAge time.Duration, cookieSecure *bool) *AuthHandler {
 	return &AuthHandler{
 		authStore:     authStore,
+		projectStore:  projectStore,
 		sessionMaxAge: sessionMaxAge,
 		cookieSecure:  cookieSecure,
 	}
@@ -63,7 +65,7 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// Login authenticates admin user and returns a session cookie.
+// Login authenticates a user and returns a session cookie.
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req loginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -71,22 +73,27 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	admin, err := h.authStore.GetAdminByUsername(c.Request.Context(), req.Username)
+	user, err := h.authStore.GetUserByUsername(c.Request.Context(), req.Username)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 		return
 	}
-	if admin == nil {
+	if user == nil {
 		h.logger(c).Warnf("login failed: invalid credentials, username=%s", req.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		h.logger(c).Warnf("login failed: invalid credentials, username=%s", req.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	if user.Disabled {
+		h.logger(c).Warnf("login failed: user is disabled, username=%s", req.Username)
+		c.JSON(http.StatusForbidden, gin.H{"error": "user is disabled"})
+		return
+	}
 
 	// Generate session token
 	token, err := security.GenerateToken(32)
@@ -99,7 +106,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	session := &store.SessionRecord{
 		ID:        tokenHash,
-		UserID:    admin.ID,
+		UserID:    user.ID,
 		ExpiresAt: expiresAt,
 	}
 	if err := h.authStore.CreateSession(c.Request.Context(), session); err != nil {
@@ -111,11 +118,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.SetSameSite(http.SameSiteLaxMode)
 	c.SetCookie(auth.SessionCookieName, token, int(h.sessionMaxAge.Seconds()), "/", "", secure, true)
 
-	h.logger(c).Infof("admin login successful, username=%s", admin.Username)
+	h.logger(c).Infof("login successful, username=%s, role=%s", user.Username, user.Role)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "login successful",
-		"username": admin.Username,
+		"username": user.Username,
+		"role":     user.Role,
 	})
 }
 
@@ -149,27 +157,27 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get(auth.ContextKeyUserID)
-	if !exists {
+	userID := auth.UserIDFromContext(c)
+	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	admin, err := h.authStore.GetAdminByID(c.Request.Context(), userID.(string))
+	user, err := h.authStore.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		h.logger(c).Errorf("failed to get admin by id: %v", err)
+		h.logger(c).Errorf("failed to get user by id: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 		return
 	}
-	if admin == nil {
-		h.logger(c).Warnf("admin user not found for id: %s", userID)
+	if user == nil {
+		h.logger(c).Warnf("user not found for id: %s", userID)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
 
 	// Verify old password
-	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.OldPassword)); err != nil {
-		h.logger(c).Warnf("password change failed: invalid old password for user %s", admin.Username)
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)); err != nil {
+		h.logger(c).Warnf("password change failed: invalid old password for user %s", user.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid old password"})
 		return
 	}
@@ -177,19 +185,19 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	// Hash new password
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		h.logger(c).Errorf("failed to hash new password for user %s: %v", admin.Username, err)
+		h.logger(c).Errorf("failed to hash new password for user %s: %v", user.Username, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
 		return
 	}
 
 	// Update password
-	if err := h.authStore.UpdateAdminPassword(c.Request.Context(), admin.ID, string(hash)); err != nil {
-		h.logger(c).Errorf("failed to update password in store for user %s: %v", admin.Username, err)
+	if err := h.authStore.UpdateUserPassword(c.Request.Context(), user.ID, string(hash)); err != nil {
+		h.logger(c).Errorf("failed to update password in store for user %s: %v", user.Username, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
 		return
 	}
 
-	h.logger(c).Infof("password updated for user %s", admin.Username)
+	h.logger(c).Infof("password updated for user %s", user.Username)
 	c.JSON(http.StatusOK, gin.H{"message": "password updated successfully"})
 }
 
@@ -198,10 +206,26 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	method, _ := c.Get(auth.ContextKeyAuthMethod)
 	resp := gin.H{"auth_method": method}
 
-	if userID, exists := c.Get(auth.ContextKeyUserID); exists {
-		admin, err := h.authStore.GetAdminByID(c.Request.Context(), userID.(string))
-		if err == nil && admin != nil {
-			resp["username"] = admin.Username
+	if userID := auth.UserIDFromContext(c); userID != "" {
+		resp["user_id"] = userID
+		resp["role"] = auth.RoleFromContext(c)
+		user, err := h.authStore.GetUserByID(c.Request.Context(), userID)
+		if err == nil && user != nil {
+			resp["username"] = user.Username
+		}
+	}
+	if p := auth.PrincipalFromContext(c.Request.Context()); p != nil {
+		if p.Project != "" {
+			resp["project"] = p.Project
+		}
+		if len(p.Scopes) > 0 {
+			resp["scopes"] = p.Scopes
+		}
+		if len(p.Templates) > 0 {
+			resp["templates"] = p.Templates
+		}
+		if len(p.AllowedCIDRs) > 0 {
+			resp["allowed_cidrs"] = p.AllowedCIDRs
 		}
 	}
 
@@ -211,33 +235,73 @@ func (h *AuthHandler) Me(c *gin.Context) {
 type createAPIKeyRequest struct {
 	Name          string `json:"name" binding:"required,max=128"`
 	ExpiresInDays *int   `json:"expires_in_days,omitempty"`
+	// Project scopes the key to the sandboxes and templates of a project
+	Project string `json:"project,omitempty"`
+	// Scopes limit the operations of the key, Templates the templates it can use and
+	// AllowedCIDRs the addresses it can be used from
+	Scopes       []string `json:"scopes,omitempty"`
+	Templates    []string `json:"templates,omitempty"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"`
 }
 
 type apiKeyResponse struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	Key       string     `json:"key,omitempty"`
-	Prefix    string     `json:"prefix"`
-	ExpiresAt *time.Time `json:"expires_at"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Key          string     `json:"key,omitempty"`
+	Prefix       string     `json:"prefix"`
+	Project      string     `json:"project,omitempty"`
+	Scopes       []string   `json:"scopes,omitempty"`
+	Templates    []string   `json:"templates,omitempty"`
+	AllowedCIDRs []string   `json:"allowed_cidrs,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
 type apiKeyListItem struct {
-	ID         string     `json:"id"`
-	Name       string     `json:"name"`
-	Prefix     string     `json:"prefix"`
-	ExpiresAt  *time.Time `json:"expires_at"`
-	LastUsedAt *time.Time `json:"last_used_at"`
-	CreatedAt  time.Time  `json:"created_at"`
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	Project      string     `json:"project,omitempty"`
+	Scopes       []string   `json:"scopes,omitempty"`
+	Templates    []string   `json:"templates,omitempty"`
+	AllowedCIDRs []string   `json:"allowed_cidrs,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
-// CreateAPIKey creates a new API key.
+// CreateAPIKey creates a new API key that acts as the current user.
 func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
 	var req createAPIKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required and must be at most 128 characters"})
 		return
 	}
+	if req.Project != "" {
+		project, err := h.projectStore.GetByName(c.Request.Context(), req.Project)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to look up project"})
+			return
+		}
+		if project == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "project not found"})
+			return
+		}
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope: " + scope})
+			return
+		}
+	}
+	for i, cidr := range req.AllowedCIDRs {
+		normalized, err := auth.NormalizeCIDR(cidr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.AllowedCIDRs[i] = normalized
+	}
 
 	rawKey, err := security.GenerateToken(32)
 	if err != nil {
@@ -255,32 +319,42 @@ func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
 	}
 
 	rec := &store.APIKeyRecord{
-		ID:        uuid.NewString(),
-		Name:      req.Name,
-		Prefix:    prefix,
-		KeyHash:   keyHash,
-		ExpiresAt: expiresAt,
+		ID:           uuid.NewString(),
+		UserID:       auth.UserIDFromContext(c),
+		Name:         req.Name,
+		Prefix:       prefix,
+		KeyHash:      keyHash,
+		ExpiresAt:    expiresAt,
+		Project:      req.Project,
+		Scopes:       req.Scopes,
+		Templates:    req.Templates,
+		AllowedCIDRs: req.AllowedCIDRs,
 	}
 	if err := h.authStore.CreateAPIKey(c.Request.Context(), rec); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API key"})
 		return
 	}
 
-	h.logger(c).Infof("API key created, id=%s, name=%s, prefix=%s", rec.ID, rec.Name, prefix)
+	h.logger(c).Infof("API key created, id=%s, name=%s, prefix=%s, project=%s, scopes=%v, templates=%v, allowed_cidrs=%v",
+		rec.ID, rec.Name, prefix, rec.Project, rec.Scopes, rec.Templates, rec.AllowedCIDRs)
 
 	c.JSON(http.StatusCreated, apiKeyResponse{
-		ID:        rec.ID,
-		Name:      rec.Name,
-		Key:       fullKey,
-		Prefix:    prefix,
-		ExpiresAt: expiresAt,
-		CreatedAt: rec.CreatedAt,
+		ID:           rec.ID,
+		Name:         rec.Name,
+		Key:          fullKey,
+		Prefix:       prefix,
+		Project:      rec.Project,
+		Scopes:       rec.Scopes,
+		Templates:    rec.Templates,
+		AllowedCIDRs: rec.AllowedCIDRs,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    rec.CreatedAt,
 	})
 }
 
-// ListAPIKeys returns all API keys (without secrets).
+// ListAPIKeys returns the API keys of the current user (without secrets).
 func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
-	keys, err := h.authStore.ListAPIKeys(c.Request.Context())
+	keys, err := h.authStore.ListAPIKeys(c.Request.Context(), auth.UserIDFromContext(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list API keys"})
 		return
@@ -289,19 +363,23 @@ func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
 	items := make([]apiKeyListItem, 0, len(keys))
 	for _, k := range keys {
 		items = append(items, apiKeyListItem{
-			ID:         k.ID,
-			Name:       k.Name,
-			Prefix:     k.Prefix,
-			ExpiresAt:  k.ExpiresAt,
-			LastUsedAt: k.LastUsedAt,
-			CreatedAt:  k.CreatedAt,
+			ID:           k.ID,
+			Name:         k.Name,
+			Prefix:       k.Prefix,
+			Project:      k.Project,
+			Scopes:       k.Scopes,
+			Templates:    k.Templates,
+			AllowedCIDRs: k.AllowedCIDRs,
+			ExpiresAt:    k.ExpiresAt,
+			LastUsedAt:   k.LastUsedAt,
+			CreatedAt:    k.CreatedAt,
 		})
 	}
 
 	c.JSON(http.StatusOK, items)
 }
 
-// DeleteAPIKey revokes an API key.
+// DeleteAPIKey revokes an API key of the current user.
 func (h *AuthHandler) DeleteAPIKey(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -309,7 +387,7 @@ func (h *AuthHandler) DeleteAPIKey(c *gin.Context) {
 		return
 	}
 
-	if err := h.authStore.DeleteAPIKey(c.Request.Context(), id); err != nil {
+	if err := h.authStore.DeleteAPIKey(c.Request.Context(), id, auth.UserIDFromContext(c)); err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
 			return
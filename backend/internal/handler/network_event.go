@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// NetworkEventHandler handles sandbox egress audit HTTP requests
+type NetworkEventHandler struct {
+	svc *service.SandboxNetworkAuditService
+}
+
+// NewNetworkEventHandler creates a new NetworkEventHandler
+func NewNetworkEventHandler(svc *service.SandboxNetworkAuditService) *NetworkEventHandler {
+	return &NetworkEventHandler{svc: svc}
+}
+
+// RegisterRoutes registers network event routes
+func (h *NetworkEventHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/sandboxes/:id/network/events", h.List)
+}
+
+// List returns the egress events of a sandbox, newest first. Supports ?kind=dns|connection,
+// ?since=<RFC3339> and ?limit=.
+func (h *NetworkEventHandler) List(c *gin.Context) {
+	query := model.NetworkEventQuery{Kind: model.NetworkEventKind(c.Query("kind"))}
+	if v := c.Query("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+			return
+		}
+		query.Since = &since
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+		query.Limit = limit
+	}
+
+	resp, err := h.svc.ListEvents(c.Request.Context(), c.Param("id"), &query)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSandboxNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidNetworkEventQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PoolHandler reports the warm pools of templates
+type PoolHandler struct {
+	svc *service.SandboxPoolService
+}
+
+// NewPoolHandler creates a new PoolHandler
+func NewPoolHandler(svc *service.SandboxPoolService) *PoolHandler {
+	return &PoolHandler{svc: svc}
+}
+
+// RegisterRoutes registers warm pool routes
+func (h *PoolHandler) RegisterRoutes(r *gin.RouterGroup) {
+	pools := r.Group("/pools")
+	{
+		pools.GET("", h.List)
+		pools.GET("/:template", h.Get)
+	}
+}
+
+func (h *PoolHandler) List(c *gin.Context) {
+	resp, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *PoolHandler) Get(c *gin.Context) {
+	pool, err := h.svc.Get(c.Request.Context(), c.Param("template"))
+	if err != nil {
+		if errors.Is(err, service.ErrPoolNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pool)
+}
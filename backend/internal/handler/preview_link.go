@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewLinkHandler handles sandbox preview link HTTP requests
+type PreviewLinkHandler struct {
+	svc *service.SandboxPreviewService
+}
+
+// NewPreviewLinkHandler creates a new PreviewLinkHandler
+func NewPreviewLinkHandler(svc *service.SandboxPreviewService) *PreviewLinkHandler {
+	return &PreviewLinkHandler{svc: svc}
+}
+
+// RegisterRoutes registers preview link routes
+func (h *PreviewLinkHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/sandboxes/:id/preview-links", h.Create)
+	r.GET("/sandboxes/:id/preview-links", h.List)
+	r.DELETE("/sandboxes/:id/preview-links/:linkId", h.Revoke)
+}
+
+func (h *PreviewLinkHandler) Create(c *gin.Context) {
+	var req model.CreatePreviewLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.svc.Create(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		writePreviewLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *PreviewLinkHandler) List(c *gin.Context) {
+	resp, err := h.svc.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writePreviewLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *PreviewLinkHandler) Revoke(c *gin.Context) {
+	if err := h.svc.Revoke(c.Request.Context(), c.Param("id"), c.Param("linkId")); err != nil {
+		writePreviewLinkError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writePreviewLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSandboxNotFound), errors.Is(err, service.ErrPreviewLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPreviewLink):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ProcessHandler handles background process HTTP requests
+type ProcessHandler struct {
+	svc *service.SandboxProcessService
+}
+
+// NewProcessHandler creates a new ProcessHandler
+func NewProcessHandler(svc *service.SandboxProcessService) *ProcessHandler {
+	return &ProcessHandler{svc: svc}
+}
+
+// RegisterRoutes registers background process routes
+func (h *ProcessHandler) RegisterRoutes(r *gin.RouterGroup) {
+	processes := r.Group("/sandboxes/:id/processes")
+	{
+		processes.POST("", h.Start)
+		processes.GET("", h.List)
+		processes.GET("/:processId", h.Get)
+		processes.GET("/:processId/output", h.Output)
+		processes.POST("/:processId/signal", h.Signal)
+		processes.POST("/:processId/kill", h.Kill)
+	}
+}
+
+func (h *ProcessHandler) Start(c *gin.Context) {
+	var req model.StartProcessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	proc, err := h.svc.Start(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		writeProcessError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, proc)
+}
+
+func (h *ProcessHandler) List(c *gin.Context) {
+	resp, err := h.svc.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeProcessError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ProcessHandler) Get(c *gin.Context) {
+	proc, err := h.svc.Get(c.Request.Context(), c.Param("id"), c.Param("processId"))
+	if err != nil {
+		writeProcessError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, proc)
+}
+
+// Output returns captured output as JSON, or with follow=true streams it as NDJSON
+// events (stdout/stderr, then exit) until the process finishes.
+func (h *ProcessHandler) Output(c *gin.Context) {
+	id := c.Param("id")
+	processID := c.Param("processId")
+
+	if c.Query("follow") != "true" {
+		resp, err := h.svc.Output(c.Request.Context(), id, processID)
+		if err != nil {
+			writeProcessError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	emit := newNDJSONEmitter(c)
+	if err := h.svc.FollowOutput(c.Request.Context(), id, processID, emit); err != nil {
+		if !c.Writer.Written() {
+			writeProcessError(c, err)
+			return
+		}
+		_ = emit(model.ExecStreamEvent{Type: model.ExecStreamEventError, Message: err.Error()})
+	}
+}
+
+func (h *ProcessHandler) Signal(c *gin.Context) {
+	var req model.SignalProcessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.Signal(c.Request.Context(), c.Param("id"), c.Param("processId"), req.Signal); err != nil {
+		writeProcessError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "signal sent"})
+}
+
+func (h *ProcessHandler) Kill(c *gin.Context) {
+	if err := h.svc.Kill(c.Request.Context(), c.Param("id"), c.Param("processId")); err != nil {
+		writeProcessError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "process killed"})
+}
+
+func writeProcessError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSandboxNotFound), errors.Is(err, service.ErrProcessNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidProcessSignal), errors.Is(err, service.ErrInvalidEnvName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSandboxNotRunning), errors.Is(err, service.ErrProcessNotRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ProjectHandler handles project HTTP requests. Creating and deleting projects
+// requires the admin role.
+type ProjectHandler struct {
+	svc *service.ProjectService
+}
+
+// NewProjectHandler creates a new ProjectHandler
+func NewProjectHandler(svc *service.ProjectService) *ProjectHandler {
+	return &ProjectHandler{svc: svc}
+}
+
+// RegisterRoutes registers project routes
+func (h *ProjectHandler) RegisterRoutes(r *gin.RouterGroup) {
+	projects := r.Group("/projects")
+	{
+		projects.POST("", auth.RequireRole(model.RoleAdmin), h.Create)
+		projects.GET("", h.List)
+		projects.GET("/:name", h.Get)
+		projects.DELETE("/:name", auth.RequireRole(model.RoleAdmin), h.Delete)
+	}
+}
+
+func (h *ProjectHandler) Create(c *gin.Context) {
+	var req model.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.svc.Create(c.Request.Context(), &req)
+	if err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) List(c *gin.Context) {
+	resp, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ProjectHandler) Get(c *gin.Context) {
+	project, err := h.svc.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		writeProjectError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeProjectError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidProject):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProjectScope):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProjectExists), errors.Is(err, service.ErrProjectNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
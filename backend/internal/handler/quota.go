@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaHandler handles quota HTTP requests. Anyone can see the quotas that apply to
+// them; managing quotas requires the admin role.
+type QuotaHandler struct {
+	svc *service.QuotaService
+}
+
+// NewQuotaHandler creates a new QuotaHandler
+func NewQuotaHandler(svc *service.QuotaService) *QuotaHandler {
+	return &QuotaHandler{svc: svc}
+}
+
+// RegisterRoutes registers quota routes
+func (h *QuotaHandler) RegisterRoutes(r *gin.RouterGroup) {
+	quotas := r.Group("/quotas")
+	{
+		quotas.GET("", h.Status)
+	}
+	limits := quotas.Group("/limits")
+	limits.Use(auth.RequireRole(model.RoleAdmin))
+	{
+		limits.GET("", h.List)
+		limits.GET("/:scope/:subject", h.Get)
+		limits.PUT("/:scope/:subject", h.Set)
+		limits.DELETE("/:scope/:subject", h.Delete)
+	}
+}
+
+func (h *QuotaHandler) Status(c *gin.Context) {
+	resp, err := h.svc.Status(c.Request.Context())
+	if err != nil {
+		writeQuotaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *QuotaHandler) List(c *gin.Context) {
+	resp, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeQuotaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *QuotaHandler) Get(c *gin.Context) {
+	status, err := h.svc.Get(c.Request.Context(), model.QuotaScope(c.Param("scope")), c.Param("subject"))
+	if err != nil {
+		writeQuotaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *QuotaHandler) Set(c *gin.Context) {
+	var req model.QuotaLimits
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	quota, err := h.svc.Set(c.Request.Context(), model.QuotaScope(c.Param("scope")), c.Param("subject"), &req)
+	if err != nil {
+		writeQuotaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, quota)
+}
+
+func (h *QuotaHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), model.QuotaScope(c.Param("scope")), c.Param("subject")); err != nil {
+		writeQuotaError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeQuotaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidQuota):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrQuotaNotFound), errors.Is(err, service.ErrQuotaSubjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// writeQuotaExceeded writes the response to a request that would exceed a quota, and
+// reports whether err was such an error. Running too many sandboxes is answered with
+// 429, exceeding any other limit with 403.
+func writeQuotaExceeded(c *gin.Context, err error) bool {
+	var exceeded *service.QuotaExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+	status := http.StatusForbidden
+	if exceeded.Violation.Resource == model.QuotaResourceSandboxes {
+		status = http.StatusTooManyRequests
+	}
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+		"code":  "QUOTA_EXCEEDED",
+		"quota": exceeded.Violation,
+	})
+	return true
+}
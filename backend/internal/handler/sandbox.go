@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	pathpkg "path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fslongjin/liteboxd/backend/internal/lifecycle"
@@ -40,11 +44,23 @@ func (h *SandboxHandler) RegisterRoutes(r *gin.RouterGroup) {
 		sandboxes.POST("/:id/restart", h.Restart)
 		sandboxes.POST("/:id/stop", h.Stop)
 		sandboxes.POST("/:id/start", h.Start)
+		sandboxes.POST("/:id/pause", h.Pause)
+		sandboxes.POST("/:id/resume", h.Resume)
+		sandboxes.POST("/:id/ttl", h.ExtendTTL)
+		sandboxes.POST("/:id/token/rotate", h.RotateToken)
+		sandboxes.PUT("/:id/network", h.UpdateNetwork)
 		sandboxes.POST("/:id/exec", h.Exec)
+		sandboxes.POST("/:id/exec/stream", h.ExecStream)
 		sandboxes.GET("/:id/exec/interactive", h.ExecInteractive)
 		sandboxes.GET("/:id/logs", h.GetLogs)
 		sandboxes.POST("/:id/files", h.UploadFile)
 		sandboxes.GET("/:id/files", h.DownloadFile)
+		sandboxes.GET("/:id/fs", h.ListDir)
+		sandboxes.GET("/:id/fs/stat", h.StatPath)
+		sandboxes.GET("/:id/fs/watch", h.WatchPath)
+		sandboxes.POST("/:id/fs/mkdir", h.MakeDir)
+		sandboxes.POST("/:id/fs/move", h.MovePath)
+		sandboxes.DELETE("/:id/fs", h.RemovePath)
 	}
 }
 
@@ -57,7 +73,24 @@ func (h *SandboxHandler) Create(c *gin.Context) {
 
 	sandbox, err := h.svc.Create(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if writeQuotaExceeded(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrSnapshotNotFound), errors.Is(err, service.ErrSnapshotNotReady),
+			errors.Is(err, service.ErrRestoreNeedsPersistence):
+			writeSnapshotError(c, err)
+		case errors.Is(err, service.ErrInvalidNetwork), errors.Is(err, service.ErrInvalidNetworkGroup),
+			errors.Is(err, service.ErrInvalidEnvName):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNetworkWideningNotAllowed), errors.Is(err, service.ErrTemplateNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrProjectNotFound), errors.Is(err, service.ErrInvalidProject),
+			errors.Is(err, service.ErrProjectScope):
+			writeProjectError(c, err)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -130,6 +163,8 @@ func (h *SandboxHandler) ListMetadata(c *gin.Context) {
 		DesiredState:    c.Query("desired_state"),
 		LifecycleStatus: c.Query("lifecycle_status"),
 		DeletionPhase:   c.Query("deletion_phase"),
+		Owner:           c.Query("owner"),
+		Project:         c.Query("project"),
 		CreatedFrom:     createdFrom,
 		CreatedTo:       createdTo,
 		DeletedFrom:     deletedFrom,
@@ -220,6 +255,9 @@ func (h *SandboxHandler) Stop(c *gin.Context) {
 func (h *SandboxHandler) Start(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.svc.Start(c.Request.Context(), id); err != nil {
+		if writeQuotaExceeded(c, err) {
+			return
+		}
 		switch {
 		case errors.Is(err, service.ErrSandboxNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -235,6 +273,117 @@ func (h *SandboxHandler) Start(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "start requested"})
 }
 
+func (h *SandboxHandler) Pause(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.Pause(c.Request.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, service.ErrSandboxNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSandboxPauseNotSupported):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSandboxPauseInvalidState),
+			errors.Is(err, service.ErrSandboxPauseInProgress),
+			errors.Is(err, service.ErrSandboxAlreadyPaused):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "pause requested"})
+}
+
+func (h *SandboxHandler) Resume(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.Resume(c.Request.Context(), id); err != nil {
+		if writeQuotaExceeded(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrSandboxNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSandboxNotPaused),
+			errors.Is(err, service.ErrSandboxPauseInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "resume requested"})
+}
+
+func (h *SandboxHandler) ExtendTTL(c *gin.Context) {
+	var req model.ExtendTTLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sandbox, err := h.svc.ExtendTTL(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		if writeQuotaExceeded(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrSandboxNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidTTL), errors.Is(err, service.ErrSandboxTTLDisabled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, sandbox)
+}
+
+func (h *SandboxHandler) RotateToken(c *gin.Context) {
+	var req model.RotateAccessTokenRequest
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sandbox, err := h.svc.RotateAccessToken(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSandboxNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidGracePeriod):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, sandbox)
+}
+
+func (h *SandboxHandler) UpdateNetwork(c *gin.Context) {
+	var req model.UpdateSandboxNetworkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sandbox, err := h.svc.UpdateNetwork(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSandboxNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidNetwork):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNetworkWideningNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNetworkChangeNotSupported), errors.Is(err, service.ErrNetworkLimitsImmutable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, sandbox)
+}
+
 func (h *SandboxHandler) GetStatusHistory(c *gin.Context) {
 	id := c.Param("id")
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
@@ -305,37 +454,126 @@ func (h *SandboxHandler) Exec(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
-func (h *SandboxHandler) UploadFile(c *gin.Context) {
-	id := c.Param("id")
-	path := c.PostForm("path")
+// ExecStream runs a non-TTY command and streams its output as NDJSON events.
+// The command is passed via repeated `command` query parameters; the request body,
+// if any, is forwarded to the process stdin.
+func (h *SandboxHandler) ExecStream(c *gin.Context) {
+	if h.drainState != nil && h.drainState.IsDraining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service is draining"})
+		return
+	}
 
-	if path == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+	id := c.Param("id")
+	command := c.QueryArray("command")
+	if len(command) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "command is required"})
 		return
 	}
+	timeout, _ := strconv.Atoi(c.Query("timeout"))
+
+	var stdin io.Reader
+	if c.Request.ContentLength != 0 {
+		stdin = c.Request.Body
+	}
 
-	file, _, err := c.Request.FormFile("file")
+	emit := newNDJSONEmitter(c)
+	err := h.svc.ExecStream(c.Request.Context(), id, command, timeout, stdin, emit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		_ = emit(model.ExecStreamEvent{Type: model.ExecStreamEventError, Message: err.Error()})
+	}
+}
+
+// newNDJSONEmitter prepares c for a long-lived NDJSON response and returns a function
+// writing one event per line. Headers are sent lazily with the first event so callers
+// can still reply with a regular JSON error if nothing was streamed yet.
+func newNDJSONEmitter(c *gin.Context) func(model.ExecStreamEvent) error {
+	// Output may be written while the request body is still uploading, and long-running
+	// streams must not be cut off by the server-wide write timeout.
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.EnableFullDuplex()
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	return func(event model.ExecStreamEvent) error {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+		}
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+}
+
+// multipartOverhead is allowed on top of the transfer size limit for multipart
+// boundaries and form fields.
+const multipartOverhead = 1 << 20
+
+// UploadFile writes a single file to "path". The content is either the raw request
+// body, with path given as a query parameter, or the multipart "file" field, with path
+// also accepted as a form field. Raw bodies are streamed to the sandbox as they arrive.
+// With extract=true the upload is treated as a tar, tar.gz or zip archive and extracted
+// into the "path" directory instead; see uploadArchive.
+func (h *SandboxHandler) UploadFile(c *gin.Context) {
+	id := c.Param("id")
+	// Large uploads take longer than the server-wide read timeout allows.
+	_ = http.NewResponseController(c.Writer).SetReadDeadline(time.Time{})
+
+	isMultipart := strings.HasPrefix(c.ContentType(), "multipart/")
+	if limit := h.svc.MaxFileTransferSize(); isMultipart && limit > 0 {
+		// Multipart forms are spooled to disk while parsing, so bound them up front.
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+multipartOverhead)
+	}
+
+	if c.Query("extract") == "true" || (isMultipart && c.PostForm("extract") == "true") {
+		h.uploadArchive(c, id, isMultipart)
 		return
 	}
-	defer file.Close()
 
-	content, err := io.ReadAll(file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
+	path := c.Query("path")
+	var content io.Reader = c.Request.Body
+	size := c.Request.ContentLength
+	if isMultipart {
+		if v := c.PostForm("path"); v != "" {
+			path = v
+		}
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			writeTransferError(c, err, "file is required")
+			return
+		}
+		defer file.Close()
+		content = file
+		size = header.Size
+	}
+
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
 		return
 	}
 
-	err = h.svc.UploadFile(c.Request.Context(), id, path, content)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.svc.UploadFile(c.Request.Context(), id, path, content, size); err != nil {
+		writeTransferError(c, err, "")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "file uploaded successfully"})
 }
 
+// DownloadFile streams a file with its Content-Length. A single byte range may be
+// requested with the Range header. With archive=true the path is downloaded as a
+// tar.gz archive instead; see downloadArchive.
 func (h *SandboxHandler) DownloadFile(c *gin.Context) {
 	id := c.Param("id")
 	path := c.Query("path")
@@ -345,13 +583,174 @@ func (h *SandboxHandler) DownloadFile(c *gin.Context) {
 		return
 	}
 
-	content, err := h.svc.DownloadFile(c.Request.Context(), id, path)
+	if c.Query("archive") == "true" {
+		h.downloadArchive(c, id, path)
+		return
+	}
+
+	info, err := h.svc.StatDownload(c.Request.Context(), id, path)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeTransferError(c, err, "")
 		return
 	}
 
-	c.Data(http.StatusOK, "application/octet-stream", content)
+	status := http.StatusOK
+	offset, length := int64(0), info.Size
+	if header := c.GetHeader("Range"); header != "" {
+		start, n, partial, ok := parseByteRange(header, info.Size)
+		if !ok {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+			c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"error": "requested range not satisfiable"})
+			return
+		}
+		if partial {
+			status = http.StatusPartialContent
+			offset, length = start, n
+			c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+n-1, info.Size))
+		}
+	}
+
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Length", strconv.FormatInt(length, 10))
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Last-Modified", info.ModifiedAt.UTC().Format(http.TimeFormat))
+	c.Status(status)
+
+	if err := h.svc.DownloadFile(c.Request.Context(), id, path, offset, length, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			for _, key := range []string{"Content-Length", "Content-Range", "Accept-Ranges", "Last-Modified"} {
+				c.Writer.Header().Del(key)
+			}
+			writeTransferError(c, err, "")
+			return
+		}
+		// The body is already partially sent; the short body tells the client the
+		// transfer failed.
+		_ = c.Error(err)
+	}
+}
+
+// parseByteRange parses a Range header against a file of the given size and returns
+// the offset and length to serve. partial is false when the header should be ignored
+// and the whole file served, as for multiple ranges or unknown units; ok is false
+// when the range cannot be satisfied.
+func parseByteRange(header string, size int64) (offset, length int64, partial, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, size, false, true
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, false
+	}
+
+	if first == "" {
+		// Suffix range: the last n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, false
+	}
+	end := size - 1
+	if last != "" {
+		e, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || e < start {
+			return 0, 0, false, false
+		}
+		if e < end {
+			end = e
+		}
+	}
+	return start, end - start + 1, true, true
+}
+
+// uploadArchive extracts an archive into a directory. The archive is either the raw
+// request body, with path and format given as query parameters, or the multipart
+// "file" field, with path and format also accepted as form fields. The format
+// (tar, tar.gz or zip) is detected from the content when omitted.
+func (h *SandboxHandler) uploadArchive(c *gin.Context, id string, isMultipart bool) {
+	path := c.Query("path")
+	format := c.Query("format")
+	var archive io.Reader = c.Request.Body
+	if isMultipart {
+		if v := c.PostForm("path"); v != "" {
+			path = v
+		}
+		if v := c.PostForm("format"); v != "" {
+			format = v
+		}
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			writeTransferError(c, err, "file is required")
+			return
+		}
+		defer file.Close()
+		archive = file
+	}
+
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+
+	if err := h.svc.UploadArchive(c.Request.Context(), id, path, format, archive); err != nil {
+		writeTransferError(c, err, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "archive extracted successfully"})
+}
+
+// downloadArchive streams the contents of a directory as a tar.gz archive.
+func (h *SandboxHandler) downloadArchive(c *gin.Context, id, path string) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	name := pathpkg.Base(pathpkg.Clean(path))
+	if name == "/" || name == "." {
+		name = "root"
+	}
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar.gz"))
+
+	if err := h.svc.DownloadArchive(c.Request.Context(), id, path, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			writeTransferError(c, err, "")
+			return
+		}
+		// The archive is already partially sent. Stopping here leaves the gzip stream
+		// unterminated, which clients detect as a truncated archive.
+		_ = c.Error(err)
+	}
+}
+
+// writeTransferError maps file transfer errors to HTTP responses. A non-empty
+// badRequest message replaces unrecognized errors with a 400 response, for failures
+// reading the request itself.
+func writeTransferError(c *gin.Context, err error, badRequest string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, service.ErrFileTooLarge), errors.As(err, &maxBytesErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrFileTooLarge.Error()})
+	case errors.Is(err, service.ErrSandboxNotFound), errors.Is(err, service.ErrPathNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidArchive), errors.Is(err, service.ErrInvalidPath), errors.Is(err, service.ErrPathIsDirectory), errors.Is(err, service.ErrNotDirectory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case badRequest != "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": badRequest})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
 func (h *SandboxHandler) GetLogs(c *gin.Context) {
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SandboxHandler) ListDir(c *gin.Context) {
+	resp, err := h.svc.ListDir(c.Request.Context(), c.Param("id"), c.DefaultQuery("path", "/"))
+	if err != nil {
+		writeFSError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *SandboxHandler) StatPath(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+
+	info, err := h.svc.StatPath(c.Request.Context(), c.Param("id"), path)
+	if err != nil {
+		writeFSError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func (h *SandboxHandler) MakeDir(c *gin.Context) {
+	var req model.MkdirRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.MakeDir(c.Request.Context(), c.Param("id"), req.Path); err != nil {
+		writeFSError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "directory created"})
+}
+
+func (h *SandboxHandler) MovePath(c *gin.Context) {
+	var req model.MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.MovePath(c.Request.Context(), c.Param("id"), &req); err != nil {
+		writeFSError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "path moved"})
+}
+
+// RemovePath deletes the path given by the "path" query parameter. Directories that
+// are not empty are only removed with recursive=true.
+func (h *SandboxHandler) RemovePath(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+
+	if err := h.svc.RemovePath(c.Request.Context(), c.Param("id"), path, c.Query("recursive") == "true"); err != nil {
+		writeFSError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// sseKeepAliveInterval is how often a comment line is sent on an idle event stream so
+// proxies do not close it.
+const sseKeepAliveInterval = 15 * time.Second
+
+// WatchPath streams create, modify and delete events for "path" and everything below
+// it as server-sent events. The tree is rescanned every "interval" seconds (default 1),
+// and a ready event is sent once the initial scan completes.
+func (h *SandboxHandler) WatchPath(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+	interval, _ := strconv.Atoi(c.Query("interval"))
+
+	emit, stop := newSSEEmitter(c)
+	err := h.svc.WatchPath(c.Request.Context(), c.Param("id"), path, time.Duration(interval)*time.Second, emit)
+	stop()
+	if err != nil && c.Request.Context().Err() == nil {
+		if !c.Writer.Written() {
+			writeFSError(c, err)
+			return
+		}
+		_ = emit(model.FSEvent{Type: model.FSEventError, Message: err.Error()})
+	}
+}
+
+// newSSEEmitter prepares c for a long-lived server-sent event stream and returns a
+// function writing one event, named after its type, per call. Headers are sent lazily
+// with the first event so callers can still reply with a regular JSON error. Until
+// stop is called, idle streams get periodic keep-alive comments.
+func newSSEEmitter(c *gin.Context) (emit func(model.FSEvent) error, stop func()) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	var mu sync.Mutex
+	started := false
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(sseKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				if started {
+					_, _ = c.Writer.WriteString(": keep-alive\n\n")
+					c.Writer.Flush()
+				}
+				mu.Unlock()
+			}
+		}
+	}()
+
+	emit = func(event model.FSEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !started {
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	stop = func() {
+		close(done)
+		wg.Wait()
+	}
+	return emit, stop
+}
+
+func writeFSError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSandboxNotFound), errors.Is(err, service.ErrPathNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPath), errors.Is(err, service.ErrNotDirectory), errors.Is(err, service.ErrTooManyWatchEntries):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPathExists), errors.Is(err, service.ErrPathIsDirectory), errors.Is(err, service.ErrDirectoryNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotHandler handles sandbox snapshot HTTP requests
+type SnapshotHandler struct {
+	svc *service.SandboxSnapshotService
+}
+
+// NewSnapshotHandler creates a new SnapshotHandler
+func NewSnapshotHandler(svc *service.SandboxSnapshotService) *SnapshotHandler {
+	return &SnapshotHandler{svc: svc}
+}
+
+// RegisterRoutes registers snapshot routes
+func (h *SnapshotHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/sandboxes/:id/snapshots", h.Create)
+	r.GET("/sandboxes/:id/snapshots", h.ListBySandbox)
+
+	snapshots := r.Group("/snapshots")
+	{
+		snapshots.GET("", h.List)
+		snapshots.GET("/:snapshotId", h.Get)
+		snapshots.DELETE("/:snapshotId", h.Delete)
+	}
+}
+
+func (h *SnapshotHandler) Create(c *gin.Context) {
+	var req model.CreateSnapshotRequest
+	// The body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot, err := h.svc.Create(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		writeSnapshotError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, snapshot)
+}
+
+func (h *SnapshotHandler) ListBySandbox(c *gin.Context) {
+	resp, err := h.svc.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeSnapshotError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// List returns snapshots of all sandboxes, or of one with ?sandbox_id=.
+func (h *SnapshotHandler) List(c *gin.Context) {
+	resp, err := h.svc.List(c.Request.Context(), c.Query("sandbox_id"))
+	if err != nil {
+		writeSnapshotError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *SnapshotHandler) Get(c *gin.Context) {
+	snapshot, err := h.svc.Get(c.Request.Context(), c.Param("snapshotId"))
+	if err != nil {
+		writeSnapshotError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+func (h *SnapshotHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("snapshotId")); err != nil {
+		writeSnapshotError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeSnapshotError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSandboxNotFound), errors.Is(err, service.ErrSnapshotNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSnapshotNotSupported), errors.Is(err, service.ErrRestoreNeedsPersistence):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSandboxNotRunning), errors.Is(err, service.ErrSnapshotInvalidState),
+		errors.Is(err, service.ErrSnapshotInProgress), errors.Is(err, service.ErrSnapshotNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -51,6 +52,24 @@ func (h *TemplateHandler) Create(c *gin.Context) {
 
 	template, err := h.svc.Create(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, service.ErrProjectScope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": gin.H{
+					"code":    "PROJECT_FORBIDDEN",
+					"message": err.Error(),
+				},
+			})
+			return
+		}
+		if errors.Is(err, service.ErrTemplateNotAllowed) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": gin.H{
+					"code":    "TEMPLATE_FORBIDDEN",
+					"message": err.Error(),
+				},
+			})
+			return
+		}
 		if isConflictError(err) {
 			c.JSON(http.StatusConflict, gin.H{
 				"error": gin.H{
@@ -82,6 +101,7 @@ func (h *TemplateHandler) List(c *gin.Context) {
 		Search:   c.Query("search"),
 		Page:     page,
 		PageSize: pageSize,
+		Project:  c.Query("project"),
 	}
 
 	result, err := h.svc.List(c.Request.Context(), opts)
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TrafficHandler handles sandbox traffic HTTP requests
+type TrafficHandler struct {
+	svc *service.SandboxTrafficService
+}
+
+// NewTrafficHandler creates a new TrafficHandler
+func NewTrafficHandler(svc *service.SandboxTrafficService) *TrafficHandler {
+	return &TrafficHandler{svc: svc}
+}
+
+// RegisterRoutes registers traffic routes
+func (h *TrafficHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/sandboxes/:id/traffic", h.Get)
+}
+
+// Get returns the bytes the gateway has relayed to and from a sandbox
+func (h *TrafficHandler) Get(c *gin.Context) {
+	traffic, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrSandboxNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, traffic)
+}
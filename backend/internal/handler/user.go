@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserHandler handles user management HTTP requests. All routes require the admin role.
+type UserHandler struct {
+	authStore *store.AuthStore
+}
+
+// NewUserHandler creates a new UserHandler.
+func NewUserHandler(authStore *store.AuthStore) *UserHandler {
+	return &UserHandler{authStore: authStore}
+}
+
+func (h *UserHandler) logger(c *gin.Context) *logx.Logger {
+	return logx.WithComponent(c.Request.Context(), "auth")
+}
+
+// RegisterRoutes registers user routes on the given router group.
+func (h *UserHandler) RegisterRoutes(r *gin.RouterGroup) {
+	users := r.Group("/users")
+	users.Use(auth.RequireRole(model.RoleAdmin))
+	{
+		users.POST("", h.Create)
+		users.GET("", h.List)
+		users.GET("/:id", h.Get)
+		users.PATCH("/:id", h.Update)
+		users.DELETE("/:id", h.Delete)
+	}
+}
+
+func toUser(rec *store.UserRecord) model.User {
+	return model.User{
+		ID:        rec.ID,
+		Username:  rec.Username,
+		Role:      model.Role(rec.Role),
+		Disabled:  rec.Disabled,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+	}
+}
+
+// findUser looks up a user by ID or username and writes an error response when there
+// is none.
+func (h *UserHandler) findUser(c *gin.Context) *store.UserRecord {
+	ref := c.Param("id")
+	user, err := h.authStore.GetUserByID(c.Request.Context(), ref)
+	if err == nil && user == nil {
+		user, err = h.authStore.GetUserByUsername(c.Request.Context(), ref)
+	}
+	if err != nil {
+		h.logger(c).Errorf("failed to get user %s: %v", ref, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return nil
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return nil
+	}
+	return user
+}
+
+// Create creates a user.
+func (h *UserHandler) Create(c *gin.Context) {
+	var req model.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = model.RoleDeveloper
+	}
+	if !auth.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be admin, developer or viewer"})
+		return
+	}
+
+	existing, err := h.authStore.GetUserByUsername(c.Request.Context(), req.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "username already exists"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+	rec := &store.UserRecord{
+		ID:           uuid.NewString(),
+		Username:     req.Username,
+		PasswordHash: string(hash),
+		Role:         string(req.Role),
+	}
+	if err := h.authStore.CreateUser(c.Request.Context(), rec); err != nil {
+		h.logger(c).Errorf("failed to create user %s: %v", req.Username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		return
+	}
+
+	h.logger(c).Infof("user created, username=%s, role=%s", rec.Username, rec.Role)
+	c.JSON(http.StatusCreated, toUser(rec))
+}
+
+// List returns all users.
+func (h *UserHandler) List(c *gin.Context) {
+	users, err := h.authStore.ListUsers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list users"})
+		return
+	}
+
+	items := make([]model.User, 0, len(users))
+	for i := range users {
+		items = append(items, toUser(&users[i]))
+	}
+	c.JSON(http.StatusOK, model.UserListResponse{Items: items})
+}
+
+// Get returns a user by ID or username.
+func (h *UserHandler) Get(c *gin.Context) {
+	user := h.findUser(c)
+	if user == nil {
+		return
+	}
+	c.JSON(http.StatusOK, toUser(user))
+}
+
+// Update changes the role, disabled flag or password of a user.
+func (h *UserHandler) Update(c *gin.Context) {
+	var req model.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if req.Role != nil && !auth.ValidRole(*req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be admin, developer or viewer"})
+		return
+	}
+
+	user := h.findUser(c)
+	if user == nil {
+		return
+	}
+
+	wasActiveAdmin := user.Role == string(model.RoleAdmin) && !user.Disabled
+	if req.Role != nil {
+		user.Role = string(*req.Role)
+	}
+	if req.Disabled != nil {
+		user.Disabled = *req.Disabled
+	}
+	if wasActiveAdmin && (user.Role != string(model.RoleAdmin) || user.Disabled) && !h.otherAdminExists(c) {
+		return
+	}
+
+	if req.Password != nil {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+			return
+		}
+		if err := h.authStore.UpdateUserPassword(c.Request.Context(), user.ID, string(hash)); err != nil {
+			h.logger(c).Errorf("failed to update password for user %s: %v", user.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
+			return
+		}
+	}
+	if err := h.authStore.UpdateUser(c.Request.Context(), user); err != nil {
+		h.logger(c).Errorf("failed to update user %s: %v", user.Username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+		return
+	}
+	// A disabled user or a new password ends the existing web sessions
+	if user.Disabled || req.Password != nil {
+		if err := h.authStore.DeleteUserSessions(c.Request.Context(), user.ID); err != nil {
+			h.logger(c).Warnf("failed to end sessions of user %s: %v", user.Username, err)
+		}
+	}
+
+	h.logger(c).Infof("user updated, username=%s, role=%s, disabled=%t", user.Username, user.Role, user.Disabled)
+	c.JSON(http.StatusOK, toUser(user))
+}
+
+// Delete deletes a user along with their sessions and API keys.
+func (h *UserHandler) Delete(c *gin.Context) {
+	user := h.findUser(c)
+	if user == nil {
+		return
+	}
+	if user.Role == string(model.RoleAdmin) && !user.Disabled && !h.otherAdminExists(c) {
+		return
+	}
+
+	if err := h.authStore.DeleteUser(c.Request.Context(), user.ID); err != nil {
+		h.logger(c).Errorf("failed to delete user %s: %v", user.Username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete user"})
+		return
+	}
+
+	h.logger(c).Infof("user deleted, username=%s", user.Username)
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
+
+// otherAdminExists checks that an active admin remains when one stops being an admin,
+// and writes a conflict response when none would.
+func (h *UserHandler) otherAdminExists(c *gin.Context) bool {
+	count, err := h.authStore.ActiveAdminCount(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return false
+	}
+	if count <= 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "at least one active admin must remain"})
+		return false
+	}
+	return true
+}
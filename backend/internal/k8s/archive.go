@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UploadArchive extracts a plain (uncompressed) tar stream into destDir, creating the
+// directory first. Entry modes are preserved by tar itself.
+func (c *Client) UploadArchive(ctx context.Context, sandboxID, destDir string, tarStream io.Reader) error {
+	var stdout, stderr bytes.Buffer
+	err := c.ExecInteractive(ctx, sandboxID, ExecInteractiveOptions{
+		Command: []string{"sh", "-c", `mkdir -p "$1" && tar -xf - -C "$1"`, "liteboxd-extract", destDir},
+		Stdin:   tarStream,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to extract archive: %w, stderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// DownloadArchive writes a gzip-compressed tar of the contents of srcPath to w.
+// Entries are relative to srcPath, so extracting the archive into a directory
+// reproduces srcPath's contents there.
+func (c *Client) DownloadArchive(ctx context.Context, sandboxID, srcPath string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	var stderr bytes.Buffer
+	err := c.ExecInteractive(ctx, sandboxID, ExecInteractiveOptions{
+		Command: []string{"tar", "-cf", "-", "-C", srcPath, "."},
+		Stdout:  gz,
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive directory: %w, stderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
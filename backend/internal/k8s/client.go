@@ -1,15 +1,17 @@
 package k8s
 
 import (
-	"archive/tar"
 	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -65,6 +67,11 @@ type Client struct {
 	controlNS     string
 
 	persistentRootFSHelperImage string
+
+	// Sandboxes of projects run in their own namespaces
+	nsMu      sync.RWMutex
+	resolveNS NamespaceResolver
+	projectNS map[string]struct{}
 }
 
 func NewClient(cfg ClientConfig) (*Client, error) {
@@ -119,18 +126,7 @@ func (c *Client) ControlNamespace() string {
 }
 
 func (c *Client) EnsureNamespace(ctx context.Context) error {
-	_, err := c.clientset.CoreV1().Namespaces().Get(ctx, c.sandboxNS, metav1.GetOptions{})
-	if err == nil {
-		return nil
-	}
-
-	ns := &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: c.sandboxNS,
-		},
-	}
-	_, err = c.clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
-	return err
+	return c.ensureNamespace(ctx, c.sandboxNS, nil)
 }
 
 type CreatePodOptions struct {
@@ -143,17 +139,41 @@ type CreatePodOptions struct {
 	TTL            int
 	Env            map[string]string
 	Annotations    map[string]string
-	StartupScript  string       // Startup script to execute after pod is ready
-	StartupFiles   []FileSpec   // Files to upload before startup script
-	ReadinessProbe *ProbeSpec   // Readiness probe configuration
-	Network        *NetworkSpec // Network configuration
-	AccessToken    string       // Access token injected by control-plane (optional)
+	Labels         map[string]string // Extra pod labels
+	StartupScript  string            // Startup script to execute after pod is ready
+	StartupFiles   []FileSpec        // Files to upload before startup script
+	ReadinessProbe *ProbeSpec        // Readiness probe configuration
+	Network        *NetworkSpec      // Network configuration
 }
 
 // NetworkSpec defines the network configuration for a pod
 type NetworkSpec struct {
-	AllowInternetAccess bool     // Enable outbound internet access
-	AllowedDomains      []string // Optional domain whitelist (future)
+	AllowInternetAccess bool         // Enable outbound internet access
+	AllowedDomains      []string     // Optional domain whitelist
+	Ports               []PortRule   // Internet ports; empty means TCP 80 and 443
+	DomainRules         []DomainRule // Further domains with their own ports
+	AllowedCIDRs        []CIDRRule   // IP ranges reachable regardless of internet access
+	DeniedCIDRs         []string     // IP ranges that are never reachable
+	EgressBandwidth     string       // Egress rate limit, e.g. "10M"; empty is unlimited
+	IngressBandwidth    string       // Ingress rate limit; empty is unlimited
+}
+
+// PortRule is a port egress is allowed to. Protocol is TCP, UDP or ANY.
+type PortRule struct {
+	Port     int
+	Protocol string
+}
+
+// DomainRule allows egress to domains on specific ports.
+type DomainRule struct {
+	Domains []string
+	Ports   []PortRule // Empty means the internet ports
+}
+
+// CIDRRule allows egress to an IP range.
+type CIDRRule struct {
+	CIDR  string
+	Ports []PortRule // Empty means all ports
 }
 
 // FileSpec defines a file to be uploaded to the sandbox
@@ -219,23 +239,20 @@ func containerWithCommandAndArgs(opts CreatePodOptions) corev1.Container {
 
 func (c *Client) CreatePod(ctx context.Context, opts CreatePodOptions) (*corev1.Pod, error) {
 	podName := fmt.Sprintf("sandbox-%s", opts.ID)
-
-	// Generate access token for sandbox network access when not provided by control-plane.
-	accessToken := opts.AccessToken
-	if accessToken == "" {
-		accessToken = generateAccessToken()
-	}
+	namespace := c.namespaceOf(ctx, opts.ID)
 
 	// Build annotations
 	annotations := map[string]string{
-		AnnotationTTL:         fmt.Sprintf("%d", opts.TTL),
-		AnnotationCreatedAt:   time.Now().UTC().Format(time.RFC3339),
-		AnnotationAccessToken: accessToken,
+		AnnotationTTL:       fmt.Sprintf("%d", opts.TTL),
+		AnnotationCreatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 	// Merge custom annotations
 	for k, v := range opts.Annotations {
 		annotations[k] = v
 	}
+	for k, v := range opts.Network.LimitAnnotations() {
+		annotations[k] = v
+	}
 
 	// Build labels
 	labels := map[string]string{
@@ -244,14 +261,18 @@ func (c *Client) CreatePod(ctx context.Context, opts CreatePodOptions) (*corev1.
 		LabelManagedBy: ManagedByServer,
 	}
 
-	if opts.Network != nil && opts.Network.AllowInternetAccess && len(opts.Network.AllowedDomains) == 0 {
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+
+	if opts.Network.UsesInternetLabel() {
 		labels[LabelInternetAccess] = "true"
 	}
 
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        podName,
-			Namespace:   c.sandboxNS,
+			Namespace:   namespace,
 			Labels:      labels,
 			Annotations: annotations,
 		},
@@ -289,26 +310,35 @@ func (c *Client) CreatePod(ctx context.Context, opts CreatePodOptions) (*corev1.
 		},
 	}
 
-	return c.clientset.CoreV1().Pods(c.sandboxNS).Create(ctx, pod, metav1.CreateOptions{})
+	return c.clientset.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
 }
 
 func (c *Client) GetPod(ctx context.Context, sandboxID string) (*corev1.Pod, error) {
 	return c.getSandboxPod(ctx, sandboxID)
 }
 
+// ListPods lists sandbox pods in all sandbox namespaces.
 func (c *Client) ListPods(ctx context.Context) (*corev1.PodList, error) {
-	return c.clientset.CoreV1().Pods(c.sandboxNS).List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("app=%s", LabelApp),
-	})
+	all := &corev1.PodList{}
+	for _, ns := range c.SandboxNamespaces() {
+		list, err := c.clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("app=%s", LabelApp),
+		})
+		if err != nil {
+			return nil, err
+		}
+		all.Items = append(all.Items, list.Items...)
+	}
+	return all, nil
 }
 
 func (c *Client) DeletePod(ctx context.Context, sandboxID string) error {
 	podName := fmt.Sprintf("sandbox-%s", sandboxID)
-	return c.clientset.CoreV1().Pods(c.sandboxNS).Delete(ctx, podName, metav1.DeleteOptions{})
+	return c.clientset.CoreV1().Pods(c.namespaceOf(ctx, sandboxID)).Delete(ctx, podName, metav1.DeleteOptions{})
 }
 
 func (c *Client) DeletePodByName(ctx context.Context, name string) error {
-	return c.clientset.CoreV1().Pods(c.sandboxNS).Delete(ctx, name, metav1.DeleteOptions{})
+	return c.clientset.CoreV1().Pods(c.namespace(ctx)).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
 func (c *Client) ListSandboxPods(ctx context.Context, sandboxID string) ([]corev1.Pod, error) {
@@ -316,7 +346,7 @@ func (c *Client) ListSandboxPods(ctx context.Context, sandboxID string) ([]corev
 		"app":          LabelApp,
 		LabelSandboxID: sandboxID,
 	}.AsSelector().String()
-	list, err := c.clientset.CoreV1().Pods(c.sandboxNS).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	list, err := c.clientset.CoreV1().Pods(c.namespaceOf(ctx, sandboxID)).List(ctx, metav1.ListOptions{LabelSelector: selector})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list sandbox pods: %w", err)
 	}
@@ -329,7 +359,7 @@ func (c *Client) ListSandboxPods(ctx context.Context, sandboxID string) ([]corev
 func (c *Client) ForceDeletePodByName(ctx context.Context, name string) error {
 	zero := int64(0)
 	policy := metav1.DeletePropagationBackground
-	err := c.clientset.CoreV1().Pods(c.sandboxNS).Delete(ctx, name, metav1.DeleteOptions{
+	err := c.clientset.CoreV1().Pods(c.namespace(ctx)).Delete(ctx, name, metav1.DeleteOptions{
 		GracePeriodSeconds: &zero,
 		PropagationPolicy:  &policy,
 	})
@@ -369,12 +399,17 @@ func (c *Client) Exec(ctx context.Context, sandboxID string, command []string) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
 	}
+	return c.execInPod(ctx, pod, command)
+}
+
+// execInPod runs a buffered, non-interactive command in an already resolved sandbox pod.
+func (c *Client) execInPod(ctx context.Context, pod *corev1.Pod, command []string) (*ExecResult, error) {
 	command = wrapCommandForRootFS(pod, command)
 
 	req := c.clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Name(pod.Name).
-		Namespace(c.sandboxNS).
+		Namespace(pod.Namespace).
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Container: "main",
@@ -414,119 +449,100 @@ func (c *Client) Exec(ctx context.Context, sandboxID string, command []string) (
 }
 
 func (c *Client) UploadFile(ctx context.Context, sandboxID string, destPath string, content []byte) error {
+	return c.UploadFileStream(ctx, sandboxID, destPath, bytes.NewReader(content))
+}
+
+// fileWriteScript writes stdin to $1, creating parent directories as needed.
+const fileWriteScript = `set -e
+mkdir -p -- "$(dirname -- "$1")"
+cat >"$1"
+`
+
+// fileCommitScript moves a completed upload ($1) into place ($2), or discards it when
+// no destination is given. A directory destination is refused, since mv would move the
+// upload into it instead of replacing it.
+const fileCommitScript = `if [ -z "$2" ]; then rm -f -- "$1"; exit 0; fi
+if [ -d "$2" ]; then rm -f -- "$1"; exit 47; fi
+mv -f -- "$1" "$2"
+`
+
+// fileReadScript copies $1 to stdout starting at byte offset $2, limited to $3 bytes
+// when $3 is not negative.
+const fileReadScript = `[ -e "$1" ] || exit 44
+[ -d "$1" ] && exit 47
+if [ "$2" -gt 0 ]; then tail -c +$(($2 + 1)) -- "$1"; else cat -- "$1"; fi | if [ "$3" -ge 0 ]; then head -c "$3"; else cat; fi
+`
+
+// UploadFileStream streams r into destPath without buffering it. Data is written to a
+// temporary file next to destPath and only moved into place once r has been read to
+// EOF, so a failed or interrupted upload never leaves a truncated destination behind.
+func (c *Client) UploadFileStream(ctx context.Context, sandboxID, destPath string, r io.Reader) error {
 	pod, err := c.getSandboxPod(ctx, sandboxID)
 	if err != nil {
 		return fmt.Errorf("failed to resolve sandbox pod: %w", err)
 	}
-	command := []string{"tar", "-xf", "-", "-C", filepath.Dir(destPath)}
-	command = wrapCommandForRootFS(pod, command)
-	filename := filepath.Base(destPath)
-
-	var tarBuf bytes.Buffer
-	tw := tar.NewWriter(&tarBuf)
-	hdr := &tar.Header{
-		Name: filename,
-		Mode: 0644,
-		Size: int64(len(content)),
-	}
-	if err := tw.WriteHeader(hdr); err != nil {
-		return fmt.Errorf("failed to write tar header: %w", err)
-	}
-	if _, err := tw.Write(content); err != nil {
-		return fmt.Errorf("failed to write tar content: %w", err)
-	}
-	if err := tw.Close(); err != nil {
-		return fmt.Errorf("failed to close tar writer: %w", err)
-	}
-
-	req := c.clientset.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(pod.Name).
-		Namespace(c.sandboxNS).
-		SubResource("exec").
-		VersionedParams(&corev1.PodExecOptions{
-			Container: "main",
-			Command:   command,
-			Stdin:     true,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       false,
-		}, scheme.ParameterCodec)
-
-	exec, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
-	if err != nil {
-		return fmt.Errorf("failed to create executor: %w", err)
-	}
+	tmpPath := path.Join(path.Dir(destPath), "."+path.Base(destPath)+".liteboxd-upload-"+randomString(8))
 
+	// The exec stdin pump treats read errors as EOF, so they are recorded here and
+	// checked once the stream completes.
+	src := &errRecordingReader{r: r}
 	var stdout, stderr bytes.Buffer
-	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdin:  &tarBuf,
-		Stdout: &stdout,
-		Stderr: &stderr,
+	err = c.execInteractiveInPod(ctx, pod, ExecInteractiveOptions{
+		Command: []string{"sh", "-c", fileWriteScript, "liteboxd-upload", tmpPath},
+		Stdin:   src,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
 	})
-	if err != nil {
-		return fmt.Errorf("failed to upload file: %w, stderr: %s", err, stderr.String())
+	if err == nil {
+		err = src.err
 	}
-
-	return nil
-}
-
-func (c *Client) DownloadFile(ctx context.Context, sandboxID string, srcPath string) ([]byte, error) {
-	pod, err := c.getSandboxPod(ctx, sandboxID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, _ = c.execInPod(cleanupCtx, pod, []string{"sh", "-c", fileCommitScript, "liteboxd-commit", tmpPath, ""})
+		if src.err != nil {
+			return src.err
+		}
+		return fmt.Errorf("failed to upload file: %w, stderr: %s", err, strings.TrimSpace(stderr.String()))
 	}
-	command := []string{"tar", "-cf", "-", "-C", filepath.Dir(srcPath), filepath.Base(srcPath)}
-	command = wrapCommandForRootFS(pod, command)
-	filename := filepath.Base(srcPath)
-
-	req := c.clientset.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(pod.Name).
-		Namespace(c.sandboxNS).
-		SubResource("exec").
-		VersionedParams(&corev1.PodExecOptions{
-			Container: "main",
-			Command:   command,
-			Stdin:     false,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       false,
-		}, scheme.ParameterCodec)
 
-	exec, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
+	result, err := c.execInPod(ctx, pod, []string{"sh", "-c", fileCommitScript, "liteboxd-commit", tmpPath, destPath})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create executor: %w", err)
+		return fmt.Errorf("failed to upload file: %w", err)
 	}
+	return fsResultError("upload file", result)
+}
 
-	var stdout, stderr bytes.Buffer
-	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdout: &stdout,
-		Stderr: &stderr,
+// DownloadFileStream copies length bytes of srcPath starting at offset to w. A negative
+// length copies through the end of the file.
+func (c *Client) DownloadFileStream(ctx context.Context, sandboxID, srcPath string, offset, length int64, w io.Writer) error {
+	var stderr bytes.Buffer
+	err := c.ExecInteractive(ctx, sandboxID, ExecInteractiveOptions{
+		Command: []string{"sh", "-c", fileReadScript, "liteboxd-download", srcPath, strconv.FormatInt(offset, 10), strconv.FormatInt(length, 10)},
+		Stdout:  w,
+		Stderr:  &stderr,
 	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to download file: %w, stderr: %s", err, stderr.String())
+	if err == nil {
+		return nil
 	}
-
-	tr := tar.NewReader(&stdout)
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to read tar: %w", err)
-		}
-		if strings.TrimPrefix(hdr.Name, "./") == filename {
-			content, err := io.ReadAll(tr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read file content: %w", err)
-			}
-			return content, nil
-		}
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		return fsResultError("download file", &ExecResult{ExitCode: exitErr.ExitStatus(), Stderr: stderr.String()})
 	}
+	return fmt.Errorf("failed to download file: %w, stderr: %s", err, strings.TrimSpace(stderr.String()))
+}
 
-	return nil, fmt.Errorf("file not found in tar archive")
+type errRecordingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errRecordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
 }
 
 func (c *Client) GetLogs(ctx context.Context, sandboxID string, tailLines int64) (string, error) {
@@ -542,7 +558,7 @@ func (c *Client) GetLogs(ctx context.Context, sandboxID string, tailLines int64)
 		opts.TailLines = &tailLines
 	}
 
-	req := c.clientset.CoreV1().Pods(c.sandboxNS).GetLogs(pod.Name, opts)
+	req := c.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts)
 	stream, err := req.Stream(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get logs: %w", err)
@@ -563,7 +579,7 @@ func (c *Client) GetPodEvents(ctx context.Context, sandboxID string) ([]string,
 		return nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
 	}
 
-	events, err := c.clientset.CoreV1().Events(c.sandboxNS).List(ctx, metav1.ListOptions{
+	events, err := c.clientset.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("involvedObject.name=%s", pod.Name),
 	})
 	if err != nil {
@@ -868,16 +884,6 @@ func (c *Client) UploadFiles(ctx context.Context, sandboxID string, files []File
 	return nil
 }
 
-// generateAccessToken generates a random access token for sandbox network access
-func generateAccessToken() string {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		// Fallback to time-based token if crypto rand fails
-		return fmt.Sprintf("%d-%s", time.Now().UnixNano(), randomString(16))
-	}
-	return hex.EncodeToString(b)
-}
-
 // randomString generates a random hex string
 func randomString(length int) string {
 	b := make([]byte, length)
@@ -903,15 +909,24 @@ func (c *Client) ExecInteractive(ctx context.Context, sandboxID string, opts Exe
 	if err != nil {
 		return fmt.Errorf("failed to resolve sandbox pod: %w", err)
 	}
-	command := wrapCommandForRootFS(pod, opts.Command)
+	return c.execInteractiveInPod(ctx, pod, opts)
+}
+
+// execInteractiveInPod streams a command's I/O in an already resolved sandbox pod.
+func (c *Client) execInteractiveInPod(ctx context.Context, pod *corev1.Pod, opts ExecInteractiveOptions) error {
+	return c.streamExec(ctx, pod, "main", wrapCommandForRootFS(pod, opts.Command), opts)
+}
 
+// streamExec runs command as-is in the given container of pod, streaming the I/O set
+// in opts. opts.Command is ignored.
+func (c *Client) streamExec(ctx context.Context, pod *corev1.Pod, container string, command []string, opts ExecInteractiveOptions) error {
 	req := c.clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Name(pod.Name).
-		Namespace(c.sandboxNS).
+		Namespace(pod.Namespace).
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
-			Container: "main",
+			Container: container,
 			Command:   command,
 			Stdin:     opts.Stdin != nil,
 			Stdout:    true,
@@ -948,18 +963,3 @@ func (c *Client) GetPodIP(ctx context.Context, sandboxID string) (string, error)
 
 	return pod.Status.PodIP, nil
 }
-
-// GetPodAccessToken retrieves the access token from a pod's annotations
-func (c *Client) GetPodAccessToken(ctx context.Context, sandboxID string) (string, error) {
-	pod, err := c.getSandboxPod(ctx, sandboxID)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve sandbox pod: %w", err)
-	}
-
-	token, ok := pod.Annotations[AnnotationAccessToken]
-	if !ok {
-		return "", fmt.Errorf("access token not found")
-	}
-
-	return token, nil
-}
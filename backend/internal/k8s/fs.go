@@ -0,0 +1,233 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filesystem errors reported by the sandbox filesystem helpers.
+var (
+	ErrPathNotFound      = errors.New("path not found")
+	ErrPathExists        = errors.New("path already exists")
+	ErrPathIsDirectory   = errors.New("path is a directory")
+	ErrNotDirectory      = errors.New("path is not a directory")
+	ErrDirectoryNotEmpty = errors.New("directory is not empty")
+)
+
+// Exit codes used by the filesystem scripts to report well-known failures.
+const (
+	fsExitNotFound     = 44
+	fsExitExists       = 45
+	fsExitNotDirectory = 46
+	fsExitIsDirectory  = 47
+	fsExitDirNotEmpty  = 48
+)
+
+// fsEntryFunc prints one entry as "<raw mode hex> <size> <mtime>\n<name>\0<link target>\0".
+// Names and link targets are NUL-terminated so any file name round-trips.
+const fsEntryFunc = `entry() {
+	stat -c '%f %s %Y' -- "$1" || exit 1
+	printf '%s\0' "$2"
+	if [ -L "$1" ]; then printf '%s' "$(readlink -- "$1")"; fi
+	printf '\0'
+}
+exists() { [ -e "$1" ] || [ -L "$1" ]; }
+`
+
+const fsListScript = fsEntryFunc + `d="$1"
+exists "$d" || exit 44
+[ -d "$d" ] || exit 46
+for f in "$d"/* "$d"/.[!.]* "$d"/..?*; do
+	exists "$f" || continue
+	entry "$f" "${f##*/}"
+done
+`
+
+const fsStatScript = fsEntryFunc + `exists "$1" || exit 44
+entry "$1" "${1##*/}"
+`
+
+const fsMkdirScript = `if { [ -e "$1" ] || [ -L "$1" ]; } && [ ! -d "$1" ]; then exit 45; fi
+mkdir -p -- "$1"
+`
+
+// fsMoveScript renames $1 to $2. An existing destination is replaced only when $3 is 1,
+// and never when it is a directory, since mv would move the source into it instead.
+const fsMoveScript = `exists() { [ -e "$1" ] || [ -L "$1" ]; }
+exists "$1" || exit 44
+if exists "$2"; then
+	[ "$3" = 1 ] || exit 45
+	if [ -d "$2" ] && [ ! -L "$2" ]; then exit 47; fi
+fi
+mv -f -- "$1" "$2"
+`
+
+const fsRemoveScript = `if ! [ -e "$1" ] && ! [ -L "$1" ]; then exit 44; fi
+if [ -d "$1" ] && [ ! -L "$1" ]; then
+	if [ "$2" = 1 ]; then rm -rf -- "$1"; else rmdir -- "$1" 2>/dev/null || exit 48; fi
+else
+	rm -f -- "$1"
+fi
+`
+
+// FileStat describes a filesystem entry inside a sandbox. Mode holds the raw st_mode
+// bits, including the file type.
+type FileStat struct {
+	Name       string
+	Path       string
+	Mode       uint32
+	Size       int64
+	ModTime    time.Time
+	LinkTarget string
+}
+
+// ListDir returns the entries of a directory, including hidden ones, without following
+// symlinks.
+func (c *Client) ListDir(ctx context.Context, sandboxID, dir string) ([]FileStat, error) {
+	result, err := c.Exec(ctx, sandboxID, []string{"sh", "-c", fsListScript, "liteboxd-ls", dir})
+	if err != nil {
+		return nil, err
+	}
+	if err := fsResultError("list directory", result); err != nil {
+		return nil, err
+	}
+	return parseFileStats(result.Stdout, dir)
+}
+
+// StatPath returns information about a single path without following symlinks.
+func (c *Client) StatPath(ctx context.Context, sandboxID, p string) (*FileStat, error) {
+	result, err := c.Exec(ctx, sandboxID, []string{"sh", "-c", fsStatScript, "liteboxd-stat", p})
+	if err != nil {
+		return nil, err
+	}
+	if err := fsResultError("stat path", result); err != nil {
+		return nil, err
+	}
+	stats, err := parseFileStats(result.Stdout, path.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) != 1 {
+		return nil, fmt.Errorf("failed to stat path: unexpected output")
+	}
+	stat := stats[0]
+	stat.Path = p
+	if stat.Name == "" {
+		stat.Name = p
+	}
+	return &stat, nil
+}
+
+// MakeDir creates a directory and any missing parents.
+func (c *Client) MakeDir(ctx context.Context, sandboxID, dir string) error {
+	result, err := c.Exec(ctx, sandboxID, []string{"sh", "-c", fsMkdirScript, "liteboxd-mkdir", dir})
+	if err != nil {
+		return err
+	}
+	return fsResultError("create directory", result)
+}
+
+// MovePath renames src to dst. An existing file at dst is replaced only when overwrite
+// is set; an existing directory is never replaced.
+func (c *Client) MovePath(ctx context.Context, sandboxID, src, dst string, overwrite bool) error {
+	flag := "0"
+	if overwrite {
+		flag = "1"
+	}
+	result, err := c.Exec(ctx, sandboxID, []string{"sh", "-c", fsMoveScript, "liteboxd-mv", src, dst, flag})
+	if err != nil {
+		return err
+	}
+	return fsResultError("move path", result)
+}
+
+// RemovePath deletes a file, symlink or directory. Non-empty directories are only
+// removed when recursive is set.
+func (c *Client) RemovePath(ctx context.Context, sandboxID, p string, recursive bool) error {
+	flag := "0"
+	if recursive {
+		flag = "1"
+	}
+	result, err := c.Exec(ctx, sandboxID, []string{"sh", "-c", fsRemoveScript, "liteboxd-rm", p, flag})
+	if err != nil {
+		return err
+	}
+	return fsResultError("remove path", result)
+}
+
+func fsResultError(op string, result *ExecResult) error {
+	switch result.ExitCode {
+	case 0:
+		return nil
+	case fsExitNotFound:
+		return ErrPathNotFound
+	case fsExitExists:
+		return ErrPathExists
+	case fsExitNotDirectory:
+		return ErrNotDirectory
+	case fsExitIsDirectory:
+		return ErrPathIsDirectory
+	case fsExitDirNotEmpty:
+		return ErrDirectoryNotEmpty
+	default:
+		return fmt.Errorf("failed to %s (exit code %d): %s", op, result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+}
+
+// parseFileStats decodes the records printed by fsEntryFunc. Entry paths are joined
+// onto dir.
+func parseFileStats(output, dir string) ([]FileStat, error) {
+	r := bufio.NewReader(strings.NewReader(output))
+	stats := make([]FileStat, 0)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return stats, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stat output: %w", err)
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("failed to parse stat output: unexpected line %q", strings.TrimSpace(line))
+		}
+		mode, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file mode %q: %w", fields[0], err)
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file size %q: %w", fields[1], err)
+		}
+		mtime, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse modification time %q: %w", fields[2], err)
+		}
+
+		name, err := r.ReadString(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stat output: %w", err)
+		}
+		link, err := r.ReadString(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stat output: %w", err)
+		}
+		name = strings.TrimSuffix(name, "\x00")
+
+		stats = append(stats, FileStat{
+			Name:       name,
+			Path:       path.Join(dir, name),
+			Mode:       uint32(mode),
+			Size:       size,
+			ModTime:    time.Unix(mtime, 0).UTC(),
+			LinkTarget: strings.TrimSuffix(link, "\x00"),
+		})
+	}
+}
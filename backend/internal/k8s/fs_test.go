@@ -0,0 +1,252 @@
+package k8s
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runFSScript runs a filesystem script with the local shell, the way execInPod would
+// run it in the sandbox.
+func runFSScript(t *testing.T, script string, args ...string) *ExecResult {
+	t.Helper()
+	cmd := exec.Command("sh", append([]string{"-c", script, "liteboxd-test"}, args...)...)
+	stdout, err := cmd.Output()
+	result := &ExecResult{Stdout: string(stdout)}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		result.Stderr = string(exitErr.Stderr)
+	} else if err != nil {
+		t.Fatalf("failed to run script: %v", err)
+	}
+	return result
+}
+
+func TestFSListScriptParsesAllEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "odd\nname"), []byte("hi"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runFSScript(t, fsListScript, dir)
+	if err := fsResultError("list directory", result); err != nil {
+		t.Fatalf("list script error = %v", err)
+	}
+	stats, err := parseFileStats(result.Stdout, dir)
+	if err != nil {
+		t.Fatalf("parseFileStats() error = %v", err)
+	}
+
+	byName := make(map[string]FileStat, len(stats))
+	for _, s := range stats {
+		byName[s.Name] = s
+	}
+	if len(byName) != 4 {
+		t.Fatalf("entries = %+v, want 4", stats)
+	}
+	if s := byName["odd\nname"]; s.Mode&0o170000 != 0o100000 || s.Mode&0o777 != 0o640 || s.Size != 2 || s.Path != filepath.Join(dir, "odd\nname") {
+		t.Fatalf("regular file entry = %+v", s)
+	}
+	if s := byName["sub"]; s.Mode&0o170000 != 0o040000 {
+		t.Fatalf("directory entry = %+v", s)
+	}
+	if s := byName["link"]; s.Mode&0o170000 != 0o120000 || s.LinkTarget != "sub" {
+		t.Fatalf("symlink entry = %+v", s)
+	}
+	if _, ok := byName[".hidden"]; !ok {
+		t.Fatalf("hidden entry missing from %+v", stats)
+	}
+}
+
+func TestFSScriptsReportErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nonEmpty := filepath.Join(dir, "full")
+	if err := os.MkdirAll(filepath.Join(nonEmpty, "child"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		args   []string
+		want   error
+	}{
+		{name: "list missing", script: fsListScript, args: []string{filepath.Join(dir, "missing")}, want: ErrPathNotFound},
+		{name: "list file", script: fsListScript, args: []string{file}, want: ErrNotDirectory},
+		{name: "stat missing", script: fsStatScript, args: []string{filepath.Join(dir, "missing")}, want: ErrPathNotFound},
+		{name: "mkdir over file", script: fsMkdirScript, args: []string{file}, want: ErrPathExists},
+		{name: "move missing", script: fsMoveScript, args: []string{filepath.Join(dir, "missing"), filepath.Join(dir, "x"), "0"}, want: ErrPathNotFound},
+		{name: "move onto existing", script: fsMoveScript, args: []string{nonEmpty, file, "0"}, want: ErrPathExists},
+		{name: "move onto directory", script: fsMoveScript, args: []string{file, nonEmpty, "1"}, want: ErrPathIsDirectory},
+		{name: "remove non-empty", script: fsRemoveScript, args: []string{nonEmpty, "0"}, want: ErrDirectoryNotEmpty},
+		{name: "remove missing", script: fsRemoveScript, args: []string{filepath.Join(dir, "missing"), "0"}, want: ErrPathNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fsResultError("test", runFSScript(t, tt.script, tt.args...))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFSScriptsModifyFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "a", "b")
+
+	if err := fsResultError("mkdir", runFSScript(t, fsMkdirScript, nested)); err != nil {
+		t.Fatalf("mkdir error = %v", err)
+	}
+	if err := fsResultError("mkdir", runFSScript(t, fsMkdirScript, nested)); err != nil {
+		t.Fatalf("mkdir on existing directory error = %v", err)
+	}
+
+	moved := filepath.Join(dir, "moved")
+	if err := fsResultError("move", runFSScript(t, fsMoveScript, nested, moved, "0")); err != nil {
+		t.Fatalf("move error = %v", err)
+	}
+	if _, err := os.Stat(moved); err != nil {
+		t.Fatalf("moved directory missing: %v", err)
+	}
+
+	if err := fsResultError("remove", runFSScript(t, fsRemoveScript, filepath.Join(dir, "a"), "0")); err != nil {
+		t.Fatalf("remove empty directory error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(moved, "f"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsResultError("remove", runFSScript(t, fsRemoveScript, moved, "1")); err != nil {
+		t.Fatalf("recursive remove error = %v", err)
+	}
+	if _, err := os.Lstat(moved); !os.IsNotExist(err) {
+		t.Fatalf("directory still exists after recursive remove: %v", err)
+	}
+}
+
+func TestFileReadScriptRanges(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data")
+	if err := os.WriteFile(file, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		offset, length string
+		want           string
+	}{
+		{offset: "0", length: "-1", want: "0123456789"},
+		{offset: "3", length: "-1", want: "3456789"},
+		{offset: "2", length: "4", want: "2345"},
+		{offset: "0", length: "0", want: ""},
+		{offset: "8", length: "10", want: "89"},
+	}
+	for _, tt := range tests {
+		result := runFSScript(t, fileReadScript, file, tt.offset, tt.length)
+		if result.ExitCode != 0 || result.Stdout != tt.want {
+			t.Fatalf("read(%s, %s) = %q (exit %d), want %q", tt.offset, tt.length, result.Stdout, result.ExitCode, tt.want)
+		}
+	}
+
+	if err := fsResultError("read", runFSScript(t, fileReadScript, dir, "0", "-1")); !errors.Is(err, ErrPathIsDirectory) {
+		t.Fatalf("read(dir) error = %v, want ErrPathIsDirectory", err)
+	}
+	if err := fsResultError("read", runFSScript(t, fileReadScript, filepath.Join(dir, "missing"), "0", "-1")); !errors.Is(err, ErrPathNotFound) {
+		t.Fatalf("read(missing) error = %v, want ErrPathNotFound", err)
+	}
+}
+
+func TestFileWriteAndCommitScripts(t *testing.T) {
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, "nested", ".out.liteboxd-upload-test")
+	dest := filepath.Join(dir, "nested", "out")
+
+	cmd := exec.Command("sh", "-c", fileWriteScript, "liteboxd-test", tmp)
+	cmd.Stdin = strings.NewReader("payload")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("write script failed: %v\n%s", err, out)
+	}
+	if result := runFSScript(t, fileCommitScript, tmp, dest); result.ExitCode != 0 {
+		t.Fatalf("commit script failed: %s", result.Stderr)
+	}
+	content, err := os.ReadFile(dest)
+	if err != nil || string(content) != "payload" {
+		t.Fatalf("destination content = %q, %v", content, err)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatalf("temporary file still exists: %v", err)
+	}
+}
+
+func TestFileCommitScriptRefusesDirectoryDestination(t *testing.T) {
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, ".out.liteboxd-upload-test")
+	if err := os.WriteFile(tmp, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "sub")
+	if err := os.Mkdir(dest, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsResultError("upload file", runFSScript(t, fileCommitScript, tmp, dest)); !errors.Is(err, ErrPathIsDirectory) {
+		t.Fatalf("commit error = %v, want ErrPathIsDirectory", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, filepath.Base(tmp))); !os.IsNotExist(err) {
+		t.Fatalf("upload was moved into the directory: %v", err)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatalf("temporary file still exists: %v", err)
+	}
+}
+
+func TestFSScriptsTreatDashPathsAsOperands(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(filepath.Join(dir, "-f"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsResultError("move", runFSScript(t, fsMoveScript, "-f", "-r", "0")); err != nil {
+		t.Fatalf("move error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "-r")); err != nil {
+		t.Fatalf("moved file missing: %v", err)
+	}
+	if err := os.Symlink("-r", filepath.Join(dir, "-s")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := parseFileStats(runFSScript(t, fsStatScript, "-s").Stdout, ".")
+	if err != nil || len(stats) != 1 || stats[0].LinkTarget != "-r" {
+		t.Fatalf("stat symlink = %+v, %v; want link target -r", stats, err)
+	}
+	if result := runFSScript(t, fileReadScript, "-r", "0", "-1"); result.ExitCode != 0 {
+		t.Fatalf("read error: %s", result.Stderr)
+	}
+	if result := runFSScript(t, fileReadScript, "-r", "1", "-1"); result.ExitCode != 0 {
+		t.Fatalf("read from offset error: %s", result.Stderr)
+	}
+	if err := fsResultError("remove", runFSScript(t, fsRemoveScript, "-r", "0")); err != nil {
+		t.Fatalf("remove error = %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "-r")); !os.IsNotExist(err) {
+		t.Fatalf("file still exists after remove: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTooManyEntries is returned when a watched tree grows beyond fsWatchMaxEntries.
+var ErrTooManyEntries = errors.New("too many entries to watch")
+
+// fsWatchMaxEntries bounds the size of a single watch snapshot held in memory.
+const fsWatchMaxEntries = 100000
+
+// fsWatchScript prints every path under $1 as "<raw mode hex> <size> <mtime> <path>"
+// once per $2 seconds, ending each scan with the marker line $3. The loop ends when
+// stdout is closed, so it does not outlive the exec session.
+const fsWatchScript = `[ -e "$1" ] || [ -L "$1" ] || exit 44
+while :; do
+	find "$1" -exec stat -c '%f %s %Y %n' {} + 2>/dev/null
+	printf '%s\n' "$3" || exit 0
+	sleep "$2"
+done
+`
+
+// WatchPath scans root and everything below it every interval and passes each scan to
+// onSnapshot, keyed by path. It runs until ctx is done, the scan fails, or onSnapshot
+// returns an error, which is then returned.
+func (c *Client) WatchPath(ctx context.Context, sandboxID, root string, interval time.Duration, onSnapshot func(map[string]FileStat) error) error {
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+
+	seconds := int(interval / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	marker := "--liteboxd-watch-" + randomString(16) + "--"
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	execDone := make(chan error, 1)
+	go func() {
+		err := c.execInteractiveInPod(watchCtx, pod, ExecInteractiveOptions{
+			Command: []string{"sh", "-c", fsWatchScript, "liteboxd-watch", root, strconv.Itoa(seconds), marker},
+			Stdout:  pw,
+			Stderr:  &stderr,
+		})
+		pw.CloseWithError(io.EOF)
+		execDone <- err
+	}()
+
+	readErr := readWatchSnapshots(pr, marker, onSnapshot)
+	cancel()
+	pr.Close()
+	execErr := <-execDone
+
+	if readErr != nil {
+		return readErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(execErr, &exitErr) {
+		return fsResultError("watch path", &ExecResult{ExitCode: exitErr.ExitStatus(), Stderr: stderr.String()})
+	}
+	if execErr != nil {
+		return fmt.Errorf("failed to watch path: %w", execErr)
+	}
+	return nil
+}
+
+// readWatchSnapshots decodes the output of fsWatchScript. Lines that do not parse,
+// such as the continuation of a file name containing a newline, are skipped.
+func readWatchSnapshots(r io.Reader, marker string, onSnapshot func(map[string]FileStat) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	snapshot := make(map[string]FileStat)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == marker {
+			if err := onSnapshot(snapshot); err != nil {
+				return err
+			}
+			snapshot = make(map[string]FileStat, len(snapshot))
+			continue
+		}
+		stat, ok := parseWatchLine(line)
+		if !ok {
+			continue
+		}
+		if len(snapshot) >= fsWatchMaxEntries {
+			return ErrTooManyEntries
+		}
+		snapshot[stat.Path] = stat
+	}
+	return scanner.Err()
+}
+
+func parseWatchLine(line string) (FileStat, bool) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 || fields[3] == "" {
+		return FileStat{}, false
+	}
+	mode, err := strconv.ParseUint(fields[0], 16, 32)
+	if err != nil {
+		return FileStat{}, false
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return FileStat{}, false
+	}
+	mtime, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return FileStat{}, false
+	}
+	return FileStat{
+		Name:    path.Base(fields[3]),
+		Path:    fields[3],
+		Mode:    uint32(mode),
+		Size:    size,
+		ModTime: time.Unix(mtime, 0).UTC(),
+	}, true
+}
@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestFSWatchScriptReportsSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	const marker = "--test-marker--"
+	cmd := exec.Command("sh", "-c", fsWatchScript, "liteboxd-test", dir, "1", marker)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	errStop := errors.New("stop")
+	var snapshots []map[string]FileStat
+	err = readWatchSnapshots(stdout, marker, func(snapshot map[string]FileStat) error {
+		snapshots = append(snapshots, snapshot)
+		if len(snapshots) == 1 {
+			if err := os.WriteFile(filepath.Join(dir, "sub", "b.txt"), nil, 0o644); err != nil {
+				t.Error(err)
+			}
+			return nil
+		}
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("readWatchSnapshots() error = %v, want errStop", err)
+	}
+
+	first := snapshots[0]
+	if len(first) != 3 {
+		t.Fatalf("first snapshot = %+v, want root, a.txt and sub", first)
+	}
+	if s := first[filepath.Join(dir, "a.txt")]; s.Name != "a.txt" || s.Size != 3 || s.Mode&0o170000 != 0o100000 {
+		t.Fatalf("a.txt entry = %+v", s)
+	}
+	if _, ok := snapshots[1][filepath.Join(dir, "sub", "b.txt")]; !ok {
+		t.Fatalf("second snapshot missing new file: %+v", snapshots[1])
+	}
+}
+
+func TestFSWatchScriptMissingRoot(t *testing.T) {
+	result := runFSScript(t, fsWatchScript, filepath.Join(t.TempDir(), "missing"), "1", "marker")
+	if err := fsResultError("watch path", result); !errors.Is(err, ErrPathNotFound) {
+		t.Fatalf("watch(missing) error = %v, want ErrPathNotFound", err)
+	}
+}
+
+func TestParseWatchLine(t *testing.T) {
+	stat, ok := parseWatchLine("81a4 12 1700000000 /workspace/with space.txt")
+	if !ok || stat.Path != "/workspace/with space.txt" || stat.Name != "with space.txt" || stat.Size != 12 || stat.Mode != 0x81a4 {
+		t.Fatalf("parseWatchLine() = %+v, %v", stat, ok)
+	}
+	for _, line := range []string{"", "name continuation", "zz 1 2 /x", "81a4 1 2 "} {
+		if _, ok := parseWatchLine(line); ok {
+			t.Fatalf("parseWatchLine(%q) should fail", line)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LabelProject marks the namespace of a project with the project name.
+const LabelProject = "liteboxd.io/project"
+
+// NamespaceResolver returns the namespace a sandbox runs in, or "" when it is unknown.
+type NamespaceResolver func(ctx context.Context, sandboxID string) (string, error)
+
+type namespaceKey struct{}
+
+// WithNamespace returns a context whose Kubernetes calls target namespace instead of
+// the namespace the sandbox is resolved to. Empty namespaces are ignored.
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	if namespace == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// SetNamespaceResolver sets how the namespace of a sandbox is looked up. Without one,
+// every sandbox is in the default sandbox namespace.
+func (c *Client) SetNamespaceResolver(resolve NamespaceResolver) {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+	c.resolveNS = resolve
+}
+
+// namespace returns the namespace set on ctx, or the default sandbox namespace.
+func (c *Client) namespace(ctx context.Context) string {
+	if ns, ok := ctx.Value(namespaceKey{}).(string); ok {
+		return ns
+	}
+	return c.sandboxNS
+}
+
+// namespaceOf returns the namespace of a sandbox: the one set on ctx, else the
+// resolved one, else the default sandbox namespace.
+func (c *Client) namespaceOf(ctx context.Context, sandboxID string) string {
+	if ns, ok := ctx.Value(namespaceKey{}).(string); ok {
+		return ns
+	}
+	c.nsMu.RLock()
+	resolve := c.resolveNS
+	c.nsMu.RUnlock()
+	if resolve != nil && sandboxID != "" {
+		if ns, err := resolve(ctx, sandboxID); err == nil && ns != "" {
+			return ns
+		}
+	}
+	return c.sandboxNS
+}
+
+// SandboxNamespaceOf returns the namespace a sandbox runs in.
+func (c *Client) SandboxNamespaceOf(ctx context.Context, sandboxID string) string {
+	return c.namespaceOf(ctx, sandboxID)
+}
+
+// WithSandboxNamespace pins the namespace of a sandbox on ctx, so that calls that
+// address its objects by name, such as its PVC or snapshots, stay in that namespace.
+func (c *Client) WithSandboxNamespace(ctx context.Context, sandboxID string) context.Context {
+	return WithNamespace(ctx, c.namespaceOf(ctx, sandboxID))
+}
+
+// AddSandboxNamespace registers the namespace of a project, so that loops over all
+// sandboxes include it.
+func (c *Client) AddSandboxNamespace(namespace string) {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+	if c.projectNS == nil {
+		c.projectNS = make(map[string]struct{})
+	}
+	c.projectNS[namespace] = struct{}{}
+}
+
+// RemoveSandboxNamespace unregisters the namespace of a deleted project.
+func (c *Client) RemoveSandboxNamespace(namespace string) {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+	delete(c.projectNS, namespace)
+}
+
+// SandboxNamespaces returns the default sandbox namespace followed by the namespaces
+// of all projects.
+func (c *Client) SandboxNamespaces() []string {
+	c.nsMu.RLock()
+	defer c.nsMu.RUnlock()
+	projects := make([]string, 0, len(c.projectNS))
+	for ns := range c.projectNS {
+		if ns != c.sandboxNS {
+			projects = append(projects, ns)
+		}
+	}
+	sort.Strings(projects)
+	return append([]string{c.sandboxNS}, projects...)
+}
+
+// IsSandboxNamespace reports whether sandboxes run in namespace.
+func (c *Client) IsSandboxNamespace(namespace string) bool {
+	if namespace == c.sandboxNS {
+		return true
+	}
+	c.nsMu.RLock()
+	defer c.nsMu.RUnlock()
+	_, ok := c.projectNS[namespace]
+	return ok
+}
+
+// EnsureProjectNamespace creates the namespace of a project when it doesn't exist yet
+// and registers it.
+func (c *Client) EnsureProjectNamespace(ctx context.Context, project, namespace string) error {
+	if err := c.ensureNamespace(ctx, namespace, map[string]string{
+		LabelManagedBy: ManagedByServer,
+		LabelProject:   project,
+	}); err != nil {
+		return err
+	}
+	c.AddSandboxNamespace(namespace)
+	return nil
+}
+
+// DeleteProjectNamespace unregisters the namespace of a project and deletes it along
+// with everything left in it.
+func (c *Client) DeleteProjectNamespace(ctx context.Context, namespace string) error {
+	if namespace == c.sandboxNS {
+		return fmt.Errorf("refusing to delete the default sandbox namespace %s", namespace)
+	}
+	c.RemoveSandboxNamespace(namespace)
+	err := c.clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete namespace %s: %w", namespace, err)
+	}
+	return nil
+}
+
+func (c *Client) ensureNamespace(ctx context.Context, name string, labels map[string]string) error {
+	_, err := c.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+	_, err = c.clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
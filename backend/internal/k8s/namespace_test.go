@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProjectSandboxesRunInProjectNamespace(t *testing.T) {
+	ctx := context.Background()
+	client := NewClientForTest()
+	client.SetNamespaceResolver(func(ctx context.Context, sandboxID string) (string, error) {
+		if sandboxID == "team" {
+			return "liteboxd-team-a", nil
+		}
+		return "", nil
+	})
+	if err := client.EnsureProjectNamespace(ctx, "team-a", "liteboxd-team-a"); err != nil {
+		t.Fatalf("EnsureProjectNamespace() error = %v", err)
+	}
+
+	ns, err := client.clientset.CoreV1().Namespaces().Get(ctx, "liteboxd-team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get namespace error = %v", err)
+	}
+	if ns.Labels[LabelProject] != "team-a" || ns.Labels[LabelManagedBy] != ManagedByServer {
+		t.Fatalf("namespace labels = %v", ns.Labels)
+	}
+
+	for _, id := range []string{"plain", "team"} {
+		if _, err := client.CreatePod(ctx, CreatePodOptions{ID: id, Image: "busybox", CPU: "100m", Memory: "64Mi"}); err != nil {
+			t.Fatalf("CreatePod(%s) error = %v", id, err)
+		}
+	}
+	if _, err := client.clientset.CoreV1().Pods("liteboxd-team-a").Get(ctx, "sandbox-team", metav1.GetOptions{}); err != nil {
+		t.Fatalf("project pod not in project namespace: %v", err)
+	}
+	if _, err := client.clientset.CoreV1().Pods(DefaultSandboxNamespace).Get(ctx, "sandbox-plain", metav1.GetOptions{}); err != nil {
+		t.Fatalf("pod not in default namespace: %v", err)
+	}
+
+	pods, err := client.ListPods(ctx)
+	if err != nil {
+		t.Fatalf("ListPods() error = %v", err)
+	}
+	if len(pods.Items) != 2 {
+		t.Fatalf("ListPods() returned %d pods, want 2", len(pods.Items))
+	}
+
+	if err := client.DeletePod(ctx, "team"); err != nil {
+		t.Fatalf("DeletePod() error = %v", err)
+	}
+	if err := client.DeleteProjectNamespace(ctx, DefaultSandboxNamespace); err == nil {
+		t.Fatal("DeleteProjectNamespace() deleted the default namespace")
+	}
+	if err := client.DeleteProjectNamespace(ctx, "liteboxd-team-a"); err != nil {
+		t.Fatalf("DeleteProjectNamespace() error = %v", err)
+	}
+	if client.IsSandboxNamespace("liteboxd-team-a") {
+		t.Fatal("deleted project namespace is still registered")
+	}
+}
@@ -0,0 +1,215 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// LabelNetworkGroup marks the pods, policies and peer services of a network group.
+const LabelNetworkGroup = "liteboxd.io/network-group"
+
+// PeerServiceName is the headless service name, and so the in-namespace DNS name, other
+// members of a network group reach a sandbox at.
+func PeerServiceName(sandboxID string) string {
+	return "peer-" + sandboxID
+}
+
+func networkGroupPolicyName(sandboxID string) string {
+	return "sandbox-group-" + sandboxID
+}
+
+// ApplyNetworkGroup lets a sandbox reach the other members of its network group and be
+// reached by them on ports, and publishes it under PeerServiceName. Without ports the
+// sandbox only makes outbound connections to its peers.
+func (m *NetworkPolicyManager) ApplyNetworkGroup(ctx context.Context, sandboxID, group string, ports []PortRule) error {
+	ctx = m.client.WithSandboxNamespace(ctx, sandboxID)
+	if err := m.ensurePolicy(ctx, m.networkGroupPolicy(sandboxID, group, ports)); err != nil {
+		return fmt.Errorf("failed to apply network group policy: %w", err)
+	}
+	if err := m.ensurePeerService(ctx, sandboxID, group, ports); err != nil {
+		return fmt.Errorf("failed to apply peer service: %w", err)
+	}
+	return nil
+}
+
+// DeleteNetworkGroup removes the network group policy and peer service of a sandbox.
+func (m *NetworkPolicyManager) DeleteNetworkGroup(ctx context.Context, sandboxID string) error {
+	namespace := m.client.namespaceOf(ctx, sandboxID)
+	err := m.client.clientset.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, networkGroupPolicyName(sandboxID), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete network group policy: %w", err)
+	}
+	err = m.client.clientset.CoreV1().Services(namespace).Delete(ctx, PeerServiceName(sandboxID), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete peer service: %w", err)
+	}
+	return nil
+}
+
+// ListNetworkGroupMembers returns the IDs of sandboxes that have a network group policy
+// or peer service, in any sandbox namespace.
+func (m *NetworkPolicyManager) ListNetworkGroupMembers(ctx context.Context) ([]string, error) {
+	opts := metav1.ListOptions{LabelSelector: LabelNetworkGroup + "," + LabelManagedBy + "=" + ManagedByServer}
+
+	seen := make(map[string]struct{})
+	var ids []string
+	add := func(labels map[string]string) {
+		id := labels[LabelSandboxID]
+		if _, ok := seen[id]; id == "" || ok {
+			return
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	for _, ns := range m.client.SandboxNamespaces() {
+		policies, err := m.client.clientset.NetworkingV1().NetworkPolicies(ns).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list network group policies: %w", err)
+		}
+		services, err := m.client.clientset.CoreV1().Services(ns).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list peer services: %w", err)
+		}
+		for i := range policies.Items {
+			add(policies.Items[i].Labels)
+		}
+		for i := range services.Items {
+			add(services.Items[i].Labels)
+		}
+	}
+	return ids, nil
+}
+
+func networkGroupLabels(sandboxID, group string) map[string]string {
+	return map[string]string{
+		LabelSandboxID:    sandboxID,
+		LabelNetworkGroup: group,
+		LabelManagedBy:    ManagedByServer,
+	}
+}
+
+// networkGroupPolicy allows egress to every member of the group and ingress from them
+// on the sandbox's own ports. Peers enforce their ports on their side.
+func (m *NetworkPolicyManager) networkGroupPolicy(sandboxID, group string, ports []PortRule) *networkingv1.NetworkPolicy {
+	peers := []networkingv1.NetworkPolicyPeer{
+		{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app":             LabelApp,
+					LabelNetworkGroup: group,
+				},
+			},
+		},
+	}
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   networkGroupPolicyName(sandboxID),
+			Labels: networkGroupLabels(sandboxID, group),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app":          LabelApp,
+					LabelSandboxID: sandboxID,
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{
+				networkingv1.PolicyTypeIngress,
+				networkingv1.PolicyTypeEgress,
+			},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{To: peers},
+			},
+		},
+	}
+	if len(ports) > 0 {
+		policy.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{
+			{
+				From:  peers,
+				Ports: networkPolicyPorts(ports),
+			},
+		}
+	}
+	return policy
+}
+
+func (m *NetworkPolicyManager) ensurePeerService(ctx context.Context, sandboxID, group string, ports []PortRule) error {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   PeerServiceName(sandboxID),
+			Labels: networkGroupLabels(sandboxID, group),
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector: map[string]string{
+				"app":          LabelApp,
+				LabelSandboxID: sandboxID,
+			},
+			Ports: servicePorts(ports),
+			// Peers can connect as soon as the pod has an address
+			PublishNotReadyAddresses: true,
+		},
+	}
+
+	services := m.client.clientset.CoreV1().Services(m.client.namespace(ctx))
+	existing, err := services.Get(ctx, service.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			_, err = services.Create(ctx, service, metav1.CreateOptions{})
+		}
+		return err
+	}
+	existing.Labels = service.Labels
+	existing.Spec.Selector = service.Spec.Selector
+	existing.Spec.Ports = service.Spec.Ports
+	existing.Spec.PublishNotReadyAddresses = true
+	_, err = services.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// protocolsOf expands a port rule to the Kubernetes protocols it covers.
+func protocolsOf(rule PortRule) []corev1.Protocol {
+	switch strings.ToUpper(rule.Protocol) {
+	case "UDP":
+		return []corev1.Protocol{corev1.ProtocolUDP}
+	case "ANY":
+		return []corev1.Protocol{corev1.ProtocolTCP, corev1.ProtocolUDP}
+	default:
+		return []corev1.Protocol{corev1.ProtocolTCP}
+	}
+}
+
+func networkPolicyPorts(ports []PortRule) []networkingv1.NetworkPolicyPort {
+	var out []networkingv1.NetworkPolicyPort
+	for _, rule := range ports {
+		for _, protocol := range protocolsOf(rule) {
+			out = append(out, networkingv1.NetworkPolicyPort{
+				Protocol: &protocol,
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: int32(rule.Port)},
+			})
+		}
+	}
+	return out
+}
+
+func servicePorts(ports []PortRule) []corev1.ServicePort {
+	var out []corev1.ServicePort
+	for _, rule := range ports {
+		for _, protocol := range protocolsOf(rule) {
+			out = append(out, corev1.ServicePort{
+				Name:       fmt.Sprintf("%s-%d", strings.ToLower(string(protocol)), rule.Port),
+				Protocol:   protocol,
+				Port:       int32(rule.Port),
+				TargetPort: intstr.FromInt32(int32(rule.Port)),
+			})
+		}
+	}
+	return out
+}
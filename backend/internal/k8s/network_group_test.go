@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyNetworkGroupCreatesPolicyAndPeerService(t *testing.T) {
+	ctx := context.Background()
+	client := NewClientForTest()
+	manager := NewNetworkPolicyManager(client)
+
+	ports := []PortRule{{Port: 8080, Protocol: "TCP"}, {Port: 5353, Protocol: "ANY"}}
+	if err := manager.ApplyNetworkGroup(ctx, "srv1", "agents", ports); err != nil {
+		t.Fatalf("ApplyNetworkGroup() error = %v", err)
+	}
+
+	policy, err := client.clientset.NetworkingV1().NetworkPolicies(client.sandboxNS).Get(ctx, networkGroupPolicyName("srv1"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get policy error = %v", err)
+	}
+	if policy.Spec.PodSelector.MatchLabels[LabelSandboxID] != "srv1" {
+		t.Fatalf("policy selects %v, want sandbox srv1", policy.Spec.PodSelector.MatchLabels)
+	}
+	if len(policy.Spec.Ingress) != 1 || len(policy.Spec.Ingress[0].Ports) != 3 {
+		t.Fatalf("ingress = %+v, want one rule with TCP 8080 and TCP/UDP 5353", policy.Spec.Ingress)
+	}
+	if peer := policy.Spec.Ingress[0].From[0].PodSelector.MatchLabels[LabelNetworkGroup]; peer != "agents" {
+		t.Fatalf("ingress peer group = %q, want agents", peer)
+	}
+	if len(policy.Spec.Egress) != 1 || policy.Spec.Egress[0].To[0].PodSelector.MatchLabels[LabelNetworkGroup] != "agents" {
+		t.Fatalf("egress = %+v, want the agents group", policy.Spec.Egress)
+	}
+
+	svc, err := client.clientset.CoreV1().Services(client.sandboxNS).Get(ctx, PeerServiceName("srv1"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get service error = %v", err)
+	}
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone || svc.Spec.Selector[LabelSandboxID] != "srv1" || len(svc.Spec.Ports) != 3 {
+		t.Fatalf("peer service = %+v, want a headless service for srv1 with 3 ports", svc.Spec)
+	}
+
+	// Without ports the sandbox only connects out to its peers
+	if err := manager.ApplyNetworkGroup(ctx, "srv1", "agents", nil); err != nil {
+		t.Fatalf("ApplyNetworkGroup() update error = %v", err)
+	}
+	policy, _ = client.clientset.NetworkingV1().NetworkPolicies(client.sandboxNS).Get(ctx, networkGroupPolicyName("srv1"), metav1.GetOptions{})
+	if len(policy.Spec.Ingress) != 0 {
+		t.Fatalf("ingress after dropping ports = %+v, want none", policy.Spec.Ingress)
+	}
+
+	members, err := manager.ListNetworkGroupMembers(ctx)
+	if err != nil || len(members) != 1 || members[0] != "srv1" {
+		t.Fatalf("ListNetworkGroupMembers() = %v, %v", members, err)
+	}
+	if err := manager.DeleteNetworkGroup(ctx, "srv1"); err != nil {
+		t.Fatalf("DeleteNetworkGroup() error = %v", err)
+	}
+	if _, err := client.clientset.CoreV1().Services(client.sandboxNS).Get(ctx, PeerServiceName("srv1"), metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("peer service still exists: %v", err)
+	}
+	if err := manager.DeleteNetworkGroup(ctx, "srv1"); err != nil {
+		t.Fatalf("DeleteNetworkGroup() twice error = %v", err)
+	}
+}
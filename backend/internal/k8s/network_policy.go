@@ -3,6 +3,7 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -15,11 +16,30 @@ import (
 )
 
 const (
-	// Annotation and label constants for network access
-	AnnotationAccessToken = "liteboxd.io/access-token"
-	LabelInternetAccess   = "liteboxd.io/internet-access"
+	// Label constants for network access
+	LabelInternetAccess = "liteboxd.io/internet-access"
+
+	// Traffic limits, read by the CNI bandwidth plugin (or Cilium's bandwidth manager)
+	// when the pod's network is set up
+	AnnotationEgressBandwidth  = "kubernetes.io/egress-bandwidth"
+	AnnotationIngressBandwidth = "kubernetes.io/ingress-bandwidth"
 )
 
+// privateCIDRs are excluded from internet egress.
+var privateCIDRs = []string{
+	"10.0.0.0/8",     // Private network
+	"172.16.0.0/12",  // Private network
+	"192.168.0.0/16", // Private network
+	"127.0.0.0/8",    // Loopback
+	"169.254.0.0/16", // Link-local
+}
+
+// defaultInternetPorts are the internet ports of a network without explicit ports.
+var defaultInternetPorts = []PortRule{
+	{Port: 443, Protocol: "TCP"},
+	{Port: 80, Protocol: "TCP"},
+}
+
 var ciliumPolicyGVR = schema.GroupVersionResource{
 	Group:    "cilium.io",
 	Version:  "v2",
@@ -36,13 +56,25 @@ func NewNetworkPolicyManager(client *Client) *NetworkPolicyManager {
 	return &NetworkPolicyManager{client: client}
 }
 
-// EnsureDefaultPolicies ensures all base network policies are applied
+// EnsureDefaultPolicies ensures all base network policies are applied in every
+// sandbox namespace
 func (m *NetworkPolicyManager) EnsureDefaultPolicies(ctx context.Context) error {
 	// Ensure namespace exists first
 	if err := m.client.EnsureNamespace(ctx); err != nil {
 		return fmt.Errorf("failed to ensure namespace: %w", err)
 	}
 
+	for _, ns := range m.client.SandboxNamespaces() {
+		if err := m.EnsureNamespacePolicies(ctx, ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureNamespacePolicies applies the base network policies in one sandbox namespace
+func (m *NetworkPolicyManager) EnsureNamespacePolicies(ctx context.Context, namespace string) error {
+	ctx = WithNamespace(ctx, namespace)
 	policies := []struct {
 		name string
 		spec *networkingv1.NetworkPolicy
@@ -71,19 +103,20 @@ func (m *NetworkPolicyManager) EnsureDefaultPolicies(ctx context.Context) error
 
 	for _, p := range policies {
 		if err := m.ensurePolicy(ctx, p.spec); err != nil {
-			return fmt.Errorf("failed to ensure policy %s: %w", p.name, err)
+			return fmt.Errorf("failed to ensure policy %s in %s: %w", p.name, namespace, err)
 		}
 	}
 
 	return nil
 }
 
-// ensurePolicy creates or updates a network policy
+// ensurePolicy creates or updates a network policy in the namespace set on ctx
 func (m *NetworkPolicyManager) ensurePolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) error {
-	existing, err := m.client.clientset.NetworkingV1().NetworkPolicies(m.client.sandboxNS).Get(ctx, policy.Name, metav1.GetOptions{})
+	policies := m.client.clientset.NetworkingV1().NetworkPolicies(m.client.namespace(ctx))
+	existing, err := policies.Get(ctx, policy.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			_, err = m.client.clientset.NetworkingV1().NetworkPolicies(m.client.sandboxNS).Create(ctx, policy, metav1.CreateOptions{})
+			_, err = policies.Create(ctx, policy, metav1.CreateOptions{})
 			return err
 		}
 		return err
@@ -91,7 +124,7 @@ func (m *NetworkPolicyManager) ensurePolicy(ctx context.Context, policy *network
 
 	// Update existing policy
 	policy.ResourceVersion = existing.ResourceVersion
-	_, err = m.client.clientset.NetworkingV1().NetworkPolicies(m.client.sandboxNS).Update(ctx, policy, metav1.UpdateOptions{})
+	_, err = policies.Update(ctx, policy, metav1.UpdateOptions{})
 	return err
 }
 
@@ -277,14 +310,8 @@ func (m *NetworkPolicyManager) allowInternetEgressPolicy() *networkingv1.Network
 					To: []networkingv1.NetworkPolicyPeer{
 						{
 							IPBlock: &networkingv1.IPBlock{
-								CIDR: "0.0.0.0/0",
-								Except: []string{
-									"10.0.0.0/8",     // Private network
-									"172.16.0.0/12",  // Private network
-									"192.168.0.0/16", // Private network
-									"127.0.0.0/8",    // Loopback
-									"169.254.0.0/16", // Link-local
-								},
+								CIDR:   "0.0.0.0/0",
+								Except: privateCIDRs,
 							},
 						},
 					},
@@ -341,12 +368,64 @@ func (m *NetworkPolicyManager) DenyInternetAccess(ctx context.Context, sandboxID
 	return m.setInternetAccessLabel(ctx, sandboxID, "false")
 }
 
+// UsesInternetLabel reports whether the network is plain open internet access, which
+// the shared label-based policy grants. Anything finer needs a per-sandbox egress policy.
+func (n *NetworkSpec) UsesInternetLabel() bool {
+	return n != nil && n.AllowInternetAccess && !n.NeedsEgressPolicy()
+}
+
+// NeedsEgressPolicy reports whether the network needs a per-sandbox CiliumNetworkPolicy.
+func (n *NetworkSpec) NeedsEgressPolicy() bool {
+	if n == nil {
+		return false
+	}
+	if len(n.AllowedCIDRs) > 0 || len(n.DeniedCIDRs) > 0 {
+		return true
+	}
+	return n.AllowInternetAccess && (n.domainRestricted() || len(n.Ports) > 0)
+}
+
+// LimitAnnotations returns the pod annotations that apply the network's traffic limits.
+// The limits take effect when the pod starts and cannot change on a running pod.
+func (n *NetworkSpec) LimitAnnotations() map[string]string {
+	annotations := make(map[string]string)
+	if n == nil {
+		return annotations
+	}
+	if n.EgressBandwidth != "" {
+		annotations[AnnotationEgressBandwidth] = n.EgressBandwidth
+	}
+	if n.IngressBandwidth != "" {
+		annotations[AnnotationIngressBandwidth] = n.IngressBandwidth
+	}
+	return annotations
+}
+
+// domainRestricted reports whether internet egress is limited to some domains.
+func (n *NetworkSpec) domainRestricted() bool {
+	return len(n.AllowedDomains) > 0 || len(n.DomainRules) > 0
+}
+
+func (n *NetworkSpec) internetPorts() []PortRule {
+	if len(n.Ports) == 0 {
+		return defaultInternetPorts
+	}
+	return n.Ports
+}
+
+// ApplyDomainAllowlistPolicy allows a sandbox to reach the given domains on TCP 80 and 443.
 func (m *NetworkPolicyManager) ApplyDomainAllowlistPolicy(ctx context.Context, sandboxID string, domains []string) error {
 	if len(domains) == 0 {
 		return nil
 	}
-	policy := m.domainAllowlistPolicy(sandboxID, domains)
-	resource := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(m.client.sandboxNS)
+	return m.ApplyEgressPolicy(ctx, sandboxID, &NetworkSpec{AllowInternetAccess: true, AllowedDomains: domains})
+}
+
+// ApplyEgressPolicy creates or updates the per-sandbox egress policy for a network.
+func (m *NetworkPolicyManager) ApplyEgressPolicy(ctx context.Context, sandboxID string, network *NetworkSpec) error {
+	namespace := m.client.namespaceOf(ctx, sandboxID)
+	policy := m.egressPolicy(namespace, sandboxID, network)
+	resource := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(namespace)
 	existing, err := resource.Get(ctx, policy.GetName(), metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -361,27 +440,30 @@ func (m *NetworkPolicyManager) ApplyDomainAllowlistPolicy(ctx context.Context, s
 }
 
 func (m *NetworkPolicyManager) GetDomainAllowlistPolicy(ctx context.Context, sandboxID string) (*unstructured.Unstructured, error) {
-	resource := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(m.client.sandboxNS)
+	resource := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(m.client.namespaceOf(ctx, sandboxID))
 	return resource.Get(ctx, domainAllowlistPolicyName(sandboxID), metav1.GetOptions{})
 }
 
+// ListManagedDomainAllowlistPolicies lists the per-sandbox egress policies in all
+// sandbox namespaces.
 func (m *NetworkPolicyManager) ListManagedDomainAllowlistPolicies(ctx context.Context) ([]unstructured.Unstructured, error) {
-	resource := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(m.client.sandboxNS)
-	list, err := resource.List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
-	items := make([]unstructured.Unstructured, 0, len(list.Items))
-	for i := range list.Items {
-		if _, ok := ParseDomainAllowlistPolicyName(list.Items[i].GetName()); ok {
-			items = append(items, list.Items[i])
+	items := make([]unstructured.Unstructured, 0)
+	for _, ns := range m.client.SandboxNamespaces() {
+		list, err := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			if _, ok := ParseDomainAllowlistPolicyName(list.Items[i].GetName()); ok {
+				items = append(items, list.Items[i])
+			}
 		}
 	}
 	return items, nil
 }
 
 func (m *NetworkPolicyManager) DeleteDomainAllowlistPolicy(ctx context.Context, sandboxID string) error {
-	resource := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(m.client.sandboxNS)
+	resource := m.client.dynamicClient.Resource(ciliumPolicyGVR).Namespace(m.client.namespaceOf(ctx, sandboxID))
 	err := resource.Delete(ctx, domainAllowlistPolicyName(sandboxID), metav1.DeleteOptions{})
 	if errors.IsNotFound(err) {
 		return nil
@@ -405,15 +487,71 @@ func ParseDomainAllowlistPolicyName(name string) (string, bool) {
 	return sandboxID, true
 }
 
-func (m *NetworkPolicyManager) domainAllowlistPolicy(sandboxID string, domains []string) *unstructured.Unstructured {
-	var toFQDNs []interface{}
-	for _, domain := range domains {
-		if strings.HasPrefix(domain, "*.") {
-			toFQDNs = append(toFQDNs, map[string]interface{}{"matchPattern": domain})
-		} else {
-			toFQDNs = append(toFQDNs, map[string]interface{}{"matchName": domain})
+func (m *NetworkPolicyManager) egressPolicy(namespace, sandboxID string, network *NetworkSpec) *unstructured.Unstructured {
+	var egress []interface{}
+	if network.AllowInternetAccess {
+		if !network.domainRestricted() {
+			egress = append(egress, map[string]interface{}{
+				"toCIDRSet": []interface{}{
+					map[string]interface{}{"cidr": "0.0.0.0/0", "except": stringSlice(privateCIDRs)},
+				},
+				"toPorts": ciliumPorts(network.internetPorts()),
+			})
+		}
+		if len(network.AllowedDomains) > 0 {
+			egress = append(egress, map[string]interface{}{
+				"toFQDNs": ciliumFQDNs(network.AllowedDomains),
+				"toPorts": ciliumPorts(network.internetPorts()),
+			})
+		}
+		for _, rule := range network.DomainRules {
+			ports := rule.Ports
+			if len(ports) == 0 {
+				ports = network.internetPorts()
+			}
+			egress = append(egress, map[string]interface{}{
+				"toFQDNs": ciliumFQDNs(rule.Domains),
+				"toPorts": ciliumPorts(ports),
+			})
 		}
 	}
+	for _, rule := range network.AllowedCIDRs {
+		entry := map[string]interface{}{
+			"toCIDRSet": []interface{}{map[string]interface{}{"cidr": rule.CIDR}},
+		}
+		if len(rule.Ports) > 0 {
+			entry["toPorts"] = ciliumPorts(rule.Ports)
+		}
+		egress = append(egress, entry)
+	}
+	egress = append(egress, map[string]interface{}{
+		"toEndpoints": []interface{}{
+			map[string]interface{}{
+				"matchLabels": map[string]interface{}{
+					"k8s-app":                     "kube-dns",
+					"io.kubernetes.pod.namespace": "kube-system",
+				},
+			},
+		},
+		"toPorts": ciliumPorts([]PortRule{{Port: 53, Protocol: "UDP"}, {Port: 53, Protocol: "TCP"}}),
+	})
+
+	spec := map[string]interface{}{
+		"endpointSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				"app":          LabelApp,
+				LabelSandboxID: sandboxID,
+			},
+		},
+		"egress": egress,
+	}
+	if len(network.DeniedCIDRs) > 0 {
+		var cidrs []interface{}
+		for _, cidr := range network.DeniedCIDRs {
+			cidrs = append(cidrs, map[string]interface{}{"cidr": cidr})
+		}
+		spec["egressDeny"] = []interface{}{map[string]interface{}{"toCIDRSet": cidrs}}
+	}
 
 	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
@@ -421,56 +559,51 @@ func (m *NetworkPolicyManager) domainAllowlistPolicy(sandboxID string, domains [
 			"kind":       "CiliumNetworkPolicy",
 			"metadata": map[string]interface{}{
 				"name":      domainAllowlistPolicyName(sandboxID),
-				"namespace": m.client.sandboxNS,
-			},
-			"spec": map[string]interface{}{
-				"endpointSelector": map[string]interface{}{
-					"matchLabels": map[string]interface{}{
-						"app":          LabelApp,
-						LabelSandboxID: sandboxID,
-					},
-				},
-				"egress": []interface{}{
-					map[string]interface{}{
-						"toFQDNs": toFQDNs,
-						"toPorts": []interface{}{
-							map[string]interface{}{
-								"ports": []interface{}{
-									map[string]interface{}{"port": "443", "protocol": "TCP"},
-									map[string]interface{}{"port": "80", "protocol": "TCP"},
-								},
-							},
-						},
-					},
-					map[string]interface{}{
-						"toEndpoints": []interface{}{
-							map[string]interface{}{
-								"matchLabels": map[string]interface{}{
-									"k8s-app":                     "kube-dns",
-									"io.kubernetes.pod.namespace": "kube-system",
-								},
-							},
-						},
-						"toPorts": []interface{}{
-							map[string]interface{}{
-								"ports": []interface{}{
-									map[string]interface{}{"port": "53", "protocol": "UDP"},
-									map[string]interface{}{"port": "53", "protocol": "TCP"},
-								},
-							},
-						},
-					},
-				},
+				"namespace": namespace,
 			},
+			"spec": spec,
 		},
 	}
 }
 
+func ciliumFQDNs(domains []string) []interface{} {
+	var toFQDNs []interface{}
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			toFQDNs = append(toFQDNs, map[string]interface{}{"matchPattern": domain})
+		} else {
+			toFQDNs = append(toFQDNs, map[string]interface{}{"matchName": domain})
+		}
+	}
+	return toFQDNs
+}
+
+func ciliumPorts(ports []PortRule) []interface{} {
+	var items []interface{}
+	for _, port := range ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		items = append(items, map[string]interface{}{"port": strconv.Itoa(port.Port), "protocol": protocol})
+	}
+	return []interface{}{map[string]interface{}{"ports": items}}
+}
+
+func stringSlice(values []string) []interface{} {
+	items := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		items = append(items, v)
+	}
+	return items
+}
+
 // setInternetAccessLabel sets or removes the internet-access label on a pod
 func (m *NetworkPolicyManager) setInternetAccessLabel(ctx context.Context, sandboxID, value string) error {
 	podName := fmt.Sprintf("sandbox-%s", sandboxID)
+	pods := m.client.clientset.CoreV1().Pods(m.client.namespaceOf(ctx, sandboxID))
 
-	pod, err := m.client.clientset.CoreV1().Pods(m.client.sandboxNS).Get(ctx, podName, metav1.GetOptions{})
+	pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to get pod: %w", err)
 	}
@@ -485,6 +618,6 @@ func (m *NetworkPolicyManager) setInternetAccessLabel(ctx context.Context, sandb
 		pod.Labels[LabelInternetAccess] = value
 	}
 
-	_, err = m.client.clientset.CoreV1().Pods(m.client.sandboxNS).Update(ctx, pod, metav1.UpdateOptions{})
+	_, err = pods.Update(ctx, pod, metav1.UpdateOptions{})
 	return err
 }
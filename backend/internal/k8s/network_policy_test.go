@@ -160,3 +160,61 @@ func TestDomainAllowlistPolicyWithEmptyDomains(t *testing.T) {
 		t.Fatalf("expected not found error, got %v", err)
 	}
 }
+
+func TestApplyEgressPolicyRendersPortsAndCIDRs(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient()
+	manager := NewNetworkPolicyManager(client)
+
+	network := &NetworkSpec{
+		AllowInternetAccess: true,
+		Ports:               []PortRule{{Port: 8080, Protocol: "TCP"}},
+		DomainRules:         []DomainRule{{Domains: []string{"github.com"}, Ports: []PortRule{{Port: 22, Protocol: "TCP"}}}},
+		AllowedCIDRs:        []CIDRRule{{CIDR: "10.20.0.0/16", Ports: []PortRule{{Port: 5432, Protocol: "TCP"}}}},
+		DeniedCIDRs:         []string{"10.20.5.0/24"},
+	}
+	if network.UsesInternetLabel() || !network.NeedsEgressPolicy() {
+		t.Fatalf("network with rules should use an egress policy")
+	}
+	if err := manager.ApplyEgressPolicy(ctx, "rules", network); err != nil {
+		t.Fatalf("ApplyEgressPolicy error: %v", err)
+	}
+	policy, err := manager.GetDomainAllowlistPolicy(ctx, "rules")
+	if err != nil {
+		t.Fatalf("Get policy error: %v", err)
+	}
+
+	egress, _, _ := unstructured.NestedSlice(policy.Object, "spec", "egress")
+	// Domain rules restrict internet egress, so there is no open internet rule
+	if len(egress) != 3 {
+		t.Fatalf("expected domain, CIDR and DNS egress rules, got %d", len(egress))
+	}
+	domainRule := egress[0].(map[string]interface{})
+	if got := domainRule["toPorts"].([]interface{})[0].(map[string]interface{})["ports"].([]interface{})[0]; got.(map[string]interface{})["port"] != "22" {
+		t.Fatalf("domain rule ports = %v", got)
+	}
+	cidrRule := egress[1].(map[string]interface{})
+	if cidr := cidrRule["toCIDRSet"].([]interface{})[0].(map[string]interface{})["cidr"]; cidr != "10.20.0.0/16" {
+		t.Fatalf("CIDR rule = %v", cidrRule)
+	}
+	deny, found, _ := unstructured.NestedSlice(policy.Object, "spec", "egressDeny")
+	if !found || len(deny) != 1 {
+		t.Fatalf("missing egressDeny rule")
+	}
+
+	// Open internet on custom ports renders the world CIDR minus private ranges
+	open := &NetworkSpec{AllowInternetAccess: true, Ports: []PortRule{{Port: 8080, Protocol: "TCP"}}}
+	if err := manager.ApplyEgressPolicy(ctx, "open", open); err != nil {
+		t.Fatalf("ApplyEgressPolicy error: %v", err)
+	}
+	policy, _ = manager.GetDomainAllowlistPolicy(ctx, "open")
+	egress, _, _ = unstructured.NestedSlice(policy.Object, "spec", "egress")
+	world := egress[0].(map[string]interface{})["toCIDRSet"].([]interface{})[0].(map[string]interface{})
+	if world["cidr"] != "0.0.0.0/0" || len(world["except"].([]interface{})) != len(privateCIDRs) {
+		t.Fatalf("open internet rule = %v", world)
+	}
+
+	if !(&NetworkSpec{AllowInternetAccess: true}).UsesInternetLabel() {
+		t.Fatalf("plain internet access should use the shared label-based policy")
+	}
+}
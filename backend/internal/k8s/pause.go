@@ -0,0 +1,161 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podCheckpointScript writes the root filesystem of the container as a tar.gz archive
+// to stdout. Kernel filesystems and the files kubelet mounts into every pod are left
+// out, since the recreated pod gets fresh ones.
+const podCheckpointScript = `set +e
+tar -czpf - -C / \
+	--exclude=./proc --exclude=./sys --exclude=./dev \
+	--exclude=./etc/hosts --exclude=./etc/hostname --exclude=./etc/resolv.conf \
+	--exclude=./run/secrets \
+	.
+status=$?
+# Exit status 1 means some files changed while being read, which is expected for a
+# running sandbox.
+[ "$status" -le 1 ] || exit "$status"
+`
+
+// podRestoreScript extracts a checkpoint archive read from stdin over the root
+// filesystem. Files the container user cannot replace, such as image files owned by
+// root in a non-root container, are reported on stderr and skipped: the container
+// could not have changed them either.
+const podRestoreScript = `set +e
+tar -xzpf - -C /
+status=$?
+[ "$status" -le 2 ] || exit "$status"
+`
+
+// CheckpointPod streams the root filesystem of an ephemeral sandbox to w and returns
+// the manifest RecreatePod needs to bring the pod back. Process state is not kept.
+func (c *Client) CheckpointPod(ctx context.Context, sandboxID string, w io.Writer) ([]byte, error) {
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, fmt.Errorf("sandbox pod is %s, not running", pod.Status.Phase)
+	}
+
+	var stderr bytes.Buffer
+	err = c.execInteractiveInPod(ctx, pod, ExecInteractiveOptions{
+		Command: []string{"sh", "-c", podCheckpointScript},
+		Stdout:  w,
+		Stderr:  &stderr,
+	})
+	if err := execStreamError("checkpoint sandbox filesystem", err, &stderr); err != nil {
+		return nil, err
+	}
+	return podManifest(pod)
+}
+
+// podManifest returns the pod as it should be created again: the same metadata and
+// spec without anything assigned by the cluster.
+func podManifest(pod *corev1.Pod) ([]byte, error) {
+	manifest := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			Labels:      pod.Labels,
+			Annotations: pod.Annotations,
+		},
+		Spec: *pod.Spec.DeepCopy(),
+	}
+	manifest.Spec.NodeName = ""
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pod manifest: %w", err)
+	}
+	return data, nil
+}
+
+// DeletePodAndWait deletes the pod of a sandbox and waits until it is gone, so a pod
+// with the same name can be created again.
+func (c *Client) DeletePodAndWait(ctx context.Context, sandboxID string) error {
+	if err := c.DeletePod(ctx, sandboxID); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete sandbox pod: %w", err)
+	}
+	name := fmt.Sprintf("sandbox-%s", sandboxID)
+	namespace := c.namespaceOf(ctx, sandboxID)
+	for {
+		_, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get sandbox pod: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for pod deletion: %w", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// RecreatePod creates a pod from a manifest returned by CheckpointPod.
+func (c *Client) RecreatePod(ctx context.Context, manifest []byte) (*corev1.Pod, error) {
+	var pod corev1.Pod
+	if err := json.Unmarshal(manifest, &pod); err != nil {
+		return nil, fmt.Errorf("failed to decode pod manifest: %w", err)
+	}
+	pod.Namespace = c.namespaceOf(ctx, pod.Labels[LabelSandboxID])
+	return c.clientset.CoreV1().Pods(pod.Namespace).Create(ctx, &pod, metav1.CreateOptions{})
+}
+
+// WaitForPodRunning waits until the main container of a sandbox pod has started.
+func (c *Client) WaitForPodRunning(ctx context.Context, sandboxID string) (*corev1.Pod, error) {
+	for {
+		pod, err := c.getSandboxPod(ctx, sandboxID)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if pod != nil {
+			switch pod.Status.Phase {
+			case corev1.PodRunning:
+				return pod, nil
+			case corev1.PodFailed, corev1.PodSucceeded:
+				return nil, fmt.Errorf("sandbox pod is %s", pod.Status.Phase)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for pod to start: %w", ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// RestorePodCheckpoint extracts a checkpoint archive written by CheckpointPod into the
+// running sandbox. It returns what tar reported about files it skipped.
+func (c *Client) RestorePodCheckpoint(ctx context.Context, sandboxID string, archive io.Reader) (string, error) {
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	err = c.execInteractiveInPod(ctx, pod, ExecInteractiveOptions{
+		Command: []string{"sh", "-c", podRestoreScript},
+		Stdin:   archive,
+		Stderr:  &stderr,
+	})
+	if err := execStreamError("restore sandbox filesystem", err, &stderr); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stderr.String()), nil
+}
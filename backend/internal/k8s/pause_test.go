@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodManifestRecreatesPod(t *testing.T) {
+	ctx := context.Background()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "sandbox-abc",
+			Namespace:       DefaultSandboxNamespace,
+			UID:             "uid-1",
+			ResourceVersion: "42",
+			Labels:          map[string]string{"app": LabelApp, LabelSandboxID: "abc"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "main", Image: "python:3.11-slim"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.5"},
+	}
+	client := NewClientForTest(pod)
+
+	manifest, err := podManifest(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeletePodAndWait(ctx, "abc"); err != nil {
+		t.Fatalf("DeletePodAndWait() error = %v", err)
+	}
+	if _, err := client.getSandboxPod(ctx, "abc"); !apierrors.IsNotFound(err) {
+		t.Fatalf("pod still present after delete: %v", err)
+	}
+
+	created, err := client.RecreatePod(ctx, manifest)
+	if err != nil {
+		t.Fatalf("RecreatePod() error = %v", err)
+	}
+	if created.Name != "sandbox-abc" || created.Labels[LabelSandboxID] != "abc" {
+		t.Fatalf("recreated pod metadata = %s %v", created.Name, created.Labels)
+	}
+	if created.UID != "" || created.ResourceVersion == "42" || created.Spec.NodeName != "" {
+		t.Fatalf("cluster-assigned fields were kept: uid=%q rv=%q node=%q", created.UID, created.ResourceVersion, created.Spec.NodeName)
+	}
+	if created.Status.PodIP != "" || created.Spec.Containers[0].Image != "python:3.11-slim" {
+		t.Fatalf("unexpected recreated pod: %+v", created)
+	}
+}
@@ -29,7 +29,7 @@ type PersistentSandboxSnapshot struct {
 }
 
 func (c *Client) GetDeployment(ctx context.Context, name string) (*appsv1.Deployment, error) {
-	deploy, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Get(ctx, name, metav1.GetOptions{})
+	deploy, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +37,7 @@ func (c *Client) GetDeployment(ctx context.Context, name string) (*appsv1.Deploy
 }
 
 func (c *Client) GetPersistentVolumeClaim(ctx context.Context, name string) (*corev1.PersistentVolumeClaim, error) {
-	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).Get(ctx, name, metav1.GetOptions{})
+	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace(ctx)).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +74,8 @@ func (c *Client) GetPersistentSandboxSnapshot(ctx context.Context, sandboxID, de
 		DeploymentName: deploymentName,
 		PVCName:        pvcName,
 	}
+	ctx = c.WithSandboxNamespace(ctx, sandboxID)
+	namespace := c.namespace(ctx)
 
 	if deploymentName != "" {
 		deploy, err := c.GetDeployment(ctx, deploymentName)
@@ -82,7 +84,7 @@ func (c *Client) GetPersistentSandboxSnapshot(ctx context.Context, sandboxID, de
 		}
 		if err == nil {
 			snapshot.Deployment = deploy
-			events, eventErr := c.GetObjectEvents(ctx, c.sandboxNS, "Deployment", deploymentName)
+			events, eventErr := c.GetObjectEvents(ctx, namespace, "Deployment", deploymentName)
 			if eventErr != nil {
 				return nil, fmt.Errorf("failed to get deployment events %s: %w", deploymentName, eventErr)
 			}
@@ -97,7 +99,7 @@ func (c *Client) GetPersistentSandboxSnapshot(ctx context.Context, sandboxID, de
 		}
 		if err == nil {
 			snapshot.PVC = pvc
-			events, eventErr := c.GetObjectEvents(ctx, c.sandboxNS, "PersistentVolumeClaim", pvcName)
+			events, eventErr := c.GetObjectEvents(ctx, namespace, "PersistentVolumeClaim", pvcName)
 			if eventErr != nil {
 				return nil, fmt.Errorf("failed to get pvc events %s: %w", pvcName, eventErr)
 			}
@@ -111,7 +113,7 @@ func (c *Client) GetPersistentSandboxSnapshot(ctx context.Context, sandboxID, de
 	}
 	if err == nil {
 		snapshot.Pod = pod
-		events, eventErr := c.GetObjectEvents(ctx, c.sandboxNS, "Pod", pod.Name)
+		events, eventErr := c.GetObjectEvents(ctx, namespace, "Pod", pod.Name)
 		if eventErr != nil {
 			return nil, fmt.Errorf("failed to get pod events %s: %w", pod.Name, eventErr)
 		}
@@ -24,6 +24,11 @@ type CreatePersistentSandboxOptions struct {
 	StorageClassName string
 	VolumeSize       string
 	VolumeClaimName  string
+	// VolumeSnapshotName provisions a new volume from this VolumeSnapshot.
+	VolumeSnapshotName string
+	// RestoreFromArchive holds the sandbox in an init container until a snapshot
+	// archive is extracted into the overlay with RestoreRootFSOverlay.
+	RestoreFromArchive bool
 }
 
 type SandboxDeletionSnapshot struct {
@@ -50,8 +55,9 @@ const (
 )
 
 func (c *Client) getSandboxPod(ctx context.Context, sandboxID string) (*corev1.Pod, error) {
+	ctx = c.WithSandboxNamespace(ctx, sandboxID)
 	legacyName := fmt.Sprintf("sandbox-%s", sandboxID)
-	pod, err := c.clientset.CoreV1().Pods(c.sandboxNS).Get(ctx, legacyName, metav1.GetOptions{})
+	pod, err := c.clientset.CoreV1().Pods(c.namespace(ctx)).Get(ctx, legacyName, metav1.GetOptions{})
 	if err == nil {
 		return pod, nil
 	}
@@ -63,7 +69,7 @@ func (c *Client) getSandboxPod(ctx context.Context, sandboxID string) (*corev1.P
 		"app":          LabelApp,
 		LabelSandboxID: sandboxID,
 	}.AsSelector().String()
-	list, err := c.clientset.CoreV1().Pods(c.sandboxNS).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	list, err := c.clientset.CoreV1().Pods(c.namespace(ctx)).List(ctx, metav1.ListOptions{LabelSelector: selector})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list sandbox pods: %w", err)
 	}
@@ -113,35 +119,36 @@ func (c *Client) CreatePersistentSandbox(ctx context.Context, opts CreatePersist
 		return nil, err
 	}
 
+	ctx = c.WithSandboxNamespace(ctx, opts.ID)
 	claimName := opts.VolumeClaimName
 	if claimName == "" {
 		claimName = fmt.Sprintf("sandbox-data-%s", opts.ID)
 	}
-	if err := c.ensurePersistentVolumeClaim(ctx, claimName, opts.StorageClassName, opts.VolumeSize); err != nil {
+	if err := c.ensurePersistentVolumeClaim(ctx, claimName, opts.StorageClassName, opts.VolumeSize, opts.VolumeSnapshotName); err != nil {
 		return nil, err
 	}
 
 	deployName := fmt.Sprintf("sandbox-%s", opts.ID)
-	accessToken := opts.AccessToken
-	if accessToken == "" {
-		accessToken = generateAccessToken()
-	}
-
 	annotations := map[string]string{
-		AnnotationTTL:         fmt.Sprintf("%d", opts.TTL),
-		AnnotationCreatedAt:   time.Now().UTC().Format(time.RFC3339),
-		AnnotationAccessToken: accessToken,
+		AnnotationTTL:       fmt.Sprintf("%d", opts.TTL),
+		AnnotationCreatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 	for k, v := range opts.Annotations {
 		annotations[k] = v
 	}
+	for k, v := range opts.Network.LimitAnnotations() {
+		annotations[k] = v
+	}
 
 	labels := map[string]string{
 		"app":          LabelApp,
 		LabelSandboxID: opts.ID,
 		LabelManagedBy: ManagedByServer,
 	}
-	if opts.Network != nil && opts.Network.AllowInternetAccess && len(opts.Network.AllowedDomains) == 0 {
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	if opts.Network.UsesInternetLabel() {
 		labels[LabelInternetAccess] = "true"
 	}
 
@@ -228,11 +235,25 @@ func (c *Client) CreatePersistentSandbox(ctx context.Context, opts CreatePersist
 		},
 	}
 
+	initContainers := []corev1.Container{prepInitContainer}
+	if opts.RestoreFromArchive {
+		initContainers = append(initContainers, corev1.Container{
+			Name:            rootfsOverlayRestoreInitName,
+			Image:           c.persistentRootFSHelperImage,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Command:         []string{"sh", "-ec", buildRootfsOverlayRestoreScript()},
+			Resources:       helperContainer.Resources,
+			// Restoring whiteouts and overlay xattrs needs the same privileges as the helper.
+			SecurityContext: helperContainer.SecurityContext,
+			VolumeMounts:    helperContainer.VolumeMounts,
+		})
+	}
+
 	replicas := int32(1)
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        deployName,
-			Namespace:   c.sandboxNS,
+			Namespace:   c.namespace(ctx),
 			Labels:      labels,
 			Annotations: annotations,
 		},
@@ -272,7 +293,7 @@ func (c *Client) CreatePersistentSandbox(ctx context.Context, opts CreatePersist
 							Type: corev1.SeccompProfileTypeRuntimeDefault,
 						},
 					},
-					InitContainers: []corev1.Container{prepInitContainer},
+					InitContainers: initContainers,
 					Containers:     []corev1.Container{mainContainer, helperContainer},
 					Volumes: []corev1.Volume{
 						{
@@ -295,9 +316,9 @@ func (c *Client) CreatePersistentSandbox(ctx context.Context, opts CreatePersist
 		},
 	}
 
-	created, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Create(ctx, deployment, metav1.CreateOptions{})
+	created, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Create(ctx, deployment, metav1.CreateOptions{})
 	if err != nil {
-		_ = c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).Delete(ctx, claimName, metav1.DeleteOptions{})
+		_ = c.clientset.CoreV1().PersistentVolumeClaims(c.namespace(ctx)).Delete(ctx, claimName, metav1.DeleteOptions{})
 		return nil, fmt.Errorf("failed to create persistent sandbox deployment: %w", err)
 	}
 	return created, nil
@@ -348,8 +369,8 @@ func resolveImageEntrypointAndCmd(ctx context.Context, image string) ([]string,
 	return entrypoint, cmd, nil
 }
 
-func (c *Client) ensurePersistentVolumeClaim(ctx context.Context, claimName, storageClass, size string) error {
-	_, err := c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).Get(ctx, claimName, metav1.GetOptions{})
+func (c *Client) ensurePersistentVolumeClaim(ctx context.Context, claimName, storageClass, size, snapshotName string) error {
+	_, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace(ctx)).Get(ctx, claimName, metav1.GetOptions{})
 	if err == nil {
 		return nil
 	}
@@ -363,7 +384,7 @@ func (c *Client) ensurePersistentVolumeClaim(ctx context.Context, claimName, sto
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      claimName,
-			Namespace: c.sandboxNS,
+			Namespace: c.namespace(ctx),
 			Labels: map[string]string{
 				"app":          LabelApp,
 				LabelSandboxID: strings.TrimPrefix(claimName, "sandbox-data-"),
@@ -380,15 +401,24 @@ func (c *Client) ensurePersistentVolumeClaim(ctx context.Context, claimName, sto
 	if storageClass != "" {
 		pvc.Spec.StorageClassName = &storageClass
 	}
-	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).Create(ctx, pvc, metav1.CreateOptions{}); err != nil {
+	if snapshotName != "" {
+		apiGroup := volumeSnapshotGVR.Group
+		pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+			APIGroup: &apiGroup,
+			Kind:     "VolumeSnapshot",
+			Name:     snapshotName,
+		}
+	}
+	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace(ctx)).Create(ctx, pvc, metav1.CreateOptions{}); err != nil {
 		return fmt.Errorf("failed to create pvc %s: %w", claimName, err)
 	}
 	return nil
 }
 
 func (c *Client) DeletePersistentSandbox(ctx context.Context, sandboxID, claimName, reclaimPolicy string) error {
+	ctx = c.WithSandboxNamespace(ctx, sandboxID)
 	deployName := fmt.Sprintf("sandbox-%s", sandboxID)
-	if err := c.clientset.AppsV1().Deployments(c.sandboxNS).Delete(ctx, deployName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+	if err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Delete(ctx, deployName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete deployment %s: %w", deployName, err)
 	}
 	if reclaimPolicy == "Retain" {
@@ -397,7 +427,7 @@ func (c *Client) DeletePersistentSandbox(ctx context.Context, sandboxID, claimNa
 	if claimName == "" {
 		claimName = fmt.Sprintf("sandbox-data-%s", sandboxID)
 	}
-	if err := c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).Delete(ctx, claimName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+	if err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace(ctx)).Delete(ctx, claimName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete pvc %s: %w", claimName, err)
 	}
 	return nil
@@ -406,7 +436,8 @@ func (c *Client) DeletePersistentSandbox(ctx context.Context, sandboxID, claimNa
 // PatchDeploymentFinalizers removes or sets deployment finalizers with provenance checks.
 // It validates the Deployment belongs to the expected sandbox via labels before mutating.
 func (c *Client) PatchDeploymentFinalizers(ctx context.Context, name string, expectedSandboxID string, finalizers []string) error {
-    deploy, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Get(ctx, name, metav1.GetOptions{})
+    ctx = c.WithSandboxNamespace(ctx, expectedSandboxID)
+    deploy, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Get(ctx, name, metav1.GetOptions{})
     if err != nil {
         if apierrors.IsNotFound(err) {
             return nil
@@ -414,11 +445,11 @@ func (c *Client) PatchDeploymentFinalizers(ctx context.Context, name string, exp
         return fmt.Errorf("failed to get deployment %s: %w", name, err)
     }
     // provenance check: ensure owned by liteboxd and sandbox-id matches
-    if err := c.validateDeploymentProvenance(deploy, expectedSandboxID); err != nil {
+    if err := c.validateDeploymentProvenance(deploy, c.namespace(ctx), expectedSandboxID); err != nil {
         return err
     }
     deploy.Finalizers = append([]string(nil), finalizers...)
-    if _, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil && !apierrors.IsNotFound(err) {
+    if _, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil && !apierrors.IsNotFound(err) {
         return fmt.Errorf("failed to update deployment %s finalizers: %w", name, err)
     }
     return nil
@@ -427,18 +458,19 @@ func (c *Client) PatchDeploymentFinalizers(ctx context.Context, name string, exp
 // PatchPVCFinalizers removes or sets PVC finalizers with provenance checks.
 // It validates PVC labels/ownership and sandbox-id before mutating.
 func (c *Client) PatchPVCFinalizers(ctx context.Context, name string, expectedSandboxID string, finalizers []string) error {
-    pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).Get(ctx, name, metav1.GetOptions{})
+    ctx = c.WithSandboxNamespace(ctx, expectedSandboxID)
+    pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace(ctx)).Get(ctx, name, metav1.GetOptions{})
     if err != nil {
         if apierrors.IsNotFound(err) {
             return nil
         }
         return fmt.Errorf("failed to get pvc %s: %w", name, err)
     }
-    if err := c.validatePVCProvenance(pvc, expectedSandboxID); err != nil {
+    if err := c.validatePVCProvenance(pvc, c.namespace(ctx), expectedSandboxID); err != nil {
         return err
     }
     pvc.Finalizers = append([]string(nil), finalizers...)
-    if _, err := c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).Update(ctx, pvc, metav1.UpdateOptions{}); err != nil && !apierrors.IsNotFound(err) {
+    if _, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace(ctx)).Update(ctx, pvc, metav1.UpdateOptions{}); err != nil && !apierrors.IsNotFound(err) {
         return fmt.Errorf("failed to update pvc %s finalizers: %w", name, err)
     }
     return nil
@@ -461,7 +493,7 @@ func (c *Client) DeletePersistentVolume(ctx context.Context, name string, expect
         }
         return fmt.Errorf("failed to get pv %s: %w", name, err)
     }
-    if err := c.validatePVProvenance(pv, expectedPVCName); err != nil {
+    if err := c.validatePVProvenance(pv, c.namespace(ctx), expectedPVCName); err != nil {
         return err
     }
     if err := c.clientset.CoreV1().PersistentVolumes().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
@@ -479,7 +511,7 @@ func (c *Client) PatchPVFinalizers(ctx context.Context, name string, expectedPVC
         }
         return fmt.Errorf("failed to get pv %s: %w", name, err)
     }
-    if err := c.validatePVProvenance(pv, expectedPVCName); err != nil {
+    if err := c.validatePVProvenance(pv, c.namespace(ctx), expectedPVCName); err != nil {
         return err
     }
     pv.Finalizers = append([]string(nil), finalizers...)
@@ -513,6 +545,7 @@ func (c *Client) DeleteVolumeAttachment(ctx context.Context, name string) error
 }
 
 func (c *Client) GetSandboxDeletionSnapshot(ctx context.Context, sandboxID, deploymentName, pvcName string) (*SandboxDeletionSnapshot, error) {
+	ctx = c.WithSandboxNamespace(ctx, sandboxID)
 	snapshot := &SandboxDeletionSnapshot{
 		Pods: []corev1.Pod{},
 	}
@@ -556,12 +589,12 @@ func (c *Client) GetSandboxDeletionSnapshot(ctx context.Context, sandboxID, depl
 }
 
 // validatePVCProvenance ensures the PVC belongs to the expected sandbox and namespace.
-func (c *Client) validatePVCProvenance(pvc *corev1.PersistentVolumeClaim, expectedSandboxID string) error {
+func (c *Client) validatePVCProvenance(pvc *corev1.PersistentVolumeClaim, namespace, expectedSandboxID string) error {
     if pvc == nil {
         return fmt.Errorf("pvc is nil")
     }
-    if pvc.Namespace != c.sandboxNS {
-        return fmt.Errorf("pvc %s/%s not in sandbox namespace %s", pvc.Namespace, pvc.Name, c.sandboxNS)
+    if pvc.Namespace != namespace {
+        return fmt.Errorf("pvc %s/%s not in sandbox namespace %s", pvc.Namespace, pvc.Name, namespace)
     }
     if pvc.Labels == nil {
         return fmt.Errorf("pvc %s missing labels for provenance check", pvc.Name)
@@ -579,15 +612,15 @@ func (c *Client) validatePVCProvenance(pvc *corev1.PersistentVolumeClaim, expect
 }
 
 // validatePVProvenance ensures the PV is bound to the expected PVC in our sandbox namespace.
-func (c *Client) validatePVProvenance(pv *corev1.PersistentVolume, expectedPVCName string) error {
+func (c *Client) validatePVProvenance(pv *corev1.PersistentVolume, namespace, expectedPVCName string) error {
     if pv == nil {
         return fmt.Errorf("pv is nil")
     }
     if pv.Spec.ClaimRef == nil {
         return fmt.Errorf("pv %s has no claimRef", pv.Name)
     }
-    if pv.Spec.ClaimRef.Namespace != c.sandboxNS {
-        return fmt.Errorf("pv %s claimRef namespace %s != %s", pv.Name, pv.Spec.ClaimRef.Namespace, c.sandboxNS)
+    if pv.Spec.ClaimRef.Namespace != namespace {
+        return fmt.Errorf("pv %s claimRef namespace %s != %s", pv.Name, pv.Spec.ClaimRef.Namespace, namespace)
     }
     if pv.Spec.ClaimRef.Name != expectedPVCName {
         return fmt.Errorf("pv %s claimRef name %s != expected pvc %s", pv.Name, pv.Spec.ClaimRef.Name, expectedPVCName)
@@ -602,12 +635,12 @@ func (c *Client) validatePVProvenance(pv *corev1.PersistentVolume, expectedPVCNa
 }
 
 // validateDeploymentProvenance ensures the Deployment belongs to the expected sandbox in our namespace.
-func (c *Client) validateDeploymentProvenance(deploy *appsv1.Deployment, expectedSandboxID string) error {
+func (c *Client) validateDeploymentProvenance(deploy *appsv1.Deployment, namespace, expectedSandboxID string) error {
     if deploy == nil {
         return fmt.Errorf("deployment is nil")
     }
-    if deploy.Namespace != c.sandboxNS {
-        return fmt.Errorf("deployment %s/%s not in sandbox namespace %s", deploy.Namespace, deploy.Name, c.sandboxNS)
+    if deploy.Namespace != namespace {
+        return fmt.Errorf("deployment %s/%s not in sandbox namespace %s", deploy.Namespace, deploy.Name, namespace)
     }
     if deploy.Labels == nil {
         return fmt.Errorf("deployment %s missing labels for provenance check", deploy.Name)
@@ -626,8 +659,9 @@ func (c *Client) validateDeploymentProvenance(deploy *appsv1.Deployment, expecte
 
 // StopPersistentSandbox scales the sandbox Deployment replicas to 0.
 func (c *Client) StopPersistentSandbox(ctx context.Context, sandboxID string) error {
+	ctx = c.WithSandboxNamespace(ctx, sandboxID)
 	deployName := fmt.Sprintf("sandbox-%s", sandboxID)
-	deploy, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Get(ctx, deployName, metav1.GetOptions{})
+	deploy, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Get(ctx, deployName, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			return apierrors.NewNotFound(appsv1.Resource("deployments"), deployName)
@@ -636,7 +670,7 @@ func (c *Client) StopPersistentSandbox(ctx context.Context, sandboxID string) er
 	}
 	replicas := int32(0)
 	deploy.Spec.Replicas = &replicas
-	if _, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+	if _, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
 		return fmt.Errorf("failed to scale deployment %s to 0: %w", deployName, err)
 	}
 	return nil
@@ -644,8 +678,9 @@ func (c *Client) StopPersistentSandbox(ctx context.Context, sandboxID string) er
 
 // StartPersistentSandbox scales the sandbox Deployment replicas back to 1.
 func (c *Client) StartPersistentSandbox(ctx context.Context, sandboxID string) error {
+	ctx = c.WithSandboxNamespace(ctx, sandboxID)
 	deployName := fmt.Sprintf("sandbox-%s", sandboxID)
-	deploy, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Get(ctx, deployName, metav1.GetOptions{})
+	deploy, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Get(ctx, deployName, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			return apierrors.NewNotFound(appsv1.Resource("deployments"), deployName)
@@ -654,7 +689,7 @@ func (c *Client) StartPersistentSandbox(ctx context.Context, sandboxID string) e
 	}
 	replicas := int32(1)
 	deploy.Spec.Replicas = &replicas
-	if _, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+	if _, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
 		return fmt.Errorf("failed to scale deployment %s to 1: %w", deployName, err)
 	}
 	return nil
@@ -662,8 +697,9 @@ func (c *Client) StartPersistentSandbox(ctx context.Context, sandboxID string) e
 
 // RestartPersistentSandbox triggers a restart by deleting the current Pod managed by the sandbox Deployment.
 func (c *Client) RestartPersistentSandbox(ctx context.Context, sandboxID string) error {
+	ctx = c.WithSandboxNamespace(ctx, sandboxID)
 	deployName := fmt.Sprintf("sandbox-%s", sandboxID)
-	if _, err := c.clientset.AppsV1().Deployments(c.sandboxNS).Get(ctx, deployName, metav1.GetOptions{}); err != nil {
+	if _, err := c.clientset.AppsV1().Deployments(c.namespace(ctx)).Get(ctx, deployName, metav1.GetOptions{}); err != nil {
 		if apierrors.IsNotFound(err) {
 			return apierrors.NewNotFound(appsv1.Resource("deployments"), deployName)
 		}
@@ -678,26 +714,28 @@ func (c *Client) RestartPersistentSandbox(ctx context.Context, sandboxID string)
 		}
 		return fmt.Errorf("failed to resolve sandbox pod: %w", err)
 	}
-	if err := c.clientset.CoreV1().Pods(c.sandboxNS).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+	if err := c.clientset.CoreV1().Pods(c.namespace(ctx)).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete pod %s for restart: %w", pod.Name, err)
 	}
 	return nil
 }
 
+// ListSandboxPVCs lists sandbox PVCs in all sandbox namespaces.
 func (c *Client) ListSandboxPVCs(ctx context.Context) ([]corev1.PersistentVolumeClaim, error) {
 	selector := labels.Set{
 		"app": LabelApp,
 	}.AsSelector().String()
-	list, err := c.clientset.CoreV1().PersistentVolumeClaims(c.sandboxNS).List(ctx, metav1.ListOptions{
-		LabelSelector: selector,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list sandbox pvcs: %w", err)
-	}
-	if list.Items == nil {
-		return []corev1.PersistentVolumeClaim{}, nil
+	items := []corev1.PersistentVolumeClaim{}
+	for _, ns := range c.SandboxNamespaces() {
+		list, err := c.clientset.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sandbox pvcs: %w", err)
+		}
+		items = append(items, list.Items...)
 	}
-	return list.Items, nil
+	return items, nil
 }
 
 func int64Ptr(v int64) *int64 {
@@ -253,9 +253,10 @@ func TestCreatePersistentSandboxUsesConfiguredHelperImage(t *testing.T) {
 
 func TestGeneratedRootfsScriptsParseWithSh(t *testing.T) {
 	scripts := map[string]string{
-		"prep":   buildRootfsOverlayPrepScript(),
-		"main":   buildRootfsOverlayMainWrapperScript(),
-		"helper": buildRootfsOverlayHelperScript(),
+		"prep":    buildRootfsOverlayPrepScript(),
+		"main":    buildRootfsOverlayMainWrapperScript(),
+		"helper":  buildRootfsOverlayHelperScript(),
+		"restore": buildRootfsOverlayRestoreScript(),
 	}
 	for name, script := range scripts {
 		t.Run(name, func(t *testing.T) {
@@ -271,8 +272,9 @@ func TestGeneratedRootfsScriptsParseWithSh(t *testing.T) {
 
 func TestRootfsScriptsAvoidKubeletDollarEscaping(t *testing.T) {
 	scripts := map[string]string{
-		"main":   buildRootfsOverlayMainWrapperScript(),
-		"helper": buildRootfsOverlayHelperScript(),
+		"main":    buildRootfsOverlayMainWrapperScript(),
+		"helper":  buildRootfsOverlayHelperScript(),
+		"restore": buildRootfsOverlayRestoreScript(),
 	}
 	for name, script := range scripts {
 		if strings.Contains(script, "$$") {
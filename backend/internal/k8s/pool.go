@@ -0,0 +1,50 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// LabelPool marks a pod waiting in the warm pool of a template. The label holds the
+// template name and is removed when the pod is claimed as a sandbox.
+const LabelPool = "liteboxd.io/pool"
+
+// ClaimPooledPod turns a warm pool pod into the pod of a claimed sandbox: the pool label
+// is dropped and the annotations describe the sandbox it now serves.
+func (c *Client) ClaimPooledPod(ctx context.Context, sandboxID string, ttl int) (*corev1.Pod, error) {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{LabelPool: nil},
+			"annotations": map[string]string{
+				AnnotationTTL:       fmt.Sprintf("%d", ttl),
+				AnnotationCreatedAt: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pool claim patch: %w", err)
+	}
+	name := fmt.Sprintf("sandbox-%s", sandboxID)
+	pod, err := c.clientset.CoreV1().Pods(c.sandboxNS).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pooled pod: %w", err)
+	}
+	return pod, nil
+}
+
+// ListPooledPods returns the pods currently waiting in warm pools.
+func (c *Client) ListPooledPods(ctx context.Context) ([]corev1.Pod, error) {
+	list, err := c.clientset.CoreV1().Pods(c.sandboxNS).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app=%s,%s", LabelApp, LabelPool),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pooled pods: %w", err)
+	}
+	return list.Items, nil
+}
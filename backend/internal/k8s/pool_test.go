@@ -0,0 +1,51 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClaimPooledPod(t *testing.T) {
+	ctx := context.Background()
+	pooled := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "sandbox-warm1",
+			Namespace:   DefaultSandboxNamespace,
+			Labels:      map[string]string{"app": LabelApp, LabelSandboxID: "warm1", LabelPool: "python"},
+			Annotations: map[string]string{"liteboxd.io/template": "python"},
+		},
+	}
+	regular := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sandbox-abc",
+			Namespace: DefaultSandboxNamespace,
+			Labels:    map[string]string{"app": LabelApp, LabelSandboxID: "abc"},
+		},
+	}
+	client := NewClientForTest(pooled, regular)
+
+	pods, err := client.ListPooledPods(ctx)
+	if err != nil {
+		t.Fatalf("ListPooledPods() error = %v", err)
+	}
+	if len(pods) != 1 || pods[0].Name != "sandbox-warm1" {
+		t.Fatalf("ListPooledPods() = %v", pods)
+	}
+
+	claimed, err := client.ClaimPooledPod(ctx, "warm1", 600)
+	if err != nil {
+		t.Fatalf("ClaimPooledPod() error = %v", err)
+	}
+	if _, ok := claimed.Labels[LabelPool]; ok {
+		t.Fatalf("pool label kept after claim: %v", claimed.Labels)
+	}
+	if claimed.Labels[LabelSandboxID] != "warm1" || claimed.Annotations["liteboxd.io/template"] != "python" {
+		t.Fatalf("claim dropped unrelated metadata: %v %v", claimed.Labels, claimed.Annotations)
+	}
+	if claimed.Annotations[AnnotationTTL] != "600" {
+		t.Fatalf("claim annotations = %v", claimed.Annotations)
+	}
+}
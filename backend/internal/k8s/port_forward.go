@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardStream is the data stream of a single port-forward. Closing it tears
+// down the connection the stream was opened on.
+type portForwardStream struct {
+	httpstream.Stream
+	conn httpstream.Connection
+}
+
+func (s *portForwardStream) Close() error {
+	s.Stream.Reset()
+	return s.conn.Close()
+}
+
+// DialPort opens a TCP stream to a port of a sandbox pod through the apiserver's
+// port-forward subresource, for when the pod network is not directly reachable.
+func (c *Client) DialPort(ctx context.Context, sandboxID string, port int) (io.ReadWriteCloser, error) {
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod.Name).
+		Namespace(pod.Namespace).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create port-forward transport: %w", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial port-forward: %w", err)
+	}
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create port-forward error stream: %w", err)
+	}
+	// Nothing is written to the error stream
+	errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create port-forward data stream: %w", err)
+	}
+
+	// The kubelet reports failures such as a closed port on the error stream and
+	// leaves the data stream open, so close the connection to end the stream.
+	go func() {
+		if message, _ := io.ReadAll(errorStream); len(message) > 0 {
+			conn.Close()
+		}
+	}()
+
+	return &portForwardStream{Stream: dataStream, conn: conn}, nil
+}
@@ -0,0 +1,243 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SandboxProcessBaseDir holds one directory per background process with its pid,
+// captured stdout/stderr and, once finished, the exit code.
+const SandboxProcessBaseDir = "/tmp/.liteboxd/processes"
+
+// ErrProcessPodReplaced is returned for processes of a pod that has since been replaced,
+// whose PIDs may have been reused by unrelated processes.
+var ErrProcessPodReplaced = errors.New("process belongs to a replaced pod")
+
+// processLauncherScript detaches the command from the exec session so it outlives the
+// API request. The command runs under a small sh wrapper that records its exit code;
+// when setsid is available the wrapper leads its own process group so signals reach
+// the whole tree. The wrapper traps termination signals only to outlive the child long
+// enough to write the exit code; traps are reset for the command itself.
+const processLauncherScript = `set -e
+dir="$1"; workdir="$2"; shift 2
+mkdir -p "$dir"
+if [ -n "$workdir" ]; then cd "$workdir"; fi
+: >"$dir/stdout"
+: >"$dir/stderr"
+runner='trap : HUP INT TERM; dir="$1"; shift; "$@" >"$dir/stdout" 2>"$dir/stderr" </dev/null; code=$?; echo "$code" >"$dir/exit_code.tmp"; mv "$dir/exit_code.tmp" "$dir/exit_code"'
+if command -v setsid >/dev/null 2>&1; then
+	setsid sh -c "$runner" liteboxd-process "$dir" "$@" </dev/null >/dev/null 2>&1 &
+else
+	sh -c "$runner" liteboxd-process "$dir" "$@" </dev/null >/dev/null 2>&1 &
+fi
+echo $! >"$dir/pid"
+echo $!
+`
+
+// processAliveFunc treats zombies as dead: sandbox images rarely run an init that reaps
+// orphaned children, so kill -0 alone would report finished processes as running.
+const processAliveFunc = `alive() { kill -0 "$1" 2>/dev/null && ! grep -q '^State:[[:space:]]*Z' "/proc/$1/status" 2>/dev/null; }
+`
+
+const processInspectScript = processAliveFunc + `for d in "$1"/*; do
+	[ -d "$d" ] || continue
+	id=${d##*/}
+	pid=$(cat "$d/pid" 2>/dev/null || true)
+	if [ -n "$pid" ] && alive "$pid"; then
+		echo "$id running"
+	elif [ -f "$d/exit_code" ]; then
+		echo "$id exited $(cat "$d/exit_code")"
+	else
+		echo "$id gone"
+	fi
+done
+`
+
+const processOutputScript = `[ -f "$1/stdout" ] && cat "$1/stdout"
+[ -f "$1/stderr" ] && cat "$1/stderr" >&2
+exit 0
+`
+
+// processFollowScript copies new output to stdout/stderr until the process exits. The
+// liveness check happens before reading so the final pass always drains the files.
+const processFollowScript = processAliveFunc + `d="$1"; pid="$2"; out=0; err=0
+while :; do
+	running=0
+	if alive "$pid"; then running=1; fi
+	size=$(($(wc -c 2>/dev/null <"$d/stdout" || echo 0)))
+	if [ "$size" -gt "$out" ]; then tail -c +$((out + 1)) "$d/stdout" | head -c $((size - out)); out=$size; fi
+	size=$(($(wc -c 2>/dev/null <"$d/stderr" || echo 0)))
+	if [ "$size" -gt "$err" ]; then tail -c +$((err + 1)) "$d/stderr" | head -c $((size - err)) >&2; err=$size; fi
+	[ "$running" = 1 ] || break
+	sleep 1
+done
+`
+
+// StartProcessOptions defines a detached command to run in a sandbox.
+type StartProcessOptions struct {
+	ID      string
+	Command []string
+	WorkDir string
+	Env     map[string]string
+}
+
+// ProcessHandle identifies a started process. PodUID pins the pod instance the PID
+// belongs to, since PIDs are meaningless after the pod is replaced.
+type ProcessHandle struct {
+	PID    int
+	PodUID string
+}
+
+// ProcessState is the observed state of a process inside the sandbox.
+type ProcessState struct {
+	Running  bool
+	Exited   bool
+	ExitCode int
+}
+
+func processDir(id string) string {
+	return path.Join(SandboxProcessBaseDir, id)
+}
+
+// CommandWithEnv prefixes command with env(1) so it runs with the given variables on
+// top of the container environment.
+func CommandWithEnv(command []string, env map[string]string) []string {
+	if len(env) == 0 {
+		return command
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	prefixed := []string{"env"}
+	for _, k := range keys {
+		prefixed = append(prefixed, k+"="+env[k])
+	}
+	return append(prefixed, command...)
+}
+
+// StartProcess launches a command in the background and returns its PID.
+func (c *Client) StartProcess(ctx context.Context, sandboxID string, opts StartProcessOptions) (*ProcessHandle, error) {
+	if len(opts.Command) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+
+	script := append([]string{"sh", "-c", processLauncherScript, "liteboxd-launch", processDir(opts.ID), opts.WorkDir}, CommandWithEnv(opts.Command, opts.Env)...)
+	result, err := c.execInPod(ctx, pod, script)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to start process (exit code %d): %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(result.Stdout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse process pid %q: %w", result.Stdout, err)
+	}
+
+	return &ProcessHandle{PID: pid, PodUID: string(pod.UID)}, nil
+}
+
+// InspectProcesses reports the state of every process started in the current pod,
+// keyed by process ID, together with the pod UID the states were observed on.
+func (c *Client) InspectProcesses(ctx context.Context, sandboxID string) (string, map[string]ProcessState, error) {
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+	result, err := c.execInPod(ctx, pod, []string{"sh", "-c", processInspectScript, "liteboxd-inspect", SandboxProcessBaseDir})
+	if err != nil {
+		return "", nil, err
+	}
+	if result.ExitCode != 0 {
+		return "", nil, fmt.Errorf("failed to inspect processes (exit code %d): %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+
+	states := make(map[string]ProcessState)
+	scanner := bufio.NewScanner(strings.NewReader(result.Stdout))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[1] {
+		case "running":
+			states[fields[0]] = ProcessState{Running: true}
+		case "exited":
+			state := ProcessState{Exited: true}
+			if len(fields) > 2 {
+				state.ExitCode, _ = strconv.Atoi(fields[2])
+			}
+			states[fields[0]] = state
+		default:
+			states[fields[0]] = ProcessState{}
+		}
+	}
+	return string(pod.UID), states, nil
+}
+
+// processPod resolves the sandbox pod a process was started in, identified by podUID.
+func (c *Client) processPod(ctx context.Context, sandboxID, podUID string) (*corev1.Pod, error) {
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+	if string(pod.UID) != podUID {
+		return nil, ErrProcessPodReplaced
+	}
+	return pod, nil
+}
+
+// SignalProcess sends a signal to the process group of a background process,
+// falling back to the process itself when it does not lead a group. The signal is
+// only sent when the sandbox still runs the pod identified by podUID.
+func (c *Client) SignalProcess(ctx context.Context, sandboxID, podUID string, pid int, signal string) error {
+	pod, err := c.processPod(ctx, sandboxID, podUID)
+	if err != nil {
+		return err
+	}
+	script := `kill -s "$2" -- "-$1" 2>/dev/null || kill -s "$2" "$1"`
+	result, err := c.execInPod(ctx, pod, []string{"sh", "-c", script, "liteboxd-signal", strconv.Itoa(pid), signal})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to signal process (exit code %d): %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// ReadProcessOutput returns the output captured so far; stdout and stderr are
+// returned in the corresponding ExecResult fields.
+func (c *Client) ReadProcessOutput(ctx context.Context, sandboxID, processID string) (*ExecResult, error) {
+	return c.Exec(ctx, sandboxID, []string{"sh", "-c", processOutputScript, "liteboxd-output", processDir(processID)})
+}
+
+// FollowProcessOutput streams captured output, starting from the beginning, until
+// the process with the given pid exits or ctx is cancelled. An empty pid reads the
+// output once and returns. Processes of a pod other than podUID are not followed.
+func (c *Client) FollowProcessOutput(ctx context.Context, sandboxID, podUID, processID, pid string, stdout, stderr io.Writer) error {
+	pod, err := c.processPod(ctx, sandboxID, podUID)
+	if err != nil {
+		return err
+	}
+	return c.execInteractiveInPod(ctx, pod, ExecInteractiveOptions{
+		Command: []string{"sh", "-c", processFollowScript, "liteboxd-follow", processDir(processID), pid},
+		Stdout:  stdout,
+		Stderr:  stderr,
+	})
+}
@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProcessCallsRefuseReplacedPod(t *testing.T) {
+	client := NewClientForTest(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sandbox-abc",
+			Namespace: DefaultSandboxNamespace,
+			UID:       "uid-2",
+			Labels:    map[string]string{"app": LabelApp, LabelSandboxID: "abc"},
+		},
+	})
+	ctx := context.Background()
+
+	if err := client.SignalProcess(ctx, "abc", "uid-1", 42, "TERM"); !errors.Is(err, ErrProcessPodReplaced) {
+		t.Fatalf("SignalProcess() error = %v, want ErrProcessPodReplaced", err)
+	}
+	if err := client.FollowProcessOutput(ctx, "abc", "uid-1", "proc-1", "42", io.Discard, io.Discard); !errors.Is(err, ErrProcessPodReplaced) {
+		t.Fatalf("FollowProcessOutput() error = %v, want ErrProcessPodReplaced", err)
+	}
+}
@@ -0,0 +1,292 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	volumeSnapshotGVR = schema.GroupVersionResource{
+		Group:    "snapshot.storage.k8s.io",
+		Version:  "v1",
+		Resource: "volumesnapshots",
+	}
+	volumeSnapshotClassGVR = schema.GroupVersionResource{
+		Group:    "snapshot.storage.k8s.io",
+		Version:  "v1",
+		Resource: "volumesnapshotclasses",
+	}
+)
+
+const (
+	LabelSnapshotID = "liteboxd.io/snapshot-id"
+
+	annotationDefaultStorageClass        = "storageclass.kubernetes.io/is-default-class"
+	annotationDefaultVolumeSnapshotClass = "snapshot.storage.kubernetes.io/is-default-class"
+
+	rootfsOverlayRestoreInitName = "rootfs-restore"
+	rootfsOverlayRestoredMarker  = "restored"
+	rootfsOverlayRestoreDoneFile = "restore.done"
+)
+
+// VolumeSnapshotStatus is the observed state of a CSI VolumeSnapshot.
+type VolumeSnapshotStatus struct {
+	ReadyToUse  bool
+	RestoreSize string
+	Error       string
+}
+
+// FindVolumeSnapshotClass returns the VolumeSnapshotClass able to snapshot volumes of
+// the given storage class, or "" when the cluster cannot snapshot them, including when
+// the snapshot CRDs are not installed. An empty storage class means the cluster default.
+func (c *Client) FindVolumeSnapshotClass(ctx context.Context, storageClassName string) (string, error) {
+	driver, err := c.storageClassProvisioner(ctx, storageClassName)
+	if err != nil || driver == "" {
+		return "", err
+	}
+
+	list, err := c.dynamicClient.Resource(volumeSnapshotClassGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to list volume snapshot classes: %w", err)
+	}
+	className := ""
+	for _, item := range list.Items {
+		if d, _, _ := unstructured.NestedString(item.Object, "driver"); d != driver {
+			continue
+		}
+		if item.GetAnnotations()[annotationDefaultVolumeSnapshotClass] == "true" {
+			return item.GetName(), nil
+		}
+		if className == "" {
+			className = item.GetName()
+		}
+	}
+	return className, nil
+}
+
+func (c *Client) storageClassProvisioner(ctx context.Context, storageClassName string) (string, error) {
+	if storageClassName != "" {
+		sc, err := c.clientset.StorageV1().StorageClasses().Get(ctx, storageClassName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return "", nil
+			}
+			return "", fmt.Errorf("failed to get storage class %s: %w", storageClassName, err)
+		}
+		return sc.Provisioner, nil
+	}
+
+	list, err := c.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list storage classes: %w", err)
+	}
+	for _, sc := range list.Items {
+		if sc.Annotations[annotationDefaultStorageClass] == "true" {
+			return sc.Provisioner, nil
+		}
+	}
+	return "", nil
+}
+
+// CreateVolumeSnapshot snapshots a sandbox PVC with the given VolumeSnapshotClass.
+func (c *Client) CreateVolumeSnapshot(ctx context.Context, name, snapshotID, sandboxID, claimName, className string) error {
+	namespace := c.namespaceOf(ctx, sandboxID)
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": volumeSnapshotGVR.GroupVersion().String(),
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"app":           LabelApp,
+					LabelSandboxID:  sandboxID,
+					LabelSnapshotID: snapshotID,
+					LabelManagedBy:  ManagedByServer,
+				},
+			},
+			"spec": map[string]interface{}{
+				"volumeSnapshotClassName": className,
+				"source": map[string]interface{}{
+					"persistentVolumeClaimName": claimName,
+				},
+			},
+		},
+	}
+	_, err := c.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Create(ctx, snapshot, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create volume snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetVolumeSnapshotStatus returns the observed state of a VolumeSnapshot in the
+// namespace set on ctx.
+func (c *Client) GetVolumeSnapshotStatus(ctx context.Context, name string) (*VolumeSnapshotStatus, error) {
+	obj, err := c.dynamicClient.Resource(volumeSnapshotGVR).Namespace(c.namespace(ctx)).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	status := &VolumeSnapshotStatus{}
+	status.ReadyToUse, _, _ = unstructured.NestedBool(obj.Object, "status", "readyToUse")
+	status.RestoreSize, _, _ = unstructured.NestedString(obj.Object, "status", "restoreSize")
+	status.Error, _, _ = unstructured.NestedString(obj.Object, "status", "error", "message")
+	return status, nil
+}
+
+// DeleteVolumeSnapshot deletes a VolumeSnapshot in the namespace set on ctx. Missing
+// snapshots are ignored.
+func (c *Client) DeleteVolumeSnapshot(ctx context.Context, name string) error {
+	err := c.dynamicClient.Resource(volumeSnapshotGVR).Namespace(c.namespace(ctx)).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete volume snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// rootfsOverlayArchiveScript writes the overlay upper dir as a tar.gz archive to stdout.
+// Whiteouts and opaque directories are kept through device nodes and trusted.overlay.*
+// xattrs, which is why it runs in the privileged helper container.
+const rootfsOverlayArchiveScript = `set -eu
+[ -d "$1" ] || exit 44
+set +e
+tar -czpf - --xattrs --xattrs-include='trusted.*' --numeric-owner -C "$1" .
+status=$?
+set -e
+# Exit status 1 means some files changed while being read, which is expected for a
+# running sandbox.
+[ "$status" -le 1 ] || exit "$status"
+`
+
+// ArchiveRootFSOverlay streams the overlay upper dir of a running persistent sandbox
+// to w as a tar.gz archive.
+func (c *Client) ArchiveRootFSOverlay(ctx context.Context, sandboxID string, w io.Writer) error {
+	pod, err := c.getSandboxPod(ctx, sandboxID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve sandbox pod: %w", err)
+	}
+	if !podHasContainer(pod.Spec.Containers, rootfsOverlayHelperName) {
+		return fmt.Errorf("sandbox %s does not use a rootfs overlay", sandboxID)
+	}
+
+	var stderr bytes.Buffer
+	err = c.streamExec(ctx, pod, rootfsOverlayHelperName, []string{
+		"sh", "-c", rootfsOverlayArchiveScript, "liteboxd-snapshot", rootfsOverlayMountTarget + "/" + rootfsOverlayStateDir + "/upper",
+	}, ExecInteractiveOptions{Stdout: w, Stderr: &stderr})
+	return execStreamError("archive rootfs overlay", err, &stderr)
+}
+
+// rootfsOverlayRestoreApplyScript replaces the overlay upper dir with the tar.gz archive
+// read from stdin and then releases the waiting restore init container.
+const rootfsOverlayRestoreApplyScript = `set -eu
+STATE="$1"
+CONTROL="$2"
+rm -rf "$STATE/upper" "$STATE/work"
+mkdir -p "$STATE/upper"
+tar -xzpf - --xattrs --xattrs-include='trusted.*' --numeric-owner -C "$STATE/upper"
+: > "$STATE/` + rootfsOverlayRestoredMarker + `"
+: > "$CONTROL/` + rootfsOverlayRestoreDoneFile + `"
+`
+
+// RestoreRootFSOverlay waits for the restore init container of a persistent sandbox
+// created with RestoreFromArchive and extracts archive into its overlay upper dir.
+func (c *Client) RestoreRootFSOverlay(ctx context.Context, sandboxID string, archive io.Reader) error {
+	var pod *corev1.Pod
+	for {
+		p, err := c.getSandboxPod(ctx, sandboxID)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to resolve sandbox pod: %w", err)
+		}
+		if p != nil {
+			if !podHasContainer(p.Spec.InitContainers, rootfsOverlayRestoreInitName) {
+				return fmt.Errorf("sandbox %s was not created for a snapshot restore", sandboxID)
+			}
+			if initContainerRunning(p, rootfsOverlayRestoreInitName) {
+				pod = p
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for restore container: %w", ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	var stderr bytes.Buffer
+	err := c.streamExec(ctx, pod, rootfsOverlayRestoreInitName, []string{
+		"sh", "-c", rootfsOverlayRestoreApplyScript, "liteboxd-restore",
+		rootfsOverlayMountTarget + "/" + rootfsOverlayStateDir, rootfsOverlayControlDir,
+	}, ExecInteractiveOptions{Stdin: archive, Stderr: &stderr})
+	return execStreamError("restore rootfs overlay", err, &stderr)
+}
+
+// buildRootfsOverlayRestoreScript waits until the control plane has extracted a
+// snapshot archive into the overlay upper dir. Once restored, the marker on the volume
+// makes later pod starts skip the wait.
+func buildRootfsOverlayRestoreScript() string {
+	return fmt.Sprintf(`
+set -eu
+
+STATE=%q
+CONTROL=%q
+
+if [ -f "$STATE/%s" ]; then
+  exit 0
+fi
+i=0
+while [ ! -f "$CONTROL/%s" ]; do
+  i=$((i+1))
+  if [ "$i" -ge 3000 ]; then
+    echo "[rootfs-restore] timed out waiting for snapshot restore" >&2
+    exit 1
+  fi
+  sleep 0.2
+done
+`, rootfsOverlayMountTarget+"/"+rootfsOverlayStateDir, rootfsOverlayControlDir, rootfsOverlayRestoredMarker, rootfsOverlayRestoreDoneFile)
+}
+
+func podHasContainer(containers []corev1.Container, name string) bool {
+	for _, container := range containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func initContainerRunning(pod *corev1.Pod, name string) bool {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == name {
+			return status.State.Running != nil
+		}
+	}
+	return false
+}
+
+// execStreamError turns the error of a streamed exec into one carrying its stderr.
+func execStreamError(op string, err error, stderr *bytes.Buffer) error {
+	if err == nil {
+		return nil
+	}
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to %s (exit code %d): %s", op, exitErr.ExitStatus(), strings.TrimSpace(stderr.String()))
+	}
+	return fmt.Errorf("failed to %s: %w", op, err)
+}
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFindVolumeSnapshotClassMatchesDriver(t *testing.T) {
+	ctx := context.Background()
+	client := NewClientForTestWithDynamic()
+	client.clientset = kubefake.NewSimpleClientset(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "longhorn"}, Provisioner: "driver.longhorn.io"},
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "local", Annotations: map[string]string{annotationDefaultStorageClass: "true"}},
+			Provisioner: "rancher.io/local-path",
+		},
+	)
+	for _, obj := range []*unstructured.Unstructured{
+		volumeSnapshotClass("other", "ebs.csi.aws.com", false),
+		volumeSnapshotClass("longhorn-a", "driver.longhorn.io", false),
+		volumeSnapshotClass("longhorn-default", "driver.longhorn.io", true),
+	} {
+		if _, err := client.dynamicClient.Resource(volumeSnapshotClassGVR).Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, err := client.FindVolumeSnapshotClass(ctx, "longhorn"); err != nil || got != "longhorn-default" {
+		t.Fatalf("FindVolumeSnapshotClass(longhorn) = %q, %v; want longhorn-default", got, err)
+	}
+	if got, err := client.FindVolumeSnapshotClass(ctx, ""); err != nil || got != "" {
+		t.Fatalf("FindVolumeSnapshotClass(default) = %q, %v; want no class for local-path", got, err)
+	}
+	if got, err := client.FindVolumeSnapshotClass(ctx, "missing"); err != nil || got != "" {
+		t.Fatalf("FindVolumeSnapshotClass(missing) = %q, %v; want no class", got, err)
+	}
+}
+
+func volumeSnapshotClass(name, driver string, isDefault bool) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": volumeSnapshotClassGVR.GroupVersion().String(),
+		"kind":       "VolumeSnapshotClass",
+		"metadata":   map[string]interface{}{"name": name},
+		"driver":     driver,
+	}}
+	if isDefault {
+		obj.SetAnnotations(map[string]string{annotationDefaultVolumeSnapshotClass: "true"})
+	}
+	return obj
+}
+
+func TestVolumeSnapshotLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := NewClientForTestWithDynamic()
+
+	if err := client.CreateVolumeSnapshot(ctx, "snap-1", "snap-1", "sbx1", "sandbox-data-sbx1", "longhorn"); err != nil {
+		t.Fatalf("CreateVolumeSnapshot() error = %v", err)
+	}
+	status, err := client.GetVolumeSnapshotStatus(ctx, "snap-1")
+	if err != nil || status.ReadyToUse {
+		t.Fatalf("GetVolumeSnapshotStatus() = %+v, %v; want not ready", status, err)
+	}
+	if err := client.DeleteVolumeSnapshot(ctx, "snap-1"); err != nil {
+		t.Fatalf("DeleteVolumeSnapshot() error = %v", err)
+	}
+	if err := client.DeleteVolumeSnapshot(ctx, "snap-1"); err != nil {
+		t.Fatalf("DeleteVolumeSnapshot() on missing snapshot error = %v", err)
+	}
+}
+
+func TestCreatePersistentSandboxFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClientWithFakeClientset()
+
+	_, err := client.CreatePersistentSandbox(ctx, CreatePersistentSandboxOptions{
+		CreatePodOptions: CreatePodOptions{
+			ID:      "restore1",
+			Image:   "busybox:1.36",
+			Command: []string{"sh", "-c", "sleep 30"},
+		},
+		VolumeSize:         "1Gi",
+		VolumeSnapshotName: "snap-abc",
+		RestoreFromArchive: true,
+	})
+	if err != nil {
+		t.Fatalf("CreatePersistentSandbox() error = %v", err)
+	}
+
+	pvc, err := client.clientset.CoreV1().PersistentVolumeClaims(DefaultSandboxNamespace).Get(ctx, "sandbox-data-restore1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get pvc error = %v", err)
+	}
+	if ds := pvc.Spec.DataSource; ds == nil || ds.Kind != "VolumeSnapshot" || ds.Name != "snap-abc" {
+		t.Fatalf("pvc dataSource = %+v, want VolumeSnapshot snap-abc", ds)
+	}
+
+	deploy, err := client.clientset.AppsV1().Deployments(DefaultSandboxNamespace).Get(ctx, "sandbox-restore1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get deployment error = %v", err)
+	}
+	inits := deploy.Spec.Template.Spec.InitContainers
+	if len(inits) != 2 || inits[0].Name != rootfsOverlayPrepInitName || inits[1].Name != rootfsOverlayRestoreInitName {
+		t.Fatalf("init containers = %+v, want prepare then restore", inits)
+	}
+	if sc := inits[1].SecurityContext; sc == nil || sc.Privileged == nil || !*sc.Privileged {
+		t.Fatalf("restore init container must be privileged")
+	}
+	if !hasVolumeMount(inits[1].VolumeMounts, rootfsOverlayVolumeName, rootfsOverlayMountTarget) {
+		t.Fatalf("restore init container rootfs mount not found")
+	}
+}
+
+func TestRootfsOverlayArchiveRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not available")
+	}
+	src := filepath.Join(t.TempDir(), "upper")
+	if err := os.MkdirAll(filepath.Join(src, "workspace"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "workspace", "notes.txt"), []byte("agent state"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	state := t.TempDir()
+	control := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(state, "upper", "stale"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := exec.Command("sh", "-c", rootfsOverlayArchiveScript, "liteboxd-test", src)
+	restore := exec.Command("sh", "-c", rootfsOverlayRestoreApplyScript, "liteboxd-test", state, control)
+	pipe, err := archive.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restore.Stdin = pipe
+	if err := archive.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := restore.CombinedOutput(); err != nil {
+		t.Fatalf("restore script failed: %v\n%s", err, out)
+	}
+	if err := archive.Wait(); err != nil {
+		t.Fatalf("archive script failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(state, "upper", "workspace", "notes.txt"))
+	if err != nil || string(content) != "agent state" {
+		t.Fatalf("restored content = %q, %v", content, err)
+	}
+	if info, err := os.Stat(filepath.Join(state, "upper", "workspace", "notes.txt")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("restored file mode = %v, %v; want 0600", info.Mode(), err)
+	}
+	if _, err := os.Stat(filepath.Join(state, "upper", "stale")); !os.IsNotExist(err) {
+		t.Fatalf("stale upper content survived restore: %v", err)
+	}
+	for _, marker := range []string{filepath.Join(state, rootfsOverlayRestoredMarker), filepath.Join(control, rootfsOverlayRestoreDoneFile)} {
+		if _, err := os.Stat(marker); err != nil {
+			t.Fatalf("marker %s missing: %v", marker, err)
+		}
+	}
+}
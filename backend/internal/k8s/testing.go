@@ -1,6 +1,7 @@
 package k8s
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
@@ -27,14 +28,25 @@ func NewClientForTestWithSetup(setup func(*kubefake.Clientset), objects ...runti
 }
 
 // NewClientForTestWithDynamic creates a Client with fake typed and dynamic clients.
+// Unstructured objects seed the dynamic client and all others the typed clientset.
 func NewClientForTestWithDynamic(objects ...runtime.Object) *Client {
+	var typed, dynamic []runtime.Object
+	for _, obj := range objects {
+		if _, ok := obj.(*unstructured.Unstructured); ok {
+			dynamic = append(dynamic, obj)
+		} else {
+			typed = append(typed, obj)
+		}
+	}
 	scheme := runtime.NewScheme()
 	listKinds := map[schema.GroupVersionResource]string{
-		ciliumPolicyGVR: "CiliumNetworkPolicyList",
+		ciliumPolicyGVR:        "CiliumNetworkPolicyList",
+		volumeSnapshotGVR:      "VolumeSnapshotList",
+		volumeSnapshotClassGVR: "VolumeSnapshotClassList",
 	}
 	return &Client{
-		clientset:                   kubefake.NewSimpleClientset(objects...),
-		dynamicClient:               dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds, objects...),
+		clientset:                   kubefake.NewSimpleClientset(typed...),
+		dynamicClient:               dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds, dynamic...),
 		sandboxNS:                   DefaultSandboxNamespace,
 		controlNS:                   DefaultControlNamespace,
 		persistentRootFSHelperImage: DefaultPersistentRootFSHelperImage,
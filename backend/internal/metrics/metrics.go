@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/lifecycle"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "liteboxd"
+
+// Registry holds the metrics of this process.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// ReconcileRuns counts sandbox reconcile runs by result (completed or failed).
+	ReconcileRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_runs_total",
+		Help:      "Sandbox reconcile runs by status.",
+	}, []string{"status"})
+
+	// ReconcileDrift counts sandboxes found out of sync with the cluster.
+	ReconcileDrift = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_drift_total",
+		Help:      "Sandboxes whose metadata drifted from the cluster state, summed over reconcile runs.",
+	})
+
+	// ReconcileFixed counts drifted sandboxes the reconciler repaired.
+	ReconcileFixed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_fixed_total",
+		Help:      "Drifted sandboxes repaired by reconcile runs.",
+	})
+
+	// DeletionRetries counts failed sandbox deletion attempts, each of which is
+	// retried later.
+	DeletionRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sandbox_deletion_retries_total",
+		Help:      "Failed sandbox deletion attempts scheduled for retry.",
+	})
+
+	// GatewayTrafficBytes counts bytes the gateway relayed to (in) and from (out)
+	// sandboxes.
+	GatewayTrafficBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_traffic_bytes_total",
+		Help:      "Bytes relayed by the gateway, by direction (in: to sandboxes, out: from sandboxes).",
+	}, []string{"direction"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		ReconcileRuns,
+		ReconcileDrift,
+		ReconcileFixed,
+		DeletionRetries,
+		GatewayTrafficBytes,
+	)
+}
+
+// Middleware records the count and latency of requests. Requests that matched no
+// route, such as host-routed gateway requests, are labelled with the route "other".
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "other"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics of Registry. With a non-empty token, scrapes must send it
+// as a bearer token.
+func Handler(token string) gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		if token != "" {
+			got := c.GetHeader("Authorization")
+			if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+token)) != 1 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+				return
+			}
+		}
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// RegisterWebSocketSessions exports the number of WebSocket sessions tracked by a
+// drain manager.
+func RegisterWebSocketSessions(drainState *lifecycle.DrainManager) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_sessions_active",
+		Help:      "Active WebSocket sessions.",
+	}, func() float64 {
+		return float64(drainState.ActiveWebSockets())
+	}))
+}
+
+// RegisterSandboxCounts exports the number of sandboxes by lifecycle status, read
+// from the store on every scrape.
+func RegisterSandboxCounts(sandboxStore *store.SandboxStore) {
+	Registry.MustRegister(&sandboxCollector{store: sandboxStore})
+}
+
+var sandboxesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "sandboxes"),
+	"Sandboxes by lifecycle status, not counting deleted ones.",
+	[]string{"status"}, nil,
+)
+
+type sandboxCollector struct {
+	store *store.SandboxStore
+}
+
+func (c *sandboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sandboxesDesc
+}
+
+func (c *sandboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	counts, err := c.store.CountByLifecycleStatus(ctx)
+	if err != nil {
+		slog.Warn("failed to count sandboxes for metrics", "component", "metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(sandboxesDesc, err)
+		return
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(sandboxesDesc, prometheus.GaugeValue, float64(count), status)
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlerRequiresTokenAndExportsRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+	r.GET("/metrics", Handler("scrape-token"))
+
+	do := func(path, auth string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	do("/items/42", "")
+	if w := do("/metrics", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("scrape without token: status %d, want 401", w.Code)
+	}
+	w := do("/metrics", "Bearer scrape-token")
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape: status %d", w.Code)
+	}
+	body, _ := io.ReadAll(w.Body)
+	want := `liteboxd_http_requests_total{method="GET",route="/items/:id",status="418"} 1`
+	if !strings.Contains(string(body), want) {
+		t.Fatalf("metrics output lacks %q", want)
+	}
+}
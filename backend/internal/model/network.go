@@ -4,11 +4,65 @@ package model
 type NetworkSpec struct {
 	// AllowInternetAccess enables outbound internet access for the sandbox.
 	// When false (default), the sandbox can only access DNS and internal services.
-	// When true, the sandbox can access the internet (HTTP/HTTPS only).
+	// When true, the sandbox can access the internet on Ports (HTTP/HTTPS by default).
 	AllowInternetAccess bool `json:"allowInternetAccess" yaml:"allowInternetAccess"`
 
 	// AllowedDomains is an optional list of domains that the sandbox is allowed to access.
-	// This is a future enhancement for domain whitelist functionality.
-	// When empty, no domain filtering is applied (beyond the internet access setting).
+	// When empty and DomainRules is empty too, no domain filtering is applied (beyond the
+	// internet access setting).
 	AllowedDomains []string `json:"allowedDomains,omitempty" yaml:"allowedDomains,omitempty"`
+
+	// Ports are the ports reachable on the internet, with or without AllowedDomains.
+	// When empty, TCP 80 and 443 are allowed.
+	Ports []PortRule `json:"ports,omitempty" yaml:"ports,omitempty"`
+
+	// DomainRules allow further domains, each on its own ports. Like AllowedDomains they
+	// only apply when AllowInternetAccess is true.
+	DomainRules []DomainRule `json:"domainRules,omitempty" yaml:"domainRules,omitempty"`
+
+	// AllowedCIDRs allow egress to IP ranges, private ones included, whether or not
+	// internet access is enabled.
+	AllowedCIDRs []CIDRRule `json:"allowedCIDRs,omitempty" yaml:"allowedCIDRs,omitempty"`
+
+	// DeniedCIDRs are never reachable. They take precedence over every other rule.
+	DeniedCIDRs []string `json:"deniedCIDRs,omitempty" yaml:"deniedCIDRs,omitempty"`
+
+	// Bandwidth caps the sandbox's traffic rates. It is fixed when the sandbox starts.
+	Bandwidth *BandwidthSpec `json:"bandwidth,omitempty" yaml:"bandwidth,omitempty"`
+}
+
+// Port protocols
+const (
+	PortProtocolTCP = "TCP"
+	PortProtocolUDP = "UDP"
+	PortProtocolAny = "ANY"
+)
+
+// PortRule is a port egress is allowed to.
+type PortRule struct {
+	Port int `json:"port" yaml:"port"`
+	// Protocol is TCP (default), UDP, or ANY
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+}
+
+// DomainRule allows egress to domains on specific ports.
+type DomainRule struct {
+	// Domains may use a leading wildcard, as in *.example.com
+	Domains []string `json:"domains" yaml:"domains"`
+	// Ports default to the network's internet ports
+	Ports []PortRule `json:"ports,omitempty" yaml:"ports,omitempty"`
+}
+
+// CIDRRule allows egress to an IP range.
+type CIDRRule struct {
+	CIDR string `json:"cidr" yaml:"cidr"`
+	// Ports limit the rule to some ports; empty allows all ports
+	Ports []PortRule `json:"ports,omitempty" yaml:"ports,omitempty"`
+}
+
+// BandwidthSpec limits traffic rates in bits per second, written as Kubernetes
+// quantities such as "10M". An empty rate is unlimited.
+type BandwidthSpec struct {
+	Egress  string `json:"egress,omitempty" yaml:"egress,omitempty"`
+	Ingress string `json:"ingress,omitempty" yaml:"ingress,omitempty"`
 }
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+type NetworkEventKind string
+
+const (
+	// NetworkEventDNS is a DNS query made by a sandbox.
+	NetworkEventDNS NetworkEventKind = "dns"
+	// NetworkEventConnection is an outbound connection attempt made by a sandbox.
+	NetworkEventConnection NetworkEventKind = "connection"
+)
+
+type NetworkEventVerdict string
+
+const (
+	NetworkVerdictAllowed NetworkEventVerdict = "allowed"
+	NetworkVerdictDenied  NetworkEventVerdict = "denied"
+)
+
+// SandboxNetworkEvent is an egress audit record of a sandbox
+type SandboxNetworkEvent struct {
+	ID        int64            `json:"id"`
+	SandboxID string           `json:"sandbox_id"`
+	Kind      NetworkEventKind `json:"kind"`
+	// Domain is the queried name of a DNS event, or the name a connection's
+	// destination was resolved from when known.
+	Domain        string              `json:"domain,omitempty"`
+	DestinationIP string              `json:"destination_ip,omitempty"`
+	Port          int                 `json:"port,omitempty"`
+	Protocol      string              `json:"protocol,omitempty"`
+	Verdict       NetworkEventVerdict `json:"verdict"`
+	ObservedAt    time.Time           `json:"observed_at"`
+}
+
+// NetworkEventQuery filters the network events of a sandbox
+type NetworkEventQuery struct {
+	Kind  NetworkEventKind
+	Since *time.Time
+	Limit int
+}
+
+type NetworkEventListResponse struct {
+	Items []SandboxNetworkEvent `json:"items"`
+}
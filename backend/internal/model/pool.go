@@ -0,0 +1,18 @@
+package model
+
+// SandboxPoolStatus reports the warm pool of a template. Hits and misses count the
+// creations that could and could not be served from the pool since the server started.
+type SandboxPoolStatus struct {
+	Template        string `json:"template"`
+	TemplateVersion int    `json:"template_version"`
+	Size            int    `json:"size"`
+	Ready           int    `json:"ready"`
+	Warming         int    `json:"warming"`
+	Failed          int    `json:"failed"`
+	Hits            int64  `json:"hits"`
+	Misses          int64  `json:"misses"`
+}
+
+type SandboxPoolListResponse struct {
+	Items []SandboxPoolStatus `json:"items"`
+}
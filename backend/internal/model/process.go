@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+type ProcessStatus string
+
+const (
+	ProcessStatusRunning ProcessStatus = "running"
+	ProcessStatusExited  ProcessStatus = "exited"
+	// ProcessStatusKilled means the process disappeared without reporting an exit code,
+	// typically after SIGKILL.
+	ProcessStatusKilled ProcessStatus = "killed"
+	// ProcessStatusLost means the pod the process ran in was replaced or removed.
+	ProcessStatusLost ProcessStatus = "lost"
+)
+
+// SandboxProcess is a background process started inside a sandbox
+type SandboxProcess struct {
+	ID         string        `json:"id"`
+	SandboxID  string        `json:"sandbox_id"`
+	Command    []string      `json:"command"`
+	WorkDir    string        `json:"work_dir,omitempty"`
+	PID        int           `json:"pid"`
+	Status     ProcessStatus `json:"status"`
+	ExitCode   *int          `json:"exit_code,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// StartProcessRequest starts a detached command in a sandbox
+type StartProcessRequest struct {
+	Command []string          `json:"command" binding:"required"`
+	WorkDir string            `json:"work_dir,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+}
+
+type ProcessListResponse struct {
+	Items []SandboxProcess `json:"items"`
+}
+
+// SignalProcessRequest sends a signal (e.g. "TERM", "INT", "KILL") to a process
+type SignalProcessRequest struct {
+	Signal string `json:"signal" binding:"required"`
+}
+
+type ProcessOutputResponse struct {
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+}
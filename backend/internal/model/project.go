@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Project groups sandboxes and templates. The sandboxes of a project run in their
+// own Kubernetes namespace.
+type Project struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Namespace   string    `json:"namespace"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateProjectRequest creates a project. Names are lowercase DNS labels; the
+// namespace of the project is derived from the name.
+type CreateProjectRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+type ProjectListResponse struct {
+	Items []Project `json:"items"`
+}
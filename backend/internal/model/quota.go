@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+// QuotaScope is what a quota limits: the sandboxes of a user, of an API key or of a
+// project.
+type QuotaScope string
+
+const (
+	QuotaScopeUser    QuotaScope = "user"
+	QuotaScopeAPIKey  QuotaScope = "api_key"
+	QuotaScopeProject QuotaScope = "project"
+)
+
+// QuotaSubjectDefault as the subject of a quota applies it to every user, API key or
+// project without a quota of its own.
+const QuotaSubjectDefault = "*"
+
+// Resources a quota limits, as reported in QuotaViolation.
+const (
+	QuotaResourceSandboxes = "sandboxes"
+	QuotaResourceCPU       = "cpu"
+	QuotaResourceMemory    = "memory"
+	QuotaResourceStorage   = "storage"
+	QuotaResourceTTL       = "ttl"
+)
+
+// QuotaLimits are the limits of a quota. Zero values are unlimited.
+type QuotaLimits struct {
+	// MaxSandboxes limits the sandboxes running at once; stopped and paused ones don't count
+	MaxSandboxes int `json:"max_sandboxes,omitempty"`
+	// MaxCPU and MaxMemory limit the summed CPU and memory limits of running sandboxes,
+	// as Kubernetes quantities (e.g. "4", "8Gi")
+	MaxCPU    string `json:"max_cpu,omitempty"`
+	MaxMemory string `json:"max_memory,omitempty"`
+	// MaxStorage limits the summed size of persistent volumes, including those of
+	// stopped sandboxes
+	MaxStorage string `json:"max_storage,omitempty"`
+	// MaxTTL limits the TTL of sandboxes in seconds; sandboxes that never expire are refused
+	MaxTTL int `json:"max_ttl,omitempty"`
+}
+
+// Quota is a quota set on a user (by ID), an API key (by ID) or a project (by name).
+type Quota struct {
+	Scope   QuotaScope `json:"scope"`
+	Subject string     `json:"subject"`
+	QuotaLimits
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type QuotaListResponse struct {
+	Items []Quota `json:"items"`
+}
+
+// QuotaUsage is what the active sandboxes of a subject count towards its quota.
+type QuotaUsage struct {
+	Sandboxes int    `json:"sandboxes"`
+	CPU       string `json:"cpu"`
+	Memory    string `json:"memory"`
+	Storage   string `json:"storage"`
+}
+
+// QuotaStatus is the quota of a subject, if any, along with its usage.
+type QuotaStatus struct {
+	Scope   QuotaScope   `json:"scope"`
+	Subject string       `json:"subject"`
+	Limits  *QuotaLimits `json:"limits,omitempty"`
+	Usage   QuotaUsage   `json:"usage"`
+}
+
+type QuotaStatusResponse struct {
+	Items []QuotaStatus `json:"items"`
+}
+
+// QuotaViolation describes the quota a request would exceed. It is returned as
+// "quota" in the body of 429 (too many sandboxes) and 403 (other limits) responses
+// whose code is QUOTA_EXCEEDED.
+type QuotaViolation struct {
+	Scope     QuotaScope `json:"scope"`
+	Subject   string     `json:"subject"`
+	Resource  string     `json:"resource"`
+	Limit     string     `json:"limit"`
+	Used      string     `json:"used"`
+	Requested string     `json:"requested"`
+}
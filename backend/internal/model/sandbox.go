@@ -10,6 +10,7 @@ const (
 	SandboxStatusSucceeded   SandboxStatus = "succeeded"
 	SandboxStatusFailed      SandboxStatus = "failed"
 	SandboxStatusStopped     SandboxStatus = "stopped"
+	SandboxStatusPaused      SandboxStatus = "paused"
 	SandboxStatusTerminating SandboxStatus = "terminating"
 	SandboxStatusUnknown     SandboxStatus = "unknown"
 )
@@ -30,6 +31,7 @@ type Sandbox struct {
 	CPU             string              `json:"cpu"`
 	Memory          string              `json:"memory"`
 	TTL             int                 `json:"ttl"`
+	TTLMode         TTLMode             `json:"ttlMode,omitempty"`
 	Env             map[string]string   `json:"env,omitempty"`
 	Status          SandboxStatus       `json:"status"`
 	Template        string              `json:"template,omitempty"`
@@ -48,10 +50,33 @@ type Sandbox struct {
 	Deletion        *SandboxDeletion    `json:"deletion,omitempty"`
 	RuntimeKind     string              `json:"runtimeKind,omitempty"`
 	RuntimeName     string              `json:"runtimeName,omitempty"`
+	FromPool        bool                `json:"fromPool,omitempty"` // Claimed from the template's warm pool
+	Network         *NetworkSpec        `json:"network,omitempty"`  // Per-sandbox network override; nil follows the template
+	NetworkGroup    string              `json:"networkGroup,omitempty"`
+	GroupPorts      []PortRule          `json:"groupPorts,omitempty"`
+	PeerHost        string              `json:"peerHost,omitempty"`  // Name other members of the network group reach the sandbox at
+	Bandwidth       *BandwidthSpec      `json:"bandwidth,omitempty"` // Effective traffic rate limits
 
 	// Network access fields
 	AccessToken string `json:"accessToken,omitempty"` // Access token for inbound requests
 	AccessURL   string `json:"accessUrl,omitempty"`   // Base URL for accessing the sandbox
+	TunnelURL   string `json:"tunnelUrl,omitempty"`   // WebSocket URL of TCP tunnels; replace {port} with the sandbox port
+	// PreviousAccessTokenExpiresAt is set while the token replaced by the last rotation
+	// is still accepted
+	PreviousAccessTokenExpiresAt *time.Time `json:"previousAccessTokenExpiresAt,omitempty"`
+	// Owner is the user that created the sandbox, unset for sandboxes created before
+	// ownership was recorded
+	Owner *SandboxOwner `json:"owner,omitempty"`
+	// Project is the project the sandbox belongs to, unset for sandboxes outside projects
+	Project string `json:"project,omitempty"`
+}
+
+// SandboxOwner identifies the user that created a sandbox, and the API key used when
+// it was created with one.
+type SandboxOwner struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	APIKeyID string `json:"apiKeyId,omitempty"`
 }
 
 type SandboxDeletion struct {
@@ -77,10 +102,21 @@ type SandboxPersistence struct {
 // CreateSandboxRequest represents a request to create a sandbox from a template.
 // All sandboxes must be created from a template.
 type CreateSandboxRequest struct {
-	// Template is required - all sandboxes must be created from a template
-	Template        string            `json:"template" binding:"required"`
+	// Template is required unless FromSnapshot is set, in which case it defaults to the
+	// template the snapshot was taken from
+	Template        string            `json:"template"`
 	TemplateVersion int               `json:"templateVersion"`
 	Overrides       *SandboxOverrides `json:"overrides"`
+	// FromSnapshot starts the sandbox from the root filesystem state of a ready snapshot.
+	// The template must have persistence enabled.
+	FromSnapshot string `json:"fromSnapshot,omitempty"`
+	// NetworkGroup lets the sandbox reach the other sandboxes of the same group, and be
+	// reached by them on GroupPorts, at peer-<id>. Sandboxes are isolated otherwise.
+	NetworkGroup string     `json:"networkGroup,omitempty"`
+	GroupPorts   []PortRule `json:"groupPorts,omitempty"`
+	// Project runs the sandbox in the namespace of a project. API keys scoped to a
+	// project default to, and may only use, that project.
+	Project string `json:"project,omitempty"`
 }
 
 // SandboxOverrides allows overriding template configuration
@@ -88,9 +124,13 @@ type SandboxOverrides struct {
 	CPU         string                       `json:"cpu,omitempty"`
 	Memory      string                       `json:"memory,omitempty"`
 	TTL         *int                         `json:"ttl,omitempty"`
+	TTLMode     TTLMode                      `json:"ttlMode,omitempty"`
 	Env         map[string]string            `json:"env,omitempty"`
 	Persistence *SandboxPersistenceOverrides `json:"persistence,omitempty"`
-	// Note: Network configuration cannot be overridden, it must be set in the template spec
+	// Network replaces the template's network configuration. Unless the server allows
+	// widening, it may only disable internet access or narrow the template's domains.
+	// Traffic limits it leaves unset follow the template.
+	Network *NetworkSpec `json:"network,omitempty"`
 }
 
 // SandboxPersistenceOverrides allows selected persistence fields to be overridden per sandbox.
@@ -98,6 +138,29 @@ type SandboxPersistenceOverrides struct {
 	Size string `json:"size,omitempty"`
 }
 
+// RotateAccessTokenRequest replaces the access token of a sandbox
+type RotateAccessTokenRequest struct {
+	// GracePeriod keeps the replaced token valid for this many seconds. By default it
+	// stops working at once.
+	GracePeriod int `json:"gracePeriod,omitempty"`
+}
+
+// ExtendTTLRequest sets a new expiry for a sandbox. Exactly one field must be set.
+type ExtendTTLRequest struct {
+	// TTL makes the sandbox expire this many seconds from now
+	TTL int `json:"ttl,omitempty"`
+	// ExpiresAt makes the sandbox expire at the given time
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// UpdateSandboxNetworkRequest changes the egress of a sandbox at runtime, under the
+// same rules as overrides.network on create.
+type UpdateSandboxNetworkRequest struct {
+	NetworkSpec
+	// Reset drops the per-sandbox override so the sandbox follows its template again
+	Reset bool `json:"reset,omitempty"`
+}
+
 type ExecRequest struct {
 	Command []string `json:"command" binding:"required"`
 	Timeout int      `json:"timeout"`
@@ -109,6 +172,34 @@ type ExecResponse struct {
 	Stderr   string `json:"stderr"`
 }
 
+// Exec stream event types
+const (
+	ExecStreamEventStdout = "stdout"
+	ExecStreamEventStderr = "stderr"
+	ExecStreamEventExit   = "exit"
+	ExecStreamEventError  = "error"
+)
+
+// ExecStreamEncodingBase64 marks output chunks that are not valid UTF-8 and are sent
+// base64-encoded instead
+const ExecStreamEncodingBase64 = "base64"
+
+// ExecStreamEvent is a single NDJSON line emitted by the streaming exec endpoint
+type ExecStreamEvent struct {
+	Type     string `json:"type"`               // "stdout", "stderr", "exit", "error"
+	Data     string `json:"data,omitempty"`     // output chunk (stdout/stderr)
+	Encoding string `json:"encoding,omitempty"` // "base64" when data is not UTF-8 text
+	ExitCode int    `json:"exit_code"`          // process exit code (exit)
+	Message  string `json:"message,omitempty"`  // error message (error)
+}
+
+// Archive formats accepted by directory uploads to /sandboxes/:id/files
+const (
+	ArchiveFormatTar   = "tar"
+	ArchiveFormatTarGz = "tar.gz"
+	ArchiveFormatZip   = "zip"
+)
+
 type SandboxListResponse struct {
 	Items []Sandbox `json:"items"`
 }
@@ -119,6 +210,8 @@ type SandboxMetadataListOptions struct {
 	DesiredState    string
 	LifecycleStatus string
 	DeletionPhase   string
+	Owner           string
+	Project         string
 	CreatedFrom     *time.Time
 	CreatedTo       *time.Time
 	DeletedFrom     *time.Time
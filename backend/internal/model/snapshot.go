@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+type SnapshotKind string
+
+const (
+	// SnapshotKindVolumeSnapshot is a CSI VolumeSnapshot of the sandbox volume.
+	SnapshotKindVolumeSnapshot SnapshotKind = "volume-snapshot"
+	// SnapshotKindArchive is a tar archive of the rootfs overlay kept by the control plane,
+	// used when the storage class cannot be snapshotted.
+	SnapshotKindArchive SnapshotKind = "archive"
+)
+
+type SnapshotStatus string
+
+const (
+	SnapshotStatusPending SnapshotStatus = "pending"
+	SnapshotStatusReady   SnapshotStatus = "ready"
+	SnapshotStatusFailed  SnapshotStatus = "failed"
+)
+
+// SandboxSnapshot is a captured root filesystem state of a persistent sandbox.
+// Snapshots outlive the sandbox they were taken from.
+type SandboxSnapshot struct {
+	ID               string         `json:"id"`
+	SandboxID        string         `json:"sandbox_id"`
+	Name             string         `json:"name,omitempty"`
+	Template         string         `json:"template"`
+	TemplateVersion  int            `json:"template_version"`
+	Kind             SnapshotKind   `json:"kind"`
+	Status           SnapshotStatus `json:"status"`
+	StatusReason     string         `json:"status_reason,omitempty"`
+	StorageClassName string         `json:"storage_class_name,omitempty"`
+	VolumeSize       string         `json:"volume_size,omitempty"`
+	SizeBytes        int64          `json:"size_bytes,omitempty"` // archive size (archive snapshots)
+	CreatedAt        time.Time      `json:"created_at"`
+	ReadyAt          *time.Time     `json:"ready_at,omitempty"`
+}
+
+// CreateSnapshotRequest captures the current state of a sandbox
+type CreateSnapshotRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+type SnapshotListResponse struct {
+	Items []SandboxSnapshot `json:"items"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Role is the set of permissions of a user. API keys act with the role of the user
+// that created them.
+type Role string
+
+const (
+	// RoleAdmin can do everything, including managing users
+	RoleAdmin Role = "admin"
+	// RoleDeveloper can create, change and exec into sandboxes and templates
+	RoleDeveloper Role = "developer"
+	// RoleViewer can only read
+	RoleViewer Role = "viewer"
+)
+
+// User is an account that can log in to the API server.
+type User struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateUserRequest creates a user. Role defaults to developer.
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,max=64"`
+	Password string `json:"password" binding:"required,min=8"`
+	Role     Role   `json:"role,omitempty"`
+}
+
+// UpdateUserRequest changes the fields of a user that are set. Disabling a user ends
+// their sessions and rejects their API keys until they are enabled again.
+type UpdateUserRequest struct {
+	Role     *Role   `json:"role,omitempty"`
+	Disabled *bool   `json:"disabled,omitempty"`
+	Password *string `json:"password,omitempty" binding:"omitempty,min=8"`
+}
+
+type UserListResponse struct {
+	Items []User `json:"items"`
+}
@@ -10,18 +10,23 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 const (
 	TokenEncryptionKeyEnv   = "SANDBOX_TOKEN_ENCRYPTION_KEY"
 	TokenEncryptionKeyIDEnv = "SANDBOX_TOKEN_ENCRYPTION_KEY_ID"
-	defaultTokenKeyID       = "v1"
+	// TokenEncryptionPreviousKeysEnv lists retired keys as comma-separated <key-id>:<key>
+	// pairs. They are only used to decrypt tokens encrypted before a key rotation.
+	TokenEncryptionPreviousKeysEnv = "SANDBOX_TOKEN_ENCRYPTION_PREVIOUS_KEYS"
+	defaultTokenKeyID              = "v1"
 )
 
 // TokenCipher provides encryption/decryption for sandbox access tokens.
 type TokenCipher struct {
-	aead  cipher.AEAD
-	keyID string
+	aead     cipher.AEAD
+	keyID    string
+	previous map[string]cipher.AEAD
 }
 
 // NewTokenCipherFromEnv initializes token encryption from environment variables.
@@ -31,26 +36,37 @@ func NewTokenCipherFromEnv() (*TokenCipher, error) {
 		return nil, fmt.Errorf("%s is required", TokenEncryptionKeyEnv)
 	}
 
-	key, err := parseAESKey(rawKey)
+	aead, err := newAEAD(rawKey)
 	if err != nil {
 		return nil, err
 	}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
-	}
-	aead, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gcm: %w", err)
-	}
-
 	keyID := os.Getenv(TokenEncryptionKeyIDEnv)
 	if keyID == "" {
 		keyID = defaultTokenKeyID
 	}
 
-	return &TokenCipher{aead: aead, keyID: keyID}, nil
+	previous := map[string]cipher.AEAD{}
+	for _, entry := range strings.Split(os.Getenv(TokenEncryptionPreviousKeysEnv), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, rawPrevious, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || id == keyID {
+			return nil, fmt.Errorf("invalid %s: entries must be <key-id>:<key> with IDs other than the current key ID", TokenEncryptionPreviousKeysEnv)
+		}
+		if previous[id], err = newAEAD(rawPrevious); err != nil {
+			return nil, fmt.Errorf("previous key %q: %w", id, err)
+		}
+	}
+
+	return &TokenCipher{aead: aead, keyID: keyID, previous: previous}, nil
+}
+
+// KeyID returns the ID of the key new tokens are encrypted with.
+func (c *TokenCipher) KeyID() string {
+	return c.keyID
 }
 
 // Encrypt encrypts token and returns base64 ciphertext, base64 nonce and key ID.
@@ -69,6 +85,23 @@ func (c *TokenCipher) Encrypt(token string) (ciphertext, nonce, keyID string, er
 
 // Decrypt decrypts token from base64 ciphertext and nonce.
 func (c *TokenCipher) Decrypt(ciphertext, nonce string) (string, error) {
+	return c.open(c.aead, ciphertext, nonce)
+}
+
+// DecryptWithKeyID decrypts a token encrypted with the key keyID, which is either the
+// current key or one of the previous keys. An empty keyID means the current key.
+func (c *TokenCipher) DecryptWithKeyID(ciphertext, nonce, keyID string) (string, error) {
+	aead := c.aead
+	if keyID != "" && keyID != c.keyID {
+		var ok bool
+		if aead, ok = c.previous[keyID]; !ok {
+			return "", fmt.Errorf("unknown token encryption key ID %q", keyID)
+		}
+	}
+	return c.open(aead, ciphertext, nonce)
+}
+
+func (c *TokenCipher) open(aead cipher.AEAD, ciphertext, nonce string) (string, error) {
 	ciphertextBytes, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
 		return "", fmt.Errorf("failed to decode ciphertext: %w", err)
@@ -77,7 +110,7 @@ func (c *TokenCipher) Decrypt(ciphertext, nonce string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to decode nonce: %w", err)
 	}
-	plain, err := c.aead.Open(nil, nonceBytes, ciphertextBytes, nil)
+	plain, err := aead.Open(nil, nonceBytes, ciphertextBytes, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt token: %w", err)
 	}
@@ -102,6 +135,22 @@ func GenerateToken(size int) (string, error) {
 	return hex.EncodeToString(buf), nil
 }
 
+func newAEAD(rawKey string) (cipher.AEAD, error) {
+	key, err := parseAESKey(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+	return aead, nil
+}
+
 func parseAESKey(raw string) ([]byte, error) {
 	if decoded, err := base64.StdEncoding.DecodeString(raw); err == nil && validAESKeyLen(len(decoded)) {
 		return decoded, nil
@@ -55,3 +55,42 @@ func TestHashTokenStable(t *testing.T) {
 		t.Fatalf("different tokens should have different hashes")
 	}
 }
+
+func TestTokenCipherDecryptsWithPreviousKeys(t *testing.T) {
+	t.Setenv(TokenEncryptionKeyEnv, "0123456789abcdef0123456789abcdef")
+	t.Setenv(TokenEncryptionKeyIDEnv, "k1")
+	t.Setenv(TokenEncryptionPreviousKeysEnv, "")
+	oldCipher, err := NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	ciphertext, nonce, oldKeyID, err := oldCipher.Encrypt("token-abc-123")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	t.Setenv(TokenEncryptionKeyEnv, "fedcba9876543210fedcba9876543210")
+	t.Setenv(TokenEncryptionKeyIDEnv, "k2")
+	t.Setenv(TokenEncryptionPreviousKeysEnv, "k1:0123456789abcdef0123456789abcdef")
+	rotated, err := NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() with previous keys error = %v", err)
+	}
+	if rotated.KeyID() != "k2" {
+		t.Fatalf("KeyID() = %q, want k2", rotated.KeyID())
+	}
+	plain, err := rotated.DecryptWithKeyID(ciphertext, nonce, oldKeyID)
+	if err != nil || plain != "token-abc-123" {
+		t.Fatalf("DecryptWithKeyID(previous key) = %q, %v", plain, err)
+	}
+	if _, err := rotated.DecryptWithKeyID(ciphertext, nonce, "k0"); err == nil {
+		t.Fatalf("DecryptWithKeyID() with unknown key ID should fail")
+	}
+
+	for _, bad := range []string{"k1", "k2:0123456789abcdef0123456789abcdef", "k1:short"} {
+		t.Setenv(TokenEncryptionPreviousKeysEnv, bad)
+		if _, err := NewTokenCipherFromEnv(); err == nil {
+			t.Fatalf("NewTokenCipherFromEnv() accepted previous keys %q", bad)
+		}
+	}
+}
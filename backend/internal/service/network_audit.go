@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+const (
+	defaultNetworkEventLimit = 100
+	maxNetworkEventLimit     = 1000
+)
+
+// NetworkObservation is an egress event reported by a NetworkEventSource.
+type NetworkObservation struct {
+	SandboxID     string
+	Kind          model.NetworkEventKind
+	Domain        string
+	DestinationIP string
+	Port          int
+	Protocol      string
+	Verdict       model.NetworkEventVerdict
+	ObservedAt    time.Time
+}
+
+// NetworkEventSource is a feed of egress observations, such as a Hubble flow export.
+type NetworkEventSource interface {
+	// Read returns the observations made since the previous call.
+	Read(ctx context.Context) ([]NetworkObservation, error)
+}
+
+// SandboxNetworkAuditService records the DNS queries and outbound connections of
+// sandboxes from a NetworkEventSource and serves them back per sandbox.
+type SandboxNetworkAuditService struct {
+	sandboxStore *store.SandboxStore
+	eventStore   *store.SandboxNetworkEventStore
+	source       NetworkEventSource
+}
+
+// NewSandboxNetworkAuditService creates the audit service. source may be nil, in which
+// case only already recorded events are served.
+func NewSandboxNetworkAuditService(sandboxStore *store.SandboxStore, eventStore *store.SandboxNetworkEventStore, source NetworkEventSource) *SandboxNetworkAuditService {
+	return &SandboxNetworkAuditService{
+		sandboxStore: sandboxStore,
+		eventStore:   eventStore,
+		source:       source,
+	}
+}
+
+func (s *SandboxNetworkAuditService) Start(interval time.Duration) {
+	if s.source == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := s.RunOnce(context.Background()); err != nil {
+				slog.Default().With("component", "sandbox_network_audit").Error("failed to collect network events", "error", err)
+			}
+		}
+	}()
+}
+
+// RunOnce reads pending observations from the source and stores those that belong to
+// a known sandbox.
+func (s *SandboxNetworkAuditService) RunOnce(ctx context.Context) error {
+	if s.source == nil {
+		return nil
+	}
+	observations, err := s.source.Read(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	records := make([]store.SandboxNetworkEventRecord, 0, len(observations))
+	for _, obs := range observations {
+		if obs.SandboxID == "" {
+			continue
+		}
+		exists, ok := known[obs.SandboxID]
+		if !ok {
+			rec, err := s.sandboxStore.GetByID(ctx, obs.SandboxID)
+			if err != nil {
+				return err
+			}
+			exists = rec != nil
+			known[obs.SandboxID] = exists
+		}
+		if !exists {
+			continue
+		}
+		observedAt := obs.ObservedAt
+		if observedAt.IsZero() {
+			observedAt = time.Now()
+		}
+		records = append(records, store.SandboxNetworkEventRecord{
+			SandboxID:     obs.SandboxID,
+			Kind:          string(obs.Kind),
+			Domain:        obs.Domain,
+			DestinationIP: obs.DestinationIP,
+			Port:          obs.Port,
+			Protocol:      obs.Protocol,
+			Verdict:       string(obs.Verdict),
+			ObservedAt:    observedAt.UTC(),
+		})
+	}
+	return s.eventStore.Append(ctx, records)
+}
+
+// ListEvents returns the recorded network events of a sandbox, newest first. Events of
+// deleted sandboxes stay available until they are purged.
+func (s *SandboxNetworkAuditService) ListEvents(ctx context.Context, sandboxID string, query *model.NetworkEventQuery) (*model.NetworkEventListResponse, error) {
+	switch query.Kind {
+	case "", model.NetworkEventDNS, model.NetworkEventConnection:
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidNetworkEventQuery, query.Kind)
+	}
+	limit := query.Limit
+	if limit < 0 || limit > maxNetworkEventLimit {
+		return nil, fmt.Errorf("%w: limit must be between 0 and %d", ErrInvalidNetworkEventQuery, maxNetworkEventLimit)
+	}
+	if limit == 0 {
+		limit = defaultNetworkEventLimit
+	}
+
+	sandbox, err := s.sandboxStore.GetByID(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	if sandbox == nil {
+		return nil, ErrSandboxNotFound
+	}
+
+	filter := store.SandboxNetworkEventFilter{Kind: string(query.Kind), Limit: limit}
+	if query.Since != nil {
+		filter.Since = query.Since.UTC()
+	}
+	records, err := s.eventStore.ListBySandbox(ctx, sandboxID, filter)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]model.SandboxNetworkEvent, 0, len(records))
+	for _, rec := range records {
+		items = append(items, model.SandboxNetworkEvent{
+			ID:            rec.ID,
+			SandboxID:     rec.SandboxID,
+			Kind:          model.NetworkEventKind(rec.Kind),
+			Domain:        rec.Domain,
+			DestinationIP: rec.DestinationIP,
+			Port:          rec.Port,
+			Protocol:      rec.Protocol,
+			Verdict:       model.NetworkEventVerdict(rec.Verdict),
+			ObservedAt:    rec.ObservedAt,
+		})
+	}
+	return &model.NetworkEventListResponse{Items: items}, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+// maxHubbleReadBytes bounds how much of the export file is consumed per Read, so a
+// large backlog is worked off over several runs.
+const maxHubbleReadBytes = 16 << 20
+
+// HubbleFileSource reads egress observations from a Hubble flow export file (the
+// JSON lines written by `hubble-export-file-path`). Reading starts at the end of the
+// file, so flows exported while the server was down are not recorded. Rotation and
+// truncation of the file are detected and the new file is read from the start.
+type HubbleFileSource struct {
+	path        string
+	inNamespace func(namespace string) bool
+
+	mu     sync.Mutex
+	file   os.FileInfo
+	offset int64
+}
+
+// NewHubbleFileSource creates a source for flows of sandbox pods in namespace.
+func NewHubbleFileSource(path, namespace string) *HubbleFileSource {
+	return &HubbleFileSource{
+		path:        path,
+		inNamespace: func(ns string) bool { return ns == namespace },
+		offset:      -1,
+	}
+}
+
+// SetNamespaceFilter makes the source read flows of sandbox pods in every namespace
+// match accepts, such as the namespaces of projects.
+func (s *HubbleFileSource) SetNamespaceFilter(match func(namespace string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inNamespace = match
+}
+
+func (s *HubbleFileSource) Read(ctx context.Context) ([]NetworkObservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case s.offset < 0:
+		s.offset = info.Size()
+	case s.file != nil && !os.SameFile(s.file, info), info.Size() < s.offset:
+		s.offset = 0
+	}
+	s.file = info
+
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxHubbleReadBytes))
+	if err != nil {
+		return nil, err
+	}
+	// A partially written last line is read again next time
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, nil
+	}
+	s.offset += int64(end + 1)
+
+	var observations []NetworkObservation
+	for _, line := range bytes.Split(data[:end], []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry hubbleExportEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			slog.Default().With("component", "sandbox_network_audit").Debug("skipping malformed hubble flow", "error", err)
+			continue
+		}
+		if obs, ok := s.observation(entry.Flow); ok {
+			observations = append(observations, obs)
+		}
+	}
+	return observations, nil
+}
+
+// observation converts an egress flow of a sandbox pod. DNS requests seen by the DNS
+// proxy become DNS events; TCP connection attempts and UDP datagrams become connection
+// events. Replies, ingress and the L4 side of DNS lookups are skipped.
+func (s *HubbleFileSource) observation(flow *hubbleFlow) (NetworkObservation, bool) {
+	if flow == nil || flow.Source == nil || !s.inNamespace(flow.Source.Namespace) || flow.IsReply {
+		return NetworkObservation{}, false
+	}
+	if flow.TrafficDirection != "" && flow.TrafficDirection != "EGRESS" {
+		return NetworkObservation{}, false
+	}
+	sandboxID := hubbleSandboxID(flow.Source.Labels)
+	if sandboxID == "" {
+		return NetworkObservation{}, false
+	}
+	obs := NetworkObservation{
+		SandboxID:  sandboxID,
+		Verdict:    model.NetworkVerdictAllowed,
+		ObservedAt: flow.Time,
+	}
+	if flow.Verdict == "DROPPED" || flow.Verdict == "ERROR" {
+		obs.Verdict = model.NetworkVerdictDenied
+	}
+	if flow.IP != nil {
+		obs.DestinationIP = flow.IP.Destination
+	}
+
+	if flow.L7 != nil {
+		if flow.L7.DNS == nil || flow.L7.Type != "REQUEST" {
+			return NetworkObservation{}, false
+		}
+		obs.Kind = model.NetworkEventDNS
+		obs.Domain = strings.TrimSuffix(flow.L7.DNS.Query, ".")
+		obs.Protocol = "UDP"
+		obs.Port = 53
+		return obs, obs.Domain != ""
+	}
+
+	if flow.L4 == nil {
+		return NetworkObservation{}, false
+	}
+	switch {
+	case flow.L4.TCP != nil:
+		// Only the opening SYN stands for a connection
+		if flow.L4.TCP.Flags == nil || !flow.L4.TCP.Flags.SYN || flow.L4.TCP.Flags.ACK {
+			return NetworkObservation{}, false
+		}
+		obs.Protocol = "TCP"
+		obs.Port = flow.L4.TCP.DestinationPort
+	case flow.L4.UDP != nil:
+		obs.Protocol = "UDP"
+		obs.Port = flow.L4.UDP.DestinationPort
+	default:
+		return NetworkObservation{}, false
+	}
+	if obs.Port == 53 {
+		return NetworkObservation{}, false
+	}
+	obs.Kind = model.NetworkEventConnection
+	if len(flow.DestinationNames) > 0 {
+		obs.Domain = strings.TrimSuffix(flow.DestinationNames[0], ".")
+	}
+	return obs, true
+}
+
+// hubbleSandboxID extracts the sandbox ID from Cilium endpoint labels such as
+// "k8s:sandbox-id=abc".
+func hubbleSandboxID(labels []string) string {
+	prefix := "k8s:" + k8s.LabelSandboxID + "="
+	for _, label := range labels {
+		if id, ok := strings.CutPrefix(label, prefix); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+type hubbleExportEntry struct {
+	Flow *hubbleFlow `json:"flow"`
+}
+
+type hubbleFlow struct {
+	Time             time.Time       `json:"time"`
+	Verdict          string          `json:"verdict"`
+	TrafficDirection string          `json:"traffic_direction"`
+	IsReply          bool            `json:"is_reply"`
+	IP               *hubbleIP       `json:"IP"`
+	L4               *hubbleL4       `json:"l4"`
+	L7               *hubbleL7       `json:"l7"`
+	Source           *hubbleEndpoint `json:"source"`
+	DestinationNames []string        `json:"destination_names"`
+}
+
+type hubbleIP struct {
+	Destination string `json:"destination"`
+}
+
+type hubbleL4 struct {
+	TCP *struct {
+		DestinationPort int `json:"destination_port"`
+		Flags           *struct {
+			SYN bool `json:"SYN"`
+			ACK bool `json:"ACK"`
+		} `json:"flags"`
+	} `json:"TCP"`
+	UDP *struct {
+		DestinationPort int `json:"destination_port"`
+	} `json:"UDP"`
+}
+
+type hubbleL7 struct {
+	Type string `json:"type"`
+	DNS  *struct {
+		Query string `json:"query"`
+	} `json:"dns"`
+}
+
+type hubbleEndpoint struct {
+	Namespace string   `json:"namespace"`
+	Labels    []string `json:"labels"`
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+type fakeNetworkEventSource struct {
+	batches [][]NetworkObservation
+}
+
+func (f *fakeNetworkEventSource) Read(ctx context.Context) ([]NetworkObservation, error) {
+	if len(f.batches) == 0 {
+		return nil, nil
+	}
+	batch := f.batches[0]
+	f.batches = f.batches[1:]
+	return batch, nil
+}
+
+func TestNetworkAuditRecordsEventsOfKnownSandboxes(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	sandboxStore := store.NewSandboxStore()
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("audit1", false, "running")); err != nil {
+		t.Fatalf("Create sandbox error = %v", err)
+	}
+	now := time.Now().UTC()
+	source := &fakeNetworkEventSource{batches: [][]NetworkObservation{{
+		{SandboxID: "audit1", Kind: model.NetworkEventDNS, Domain: "github.com", Verdict: model.NetworkVerdictAllowed, ObservedAt: now.Add(-time.Second)},
+		{SandboxID: "audit1", Kind: model.NetworkEventConnection, DestinationIP: "1.2.3.4", Port: 22, Protocol: "TCP", Verdict: model.NetworkVerdictDenied, ObservedAt: now},
+		{SandboxID: "unknown", Kind: model.NetworkEventDNS, Domain: "example.com", Verdict: model.NetworkVerdictAllowed, ObservedAt: now},
+	}}}
+	svc := NewSandboxNetworkAuditService(sandboxStore, store.NewSandboxNetworkEventStore(), source)
+
+	if err := svc.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	resp, err := svc.ListEvents(ctx, "audit1", &model.NetworkEventQuery{})
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].Verdict != model.NetworkVerdictDenied || resp.Items[1].Domain != "github.com" {
+		t.Fatalf("ListEvents() = %+v", resp.Items)
+	}
+	resp, err = svc.ListEvents(ctx, "audit1", &model.NetworkEventQuery{Kind: model.NetworkEventDNS})
+	if err != nil || len(resp.Items) != 1 {
+		t.Fatalf("ListEvents(kind=dns) = %+v, %v", resp, err)
+	}
+
+	if _, err := svc.ListEvents(ctx, "unknown", &model.NetworkEventQuery{}); !errors.Is(err, ErrSandboxNotFound) {
+		t.Fatalf("ListEvents(unknown) error = %v, want ErrSandboxNotFound", err)
+	}
+	if _, err := svc.ListEvents(ctx, "audit1", &model.NetworkEventQuery{Kind: "http"}); !errors.Is(err, ErrInvalidNetworkEventQuery) {
+		t.Fatalf("ListEvents(kind=http) error = %v, want ErrInvalidNetworkEventQuery", err)
+	}
+	if _, err := svc.ListEvents(ctx, "audit1", &model.NetworkEventQuery{Limit: maxNetworkEventLimit + 1}); !errors.Is(err, ErrInvalidNetworkEventQuery) {
+		t.Fatalf("ListEvents(limit) error = %v, want ErrInvalidNetworkEventQuery", err)
+	}
+}
+
+func TestHubbleFileSourceReadsSandboxEgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	if err := os.WriteFile(path, []byte(`{"flow":{"source":{"namespace":"liteboxd-sandbox","labels":["k8s:sandbox-id=old"]}}}`+"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	source := NewHubbleFileSource(path, k8s.DefaultSandboxNamespace)
+	ctx := context.Background()
+
+	// Flows exported before the first read are skipped
+	if got, err := source.Read(ctx); err != nil || len(got) != 0 {
+		t.Fatalf("first Read() = %+v, %v; want nothing", got, err)
+	}
+
+	src := `"source":{"namespace":"` + k8s.DefaultSandboxNamespace + `","labels":["k8s:app=liteboxd","k8s:sandbox-id=sb1"]}`
+	lines := []string{
+		`{"flow":{"time":"2026-01-02T03:04:05Z","verdict":"FORWARDED","traffic_direction":"EGRESS",` + src + `,"IP":{"destination":"10.96.0.10"},"l7":{"type":"REQUEST","dns":{"query":"github.com."}}}}`,
+		`{"flow":{"time":"2026-01-02T03:04:05Z","verdict":"FORWARDED","traffic_direction":"EGRESS",` + src + `,"IP":{"destination":"10.96.0.10"},"l7":{"type":"RESPONSE","dns":{"query":"github.com."}}}}`,
+		`{"flow":{"time":"2026-01-02T03:04:06Z","verdict":"DROPPED","traffic_direction":"EGRESS",` + src + `,"IP":{"destination":"140.82.112.3"},"l4":{"TCP":{"destination_port":22,"flags":{"SYN":true}}},"destination_names":["github.com"]}}`,
+		`{"flow":{"time":"2026-01-02T03:04:07Z","verdict":"FORWARDED","traffic_direction":"EGRESS",` + src + `,"IP":{"destination":"140.82.112.3"},"l4":{"TCP":{"destination_port":443,"flags":{"ACK":true}}}}}`,
+		`{"flow":{"time":"2026-01-02T03:04:08Z","verdict":"FORWARDED","traffic_direction":"EGRESS","source":{"namespace":"kube-system","labels":["k8s:sandbox-id=sb1"]},"l4":{"UDP":{"destination_port":123}}}}`,
+		`not json`,
+		`{"flow":{"time":"2026-01-02T03:04:09Z","verdict":"FORWARDED","traffic_direction":"EGRESS",` + src + `,"l4":{"UDP":{"destination_port":123}}`,
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			line += "\n"
+		}
+		if _, err := f.WriteString(line); err != nil {
+			t.Fatalf("WriteString() error = %v", err)
+		}
+	}
+
+	got, err := source.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Read() = %+v, want a DNS query and a dropped connection", got)
+	}
+	if got[0].SandboxID != "sb1" || got[0].Kind != model.NetworkEventDNS || got[0].Domain != "github.com" {
+		t.Fatalf("DNS observation = %+v", got[0])
+	}
+	if got[1].Kind != model.NetworkEventConnection || got[1].Verdict != model.NetworkVerdictDenied || got[1].Port != 22 || got[1].Domain != "github.com" {
+		t.Fatalf("connection observation = %+v", got[1])
+	}
+
+	// The partially written line is picked up once complete
+	if _, err := f.WriteString("}}\n"); err != nil {
+		t.Fatalf("WriteString() error = %v", err)
+	}
+	f.Close()
+	got, err = source.Read(ctx)
+	if err != nil || len(got) != 1 || got[0].Protocol != "UDP" || got[0].Port != 123 {
+		t.Fatalf("Read() after completing line = %+v, %v", got, err)
+	}
+
+	// A truncated file is read from the start
+	if err := os.WriteFile(path, []byte(lines[0]+"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	got, err = source.Read(ctx)
+	if err != nil || len(got) != 1 || got[0].Kind != model.NetworkEventDNS {
+		t.Fatalf("Read() after truncation = %+v, %v", got, err)
+	}
+}
@@ -35,17 +35,19 @@ func (r *SandboxNetworkPolicyReconciler) Start(interval time.Duration) {
 }
 
 func (r *SandboxNetworkPolicyReconciler) RunOnce(ctx context.Context) error {
-	if r.k8sClient.GetDynamicClient() == nil {
-		return nil
-	}
-
 	records, err := r.sandboxStore.ListForReconcile(ctx)
 	if err != nil {
 		return err
 	}
+	if err := r.reconcileNetworkGroups(ctx, records); err != nil {
+		return err
+	}
+	if r.k8sClient.GetDynamicClient() == nil {
+		return nil
+	}
 
 	manager := k8s.NewNetworkPolicyManager(r.k8sClient)
-	desiredPolicies := make(map[string][]string, len(records))
+	desiredPolicies := make(map[string]*k8s.NetworkSpec, len(records))
 	recordsByID := make(map[string]store.SandboxRecord, len(records))
 
 	applied := 0
@@ -56,7 +58,7 @@ func (r *SandboxNetworkPolicyReconciler) RunOnce(ctx context.Context) error {
 		rec := records[i]
 		recordsByID[rec.ID] = rec
 
-		domains, shouldExist, err := r.desiredAllowedDomains(ctx, &rec)
+		network, shouldExist, err := r.desiredNetwork(ctx, &rec)
 		if err != nil {
 			failures++
 			logWithSandboxID(ctx, rec.ID).Warn("failed to resolve desired network policy state", "error", err)
@@ -66,10 +68,10 @@ func (r *SandboxNetworkPolicyReconciler) RunOnce(ctx context.Context) error {
 			continue
 		}
 
-		desiredPolicies[rec.ID] = domains
-		if err := manager.ApplyDomainAllowlistPolicy(ctx, rec.ID, domains); err != nil {
+		desiredPolicies[rec.ID] = network
+		if err := manager.ApplyEgressPolicy(ctx, rec.ID, network); err != nil {
 			failures++
-			logWithSandboxID(ctx, rec.ID).Warn("failed to reconcile domain allowlist policy", "error", err)
+			logWithSandboxID(ctx, rec.ID).Warn("failed to reconcile egress policy", "error", err)
 			continue
 		}
 		applied++
@@ -91,7 +93,7 @@ func (r *SandboxNetworkPolicyReconciler) RunOnce(ctx context.Context) error {
 
 		rec, exists := recordsByID[sandboxID]
 		if exists {
-			_, shouldExist, err := r.desiredAllowedDomains(ctx, &rec)
+			_, shouldExist, err := r.desiredNetwork(ctx, &rec)
 			if err != nil {
 				failures++
 				logWithSandboxID(ctx, sandboxID).Warn("failed to confirm network policy cleanup state", "error", err)
@@ -102,7 +104,7 @@ func (r *SandboxNetworkPolicyReconciler) RunOnce(ctx context.Context) error {
 			}
 		}
 
-		if err := manager.DeleteDomainAllowlistPolicy(ctx, sandboxID); err != nil {
+		if err := manager.DeleteDomainAllowlistPolicy(k8s.WithNamespace(ctx, policies[i].GetNamespace()), sandboxID); err != nil {
 			failures++
 			logWithSandboxID(ctx, sandboxID).Warn("failed to delete domain allowlist policy", "error", err)
 			continue
@@ -122,24 +124,85 @@ func (r *SandboxNetworkPolicyReconciler) RunOnce(ctx context.Context) error {
 	return nil
 }
 
-func (r *SandboxNetworkPolicyReconciler) desiredAllowedDomains(ctx context.Context, rec *store.SandboxRecord) ([]string, bool, error) {
+// reconcileNetworkGroups keeps the group policy and peer service of every live network
+// group member, and removes those of sandboxes that are gone.
+func (r *SandboxNetworkPolicyReconciler) reconcileNetworkGroups(ctx context.Context, records []store.SandboxRecord) error {
+	manager := k8s.NewNetworkPolicyManager(r.k8sClient)
+	desired := make(map[string]struct{})
+	failures := 0
+	for i := range records {
+		rec := &records[i]
+		if rec.NetworkGroup == "" || !sandboxNetworkActive(rec) {
+			continue
+		}
+		desired[rec.ID] = struct{}{}
+		if err := manager.ApplyNetworkGroup(ctx, rec.ID, rec.NetworkGroup, toK8sPorts(rec.GroupPorts())); err != nil {
+			failures++
+			logWithSandboxID(ctx, rec.ID).Warn("failed to reconcile network group", "error", err)
+		}
+	}
+
+	members, err := manager.ListNetworkGroupMembers(ctx)
+	if err != nil {
+		return err
+	}
+	deleted := 0
+	for _, id := range members {
+		if _, ok := desired[id]; ok {
+			continue
+		}
+		if err := manager.DeleteNetworkGroup(ctx, id); err != nil {
+			failures++
+			logWithSandboxID(ctx, id).Warn("failed to delete network group resources", "error", err)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 || failures > 0 {
+		slog.Default().With("component", "sandbox_network_policy_reconciler").Info(
+			"network group reconcile completed",
+			"member_count", len(desired),
+			"deleted_count", deleted,
+			"failure_count", failures,
+		)
+	}
+	return nil
+}
+
+// sandboxNetworkActive reports whether a sandbox should still have network policies.
+func sandboxNetworkActive(rec *store.SandboxRecord) bool {
 	if rec.DesiredState == store.DesiredStateDeleted {
-		return nil, false, nil
+		return false
 	}
 	switch rec.LifecycleStatus {
 	case "deleted", "terminating", "failed", "succeeded":
-		return nil, false, nil
+		return false
 	}
+	return true
+}
 
-	version, err := r.templateStore.GetVersionByName(ctx, rec.TemplateName, rec.TemplateVersion)
-	if err != nil {
-		return nil, false, err
-	}
-	if version == nil || version.Spec.Network == nil {
+// desiredNetwork returns the network a sandbox's egress policy renders, and whether the
+// sandbox should have one at all.
+func (r *SandboxNetworkPolicyReconciler) desiredNetwork(ctx context.Context, rec *store.SandboxRecord) (*k8s.NetworkSpec, bool, error) {
+	if !sandboxNetworkActive(rec) {
 		return nil, false, nil
 	}
-	if !version.Spec.Network.AllowInternetAccess || len(version.Spec.Network.AllowedDomains) == 0 {
+
+	// A per-sandbox override takes precedence over the template
+	network := rec.NetworkOverride()
+	if network == nil {
+		version, err := r.templateStore.GetVersionByName(ctx, rec.TemplateName, rec.TemplateVersion)
+		if err != nil {
+			return nil, false, err
+		}
+		if version == nil {
+			return nil, false, nil
+		}
+		network = version.Spec.Network
+	}
+	k8sNetwork := toK8sNetwork(network)
+	if !k8sNetwork.NeedsEgressPolicy() {
 		return nil, false, nil
 	}
-	return version.Spec.Network.AllowedDomains, true, nil
+	return k8sNetwork, true, nil
 }
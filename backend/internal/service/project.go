@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/google/uuid"
+)
+
+// ProjectService manages projects and the Kubernetes namespaces their sandboxes run in.
+type ProjectService struct {
+	k8sClient    *k8s.Client
+	store        *store.ProjectStore
+	sandboxStore *store.SandboxStore
+}
+
+// NewProjectService creates a new ProjectService
+func NewProjectService(k8sClient *k8s.Client, projectStore *store.ProjectStore, sandboxStore *store.SandboxStore) *ProjectService {
+	return &ProjectService{
+		k8sClient:    k8sClient,
+		store:        projectStore,
+		sandboxStore: sandboxStore,
+	}
+}
+
+// projectNamespace returns the namespace of a project: the sandbox namespace suffixed
+// with the project name.
+func (s *ProjectService) projectNamespace(name string) string {
+	return s.k8sClient.SandboxNamespace() + "-" + name
+}
+
+func (s *ProjectService) validateName(name string) error {
+	if err := validateName(name); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProject, err)
+	}
+	if ns := s.projectNamespace(name); len(ns) > 63 {
+		return fmt.Errorf("%w: namespace %s is longer than 63 characters", ErrInvalidProject, ns)
+	}
+	return nil
+}
+
+// Create creates a project along with its namespace and the default network policies
+// in it.
+func (s *ProjectService) Create(ctx context.Context, req *model.CreateProjectRequest) (*model.Project, error) {
+	if err := s.validateName(req.Name); err != nil {
+		return nil, err
+	}
+	existing, err := s.store.GetByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: %s", ErrProjectExists, req.Name)
+	}
+
+	rec := &store.ProjectRecord{
+		ID:          uuid.NewString(),
+		Name:        req.Name,
+		Namespace:   s.projectNamespace(req.Name),
+		Description: req.Description,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.ensureNamespace(ctx, rec); err != nil {
+		return nil, err
+	}
+	if err := s.store.Create(ctx, rec); err != nil {
+		return nil, err
+	}
+	return recordToProject(rec), nil
+}
+
+// Get returns a project by name.
+func (s *ProjectService) Get(ctx context.Context, name string) (*model.Project, error) {
+	rec, err := s.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return recordToProject(rec), nil
+}
+
+// List returns all projects.
+func (s *ProjectService) List(ctx context.Context) (*model.ProjectListResponse, error) {
+	records, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]model.Project, 0, len(records))
+	for i := range records {
+		items = append(items, *recordToProject(&records[i]))
+	}
+	return &model.ProjectListResponse{Items: items}, nil
+}
+
+// Delete deletes a project and its namespace. Projects that still have sandboxes
+// can't be deleted.
+func (s *ProjectService) Delete(ctx context.Context, name string) error {
+	rec, err := s.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	count, err := s.sandboxStore.CountActiveInProject(ctx, name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %d sandboxes left in %s", ErrProjectNotEmpty, count, name)
+	}
+	if err := s.k8sClient.DeleteProjectNamespace(ctx, rec.Namespace); err != nil {
+		return err
+	}
+	if _, err := s.store.Delete(ctx, name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EnsureNamespaces creates the namespaces of all projects that are missing, and
+// registers them with the Kubernetes client so that reconcile and cleanup loops
+// cover them. Called once at startup, before the default network policies are applied.
+func (s *ProjectService) EnsureNamespaces(ctx context.Context) error {
+	records, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		if err := s.k8sClient.EnsureProjectNamespace(ctx, records[i].Name, records[i].Namespace); err != nil {
+			return fmt.Errorf("failed to ensure namespace of project %s: %w", records[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// RegisterNamespaces registers the namespaces of all projects with the Kubernetes
+// client without creating them, for processes that don't manage projects.
+func (s *ProjectService) RegisterNamespaces(ctx context.Context) error {
+	records, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		s.k8sClient.AddSandboxNamespace(records[i].Namespace)
+	}
+	return nil
+}
+
+// StartNamespaceSync registers the namespaces of projects created by the API server
+// every interval.
+func (s *ProjectService) StartNamespaceSync(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := s.RegisterNamespaces(context.Background()); err != nil {
+				slog.Default().With("component", "project").Warn("failed to sync project namespaces", "error", err)
+			}
+		}
+	}()
+}
+
+// resolve returns the project a request of the caller runs in. API keys scoped to a
+// project default to it and can't use another one. An empty result means no project.
+func (s *ProjectService) resolve(ctx context.Context, name string) (*store.ProjectRecord, error) {
+	if p := auth.PrincipalFromContext(ctx); p != nil && p.Project != "" {
+		if name == "" {
+			name = p.Project
+		} else if name != p.Project {
+			return nil, fmt.Errorf("%w: %s", ErrProjectScope, p.Project)
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	return s.get(ctx, name)
+}
+
+func (s *ProjectService) get(ctx context.Context, name string) (*store.ProjectRecord, error) {
+	rec, err := s.store.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+	return rec, nil
+}
+
+func (s *ProjectService) ensureNamespace(ctx context.Context, rec *store.ProjectRecord) error {
+	if err := s.k8sClient.EnsureProjectNamespace(ctx, rec.Name, rec.Namespace); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %w", rec.Namespace, err)
+	}
+	if err := k8s.NewNetworkPolicyManager(s.k8sClient).EnsureNamespacePolicies(ctx, rec.Namespace); err != nil {
+		return fmt.Errorf("failed to apply network policies in %s: %w", rec.Namespace, err)
+	}
+	return nil
+}
+
+// projectScope returns the project the caller's API key is scoped to, or "".
+func projectScope(ctx context.Context) string {
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		return p.Project
+	}
+	return ""
+}
+
+// templateScope returns the templates the caller's API key is limited to, or nil.
+func templateScope(ctx context.Context) []string {
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		return p.Templates
+	}
+	return nil
+}
+
+func recordToProject(rec *store.ProjectRecord) *model.Project {
+	return &model.Project{
+		ID:          rec.ID,
+		Name:        rec.Name,
+		Namespace:   rec.Namespace,
+		Description: rec.Description,
+		CreatedAt:   rec.CreatedAt,
+	}
+}
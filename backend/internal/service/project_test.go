@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func TestCreateSandboxInProject(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+
+	client := k8s.NewClientForTest()
+	sandboxStore := store.NewSandboxStore()
+	client.SetNamespaceResolver(sandboxStore.GetClusterNamespace)
+	projectSvc := NewProjectService(client, store.NewProjectStore(), sandboxStore)
+
+	project, err := projectSvc.Create(ctx, &model.CreateProjectRequest{Name: "team-a"})
+	if err != nil {
+		t.Fatalf("Create project error = %v", err)
+	}
+	if project.Namespace != "liteboxd-sandbox-team-a" {
+		t.Fatalf("Namespace = %q, want liteboxd-sandbox-team-a", project.Namespace)
+	}
+	if _, err := projectSvc.Create(ctx, &model.CreateProjectRequest{Name: "team-a"}); !errors.Is(err, ErrProjectExists) {
+		t.Fatalf("second Create() error = %v, want ErrProjectExists", err)
+	}
+	if _, err := projectSvc.Create(ctx, &model.CreateProjectRequest{Name: "Team_A"}); !errors.Is(err, ErrInvalidProject) {
+		t.Fatalf("Create() with an invalid name error = %v, want ErrInvalidProject", err)
+	}
+
+	templateSvc := NewTemplateService()
+	spec := model.TemplateSpec{Image: "busybox:1.36", Command: []string{"sh", "-c", "sleep 30"}, StartupTimeout: 1}
+	if _, err := templateSvc.Create(ctx, &model.CreateTemplateRequest{Name: "team-tpl", Spec: spec, Project: "team-a"}); err != nil {
+		t.Fatalf("Create template error = %v", err)
+	}
+
+	svc := NewSandboxService(client, sandboxStore, cipher)
+	svc.SetTemplateService(templateSvc)
+	svc.SetProjectService(projectSvc)
+
+	if _, err := svc.Create(ctx, &model.CreateSandboxRequest{Template: "team-tpl"}); !errors.Is(err, ErrInvalidProject) {
+		t.Fatalf("Create() outside the template's project error = %v, want ErrInvalidProject", err)
+	}
+
+	// API keys scoped to a project default to it
+	scoped := auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-alice", Username: "alice", Role: model.RoleDeveloper, Project: "team-a"})
+	sb, err := svc.Create(scoped, &model.CreateSandboxRequest{Template: "team-tpl"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sb.Project != "team-a" {
+		t.Fatalf("Project = %q, want team-a", sb.Project)
+	}
+	pods, err := client.ListPods(ctx)
+	if err != nil {
+		t.Fatalf("ListPods() error = %v", err)
+	}
+	if len(pods.Items) != 1 || pods.Items[0].Namespace != "liteboxd-sandbox-team-a" {
+		t.Fatalf("pods = %+v, want one in liteboxd-sandbox-team-a", pods.Items)
+	}
+	if _, err := svc.Create(scoped, &model.CreateSandboxRequest{Template: "team-tpl", Project: "team-b"}); !errors.Is(err, ErrProjectScope) {
+		t.Fatalf("Create() in another project error = %v, want ErrProjectScope", err)
+	}
+
+	if err := projectSvc.Delete(ctx, "team-a"); !errors.Is(err, ErrProjectNotEmpty) {
+		t.Fatalf("Delete() error = %v, want ErrProjectNotEmpty", err)
+	}
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// QuotaExceededError is returned when creating or starting a sandbox would exceed a
+// quota. It matches ErrQuotaExceeded.
+type QuotaExceededError struct {
+	Violation model.QuotaViolation
+}
+
+func (e *QuotaExceededError) Error() string {
+	v := e.Violation
+	msg := fmt.Sprintf("%s quota of %s %s exceeded: requested %s", v.Resource, v.Scope, v.Subject, v.Requested)
+	if v.Used != "" {
+		msg += ", used " + v.Used
+	}
+	return msg + ", limit " + v.Limit
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// QuotaService manages quotas and checks sandboxes against them. A sandbox counts
+// towards the quotas of the user and API key that created it and of its project.
+type QuotaService struct {
+	store        *store.QuotaStore
+	sandboxStore *store.SandboxStore
+	authStore    *store.AuthStore
+	projectStore *store.ProjectStore
+
+	// mu serializes checks with the reservations they write, so that concurrent
+	// requests can't both take the last free slot of a quota.
+	mu sync.Mutex
+}
+
+// NewQuotaService creates a new QuotaService
+func NewQuotaService(quotaStore *store.QuotaStore, sandboxStore *store.SandboxStore, authStore *store.AuthStore, projectStore *store.ProjectStore) *QuotaService {
+	return &QuotaService{
+		store:        quotaStore,
+		sandboxStore: sandboxStore,
+		authStore:    authStore,
+		projectStore: projectStore,
+	}
+}
+
+// quotaSubject is a user ID, API key ID or project name a sandbox counts towards.
+type quotaSubject struct {
+	scope model.QuotaScope
+	id    string
+}
+
+// quotaSubjects returns the subjects of a sandbox, skipping empty ones.
+func quotaSubjects(userID, apiKeyID, project string) []quotaSubject {
+	var subjects []quotaSubject
+	if userID != "" {
+		subjects = append(subjects, quotaSubject{model.QuotaScopeUser, userID})
+	}
+	if apiKeyID != "" {
+		subjects = append(subjects, quotaSubject{model.QuotaScopeAPIKey, apiKeyID})
+	}
+	if project != "" {
+		subjects = append(subjects, quotaSubject{model.QuotaScopeProject, project})
+	}
+	return subjects
+}
+
+// quotaDemand is what a sandbox adds to the usage of its subjects.
+type quotaDemand struct {
+	sandboxes int
+	cpu       resource.Quantity
+	memory    resource.Quantity
+	storage   resource.Quantity
+	// ttl is checked when set; 0 means the sandbox never expires
+	ttl *int
+}
+
+// quotaUsage is what the active sandboxes of a subject hold.
+type quotaUsage struct {
+	sandboxes int
+	cpu       resource.Quantity
+	memory    resource.Quantity
+	storage   resource.Quantity
+}
+
+// quantityString formats a quantity, leaving zero ones empty.
+func quantityString(q resource.Quantity) string {
+	if q.IsZero() {
+		return ""
+	}
+	return q.String()
+}
+
+// parseQuantity parses a Kubernetes quantity, treating invalid ones as zero; they
+// are rejected when the sandbox is created.
+func parseQuantity(s string) resource.Quantity {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return resource.Quantity{}
+	}
+	return q
+}
+
+// Reserve checks demand against the quotas of subjects, ignoring the sandbox
+// excludeID, and holds the admitted resources in a reservation. Release must be
+// called once the sandbox counts towards the quotas itself, or when admitting it
+// failed.
+func (s *QuotaService) Reserve(ctx context.Context, subjects []quotaSubject, demand quotaDemand, excludeID string) (release func(), err error) {
+	rec := &store.QuotaReservationRecord{
+		ID:        uuid.NewString(),
+		CPU:       quantityString(demand.cpu),
+		Memory:    quantityString(demand.memory),
+		Storage:   quantityString(demand.storage),
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, subject := range subjects {
+		switch subject.scope {
+		case model.QuotaScopeUser:
+			rec.OwnerID = subject.id
+		case model.QuotaScopeAPIKey:
+			rec.OwnerAPIKeyID = subject.id
+		case model.QuotaScopeProject:
+			rec.Project = subject.id
+		}
+	}
+
+	s.mu.Lock()
+	err = s.store.Reserve(ctx, rec, func(list store.ResourceLister) error {
+		for _, subject := range subjects {
+			if err := s.check(ctx, list, subject, demand, excludeID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if err := s.store.DeleteReservation(context.Background(), rec.ID); err != nil {
+				slog.Default().With("component", "quota").Warn("failed to release quota reservation", "error", err)
+			}
+		})
+	}, nil
+}
+
+// CheckTTL checks a TTL in seconds against the quotas of subjects.
+func (s *QuotaService) CheckTTL(ctx context.Context, subjects []quotaSubject, ttl int) error {
+	for _, subject := range subjects {
+		if err := s.check(ctx, s.sandboxStore.ListActiveResources, subject, quotaDemand{ttl: &ttl}, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *QuotaService) check(ctx context.Context, list store.ResourceLister, subject quotaSubject, demand quotaDemand, excludeID string) error {
+	quota, err := s.effective(ctx, subject)
+	if err != nil || quota == nil {
+		return err
+	}
+	exceeded := func(name, limit, used, requested string) error {
+		return &QuotaExceededError{Violation: model.QuotaViolation{
+			Scope:     subject.scope,
+			Subject:   subject.id,
+			Resource:  name,
+			Limit:     limit,
+			Used:      used,
+			Requested: requested,
+		}}
+	}
+
+	if demand.ttl != nil && quota.MaxTTL > 0 && (*demand.ttl == 0 || *demand.ttl > quota.MaxTTL) {
+		return exceeded(model.QuotaResourceTTL, strconv.Itoa(quota.MaxTTL), "", strconv.Itoa(*demand.ttl))
+	}
+	if demand.sandboxes == 0 && demand.cpu.IsZero() && demand.memory.IsZero() && demand.storage.IsZero() {
+		return nil
+	}
+
+	usage, err := usageOf(ctx, list, subject, excludeID)
+	if err != nil {
+		return err
+	}
+	if quota.MaxSandboxes > 0 && demand.sandboxes > 0 && usage.sandboxes+demand.sandboxes > quota.MaxSandboxes {
+		return exceeded(model.QuotaResourceSandboxes, strconv.Itoa(quota.MaxSandboxes),
+			strconv.Itoa(usage.sandboxes), strconv.Itoa(demand.sandboxes))
+	}
+	for _, r := range []struct {
+		name      string
+		limit     string
+		used, req resource.Quantity
+	}{
+		{model.QuotaResourceCPU, quota.MaxCPU, usage.cpu, demand.cpu},
+		{model.QuotaResourceMemory, quota.MaxMemory, usage.memory, demand.memory},
+		{model.QuotaResourceStorage, quota.MaxStorage, usage.storage, demand.storage},
+	} {
+		if r.limit == "" || r.req.IsZero() {
+			continue
+		}
+		total := r.used.DeepCopy()
+		total.Add(r.req)
+		if total.Cmp(parseQuantity(r.limit)) > 0 {
+			return exceeded(r.name, r.limit, r.used.String(), r.req.String())
+		}
+	}
+	return nil
+}
+
+// effective returns the quota of a subject, else the default quota of its scope, else nil.
+func (s *QuotaService) effective(ctx context.Context, subject quotaSubject) (*store.QuotaRecord, error) {
+	quota, err := s.store.Get(ctx, string(subject.scope), subject.id)
+	if err != nil || quota != nil {
+		return quota, err
+	}
+	return s.store.Get(ctx, string(subject.scope), model.QuotaSubjectDefault)
+}
+
+// usageOf sums the resources of the active sandboxes and reservations of a subject.
+// Stopped and paused sandboxes only hold their storage.
+func usageOf(ctx context.Context, list store.ResourceLister, subject quotaSubject, excludeID string) (quotaUsage, error) {
+	var usage quotaUsage
+	items, err := list(ctx, subject.scope, subject.id)
+	if err != nil {
+		return usage, err
+	}
+	for _, item := range items {
+		if item.ID == excludeID {
+			continue
+		}
+		if item.PersistenceEnabled {
+			usage.storage.Add(parseQuantity(item.PersistenceSize))
+		}
+		if item.LifecycleStatus == string(model.SandboxStatusStopped) || item.LifecycleStatus == string(model.SandboxStatusPaused) {
+			continue
+		}
+		usage.sandboxes++
+		usage.cpu.Add(parseQuantity(item.CPU))
+		usage.memory.Add(parseQuantity(item.Memory))
+	}
+	return usage, nil
+}
+
+// Status returns the quotas that apply to the caller along with their usage: those of
+// its user, of its API key and of the project the key is scoped to.
+func (s *QuotaService) Status(ctx context.Context) (*model.QuotaStatusResponse, error) {
+	items := make([]model.QuotaStatus, 0)
+	p := auth.PrincipalFromContext(ctx)
+	if p == nil {
+		return &model.QuotaStatusResponse{Items: items}, nil
+	}
+	for _, subject := range quotaSubjects(p.UserID, p.APIKeyID, p.Project) {
+		status, err := s.status(ctx, subject)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *status)
+	}
+	return &model.QuotaStatusResponse{Items: items}, nil
+}
+
+func (s *QuotaService) status(ctx context.Context, subject quotaSubject) (*model.QuotaStatus, error) {
+	status := &model.QuotaStatus{Scope: subject.scope, Subject: subject.id}
+	quota, err := s.effective(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if quota != nil {
+		limits := recordToQuota(quota).QuotaLimits
+		status.Limits = &limits
+	}
+	if subject.id != model.QuotaSubjectDefault {
+		usage, err := usageOf(ctx, s.sandboxStore.ListActiveResources, subject, "")
+		if err != nil {
+			return nil, err
+		}
+		status.Usage = model.QuotaUsage{
+			Sandboxes: usage.sandboxes,
+			CPU:       usage.cpu.String(),
+			Memory:    usage.memory.String(),
+			Storage:   usage.storage.String(),
+		}
+	}
+	return status, nil
+}
+
+// List returns all quotas.
+func (s *QuotaService) List(ctx context.Context) (*model.QuotaListResponse, error) {
+	records, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]model.Quota, 0, len(records))
+	for i := range records {
+		items = append(items, *recordToQuota(&records[i]))
+	}
+	return &model.QuotaListResponse{Items: items}, nil
+}
+
+// Get returns the quota of a subject, or the default quota of its scope, with its usage.
+// Users may be given by ID or username.
+func (s *QuotaService) Get(ctx context.Context, scope model.QuotaScope, subject string) (*model.QuotaStatus, error) {
+	id, err := s.resolveSubject(ctx, scope, subject)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(ctx, quotaSubject{scope, id})
+}
+
+// Set creates or replaces the quota of a subject. Users may be given by ID or username.
+func (s *QuotaService) Set(ctx context.Context, scope model.QuotaScope, subject string, limits *model.QuotaLimits) (*model.Quota, error) {
+	id, err := s.resolveSubject(ctx, scope, subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuotaLimits(limits); err != nil {
+		return nil, err
+	}
+	rec := &store.QuotaRecord{
+		Scope:        string(scope),
+		Subject:      id,
+		MaxSandboxes: limits.MaxSandboxes,
+		MaxCPU:       limits.MaxCPU,
+		MaxMemory:    limits.MaxMemory,
+		MaxStorage:   limits.MaxStorage,
+		MaxTTL:       limits.MaxTTL,
+		UpdatedAt:    time.Now().UTC(),
+	}
+	if err := s.store.Upsert(ctx, rec); err != nil {
+		return nil, err
+	}
+	return recordToQuota(rec), nil
+}
+
+// Delete removes the quota of a subject. Users may be given by ID or username. Quotas
+// of subjects that no longer exist can still be removed by ID.
+func (s *QuotaService) Delete(ctx context.Context, scope model.QuotaScope, subject string) error {
+	id, err := s.resolveSubject(ctx, scope, subject)
+	if errors.Is(err, ErrQuotaSubjectNotFound) {
+		id, err = subject, nil
+	}
+	if err != nil {
+		return err
+	}
+	deleted, err := s.store.Delete(ctx, string(scope), id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("%w: %s %s", ErrQuotaNotFound, scope, id)
+	}
+	return nil
+}
+
+// resolveSubject validates a scope and subject, checks that the subject exists, and
+// returns the ID of user subjects given by username.
+func (s *QuotaService) resolveSubject(ctx context.Context, scope model.QuotaScope, subject string) (string, error) {
+	if subject == "" {
+		return "", fmt.Errorf("%w: subject is required", ErrInvalidQuota)
+	}
+	switch scope {
+	case model.QuotaScopeUser:
+		if subject == model.QuotaSubjectDefault {
+			return subject, nil
+		}
+		user, err := s.authStore.GetUserByID(ctx, subject)
+		if err == nil && user == nil {
+			user, err = s.authStore.GetUserByUsername(ctx, subject)
+		}
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			return "", fmt.Errorf("%w: user %s", ErrQuotaSubjectNotFound, subject)
+		}
+		return user.ID, nil
+	case model.QuotaScopeProject:
+		if subject == model.QuotaSubjectDefault {
+			return subject, nil
+		}
+		project, err := s.projectStore.GetByName(ctx, subject)
+		if err != nil {
+			return "", err
+		}
+		if project == nil {
+			return "", fmt.Errorf("%w: project %s", ErrQuotaSubjectNotFound, subject)
+		}
+		return project.Name, nil
+	case model.QuotaScopeAPIKey:
+		if subject == model.QuotaSubjectDefault {
+			return subject, nil
+		}
+		key, err := s.authStore.GetAPIKeyByID(ctx, subject)
+		if err != nil {
+			return "", err
+		}
+		if key == nil {
+			return "", fmt.Errorf("%w: api key %s", ErrQuotaSubjectNotFound, subject)
+		}
+		return key.ID, nil
+	default:
+		return "", fmt.Errorf("%w: scope must be one of user, api_key, project", ErrInvalidQuota)
+	}
+}
+
+func validateQuotaLimits(limits *model.QuotaLimits) error {
+	if limits.MaxSandboxes < 0 || limits.MaxTTL < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidQuota)
+	}
+	for name, value := range map[string]string{
+		"max_cpu":     limits.MaxCPU,
+		"max_memory":  limits.MaxMemory,
+		"max_storage": limits.MaxStorage,
+	} {
+		if value == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidQuota, name, err)
+		}
+		if q.Sign() < 0 {
+			return fmt.Errorf("%w: %s must not be negative", ErrInvalidQuota, name)
+		}
+	}
+	return nil
+}
+
+func recordToQuota(rec *store.QuotaRecord) *model.Quota {
+	return &model.Quota{
+		Scope:   model.QuotaScope(rec.Scope),
+		Subject: rec.Subject,
+		QuotaLimits: model.QuotaLimits{
+			MaxSandboxes: rec.MaxSandboxes,
+			MaxCPU:       rec.MaxCPU,
+			MaxMemory:    rec.MaxMemory,
+			MaxStorage:   rec.MaxStorage,
+			MaxTTL:       rec.MaxTTL,
+		},
+		UpdatedAt: rec.UpdatedAt,
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func TestCreateSandboxChecksQuotas(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+
+	authStore := store.NewAuthStore()
+	if err := authStore.CreateUser(ctx, &store.UserRecord{ID: "u-alice", Username: "alice", Role: string(model.RoleDeveloper)}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	client := k8s.NewClientForTest()
+	sandboxStore := store.NewSandboxStore()
+	quotaSvc := NewQuotaService(store.NewQuotaStore(), sandboxStore, authStore, store.NewProjectStore())
+
+	templateSvc := NewTemplateService()
+	spec := model.TemplateSpec{
+		Image:          "busybox:1.36",
+		Command:        []string{"sh", "-c", "sleep 30"},
+		Resources:      model.ResourceSpec{CPU: "500m", Memory: "512Mi"},
+		TTL:            3600,
+		StartupTimeout: 1,
+	}
+	if _, err := templateSvc.Create(ctx, &model.CreateTemplateRequest{Name: "tpl", Spec: spec}); err != nil {
+		t.Fatalf("Create template error = %v", err)
+	}
+
+	svc := NewSandboxService(client, sandboxStore, cipher)
+	svc.SetTemplateService(templateSvc)
+	svc.SetQuotaService(quotaSvc)
+
+	if _, err := quotaSvc.Set(ctx, model.QuotaScopeUser, "alice", &model.QuotaLimits{MaxSandboxes: 2, MaxCPU: "1500m", MaxTTL: 7200}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := quotaSvc.Set(ctx, model.QuotaScopeUser, "mallory", &model.QuotaLimits{MaxSandboxes: 1}); !errors.Is(err, ErrQuotaSubjectNotFound) {
+		t.Fatalf("Set() for an unknown user error = %v, want ErrQuotaSubjectNotFound", err)
+	}
+	if _, err := quotaSvc.Set(ctx, model.QuotaScopeUser, "alice", &model.QuotaLimits{MaxCPU: "lots"}); !errors.Is(err, ErrInvalidQuota) {
+		t.Fatalf("Set() with an invalid quantity error = %v, want ErrInvalidQuota", err)
+	}
+
+	alice := auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-alice", Username: "alice", Role: model.RoleDeveloper, APIKeyID: "k-1"})
+	violation := func(err error) model.QuotaViolation {
+		t.Helper()
+		var exceeded *QuotaExceededError
+		if !errors.As(err, &exceeded) || !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("error = %v, want a QuotaExceededError", err)
+		}
+		return exceeded.Violation
+	}
+
+	noExpiry := 0
+	_, err = svc.Create(alice, &model.CreateSandboxRequest{Template: "tpl", Overrides: &model.SandboxOverrides{TTL: &noExpiry}})
+	if v := violation(err); v.Resource != model.QuotaResourceTTL || v.Subject != "u-alice" {
+		t.Fatalf("violation = %+v, want the ttl of u-alice", v)
+	}
+
+	if _, err := svc.Create(alice, &model.CreateSandboxRequest{Template: "tpl"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_, err = svc.Create(alice, &model.CreateSandboxRequest{Template: "tpl", Overrides: &model.SandboxOverrides{CPU: "1500m"}})
+	if v := violation(err); v.Resource != model.QuotaResourceCPU || v.Used != "500m" || v.Requested != "1500m" {
+		t.Fatalf("violation = %+v, want cpu with 500m used", v)
+	}
+	if _, err := svc.Create(alice, &model.CreateSandboxRequest{Template: "tpl"}); err != nil {
+		t.Fatalf("second Create() error = %v", err)
+	}
+	_, err = svc.Create(alice, &model.CreateSandboxRequest{Template: "tpl"})
+	if v := violation(err); v.Resource != model.QuotaResourceSandboxes || v.Used != "2" {
+		t.Fatalf("violation = %+v, want sandboxes with 2 used", v)
+	}
+
+	if _, err := quotaSvc.Set(ctx, model.QuotaScopeAPIKey, "k-missing", &model.QuotaLimits{MaxSandboxes: 1}); !errors.Is(err, ErrQuotaSubjectNotFound) {
+		t.Fatalf("Set() for an unknown api key error = %v, want ErrQuotaSubjectNotFound", err)
+	}
+	if err := authStore.CreateAPIKey(ctx, &store.APIKeyRecord{ID: "k-2", UserID: "u-alice", Name: "ci", Prefix: "cccc", KeyHash: "h2"}); err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if _, err := quotaSvc.Set(ctx, model.QuotaScopeAPIKey, "k-2", &model.QuotaLimits{MaxSandboxes: 1}); err != nil {
+		t.Fatalf("Set() for an api key error = %v", err)
+	}
+	if err := authStore.DeleteAPIKey(ctx, "k-2", "u-alice"); err != nil {
+		t.Fatalf("DeleteAPIKey() error = %v", err)
+	}
+	if err := quotaSvc.Delete(ctx, model.QuotaScopeAPIKey, "k-2"); err != nil {
+		t.Fatalf("Delete() for a removed api key error = %v", err)
+	}
+
+	// Default quotas apply to API keys without one of their own
+	if _, err := quotaSvc.Set(ctx, model.QuotaScopeAPIKey, model.QuotaSubjectDefault, &model.QuotaLimits{MaxSandboxes: 1}); err != nil {
+		t.Fatalf("Set() default error = %v", err)
+	}
+	if err := quotaSvc.Delete(ctx, model.QuotaScopeUser, "alice"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = svc.Create(alice, &model.CreateSandboxRequest{Template: "tpl"})
+	if v := violation(err); v.Scope != model.QuotaScopeAPIKey || v.Subject != "k-1" {
+		t.Fatalf("violation = %+v, want the default quota of api key k-1", v)
+	}
+
+	status, err := quotaSvc.Status(alice)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(status.Items) != 2 || status.Items[0].Limits != nil || status.Items[0].Usage.Sandboxes != 2 || status.Items[0].Usage.CPU != "1" {
+		t.Fatalf("Status() = %+v, want the unlimited user with 2 sandboxes and 1 CPU", status.Items)
+	}
+	if status.Items[1].Limits == nil || status.Items[1].Limits.MaxSandboxes != 1 {
+		t.Fatalf("Status() = %+v, want the api key with the default quota", status.Items)
+	}
+}
+
+func TestQuotaReservationsHoldSlotsUntilReleased(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	authStore := store.NewAuthStore()
+	if err := authStore.CreateUser(ctx, &store.UserRecord{ID: "u-alice", Username: "alice", Role: string(model.RoleDeveloper)}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	sandboxStore := store.NewSandboxStore()
+	quotaSvc := NewQuotaService(store.NewQuotaStore(), sandboxStore, authStore, store.NewProjectStore())
+	if _, err := quotaSvc.Set(ctx, model.QuotaScopeUser, "alice", &model.QuotaLimits{MaxSandboxes: 1}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	subjects := quotaSubjects("u-alice", "", "")
+	demand := quotaDemand{sandboxes: 1, cpu: parseQuantity("500m")}
+
+	release, err := quotaSvc.Reserve(ctx, subjects, demand, "")
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	// The pending reservation takes the slot without holding a lock
+	if _, err := quotaSvc.Reserve(ctx, subjects, demand, ""); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("second Reserve() error = %v, want ErrQuotaExceeded", err)
+	}
+	release()
+	release()
+	release, err = quotaSvc.Reserve(ctx, subjects, demand, "")
+	if err != nil {
+		t.Fatalf("Reserve() after release error = %v", err)
+	}
+	release()
+
+	// A start that fails in Kubernetes gives its reservation back
+	rec := makeTestSandboxRecord("sbx-stopped", true, "stopped")
+	rec.OwnerID = "u-alice"
+	if err := sandboxStore.Create(ctx, rec); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	svc := NewSandboxService(k8s.NewClientForTest(), sandboxStore, nil)
+	svc.SetQuotaService(quotaSvc)
+	if err := svc.Start(ctx, "sbx-stopped"); err == nil {
+		t.Fatal("Start() without a deployment succeeded")
+	}
+	release, err = quotaSvc.Reserve(ctx, subjects, demand, "")
+	if err != nil {
+		t.Fatalf("Reserve() after a failed start error = %v", err)
+	}
+	release()
+}
@@ -2,15 +2,21 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
 	"github.com/fslongjin/liteboxd/backend/internal/k8s"
 	"github.com/fslongjin/liteboxd/backend/internal/logx"
 	"github.com/fslongjin/liteboxd/backend/internal/model"
@@ -28,8 +34,20 @@ const durableStoreWriteTimeout = 5 * time.Second
 type SandboxService struct {
 	k8sClient    *k8s.Client
 	templateSvc  *TemplateService
+	snapshotSvc  *SandboxSnapshotService
+	poolSvc      *SandboxPoolService
+	projectSvc   *ProjectService
+	quotaSvc     *QuotaService
 	sandboxStore *store.SandboxStore
 	tokenCipher  *security.TokenCipher
+
+	maxFileTransferSize int64
+	maxTTL              time.Duration
+
+	allowNetworkWidening bool
+
+	checkpointDir string
+	pausing       sync.Map // sandbox ID -> struct{} while a pause checkpoint is running
 }
 
 func NewSandboxService(k8sClient *k8s.Client, sandboxStore *store.SandboxStore, tokenCipher *security.TokenCipher) *SandboxService {
@@ -37,6 +55,7 @@ func NewSandboxService(k8sClient *k8s.Client, sandboxStore *store.SandboxStore,
 		k8sClient:    k8sClient,
 		sandboxStore: sandboxStore,
 		tokenCipher:  tokenCipher,
+		maxTTL:       DefaultMaxTTL,
 	}
 }
 
@@ -45,7 +64,63 @@ func (s *SandboxService) SetTemplateService(templateSvc *TemplateService) {
 	s.templateSvc = templateSvc
 }
 
+// SetSnapshotService sets the snapshot service for creating sandboxes from snapshots
+func (s *SandboxService) SetSnapshotService(snapshotSvc *SandboxSnapshotService) {
+	s.snapshotSvc = snapshotSvc
+}
+
+// SetPoolService sets the warm pool service Create claims ready sandboxes from
+func (s *SandboxService) SetPoolService(poolSvc *SandboxPoolService) {
+	s.poolSvc = poolSvc
+}
+
+// SetProjectService sets the project service Create resolves the project of a sandbox with
+func (s *SandboxService) SetProjectService(projectSvc *ProjectService) {
+	s.projectSvc = projectSvc
+}
+
+// SetQuotaService sets the quota service that limits the sandboxes of users, API
+// keys and projects. Without one, there are no quotas.
+func (s *SandboxService) SetQuotaService(quotaSvc *QuotaService) {
+	s.quotaSvc = quotaSvc
+}
+
+// SetCheckpointDir sets where paused sandboxes keep their filesystem checkpoints.
+func (s *SandboxService) SetCheckpointDir(dir string) {
+	s.checkpointDir = dir
+}
+
+// SetMaxFileTransferSize caps the bytes a single file upload or download may transfer.
+// Zero or a negative value disables the cap.
+func (s *SandboxService) SetMaxFileTransferSize(limit int64) {
+	s.maxFileTransferSize = limit
+}
+
+// MaxFileTransferSize returns the per-request transfer cap, or zero when unlimited.
+func (s *SandboxService) MaxFileTransferSize() int64 {
+	return s.maxFileTransferSize
+}
+
 func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxRequest) (*model.Sandbox, error) {
+	var snapshot *store.SandboxSnapshotRecord
+	if req.FromSnapshot != "" {
+		if s.snapshotSvc == nil {
+			return nil, fmt.Errorf("snapshot service not configured")
+		}
+		var err error
+		snapshot, err = s.snapshotSvc.getReady(ctx, req.FromSnapshot)
+		if err != nil {
+			return nil, err
+		}
+		// Default to the template the snapshot was taken from
+		if req.Template == "" {
+			r := *req
+			r.Template = snapshot.TemplateName
+			r.TemplateVersion = snapshot.TemplateVersion
+			req = &r
+		}
+	}
+
 	// All sandboxes must be created from a template
 	if req.Template == "" {
 		return nil, fmt.Errorf("template is required")
@@ -54,6 +129,9 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 	if s.templateSvc == nil {
 		return nil, fmt.Errorf("template service not configured")
 	}
+	if !auth.PrincipalFromContext(ctx).AllowsTemplate(req.Template) {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotAllowed, req.Template)
+	}
 
 	// Get template spec
 	spec, err := s.templateSvc.GetSpecForSandbox(ctx, req.Template, req.TemplateVersion)
@@ -67,6 +145,19 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		return nil, fmt.Errorf("failed to get template info: %w", err)
 	}
 
+	project, err := s.resolveProject(ctx, req.Project)
+	if err != nil {
+		return nil, err
+	}
+	namespace := s.k8sClient.SandboxNamespace()
+	projectName := ""
+	if project != nil {
+		namespace, projectName = project.Namespace, project.Name
+	}
+	if template.Project != "" && template.Project != projectName {
+		return nil, fmt.Errorf("%w: template %s can only be used in project %s", ErrInvalidProject, req.Template, template.Project)
+	}
+
 	templateVersion := req.TemplateVersion
 	if templateVersion <= 0 {
 		templateVersion = template.LatestVersion
@@ -77,6 +168,7 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 	cpu := spec.Resources.CPU
 	memory := spec.Resources.Memory
 	ttl := spec.TTL
+	ttlMode := spec.TTLMode
 	env := spec.Env
 	var persistence *model.PersistenceSpec
 	if spec.Persistence != nil {
@@ -92,10 +184,10 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		startupTimeout = 300 // default 5 minutes
 	}
 
-	// Network config from template (cannot be overridden)
 	networkConfig := spec.Network
+	networkJSON := ""
 
-	// Apply overrides (network configuration is NOT allowed to be overridden)
+	// Apply overrides
 	if req.Overrides != nil {
 		if req.Overrides.CPU != "" {
 			cpu = req.Overrides.CPU
@@ -106,7 +198,17 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		if req.Overrides.TTL != nil {
 			ttl = *req.Overrides.TTL
 		}
+		if req.Overrides.TTLMode != "" {
+			if err := validateTTLMode(req.Overrides.TTLMode); err != nil {
+				return nil, fmt.Errorf("invalid overrides.ttlMode: %w", err)
+			}
+			ttlMode = req.Overrides.TTLMode
+		}
 		if req.Overrides.Env != nil {
+			// Claimed pool members get the env through env(1) on each command.
+			if err := validateEnvNames(req.Overrides.Env); err != nil {
+				return nil, err
+			}
 			if env == nil {
 				env = make(map[string]string)
 			}
@@ -125,6 +227,35 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 				persistence.Size = req.Overrides.Persistence.Size
 			}
 		}
+		if req.Overrides.Network != nil {
+			networkConfig, err = s.resolveNetworkOverride(spec.Network, req.Overrides.Network)
+			if err != nil {
+				return nil, err
+			}
+			data, err := json.Marshal(networkConfig)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal network: %w", err)
+			}
+			networkJSON = string(data)
+		}
+	}
+
+	groupPorts, err := validateNetworkGroup(req.NetworkGroup, req.GroupPorts)
+	if err != nil {
+		return nil, err
+	}
+	if req.NetworkGroup != "" {
+		if err := s.checkNetworkGroupOwner(ctx, namespace, req.NetworkGroup); err != nil {
+			return nil, err
+		}
+	}
+	groupPortsJSON := ""
+	if len(groupPorts) > 0 {
+		data, err := json.Marshal(groupPorts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal group ports: %w", err)
+		}
+		groupPortsJSON = string(data)
 	}
 
 	// Validate required fields
@@ -132,11 +263,52 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		return nil, fmt.Errorf("template spec is invalid: image is required")
 	}
 
-	id := generateID()
+	if snapshot != nil {
+		if persistence == nil || !persistence.Enabled {
+			return nil, ErrRestoreNeedsPersistence
+		}
+		if snapshot.Kind == string(model.SnapshotKindVolumeSnapshot) {
+			// Volume snapshots can't be restored across namespaces
+			snapshotNS, err := s.sandboxStore.GetClusterNamespace(ctx, snapshot.SandboxID)
+			if err != nil {
+				return nil, err
+			}
+			if snapshotNS != "" && snapshotNS != namespace {
+				return nil, fmt.Errorf("%w: snapshot %s was taken in namespace %s", ErrInvalidProject, snapshot.ID, snapshotNS)
+			}
+			// A volume snapshot can only be restored into its own storage class, on a
+			// volume at least as large as the one it was taken from.
+			persistence.StorageClassName = snapshot.StorageClassName
+			if snapshotSize, err := resource.ParseQuantity(snapshot.VolumeSize); err == nil {
+				size, err := resource.ParseQuantity(persistence.Size)
+				if err != nil || size.Cmp(snapshotSize) < 0 {
+					persistence.Size = snapshot.VolumeSize
+				}
+			}
+		}
+	}
 
 	if ttl < 0 {
 		return nil, fmt.Errorf("ttl must be >= 0")
 	}
+	if ttlMode == "" {
+		ttlMode = model.TTLModeFixed
+	}
+
+	// The reservation holds the quotas until the record is stored and counts towards them
+	demand := quotaDemand{sandboxes: 1, cpu: parseQuantity(cpu), memory: parseQuantity(memory), ttl: &ttl}
+	if persistence != nil && persistence.Enabled {
+		demand.storage = parseQuantity(persistence.Size)
+	}
+	ownerID, ownerAPIKeyID := "", ""
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		ownerID, ownerAPIKeyID = p.UserID, p.APIKeyID
+	}
+	release, err := s.reserveQuota(ctx, quotaSubjects(ownerID, ownerAPIKeyID, projectName), demand, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	accessToken, err := security.GenerateToken(32)
 	if err != nil {
@@ -148,11 +320,26 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 	}
 	tokenHash := security.HashToken(accessToken)
 
-	gatewayURL := os.Getenv("GATEWAY_URL")
-	if gatewayURL == "" {
-		gatewayURL = "http://localhost:8080" // Default for development
+	// A request that keeps the template's pod spec is served from its warm pool when a
+	// member is ready. Env overrides are passed to every exec in the sandbox instead.
+	// Pools run in the default namespace, so sandboxes of projects never use them.
+	id := generateID()
+	var pooledPod *corev1.Pod
+	if s.poolSvc != nil && spec.PoolSize > 0 && templateVersion == template.LatestVersion && snapshot == nil &&
+		(persistence == nil || !persistence.Enabled) && cpu == spec.Resources.CPU && memory == spec.Resources.Memory &&
+		networkJSON == "" && req.NetworkGroup == "" && project == nil {
+		if pooledID := s.poolSvc.Claim(ctx, req.Template, templateVersion); pooledID != "" {
+			pod, err := s.k8sClient.ClaimPooledPod(ctx, pooledID, ttl)
+			if err != nil {
+				logWithSandboxID(ctx, pooledID).Warn("failed to claim pooled pod, creating a new sandbox", "error", err)
+				s.poolSvc.AbortClaim(ctx, pooledID, "claim failed")
+			} else {
+				id, pooledPod = pooledID, pod
+			}
+		}
 	}
-	accessURL := fmt.Sprintf("%s/api/v1/sandbox/%s", gatewayURL, id)
+
+	accessURL := sandboxAccessURL(id)
 
 	now := time.Now().UTC()
 	expiresAt := computeExpiresAt(now, ttl)
@@ -186,11 +373,12 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		CPU:                   cpu,
 		Memory:                memory,
 		TTL:                   ttl,
+		TTLMode:               string(ttlMode),
 		EnvJSON:               string(envJSONBytes),
 		DesiredState:          store.DesiredStateActive,
 		LifecycleStatus:       "creating",
 		StatusReason:          "",
-		ClusterNamespace:      s.k8sClient.SandboxNamespace(),
+		ClusterNamespace:      namespace,
 		PodName:               fmt.Sprintf("sandbox-%s", id),
 		AccessTokenCiphertext: ciphertext,
 		AccessTokenNonce:      nonce,
@@ -208,45 +396,64 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		CreatedAt:             now,
 		ExpiresAt:             expiresAt,
 		UpdatedAt:             now,
+		FromPool:              pooledPod != nil,
+		NetworkJSON:           networkJSON,
+		NetworkGroup:          req.NetworkGroup,
+		GroupPortsJSON:        groupPortsJSON,
+		Project:               projectName,
+	}
+	if networkConfig != nil {
+		record.EgressBandwidth, record.IngressBandwidth = bandwidthOf(networkConfig)
+	}
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		record.OwnerID, record.OwnerName, record.OwnerAPIKeyID = p.UserID, p.Username, p.APIKeyID
 	}
-	if err := s.sandboxStore.Create(ctx, record); err != nil {
+	err = s.sandboxStore.Create(ctx, record)
+	release()
+	if err != nil {
+		if pooledPod != nil {
+			s.poolSvc.AbortClaim(ctx, id, "claim failed")
+		}
 		return nil, err
 	}
 	s.appendStatusHistoryDurable(id, "api", "", "creating", "create requested")
 
-	// Convert model.FileSpec to k8s.FileSpec
-	var files []k8s.FileSpec
-	for _, f := range startupFiles {
-		files = append(files, k8s.FileSpec{
-			Source:      f.Source,
-			Destination: f.Destination,
-			Content:     f.Content,
-		})
-	}
-
-	// Convert model.ProbeSpec to k8s.ProbeSpec
-	var probe *k8s.ProbeSpec
-	if readinessProbe != nil {
-		probe = &k8s.ProbeSpec{
-			Exec:                readinessProbe.Exec.Command,
-			InitialDelaySeconds: readinessProbe.InitialDelaySeconds,
-			PeriodSeconds:       readinessProbe.PeriodSeconds,
-			FailureThreshold:    readinessProbe.FailureThreshold,
+	if pooledPod != nil {
+		s.poolSvc.CompleteClaim(ctx, id)
+		lifecycleStatus := string(convertPodStatus(pooledPod))
+		if err := s.updateObservedStateDurable(id, string(pooledPod.UID), string(pooledPod.Status.Phase), pooledPod.Status.PodIP, lifecycleStatus, ""); err != nil {
+			return nil, err
+		}
+		s.appendStatusHistoryDurable(id, "api", "creating", lifecycleStatus, "claimed from warm pool")
+		record.LifecycleStatus = lifecycleStatus
+		record.PodPhase = string(pooledPod.Status.Phase)
+		record.PodIP = pooledPod.Status.PodIP
+		sandbox, err := s.recordToSandbox(ctx, record)
+		if err != nil {
+			return nil, err
 		}
+		sandbox.AccessToken = accessToken
+		return sandbox, nil
 	}
 
+	files := toK8sFiles(startupFiles)
+	probe := toK8sProbe(readinessProbe)
+
 	// Build annotations
 	annotations := map[string]string{
 		"liteboxd.io/template":         req.Template,
 		"liteboxd.io/template-version": strconv.Itoa(templateVersion),
 	}
 
-	// Convert model.NetworkSpec to k8s.NetworkSpec
-	var k8sNetwork *k8s.NetworkSpec
-	if networkConfig != nil {
-		k8sNetwork = &k8s.NetworkSpec{
-			AllowInternetAccess: networkConfig.AllowInternetAccess,
-			AllowedDomains:      networkConfig.AllowedDomains,
+	k8sNetwork := toK8sNetwork(networkConfig)
+	var labels map[string]string
+	if req.NetworkGroup != "" {
+		labels = map[string]string{k8s.LabelNetworkGroup: req.NetworkGroup}
+		// Open the group before the sandbox starts so peers can connect right away
+		if err := s.applyNetworkGroup(ctx, id, req.NetworkGroup, groupPorts); err != nil {
+			s.updateStatusDurable(id, string(model.SandboxStatusFailed), err.Error())
+			s.appendStatusHistoryDurable(id, "api", "creating", string(model.SandboxStatusFailed), err.Error())
+			return nil, err
 		}
 	}
 
@@ -260,11 +467,11 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		TTL:            ttl,
 		Env:            env,
 		Annotations:    annotations,
+		Labels:         labels,
 		StartupScript:  startupScript,
 		StartupFiles:   files,
 		ReadinessProbe: probe,
 		Network:        k8sNetwork,
-		AccessToken:    accessToken,
 	}
 
 	var lifecycleStatus = string(model.SandboxStatusPending)
@@ -280,16 +487,23 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 				TTL:            ttl,
 				Env:            env,
 				Annotations:    annotations,
+				Labels:         labels,
 				StartupScript:  startupScript,
 				StartupFiles:   files,
 				ReadinessProbe: probe,
 				Network:        k8sNetwork,
-				AccessToken:    accessToken,
 			},
 			StorageClassName: persistenceStorageClass,
 			VolumeSize:       persistenceSize,
 			VolumeClaimName:  volumeClaimName,
 		}
+		if snapshot != nil {
+			if snapshot.Kind == string(model.SnapshotKindVolumeSnapshot) {
+				persistentOpts.VolumeSnapshotName = snapshot.VolumeSnapshotName
+			} else {
+				persistentOpts.RestoreFromArchive = true
+			}
+		}
 		if _, err := s.k8sClient.CreatePersistentSandbox(ctx, persistentOpts); err != nil {
 			s.updateStatusDurable(id, string(model.SandboxStatusFailed), err.Error())
 			s.appendStatusHistoryDurable(id, "api", "creating", string(model.SandboxStatusFailed), err.Error())
@@ -321,27 +535,47 @@ func (s *SandboxService) Create(ctx context.Context, req *model.CreateSandboxReq
 		s.appendStatusHistoryDurable(id, "api", "creating", lifecycleStatus, "pod created")
 	}
 
-	sandbox, err := s.recordToSandbox(record)
+	sandbox, err := s.recordToSandbox(ctx, record)
 	if err != nil {
 		return nil, err
 	}
 	// For create response, return original plaintext token to avoid extra decrypt operation.
 	sandbox.AccessToken = accessToken
 
+	restoreArchive := ""
+	if snapshot != nil && snapshot.Kind == string(model.SnapshotKindArchive) {
+		restoreArchive = snapshot.ID
+	}
+
 	// Run post-creation tasks asynchronously (wait for ready, upload files, exec startup script)
 	bgCtx := logx.WithRequestID(context.Background(), logx.RequestIDFromContext(ctx))
 	go func() {
-		s.runPostCreationTasks(bgCtx, id, probe, files, startupScript, startupTimeout, persistenceEnabled, runtimeName, volumeClaimName)
+		s.runPostCreationTasks(bgCtx, id, probe, files, startupScript, startupTimeout, persistenceEnabled, runtimeName, volumeClaimName, restoreArchive)
 	}()
 
 	return sandbox, nil
 }
 
 // runPostCreationTasks runs tasks after pod creation in the background.
-// Order: startup script first (so services like nginx can listen), then wait for ready (probe), then upload files.
-func (s *SandboxService) runPostCreationTasks(ctx context.Context, id string, probe *k8s.ProbeSpec, files []k8s.FileSpec, startupScript string, startupTimeout int, persistent bool, deploymentName, pvcName string) {
+// Order: restore the snapshot archive (the sandbox does not start before it), then startup script
+// (so services like nginx can listen), then wait for ready (probe), then upload files.
+func (s *SandboxService) runPostCreationTasks(ctx context.Context, id string, probe *k8s.ProbeSpec, files []k8s.FileSpec, startupScript string, startupTimeout int, persistent bool, deploymentName, pvcName, restoreArchive string) {
 	logger := logWithSandboxID(ctx, id)
 
+	if restoreArchive != "" {
+		if err := s.restoreSnapshotArchive(ctx, id, restoreArchive, time.Duration(startupTimeout)*time.Second); err != nil {
+			logger.Warn("post-creation snapshot restore failed", "snapshot_id", restoreArchive, "error", err)
+			reason := "snapshot restore failed: " + err.Error()
+			now := time.Now().UTC()
+			updated, _ := s.sandboxStore.UpdateStatusIfActive(context.Background(), id, string(model.SandboxStatusFailed), reason, now)
+			if updated {
+				_ = s.sandboxStore.AppendStatusHistory(context.Background(), id, "system", "pending", string(model.SandboxStatusFailed), reason, nil, now)
+			}
+			return
+		}
+		logger.Info("snapshot archive restored", "snapshot_id", restoreArchive)
+	}
+
 	// Execute startup script first if specified (e.g. start nginx), so readiness probe can succeed
 	if startupScript != "" {
 		execCtx, execCancel := context.WithTimeout(logx.WithRequestID(context.Background(), logx.RequestIDFromContext(ctx)), 60*time.Second)
@@ -395,7 +629,7 @@ func (s *SandboxService) Get(ctx context.Context, id string) (*model.Sandbox, er
 	if record == nil || record.LifecycleStatus == "deleted" {
 		return nil, fmt.Errorf("sandbox not found")
 	}
-	return s.recordToSandbox(record)
+	return s.recordToSandbox(ctx, record)
 }
 
 func (s *SandboxService) List(ctx context.Context) (*model.SandboxListResponse, error) {
@@ -414,8 +648,12 @@ func (s *SandboxService) List(ctx context.Context) (*model.SandboxListResponse,
 
 // ListForUser lists sandboxes visible to end users.
 // When includeTerminating is true, also returns items under deletion.
+// Callers other than admins only see their own sandboxes, API keys scoped to a
+// project only those of the project and API keys limited to templates only those of
+// the templates.
 func (s *SandboxService) ListForUser(ctx context.Context, includeTerminating bool) (*model.SandboxListResponse, error) {
-	records, err := s.sandboxStore.ListForUser(ctx, includeTerminating)
+	scope := store.SandboxScope{OwnerID: ownerFilter(ctx), Project: projectScope(ctx), Templates: templateScope(ctx)}
+	records, err := s.sandboxStore.ListForUser(ctx, includeTerminating, scope)
 	if err != nil {
 		return nil, err
 	}
@@ -427,12 +665,21 @@ func (s *SandboxService) ListForUser(ctx context.Context, includeTerminating boo
 }
 
 func (s *SandboxService) ListMetadata(ctx context.Context, opts model.SandboxMetadataListOptions) (*model.SandboxMetadataListResponse, error) {
+	if owner := ownerFilter(ctx); owner != "" {
+		opts.Owner = owner
+	}
+	if project := projectScope(ctx); project != "" {
+		opts.Project = project
+	}
 	query := store.SandboxMetadataQuery{
 		ID:              opts.ID,
 		Template:        opts.Template,
 		DesiredState:    opts.DesiredState,
 		LifecycleStatus: opts.LifecycleStatus,
 		DeletionPhase:   opts.DeletionPhase,
+		Owner:           opts.Owner,
+		Project:         opts.Project,
+		Templates:       templateScope(ctx),
 		CreatedFrom:     opts.CreatedFrom,
 		CreatedTo:       opts.CreatedTo,
 		DeletedFrom:     opts.DeletedFrom,
@@ -512,6 +759,7 @@ func (s *SandboxService) Delete(ctx context.Context, id string) (*model.Sandbox,
 		return nil, err
 	}
 	_ = s.sandboxStore.AppendStatusHistory(ctx, id, "api", record.LifecycleStatus, string(model.SandboxStatusTerminating), "delete requested", nil, now)
+	s.removeCheckpoint(id)
 	record.DesiredState = store.DesiredStateDeleted
 	record.LifecycleStatus = "terminating"
 	record.StatusReason = "delete requested"
@@ -609,6 +857,11 @@ func (s *SandboxService) Start(ctx context.Context, id string) error {
 	if record.LifecycleStatus != "stopped" {
 		return ErrSandboxNotStopped
 	}
+	release, err := s.reserveRunQuota(ctx, record)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	if err := s.k8sClient.StartPersistentSandbox(ctx, id); err != nil {
 		if apierrors.IsNotFound(err) {
@@ -639,7 +892,8 @@ func (s *SandboxService) Exec(ctx context.Context, id string, req *model.ExecReq
 	execCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
 	defer cancel()
 
-	result, err := s.k8sClient.Exec(execCtx, id, req.Command)
+	defer s.keepIdleTTLAlive(execCtx, id)()
+	result, err := s.k8sClient.Exec(execCtx, id, s.execCommand(ctx, id, req.Command))
 	if err != nil {
 		return nil, fmt.Errorf("failed to exec: %w", err)
 	}
@@ -651,12 +905,104 @@ func (s *SandboxService) Exec(ctx context.Context, id string, req *model.ExecReq
 	}, nil
 }
 
-func (s *SandboxService) UploadFile(ctx context.Context, id, path string, content []byte) error {
-	return s.k8sClient.UploadFile(ctx, id, path, content)
+// ExecStream runs a non-TTY command and reports stdout/stderr chunks through emit as
+// they are produced, followed by a final exit event. stdin may be nil.
+// The returned error is non-nil only when the exec session itself failed.
+func (s *SandboxService) ExecStream(ctx context.Context, id string, command []string, timeout int, stdin io.Reader, emit func(model.ExecStreamEvent) error) error {
+	if timeout <= 0 {
+		timeout = 30
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	defer s.keepIdleTTLAlive(execCtx, id)()
+	var mu sync.Mutex
+	stdout := &execStreamWriter{eventType: model.ExecStreamEventStdout, emit: emit, mu: &mu}
+	stderr := &execStreamWriter{eventType: model.ExecStreamEventStderr, emit: emit, mu: &mu}
+	err := s.k8sClient.ExecInteractive(execCtx, id, k8s.ExecInteractiveOptions{
+		Command: s.execCommand(ctx, id, command),
+		TTY:     false,
+		Stdin:   stdin,
+		Stdout:  stdout,
+		Stderr:  stderr,
+	})
+	if flushErr := errors.Join(stdout.Flush(), stderr.Flush()); flushErr != nil {
+		return flushErr
+	}
+
+	exitCode := 0
+	if err != nil {
+		exitErr, ok := err.(interface{ ExitStatus() int })
+		if !ok {
+			return fmt.Errorf("failed to exec: %w", err)
+		}
+		exitCode = exitErr.ExitStatus()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return emit(model.ExecStreamEvent{Type: model.ExecStreamEventExit, ExitCode: exitCode})
+}
+
+// execStreamWriter adapts one output stream of a streaming exec to emit events. A
+// multi-byte character split across writes is held back until it is complete, and
+// chunks that are still not valid UTF-8 are sent base64-encoded.
+// stdout and stderr are copied from separate goroutines, so they share a lock.
+type execStreamWriter struct {
+	eventType string
+	emit      func(model.ExecStreamEvent) error
+	mu        *sync.Mutex
+	pending   []byte
 }
 
-func (s *SandboxService) DownloadFile(ctx context.Context, id, path string) ([]byte, error) {
-	return s.k8sClient.DownloadFile(ctx, id, path)
+func (w *execStreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := append(w.pending, p...)
+	cut := len(data) - incompleteRuneLen(data)
+	w.pending = append([]byte(nil), data[cut:]...)
+	if err := w.emitChunk(data[:cut]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush emits output held back by Write once the stream has ended.
+func (w *execStreamWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := w.pending
+	w.pending = nil
+	return w.emitChunk(data)
+}
+
+func (w *execStreamWriter) emitChunk(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	event := model.ExecStreamEvent{Type: w.eventType, Data: string(data)}
+	if !utf8.Valid(data) {
+		event.Data = base64.StdEncoding.EncodeToString(data)
+		event.Encoding = model.ExecStreamEncodingBase64
+	}
+	return w.emit(event)
+}
+
+// incompleteRuneLen returns the length of a multi-byte UTF-8 sequence cut off at the
+// end of p, or 0 when p ends on a character boundary.
+func incompleteRuneLen(p []byte) int {
+	for n := 1; n < utf8.UTFMax && n <= len(p); n++ {
+		if utf8.RuneStart(p[len(p)-n]) {
+			if utf8.FullRune(p[len(p)-n:]) {
+				return 0
+			}
+			return n
+		}
+	}
+	return 0
 }
 
 func (s *SandboxService) GetLogs(ctx context.Context, id string, tailLines int64) (*model.LogsResponse, error) {
@@ -731,10 +1077,12 @@ func (s *SandboxService) ExecInteractive(ctx context.Context, ws *websocket.Conn
 		}
 	}()
 
+	defer s.keepIdleTTLAlive(ctx, id)()
+
 	// Run interactive exec (blocks until process exits)
 	var stdinArg io.Reader = stdinReader
 	err := s.k8sClient.ExecInteractive(ctx, id, k8s.ExecInteractiveOptions{
-		Command:           command,
+		Command:           s.execCommand(ctx, id, command),
 		TTY:               tty,
 		Stdin:             stdinArg,
 		Stdout:            wsWriter,
@@ -808,7 +1156,7 @@ func (s *SandboxService) cleanHistoricalData(retention time.Duration) {
 		logger.Error("failed to purge sandbox metadata", "error", err, "cutoff", cutoff.Format(time.RFC3339))
 		return
 	}
-	if res.DeletedSandboxes+res.DeletedStatusHistory+res.DeletedReconcileRuns+res.DeletedReconcileItems > 0 {
+	if res.DeletedSandboxes+res.DeletedStatusHistory+res.DeletedReconcileRuns+res.DeletedReconcileItems+res.DeletedNetworkEvents > 0 {
 		logger.Info(
 			"purged sandbox metadata",
 			"cutoff", cutoff.Format(time.RFC3339),
@@ -816,6 +1164,7 @@ func (s *SandboxService) cleanHistoricalData(retention time.Duration) {
 			"deleted_status_history", res.DeletedStatusHistory,
 			"deleted_reconcile_runs", res.DeletedReconcileRuns,
 			"deleted_reconcile_items", res.DeletedReconcileItems,
+			"deleted_network_events", res.DeletedNetworkEvents,
 		)
 	}
 }
@@ -840,12 +1189,17 @@ func (s *SandboxService) cleanExpiredSandboxes() {
 	}
 }
 
-func (s *SandboxService) recordToSandbox(record *store.SandboxRecord) (*model.Sandbox, error) {
-	accessToken, err := s.tokenCipher.Decrypt(record.AccessTokenCiphertext, record.AccessTokenNonce)
+// recordToSandbox converts a record along with its decrypted access token. The token
+// grants access to every port of the sandbox, so it is left out for viewers.
+func (s *SandboxService) recordToSandbox(ctx context.Context, record *store.SandboxRecord) (*model.Sandbox, error) {
+	sandbox := s.recordToSandboxMetadata(record)
+	if p := auth.PrincipalFromContext(ctx); p != nil && !p.HasRole(model.RoleDeveloper) {
+		return &sandbox, nil
+	}
+	accessToken, err := s.tokenCipher.DecryptWithKeyID(record.AccessTokenCiphertext, record.AccessTokenNonce, record.AccessTokenKeyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt access token: %w", err)
 	}
-	sandbox := s.recordToSandboxMetadata(record)
 	sandbox.AccessToken = accessToken
 	return &sandbox, nil
 }
@@ -880,6 +1234,7 @@ func (s *SandboxService) recordToSandboxMetadata(record *store.SandboxRecord) mo
 		CPU:             record.CPU,
 		Memory:          record.Memory,
 		TTL:             record.TTL,
+		TTLMode:         ttlModeOf(record),
 		Env:             record.EnvMap(),
 		Status:          parseLifecycleStatus(record.LifecycleStatus),
 		Template:        record.TemplateName,
@@ -895,11 +1250,88 @@ func (s *SandboxService) recordToSandboxMetadata(record *store.SandboxRecord) mo
 		UpdatedAt:       record.UpdatedAt,
 		DeletedAt:       record.DeletedAt,
 		AccessURL:       record.AccessURL,
+		TunnelURL:       sandboxTunnelURL(record.ID),
 		Persistence:     persistence,
 		Deletion:        deletion,
 		RuntimeKind:     record.RuntimeKind,
 		RuntimeName:     record.RuntimeName,
+		FromPool:        record.FromPool,
+		Network:         record.NetworkOverride(),
+		NetworkGroup:    record.NetworkGroup,
+		GroupPorts:      record.GroupPorts(),
+		PeerHost:        peerHost(record),
+		Bandwidth:       recordBandwidth(record),
+
+		PreviousAccessTokenExpiresAt: previousTokenExpiry(record, time.Now().UTC()),
+		Owner:                        recordOwner(record),
+		Project:                      record.Project,
+	}
+}
+
+func recordOwner(record *store.SandboxRecord) *model.SandboxOwner {
+	if record.OwnerID == "" {
+		return nil
+	}
+	return &model.SandboxOwner{
+		UserID:   record.OwnerID,
+		Username: record.OwnerName,
+		APIKeyID: record.OwnerAPIKeyID,
+	}
+}
+
+// reserveQuota checks a sandbox against the quotas of its subjects and reserves what
+// it takes; see QuotaService.Reserve.
+func (s *SandboxService) reserveQuota(ctx context.Context, subjects []quotaSubject, demand quotaDemand, excludeID string) (func(), error) {
+	if s.quotaSvc == nil {
+		return func() {}, nil
+	}
+	return s.quotaSvc.Reserve(ctx, subjects, demand, excludeID)
+}
+
+// reserveRunQuota checks a stopped or paused sandbox that is about to run again
+// against the quotas of its owner, API key and project. Its storage is already
+// counted. The reservation must be released once the sandbox is recorded as running
+// again, or when starting it failed.
+func (s *SandboxService) reserveRunQuota(ctx context.Context, record *store.SandboxRecord) (func(), error) {
+	demand := quotaDemand{sandboxes: 1, cpu: parseQuantity(record.CPU), memory: parseQuantity(record.Memory)}
+	return s.reserveQuota(ctx, quotaSubjects(record.OwnerID, record.OwnerAPIKeyID, record.Project), demand, record.ID)
+}
+
+// resolveProject returns the project a new sandbox runs in, or nil for the default
+// sandbox namespace.
+func (s *SandboxService) resolveProject(ctx context.Context, name string) (*store.ProjectRecord, error) {
+	if s.projectSvc == nil {
+		if name != "" {
+			return nil, fmt.Errorf("project service not configured")
+		}
+		return nil, nil
+	}
+	return s.projectSvc.resolve(ctx, name)
+}
+
+// ownerFilter returns the user whose sandboxes a caller may list, or "" when the
+// caller may see all of them: admins and work not done on behalf of a caller.
+func ownerFilter(ctx context.Context) string {
+	p := auth.PrincipalFromContext(ctx)
+	if p == nil || p.IsAdmin() {
+		return ""
 	}
+	return p.UserID
+}
+
+func recordBandwidth(record *store.SandboxRecord) *model.BandwidthSpec {
+	if record.EgressBandwidth == "" && record.IngressBandwidth == "" {
+		return nil
+	}
+	return &model.BandwidthSpec{Egress: record.EgressBandwidth, Ingress: record.IngressBandwidth}
+}
+
+// peerHost returns the name a sandbox is reachable at from its network group.
+func peerHost(record *store.SandboxRecord) string {
+	if record.NetworkGroup == "" {
+		return ""
+	}
+	return k8s.PeerServiceName(record.ID)
 }
 
 func computeExpiresAt(now time.Time, ttl int) time.Time {
@@ -909,6 +1341,73 @@ func computeExpiresAt(now time.Time, ttl int) time.Time {
 	return now.Add(time.Duration(ttl) * time.Second)
 }
 
+// ttlModeOf returns the TTL mode of a sandbox. Sandboxes created before TTL modes
+// existed have none stored and use a fixed TTL.
+func ttlModeOf(record *store.SandboxRecord) model.TTLMode {
+	if record.TTLMode == "" {
+		return model.TTLModeFixed
+	}
+	return model.TTLMode(record.TTLMode)
+}
+
+// toK8sProbe converts a template readiness probe to the k8s client form.
+func toK8sProbe(readinessProbe *model.ProbeSpec) *k8s.ProbeSpec {
+	if readinessProbe == nil {
+		return nil
+	}
+	return &k8s.ProbeSpec{
+		Exec:                readinessProbe.Exec.Command,
+		InitialDelaySeconds: readinessProbe.InitialDelaySeconds,
+		PeriodSeconds:       readinessProbe.PeriodSeconds,
+		FailureThreshold:    readinessProbe.FailureThreshold,
+	}
+}
+
+// toK8sFiles converts template startup files to the k8s client form.
+func toK8sFiles(startupFiles []model.FileSpec) []k8s.FileSpec {
+	var files []k8s.FileSpec
+	for _, f := range startupFiles {
+		files = append(files, k8s.FileSpec{
+			Source:      f.Source,
+			Destination: f.Destination,
+			Content:     f.Content,
+		})
+	}
+	return files
+}
+
+// toK8sNetwork converts a template network spec to the k8s client form.
+func toK8sNetwork(networkConfig *model.NetworkSpec) *k8s.NetworkSpec {
+	if networkConfig == nil {
+		return nil
+	}
+	network := &k8s.NetworkSpec{
+		AllowInternetAccess: networkConfig.AllowInternetAccess,
+		AllowedDomains:      networkConfig.AllowedDomains,
+		Ports:               toK8sPorts(networkConfig.Ports),
+		DeniedCIDRs:         networkConfig.DeniedCIDRs,
+	}
+	if networkConfig.Bandwidth != nil {
+		network.EgressBandwidth = networkConfig.Bandwidth.Egress
+		network.IngressBandwidth = networkConfig.Bandwidth.Ingress
+	}
+	for _, rule := range networkConfig.DomainRules {
+		network.DomainRules = append(network.DomainRules, k8s.DomainRule{Domains: rule.Domains, Ports: toK8sPorts(rule.Ports)})
+	}
+	for _, rule := range networkConfig.AllowedCIDRs {
+		network.AllowedCIDRs = append(network.AllowedCIDRs, k8s.CIDRRule{CIDR: rule.CIDR, Ports: toK8sPorts(rule.Ports)})
+	}
+	return network
+}
+
+func toK8sPorts(ports []model.PortRule) []k8s.PortRule {
+	var out []k8s.PortRule
+	for _, p := range ports {
+		out = append(out, k8s.PortRule{Port: p.Port, Protocol: p.Protocol})
+	}
+	return out
+}
+
 func parseLifecycleStatus(v string) model.SandboxStatus {
 	switch v {
 	case string(model.SandboxStatusPending), "creating":
@@ -921,6 +1420,8 @@ func parseLifecycleStatus(v string) model.SandboxStatus {
 		return model.SandboxStatusFailed
 	case string(model.SandboxStatusStopped):
 		return model.SandboxStatusStopped
+	case string(model.SandboxStatusPaused):
+		return model.SandboxStatusPaused
 	case string(model.SandboxStatusTerminating):
 		return model.SandboxStatusTerminating
 	default:
@@ -948,6 +1449,40 @@ func convertPodStatus(pod *corev1.Pod) model.SandboxStatus {
 	}
 }
 
+// sandboxAccessURL returns the gateway URL of a sandbox. With GATEWAY_BASE_DOMAIN set
+// it is the host form, in which clients replace {port} with the sandbox port; otherwise
+// clients append /port/{port}/ to it.
+func sandboxAccessURL(id string) string {
+	gatewayURL := os.Getenv("GATEWAY_URL")
+	if gatewayURL == "" {
+		gatewayURL = "http://localhost:8080" // Default for development
+	}
+	if baseDomain := strings.Trim(strings.ToLower(os.Getenv("GATEWAY_BASE_DOMAIN")), "."); baseDomain != "" {
+		scheme := "http"
+		if u, err := url.Parse(gatewayURL); err == nil && u.Scheme != "" {
+			scheme = u.Scheme
+		}
+		return fmt.Sprintf("%s://{port}-%s.%s", scheme, id, baseDomain)
+	}
+	return fmt.Sprintf("%s/api/v1/sandbox/%s", gatewayURL, id)
+}
+
+// sandboxTunnelURL returns the WebSocket URL of the TCP tunnels of a sandbox, in which
+// clients replace {port} with the sandbox port. Tunnels are always path routed.
+func sandboxTunnelURL(id string) string {
+	gatewayURL := os.Getenv("GATEWAY_URL")
+	if gatewayURL == "" {
+		gatewayURL = "http://localhost:8080" // Default for development
+	}
+	switch {
+	case strings.HasPrefix(gatewayURL, "https://"):
+		gatewayURL = "wss://" + strings.TrimPrefix(gatewayURL, "https://")
+	case strings.HasPrefix(gatewayURL, "http://"):
+		gatewayURL = "ws://" + strings.TrimPrefix(gatewayURL, "http://")
+	}
+	return fmt.Sprintf("%s/api/v1/sandbox/%s/tcp/{port}", strings.TrimSuffix(gatewayURL, "/"), id)
+}
+
 func generateID() string {
 	id := uuid.New().String()
 	return id[:8]
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/metrics"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -53,12 +54,14 @@ func (s *SandboxDeletionService) RunPending(ctx context.Context) error {
 	for i := range records {
 		if err := s.processSandbox(ctx, &records[i]); err != nil {
 			logWithSandboxID(ctx, records[i].ID).Warn("deletion reconcile failed", "error", err)
+			metrics.DeletionRetries.Inc()
 		}
 	}
 	return nil
 }
 
 func (s *SandboxDeletionService) processSandbox(ctx context.Context, rec *store.SandboxRecord) error {
+	ctx = k8s.WithNamespace(ctx, rec.ClusterNamespace)
 	now := time.Now().UTC()
 	retryAt := now.Add(computeDeletionRetry(rec.DeletionAttempts))
 	nextAttempts := rec.DeletionAttempts + 1
@@ -211,6 +214,12 @@ func (s *SandboxDeletionService) handleVerifying(ctx context.Context, rec *store
 		return s.transitionPhase(ctx, rec, store.DeletionPhaseForceCleanup)
 	}
 
+	if rec.NetworkGroup != "" {
+		if err := k8s.NewNetworkPolicyManager(s.k8sClient).DeleteNetworkGroup(ctx, rec.ID); err != nil {
+			return err
+		}
+	}
+
 	if err := s.sandboxStore.MarkDeletionCompleted(ctx, rec.ID, "delete completed", now); err != nil {
 		return err
 	}
@@ -11,4 +11,51 @@ var (
 	ErrSandboxStartNotSupported   = errors.New("sandbox start is only supported for persistence-enabled sandboxes")
 	ErrSandboxNotStopped          = errors.New("sandbox is not stopped")
 	ErrSandboxAlreadyStopped      = errors.New("sandbox is already stopped")
+	ErrSandboxNotRunning          = errors.New("sandbox is not running")
+	ErrSandboxPauseNotSupported   = errors.New("sandbox pause is only supported for sandboxes without persistence; use stop and start instead")
+	ErrSandboxPauseInvalidState   = errors.New("sandbox can only be paused while running")
+	ErrSandboxPauseInProgress     = errors.New("sandbox is still being paused")
+	ErrSandboxAlreadyPaused       = errors.New("sandbox is already paused")
+	ErrSandboxNotPaused           = errors.New("sandbox is not paused")
+	ErrSandboxTTLDisabled         = errors.New("sandbox has no TTL")
+	ErrInvalidTTL                 = errors.New("invalid ttl")
+	ErrProcessNotFound            = errors.New("process not found")
+	ErrProcessNotRunning          = errors.New("process is not running")
+	ErrInvalidProcessSignal       = errors.New("unsupported signal")
+	ErrInvalidEnvName             = errors.New("invalid environment variable name")
+	ErrInvalidArchive             = errors.New("invalid archive")
+	ErrInvalidPath                = errors.New("path must be absolute")
+	ErrPathNotFound               = errors.New("path not found")
+	ErrPathExists                 = errors.New("path already exists")
+	ErrPathIsDirectory            = errors.New("path is a directory")
+	ErrNotDirectory               = errors.New("path is not a directory")
+	ErrDirectoryNotEmpty          = errors.New("directory is not empty")
+	ErrFileTooLarge               = errors.New("file exceeds the transfer size limit")
+	ErrTooManyWatchEntries        = errors.New("too many entries to watch; watch a smaller directory")
+	ErrSnapshotNotFound           = errors.New("snapshot not found")
+	ErrSnapshotNotSupported       = errors.New("snapshots are only supported for persistence-enabled sandboxes")
+	ErrSnapshotInvalidState       = errors.New("sandbox is terminating or deleted")
+	ErrSnapshotInProgress         = errors.New("snapshot is still being created")
+	ErrSnapshotNotReady           = errors.New("snapshot is not ready")
+	ErrRestoreNeedsPersistence    = errors.New("restoring a snapshot requires a template with persistence enabled")
+	ErrPoolNotFound               = errors.New("template has no warm pool")
+	ErrInvalidNetwork             = errors.New("invalid network configuration")
+	ErrNetworkWideningNotAllowed  = errors.New("network override may only narrow the template's egress")
+	ErrNetworkChangeNotSupported  = errors.New("persistent sandboxes cannot switch unrestricted internet access at runtime")
+	ErrNetworkLimitsImmutable     = errors.New("bandwidth limits cannot change on a running sandbox")
+	ErrInvalidNetworkEventQuery   = errors.New("invalid network event query")
+	ErrInvalidNetworkGroup        = errors.New("invalid network group")
+	ErrInvalidPreviewLink         = errors.New("invalid preview link")
+	ErrPreviewLinkNotFound        = errors.New("preview link not found")
+	ErrInvalidGracePeriod         = errors.New("invalid grace period")
+	ErrInvalidProject             = errors.New("invalid project")
+	ErrProjectNotFound            = errors.New("project not found")
+	ErrProjectExists              = errors.New("project already exists")
+	ErrProjectNotEmpty            = errors.New("project still has sandboxes")
+	ErrProjectScope               = errors.New("API key is scoped to another project")
+	ErrQuotaExceeded              = errors.New("quota exceeded")
+	ErrInvalidQuota               = errors.New("invalid quota")
+	ErrQuotaNotFound              = errors.New("quota not found")
+	ErrQuotaSubjectNotFound       = errors.New("quota subject not found")
+	ErrTemplateNotAllowed         = errors.New("API key may not use this template")
 )
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+// UploadFile streams content into path inside the sandbox. size is the content length
+// when known, or -1; known sizes over the transfer cap are rejected before any data is
+// sent, and unknown ones fail as soon as the cap is crossed.
+func (s *SandboxService) UploadFile(ctx context.Context, id, path string, content io.Reader, size int64) error {
+	path, err := cleanSandboxPath(path)
+	if err != nil {
+		return err
+	}
+	if s.maxFileTransferSize > 0 && size > s.maxFileTransferSize {
+		return ErrFileTooLarge
+	}
+	return mapFSError(s.k8sClient.UploadFileStream(ctx, id, path, s.limitTransfer(content)))
+}
+
+// StatDownload returns the file a download would serve, rejecting directories and
+// files over the transfer cap.
+func (s *SandboxService) StatDownload(ctx context.Context, id, path string) (*model.FileInfo, error) {
+	path, err := cleanSandboxPath(path)
+	if err != nil {
+		return nil, err
+	}
+	execCtx, cancel := context.WithTimeout(ctx, fsExecTimeout)
+	defer cancel()
+	stat, err := s.k8sClient.StatPath(execCtx, id, path)
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+	info := fileStatToModel(stat)
+	if info.Type == model.FileTypeDirectory {
+		return nil, fmt.Errorf("%w; download it as an archive", ErrPathIsDirectory)
+	}
+	if s.maxFileTransferSize > 0 && info.Size > s.maxFileTransferSize {
+		return nil, ErrFileTooLarge
+	}
+	return &info, nil
+}
+
+// DownloadFile streams length bytes of path starting at offset to w. A negative length
+// reads through the end of the file.
+func (s *SandboxService) DownloadFile(ctx context.Context, id, path string, offset, length int64, w io.Writer) error {
+	path, err := cleanSandboxPath(path)
+	if err != nil {
+		return err
+	}
+	return mapFSError(s.k8sClient.DownloadFileStream(ctx, id, path, offset, length, w))
+}
+
+// UploadArchive extracts a tar, tar.gz or zip archive into destDir inside the sandbox.
+// An empty format is detected from the archive's leading bytes. Archives are re-encoded
+// as a plain tar stream before reaching the sandbox, which rejects entries escaping
+// destDir and drops special files while keeping file modes.
+func (s *SandboxService) UploadArchive(ctx context.Context, id, destDir, format string, archive io.Reader) error {
+	if destDir == "" {
+		return fmt.Errorf("%w: destination path is required", ErrInvalidArchive)
+	}
+	destDir, err := cleanSandboxPath(destDir)
+	if err != nil {
+		return err
+	}
+
+	limited := s.limitTransfer(archive)
+	br := bufio.NewReader(limited)
+	if format == "" {
+		format = detectArchiveFormat(br)
+	}
+	budget := s.newExtractBudget()
+
+	var convert func(tw *tar.Writer) error
+	switch format {
+	case model.ArchiveFormatTar:
+		convert = func(tw *tar.Writer) error { return copyTarEntries(tar.NewReader(br), tw, budget) }
+	case model.ArchiveFormatTarGz, "tgz":
+		convert = func(tw *tar.Writer) error {
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			defer gz.Close()
+			return copyTarEntries(tar.NewReader(gz), tw, budget)
+		}
+	case model.ArchiveFormatZip:
+		zr, cleanup, err := openZipArchive(br)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		convert = func(tw *tar.Writer) error { return copyZipEntries(zr, tw, budget) }
+	default:
+		return fmt.Errorf("%w: unsupported format %q", ErrInvalidArchive, format)
+	}
+
+	pr, pw := io.Pipe()
+	convertErr := make(chan error, 1)
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := convert(tw)
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+		convertErr <- err
+	}()
+
+	uploadErr := s.k8sClient.UploadArchive(ctx, id, destDir, pr)
+	// Unblock the converter if the exec ended before consuming the whole stream.
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err := <-convertErr; err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		// Archive readers flatten read errors, so check the cap directly.
+		if lr, ok := limited.(*transferLimitReader); ok && lr.remaining < 0 {
+			return ErrFileTooLarge
+		}
+		return err
+	}
+	return uploadErr
+}
+
+// DownloadArchive streams the contents of srcPath as a tar.gz archive to w. The
+// archive size is unknown up front, so the transfer cap aborts the stream once crossed.
+func (s *SandboxService) DownloadArchive(ctx context.Context, id, srcPath string, w io.Writer) error {
+	srcPath, err := cleanSandboxPath(srcPath)
+	if err != nil {
+		return err
+	}
+	if s.maxFileTransferSize <= 0 {
+		return s.k8sClient.DownloadArchive(ctx, id, srcPath, w)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lw := &transferLimitWriter{w: w, remaining: s.maxFileTransferSize, cancel: cancel}
+	err = s.k8sClient.DownloadArchive(ctx, id, srcPath, lw)
+	if lw.exceeded {
+		return ErrFileTooLarge
+	}
+	return err
+}
+
+// limitTransfer wraps r so reading past the transfer cap fails with ErrFileTooLarge.
+func (s *SandboxService) limitTransfer(r io.Reader) io.Reader {
+	if s.maxFileTransferSize <= 0 {
+		return r
+	}
+	return &transferLimitReader{r: r, remaining: s.maxFileTransferSize}
+}
+
+type transferLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *transferLimitReader) Read(p []byte) (int, error) {
+	// Read one byte past the cap so content of exactly the cap size still succeeds.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return 0, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// transferLimitWriter fails writes past the cap and cancels the producing exec, which
+// would otherwise block once its output is no longer consumed.
+type transferLimitWriter struct {
+	w         io.Writer
+	remaining int64
+	cancel    context.CancelFunc
+	exceeded  bool
+}
+
+func (l *transferLimitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		l.exceeded = true
+		l.cancel()
+		return 0, ErrFileTooLarge
+	}
+	l.remaining -= int64(len(p))
+	return l.w.Write(p)
+}
+
+func detectArchiveFormat(br *bufio.Reader) string {
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return model.ArchiveFormatTarGz
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return model.ArchiveFormatZip
+	default:
+		return model.ArchiveFormatTar
+	}
+}
+
+// openZipArchive returns a zip reader over the upload. Zip needs random access, so
+// the upload is spooled to a temporary file first, reading through the transfer cap
+// even when the source itself is seekable.
+func openZipArchive(br *bufio.Reader) (*zip.Reader, func(), error) {
+	tmp, err := os.CreateTemp("", "liteboxd-upload-*.zip")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to buffer archive: %w", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	size, err := io.Copy(tmp, br)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to buffer archive: %w", err)
+	}
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return zr, cleanup, nil
+}
+
+// sanitizeArchivePath returns the entry name relative to the extraction root, or
+// "" for the root itself. Absolute names are made relative; names escaping the root
+// are rejected.
+func sanitizeArchivePath(name string) (string, error) {
+	rel := path.Clean(strings.TrimLeft(name, "/"))
+	if rel == "." {
+		return "", nil
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%w: entry %q escapes the destination", ErrInvalidArchive, name)
+	}
+	return rel, nil
+}
+
+// extractBudget caps the total size of the files extracted from an archive, so an
+// archive under the transfer cap cannot decompress past it. A nil budget is unlimited.
+type extractBudget struct {
+	remaining int64
+}
+
+func (s *SandboxService) newExtractBudget() *extractBudget {
+	if s.maxFileTransferSize <= 0 {
+		return nil
+	}
+	return &extractBudget{remaining: s.maxFileTransferSize}
+}
+
+// take reserves n bytes of extracted content. Entry sizes come from the archive
+// headers; the tar writer refuses to write more than the declared size.
+func (b *extractBudget) take(n int64) error {
+	if b == nil {
+		return nil
+	}
+	if n < 0 || n > b.remaining {
+		return ErrFileTooLarge
+	}
+	b.remaining -= n
+	return nil
+}
+
+func copyTarEntries(tr *tar.Reader, tw *tar.Writer, budget *extractBudget) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+
+		name, err := sanitizeArchivePath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+
+		out := &tar.Header{
+			Name:    name,
+			Mode:    hdr.Mode & 0o7777,
+			ModTime: hdr.ModTime,
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			out.Typeflag = tar.TypeDir
+			out.Name += "/"
+		case tar.TypeReg:
+			if err := budget.take(hdr.Size); err != nil {
+				return err
+			}
+			out.Typeflag = tar.TypeReg
+			out.Size = hdr.Size
+		case tar.TypeSymlink:
+			out.Typeflag = tar.TypeSymlink
+			out.Linkname = hdr.Linkname
+		case tar.TypeLink:
+			link, err := sanitizeArchivePath(hdr.Linkname)
+			if err != nil || link == "" {
+				return fmt.Errorf("%w: hard link %q escapes the destination", ErrInvalidArchive, hdr.Linkname)
+			}
+			out.Typeflag = tar.TypeLink
+			out.Linkname = link
+		default:
+			continue
+		}
+
+		if err := tw.WriteHeader(out); err != nil {
+			return err
+		}
+		if out.Typeflag == tar.TypeReg {
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func copyZipEntries(zr *zip.Reader, tw *tar.Writer, budget *extractBudget) error {
+	for _, f := range zr.File {
+		name, err := sanitizeArchivePath(f.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+
+		mode := f.Mode()
+		perm := int64(mode.Perm())
+		out := &tar.Header{Name: name, ModTime: f.Modified}
+		switch {
+		case mode.IsDir():
+			if perm == 0 {
+				perm = 0o755
+			}
+			out.Typeflag = tar.TypeDir
+			out.Name += "/"
+		case mode&os.ModeSymlink != 0:
+			if err := budget.take(int64(f.UncompressedSize64)); err != nil {
+				return err
+			}
+			target, err := readZipEntry(f)
+			if err != nil {
+				return err
+			}
+			out.Typeflag = tar.TypeSymlink
+			out.Linkname = string(target)
+		case mode.IsRegular():
+			if perm == 0 {
+				perm = 0o644
+			}
+			if err := budget.take(int64(f.UncompressedSize64)); err != nil {
+				return err
+			}
+			out.Typeflag = tar.TypeReg
+			out.Size = int64(f.UncompressedSize64)
+		default:
+			continue
+		}
+		out.Mode = perm
+
+		if err := tw.WriteHeader(out); err != nil {
+			return err
+		}
+		if out.Typeflag == tar.TypeReg {
+			rc, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			_, err = io.Copy(tw, rc)
+			rc.Close()
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+		}
+	}
+	return nil
+}
+
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+type archiveEntry struct {
+	name     string
+	typeflag byte
+	mode     int64
+	body     string
+	linkname string
+}
+
+func readTarEntries(t *testing.T, data []byte) []archiveEntry {
+	t.Helper()
+	var entries []archiveEntry
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("tar Next() error = %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		entries = append(entries, archiveEntry{name: hdr.Name, typeflag: hdr.Typeflag, mode: hdr.Mode, body: string(body), linkname: hdr.Linkname})
+	}
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_ = w.Close()
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	_, _ = zw.Create("a.txt")
+	_ = zw.Close()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "gzip", data: gz.Bytes(), want: model.ArchiveFormatTarGz},
+		{name: "zip", data: zipped.Bytes(), want: model.ArchiveFormatZip},
+		{name: "tar", data: make([]byte, 1024), want: model.ArchiveFormatTar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectArchiveFormat(bufio.NewReader(bytes.NewReader(tt.data))); got != tt.want {
+				t.Fatalf("detectArchiveFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyTarEntriesNormalizesNamesAndKeepsModes(t *testing.T) {
+	var src bytes.Buffer
+	tw := tar.NewWriter(&src)
+	_ = tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0o755})
+	_ = tw.WriteHeader(&tar.Header{Name: "/bin/", Typeflag: tar.TypeDir, Mode: 0o750})
+	_ = tw.WriteHeader(&tar.Header{Name: "/bin/run.sh", Typeflag: tar.TypeReg, Mode: 0o755, Size: 5})
+	_, _ = tw.Write([]byte("hello"))
+	_ = tw.WriteHeader(&tar.Header{Name: "run", Typeflag: tar.TypeSymlink, Linkname: "bin/run.sh"})
+	_ = tw.WriteHeader(&tar.Header{Name: "fifo", Typeflag: tar.TypeFifo, Mode: 0o644})
+	_ = tw.Close()
+
+	var out bytes.Buffer
+	dst := tar.NewWriter(&out)
+	if err := copyTarEntries(tar.NewReader(&src), dst, nil); err != nil {
+		t.Fatalf("copyTarEntries() error = %v", err)
+	}
+	_ = dst.Close()
+
+	entries := readTarEntries(t, out.Bytes())
+	want := []archiveEntry{
+		{name: "bin/", typeflag: tar.TypeDir, mode: 0o750},
+		{name: "bin/run.sh", typeflag: tar.TypeReg, mode: 0o755, body: "hello"},
+		{name: "run", typeflag: tar.TypeSymlink, linkname: "bin/run.sh"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Fatalf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestCopyTarEntriesRejectsEscapingPaths(t *testing.T) {
+	for _, hdr := range []*tar.Header{
+		{Name: "../evil", Typeflag: tar.TypeReg},
+		{Name: "a/../../evil", Typeflag: tar.TypeReg},
+		{Name: "link", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"},
+	} {
+		var src bytes.Buffer
+		tw := tar.NewWriter(&src)
+		_ = tw.WriteHeader(hdr)
+		_ = tw.Close()
+
+		err := copyTarEntries(tar.NewReader(&src), tar.NewWriter(io.Discard), nil)
+		if !errors.Is(err, ErrInvalidArchive) {
+			t.Fatalf("copyTarEntries(%q) error = %v, want ErrInvalidArchive", hdr.Name, err)
+		}
+	}
+}
+
+func TestCopyZipEntriesConvertsToTar(t *testing.T) {
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	dirHdr := &zip.FileHeader{Name: "src/"}
+	dirHdr.SetMode(0o700 | fs.ModeDir)
+	_, _ = zw.CreateHeader(dirHdr)
+	fileHdr := &zip.FileHeader{Name: "src/main.py", Method: zip.Deflate}
+	fileHdr.SetMode(0o755)
+	fw, _ := zw.CreateHeader(fileHdr)
+	_, _ = fw.Write([]byte("print(1)"))
+	_ = zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(zipped.Bytes()), int64(zipped.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	var out bytes.Buffer
+	dst := tar.NewWriter(&out)
+	if err := copyZipEntries(zr, dst, nil); err != nil {
+		t.Fatalf("copyZipEntries() error = %v", err)
+	}
+	_ = dst.Close()
+
+	entries := readTarEntries(t, out.Bytes())
+	want := []archiveEntry{
+		{name: "src/", typeflag: tar.TypeDir, mode: 0o700},
+		{name: "src/main.py", typeflag: tar.TypeReg, mode: 0o755, body: "print(1)"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Fatalf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestExtractBudgetCapsDecompressedSize(t *testing.T) {
+	svc := &SandboxService{}
+	svc.SetMaxFileTransferSize(1024)
+
+	// Highly compressible content stays far under the cap once gzipped.
+	var raw bytes.Buffer
+	tw := tar.NewWriter(&raw)
+	_ = tw.WriteHeader(&tar.Header{Name: "a.bin", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4096})
+	_, _ = tw.Write(make([]byte, 4096))
+	_ = tw.Close()
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write(raw.Bytes())
+	_ = gw.Close()
+	if gz.Len() > 1024 {
+		t.Fatalf("compressed size = %d, want under the cap", gz.Len())
+	}
+
+	gr, err := gzip.NewReader(&gz)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	err = copyTarEntries(tar.NewReader(gr), tar.NewWriter(io.Discard), svc.newExtractBudget())
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("copyTarEntries() error = %v, want ErrFileTooLarge", err)
+	}
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	fw, _ := zw.CreateHeader(&zip.FileHeader{Name: "a.bin", Method: zip.Deflate})
+	_, _ = fw.Write(make([]byte, 4096))
+	_ = zw.Close()
+	zr, cleanup, err := openZipArchive(bufio.NewReader(svc.limitTransfer(bytes.NewReader(zipped.Bytes()))))
+	if err != nil {
+		t.Fatalf("openZipArchive() error = %v", err)
+	}
+	defer cleanup()
+	err = copyZipEntries(zr, tar.NewWriter(io.Discard), svc.newExtractBudget())
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("copyZipEntries() error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestOpenZipArchiveReadsThroughTransferCap(t *testing.T) {
+	svc := &SandboxService{}
+	svc.SetMaxFileTransferSize(16)
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	_, _ = zw.Create("a.txt")
+	_ = zw.Close()
+
+	// A seekable source must not bypass the cap.
+	_, _, err := openZipArchive(bufio.NewReader(svc.limitTransfer(bytes.NewReader(zipped.Bytes()))))
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("openZipArchive() error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestTransferLimitReader(t *testing.T) {
+	svc := &SandboxService{}
+	svc.SetMaxFileTransferSize(4)
+
+	got, err := io.ReadAll(svc.limitTransfer(bytes.NewReader([]byte("1234"))))
+	if err != nil || string(got) != "1234" {
+		t.Fatalf("read at limit = %q, %v; want full content", got, err)
+	}
+
+	_, err = io.ReadAll(svc.limitTransfer(bytes.NewReader([]byte("12345"))))
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("read over limit error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestUploadFileRejectsKnownOversizedContent(t *testing.T) {
+	svc := &SandboxService{}
+	svc.SetMaxFileTransferSize(4)
+
+	err := svc.UploadFile(context.Background(), "sbx", "/workspace/f", bytes.NewReader(make([]byte, 5)), 5)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("UploadFile() error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestFileTransfersRequireAbsolutePaths(t *testing.T) {
+	svc := &SandboxService{}
+	ctx := context.Background()
+
+	if err := svc.UploadFile(ctx, "sbx", "workspace/f", bytes.NewReader(nil), 0); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("UploadFile() error = %v, want ErrInvalidPath", err)
+	}
+	if _, err := svc.StatDownload(ctx, "sbx", "-f"); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("StatDownload() error = %v, want ErrInvalidPath", err)
+	}
+	if err := svc.DownloadFile(ctx, "sbx", "f", 0, -1, io.Discard); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("DownloadFile() error = %v, want ErrInvalidPath", err)
+	}
+	if err := svc.UploadArchive(ctx, "sbx", "out", "", bytes.NewReader(nil)); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("UploadArchive() error = %v, want ErrInvalidPath", err)
+	}
+	if err := svc.DownloadArchive(ctx, "sbx", "out", io.Discard); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("DownloadArchive() error = %v, want ErrInvalidPath", err)
+	}
+}
+
+func TestTransferLimitWriterCancelsOnOverflow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out bytes.Buffer
+	w := &transferLimitWriter{w: &out, remaining: 4, cancel: cancel}
+
+	if _, err := w.Write([]byte("123")); err != nil {
+		t.Fatalf("Write() under limit error = %v", err)
+	}
+	if _, err := w.Write([]byte("45")); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("Write() over limit error = %v, want ErrFileTooLarge", err)
+	}
+	if !w.exceeded || ctx.Err() == nil {
+		t.Fatalf("writer should be marked exceeded and cancel the context")
+	}
+	if out.String() != "123" {
+		t.Fatalf("written = %q, want %q", out.String(), "123")
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const fsExecTimeout = 30 * time.Second
+
+// ListDir returns the entries of a directory inside the sandbox.
+func (s *SandboxService) ListDir(ctx context.Context, id, dir string) (*model.FileListResponse, error) {
+	dir, err := cleanSandboxPath(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, fsExecTimeout)
+	defer cancel()
+	stats, err := s.k8sClient.ListDir(execCtx, id, dir)
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+
+	items := make([]model.FileInfo, 0, len(stats))
+	for i := range stats {
+		items = append(items, fileStatToModel(&stats[i]))
+	}
+	return &model.FileListResponse{Path: dir, Items: items}, nil
+}
+
+// StatPath returns information about a single path inside the sandbox.
+func (s *SandboxService) StatPath(ctx context.Context, id, p string) (*model.FileInfo, error) {
+	p, err := cleanSandboxPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, fsExecTimeout)
+	defer cancel()
+	stat, err := s.k8sClient.StatPath(execCtx, id, p)
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+	info := fileStatToModel(stat)
+	return &info, nil
+}
+
+// MakeDir creates a directory and any missing parents.
+func (s *SandboxService) MakeDir(ctx context.Context, id, dir string) error {
+	dir, err := cleanSandboxPath(dir)
+	if err != nil {
+		return err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, fsExecTimeout)
+	defer cancel()
+	return mapFSError(s.k8sClient.MakeDir(execCtx, id, dir))
+}
+
+// MovePath renames a file or directory.
+func (s *SandboxService) MovePath(ctx context.Context, id string, req *model.MoveRequest) error {
+	src, err := cleanSandboxPath(req.Source)
+	if err != nil {
+		return err
+	}
+	dst, err := cleanSandboxPath(req.Destination)
+	if err != nil {
+		return err
+	}
+	if src == "/" || dst == "/" {
+		return fmt.Errorf("%w: cannot move the root directory", ErrInvalidPath)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, fsExecTimeout)
+	defer cancel()
+	return mapFSError(s.k8sClient.MovePath(execCtx, id, src, dst, req.Overwrite))
+}
+
+// RemovePath deletes a path. Non-empty directories require recursive.
+func (s *SandboxService) RemovePath(ctx context.Context, id, p string, recursive bool) error {
+	p, err := cleanSandboxPath(p)
+	if err != nil {
+		return err
+	}
+	if p == "/" {
+		return fmt.Errorf("%w: cannot remove the root directory", ErrInvalidPath)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, fsExecTimeout)
+	defer cancel()
+	return mapFSError(s.k8sClient.RemovePath(execCtx, id, p, recursive))
+}
+
+// cleanSandboxPath requires an absolute path and normalizes it.
+func cleanSandboxPath(p string) (string, error) {
+	if !path.IsAbs(p) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPath, p)
+	}
+	return path.Clean(p), nil
+}
+
+// mapFSError translates k8s filesystem errors into service errors.
+func mapFSError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsNotFound(err):
+		return ErrSandboxNotFound
+	case errors.Is(err, k8s.ErrPathNotFound):
+		return ErrPathNotFound
+	case errors.Is(err, k8s.ErrPathExists):
+		return ErrPathExists
+	case errors.Is(err, k8s.ErrPathIsDirectory):
+		return ErrPathIsDirectory
+	case errors.Is(err, k8s.ErrNotDirectory):
+		return ErrNotDirectory
+	case errors.Is(err, k8s.ErrDirectoryNotEmpty):
+		return ErrDirectoryNotEmpty
+	case errors.Is(err, k8s.ErrTooManyEntries):
+		return ErrTooManyWatchEntries
+	default:
+		return err
+	}
+}
+
+func fileStatToModel(stat *k8s.FileStat) model.FileInfo {
+	return model.FileInfo{
+		Name:       stat.Name,
+		Path:       stat.Path,
+		Type:       fileTypeFromMode(stat.Mode),
+		Size:       stat.Size,
+		Mode:       fmt.Sprintf("%04o", stat.Mode&0o7777),
+		ModifiedAt: stat.ModTime,
+		LinkTarget: stat.LinkTarget,
+	}
+}
+
+// fileTypeFromMode returns the file type encoded in raw st_mode bits.
+func fileTypeFromMode(mode uint32) model.FileType {
+	switch mode & 0o170000 {
+	case 0o100000:
+		return model.FileTypeFile
+	case 0o040000:
+		return model.FileTypeDirectory
+	case 0o120000:
+		return model.FileTypeSymlink
+	default:
+		return model.FileTypeOther
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+func TestFileStatToModel(t *testing.T) {
+	mtime := time.Unix(1700000000, 0).UTC()
+	tests := []struct {
+		mode     uint32
+		wantType model.FileType
+		wantMode string
+	}{
+		{mode: 0o100644, wantType: model.FileTypeFile, wantMode: "0644"},
+		{mode: 0o040755, wantType: model.FileTypeDirectory, wantMode: "0755"},
+		{mode: 0o120777, wantType: model.FileTypeSymlink, wantMode: "0777"},
+		{mode: 0o041777, wantType: model.FileTypeDirectory, wantMode: "1777"},
+		{mode: 0o010644, wantType: model.FileTypeOther, wantMode: "0644"},
+	}
+	for _, tt := range tests {
+		info := fileStatToModel(&k8s.FileStat{Name: "x", Path: "/x", Mode: tt.mode, Size: 3, ModTime: mtime})
+		if info.Type != tt.wantType || info.Mode != tt.wantMode {
+			t.Fatalf("fileStatToModel(%o) = type %q mode %q, want %q %q", tt.mode, info.Type, info.Mode, tt.wantType, tt.wantMode)
+		}
+		if info.Size != 3 || !info.ModifiedAt.Equal(mtime) || info.Path != "/x" {
+			t.Fatalf("fileStatToModel(%o) = %+v", tt.mode, info)
+		}
+	}
+}
+
+func TestSandboxFSRejectsInvalidPaths(t *testing.T) {
+	svc := &SandboxService{k8sClient: k8s.NewClientForTest()}
+	ctx := context.Background()
+
+	if _, err := svc.ListDir(ctx, "sbx", "workspace"); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("ListDir(relative) error = %v, want ErrInvalidPath", err)
+	}
+	if err := svc.RemovePath(ctx, "sbx", "/workspace/..", true); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("RemovePath(root) error = %v, want ErrInvalidPath", err)
+	}
+	if err := svc.MovePath(ctx, "sbx", &model.MoveRequest{Source: "/", Destination: "/tmp/root"}); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("MovePath(root) error = %v, want ErrInvalidPath", err)
+	}
+}
+
+func TestSandboxFSMissingPodReportsSandboxNotFound(t *testing.T) {
+	svc := &SandboxService{k8sClient: k8s.NewClientForTest()}
+
+	if _, err := svc.StatPath(context.Background(), "missing", "/workspace"); !errors.Is(err, ErrSandboxNotFound) {
+		t.Fatalf("StatPath() error = %v, want ErrSandboxNotFound", err)
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+const (
+	defaultWatchInterval = time.Second
+	maxWatchInterval     = time.Minute
+)
+
+// WatchPath reports changes to p and everything below it until ctx is done. The tree
+// is rescanned every interval, so several changes to a path between two scans are
+// reported as one event. A ready event is emitted once the initial scan completes.
+func (s *SandboxService) WatchPath(ctx context.Context, id, p string, interval time.Duration, emit func(model.FSEvent) error) error {
+	p, err := cleanSandboxPath(p)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	if interval > maxWatchInterval {
+		interval = maxWatchInterval
+	}
+
+	var prev map[string]k8s.FileStat
+	err = s.k8sClient.WatchPath(ctx, id, p, interval, func(snapshot map[string]k8s.FileStat) error {
+		if prev == nil {
+			prev = snapshot
+			return emit(model.FSEvent{Type: model.FSEventReady, Path: p})
+		}
+		for _, event := range diffSnapshots(prev, snapshot) {
+			if err := emit(event); err != nil {
+				return err
+			}
+		}
+		prev = snapshot
+		return nil
+	})
+	return mapFSError(err)
+}
+
+// diffSnapshots returns the events turning prev into next. Deletes come first, children
+// before their parents; creates and modifications follow, parents before children.
+// Directory modification times change whenever an entry is added or removed, so only
+// files and symlinks get modify events. A path whose type changed is reported as
+// deleted and created again.
+func diffSnapshots(prev, next map[string]k8s.FileStat) []model.FSEvent {
+	var deleted, changed []string
+	for p, old := range prev {
+		cur, ok := next[p]
+		if !ok || fileTypeFromMode(cur.Mode) != fileTypeFromMode(old.Mode) {
+			deleted = append(deleted, p)
+		}
+	}
+	for p, cur := range next {
+		old, ok := prev[p]
+		if !ok || fileTypeFromMode(cur.Mode) != fileTypeFromMode(old.Mode) {
+			changed = append(changed, p)
+		} else if fileTypeFromMode(cur.Mode) != model.FileTypeDirectory &&
+			(cur.Size != old.Size || !cur.ModTime.Equal(old.ModTime) || cur.Mode != old.Mode) {
+			changed = append(changed, p)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(deleted)))
+	sort.Strings(changed)
+
+	events := make([]model.FSEvent, 0, len(deleted)+len(changed))
+	for _, p := range deleted {
+		old := prev[p]
+		events = append(events, model.FSEvent{Type: model.FSEventDelete, Path: p, FileType: fileTypeFromMode(old.Mode)})
+	}
+	for _, p := range changed {
+		cur := next[p]
+		eventType := model.FSEventModify
+		if old, ok := prev[p]; !ok || fileTypeFromMode(old.Mode) != fileTypeFromMode(cur.Mode) {
+			eventType = model.FSEventCreate
+		}
+		modTime := cur.ModTime
+		events = append(events, model.FSEvent{
+			Type:       eventType,
+			Path:       p,
+			FileType:   fileTypeFromMode(cur.Mode),
+			Size:       cur.Size,
+			ModifiedAt: &modTime,
+		})
+	}
+	return events
+}
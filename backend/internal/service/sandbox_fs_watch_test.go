@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	t0 := time.Unix(1700000000, 0).UTC()
+	t1 := t0.Add(time.Second)
+	prev := map[string]k8s.FileStat{
+		"/w":          {Path: "/w", Mode: 0o040755, ModTime: t0},
+		"/w/keep.txt": {Path: "/w/keep.txt", Mode: 0o100644, Size: 1, ModTime: t0},
+		"/w/edit.txt": {Path: "/w/edit.txt", Mode: 0o100644, Size: 1, ModTime: t0},
+		"/w/old":      {Path: "/w/old", Mode: 0o040755, ModTime: t0},
+		"/w/old/f":    {Path: "/w/old/f", Mode: 0o100644, ModTime: t0},
+		"/w/swap":     {Path: "/w/swap", Mode: 0o100644, ModTime: t0},
+	}
+	next := map[string]k8s.FileStat{
+		"/w":          {Path: "/w", Mode: 0o040755, ModTime: t1},
+		"/w/keep.txt": {Path: "/w/keep.txt", Mode: 0o100644, Size: 1, ModTime: t0},
+		"/w/edit.txt": {Path: "/w/edit.txt", Mode: 0o100644, Size: 2, ModTime: t1},
+		"/w/new":      {Path: "/w/new", Mode: 0o040755, ModTime: t1},
+		"/w/new/f":    {Path: "/w/new/f", Mode: 0o100644, ModTime: t1},
+		"/w/swap":     {Path: "/w/swap", Mode: 0o040755, ModTime: t1},
+	}
+
+	events := diffSnapshots(prev, next)
+	want := []struct {
+		eventType model.FSEventType
+		path      string
+		fileType  model.FileType
+	}{
+		{model.FSEventDelete, "/w/swap", model.FileTypeFile},
+		{model.FSEventDelete, "/w/old/f", model.FileTypeFile},
+		{model.FSEventDelete, "/w/old", model.FileTypeDirectory},
+		{model.FSEventModify, "/w/edit.txt", model.FileTypeFile},
+		{model.FSEventCreate, "/w/new", model.FileTypeDirectory},
+		{model.FSEventCreate, "/w/new/f", model.FileTypeFile},
+		{model.FSEventCreate, "/w/swap", model.FileTypeDirectory},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want %d events", events, len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != w.eventType || e.Path != w.path || e.FileType != w.fileType {
+			t.Fatalf("event %d = %+v, want %s %s (%s)", i, e, w.eventType, w.path, w.fileType)
+		}
+	}
+	if events[3].Size != 2 || events[3].ModifiedAt == nil || !events[3].ModifiedAt.Equal(t1) {
+		t.Fatalf("modify event = %+v, want size 2 and the new modification time", events[3])
+	}
+}
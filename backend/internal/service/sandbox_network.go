@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// SetAllowNetworkWidening lets per-sandbox network overrides grant more egress than the
+// template. By default overrides may only narrow it.
+func (s *SandboxService) SetAllowNetworkWidening(allow bool) {
+	s.allowNetworkWidening = allow
+}
+
+// resolveNetworkOverride validates a per-sandbox network override against the template's
+// network configuration and returns its normalized form.
+func (s *SandboxService) resolveNetworkOverride(base, override *model.NetworkSpec) (*model.NetworkSpec, error) {
+	network := copyNetworkSpec(override)
+	if err := validateNetworkSpec(network); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNetwork, err)
+	}
+	if !network.AllowInternetAccess {
+		// Domains and internet ports are only enforced while internet access is on
+		network.AllowedDomains = nil
+		network.Ports = nil
+		network.DomainRules = nil
+	}
+	inheritLimits(base, network)
+	if !s.allowNetworkWidening && !networkNarrows(base, network) {
+		return nil, ErrNetworkWideningNotAllowed
+	}
+	return network, nil
+}
+
+func copyNetworkSpec(spec *model.NetworkSpec) *model.NetworkSpec {
+	out := &model.NetworkSpec{
+		AllowInternetAccess: spec.AllowInternetAccess,
+		AllowedDomains:      append([]string(nil), spec.AllowedDomains...),
+		Ports:               append([]model.PortRule(nil), spec.Ports...),
+		DeniedCIDRs:         append([]string(nil), spec.DeniedCIDRs...),
+	}
+	if spec.Bandwidth != nil {
+		bandwidth := *spec.Bandwidth
+		out.Bandwidth = &bandwidth
+	}
+	for _, rule := range spec.DomainRules {
+		out.DomainRules = append(out.DomainRules, model.DomainRule{
+			Domains: append([]string(nil), rule.Domains...),
+			Ports:   append([]model.PortRule(nil), rule.Ports...),
+		})
+	}
+	for _, rule := range spec.AllowedCIDRs {
+		out.AllowedCIDRs = append(out.AllowedCIDRs, model.CIDRRule{
+			CIDR:  rule.CIDR,
+			Ports: append([]model.PortRule(nil), rule.Ports...),
+		})
+	}
+	return out
+}
+
+// networkNarrows reports whether network allows no egress beyond base.
+func networkNarrows(base, network *model.NetworkSpec) bool {
+	if base == nil {
+		base = &model.NetworkSpec{}
+	}
+	for _, denied := range base.DeniedCIDRs {
+		if !slices.Contains(network.DeniedCIDRs, denied) {
+			return false
+		}
+	}
+	for _, rule := range network.AllowedCIDRs {
+		if !cidrRuleAllowed(base.AllowedCIDRs, rule) {
+			return false
+		}
+	}
+	if !limitsNarrow(base, network) {
+		return false
+	}
+	if !network.AllowInternetAccess {
+		return true
+	}
+	if !base.AllowInternetAccess {
+		return false
+	}
+	baseGrants := internetGrants(base)
+	for _, grant := range internetGrants(network) {
+		if !slices.ContainsFunc(baseGrants, func(b internetGrant) bool {
+			return (b.domain == "" || (grant.domain != "" && domainAllowed([]string{b.domain}, grant.domain))) &&
+				portsAllowed(b.ports, grant.ports)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// inheritLimits fills in the traffic limits network leaves unset from base.
+func inheritLimits(base, network *model.NetworkSpec) {
+	if base == nil {
+		return
+	}
+	baseEgress, baseIngress := bandwidthOf(base)
+	egress, ingress := bandwidthOf(network)
+	if egress == "" {
+		egress = baseEgress
+	}
+	if ingress == "" {
+		ingress = baseIngress
+	}
+	network.Bandwidth = nil
+	if egress != "" || ingress != "" {
+		network.Bandwidth = &model.BandwidthSpec{Egress: egress, Ingress: ingress}
+	}
+}
+
+// limitsNarrow reports whether network's traffic limits are at least as strict as base's.
+func limitsNarrow(base, network *model.NetworkSpec) bool {
+	baseEgress, baseIngress := bandwidthOf(base)
+	egress, ingress := bandwidthOf(network)
+	return rateNarrows(baseEgress, egress) && rateNarrows(baseIngress, ingress)
+}
+
+func bandwidthOf(network *model.NetworkSpec) (egress, ingress string) {
+	if network == nil || network.Bandwidth == nil {
+		return "", ""
+	}
+	return network.Bandwidth.Egress, network.Bandwidth.Ingress
+}
+
+// rateNarrows reports whether rate is no higher than base. An empty rate is unlimited.
+func rateNarrows(base, rate string) bool {
+	if base == "" {
+		return true
+	}
+	if rate == "" {
+		return false
+	}
+	baseQuantity, err := resource.ParseQuantity(base)
+	if err != nil {
+		return false
+	}
+	quantity, err := resource.ParseQuantity(rate)
+	return err == nil && quantity.Cmp(baseQuantity) <= 0
+}
+
+// limitsEqual reports whether two network configurations have the same traffic limits.
+func limitsEqual(a, b *model.NetworkSpec) bool {
+	aEgress, aIngress := bandwidthOf(a)
+	bEgress, bIngress := bandwidthOf(b)
+	return aEgress == bEgress && aIngress == bIngress
+}
+
+// internetGrant is a domain, or any internet destination when domain is empty, reachable
+// on some ports.
+type internetGrant struct {
+	domain string
+	ports  []model.PortRule
+}
+
+func internetGrants(network *model.NetworkSpec) []internetGrant {
+	ports := network.Ports
+	if len(ports) == 0 {
+		ports = []model.PortRule{{Port: 443, Protocol: model.PortProtocolTCP}, {Port: 80, Protocol: model.PortProtocolTCP}}
+	}
+	if len(network.AllowedDomains) == 0 && len(network.DomainRules) == 0 {
+		return []internetGrant{{ports: ports}}
+	}
+	var grants []internetGrant
+	for _, domain := range network.AllowedDomains {
+		grants = append(grants, internetGrant{domain: domain, ports: ports})
+	}
+	for _, rule := range network.DomainRules {
+		rulePorts := rule.Ports
+		if len(rulePorts) == 0 {
+			rulePorts = ports
+		}
+		for _, domain := range rule.Domains {
+			grants = append(grants, internetGrant{domain: domain, ports: rulePorts})
+		}
+	}
+	return grants
+}
+
+// domainAllowed reports whether a domain or wildcard pattern is covered by an allowlist.
+func domainAllowed(allowlist []string, domain string) bool {
+	for _, allowed := range allowlist {
+		if allowed == domain {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(domain, allowed[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// portsAllowed reports whether every port in ports is also in allowed. An empty list
+// stands for all ports.
+func portsAllowed(allowed, ports []model.PortRule) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if len(ports) == 0 {
+		return false
+	}
+	for _, port := range ports {
+		if !slices.ContainsFunc(allowed, func(a model.PortRule) bool {
+			return a.Port == port.Port && (a.Protocol == port.Protocol || a.Protocol == model.PortProtocolAny)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// cidrRuleAllowed reports whether a CIDR rule lies within one of the allowed rules.
+func cidrRuleAllowed(allowed []model.CIDRRule, rule model.CIDRRule) bool {
+	_, ruleNet, err := net.ParseCIDR(rule.CIDR)
+	if err != nil {
+		return false
+	}
+	ruleOnes, _ := ruleNet.Mask.Size()
+	for _, a := range allowed {
+		_, allowedNet, err := net.ParseCIDR(a.CIDR)
+		if err != nil {
+			continue
+		}
+		allowedOnes, allowedBits := allowedNet.Mask.Size()
+		if len(ruleNet.IP) == allowedBits/8 && allowedOnes <= ruleOnes && allowedNet.Contains(ruleNet.IP) && portsAllowed(a.Ports, rule.Ports) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsInternetLabel reports whether network is plain open internet access, which is
+// enforced by the internet-access pod label rather than a per-sandbox egress policy.
+func needsInternetLabel(network *model.NetworkSpec) bool {
+	return toK8sNetwork(network).UsesInternetLabel()
+}
+
+// validateNetworkGroup checks a network group name and returns the normalized ports the
+// sandbox accepts connections on from its group.
+func validateNetworkGroup(group string, ports []model.PortRule) ([]model.PortRule, error) {
+	if group == "" {
+		if len(ports) > 0 {
+			return nil, fmt.Errorf("%w: groupPorts requires networkGroup", ErrInvalidNetworkGroup)
+		}
+		return nil, nil
+	}
+	if len(group) > 63 || !namePattern.MatchString(group) {
+		return nil, fmt.Errorf("%w: name must be at most 63 lowercase alphanumeric characters or '-'", ErrInvalidNetworkGroup)
+	}
+	ports, err := normalizePorts(append([]model.PortRule(nil), ports...), "groupPorts")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNetworkGroup, err)
+	}
+	return ports, nil
+}
+
+// checkNetworkGroupOwner refuses to add a sandbox to a network group that has members
+// of another owner, since members of a group reach each other's ports.
+func (s *SandboxService) checkNetworkGroupOwner(ctx context.Context, namespace, group string) error {
+	ownerID := ""
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		ownerID = p.UserID
+	}
+	owners, err := s.sandboxStore.ListNetworkGroupOwners(ctx, namespace, group)
+	if err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if owner != ownerID {
+			return fmt.Errorf("%w: network group %s is used by another user", ErrInvalidNetworkGroup, group)
+		}
+	}
+	return nil
+}
+
+// applyNetworkGroup opens traffic between a sandbox and the rest of its network group.
+func (s *SandboxService) applyNetworkGroup(ctx context.Context, id, group string, ports []model.PortRule) error {
+	return k8s.NewNetworkPolicyManager(s.k8sClient).ApplyNetworkGroup(ctx, id, group, toK8sPorts(ports))
+}
+
+// effectiveNetwork returns the network configuration a sandbox runs with: its own
+// override, or else its template's.
+func (s *SandboxService) effectiveNetwork(ctx context.Context, record *store.SandboxRecord) (*model.NetworkSpec, error) {
+	if network := record.NetworkOverride(); network != nil {
+		return network, nil
+	}
+	if s.templateSvc == nil {
+		return nil, fmt.Errorf("template service not configured")
+	}
+	spec, err := s.templateSvc.GetSpecForSandbox(ctx, record.TemplateName, record.TemplateVersion)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Network, nil
+}
+
+// UpdateNetwork changes the egress of a sandbox. The new configuration is persisted
+// first, so the network policy reconciler converges to it even if applying it to the
+// running sandbox fails.
+func (s *SandboxService) UpdateNetwork(ctx context.Context, id string, req *model.UpdateSandboxNetworkRequest) (*model.Sandbox, error) {
+	record, err := s.sandboxStore.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.LifecycleStatus == "deleted" || record.DesiredState == store.DesiredStateDeleted {
+		return nil, ErrSandboxNotFound
+	}
+	if s.templateSvc == nil {
+		return nil, fmt.Errorf("template service not configured")
+	}
+	spec, err := s.templateSvc.GetSpecForSandbox(ctx, record.TemplateName, record.TemplateVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	current := record.NetworkOverride()
+	if current == nil {
+		current = spec.Network
+	}
+	network := spec.Network
+	networkJSON := ""
+	if !req.Reset {
+		network, err = s.resolveNetworkOverride(spec.Network, &req.NetworkSpec)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal network: %w", err)
+		}
+		networkJSON = string(data)
+	}
+	// The internet-access label is part of a persistent sandbox's deployment selector,
+	// which cannot change.
+	if record.PersistenceEnabled && needsInternetLabel(current) != needsInternetLabel(network) {
+		return nil, ErrNetworkChangeNotSupported
+	}
+	// Pod bandwidth limits are applied when the pod's network is set up
+	if !limitsEqual(current, network) {
+		return nil, ErrNetworkLimitsImmutable
+	}
+
+	now := time.Now().UTC()
+	if err := s.sandboxStore.SetNetwork(ctx, id, networkJSON, now); err != nil {
+		return nil, err
+	}
+	record.NetworkJSON = networkJSON
+	record.UpdatedAt = now
+	if err := s.applyNetwork(ctx, id, !record.PersistenceEnabled, network); err != nil {
+		return nil, fmt.Errorf("failed to apply network: %w", err)
+	}
+	_ = s.sandboxStore.AppendStatusHistory(ctx, id, "api", record.LifecycleStatus, record.LifecycleStatus, "network updated", network, now)
+
+	sandbox := s.recordToSandboxMetadata(record)
+	return &sandbox, nil
+}
+
+// applyNetwork applies a network configuration to a live sandbox. The internet-access
+// label is only set on plain pods; a sandbox without a pod picks it up from its record
+// when the pod is recreated.
+func (s *SandboxService) applyNetwork(ctx context.Context, id string, setLabel bool, network *model.NetworkSpec) error {
+	manager := k8s.NewNetworkPolicyManager(s.k8sClient)
+	if setLabel {
+		if err := s.applyInternetLabel(ctx, id, network); err != nil {
+			return err
+		}
+	}
+	if s.k8sClient.GetDynamicClient() == nil {
+		return nil
+	}
+	if k8sNetwork := toK8sNetwork(network); k8sNetwork.NeedsEgressPolicy() {
+		return manager.ApplyEgressPolicy(ctx, id, k8sNetwork)
+	}
+	return manager.DeleteDomainAllowlistPolicy(ctx, id)
+}
+
+// applyInternetLabel sets the internet-access label of a sandbox pod to match network.
+// A missing pod is not an error.
+func (s *SandboxService) applyInternetLabel(ctx context.Context, id string, network *model.NetworkSpec) error {
+	if _, err := s.k8sClient.GetPod(ctx, id); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	manager := k8s.NewNetworkPolicyManager(s.k8sClient)
+	if needsInternetLabel(network) {
+		return manager.AllowInternetAccess(ctx, id)
+	}
+	return manager.DenyInternetAccess(ctx, id)
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveNetworkOverride(t *testing.T) {
+	allowlisted := &model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"api.example.com", "*.pypi.org"}}
+	open := &model.NetworkSpec{AllowInternetAccess: true}
+	rules := &model.NetworkSpec{
+		AllowInternetAccess: true,
+		DomainRules:         []model.DomainRule{{Domains: []string{"github.com"}, Ports: []model.PortRule{{Port: 22, Protocol: "TCP"}}}},
+		AllowedCIDRs:        []model.CIDRRule{{CIDR: "10.20.0.0/16", Ports: []model.PortRule{{Port: 5432, Protocol: "TCP"}}}},
+		DeniedCIDRs:         []string{"10.20.5.0/24"},
+	}
+
+	tests := []struct {
+		name     string
+		base     *model.NetworkSpec
+		override model.NetworkSpec
+		widening bool
+		wantErr  error
+	}{
+		{name: "disable internet", base: allowlisted, override: model.NetworkSpec{}},
+		{name: "disable internet without template network", base: nil, override: model.NetworkSpec{}},
+		{name: "subset of domains", base: allowlisted, override: model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"API.example.com."}}},
+		{name: "subdomain of wildcard", base: allowlisted, override: model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"files.pypi.org", "*.cdn.pypi.org"}}},
+		{name: "domains under open internet", base: open, override: model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"github.com"}}},
+		{name: "domain outside allowlist", base: allowlisted, override: model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"github.com"}}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "wildcard root", base: allowlisted, override: model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"pypi.org"}}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "open internet over allowlist", base: allowlisted, override: model.NetworkSpec{AllowInternetAccess: true}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "internet without template network", base: nil, override: model.NetworkSpec{AllowInternetAccess: true}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "widening allowed", base: nil, override: model.NetworkSpec{AllowInternetAccess: true}, widening: true},
+		{name: "invalid domain", base: open, override: model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"https://github.com"}}, wantErr: ErrInvalidNetwork},
+		{name: "internet port outside template", base: open, override: model.NetworkSpec{AllowInternetAccess: true, Ports: []model.PortRule{{Port: 22}}}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "subset of template ports", base: open, override: model.NetworkSpec{AllowInternetAccess: true, Ports: []model.PortRule{{Port: 443}}}},
+		{name: "domain rule within template rule", base: rules, override: model.NetworkSpec{AllowInternetAccess: true, DomainRules: []model.DomainRule{{Domains: []string{"github.com"}, Ports: []model.PortRule{{Port: 22}}}}, DeniedCIDRs: []string{"10.20.5.0/24"}}},
+		{name: "domain rule on other port", base: rules, override: model.NetworkSpec{AllowInternetAccess: true, DomainRules: []model.DomainRule{{Domains: []string{"github.com"}, Ports: []model.PortRule{{Port: 23}}}}, DeniedCIDRs: []string{"10.20.5.0/24"}}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "narrower CIDR", base: rules, override: model.NetworkSpec{AllowedCIDRs: []model.CIDRRule{{CIDR: "10.20.1.0/24", Ports: []model.PortRule{{Port: 5432}}}}, DeniedCIDRs: []string{"10.20.5.0/24"}}},
+		{name: "CIDR outside template", base: rules, override: model.NetworkSpec{AllowedCIDRs: []model.CIDRRule{{CIDR: "10.0.0.0/8", Ports: []model.PortRule{{Port: 5432}}}}, DeniedCIDRs: []string{"10.20.5.0/24"}}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "CIDR on all ports", base: rules, override: model.NetworkSpec{AllowedCIDRs: []model.CIDRRule{{CIDR: "10.20.1.0/24"}}, DeniedCIDRs: []string{"10.20.5.0/24"}}, wantErr: ErrNetworkWideningNotAllowed},
+		{name: "dropped denied CIDR", base: rules, override: model.NetworkSpec{}, wantErr: ErrNetworkWideningNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &SandboxService{allowNetworkWidening: tt.widening}
+			_, err := svc.resolveNetworkOverride(tt.base, &tt.override)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("resolveNetworkOverride() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveNetworkOverride() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveNetworkOverrideLimits(t *testing.T) {
+	base := &model.NetworkSpec{AllowInternetAccess: true, Bandwidth: &model.BandwidthSpec{Egress: "10M"}}
+	svc := &SandboxService{}
+
+	network, err := svc.resolveNetworkOverride(base, &model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"github.com"}})
+	if err != nil {
+		t.Fatalf("resolveNetworkOverride() error = %v", err)
+	}
+	if network.Bandwidth == nil || network.Bandwidth.Egress != "10M" {
+		t.Fatalf("override did not inherit the template limits: %+v", network.Bandwidth)
+	}
+	network, err = svc.resolveNetworkOverride(base, &model.NetworkSpec{Bandwidth: &model.BandwidthSpec{Egress: "5000k", Ingress: "1M"}})
+	if err != nil {
+		t.Fatalf("resolveNetworkOverride() narrower limits error = %v", err)
+	}
+	if network.Bandwidth.Egress != "5M" || network.Bandwidth.Ingress != "1M" {
+		t.Fatalf("narrower limits = %+v", network.Bandwidth)
+	}
+
+	override := &model.NetworkSpec{Bandwidth: &model.BandwidthSpec{Egress: "20M"}}
+	if _, err := svc.resolveNetworkOverride(base, override); !errors.Is(err, ErrNetworkWideningNotAllowed) {
+		t.Fatalf("resolveNetworkOverride(%+v) error = %v, want ErrNetworkWideningNotAllowed", override, err)
+	}
+}
+
+func TestUpdateNetworkAppliesAndPersistsOverride(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	templateSvc := NewTemplateService()
+	if _, err := templateSvc.store.Create(ctx, &model.CreateTemplateRequest{
+		Name: "python",
+		Spec: model.TemplateSpec{
+			Image:   "python:3.11",
+			Network: &model.NetworkSpec{AllowInternetAccess: true},
+		},
+	}); err != nil {
+		t.Fatalf("Create template error = %v", err)
+	}
+	rec := makeTestSandboxRecord("net1", false, "running")
+	rec.RuntimeKind = "pod"
+	sandboxStore := store.NewSandboxStore()
+	if err := sandboxStore.Create(ctx, rec); err != nil {
+		t.Fatalf("Create sandbox error = %v", err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "sandbox-net1",
+		Namespace: k8s.DefaultSandboxNamespace,
+		Labels:    map[string]string{"app": k8s.LabelApp, k8s.LabelSandboxID: "net1", k8s.LabelInternetAccess: "true"},
+	}}
+	k8sClient := k8s.NewClientForTestWithDynamic(pod)
+	svc := NewSandboxService(k8sClient, sandboxStore, nil)
+	svc.SetTemplateService(templateSvc)
+
+	sb, err := svc.UpdateNetwork(ctx, "net1", &model.UpdateSandboxNetworkRequest{
+		NetworkSpec: model.NetworkSpec{AllowInternetAccess: true, AllowedDomains: []string{"github.com"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateNetwork() error = %v", err)
+	}
+	if sb.Network == nil || len(sb.Network.AllowedDomains) != 1 {
+		t.Fatalf("UpdateNetwork() network = %+v", sb.Network)
+	}
+	got, err := k8sClient.GetPod(ctx, "net1")
+	if err != nil {
+		t.Fatalf("GetPod() error = %v", err)
+	}
+	if _, ok := got.Labels[k8s.LabelInternetAccess]; ok {
+		t.Fatalf("internet-access label kept under a domain allowlist")
+	}
+	mgr := k8s.NewNetworkPolicyManager(k8sClient)
+	if _, err := mgr.GetDomainAllowlistPolicy(ctx, "net1"); err != nil {
+		t.Fatalf("GetDomainAllowlistPolicy() error = %v", err)
+	}
+
+	// The reconciler converges to the override rather than the template
+	if err := mgr.DeleteDomainAllowlistPolicy(ctx, "net1"); err != nil {
+		t.Fatalf("DeleteDomainAllowlistPolicy() error = %v", err)
+	}
+	reconciler := NewSandboxNetworkPolicyReconciler(k8sClient, sandboxStore, store.NewTemplateStore())
+	if err := reconciler.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if _, err := mgr.GetDomainAllowlistPolicy(ctx, "net1"); err != nil {
+		t.Fatalf("reconciler did not restore the override policy: %v", err)
+	}
+
+	if _, err := svc.UpdateNetwork(ctx, "net1", &model.UpdateSandboxNetworkRequest{Reset: true}); err != nil {
+		t.Fatalf("UpdateNetwork(reset) error = %v", err)
+	}
+	got, _ = k8sClient.GetPod(ctx, "net1")
+	if got.Labels[k8s.LabelInternetAccess] != "true" {
+		t.Fatalf("reset did not restore the template's internet access: %v", got.Labels)
+	}
+	if _, err := mgr.GetDomainAllowlistPolicy(ctx, "net1"); err == nil {
+		t.Fatalf("reset kept the domain allowlist policy")
+	}
+	after, _ := sandboxStore.GetByID(ctx, "net1")
+	if after.NetworkOverride() != nil {
+		t.Fatalf("reset kept the override: %s", after.NetworkJSON)
+	}
+}
+
+func TestUpdateNetworkRejectsLabelChangeOnPersistentSandbox(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	templateSvc := NewTemplateService()
+	if _, err := templateSvc.store.Create(ctx, &model.CreateTemplateRequest{
+		Name: "python",
+		Spec: model.TemplateSpec{
+			Image:   "python:3.11",
+			Network: &model.NetworkSpec{AllowInternetAccess: true},
+		},
+	}); err != nil {
+		t.Fatalf("Create template error = %v", err)
+	}
+	sandboxStore := store.NewSandboxStore()
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("net2", true, "running")); err != nil {
+		t.Fatalf("Create sandbox error = %v", err)
+	}
+	svc := NewSandboxService(k8s.NewClientForTestWithDynamic(), sandboxStore, nil)
+	svc.SetTemplateService(templateSvc)
+
+	_, err := svc.UpdateNetwork(ctx, "net2", &model.UpdateSandboxNetworkRequest{})
+	if !errors.Is(err, ErrNetworkChangeNotSupported) {
+		t.Fatalf("UpdateNetwork() error = %v, want ErrNetworkChangeNotSupported", err)
+	}
+	rec, _ := sandboxStore.GetByID(ctx, "net2")
+	if rec.NetworkJSON != "" {
+		t.Fatalf("rejected change was persisted: %s", rec.NetworkJSON)
+	}
+}
+
+func TestCreateSandboxJoinsNetworkGroup(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	templateSvc := NewTemplateService()
+	if _, err := templateSvc.store.Create(ctx, &model.CreateTemplateRequest{
+		Name: "agent",
+		Spec: model.TemplateSpec{Image: "busybox:1.36", Command: []string{"sh", "-c", "sleep 30"}},
+	}); err != nil {
+		t.Fatalf("Create template error = %v", err)
+	}
+	sandboxStore := store.NewSandboxStore()
+	k8sClient := k8s.NewClientForTest()
+	svc := NewSandboxService(k8sClient, sandboxStore, cipher)
+	svc.SetTemplateService(templateSvc)
+
+	sb, err := svc.Create(ctx, &model.CreateSandboxRequest{
+		Template:     "agent",
+		NetworkGroup: "team-a",
+		GroupPorts:   []model.PortRule{{Port: 8080}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sb.NetworkGroup != "team-a" || sb.PeerHost != "peer-"+sb.ID || len(sb.GroupPorts) != 1 || sb.GroupPorts[0].Protocol != model.PortProtocolTCP {
+		t.Fatalf("Create() = group %q peer %q ports %+v", sb.NetworkGroup, sb.PeerHost, sb.GroupPorts)
+	}
+	pod, err := k8sClient.GetPod(ctx, sb.ID)
+	if err != nil {
+		t.Fatalf("GetPod() error = %v", err)
+	}
+	if pod.Labels[k8s.LabelNetworkGroup] != "team-a" {
+		t.Fatalf("pod labels = %v, want the network group label", pod.Labels)
+	}
+	mgr := k8s.NewNetworkPolicyManager(k8sClient)
+	members, err := mgr.ListNetworkGroupMembers(ctx)
+	if err != nil || len(members) != 1 || members[0] != sb.ID {
+		t.Fatalf("ListNetworkGroupMembers() = %v, %v", members, err)
+	}
+
+	for _, req := range []*model.CreateSandboxRequest{
+		{Template: "agent", NetworkGroup: "Team_A"},
+		{Template: "agent", GroupPorts: []model.PortRule{{Port: 8080}}},
+		{Template: "agent", NetworkGroup: "team-a", GroupPorts: []model.PortRule{{Port: 0}}},
+	} {
+		if _, err := svc.Create(ctx, req); !errors.Is(err, ErrInvalidNetworkGroup) {
+			t.Fatalf("Create(%+v) error = %v, want ErrInvalidNetworkGroup", req, err)
+		}
+	}
+	// Members of a group reach each other, so other users can't join it
+	bob := auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-bob", Role: model.RoleDeveloper})
+	if _, err := svc.Create(bob, &model.CreateSandboxRequest{Template: "agent", NetworkGroup: "team-a"}); !errors.Is(err, ErrInvalidNetworkGroup) {
+		t.Fatalf("Create() in another user's group error = %v, want ErrInvalidNetworkGroup", err)
+	}
+
+	// The reconciler drops the resources of sandboxes that left the group
+	if err := sandboxStore.MarkDeletionRequested(ctx, sb.ID, time.Now().UTC()); err != nil {
+		t.Fatalf("MarkDeletionRequested() error = %v", err)
+	}
+	reconciler := NewSandboxNetworkPolicyReconciler(k8sClient, sandboxStore, store.NewTemplateStore())
+	if err := reconciler.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	members, err = mgr.ListNetworkGroupMembers(ctx)
+	if err != nil || len(members) != 0 {
+		t.Fatalf("ListNetworkGroupMembers() after deletion = %v, %v", members, err)
+	}
+}
+
+func TestCreateSandboxAppliesNetworkLimits(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	templateSvc := NewTemplateService()
+	if _, err := templateSvc.store.Create(ctx, &model.CreateTemplateRequest{
+		Name: "loader",
+		Spec: model.TemplateSpec{
+			Image:   "busybox:1.36",
+			Command: []string{"sh", "-c", "sleep 30"},
+			Network: &model.NetworkSpec{Bandwidth: &model.BandwidthSpec{Egress: "10M", Ingress: "20M"}},
+		},
+	}); err != nil {
+		t.Fatalf("Create template error = %v", err)
+	}
+	sandboxStore := store.NewSandboxStore()
+	k8sClient := k8s.NewClientForTest()
+	svc := NewSandboxService(k8sClient, sandboxStore, cipher)
+	svc.SetTemplateService(templateSvc)
+	svc.SetAllowNetworkWidening(true)
+
+	sb, err := svc.Create(ctx, &model.CreateSandboxRequest{
+		Template:  "loader",
+		Overrides: &model.SandboxOverrides{Network: &model.NetworkSpec{Bandwidth: &model.BandwidthSpec{Egress: "5M"}}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sb.Bandwidth == nil || sb.Bandwidth.Egress != "5M" || sb.Bandwidth.Ingress != "20M" {
+		t.Fatalf("Create() limits = %+v", sb.Bandwidth)
+	}
+	pod, err := k8sClient.GetPod(ctx, sb.ID)
+	if err != nil {
+		t.Fatalf("GetPod() error = %v", err)
+	}
+	if pod.Annotations[k8s.AnnotationEgressBandwidth] != "5M" || pod.Annotations[k8s.AnnotationIngressBandwidth] != "20M" {
+		t.Fatalf("pod annotations = %v", pod.Annotations)
+	}
+	got, err := svc.Get(ctx, sb.ID)
+	if err != nil || got.Bandwidth == nil || got.Bandwidth.Egress != "5M" {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+
+	_, err = svc.UpdateNetwork(ctx, sb.ID, &model.UpdateSandboxNetworkRequest{Reset: true})
+	if !errors.Is(err, ErrNetworkLimitsImmutable) {
+		t.Fatalf("UpdateNetwork() error = %v, want ErrNetworkLimitsImmutable", err)
+	}
+}
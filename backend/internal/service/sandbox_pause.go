@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Pausing a sandbox without persistence checkpoints its container filesystem to the
+// server and deletes the pod; resuming recreates the pod from the same spec and restores
+// the filesystem before rerunning the template startup script. Running processes are
+// not preserved.
+//
+// The TTL clock is frozen while paused: stopped_at records when the pause started, a
+// paused sandbox never expires, and resuming pushes expires_at back by the paused time.
+
+const (
+	pauseCheckpointTimeout = 30 * time.Minute
+
+	pauseReasonCheckpointing = "checkpoint in progress"
+	pauseReasonPaused        = "paused by request"
+)
+
+// Pause checkpoints a running sandbox without persistence and releases its pod. The
+// checkpoint runs in the background; the sandbox reports paused from the start.
+func (s *SandboxService) Pause(ctx context.Context, id string) error {
+	if s.checkpointDir == "" {
+		return fmt.Errorf("checkpoint directory not configured")
+	}
+	record, err := s.sandboxStore.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.LifecycleStatus == "deleted" || record.DesiredState == store.DesiredStateDeleted {
+		return ErrSandboxNotFound
+	}
+	if record.PersistenceEnabled {
+		return ErrSandboxPauseNotSupported
+	}
+	switch record.LifecycleStatus {
+	case string(model.SandboxStatusRunning):
+	case string(model.SandboxStatusPaused):
+		if _, busy := s.pausing.Load(id); busy {
+			return ErrSandboxPauseInProgress
+		}
+		return ErrSandboxAlreadyPaused
+	default:
+		return ErrSandboxPauseInvalidState
+	}
+	if _, busy := s.pausing.LoadOrStore(id, struct{}{}); busy {
+		return ErrSandboxPauseInProgress
+	}
+
+	now := time.Now().UTC()
+	if err := s.sandboxStore.SetPaused(ctx, id, pauseReasonCheckpointing, now); err != nil {
+		s.pausing.Delete(id)
+		return err
+	}
+	_ = s.sandboxStore.AppendStatusHistory(ctx, id, "api", record.LifecycleStatus, string(model.SandboxStatusPaused), "pause requested", nil, now)
+
+	bgCtx := logx.WithRequestID(context.Background(), logx.RequestIDFromContext(ctx))
+	go s.runPauseCheckpoint(bgCtx, id, record.ExpiresAt, now)
+	return nil
+}
+
+func (s *SandboxService) runPauseCheckpoint(ctx context.Context, id string, expiresAt, pausedAt time.Time) {
+	defer s.pausing.Delete(id)
+	logger := logWithSandboxID(ctx, id)
+
+	checkpointCtx, cancel := context.WithTimeout(ctx, pauseCheckpointTimeout)
+	defer cancel()
+	if err := s.writeCheckpoint(checkpointCtx, id); err != nil {
+		logger.Warn("sandbox pause failed", "error", err)
+		s.removeCheckpoint(id)
+		reason := "pause failed: " + err.Error()
+		now := time.Now().UTC()
+		if err := s.sandboxStore.SetUnpaused(context.Background(), id, string(model.SandboxStatusRunning), reason, expiresAt.Add(now.Sub(pausedAt)), now); err == nil {
+			_ = s.sandboxStore.AppendStatusHistory(context.Background(), id, "system", string(model.SandboxStatusPaused), string(model.SandboxStatusRunning), reason, nil, now)
+		}
+		return
+	}
+
+	updated, _ := s.sandboxStore.UpdateStatusIfActive(context.Background(), id, string(model.SandboxStatusPaused), pauseReasonPaused, time.Now().UTC())
+	if !updated {
+		// Deleted while the checkpoint was being written
+		s.removeCheckpoint(id)
+		return
+	}
+	logger.Info("sandbox paused")
+}
+
+// writeCheckpoint stores the filesystem archive and pod manifest of a sandbox and then
+// deletes its pod. The manifest is written last, so its presence marks a complete
+// checkpoint.
+func (s *SandboxService) writeCheckpoint(ctx context.Context, id string) error {
+	if err := os.MkdirAll(s.checkpointDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	f, err := os.CreateTemp(s.checkpointDir, id+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint archive: %w", err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	manifest, err := s.k8sClient.CheckpointPod(ctx, id, f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write checkpoint archive: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.checkpointArchivePath(id)); err != nil {
+		return fmt.Errorf("failed to store checkpoint archive: %w", err)
+	}
+	if err := os.WriteFile(s.checkpointManifestPath(id), manifest, 0o600); err != nil {
+		return fmt.Errorf("failed to store pod manifest: %w", err)
+	}
+	return s.k8sClient.DeletePodAndWait(ctx, id)
+}
+
+// Resume recreates the pod of a paused sandbox and restores its checkpoint in the
+// background. The sandbox is pending until the restored pod is ready again.
+func (s *SandboxService) Resume(ctx context.Context, id string) error {
+	record, err := s.sandboxStore.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.LifecycleStatus == "deleted" || record.DesiredState == store.DesiredStateDeleted {
+		return ErrSandboxNotFound
+	}
+	if record.LifecycleStatus != string(model.SandboxStatusPaused) {
+		return ErrSandboxNotPaused
+	}
+	if _, busy := s.pausing.Load(id); busy {
+		return ErrSandboxPauseInProgress
+	}
+	release, err := s.reserveRunQuota(ctx, record)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	now := time.Now().UTC()
+	newExpiresAt := record.ExpiresAt
+	if record.StoppedAt != nil {
+		newExpiresAt = record.ExpiresAt.Add(now.Sub(*record.StoppedAt))
+	}
+
+	manifest, err := os.ReadFile(s.checkpointManifestPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		// The server stopped before the checkpoint completed. If the pod was not
+		// deleted yet, the sandbox simply keeps running.
+		if _, podErr := s.k8sClient.GetPod(ctx, id); podErr != nil {
+			return fmt.Errorf("checkpoint of paused sandbox is missing: %w", podErr)
+		}
+		s.removeCheckpoint(id)
+		reason := "resumed before the checkpoint completed"
+		if err := s.sandboxStore.SetUnpaused(ctx, id, string(model.SandboxStatusRunning), reason, newExpiresAt, now); err != nil {
+			return err
+		}
+		_ = s.sandboxStore.AppendStatusHistory(ctx, id, "api", string(model.SandboxStatusPaused), string(model.SandboxStatusRunning), reason, nil, now)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read pod manifest: %w", err)
+	}
+
+	// A pause interrupted after the manifest was written may have left the pod behind
+	if err := s.k8sClient.DeletePodAndWait(ctx, id); err != nil {
+		return err
+	}
+	if _, err := s.k8sClient.RecreatePod(ctx, manifest); err != nil {
+		return fmt.Errorf("failed to recreate sandbox pod: %w", err)
+	}
+	// The manifest carries the labels from when the sandbox was paused, which may
+	// predate a network change.
+	if network, err := s.effectiveNetwork(ctx, record); err != nil {
+		logWithSandboxID(ctx, id).Warn("failed to resolve network for resumed sandbox", "error", err)
+	} else if err := s.applyInternetLabel(ctx, id, network); err != nil {
+		logWithSandboxID(ctx, id).Warn("failed to apply network to resumed sandbox", "error", err)
+	}
+	if err := s.sandboxStore.SetUnpaused(ctx, id, string(model.SandboxStatusPending), "resume requested", newExpiresAt, now); err != nil {
+		return err
+	}
+	_ = s.sandboxStore.AppendStatusHistory(ctx, id, "api", string(model.SandboxStatusPaused), string(model.SandboxStatusPending), "resume requested", nil, now)
+
+	bgCtx := logx.WithRequestID(context.Background(), logx.RequestIDFromContext(ctx))
+	go s.runResumeTasks(bgCtx, id, record.TemplateName, record.TemplateVersion)
+	return nil
+}
+
+// runResumeTasks restores the checkpoint into the recreated pod, reruns the template
+// startup script and waits for readiness, like the tasks run after creation.
+func (s *SandboxService) runResumeTasks(ctx context.Context, id, templateName string, templateVersion int) {
+	logger := logWithSandboxID(ctx, id)
+	fail := func(reason string) {
+		now := time.Now().UTC()
+		updated, _ := s.sandboxStore.UpdateStatusIfActive(context.Background(), id, string(model.SandboxStatusFailed), reason, now)
+		if updated {
+			_ = s.sandboxStore.AppendStatusHistory(context.Background(), id, "system", string(model.SandboxStatusPending), string(model.SandboxStatusFailed), reason, nil, now)
+		}
+	}
+
+	spec := &model.TemplateSpec{}
+	if s.templateSvc != nil {
+		if tplSpec, err := s.templateSvc.GetSpecForSandbox(ctx, templateName, templateVersion); err == nil {
+			spec = tplSpec
+		} else {
+			logger.Warn("resume could not load template, skipping startup script", "error", err)
+		}
+	}
+	startupTimeout := time.Duration(spec.StartupTimeout) * time.Second
+	if startupTimeout <= 0 {
+		startupTimeout = 300 * time.Second
+	}
+
+	restoreCtx, cancel := context.WithTimeout(ctx, startupTimeout+pauseCheckpointTimeout)
+	defer cancel()
+	if err := s.restoreCheckpoint(restoreCtx, id); err != nil {
+		logger.Warn("sandbox resume failed", "error", err)
+		fail("checkpoint restore failed: " + err.Error())
+		return
+	}
+	s.removeCheckpoint(id)
+
+	if spec.StartupScript != "" {
+		execCtx, execCancel := context.WithTimeout(ctx, 60*time.Second)
+		defer execCancel()
+		if _, err := s.k8sClient.Exec(execCtx, id, []string{"sh", "-c", spec.StartupScript}); err != nil {
+			logger.Warn("resume startup script failed", "error", err)
+		}
+	}
+
+	readyCtx, readyCancel := context.WithTimeout(ctx, startupTimeout)
+	defer readyCancel()
+	if err := s.k8sClient.WaitForReady(readyCtx, id, toK8sProbe(spec.ReadinessProbe)); err != nil {
+		logger.Warn("resumed pod not ready", "error", err)
+		fail("startup/readiness failed")
+		return
+	}
+
+	now := time.Now().UTC()
+	updated, _ := s.sandboxStore.UpdateStatusIfActive(context.Background(), id, string(model.SandboxStatusRunning), "", now)
+	if updated {
+		_ = s.sandboxStore.AppendStatusHistory(context.Background(), id, "system", string(model.SandboxStatusPending), string(model.SandboxStatusRunning), "sandbox resumed", nil, now)
+	}
+	logger.Info("sandbox resumed")
+}
+
+func (s *SandboxService) restoreCheckpoint(ctx context.Context, id string) error {
+	if _, err := s.k8sClient.WaitForPodRunning(ctx, id); err != nil {
+		return err
+	}
+	archive, err := os.Open(s.checkpointArchivePath(id))
+	if err != nil {
+		return fmt.Errorf("failed to open checkpoint archive: %w", err)
+	}
+	defer archive.Close()
+
+	skipped, err := s.k8sClient.RestorePodCheckpoint(ctx, id, archive)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return ErrSandboxNotFound
+		}
+		return err
+	}
+	if skipped != "" {
+		logWithSandboxID(ctx, id).Warn("checkpoint restore skipped some files", "detail", skipped)
+	}
+	return nil
+}
+
+// removeCheckpoint deletes the checkpoint files of a sandbox, if any.
+func (s *SandboxService) removeCheckpoint(id string) {
+	if s.checkpointDir == "" {
+		return
+	}
+	_ = os.Remove(s.checkpointArchivePath(id))
+	_ = os.Remove(s.checkpointManifestPath(id))
+}
+
+func (s *SandboxService) checkpointArchivePath(id string) string {
+	return filepath.Join(s.checkpointDir, id+".tar.gz")
+}
+
+func (s *SandboxService) checkpointManifestPath(id string) string {
+	return filepath.Join(s.checkpointDir, id+".pod.json")
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func TestPauseValidation(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	sandboxStore := store.NewSandboxStore()
+	for _, rec := range []*store.SandboxRecord{
+		makeTestSandboxRecord("pause-persistent", true, "running"),
+		makeTestSandboxRecord("pause-pending", false, "pending"),
+		makeTestSandboxRecord("pause-paused", false, "paused"),
+	} {
+		if err := sandboxStore.Create(ctx, rec); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	svc := &SandboxService{sandboxStore: sandboxStore, checkpointDir: t.TempDir()}
+	cases := []struct {
+		id   string
+		want error
+	}{
+		{"missing", ErrSandboxNotFound},
+		{"pause-persistent", ErrSandboxPauseNotSupported},
+		{"pause-pending", ErrSandboxPauseInvalidState},
+		{"pause-paused", ErrSandboxAlreadyPaused},
+	}
+	for _, tc := range cases {
+		if err := svc.Pause(ctx, tc.id); !errors.Is(err, tc.want) {
+			t.Errorf("Pause(%s) error = %v, want %v", tc.id, err, tc.want)
+		}
+	}
+	if err := svc.Resume(ctx, "pause-pending"); !errors.Is(err, ErrSandboxNotPaused) {
+		t.Errorf("Resume(pending) error = %v, want ErrSandboxNotPaused", err)
+	}
+}
+
+func TestPauseFailureResumesWithTTLRestored(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	sandboxStore := store.NewSandboxStore()
+
+	rec := makeTestSandboxRecord("pause-fail", false, "running")
+	originalExpires := rec.ExpiresAt
+	if err := sandboxStore.Create(ctx, rec); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Without a pod the checkpoint fails and the sandbox goes back to running
+	svc := &SandboxService{sandboxStore: sandboxStore, k8sClient: k8s.NewClientForTest(), checkpointDir: t.TempDir()}
+	if err := svc.Pause(ctx, "pause-fail"); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+
+	var got *store.SandboxRecord
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var err error
+		got, err = sandboxStore.GetByID(ctx, "pause-fail")
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.LifecycleStatus != "paused" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got.LifecycleStatus != "running" || !strings.HasPrefix(got.StatusReason, "pause failed:") {
+		t.Fatalf("status = %q (%q), want running after a failed pause", got.LifecycleStatus, got.StatusReason)
+	}
+	if got.StoppedAt != nil {
+		t.Fatalf("StoppedAt = %v, want nil", got.StoppedAt)
+	}
+	if got.ExpiresAt.Before(originalExpires) || got.ExpiresAt.Sub(originalExpires) > time.Minute {
+		t.Fatalf("ExpiresAt = %v, want about %v", got.ExpiresAt, originalExpires)
+	}
+}
+
+func TestResumeExtendsTTLByPausedTime(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	sandboxStore := store.NewSandboxStore()
+
+	now := time.Now().UTC()
+	pausedAt := now.Add(-30 * time.Minute)
+	rec := makeTestSandboxRecord("resume-ok", false, "paused")
+	rec.StoppedAt = &pausedAt
+	rec.ExpiresAt = now.Add(10 * time.Minute)
+	if err := sandboxStore.Create(ctx, rec); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The checkpoint never completed and the pod is still there: resume in place
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sandbox-resume-ok", Namespace: k8s.DefaultSandboxNamespace}}
+	svc := &SandboxService{sandboxStore: sandboxStore, k8sClient: k8s.NewClientForTest(pod), checkpointDir: t.TempDir()}
+	if err := svc.Resume(ctx, "resume-ok"); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+
+	got, err := sandboxStore.GetByID(ctx, "resume-ok")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.LifecycleStatus != "running" || got.StoppedAt != nil {
+		t.Fatalf("status = %q, stopped_at = %v; want running with the TTL clock restarted", got.LifecycleStatus, got.StoppedAt)
+	}
+	extension := got.ExpiresAt.Sub(rec.ExpiresAt)
+	if extension < 29*time.Minute || extension > 31*time.Minute {
+		t.Fatalf("ExpiresAt extension = %v, want ~30m", extension)
+	}
+}
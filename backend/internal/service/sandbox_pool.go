@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// A warm pool keeps poolSize sandboxes of the latest version of a template created and
+// ready, so Create can hand one out instead of waiting for image pull, startup script
+// and readiness probe. Pool members are plain sandbox pods labelled with the template;
+// they have no sandbox record until claimed. Claiming drops the label, rotates the
+// access token and creates the record under the member's ID.
+//
+// Pools are kept full by the reconcile loop and refilled right after every claim.
+
+const (
+	// poolWarmGrace is added to the startup timeout before a member stuck warming is replaced.
+	poolWarmGrace = 2 * time.Minute
+	// poolClaimTimeout is how long a claim may take before its member counts as abandoned.
+	poolClaimTimeout = time.Minute
+)
+
+type SandboxPoolService struct {
+	k8sClient    *k8s.Client
+	sandboxStore *store.SandboxStore
+	poolStore    *store.SandboxPoolStore
+	templateSvc  *TemplateService
+
+	mu            sync.Mutex // serializes reconciles
+	refillPending atomic.Bool
+
+	statsMu sync.Mutex
+	stats   map[string]*poolStats
+}
+
+type poolStats struct {
+	hits   int64
+	misses int64
+}
+
+// poolTemplate is the desired pool of one template.
+type poolTemplate struct {
+	name    string
+	version int
+	spec    *model.TemplateSpec
+}
+
+func NewSandboxPoolService(k8sClient *k8s.Client, sandboxStore *store.SandboxStore, poolStore *store.SandboxPoolStore, templateSvc *TemplateService) *SandboxPoolService {
+	return &SandboxPoolService{
+		k8sClient:    k8sClient,
+		sandboxStore: sandboxStore,
+		poolStore:    poolStore,
+		templateSvc:  templateSvc,
+		stats:        make(map[string]*poolStats),
+	}
+}
+
+// Claim takes a ready member out of the pool of a template version and returns its ID,
+// or "" when the pool is empty. Either way a refill is queued. The caller finishes the
+// claim with CompleteClaim once the sandbox record exists, or gives the member up with
+// AbortClaim.
+func (s *SandboxPoolService) Claim(ctx context.Context, templateName string, templateVersion int) string {
+	defer s.requestRefill()
+
+	member, err := s.poolStore.Claim(ctx, templateName, templateVersion, time.Now().UTC())
+	if err != nil {
+		logx.LoggerWithRequestID(ctx).Warn("failed to claim from warm pool", "component", "sandbox_pool", "template", templateName, "error", err)
+	}
+	s.count(templateName, member != nil)
+	if member == nil {
+		return ""
+	}
+	return member.ID
+}
+
+// CompleteClaim removes a claimed member from the pool after it became a sandbox.
+func (s *SandboxPoolService) CompleteClaim(ctx context.Context, id string) {
+	if _, err := s.poolStore.Delete(ctx, id); err != nil {
+		logWithSandboxID(ctx, id).Warn("failed to complete warm pool claim", "error", err)
+	}
+}
+
+// AbortClaim gives up a claimed member that could not become a sandbox and deletes its pod.
+func (s *SandboxPoolService) AbortClaim(ctx context.Context, id, reason string) {
+	s.removeMember(ctx, id, reason)
+}
+
+func (s *SandboxPoolService) count(templateName string, hit bool) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	st := s.stats[templateName]
+	if st == nil {
+		st = &poolStats{}
+		s.stats[templateName] = st
+	}
+	if hit {
+		st.hits++
+	} else {
+		st.misses++
+	}
+}
+
+// requestRefill queues a reconcile in the background. Requests made while one is queued
+// are coalesced; a request made while one is running queues another.
+func (s *SandboxPoolService) requestRefill() {
+	if s.refillPending.Swap(true) {
+		return
+	}
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.refillPending.Store(false)
+		if err := s.reconcileLocked(context.Background()); err != nil {
+			slog.Default().With("component", "sandbox_pool").Warn("warm pool refill failed", "error", err)
+		}
+	}()
+}
+
+// Reconcile replaces pool members that failed, went missing or belong to an outdated
+// template version, removes pools that are no longer configured and creates members
+// until every pool is full again.
+func (s *SandboxPoolService) Reconcile(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reconcileLocked(ctx)
+}
+
+func (s *SandboxPoolService) reconcileLocked(ctx context.Context) error {
+	logger := slog.Default().With("component", "sandbox_pool")
+
+	pools, err := s.desiredPools(ctx)
+	if err != nil {
+		return err
+	}
+	members, err := s.poolStore.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	pods, err := s.k8sClient.ListPooledPods(ctx)
+	if err != nil {
+		return err
+	}
+	podByID := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podByID[pods[i].Labels[k8s.LabelSandboxID]] = &pods[i]
+	}
+
+	active := make(map[string]int, len(pools))
+	for _, m := range members {
+		pod := podByID[m.ID]
+		delete(podByID, m.ID)
+
+		pool, ok := pools[m.TemplateName]
+		reason := ""
+		switch {
+		case !ok:
+			reason = "pool removed"
+		case m.TemplateVersion != pool.version:
+			reason = "template updated"
+		case m.Status == store.PoolMemberClaimed:
+			// Claims normally complete within a request; a stale one was interrupted.
+			if time.Since(m.UpdatedAt) < poolClaimTimeout {
+				continue
+			}
+			rec, err := s.sandboxStore.GetByID(ctx, m.ID)
+			if err != nil {
+				continue
+			}
+			if rec != nil {
+				s.CompleteClaim(ctx, m.ID)
+				continue
+			}
+			reason = "claim abandoned"
+		case m.Status == store.PoolMemberFailed:
+			reason = "warming failed"
+		case m.Status == store.PoolMemberReady && (pod == nil || pod.Status.Phase != corev1.PodRunning):
+			reason = "pod is gone"
+		case m.Status == store.PoolMemberWarming && time.Since(m.CreatedAt) > poolWarmTimeout(pool.spec)+poolWarmGrace:
+			reason = "warming timed out"
+		}
+		if reason == "" {
+			active[m.TemplateName]++
+			continue
+		}
+		s.removeMember(ctx, m.ID, reason)
+	}
+
+	// Pooled pods without a member row belong to a sandbox whose label could not be
+	// dropped, or were left behind when their member was removed.
+	for id := range podByID {
+		if rec, err := s.sandboxStore.GetByID(ctx, id); err != nil || rec != nil {
+			continue
+		}
+		if err := s.k8sClient.DeletePod(ctx, id); err != nil && !apierrors.IsNotFound(err) {
+			logger.Warn("failed to delete orphaned pool pod", "sandbox_id", id, "error", err)
+		}
+	}
+
+	for _, pool := range pools {
+		for n := active[pool.name]; n < pool.spec.PoolSize; n++ {
+			if err := s.warm(ctx, pool); err != nil {
+				logger.Warn("failed to create pool member", "template", pool.name, "error", err)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// desiredPools returns the templates whose latest version asks for a warm pool.
+func (s *SandboxPoolService) desiredPools(ctx context.Context) (map[string]poolTemplate, error) {
+	pools := make(map[string]poolTemplate)
+	for page := 1; ; page++ {
+		resp, err := s.templateSvc.List(ctx, model.TemplateListOptions{Page: page, PageSize: 100})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp.Items {
+			if t.Spec == nil || t.Spec.PoolSize <= 0 || (t.Spec.Persistence != nil && t.Spec.Persistence.Enabled) {
+				continue
+			}
+			pools[t.Name] = poolTemplate{name: t.Name, version: t.LatestVersion, spec: t.Spec}
+		}
+		if page*resp.PageSize >= resp.Total || len(resp.Items) == 0 {
+			return pools, nil
+		}
+	}
+}
+
+func (s *SandboxPoolService) removeMember(ctx context.Context, id, reason string) {
+	removed, err := s.poolStore.Delete(ctx, id)
+	if err != nil || !removed {
+		// Claimed meanwhile
+		return
+	}
+	if err := s.k8sClient.DeletePod(ctx, id); err != nil && !apierrors.IsNotFound(err) {
+		slog.Default().With("component", "sandbox_pool").Warn("failed to delete pool pod", "sandbox_id", id, "error", err)
+	}
+	slog.Default().With("component", "sandbox_pool").Info("pool member removed", "sandbox_id", id, "reason", reason)
+}
+
+// warm creates a pool member and prepares it in the background like a new sandbox.
+func (s *SandboxPoolService) warm(ctx context.Context, pool poolTemplate) error {
+	spec := pool.spec
+	id := generateID()
+	now := time.Now().UTC()
+	if err := s.poolStore.Create(ctx, &store.SandboxPoolMemberRecord{
+		ID:              id,
+		TemplateName:    pool.name,
+		TemplateVersion: pool.version,
+		Status:          store.PoolMemberWarming,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}); err != nil {
+		return err
+	}
+
+	files := toK8sFiles(spec.Files)
+	probe := toK8sProbe(spec.ReadinessProbe)
+	_, err := s.k8sClient.CreatePod(ctx, k8s.CreatePodOptions{
+		ID:      id,
+		Image:   spec.Image,
+		Command: spec.Command,
+		Args:    spec.Args,
+		CPU:     spec.Resources.CPU,
+		Memory:  spec.Resources.Memory,
+		TTL:     spec.TTL,
+		Env:     spec.Env,
+		Annotations: map[string]string{
+			"liteboxd.io/template":         pool.name,
+			"liteboxd.io/template-version": strconv.Itoa(pool.version),
+		},
+		Labels:         map[string]string{k8s.LabelPool: pool.name},
+		StartupScript:  spec.StartupScript,
+		StartupFiles:   files,
+		ReadinessProbe: probe,
+		Network:        toK8sNetwork(spec.Network),
+	})
+	if err != nil {
+		_, _ = s.poolStore.Delete(ctx, id)
+		return fmt.Errorf("failed to create pod: %w", err)
+	}
+
+	go s.runWarmTasks(context.Background(), id, spec, files, probe)
+	return nil
+}
+
+// runWarmTasks runs the post-creation tasks of a sandbox for a pool member: startup
+// script, readiness and file upload, after which the member can be claimed.
+func (s *SandboxPoolService) runWarmTasks(ctx context.Context, id string, spec *model.TemplateSpec, files []k8s.FileSpec, probe *k8s.ProbeSpec) {
+	logger := slog.Default().With("component", "sandbox_pool", "sandbox_id", id)
+	fail := func(reason string) {
+		_, _ = s.poolStore.UpdateStatus(context.Background(), id, store.PoolMemberFailed, reason, time.Now().UTC())
+	}
+
+	if spec.StartupScript != "" {
+		execCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
+		if _, err := s.k8sClient.Exec(execCtx, id, []string{"sh", "-c", spec.StartupScript}); err != nil {
+			logger.Warn("pool member startup script failed", "error", err)
+		}
+	}
+
+	readyCtx, cancel := context.WithTimeout(ctx, poolWarmTimeout(spec))
+	defer cancel()
+	if err := s.k8sClient.WaitForReady(readyCtx, id, probe); err != nil {
+		logger.Warn("pool member not ready", "error", err)
+		fail("startup/readiness failed")
+		return
+	}
+	if len(files) > 0 {
+		if err := s.k8sClient.UploadFiles(ctx, id, files); err != nil {
+			logger.Warn("pool member file upload failed", "error", err)
+			fail("file upload failed")
+			return
+		}
+	}
+
+	if updated, _ := s.poolStore.UpdateStatus(context.Background(), id, store.PoolMemberReady, "", time.Now().UTC()); updated {
+		logger.Info("pool member ready")
+	}
+}
+
+func poolWarmTimeout(spec *model.TemplateSpec) time.Duration {
+	if spec.StartupTimeout > 0 {
+		return time.Duration(spec.StartupTimeout) * time.Second
+	}
+	return 300 * time.Second
+}
+
+// List reports every configured pool.
+func (s *SandboxPoolService) List(ctx context.Context) (*model.SandboxPoolListResponse, error) {
+	pools, err := s.desiredPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.poolStore.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]*model.SandboxPoolStatus, len(pools))
+	for _, pool := range pools {
+		statuses[pool.name] = &model.SandboxPoolStatus{
+			Template:        pool.name,
+			TemplateVersion: pool.version,
+			Size:            pool.spec.PoolSize,
+		}
+	}
+	for _, m := range members {
+		st := statuses[m.TemplateName]
+		if st == nil || m.TemplateVersion != st.TemplateVersion {
+			continue
+		}
+		switch m.Status {
+		case store.PoolMemberReady:
+			st.Ready++
+		case store.PoolMemberWarming:
+			st.Warming++
+		case store.PoolMemberFailed:
+			st.Failed++
+		}
+	}
+
+	s.statsMu.Lock()
+	for name, st := range statuses {
+		if counts := s.stats[name]; counts != nil {
+			st.Hits = counts.hits
+			st.Misses = counts.misses
+		}
+	}
+	s.statsMu.Unlock()
+
+	items := make([]model.SandboxPoolStatus, 0, len(statuses))
+	for _, st := range statuses {
+		items = append(items, *st)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Template < items[j].Template })
+	return &model.SandboxPoolListResponse{Items: items}, nil
+}
+
+// Get reports the pool of one template.
+func (s *SandboxPoolService) Get(ctx context.Context, templateName string) (*model.SandboxPoolStatus, error) {
+	resp, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Items {
+		if resp.Items[i].Template == templateName {
+			return &resp.Items[i], nil
+		}
+	}
+	return nil, ErrPoolNotFound
+}
+
+// execCommand returns command as it should run in a sandbox. Sandboxes claimed from a
+// warm pool run on a pod started with the template env only, so their own env, which
+// includes the overrides of the create request, is passed to every command.
+func (s *SandboxService) execCommand(ctx context.Context, id string, command []string) []string {
+	record, err := s.sandboxStore.GetByID(ctx, id)
+	if err != nil || record == nil || !record.FromPool {
+		return command
+	}
+	return k8s.CommandWithEnv(command, record.EnvMap())
+}
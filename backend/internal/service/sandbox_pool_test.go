@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func makePooledPod(id, templateName string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sandbox-" + id,
+			Namespace: k8s.DefaultSandboxNamespace,
+			UID:       types.UID("uid-" + id),
+			Labels:    map[string]string{"app": k8s.LabelApp, k8s.LabelSandboxID: id, k8s.LabelPool: templateName},
+		},
+		Status: corev1.PodStatus{Phase: phase, PodIP: "10.0.0.9"},
+	}
+}
+
+func addPoolMember(t *testing.T, poolStore *store.SandboxPoolStore, id string, version int, status string) {
+	t.Helper()
+	now := time.Now().UTC()
+	if err := poolStore.Create(context.Background(), &store.SandboxPoolMemberRecord{
+		ID:              id,
+		TemplateName:    "warm",
+		TemplateVersion: version,
+		Status:          store.PoolMemberWarming,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}); err != nil {
+		t.Fatalf("Create pool member error = %v", err)
+	}
+	if status != store.PoolMemberWarming {
+		if _, err := poolStore.UpdateStatus(context.Background(), id, status, "", now); err != nil {
+			t.Fatalf("UpdateStatus pool member error = %v", err)
+		}
+	}
+}
+
+func createPoolTemplate(t *testing.T, templateSvc *TemplateService, poolSize int) {
+	t.Helper()
+	if _, err := templateSvc.store.Create(context.Background(), &model.CreateTemplateRequest{
+		Name: "warm",
+		Spec: model.TemplateSpec{
+			Image:          "busybox:1.36",
+			Command:        []string{"sh", "-c", "sleep 3600"},
+			StartupTimeout: 1,
+			Env:            map[string]string{"BASE": "1"},
+			PoolSize:       poolSize,
+		},
+	}); err != nil {
+		t.Fatalf("Create template error = %v", err)
+	}
+}
+
+func TestCreateClaimsSandboxFromWarmPool(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	templateSvc := NewTemplateService()
+	createPoolTemplate(t, templateSvc, 1)
+
+	poolStore := store.NewSandboxPoolStore()
+	addPoolMember(t, poolStore, "warm1", 1, store.PoolMemberReady)
+	k8sClient := k8s.NewClientForTest(makePooledPod("warm1", "warm", corev1.PodRunning))
+	sandboxStore := store.NewSandboxStore()
+	svc := NewSandboxService(k8sClient, sandboxStore, cipher)
+	svc.SetTemplateService(templateSvc)
+	svc.SetPoolService(NewSandboxPoolService(k8sClient, sandboxStore, poolStore, templateSvc))
+
+	sb, err := svc.Create(ctx, &model.CreateSandboxRequest{
+		Template:  "warm",
+		Overrides: &model.SandboxOverrides{Env: map[string]string{"EXTRA": "2"}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sb.ID != "warm1" || !sb.FromPool || sb.Status != model.SandboxStatusRunning || sb.AccessToken == "" {
+		t.Fatalf("Create() = id %s fromPool %v status %s; want the running pool member", sb.ID, sb.FromPool, sb.Status)
+	}
+
+	pod, err := k8sClient.GetPod(ctx, "warm1")
+	if err != nil {
+		t.Fatalf("GetPod() error = %v", err)
+	}
+	if _, pooled := pod.Labels[k8s.LabelPool]; pooled {
+		t.Fatalf("claimed pod still carries the pool label")
+	}
+	if _, ok := pod.Annotations["liteboxd.io/access-token"]; ok {
+		t.Fatalf("claimed pod carries the access token: %v", pod.Annotations)
+	}
+	members, err := poolStore.List(ctx, "warm")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for _, m := range members {
+		if m.ID == "warm1" {
+			t.Fatalf("claimed member still in pool: %+v", m)
+		}
+	}
+
+	_, err = svc.Create(ctx, &model.CreateSandboxRequest{
+		Template:  "warm",
+		Overrides: &model.SandboxOverrides{Env: map[string]string{"-i": "1"}},
+	})
+	if !errors.Is(err, ErrInvalidEnvName) {
+		t.Fatalf("Create() with an invalid env name error = %v, want ErrInvalidEnvName", err)
+	}
+
+	// The request env reaches commands even though the pod was started without it
+	got := svc.execCommand(ctx, "warm1", []string{"printenv"})
+	want := []string{"env", "BASE=1", "EXTRA=2", "printenv"}
+	if len(got) != len(want) {
+		t.Fatalf("execCommand() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("execCommand() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCreateSkipsWarmPoolForIncompatibleOverrides(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	templateSvc := NewTemplateService()
+	createPoolTemplate(t, templateSvc, 1)
+
+	poolStore := store.NewSandboxPoolStore()
+	addPoolMember(t, poolStore, "warm1", 1, store.PoolMemberReady)
+	k8sClient := k8s.NewClientForTest(makePooledPod("warm1", "warm", corev1.PodRunning))
+	sandboxStore := store.NewSandboxStore()
+	svc := NewSandboxService(k8sClient, sandboxStore, cipher)
+	svc.SetTemplateService(templateSvc)
+	svc.SetPoolService(NewSandboxPoolService(k8sClient, sandboxStore, poolStore, templateSvc))
+
+	sb, err := svc.Create(ctx, &model.CreateSandboxRequest{
+		Template:  "warm",
+		Overrides: &model.SandboxOverrides{CPU: "2"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sb.ID == "warm1" || sb.FromPool {
+		t.Fatalf("sandbox with a CPU override was served from the pool")
+	}
+	members, err := poolStore.List(ctx, "warm")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(members) != 1 || members[0].Status != store.PoolMemberReady {
+		t.Fatalf("pool members = %+v, want warm1 still ready", members)
+	}
+}
+
+func TestPoolReconcileReplacesStaleMembers(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	templateSvc := NewTemplateService()
+	createPoolTemplate(t, templateSvc, 2)
+	if _, err := templateSvc.store.Update(ctx, "warm", &model.UpdateTemplateRequest{
+		Spec: model.TemplateSpec{Image: "busybox:1.37", StartupTimeout: 1, PoolSize: 2},
+	}); err != nil {
+		t.Fatalf("Update template error = %v", err)
+	}
+
+	poolStore := store.NewSandboxPoolStore()
+	addPoolMember(t, poolStore, "old", 1, store.PoolMemberReady)
+	addPoolMember(t, poolStore, "bad", 2, store.PoolMemberFailed)
+	addPoolMember(t, poolStore, "good", 2, store.PoolMemberReady)
+	k8sClient := k8s.NewClientForTest(
+		makePooledPod("old", "warm", corev1.PodRunning),
+		makePooledPod("bad", "warm", corev1.PodRunning),
+		makePooledPod("good", "warm", corev1.PodRunning),
+		makePooledPod("orphan", "warm", corev1.PodRunning),
+	)
+	poolSvc := NewSandboxPoolService(k8sClient, store.NewSandboxStore(), poolStore, templateSvc)
+
+	if err := poolSvc.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	for _, id := range []string{"old", "bad", "orphan"} {
+		if _, err := k8sClient.GetPod(ctx, id); err == nil {
+			t.Fatalf("pod %s was not removed", id)
+		}
+	}
+	members, err := poolStore.List(ctx, "warm")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(members) != 2 || members[0].ID != "good" {
+		t.Fatalf("pool members = %+v, want good plus one new member", members)
+	}
+	if members[1].TemplateVersion != 2 {
+		t.Fatalf("new member version = %d, want 2", members[1].TemplateVersion)
+	}
+	pod, err := k8sClient.GetPod(ctx, members[1].ID)
+	if err != nil {
+		t.Fatalf("new member has no pod: %v", err)
+	}
+	if pod.Labels[k8s.LabelPool] != "warm" || pod.Spec.Containers[0].Image != "busybox:1.37" {
+		t.Fatalf("new member pod = %v %s", pod.Labels, pod.Spec.Containers[0].Image)
+	}
+}
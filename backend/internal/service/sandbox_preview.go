@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPreviewLinkTTL = time.Hour
+	maxPreviewLinkTTL     = 7 * 24 * time.Hour
+)
+
+// SandboxPreviewService mints preview links, which give access to one port of a
+// sandbox through the gateway without its access token. The gateway checks the
+// links against the token hashes kept in the store.
+type SandboxPreviewService struct {
+	sandboxStore *store.SandboxStore
+	linkStore    *store.SandboxPreviewLinkStore
+}
+
+func NewSandboxPreviewService(sandboxStore *store.SandboxStore, linkStore *store.SandboxPreviewLinkStore) *SandboxPreviewService {
+	return &SandboxPreviewService{
+		sandboxStore: sandboxStore,
+		linkStore:    linkStore,
+	}
+}
+
+// Create mints a preview link. The token is returned only here.
+func (s *SandboxPreviewService) Create(ctx context.Context, sandboxID string, req *model.CreatePreviewLinkRequest) (*model.PreviewLink, error) {
+	if err := s.checkSandbox(ctx, sandboxID); err != nil {
+		return nil, err
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		return nil, fmt.Errorf("%w: port must be between 1 and 65535", ErrInvalidPreviewLink)
+	}
+	prefix, err := normalizePathPrefix(req.PathPrefix)
+	if err != nil {
+		return nil, err
+	}
+	ttl := defaultPreviewLinkTTL
+	if req.TTL != 0 {
+		ttl = time.Duration(req.TTL) * time.Second
+		if req.TTL < 0 || ttl > maxPreviewLinkTTL {
+			return nil, fmt.Errorf("%w: ttl must be between 1 and %d seconds", ErrInvalidPreviewLink, int(maxPreviewLinkTTL.Seconds()))
+		}
+	}
+
+	token, err := security.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	record := &store.SandboxPreviewLinkRecord{
+		ID:          "prv-" + uuid.New().String()[:8],
+		SandboxID:   sandboxID,
+		TokenSHA256: security.HashToken(token),
+		Port:        req.Port,
+		PathPrefix:  prefix,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}
+	if err := s.linkStore.Create(ctx, record); err != nil {
+		return nil, err
+	}
+	// Expired links can no longer be used, so they are dropped as new ones are minted.
+	if _, err := s.linkStore.DeleteExpired(ctx, now); err != nil {
+		logWithSandboxID(ctx, sandboxID).Warn("failed to delete expired preview links", "error", err)
+	}
+
+	link := previewLinkRecordToModel(record)
+	link.Token = token
+	link.URL = previewLinkURL(record, token)
+	logWithSandboxID(ctx, sandboxID).Info("preview link created", "link_id", record.ID, "port", record.Port)
+	return link, nil
+}
+
+// List returns the unexpired preview links of a sandbox, newest first.
+func (s *SandboxPreviewService) List(ctx context.Context, sandboxID string) (*model.PreviewLinkListResponse, error) {
+	if err := s.checkSandbox(ctx, sandboxID); err != nil {
+		return nil, err
+	}
+	records, err := s.linkStore.List(ctx, sandboxID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]model.PreviewLink, 0, len(records))
+	for i := range records {
+		items = append(items, *previewLinkRecordToModel(&records[i]))
+	}
+	return &model.PreviewLinkListResponse{Items: items}, nil
+}
+
+// Revoke deletes a preview link. The gateway rejects its token from then on.
+func (s *SandboxPreviewService) Revoke(ctx context.Context, sandboxID, linkID string) error {
+	if err := s.checkSandbox(ctx, sandboxID); err != nil {
+		return err
+	}
+	deleted, err := s.linkStore.Delete(ctx, sandboxID, linkID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPreviewLinkNotFound
+	}
+	logWithSandboxID(ctx, sandboxID).Info("preview link revoked", "link_id", linkID)
+	return nil
+}
+
+func (s *SandboxPreviewService) checkSandbox(ctx context.Context, sandboxID string) error {
+	sandbox, err := s.sandboxStore.GetByID(ctx, sandboxID)
+	if err != nil {
+		return err
+	}
+	if sandbox == nil || sandbox.DesiredState == store.DesiredStateDeleted || sandbox.LifecycleStatus == "deleted" {
+		return ErrSandboxNotFound
+	}
+	return nil
+}
+
+// normalizePathPrefix cleans a preview link path prefix. The root prefix is returned
+// as "", meaning the whole port.
+func normalizePathPrefix(prefix string) (string, error) {
+	if prefix == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "?#") {
+		return "", fmt.Errorf("%w: path_prefix must be an absolute path", ErrInvalidPreviewLink)
+	}
+	prefix = path.Clean(prefix)
+	if prefix == "/" {
+		return "", nil
+	}
+	return prefix, nil
+}
+
+// previewLinkURL returns the gateway URL a preview link opens, with its token.
+func previewLinkURL(record *store.SandboxPreviewLinkRecord, token string) string {
+	port := strconv.Itoa(record.Port)
+	base := sandboxAccessURL(record.SandboxID)
+	if strings.Contains(base, "{port}") {
+		base = strings.Replace(base, "{port}", port, 1)
+	} else {
+		base += "/port/" + port
+	}
+	p := record.PathPrefix
+	if p == "" {
+		p = "/"
+	}
+	return base + p + "?" + url.Values{model.PreviewTokenParam: {token}}.Encode()
+}
+
+func previewLinkRecordToModel(record *store.SandboxPreviewLinkRecord) *model.PreviewLink {
+	return &model.PreviewLink{
+		ID:         record.ID,
+		SandboxID:  record.SandboxID,
+		Port:       record.Port,
+		PathPrefix: record.PathPrefix,
+		ExpiresAt:  record.ExpiresAt,
+		CreatedAt:  record.CreatedAt,
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func TestPreviewLinkCreateListRevoke(t *testing.T) {
+	initServiceTestDB(t)
+	t.Setenv("GATEWAY_URL", "https://gw.example.com")
+	t.Setenv("GATEWAY_BASE_DOMAIN", "")
+	ctx := context.Background()
+
+	sandboxStore := store.NewSandboxStore()
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("prev1", false, "running")); err != nil {
+		t.Fatalf("Create sandbox error = %v", err)
+	}
+	linkStore := store.NewSandboxPreviewLinkStore()
+	svc := NewSandboxPreviewService(sandboxStore, linkStore)
+
+	link, err := svc.Create(ctx, "prev1", &model.CreatePreviewLinkRequest{Port: 3000, PathPrefix: "/docs/", TTL: 600})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if link.Token == "" || link.PathPrefix != "/docs" {
+		t.Fatalf("Create() = %+v", link)
+	}
+	wantURL := "https://gw.example.com/api/v1/sandbox/prev1/port/3000/docs?liteboxd_preview_token=" + link.Token
+	if link.URL != wantURL {
+		t.Fatalf("URL = %q, want %q", link.URL, wantURL)
+	}
+	rec, err := linkStore.GetByTokenHash(ctx, security.HashToken(link.Token))
+	if err != nil || rec == nil || rec.ID != link.ID {
+		t.Fatalf("stored link = %+v, %v", rec, err)
+	}
+	if d := rec.ExpiresAt.Sub(rec.CreatedAt); d.Seconds() != 600 {
+		t.Fatalf("link lifetime = %v, want 600s", d)
+	}
+
+	t.Setenv("GATEWAY_BASE_DOMAIN", "sandbox.example.com")
+	hostLink, err := svc.Create(ctx, "prev1", &model.CreatePreviewLinkRequest{Port: 8080})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(hostLink.URL, "https://8080-prev1.sandbox.example.com/?liteboxd_preview_token=") {
+		t.Fatalf("host URL = %q", hostLink.URL)
+	}
+
+	list, err := svc.List(ctx, "prev1")
+	if err != nil || len(list.Items) != 2 || list.Items[0].Token != "" || list.Items[0].URL != "" {
+		t.Fatalf("List() = %+v, %v; want two links without tokens", list, err)
+	}
+
+	if err := svc.Revoke(ctx, "prev1", link.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := svc.Revoke(ctx, "prev1", link.ID); !errors.Is(err, ErrPreviewLinkNotFound) {
+		t.Fatalf("Revoke() again error = %v, want ErrPreviewLinkNotFound", err)
+	}
+	if rec, _ := linkStore.GetByTokenHash(ctx, security.HashToken(link.Token)); rec != nil {
+		t.Fatalf("revoked link still stored: %+v", rec)
+	}
+}
+
+func TestPreviewLinkCreateValidation(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	sandboxStore := store.NewSandboxStore()
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("prev2", false, "running")); err != nil {
+		t.Fatalf("Create sandbox error = %v", err)
+	}
+	svc := NewSandboxPreviewService(sandboxStore, store.NewSandboxPreviewLinkStore())
+
+	for _, req := range []model.CreatePreviewLinkRequest{
+		{Port: 0},
+		{Port: 70000},
+		{Port: 80, PathPrefix: "docs"},
+		{Port: 80, PathPrefix: "/docs?x=1"},
+		{Port: 80, TTL: -1},
+		{Port: 80, TTL: 8 * 24 * 3600},
+	} {
+		if _, err := svc.Create(ctx, "prev2", &req); !errors.Is(err, ErrInvalidPreviewLink) {
+			t.Fatalf("Create(%+v) error = %v, want ErrInvalidPreviewLink", req, err)
+		}
+	}
+	if _, err := svc.Create(ctx, "missing", &model.CreatePreviewLinkRequest{Port: 80}); !errors.Is(err, ErrSandboxNotFound) {
+		t.Fatalf("Create() for missing sandbox error = %v", err)
+	}
+}
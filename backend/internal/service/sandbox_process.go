@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/google/uuid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const processExecTimeout = 30 * time.Second
+
+// allowedProcessSignals is the set of signals that may be sent to background processes.
+var allowedProcessSignals = map[string]struct{}{
+	"HUP": {}, "INT": {}, "QUIT": {}, "KILL": {}, "TERM": {},
+	"USR1": {}, "USR2": {}, "STOP": {}, "CONT": {},
+}
+
+// envNamePattern matches names env(1) accepts as assignments; anything else, such as a
+// name containing "=" or starting with "-", would change how the command is parsed.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnvNames rejects variable names that cannot be passed through env(1).
+func validateEnvNames(env map[string]string) error {
+	for name := range env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: %q", ErrInvalidEnvName, name)
+		}
+	}
+	return nil
+}
+
+// SandboxProcessService manages detached background processes inside sandboxes.
+// Process metadata lives in the store; liveness and exit codes are observed lazily
+// from the sandbox whenever processes are read.
+type SandboxProcessService struct {
+	k8sClient    *k8s.Client
+	sandboxStore *store.SandboxStore
+	processStore *store.SandboxProcessStore
+}
+
+func NewSandboxProcessService(k8sClient *k8s.Client, sandboxStore *store.SandboxStore, processStore *store.SandboxProcessStore) *SandboxProcessService {
+	return &SandboxProcessService{
+		k8sClient:    k8sClient,
+		sandboxStore: sandboxStore,
+		processStore: processStore,
+	}
+}
+
+// Start launches a command detached from the request and records it.
+func (s *SandboxProcessService) Start(ctx context.Context, sandboxID string, req *model.StartProcessRequest) (*model.SandboxProcess, error) {
+	if err := validateEnvNames(req.Env); err != nil {
+		return nil, err
+	}
+	record, err := s.sandboxStore.GetByID(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.LifecycleStatus == "deleted" {
+		return nil, ErrSandboxNotFound
+	}
+	if record.LifecycleStatus != string(model.SandboxStatusRunning) {
+		return nil, ErrSandboxNotRunning
+	}
+
+	env := req.Env
+	if record.FromPool {
+		// The pod was started from a warm pool with the template env only
+		env = record.EnvMap()
+		for k, v := range req.Env {
+			env[k] = v
+		}
+	}
+
+	id := "proc-" + uuid.New().String()[:8]
+	execCtx, cancel := context.WithTimeout(ctx, processExecTimeout)
+	defer cancel()
+	handle, err := s.k8sClient.StartProcess(execCtx, sandboxID, k8s.StartProcessOptions{
+		ID:      id,
+		Command: req.Command,
+		WorkDir: req.WorkDir,
+		Env:     env,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start process: %w", err)
+	}
+
+	commandJSON, _ := json.Marshal(req.Command)
+	now := time.Now().UTC()
+	proc := &store.SandboxProcessRecord{
+		ID:          id,
+		SandboxID:   sandboxID,
+		CommandJSON: string(commandJSON),
+		WorkDir:     req.WorkDir,
+		PID:         handle.PID,
+		PodUID:      handle.PodUID,
+		Status:      string(model.ProcessStatusRunning),
+		StartedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.processStore.Create(ctx, proc); err != nil {
+		return nil, err
+	}
+	logWithSandboxID(ctx, sandboxID).Info("background process started", "process_id", id, "pid", handle.PID)
+	return processRecordToModel(proc), nil
+}
+
+// List returns all processes of a sandbox with refreshed state.
+func (s *SandboxProcessService) List(ctx context.Context, sandboxID string) (*model.ProcessListResponse, error) {
+	if err := s.ensureSandbox(ctx, sandboxID); err != nil {
+		return nil, err
+	}
+	records, err := s.processStore.ListBySandbox(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	s.refresh(ctx, sandboxID, records)
+
+	items := make([]model.SandboxProcess, 0, len(records))
+	for i := range records {
+		items = append(items, *processRecordToModel(&records[i]))
+	}
+	return &model.ProcessListResponse{Items: items}, nil
+}
+
+// Get returns a single process with refreshed state.
+func (s *SandboxProcessService) Get(ctx context.Context, sandboxID, processID string) (*model.SandboxProcess, error) {
+	record, err := s.getRecord(ctx, sandboxID, processID)
+	if err != nil {
+		return nil, err
+	}
+	return processRecordToModel(record), nil
+}
+
+// Signal sends a signal to a running process. Signal names are accepted with or
+// without the SIG prefix.
+func (s *SandboxProcessService) Signal(ctx context.Context, sandboxID, processID, signal string) error {
+	signal = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(signal)), "SIG")
+	if _, ok := allowedProcessSignals[signal]; !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidProcessSignal, signal)
+	}
+
+	record, err := s.getRecord(ctx, sandboxID, processID)
+	if err != nil {
+		return err
+	}
+	if record.Status != string(model.ProcessStatusRunning) {
+		return ErrProcessNotRunning
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, processExecTimeout)
+	defer cancel()
+	if err := s.k8sClient.SignalProcess(execCtx, sandboxID, record.PodUID, record.PID, signal); err != nil {
+		if errors.Is(err, k8s.ErrProcessPodReplaced) {
+			return ErrProcessNotFound
+		}
+		return fmt.Errorf("failed to signal process: %w", err)
+	}
+	logWithSandboxID(ctx, sandboxID).Info("background process signaled", "process_id", processID, "signal", signal)
+	return nil
+}
+
+// Kill terminates a running process with SIGKILL.
+func (s *SandboxProcessService) Kill(ctx context.Context, sandboxID, processID string) error {
+	return s.Signal(ctx, sandboxID, processID, "KILL")
+}
+
+// Output returns the stdout/stderr captured so far.
+func (s *SandboxProcessService) Output(ctx context.Context, sandboxID, processID string) (*model.ProcessOutputResponse, error) {
+	if _, err := s.getRecord(ctx, sandboxID, processID); err != nil {
+		return nil, err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, processExecTimeout)
+	defer cancel()
+	result, err := s.k8sClient.ReadProcessOutput(execCtx, sandboxID, processID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process output: %w", err)
+	}
+	return &model.ProcessOutputResponse{Stdout: result.Stdout, Stderr: result.Stderr}, nil
+}
+
+// FollowOutput streams output through emit until the process finishes, then emits an
+// exit event. The exit code is -1 when the process ended without reporting one.
+func (s *SandboxProcessService) FollowOutput(ctx context.Context, sandboxID, processID string, emit func(model.ExecStreamEvent) error) error {
+	record, err := s.getRecord(ctx, sandboxID, processID)
+	if err != nil {
+		return err
+	}
+
+	// An empty PID never matches a live process, so follow degrades to a single read
+	// once the process is no longer running.
+	pid := ""
+	if record.Status == string(model.ProcessStatusRunning) {
+		pid = fmt.Sprintf("%d", record.PID)
+	}
+
+	var mu sync.Mutex
+	stdout := &execStreamWriter{eventType: model.ExecStreamEventStdout, emit: emit, mu: &mu}
+	stderr := &execStreamWriter{eventType: model.ExecStreamEventStderr, emit: emit, mu: &mu}
+	err = s.k8sClient.FollowProcessOutput(ctx, sandboxID, record.PodUID, processID, pid, stdout, stderr)
+	if errors.Is(err, k8s.ErrProcessPodReplaced) {
+		return ErrProcessNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to follow process output: %w", err)
+	}
+	if err := errors.Join(stdout.Flush(), stderr.Flush()); err != nil {
+		return err
+	}
+
+	record, err = s.getRecord(ctx, sandboxID, processID)
+	if err != nil {
+		return err
+	}
+	exitCode := -1
+	if record.ExitCode != nil {
+		exitCode = *record.ExitCode
+	}
+	return emit(model.ExecStreamEvent{Type: model.ExecStreamEventExit, ExitCode: exitCode})
+}
+
+func (s *SandboxProcessService) ensureSandbox(ctx context.Context, sandboxID string) error {
+	record, err := s.sandboxStore.GetByID(ctx, sandboxID)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.LifecycleStatus == "deleted" {
+		return ErrSandboxNotFound
+	}
+	return nil
+}
+
+func (s *SandboxProcessService) getRecord(ctx context.Context, sandboxID, processID string) (*store.SandboxProcessRecord, error) {
+	if err := s.ensureSandbox(ctx, sandboxID); err != nil {
+		return nil, err
+	}
+	record, err := s.processStore.Get(ctx, sandboxID, processID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrProcessNotFound
+	}
+	records := []store.SandboxProcessRecord{*record}
+	s.refresh(ctx, sandboxID, records)
+	return &records[0], nil
+}
+
+// refresh observes running processes in the sandbox and persists any that finished.
+// Records are updated in place. Observation failures leave the stored state untouched,
+// except when the pod is gone, which means every running process was lost.
+func (s *SandboxProcessService) refresh(ctx context.Context, sandboxID string, records []store.SandboxProcessRecord) {
+	running := false
+	for i := range records {
+		if records[i].Status == string(model.ProcessStatusRunning) {
+			running = true
+			break
+		}
+	}
+	if !running {
+		return
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, processExecTimeout)
+	defer cancel()
+	podUID, states, err := s.k8sClient.InspectProcesses(execCtx, sandboxID)
+	if err != nil && !apierrors.IsNotFound(err) {
+		logWithSandboxID(ctx, sandboxID).Warn("failed to inspect background processes", "error", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for i := range records {
+		rec := &records[i]
+		if rec.Status != string(model.ProcessStatusRunning) {
+			continue
+		}
+
+		status := model.ProcessStatusLost
+		var exitCode *int
+		if err == nil && podUID == rec.PodUID {
+			state, ok := states[rec.ID]
+			switch {
+			case !ok:
+				status = model.ProcessStatusLost
+			case state.Running:
+				continue
+			case state.Exited:
+				status = model.ProcessStatusExited
+				code := state.ExitCode
+				exitCode = &code
+			default:
+				status = model.ProcessStatusKilled
+			}
+		}
+
+		updated, updateErr := s.processStore.UpdateState(ctx, rec.ID, string(status), exitCode, &now)
+		if updateErr != nil {
+			logx.LoggerWithRequestID(ctx).Warn("failed to persist process state", "component", "sandbox_process", "process_id", rec.ID, "error", updateErr)
+			continue
+		}
+		if updated {
+			rec.Status = string(status)
+			rec.ExitCode = exitCode
+			finishedAt := now
+			rec.FinishedAt = &finishedAt
+		}
+	}
+}
+
+func processRecordToModel(rec *store.SandboxProcessRecord) *model.SandboxProcess {
+	return &model.SandboxProcess{
+		ID:         rec.ID,
+		SandboxID:  rec.SandboxID,
+		Command:    rec.Command(),
+		WorkDir:    rec.WorkDir,
+		PID:        rec.PID,
+		Status:     model.ProcessStatus(rec.Status),
+		ExitCode:   rec.ExitCode,
+		StartedAt:  rec.StartedAt,
+		FinishedAt: rec.FinishedAt,
+	}
+}
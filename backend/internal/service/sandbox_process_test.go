@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func newTestProcessService(t *testing.T) (*SandboxProcessService, *store.SandboxStore, *store.SandboxProcessStore) {
+	t.Helper()
+	initServiceTestDB(t)
+	sandboxStore := store.NewSandboxStore()
+	processStore := store.NewSandboxProcessStore()
+	return NewSandboxProcessService(k8s.NewClientForTest(), sandboxStore, processStore), sandboxStore, processStore
+}
+
+func TestSandboxProcessStartRequiresRunningSandbox(t *testing.T) {
+	svc, sandboxStore, _ := newTestProcessService(t)
+	ctx := context.Background()
+
+	_, err := svc.Start(ctx, "missing", &model.StartProcessRequest{Command: []string{"sleep", "1"}})
+	if !errors.Is(err, ErrSandboxNotFound) {
+		t.Fatalf("Start() on missing sandbox error = %v, want ErrSandboxNotFound", err)
+	}
+
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("sbx-stop", true, "stopped")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_, err = svc.Start(ctx, "sbx-stop", &model.StartProcessRequest{Command: []string{"sleep", "1"}})
+	if !errors.Is(err, ErrSandboxNotRunning) {
+		t.Fatalf("Start() on stopped sandbox error = %v, want ErrSandboxNotRunning", err)
+	}
+}
+
+func TestSandboxProcessStartRejectsInvalidEnvNames(t *testing.T) {
+	svc, sandboxStore, _ := newTestProcessService(t)
+	ctx := context.Background()
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("sbx-run", false, "running")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	for _, name := range []string{"", "1ABC", "A=B", "-i", "FOO BAR"} {
+		_, err := svc.Start(ctx, "sbx-run", &model.StartProcessRequest{
+			Command: []string{"sleep", "1"},
+			Env:     map[string]string{name: "x"},
+		})
+		if !errors.Is(err, ErrInvalidEnvName) {
+			t.Fatalf("Start() with env name %q error = %v, want ErrInvalidEnvName", name, err)
+		}
+	}
+}
+
+func TestSandboxProcessListMarksProcessesLostWhenPodIsGone(t *testing.T) {
+	svc, sandboxStore, processStore := newTestProcessService(t)
+	ctx := context.Background()
+
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("sbx-proc", false, "running")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	now := time.Now().UTC()
+	exitCode := 0
+	records := []*store.SandboxProcessRecord{
+		{ID: "proc-run", SandboxID: "sbx-proc", CommandJSON: `["sleep","100"]`, PID: 42, PodUID: "uid-1", Status: "running", StartedAt: now, UpdatedAt: now},
+		{ID: "proc-done", SandboxID: "sbx-proc", CommandJSON: `["true"]`, PID: 43, PodUID: "uid-1", Status: "exited", ExitCode: &exitCode, StartedAt: now.Add(-time.Minute), FinishedAt: &now, UpdatedAt: now},
+	}
+	for _, rec := range records {
+		if err := processStore.Create(ctx, rec); err != nil {
+			t.Fatalf("Create(process) error = %v", err)
+		}
+	}
+
+	resp, err := svc.List(ctx, "sbx-proc")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(resp.Items) != 2 {
+		t.Fatalf("List() len = %d, want 2", len(resp.Items))
+	}
+	got := map[string]model.SandboxProcess{}
+	for _, item := range resp.Items {
+		got[item.ID] = item
+	}
+	if got["proc-run"].Status != model.ProcessStatusLost || got["proc-run"].FinishedAt == nil {
+		t.Fatalf("proc-run = %+v, want lost with finished_at", got["proc-run"])
+	}
+	if got["proc-done"].Status != model.ProcessStatusExited || got["proc-done"].ExitCode == nil || *got["proc-done"].ExitCode != 0 {
+		t.Fatalf("proc-done = %+v, want exited with code 0", got["proc-done"])
+	}
+	if cmd := got["proc-run"].Command; len(cmd) != 2 || cmd[0] != "sleep" {
+		t.Fatalf("proc-run command = %v", cmd)
+	}
+
+	stored, err := processStore.Get(ctx, "sbx-proc", "proc-run")
+	if err != nil {
+		t.Fatalf("Get(process) error = %v", err)
+	}
+	if stored.Status != string(model.ProcessStatusLost) {
+		t.Fatalf("stored status = %q, want lost", stored.Status)
+	}
+
+	if err := svc.Signal(ctx, "sbx-proc", "proc-run", "TERM"); !errors.Is(err, ErrProcessNotRunning) {
+		t.Fatalf("Signal() on lost process error = %v, want ErrProcessNotRunning", err)
+	}
+	if err := svc.Signal(ctx, "sbx-proc", "proc-run", "SIGBOGUS"); !errors.Is(err, ErrInvalidProcessSignal) {
+		t.Fatalf("Signal() with bad signal error = %v, want ErrInvalidProcessSignal", err)
+	}
+	if _, err := svc.Get(ctx, "sbx-proc", "proc-missing"); !errors.Is(err, ErrProcessNotFound) {
+		t.Fatalf("Get() missing process error = %v, want ErrProcessNotFound", err)
+	}
+}
+
+func TestSandboxProcessFollowOutputRefusesReplacedPod(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	client := k8s.NewClientForTest(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sandbox-sbx-proc",
+			Namespace: k8s.DefaultSandboxNamespace,
+			UID:       "uid-2",
+			Labels:    map[string]string{"app": k8s.LabelApp, k8s.LabelSandboxID: "sbx-proc"},
+		},
+	})
+	sandboxStore := store.NewSandboxStore()
+	processStore := store.NewSandboxProcessStore()
+	svc := NewSandboxProcessService(client, sandboxStore, processStore)
+
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("sbx-proc", true, "running")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	now := time.Now().UTC()
+	exitCode := 0
+	if err := processStore.Create(ctx, &store.SandboxProcessRecord{
+		ID: "proc-done", SandboxID: "sbx-proc", CommandJSON: `["true"]`, PID: 43, PodUID: "uid-1",
+		Status: "exited", ExitCode: &exitCode, StartedAt: now, FinishedAt: &now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Create(process) error = %v", err)
+	}
+
+	err := svc.FollowOutput(ctx, "sbx-proc", "proc-done", func(model.ExecStreamEvent) error { return nil })
+	if !errors.Is(err, ErrProcessNotFound) {
+		t.Fatalf("FollowOutput() after the pod was replaced error = %v, want ErrProcessNotFound", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/metrics"
 	"github.com/fslongjin/liteboxd/backend/internal/model"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
 	"github.com/google/uuid"
@@ -25,6 +26,7 @@ const (
 type SandboxReconcileService struct {
 	k8sClient       *k8s.Client
 	sandboxStore    *store.SandboxStore
+	poolSvc         *SandboxPoolService
 	lostGracePeriod time.Duration
 }
 
@@ -36,6 +38,11 @@ func NewSandboxReconcileService(k8sClient *k8s.Client, sandboxStore *store.Sandb
 	}
 }
 
+// SetPoolService makes every run also reconcile the warm pools of templates.
+func (s *SandboxReconcileService) SetPoolService(poolSvc *SandboxPoolService) {
+	s.poolSvc = poolSvc
+}
+
 func (s *SandboxReconcileService) Start(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	go func() {
@@ -64,12 +71,14 @@ func (s *SandboxReconcileService) Run(ctx context.Context, trigger string) (*mod
 	dbRecords, err := s.sandboxStore.ListForReconcile(ctx)
 	if err != nil {
 		_ = s.sandboxStore.FinishReconcileRun(ctx, runID, reconcileStatusFailed, err.Error(), 0, 0, 0, 0, time.Now().UTC())
+		metrics.ReconcileRuns.WithLabelValues(reconcileStatusFailed).Inc()
 		return nil, err
 	}
 
 	podList, err := s.k8sClient.ListPods(ctx)
 	if err != nil {
 		_ = s.sandboxStore.FinishReconcileRun(ctx, runID, reconcileStatusFailed, err.Error(), len(dbRecords), 0, 0, 0, time.Now().UTC())
+		metrics.ReconcileRuns.WithLabelValues(reconcileStatusFailed).Inc()
 		return nil, err
 	}
 
@@ -79,6 +88,10 @@ func (s *SandboxReconcileService) Run(ctx context.Context, trigger string) (*mod
 		if sandboxID == "" {
 			continue
 		}
+		if _, pooled := pod.Labels[k8s.LabelPool]; pooled {
+			// Warm pool members have no record until claimed
+			continue
+		}
 		podMap[sandboxID] = i
 	}
 
@@ -90,6 +103,7 @@ func (s *SandboxReconcileService) Run(ctx context.Context, trigger string) (*mod
 			handled, err := s.reconcilePersistentSandbox(ctx, runID, &rec, podMap)
 			if err != nil {
 				_ = s.sandboxStore.FinishReconcileRun(ctx, runID, reconcileStatusFailed, err.Error(), len(dbRecords), len(podList.Items), driftCount, fixedCount, time.Now().UTC())
+				metrics.ReconcileRuns.WithLabelValues(reconcileStatusFailed).Inc()
 				return nil, err
 			}
 			if handled.drifted {
@@ -105,6 +119,7 @@ func (s *SandboxReconcileService) Run(ctx context.Context, trigger string) (*mod
 			handled, err := s.reconcileDeletedSandbox(ctx, runID, &rec, podMap)
 			if err != nil {
 				_ = s.sandboxStore.FinishReconcileRun(ctx, runID, reconcileStatusFailed, err.Error(), len(dbRecords), len(podList.Items), driftCount, fixedCount, time.Now().UTC())
+				metrics.ReconcileRuns.WithLabelValues(reconcileStatusFailed).Inc()
 				return nil, err
 			}
 			if handled.drifted {
@@ -130,7 +145,7 @@ func (s *SandboxReconcileService) Run(ctx context.Context, trigger string) (*mod
 						action = "mark_deleted"
 					}
 				}
-			case "stopped":
+			case "stopped", "paused":
 				// Pod intentionally absent — not drift
 				continue
 			default:
@@ -159,6 +174,10 @@ func (s *SandboxReconcileService) Run(ctx context.Context, trigger string) (*mod
 
 		pod := podList.Items[idx]
 		delete(podMap, rec.ID)
+		if rec.LifecycleStatus == "paused" {
+			// The pod is being checkpointed and will be removed by the pause itself
+			continue
+		}
 
 		podStatus := convertPodStatus(&pod)
 		newLifecycle := string(podStatus)
@@ -201,10 +220,19 @@ func (s *SandboxReconcileService) Run(ctx context.Context, trigger string) (*mod
 		})
 	}
 
+	if s.poolSvc != nil {
+		if err := s.poolSvc.Reconcile(ctx); err != nil {
+			slog.Default().With("component", "sandbox_reconciler").Warn("warm pool reconcile failed", "run_id", runID, "error", err)
+		}
+	}
+
 	finishedAt := time.Now().UTC()
 	if err := s.sandboxStore.FinishReconcileRun(ctx, runID, reconcileStatusCompleted, "", len(dbRecords), len(podList.Items), driftCount, fixedCount, finishedAt); err != nil {
 		return nil, err
 	}
+	metrics.ReconcileRuns.WithLabelValues(reconcileStatusCompleted).Inc()
+	metrics.ReconcileDrift.Add(float64(driftCount))
+	metrics.ReconcileFixed.Add(float64(fixedCount))
 
 	return s.GetRun(ctx, runID)
 }
@@ -414,7 +442,7 @@ func (s *SandboxReconcileService) reconcileStoppedPersistentSandbox(ctx context.
 		if !isTerminalOrDeletingPod(snapshot.Pod) {
 			action = "cleanup_unexpected_pod"
 		}
-		if err := s.k8sClient.DeletePodByName(ctx, snapshot.Pod.Name); err != nil && !apierrors.IsNotFound(err) {
+		if err := s.k8sClient.DeletePodByName(k8s.WithNamespace(ctx, snapshot.Pod.Namespace), snapshot.Pod.Name); err != nil && !apierrors.IsNotFound(err) {
 			action = "cleanup_failed"
 			_ = s.sandboxStore.AddReconcileItem(ctx, &store.ReconcileItemRecord{
 				RunID:     runID,
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/logx"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+	"github.com/google/uuid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const snapshotArchiveTimeout = 30 * time.Minute
+
+// SandboxSnapshotService captures the root filesystem of persistent sandboxes. When the
+// storage class supports it a CSI VolumeSnapshot of the sandbox volume is taken;
+// otherwise the overlay upper dir is archived into archiveDir on the control plane.
+// The readiness of CSI snapshots is observed lazily whenever snapshots are read.
+type SandboxSnapshotService struct {
+	k8sClient     *k8s.Client
+	sandboxStore  *store.SandboxStore
+	snapshotStore *store.SandboxSnapshotStore
+	archiveDir    string
+}
+
+func NewSandboxSnapshotService(k8sClient *k8s.Client, sandboxStore *store.SandboxStore, snapshotStore *store.SandboxSnapshotStore, archiveDir string) *SandboxSnapshotService {
+	return &SandboxSnapshotService{
+		k8sClient:     k8sClient,
+		sandboxStore:  sandboxStore,
+		snapshotStore: snapshotStore,
+		archiveDir:    archiveDir,
+	}
+}
+
+// Create starts a snapshot of a sandbox. The returned snapshot is pending until the
+// volume snapshot is ready or the archive has been written.
+func (s *SandboxSnapshotService) Create(ctx context.Context, sandboxID string, req *model.CreateSnapshotRequest) (*model.SandboxSnapshot, error) {
+	sandbox, err := s.sandboxStore.GetByID(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	if sandbox == nil || sandbox.LifecycleStatus == "deleted" {
+		return nil, ErrSandboxNotFound
+	}
+	if !sandbox.PersistenceEnabled {
+		return nil, ErrSnapshotNotSupported
+	}
+	if sandbox.DesiredState == store.DesiredStateDeleted || sandbox.LifecycleStatus == "terminating" {
+		return nil, ErrSnapshotInvalidState
+	}
+
+	className, err := s.k8sClient.FindVolumeSnapshotClass(ctx, sandbox.StorageClassName)
+	if err != nil {
+		return nil, err
+	}
+	kind := model.SnapshotKindVolumeSnapshot
+	if className == "" {
+		// Archives are read from the running sandbox.
+		if sandbox.LifecycleStatus != string(model.SandboxStatusRunning) {
+			return nil, ErrSandboxNotRunning
+		}
+		kind = model.SnapshotKindArchive
+	}
+
+	id := "snap-" + uuid.New().String()[:8]
+	now := time.Now().UTC()
+	record := &store.SandboxSnapshotRecord{
+		ID:               id,
+		SandboxID:        sandboxID,
+		Name:             req.Name,
+		TemplateName:     sandbox.TemplateName,
+		TemplateVersion:  sandbox.TemplateVersion,
+		Kind:             string(kind),
+		Status:           string(model.SnapshotStatusPending),
+		StorageClassName: sandbox.StorageClassName,
+		VolumeSize:       sandbox.PersistenceSize,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		OwnerID:          sandbox.OwnerID,
+		Project:          sandbox.Project,
+	}
+	if kind == model.SnapshotKindVolumeSnapshot {
+		record.VolumeSnapshotName = id
+	}
+	if err := s.snapshotStore.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	logger := logWithSandboxID(ctx, sandboxID).With("snapshot_id", id, "kind", kind)
+	if kind == model.SnapshotKindVolumeSnapshot {
+		if err := s.k8sClient.CreateVolumeSnapshot(ctx, record.VolumeSnapshotName, id, sandboxID, sandbox.VolumeClaimName, className); err != nil {
+			_ = s.snapshotStore.Delete(context.Background(), id)
+			return nil, err
+		}
+	} else {
+		bgCtx := logx.WithRequestID(context.Background(), logx.RequestIDFromContext(ctx))
+		go s.writeArchive(bgCtx, record)
+	}
+	logger.Info("sandbox snapshot started")
+	return snapshotRecordToModel(record), nil
+}
+
+// Get returns a snapshot with refreshed state.
+func (s *SandboxSnapshotService) Get(ctx context.Context, id string) (*model.SandboxSnapshot, error) {
+	record, err := s.getRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotRecordToModel(record), nil
+}
+
+// List returns the snapshots of a sandbox, or of all sandboxes when sandboxID is empty.
+// Snapshots of deleted sandboxes are still listed. Only the snapshots the caller may
+// see are returned.
+func (s *SandboxSnapshotService) List(ctx context.Context, sandboxID string) (*model.SnapshotListResponse, error) {
+	scope := store.SandboxScope{OwnerID: ownerFilter(ctx), Project: projectScope(ctx), Templates: templateScope(ctx)}
+	records, err := s.snapshotStore.List(ctx, sandboxID, scope)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]model.SandboxSnapshot, 0, len(records))
+	for i := range records {
+		if !snapshotVisible(ctx, &records[i]) {
+			continue
+		}
+		s.refresh(ctx, &records[i])
+		items = append(items, *snapshotRecordToModel(&records[i]))
+	}
+	return &model.SnapshotListResponse{Items: items}, nil
+}
+
+// Delete removes a snapshot and the data backing it.
+func (s *SandboxSnapshotService) Delete(ctx context.Context, id string) error {
+	record, err := s.getRecord(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	switch model.SnapshotKind(record.Kind) {
+	case model.SnapshotKindVolumeSnapshot:
+		if err := s.k8sClient.DeleteVolumeSnapshot(s.k8sClient.WithSandboxNamespace(ctx, record.SandboxID), record.VolumeSnapshotName); err != nil {
+			return err
+		}
+	case model.SnapshotKindArchive:
+		if record.Status == string(model.SnapshotStatusPending) {
+			return ErrSnapshotInProgress
+		}
+		if err := os.Remove(s.archivePath(id)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove snapshot archive: %w", err)
+		}
+	}
+	if err := s.snapshotStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	logWithSandboxID(ctx, record.SandboxID).Info("sandbox snapshot deleted", "snapshot_id", id)
+	return nil
+}
+
+// getReady returns a snapshot that a sandbox can be restored from.
+func (s *SandboxSnapshotService) getReady(ctx context.Context, id string) (*store.SandboxSnapshotRecord, error) {
+	record, err := s.getRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != string(model.SnapshotStatusReady) {
+		return nil, fmt.Errorf("%w: snapshot %s is %s", ErrSnapshotNotReady, id, record.Status)
+	}
+	return record, nil
+}
+
+// openArchive opens the archive of an archive snapshot.
+func (s *SandboxSnapshotService) openArchive(id string) (*os.File, error) {
+	return os.Open(s.archivePath(id))
+}
+
+// getRecord returns a snapshot the caller may see. Other users' snapshots are reported
+// as missing, like their sandboxes.
+func (s *SandboxSnapshotService) getRecord(ctx context.Context, id string) (*store.SandboxSnapshotRecord, error) {
+	record, err := s.snapshotStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || !snapshotVisible(ctx, record) {
+		return nil, ErrSnapshotNotFound
+	}
+	s.refresh(ctx, record)
+	return record, nil
+}
+
+// snapshotVisible reports whether the caller may see a snapshot. The same rules as
+// for sandboxes apply: non-admins only see their own snapshots, project-scoped keys
+// only those of the project and template-limited keys only those of the templates.
+func snapshotVisible(ctx context.Context, record *store.SandboxSnapshotRecord) bool {
+	p := auth.PrincipalFromContext(ctx)
+	if p == nil {
+		return true
+	}
+	if !p.IsAdmin() && record.OwnerID != p.UserID {
+		return false
+	}
+	if p.Project != "" && record.Project != p.Project {
+		return false
+	}
+	return p.AllowsTemplate(record.TemplateName)
+}
+
+// refresh observes the VolumeSnapshot of a pending CSI snapshot and records its outcome.
+// Failures to observe leave the snapshot pending.
+func (s *SandboxSnapshotService) refresh(ctx context.Context, record *store.SandboxSnapshotRecord) {
+	if record.Kind != string(model.SnapshotKindVolumeSnapshot) || record.Status != string(model.SnapshotStatusPending) {
+		return
+	}
+
+	status, err := s.k8sClient.GetVolumeSnapshotStatus(s.k8sClient.WithSandboxNamespace(ctx, record.SandboxID), record.VolumeSnapshotName)
+	switch {
+	case apierrors.IsNotFound(err):
+		s.finish(ctx, record, model.SnapshotStatusFailed, "volume snapshot not found", 0)
+	case err != nil:
+		logWithSandboxID(ctx, record.SandboxID).Warn("failed to observe volume snapshot", "snapshot_id", record.ID, "error", err)
+	case status.Error != "":
+		s.finish(ctx, record, model.SnapshotStatusFailed, status.Error, 0)
+	case status.ReadyToUse:
+		var size int64
+		if q, err := resource.ParseQuantity(status.RestoreSize); err == nil {
+			size = q.Value()
+		}
+		s.finish(ctx, record, model.SnapshotStatusReady, "", size)
+	}
+}
+
+func (s *SandboxSnapshotService) finish(ctx context.Context, record *store.SandboxSnapshotRecord, status model.SnapshotStatus, reason string, sizeBytes int64) {
+	var readyAt *time.Time
+	if status == model.SnapshotStatusReady {
+		now := time.Now().UTC()
+		readyAt = &now
+	}
+	updated, err := s.snapshotStore.UpdateStatus(ctx, record.ID, string(status), reason, sizeBytes, readyAt)
+	if err != nil {
+		logWithSandboxID(ctx, record.SandboxID).Warn("failed to update snapshot status", "snapshot_id", record.ID, "error", err)
+		return
+	}
+	if updated {
+		record.Status = string(status)
+		record.StatusReason = reason
+		record.SizeBytes = sizeBytes
+		record.ReadyAt = readyAt
+	}
+}
+
+// writeArchive streams the overlay of the sandbox into the archive of record. The
+// archive is written to a temporary file first, so a ready snapshot always has a
+// complete archive.
+func (s *SandboxSnapshotService) writeArchive(ctx context.Context, record *store.SandboxSnapshotRecord) {
+	logger := logWithSandboxID(ctx, record.SandboxID).With("snapshot_id", record.ID)
+	size, err := s.archiveSandbox(ctx, record)
+	if err != nil {
+		logger.Warn("sandbox snapshot failed", "error", err)
+		s.finish(context.Background(), record, model.SnapshotStatusFailed, err.Error(), 0)
+		return
+	}
+	s.finish(context.Background(), record, model.SnapshotStatusReady, "", size)
+	logger.Info("sandbox snapshot archived", "size_bytes", size)
+}
+
+func (s *SandboxSnapshotService) archiveSandbox(ctx context.Context, record *store.SandboxSnapshotRecord) (int64, error) {
+	if err := os.MkdirAll(s.archiveDir, 0o750); err != nil {
+		return 0, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	f, err := os.CreateTemp(s.archiveDir, record.ID+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create snapshot archive: %w", err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	archiveCtx, cancel := context.WithTimeout(ctx, snapshotArchiveTimeout)
+	defer cancel()
+	err = s.k8sClient.ArchiveRootFSOverlay(archiveCtx, record.SandboxID, f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write snapshot archive: %w", closeErr)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat snapshot archive: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.archivePath(record.ID)); err != nil {
+		return 0, fmt.Errorf("failed to store snapshot archive: %w", err)
+	}
+	return info.Size(), nil
+}
+
+func (s *SandboxSnapshotService) archivePath(id string) string {
+	return filepath.Join(s.archiveDir, id+".tar.gz")
+}
+
+// restoreSnapshotArchive extracts an archive snapshot into a sandbox created with
+// RestoreFromArchive, which holds its startup until the archive is in place.
+func (s *SandboxService) restoreSnapshotArchive(ctx context.Context, id, snapshotID string, timeout time.Duration) error {
+	if s.snapshotSvc == nil {
+		return errors.New("snapshot service not configured")
+	}
+	archive, err := s.snapshotSvc.openArchive(snapshotID)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot archive: %w", err)
+	}
+	defer archive.Close()
+
+	restoreCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return s.k8sClient.RestoreRootFSOverlay(restoreCtx, id, archive)
+}
+
+func snapshotRecordToModel(record *store.SandboxSnapshotRecord) *model.SandboxSnapshot {
+	return &model.SandboxSnapshot{
+		ID:               record.ID,
+		SandboxID:        record.SandboxID,
+		Name:             record.Name,
+		Template:         record.TemplateName,
+		TemplateVersion:  record.TemplateVersion,
+		Kind:             model.SnapshotKind(record.Kind),
+		Status:           model.SnapshotStatus(record.Status),
+		StatusReason:     record.StatusReason,
+		StorageClassName: record.StorageClassName,
+		VolumeSize:       record.VolumeSize,
+		SizeBytes:        record.SizeBytes,
+		CreatedAt:        record.CreatedAt,
+		ReadyAt:          record.ReadyAt,
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func newTestSnapshotService(t *testing.T, client *k8s.Client) (*SandboxSnapshotService, *store.SandboxStore, *store.SandboxSnapshotStore) {
+	t.Helper()
+	initServiceTestDB(t)
+	sandboxStore := store.NewSandboxStore()
+	snapshotStore := store.NewSandboxSnapshotStore()
+	return NewSandboxSnapshotService(client, sandboxStore, snapshotStore, t.TempDir()), sandboxStore, snapshotStore
+}
+
+func makeTestSnapshotRecord(id, kind, status string) *store.SandboxSnapshotRecord {
+	now := time.Now().UTC()
+	return &store.SandboxSnapshotRecord{
+		ID:                 id,
+		SandboxID:          "sbx-snap",
+		TemplateName:       "python",
+		TemplateVersion:    1,
+		Kind:               kind,
+		Status:             status,
+		VolumeSnapshotName: id,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+}
+
+func TestSandboxSnapshotCreateRequiresPersistentRunningSandbox(t *testing.T) {
+	svc, sandboxStore, _ := newTestSnapshotService(t, k8s.NewClientForTest())
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "missing", &model.CreateSnapshotRequest{}); !errors.Is(err, ErrSandboxNotFound) {
+		t.Fatalf("Create() on missing sandbox error = %v, want ErrSandboxNotFound", err)
+	}
+
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("sbx-pod", false, "running")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := svc.Create(ctx, "sbx-pod", &model.CreateSnapshotRequest{}); !errors.Is(err, ErrSnapshotNotSupported) {
+		t.Fatalf("Create() on non-persistent sandbox error = %v, want ErrSnapshotNotSupported", err)
+	}
+
+	// Without a snapshot-capable storage class the overlay is archived from the running sandbox.
+	if err := sandboxStore.Create(ctx, makeTestSandboxRecord("sbx-stop", true, "stopped")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := svc.Create(ctx, "sbx-stop", &model.CreateSnapshotRequest{}); !errors.Is(err, ErrSandboxNotRunning) {
+		t.Fatalf("Create() on stopped sandbox error = %v, want ErrSandboxNotRunning", err)
+	}
+}
+
+func TestSandboxSnapshotRefreshesVolumeSnapshotState(t *testing.T) {
+	client := k8s.NewClientForTestWithDynamic()
+	svc, _, snapshotStore := newTestSnapshotService(t, client)
+	ctx := context.Background()
+
+	for _, id := range []string{"snap-pending", "snap-gone"} {
+		if err := snapshotStore.Create(ctx, makeTestSnapshotRecord(id, string(model.SnapshotKindVolumeSnapshot), "pending")); err != nil {
+			t.Fatalf("Create(%s) error = %v", id, err)
+		}
+	}
+	if err := client.CreateVolumeSnapshot(ctx, "snap-pending", "snap-pending", "sbx-snap", "sandbox-data-sbx-snap", "longhorn"); err != nil {
+		t.Fatalf("CreateVolumeSnapshot() error = %v", err)
+	}
+
+	resp, err := svc.List(ctx, "sbx-snap")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	states := map[string]model.SnapshotStatus{}
+	for _, item := range resp.Items {
+		states[item.ID] = item.Status
+	}
+	if states["snap-pending"] != model.SnapshotStatusPending || states["snap-gone"] != model.SnapshotStatusFailed {
+		t.Fatalf("snapshot states = %v, want snap-pending pending and snap-gone failed", states)
+	}
+
+	if err := svc.Delete(ctx, "snap-pending"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := client.GetVolumeSnapshotStatus(ctx, "snap-pending"); err == nil {
+		t.Fatalf("volume snapshot still exists after Delete()")
+	}
+	if _, err := svc.Get(ctx, "snap-pending"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("Get() after Delete() error = %v, want ErrSnapshotNotFound", err)
+	}
+}
+
+func TestSandboxSnapshotDeleteArchive(t *testing.T) {
+	svc, _, snapshotStore := newTestSnapshotService(t, k8s.NewClientForTest())
+	ctx := context.Background()
+
+	if err := snapshotStore.Create(ctx, makeTestSnapshotRecord("snap-busy", string(model.SnapshotKindArchive), "pending")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := svc.Delete(ctx, "snap-busy"); !errors.Is(err, ErrSnapshotInProgress) {
+		t.Fatalf("Delete() on pending archive error = %v, want ErrSnapshotInProgress", err)
+	}
+
+	if err := snapshotStore.Create(ctx, makeTestSnapshotRecord("snap-done", string(model.SnapshotKindArchive), "ready")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	archive := filepath.Join(svc.archiveDir, "snap-done.tar.gz")
+	if err := os.WriteFile(archive, []byte("archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, "snap-done"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Fatalf("archive still exists after Delete(): %v", err)
+	}
+}
+
+func TestCreateSandboxFromSnapshotRequiresReadySnapshot(t *testing.T) {
+	client := k8s.NewClientForTest()
+	snapshotSvc, sandboxStore, snapshotStore := newTestSnapshotService(t, client)
+	ctx := context.Background()
+
+	svc := NewSandboxService(client, sandboxStore, nil)
+	svc.SetTemplateService(NewTemplateService())
+	svc.SetSnapshotService(snapshotSvc)
+
+	if _, err := svc.Create(ctx, &model.CreateSandboxRequest{FromSnapshot: "missing"}); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("Create() from missing snapshot error = %v, want ErrSnapshotNotFound", err)
+	}
+	if err := snapshotStore.Create(ctx, makeTestSnapshotRecord("snap-wip", string(model.SnapshotKindArchive), "pending")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := svc.Create(ctx, &model.CreateSandboxRequest{FromSnapshot: "snap-wip"}); !errors.Is(err, ErrSnapshotNotReady) {
+		t.Fatalf("Create() from pending snapshot error = %v, want ErrSnapshotNotReady", err)
+	}
+}
+
+func TestSandboxSnapshotsHiddenFromOtherUsers(t *testing.T) {
+	client := k8s.NewClientForTest()
+	snapshotSvc, sandboxStore, snapshotStore := newTestSnapshotService(t, client)
+	ctx := context.Background()
+	svc := NewSandboxService(client, sandboxStore, nil)
+	svc.SetTemplateService(NewTemplateService())
+	svc.SetSnapshotService(snapshotSvc)
+
+	rec := makeTestSnapshotRecord("snap-alice", string(model.SnapshotKindArchive), "ready")
+	rec.OwnerID, rec.Project = "u-alice", "team-a"
+	if err := snapshotStore.Create(ctx, rec); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(snapshotSvc.archiveDir, "snap-alice.tar.gz"), []byte("archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	alice := auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-alice", Role: model.RoleDeveloper})
+	admin := auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-admin", Role: model.RoleAdmin})
+	for name, other := range map[string]context.Context{
+		"bob":          auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-bob", Role: model.RoleDeveloper}),
+		"admin/team-b": auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-admin", Role: model.RoleAdmin, Project: "team-b"}),
+		"alice/node":   auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-alice", Role: model.RoleDeveloper, Templates: []string{"node"}}),
+	} {
+		if _, err := snapshotSvc.Get(other, "snap-alice"); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Fatalf("Get() as %s error = %v, want ErrSnapshotNotFound", name, err)
+		}
+		resp, err := snapshotSvc.List(other, "")
+		if err != nil || len(resp.Items) != 0 {
+			t.Fatalf("List() as %s = %+v, %v; want no snapshots", name, resp, err)
+		}
+		if _, err := svc.Create(other, &model.CreateSandboxRequest{FromSnapshot: "snap-alice"}); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Fatalf("Create() from snapshot as %s error = %v, want ErrSnapshotNotFound", name, err)
+		}
+		if err := snapshotSvc.Delete(other, "snap-alice"); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Fatalf("Delete() as %s error = %v, want ErrSnapshotNotFound", name, err)
+		}
+	}
+
+	for _, owner := range []context.Context{alice, admin} {
+		if _, err := snapshotSvc.Get(owner, "snap-alice"); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp, err := snapshotSvc.List(owner, "")
+		if err != nil || len(resp.Items) != 1 {
+			t.Fatalf("List() = %+v, %v; want the snapshot", resp, err)
+		}
+	}
+	if err := snapshotSvc.Delete(alice, "snap-alice"); err != nil {
+		t.Fatalf("Delete() as owner error = %v", err)
+	}
+}
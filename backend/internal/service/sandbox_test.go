@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
 	"github.com/fslongjin/liteboxd/backend/internal/k8s"
 	"github.com/fslongjin/liteboxd/backend/internal/model"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
@@ -95,6 +97,7 @@ func TestParseLifecycleStatusAll(t *testing.T) {
 		{"succeeded", model.SandboxStatusSucceeded},
 		{"failed", model.SandboxStatusFailed},
 		{"stopped", model.SandboxStatusStopped},
+		{"paused", model.SandboxStatusPaused},
 		{"terminating", model.SandboxStatusTerminating},
 		{"", model.SandboxStatusUnknown},
 		{"bogus", model.SandboxStatusUnknown},
@@ -109,6 +112,21 @@ func TestParseLifecycleStatusAll(t *testing.T) {
 
 // --- Stop validation tests ---
 
+func TestSandboxAccessURL(t *testing.T) {
+	t.Setenv("GATEWAY_URL", "https://gateway.example.com")
+	t.Setenv("GATEWAY_BASE_DOMAIN", "")
+	if got := sandboxAccessURL("abc12345"); got != "https://gateway.example.com/api/v1/sandbox/abc12345" {
+		t.Fatalf("path form = %q", got)
+	}
+	t.Setenv("GATEWAY_BASE_DOMAIN", "Sandbox.Example.com.")
+	if got := sandboxAccessURL("abc12345"); got != "https://{port}-abc12345.sandbox.example.com" {
+		t.Fatalf("host form = %q", got)
+	}
+	if got := sandboxTunnelURL("abc12345"); got != "wss://gateway.example.com/api/v1/sandbox/abc12345/tcp/{port}" {
+		t.Fatalf("tunnel url = %q", got)
+	}
+}
+
 func TestStopNotFound(t *testing.T) {
 	initServiceTestDB(t)
 	ctx := context.Background()
@@ -292,6 +310,42 @@ func TestListForUserIncludesTerminatingWithoutDecryptingToken(t *testing.T) {
 	}
 }
 
+func TestListsOnlyShowAllowedTemplates(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	sandboxStore := store.NewSandboxStore()
+	for id, template := range map[string]string{"tpl-py": "python", "tpl-node": "node"} {
+		rec := makeTestSandboxRecord(id, false, "running")
+		rec.TemplateName, rec.OwnerID = template, "u-alice"
+		if err := sandboxStore.Create(ctx, rec); err != nil {
+			t.Fatalf("Create(%s) error = %v", id, err)
+		}
+	}
+	svc := &SandboxService{sandboxStore: sandboxStore}
+
+	for _, tt := range []struct {
+		templates []string
+		want      int
+	}{
+		{nil, 2},
+		{[]string{"python"}, 1},
+		{[]string{"ruby"}, 0},
+	} {
+		pctx := auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-alice", Role: model.RoleDeveloper, Templates: tt.templates})
+		list, err := svc.ListForUser(pctx, false)
+		if err != nil || len(list.Items) != tt.want {
+			t.Fatalf("ListForUser(templates=%v) = %+v, %v; want %d items", tt.templates, list, err, tt.want)
+		}
+		meta, err := svc.ListMetadata(pctx, model.SandboxMetadataListOptions{})
+		if err != nil || len(meta.Items) != tt.want || meta.Total != tt.want {
+			t.Fatalf("ListMetadata(templates=%v) = %+v, %v; want %d items", tt.templates, meta, err, tt.want)
+		}
+		if tt.want == 1 && (list.Items[0].ID != "tpl-py" || meta.Items[0].ID != "tpl-py") {
+			t.Fatalf("lists = %+v, %+v; want only tpl-py", list.Items, meta.Items)
+		}
+	}
+}
+
 // --- Start validation tests ---
 
 func TestStartNotFound(t *testing.T) {
@@ -517,3 +571,37 @@ func TestStopThenStartCycle(t *testing.T) {
 		t.Fatalf("Start(not-stopped) error = %v, want ErrSandboxNotStopped", err)
 	}
 }
+
+func TestExecStreamWriterKeepsCharactersAndBinaryIntact(t *testing.T) {
+	var events []model.ExecStreamEvent
+	var mu sync.Mutex
+	w := &execStreamWriter{eventType: model.ExecStreamEventStdout, mu: &mu, emit: func(e model.ExecStreamEvent) error {
+		events = append(events, e)
+		return nil
+	}}
+
+	// "你" is split across two writes
+	for _, chunk := range []string{"a\xe4\xbd", "\xa0b", "\xff\x00", "\xe4"} {
+		if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := []model.ExecStreamEvent{
+		{Type: model.ExecStreamEventStdout, Data: "a"},
+		{Type: model.ExecStreamEventStdout, Data: "你b"},
+		{Type: model.ExecStreamEventStdout, Data: "/wA=", Encoding: model.ExecStreamEncodingBase64},
+		{Type: model.ExecStreamEventStdout, Data: "5A==", Encoding: model.ExecStreamEncodingBase64},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want %+v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+// maxTokenGracePeriod bounds how long a replaced access token may stay valid.
+const maxTokenGracePeriod = 24 * time.Hour
+
+// RotateAccessToken replaces the access token of a sandbox, for example after it has
+// leaked. With a grace period the replaced token keeps working at the gateway for that
+// long, so clients can switch over; otherwise it is rejected at once. The returned
+// sandbox carries the new token.
+func (s *SandboxService) RotateAccessToken(ctx context.Context, id string, req *model.RotateAccessTokenRequest) (*model.Sandbox, error) {
+	grace := time.Duration(req.GracePeriod) * time.Second
+	if req.GracePeriod < 0 || grace > maxTokenGracePeriod {
+		return nil, fmt.Errorf("%w: gracePeriod must be between 0 and %d seconds", ErrInvalidGracePeriod, int(maxTokenGracePeriod.Seconds()))
+	}
+
+	record, err := s.sandboxStore.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.LifecycleStatus == "deleted" || record.DesiredState == store.DesiredStateDeleted {
+		return nil, ErrSandboxNotFound
+	}
+
+	accessToken, err := security.GenerateToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	ciphertext, nonce, keyID, err := s.tokenCipher.Encrypt(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	now := time.Now().UTC()
+	var previousExpiresAt *time.Time
+	if grace > 0 {
+		t := now.Add(grace)
+		previousExpiresAt = &t
+	}
+	rotated, err := s.sandboxStore.RotateAccessToken(ctx, id, ciphertext, nonce, keyID, security.HashToken(accessToken), previousExpiresAt, now)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, ErrSandboxNotFound
+	}
+	logWithSandboxID(ctx, id).Info("sandbox access token rotated", "grace_period", grace.String())
+
+	record, err = s.sandboxStore.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrSandboxNotFound
+	}
+	return s.recordToSandbox(ctx, record)
+}
+
+// ReencryptAccessTokens re-encrypts the access tokens stored under previous encryption
+// keys with the current key, after SANDBOX_TOKEN_ENCRYPTION_KEY_ID has been changed.
+// Once it has run, the previous keys are no longer needed. It returns the number of
+// tokens re-encrypted.
+func (s *SandboxService) ReencryptAccessTokens(ctx context.Context) (int, error) {
+	logger := slog.Default().With("component", "token_key_rotation")
+	records, err := s.sandboxStore.ListAccessTokensNotUnderKey(ctx, s.tokenCipher.KeyID())
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var errs []error
+	for i := range records {
+		record := &records[i]
+		token, err := s.tokenCipher.DecryptWithKeyID(record.AccessTokenCiphertext, record.AccessTokenNonce, record.AccessTokenKeyID)
+		if err != nil {
+			logger.Warn("failed to decrypt access token", "sandbox_id", record.ID, "key_id", record.AccessTokenKeyID, "error", err)
+			errs = append(errs, fmt.Errorf("sandbox %s: %w", record.ID, err))
+			continue
+		}
+		ciphertext, nonce, keyID, err := s.tokenCipher.Encrypt(token)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sandbox %s: %w", record.ID, err))
+			continue
+		}
+		updated, err := s.sandboxStore.ReencryptAccessToken(ctx, record.ID, record.AccessTokenKeyID, ciphertext, nonce, keyID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sandbox %s: %w", record.ID, err))
+			continue
+		}
+		if updated {
+			count++
+		}
+	}
+	if count > 0 {
+		logger.Info("re-encrypted sandbox access tokens", "count", count, "key_id", s.tokenCipher.KeyID())
+	}
+	return count, errors.Join(errs...)
+}
+
+// previousTokenExpiry returns when the token replaced by the last rotation stops being
+// accepted, or nil once it no longer is.
+func previousTokenExpiry(record *store.SandboxRecord, now time.Time) *time.Time {
+	if record.PreviousAccessTokenSHA256 == "" || record.PreviousAccessTokenExpiresAt == nil || !now.Before(*record.PreviousAccessTokenExpiresAt) {
+		return nil
+	}
+	return record.PreviousAccessTokenExpiresAt
+}
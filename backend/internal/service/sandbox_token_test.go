@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/security"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func seedEncryptedSandbox(t *testing.T, sandboxStore *store.SandboxStore, cipher *security.TokenCipher, id, token string) {
+	t.Helper()
+	rec := makeTestSandboxRecord(id, false, "running")
+	var err error
+	rec.AccessTokenCiphertext, rec.AccessTokenNonce, rec.AccessTokenKeyID, err = cipher.Encrypt(token)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	rec.AccessTokenSHA256 = security.HashToken(token)
+	if err := sandboxStore.Create(context.Background(), rec); err != nil {
+		t.Fatalf("Create sandbox error = %v", err)
+	}
+}
+
+func TestRotateAccessToken(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	sandboxStore := store.NewSandboxStore()
+	seedEncryptedSandbox(t, sandboxStore, cipher, "rot1", "old-token")
+	svc := NewSandboxService(k8s.NewClientForTest(), sandboxStore, cipher)
+
+	sandbox, err := svc.RotateAccessToken(ctx, "rot1", &model.RotateAccessTokenRequest{GracePeriod: 300})
+	if err != nil {
+		t.Fatalf("RotateAccessToken() error = %v", err)
+	}
+	if sandbox.AccessToken == "" || sandbox.AccessToken == "old-token" || sandbox.PreviousAccessTokenExpiresAt == nil {
+		t.Fatalf("RotateAccessToken() = %+v", sandbox)
+	}
+	record, err := sandboxStore.GetByID(ctx, "rot1")
+	if err != nil || record == nil {
+		t.Fatalf("GetByID() = %v, %v", record, err)
+	}
+	if record.AccessTokenSHA256 != security.HashToken(sandbox.AccessToken) || record.PreviousAccessTokenSHA256 != security.HashToken("old-token") {
+		t.Fatalf("token hashes not rotated: %+v", record)
+	}
+	if d := time.Until(*record.PreviousAccessTokenExpiresAt); d <= 4*time.Minute || d > 5*time.Minute {
+		t.Fatalf("previous token expires in %v, want about 5m", d)
+	}
+
+	// Rotating again without a grace period drops the previous token at once.
+	rotated, err := svc.RotateAccessToken(ctx, "rot1", &model.RotateAccessTokenRequest{})
+	if err != nil {
+		t.Fatalf("RotateAccessToken() error = %v", err)
+	}
+	record, _ = sandboxStore.GetByID(ctx, "rot1")
+	if record.PreviousAccessTokenSHA256 != "" || record.PreviousAccessTokenExpiresAt != nil || rotated.PreviousAccessTokenExpiresAt != nil {
+		t.Fatalf("previous token kept without grace period: %+v", record)
+	}
+
+	for _, grace := range []int{-1, 86401} {
+		if _, err := svc.RotateAccessToken(ctx, "rot1", &model.RotateAccessTokenRequest{GracePeriod: grace}); !errors.Is(err, ErrInvalidGracePeriod) {
+			t.Fatalf("RotateAccessToken(grace=%d) error = %v, want ErrInvalidGracePeriod", grace, err)
+		}
+	}
+	if _, err := svc.RotateAccessToken(ctx, "missing", &model.RotateAccessTokenRequest{}); !errors.Is(err, ErrSandboxNotFound) {
+		t.Fatalf("RotateAccessToken(missing) error = %v", err)
+	}
+}
+
+func TestGetHidesAccessTokenFromViewers(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	sandboxStore := store.NewSandboxStore()
+	seedEncryptedSandbox(t, sandboxStore, cipher, "view1", "secret-token")
+	svc := NewSandboxService(k8s.NewClientForTest(), sandboxStore, cipher)
+
+	tests := []struct {
+		role model.Role
+		want string
+	}{
+		{model.RoleViewer, ""},
+		{model.RoleDeveloper, "secret-token"},
+		{model.RoleAdmin, "secret-token"},
+	}
+	for _, tt := range tests {
+		sandbox, err := svc.Get(auth.WithPrincipal(ctx, &auth.Principal{UserID: "u1", Role: tt.role}), "view1")
+		if err != nil {
+			t.Fatalf("Get() as %s error = %v", tt.role, err)
+		}
+		if sandbox.AccessToken != tt.want {
+			t.Fatalf("Get() as %s AccessToken = %q, want %q", tt.role, sandbox.AccessToken, tt.want)
+		}
+	}
+}
+
+func TestReencryptAccessTokensMovesRowsToCurrentKey(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	t.Setenv(security.TokenEncryptionKeyEnv, "0123456789abcdef")
+	t.Setenv(security.TokenEncryptionKeyIDEnv, "k1")
+	oldCipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	sandboxStore := store.NewSandboxStore()
+	seedEncryptedSandbox(t, sandboxStore, oldCipher, "key1", "token-1")
+	seedEncryptedSandbox(t, sandboxStore, oldCipher, "key2", "token-2")
+
+	t.Setenv(security.TokenEncryptionKeyEnv, "fedcba9876543210")
+	t.Setenv(security.TokenEncryptionKeyIDEnv, "k2")
+	t.Setenv(security.TokenEncryptionPreviousKeysEnv, "k1:0123456789abcdef")
+	cipher, err := security.NewTokenCipherFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenCipherFromEnv() error = %v", err)
+	}
+	svc := NewSandboxService(k8s.NewClientForTest(), sandboxStore, cipher)
+
+	// Tokens under a previous key are readable before they are re-encrypted.
+	if sandbox, err := svc.Get(ctx, "key1"); err != nil || sandbox.AccessToken != "token-1" {
+		t.Fatalf("Get() before re-encryption = %+v, %v", sandbox, err)
+	}
+
+	n, err := svc.ReencryptAccessTokens(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("ReencryptAccessTokens() = %d, %v; want 2", n, err)
+	}
+	if n, err := svc.ReencryptAccessTokens(ctx); err != nil || n != 0 {
+		t.Fatalf("second ReencryptAccessTokens() = %d, %v; want 0", n, err)
+	}
+	for id, token := range map[string]string{"key1": "token-1", "key2": "token-2"} {
+		record, _ := sandboxStore.GetByID(ctx, id)
+		if record.AccessTokenKeyID != "k2" {
+			t.Fatalf("%s still under key %q", id, record.AccessTokenKeyID)
+		}
+		plain, err := cipher.Decrypt(record.AccessTokenCiphertext, record.AccessTokenNonce)
+		if err != nil || plain != token {
+			t.Fatalf("%s decrypts to %q, %v", id, plain, err)
+		}
+	}
+}
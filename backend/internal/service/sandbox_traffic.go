@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+// SandboxTrafficService reports the traffic counters the gateway keeps per sandbox.
+type SandboxTrafficService struct {
+	sandboxStore *store.SandboxStore
+	trafficStore *store.SandboxTrafficStore
+}
+
+func NewSandboxTrafficService(sandboxStore *store.SandboxStore, trafficStore *store.SandboxTrafficStore) *SandboxTrafficService {
+	return &SandboxTrafficService{
+		sandboxStore: sandboxStore,
+		trafficStore: trafficStore,
+	}
+}
+
+// Get returns the traffic of a sandbox. Deleted sandboxes keep their counters until
+// their metadata is purged.
+func (s *SandboxTrafficService) Get(ctx context.Context, sandboxID string) (*model.SandboxTraffic, error) {
+	sandbox, err := s.sandboxStore.GetByID(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	if sandbox == nil {
+		return nil, ErrSandboxNotFound
+	}
+
+	traffic := &model.SandboxTraffic{SandboxID: sandboxID}
+	rec, err := s.trafficStore.Get(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	if rec != nil {
+		traffic.BytesIn = rec.BytesIn
+		traffic.BytesOut = rec.BytesOut
+		traffic.UpdatedAt = &rec.UpdatedAt
+	}
+	return traffic, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+// DefaultMaxTTL bounds how far ahead ExtendTTL may push an expiry.
+const DefaultMaxTTL = 24 * time.Hour
+
+// idleTTLKeepAliveInterval is how often long exec sessions count as activity.
+const idleTTLKeepAliveInterval = time.Minute
+
+// SetMaxTTL sets how far ahead ExtendTTL may push an expiry. Zero disables the bound.
+func (s *SandboxService) SetMaxTTL(d time.Duration) {
+	s.maxTTL = d
+}
+
+// ExtendTTL sets a new expiry for a sandbox, either TTL seconds from now or an absolute
+// time, bounded by the server's max TTL. Stopped and paused sandboxes count the new TTL
+// from the moment they stopped, since their TTL clock is frozen until they run again.
+func (s *SandboxService) ExtendTTL(ctx context.Context, id string, req *model.ExtendTTLRequest) (*model.Sandbox, error) {
+	if (req.TTL > 0) == (req.ExpiresAt != nil) {
+		return nil, fmt.Errorf("%w: set exactly one of ttl or expiresAt", ErrInvalidTTL)
+	}
+	if req.TTL < 0 {
+		return nil, fmt.Errorf("%w: ttl must be positive", ErrInvalidTTL)
+	}
+
+	record, err := s.sandboxStore.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.LifecycleStatus == "deleted" || record.DesiredState == store.DesiredStateDeleted {
+		return nil, ErrSandboxNotFound
+	}
+	if record.TTL == 0 {
+		return nil, ErrSandboxTTLDisabled
+	}
+
+	now := time.Now().UTC()
+	ttl := time.Duration(req.TTL) * time.Second
+	if req.ExpiresAt != nil {
+		ttl = req.ExpiresAt.Sub(now)
+		if ttl <= 0 {
+			return nil, fmt.Errorf("%w: expiresAt must be in the future", ErrInvalidTTL)
+		}
+	}
+	if s.maxTTL > 0 && ttl > s.maxTTL {
+		return nil, fmt.Errorf("%w: at most %s ahead is allowed", ErrInvalidTTL, s.maxTTL)
+	}
+	if s.quotaSvc != nil {
+		subjects := quotaSubjects(record.OwnerID, record.OwnerAPIKeyID, record.Project)
+		if err := s.quotaSvc.CheckTTL(ctx, subjects, int(ttl/time.Second)); err != nil {
+			return nil, err
+		}
+	}
+
+	base := now
+	if record.StoppedAt != nil && (record.LifecycleStatus == "stopped" || record.LifecycleStatus == "paused") {
+		base = *record.StoppedAt
+	}
+	expiresAt := base.Add(ttl)
+	if err := s.sandboxStore.SetExpiresAt(ctx, id, expiresAt, now); err != nil {
+		return nil, err
+	}
+	record.ExpiresAt = expiresAt
+	record.UpdatedAt = now
+	sandbox := s.recordToSandboxMetadata(record)
+	return &sandbox, nil
+}
+
+// touchIdleTTL counts exec activity towards the idle TTL of a sandbox.
+func (s *SandboxService) touchIdleTTL(ctx context.Context, id string) {
+	record, err := s.sandboxStore.GetByID(ctx, id)
+	if err != nil || record == nil {
+		return
+	}
+	if err := s.sandboxStore.TouchIdleTTL(ctx, record, time.Now().UTC()); err != nil {
+		logWithSandboxID(ctx, id).Warn("failed to extend idle ttl", "error", err)
+	}
+}
+
+// keepIdleTTLAlive counts a long-running exec session as activity for as long as it
+// lasts. The returned function ends it.
+func (s *SandboxService) keepIdleTTLAlive(ctx context.Context, id string) func() {
+	s.touchIdleTTL(ctx, id)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(idleTTLKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.touchIdleTTL(ctx, id)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
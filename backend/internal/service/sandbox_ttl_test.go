@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
+)
+
+func TestExtendTTLValidation(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	sandboxStore := store.NewSandboxStore()
+
+	forever := makeTestSandboxRecord("ttl-forever", false, "running")
+	forever.TTL = 0
+	for _, rec := range []*store.SandboxRecord{makeTestSandboxRecord("ttl-run", false, "running"), forever} {
+		if err := sandboxStore.Create(ctx, rec); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	svc := &SandboxService{sandboxStore: sandboxStore, maxTTL: 2 * time.Hour}
+	past := time.Now().Add(-time.Minute)
+	cases := []struct {
+		id   string
+		req  model.ExtendTTLRequest
+		want error
+	}{
+		{"ttl-run", model.ExtendTTLRequest{}, ErrInvalidTTL},
+		{"ttl-run", model.ExtendTTLRequest{TTL: 60, ExpiresAt: &past}, ErrInvalidTTL},
+		{"ttl-run", model.ExtendTTLRequest{ExpiresAt: &past}, ErrInvalidTTL},
+		{"ttl-run", model.ExtendTTLRequest{TTL: 3 * 3600}, ErrInvalidTTL},
+		{"ttl-forever", model.ExtendTTLRequest{TTL: 60}, ErrSandboxTTLDisabled},
+		{"missing", model.ExtendTTLRequest{TTL: 60}, ErrSandboxNotFound},
+	}
+	for _, tc := range cases {
+		if _, err := svc.ExtendTTL(ctx, tc.id, &tc.req); !errors.Is(err, tc.want) {
+			t.Errorf("ExtendTTL(%s, %+v) error = %v, want %v", tc.id, tc.req, err, tc.want)
+		}
+	}
+}
+
+func TestExtendTTLCountsFromStopForFrozenSandboxes(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+	sandboxStore := store.NewSandboxStore()
+
+	now := time.Now().UTC()
+	pausedAt := now.Add(-20 * time.Minute)
+	paused := makeTestSandboxRecord("ttl-paused", false, "paused")
+	paused.StoppedAt = &pausedAt
+	for _, rec := range []*store.SandboxRecord{makeTestSandboxRecord("ttl-running", false, "running"), paused} {
+		if err := sandboxStore.Create(ctx, rec); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	svc := &SandboxService{sandboxStore: sandboxStore, maxTTL: DefaultMaxTTL}
+	want := map[string]time.Time{
+		"ttl-running": now.Add(time.Hour),
+		"ttl-paused":  pausedAt.Add(time.Hour),
+	}
+	for id, expires := range want {
+		sandbox, err := svc.ExtendTTL(ctx, id, &model.ExtendTTLRequest{TTL: 3600})
+		if err != nil {
+			t.Fatalf("ExtendTTL(%s) error = %v", id, err)
+		}
+		got, err := sandboxStore.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.ExpiresAt.Sub(expires).Abs() > 5*time.Second || !sandbox.ExpiresAt.Equal(got.ExpiresAt) {
+			t.Errorf("%s ExpiresAt = %v (response %v), want ~%v", id, got.ExpiresAt, sandbox.ExpiresAt, expires)
+		}
+	}
+}
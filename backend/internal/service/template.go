@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
+	"net"
 	"regexp"
 	"strings"
 
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
 	"github.com/fslongjin/liteboxd/backend/internal/model"
 	"github.com/fslongjin/liteboxd/backend/internal/store"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -13,14 +15,16 @@ import (
 
 // TemplateService handles template business logic
 type TemplateService struct {
-	store      *store.TemplateStore
-	prepullSvc *PrepullService
+	store        *store.TemplateStore
+	projectStore *store.ProjectStore
+	prepullSvc   *PrepullService
 }
 
 // NewTemplateService creates a new TemplateService
 func NewTemplateService() *TemplateService {
 	return &TemplateService{
-		store: store.NewTemplateStore(),
+		store:        store.NewTemplateStore(),
+		projectStore: store.NewProjectStore(),
 	}
 }
 
@@ -48,15 +52,43 @@ func validateSpec(spec *model.TemplateSpec) error {
 	if spec.Image == "" {
 		return fmt.Errorf("image is required")
 	}
+	if err := validateTTLMode(spec.TTLMode); err != nil {
+		return err
+	}
 	if err := validatePersistenceSpec(spec.Persistence); err != nil {
 		return err
 	}
 	if err := validateNetworkSpec(spec.Network); err != nil {
 		return err
 	}
+	if err := validatePoolSize(spec); err != nil {
+		return err
+	}
 	return nil
 }
 
+// maxPoolSize bounds the warm pool of a single template.
+const maxPoolSize = 50
+
+func validatePoolSize(spec *model.TemplateSpec) error {
+	if spec.PoolSize < 0 || spec.PoolSize > maxPoolSize {
+		return fmt.Errorf("poolSize must be between 0 and %d", maxPoolSize)
+	}
+	if spec.PoolSize > 0 && spec.Persistence != nil && spec.Persistence.Enabled {
+		return fmt.Errorf("poolSize is not supported when persistence.enabled=true")
+	}
+	return nil
+}
+
+func validateTTLMode(mode model.TTLMode) error {
+	switch mode {
+	case "", model.TTLModeFixed, model.TTLModeIdle:
+		return nil
+	default:
+		return fmt.Errorf("ttlMode must be %q or %q", model.TTLModeFixed, model.TTLModeIdle)
+	}
+}
+
 func validatePersistenceSpec(spec *model.PersistenceSpec) error {
 	if spec == nil || !spec.Enabled {
 		return nil
@@ -106,9 +138,100 @@ func validateNetworkSpec(spec *model.NetworkSpec) error {
 	// Normalize and validate domains, but don't enforce AllowInternetAccess requirement.
 	// The domains are stored but only applied when AllowInternetAccess is true.
 	spec.AllowedDomains = normalized
+
+	if spec.Ports, err = normalizePorts(spec.Ports, "ports"); err != nil {
+		return err
+	}
+	for i := range spec.DomainRules {
+		rule := &spec.DomainRules[i]
+		if len(rule.Domains) == 0 {
+			return fmt.Errorf("domainRules[%d].domains is required", i)
+		}
+		if rule.Domains, err = normalizeAllowedDomains(rule.Domains); err != nil {
+			return fmt.Errorf("domainRules[%d]: %w", i, err)
+		}
+		if rule.Ports, err = normalizePorts(rule.Ports, fmt.Sprintf("domainRules[%d].ports", i)); err != nil {
+			return err
+		}
+	}
+	for i := range spec.AllowedCIDRs {
+		rule := &spec.AllowedCIDRs[i]
+		if rule.CIDR, err = normalizeCIDR(rule.CIDR); err != nil {
+			return fmt.Errorf("allowedCIDRs[%d]: %w", i, err)
+		}
+		if rule.Ports, err = normalizePorts(rule.Ports, fmt.Sprintf("allowedCIDRs[%d].ports", i)); err != nil {
+			return err
+		}
+	}
+	for i, cidr := range spec.DeniedCIDRs {
+		if spec.DeniedCIDRs[i], err = normalizeCIDR(cidr); err != nil {
+			return fmt.Errorf("deniedCIDRs[%d]: %w", i, err)
+		}
+	}
+	if spec.Bandwidth != nil {
+		if spec.Bandwidth.Egress, err = normalizeBandwidth(spec.Bandwidth.Egress, "bandwidth.egress"); err != nil {
+			return err
+		}
+		if spec.Bandwidth.Ingress, err = normalizeBandwidth(spec.Bandwidth.Ingress, "bandwidth.ingress"); err != nil {
+			return err
+		}
+		if spec.Bandwidth.Egress == "" && spec.Bandwidth.Ingress == "" {
+			spec.Bandwidth = nil
+		}
+	}
 	return nil
 }
 
+// Bounds the kubelet accepts for pod bandwidth annotations
+var (
+	minBandwidth = resource.MustParse("1k")
+	maxBandwidth = resource.MustParse("1P")
+)
+
+// normalizeBandwidth validates a rate in bits per second and returns its canonical form.
+func normalizeBandwidth(value, field string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return "", fmt.Errorf("%s %q is not a valid quantity", field, value)
+	}
+	if quantity.Cmp(minBandwidth) < 0 || quantity.Cmp(maxBandwidth) > 0 {
+		return "", fmt.Errorf("%s must be between 1k and 1P", field)
+	}
+	return quantity.String(), nil
+}
+
+// normalizePorts validates port rules and fills in the default TCP protocol.
+func normalizePorts(ports []model.PortRule, field string) ([]model.PortRule, error) {
+	for i := range ports {
+		if ports[i].Port < 1 || ports[i].Port > 65535 {
+			return nil, fmt.Errorf("%s[%d].port must be between 1 and 65535", field, i)
+		}
+		protocol := strings.ToUpper(strings.TrimSpace(ports[i].Protocol))
+		switch protocol {
+		case "":
+			protocol = model.PortProtocolTCP
+		case model.PortProtocolTCP, model.PortProtocolUDP, model.PortProtocolAny:
+		default:
+			return nil, fmt.Errorf("%s[%d].protocol must be one of %q, %q or %q", field, i, model.PortProtocolTCP, model.PortProtocolUDP, model.PortProtocolAny)
+		}
+		ports[i].Protocol = protocol
+	}
+	return ports, nil
+}
+
+// normalizeCIDR validates an IPv4 or IPv6 CIDR and returns it in canonical form.
+func normalizeCIDR(value string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("invalid CIDR: %s", value)
+	}
+	return ipNet.String(), nil
+}
+
 func normalizeAllowedDomains(domains []string) ([]string, error) {
 	seen := make(map[string]struct{})
 	var normalized []string
@@ -184,6 +307,28 @@ func (s *TemplateService) Create(ctx context.Context, req *model.CreateTemplateR
 		return nil, fmt.Errorf("invalid spec: %w", err)
 	}
 
+	if !auth.PrincipalFromContext(ctx).AllowsTemplate(req.Name) {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotAllowed, req.Name)
+	}
+
+	// API keys scoped to a project create templates of that project
+	if scope := projectScope(ctx); scope != "" {
+		if req.Project == "" {
+			req.Project = scope
+		} else if req.Project != scope {
+			return nil, fmt.Errorf("%w: %s", ErrProjectScope, scope)
+		}
+	}
+	if req.Project != "" {
+		project, err := s.projectStore.GetByName(ctx, req.Project)
+		if err != nil {
+			return nil, err
+		}
+		if project == nil {
+			return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, req.Project)
+		}
+	}
+
 	// Check if name already exists
 	exists, err := s.store.Exists(ctx, req.Name)
 	if err != nil {
@@ -209,13 +354,24 @@ func (s *TemplateService) Create(ctx context.Context, req *model.CreateTemplateR
 	return template, nil
 }
 
-// Get retrieves a template by name
+// Get retrieves a template by name. Templates of other projects than the one the
+// caller's API key is scoped to are reported as missing.
 func (s *TemplateService) Get(ctx context.Context, name string) (*model.Template, error) {
-	return s.store.Get(ctx, name)
+	template, err := s.store.Get(ctx, name)
+	if err != nil || template == nil || !templateVisible(ctx, template) {
+		return nil, err
+	}
+	return template, nil
 }
 
 // List returns a paginated list of templates
 func (s *TemplateService) List(ctx context.Context, opts model.TemplateListOptions) (*model.TemplateListResponse, error) {
+	if scope := projectScope(ctx); scope != "" {
+		opts.Project = scope
+	}
+	if p := auth.PrincipalFromContext(ctx); p != nil && len(p.Templates) > 0 {
+		opts.Names = p.Templates
+	}
 	return s.store.List(ctx, opts)
 }
 
@@ -225,15 +381,44 @@ func (s *TemplateService) Update(ctx context.Context, name string, req *model.Up
 	if err := validateSpec(&req.Spec); err != nil {
 		return nil, fmt.Errorf("invalid spec: %w", err)
 	}
+	if err := s.checkVisible(ctx, name); err != nil {
+		return nil, err
+	}
 
 	return s.store.Update(ctx, name, req)
 }
 
 // Delete deletes a template
 func (s *TemplateService) Delete(ctx context.Context, name string) error {
+	if err := s.checkVisible(ctx, name); err != nil {
+		return err
+	}
 	return s.store.Delete(ctx, name)
 }
 
+// checkVisible reports templates hidden from the caller as not found.
+func (s *TemplateService) checkVisible(ctx context.Context, name string) error {
+	template, err := s.store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if template != nil && !templateVisible(ctx, template) {
+		return fmt.Errorf("template not found")
+	}
+	return nil
+}
+
+// templateVisible reports whether the caller may use a template: global templates are
+// visible to all, project templates only outside projects or within their own. API keys
+// with a template allowlist only see the templates on it.
+func templateVisible(ctx context.Context, template *model.Template) bool {
+	if !auth.PrincipalFromContext(ctx).AllowsTemplate(template.Name) {
+		return false
+	}
+	scope := projectScope(ctx)
+	return template.Project == "" || scope == "" || template.Project == scope
+}
+
 // GetVersion retrieves a specific version of a template
 func (s *TemplateService) GetVersion(ctx context.Context, name string, version int) (*model.TemplateVersion, error) {
 	return s.store.GetVersionByName(ctx, name, version)
@@ -249,13 +434,16 @@ func (s *TemplateService) Rollback(ctx context.Context, name string, req *model.
 	if req.TargetVersion < 1 {
 		return nil, fmt.Errorf("target version must be at least 1")
 	}
+	if err := s.checkVisible(ctx, name); err != nil {
+		return nil, err
+	}
 	return s.store.Rollback(ctx, name, req.TargetVersion, req.Changelog)
 }
 
 // GetSpecForSandbox retrieves the template spec for creating a sandbox
 // If version is 0, it returns the latest version
 func (s *TemplateService) GetSpecForSandbox(ctx context.Context, name string, version int) (*model.TemplateSpec, error) {
-	template, err := s.store.Get(ctx, name)
+	template, err := s.Get(ctx, name)
 	if err != nil {
 		return nil, err
 	}
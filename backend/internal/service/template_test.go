@@ -1,10 +1,15 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/fslongjin/liteboxd/backend/internal/auth"
+	"github.com/fslongjin/liteboxd/backend/internal/k8s"
 	"github.com/fslongjin/liteboxd/backend/internal/model"
+	"github.com/fslongjin/liteboxd/backend/internal/store"
 )
 
 func TestNormalizeAllowedDomains(t *testing.T) {
@@ -147,3 +152,110 @@ func TestValidateSpecPersistentTemplateWithoutCommand(t *testing.T) {
 		t.Fatalf("expected validateSpec success for persistent template without command, got: %v", err)
 	}
 }
+
+func TestValidatePoolSize(t *testing.T) {
+	if err := validatePoolSize(&model.TemplateSpec{PoolSize: 3}); err != nil {
+		t.Fatalf("expected poolSize 3 to be valid, got: %v", err)
+	}
+	if err := validatePoolSize(&model.TemplateSpec{PoolSize: maxPoolSize + 1}); err == nil {
+		t.Fatalf("expected poolSize above the maximum to be rejected")
+	}
+	spec := &model.TemplateSpec{
+		PoolSize:    1,
+		Persistence: &model.PersistenceSpec{Enabled: true},
+	}
+	if err := validatePoolSize(spec); err == nil {
+		t.Fatalf("expected poolSize to be rejected for persistent templates")
+	}
+}
+
+func TestValidateNetworkSpecPortsAndCIDRs(t *testing.T) {
+	spec := &model.NetworkSpec{
+		AllowInternetAccess: true,
+		Ports:               []model.PortRule{{Port: 8080}},
+		DomainRules:         []model.DomainRule{{Domains: []string{"GitHub.com"}, Ports: []model.PortRule{{Port: 22, Protocol: "tcp"}}}},
+		AllowedCIDRs:        []model.CIDRRule{{CIDR: "10.20.3.4/16", Ports: []model.PortRule{{Port: 5432}}}},
+		DeniedCIDRs:         []string{"10.20.5.0/24"},
+	}
+	if err := validateNetworkSpec(spec); err != nil {
+		t.Fatalf("validateNetworkSpec() error = %v", err)
+	}
+	if spec.Ports[0].Protocol != model.PortProtocolTCP || spec.DomainRules[0].Ports[0].Protocol != model.PortProtocolTCP {
+		t.Fatalf("ports were not normalized: %+v %+v", spec.Ports, spec.DomainRules[0].Ports)
+	}
+	if spec.DomainRules[0].Domains[0] != "github.com" || spec.AllowedCIDRs[0].CIDR != "10.20.0.0/16" {
+		t.Fatalf("rules were not normalized: %+v %+v", spec.DomainRules, spec.AllowedCIDRs)
+	}
+
+	invalid := []*model.NetworkSpec{
+		{Ports: []model.PortRule{{Port: 0}}},
+		{Ports: []model.PortRule{{Port: 22, Protocol: "ICMP"}}},
+		{DomainRules: []model.DomainRule{{Ports: []model.PortRule{{Port: 22}}}}},
+		{AllowedCIDRs: []model.CIDRRule{{CIDR: "10.20.0.0"}}},
+		{DeniedCIDRs: []string{"not-a-cidr"}},
+	}
+	for i, spec := range invalid {
+		if err := validateNetworkSpec(spec); err == nil {
+			t.Fatalf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestValidateNetworkSpecLimits(t *testing.T) {
+	spec := &model.NetworkSpec{Bandwidth: &model.BandwidthSpec{Egress: " 10000k ", Ingress: "1G"}}
+	if err := validateNetworkSpec(spec); err != nil {
+		t.Fatalf("validateNetworkSpec() error = %v", err)
+	}
+	if spec.Bandwidth.Egress != "10M" || spec.Bandwidth.Ingress != "1G" {
+		t.Fatalf("bandwidth was not normalized: %+v", spec.Bandwidth)
+	}
+	empty := &model.NetworkSpec{Bandwidth: &model.BandwidthSpec{}}
+	if err := validateNetworkSpec(empty); err != nil || empty.Bandwidth != nil {
+		t.Fatalf("empty bandwidth = %+v, %v; want it dropped", empty.Bandwidth, err)
+	}
+
+	invalid := []*model.NetworkSpec{
+		{Bandwidth: &model.BandwidthSpec{Egress: "fast"}},
+		{Bandwidth: &model.BandwidthSpec{Egress: "100"}},
+		{Bandwidth: &model.BandwidthSpec{Ingress: "2P"}},
+	}
+	for i, spec := range invalid {
+		if err := validateNetworkSpec(spec); err == nil {
+			t.Fatalf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestTemplateAllowlistOfAPIKey(t *testing.T) {
+	initServiceTestDB(t)
+	ctx := context.Background()
+
+	templateSvc := NewTemplateService()
+	spec := model.TemplateSpec{Image: "busybox:1.36", Command: []string{"sh", "-c", "sleep 30"}, StartupTimeout: 1}
+	for _, name := range []string{"python", "node"} {
+		if _, err := templateSvc.Create(ctx, &model.CreateTemplateRequest{Name: name, Spec: spec}); err != nil {
+			t.Fatalf("Create template %s error = %v", name, err)
+		}
+	}
+
+	ci := auth.WithPrincipal(ctx, &auth.Principal{UserID: "u-ci", Username: "ci", Role: model.RoleDeveloper, Templates: []string{"python"}})
+	if _, err := templateSvc.Create(ci, &model.CreateTemplateRequest{Name: "ruby", Spec: spec}); !errors.Is(err, ErrTemplateNotAllowed) {
+		t.Fatalf("Create() of a template off the allowlist error = %v, want ErrTemplateNotAllowed", err)
+	}
+	list, err := templateSvc.List(ci, model.TemplateListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "python" {
+		t.Fatalf("List() = %+v, want only python", list.Items)
+	}
+	if tpl, err := templateSvc.Get(ci, "node"); err != nil || tpl != nil {
+		t.Fatalf("Get(node) = %+v, %v, want nil", tpl, err)
+	}
+
+	svc := NewSandboxService(k8s.NewClientForTest(), store.NewSandboxStore(), nil)
+	svc.SetTemplateService(templateSvc)
+	if _, err := svc.Create(ci, &model.CreateSandboxRequest{Template: "node"}); !errors.Is(err, ErrTemplateNotAllowed) {
+		t.Fatalf("Create() from a template off the allowlist error = %v, want ErrTemplateNotAllowed", err)
+	}
+}
@@ -3,15 +3,18 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
 
-// AdminUserRecord represents an admin user in the database.
-type AdminUserRecord struct {
+// UserRecord represents a user in the database.
+type UserRecord struct {
 	ID           string
 	Username     string
 	PasswordHash string
+	Role         string
+	Disabled     bool
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
@@ -27,12 +30,20 @@ type SessionRecord struct {
 // APIKeyRecord represents an API key in the database.
 type APIKeyRecord struct {
 	ID         string
+	UserID     string
 	Name       string
 	Prefix     string
 	KeyHash    string
 	ExpiresAt  *time.Time
 	LastUsedAt *time.Time
 	CreatedAt  time.Time
+	// Project limits the key to the sandboxes and templates of one project
+	Project string
+	// Scopes limit the requests the key can make, Templates the templates it can use
+	// and AllowedCIDRs the addresses it can be used from. Empty lists don't limit.
+	Scopes       []string
+	Templates    []string
+	AllowedCIDRs []string
 }
 
 // AuthStore handles authentication-related persistence.
@@ -45,72 +56,125 @@ func NewAuthStore() *AuthStore {
 	return &AuthStore{db: DB}
 }
 
-// --- Admin methods ---
+// --- User methods ---
 
-// GetAdminByUsername returns the admin user with the given username, or nil if not found.
-func (s *AuthStore) GetAdminByUsername(ctx context.Context, username string) (*AdminUserRecord, error) {
-	row := s.db.QueryRowContext(ctx, `
-		SELECT id, username, password_hash, created_at, updated_at
-		FROM admin_users WHERE username = ?
-	`, username)
+const userColumns = "id, username, password_hash, role, disabled, created_at, updated_at"
+
+func scanUser(scanner interface{ Scan(dest ...any) error }) (*UserRecord, error) {
+	var rec UserRecord
+	if err := scanner.Scan(&rec.ID, &rec.Username, &rec.PasswordHash, &rec.Role, &rec.Disabled, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
 
-	var rec AdminUserRecord
-	err := row.Scan(&rec.ID, &rec.Username, &rec.PasswordHash, &rec.CreatedAt, &rec.UpdatedAt)
+// GetUserByUsername returns the user with the given username, or nil if not found.
+func (s *AuthStore) GetUserByUsername(ctx context.Context, username string) (*UserRecord, error) {
+	rec, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM admin_users WHERE username = ?`, username))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to query admin user: %w", err)
+		return nil, fmt.Errorf("failed to query user: %w", err)
 	}
-	return &rec, nil
+	return rec, nil
 }
 
-// GetAdminByID returns the admin user with the given ID, or nil if not found.
-func (s *AuthStore) GetAdminByID(ctx context.Context, id string) (*AdminUserRecord, error) {
-	row := s.db.QueryRowContext(ctx, `
-		SELECT id, username, password_hash, created_at, updated_at
-		FROM admin_users WHERE id = ?
-	`, id)
-
-	var rec AdminUserRecord
-	err := row.Scan(&rec.ID, &rec.Username, &rec.PasswordHash, &rec.CreatedAt, &rec.UpdatedAt)
+// GetUserByID returns the user with the given ID, or nil if not found.
+func (s *AuthStore) GetUserByID(ctx context.Context, id string) (*UserRecord, error) {
+	rec, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM admin_users WHERE id = ?`, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to query admin user by id: %w", err)
+		return nil, fmt.Errorf("failed to query user by id: %w", err)
 	}
-	return &rec, nil
+	return rec, nil
 }
 
-// CreateAdmin inserts a new admin user record.
-func (s *AuthStore) CreateAdmin(ctx context.Context, rec *AdminUserRecord) error {
+// ListUsers returns all users ordered by username.
+func (s *AuthStore) ListUsers(ctx context.Context) ([]UserRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+userColumns+` FROM admin_users ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []UserRecord
+	for rows.Next() {
+		rec, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *rec)
+	}
+	return users, rows.Err()
+}
+
+// CreateUser inserts a new user record.
+func (s *AuthStore) CreateUser(ctx context.Context, rec *UserRecord) error {
 	now := time.Now()
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO admin_users (id, username, password_hash, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, rec.ID, rec.Username, rec.PasswordHash, now, now)
+		INSERT INTO admin_users (id, username, password_hash, role, disabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.Username, rec.PasswordHash, rec.Role, rec.Disabled, now, now)
 	if err != nil {
-		return fmt.Errorf("failed to create admin user: %w", err)
+		return fmt.Errorf("failed to create user: %w", err)
 	}
+	rec.CreatedAt = now
+	rec.UpdatedAt = now
 	return nil
 }
 
-// UpdateAdminPassword updates the password hash for the given admin user.
-func (s *AuthStore) UpdateAdminPassword(ctx context.Context, id, passwordHash string) error {
+// UpdateUser saves the role and disabled flag of a user.
+func (s *AuthStore) UpdateUser(ctx context.Context, rec *UserRecord) error {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE admin_users SET role = ?, disabled = ?, updated_at = ? WHERE id = ?
+	`, rec.Role, rec.Disabled, now, rec.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	rec.UpdatedAt = now
+	return nil
+}
+
+// UpdateUserPassword updates the password hash for the given user.
+func (s *AuthStore) UpdateUserPassword(ctx context.Context, id, passwordHash string) error {
 	_, err := s.db.ExecContext(ctx, `
 		UPDATE admin_users SET password_hash = ?, updated_at = ? WHERE id = ?
 	`, passwordHash, time.Now(), id)
 	if err != nil {
-		return fmt.Errorf("failed to update admin password: %w", err)
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser deletes a user together with their sessions and API keys.
+func (s *AuthStore) DeleteUser(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM admin_users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
 	}
 	return nil
 }
 
-// AdminCount returns the number of admin users in the database.
-func (s *AuthStore) AdminCount(ctx context.Context) (int, error) {
+// UserCount returns the number of users in the database.
+func (s *AuthStore) UserCount(ctx context.Context) (int, error) {
 	var count int
 	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM admin_users`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// ActiveAdminCount returns the number of admins that are not disabled.
+func (s *AuthStore) ActiveAdminCount(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM admin_users WHERE role = 'admin' AND disabled = 0
+	`).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count admin users: %w", err)
 	}
@@ -159,6 +223,15 @@ func (s *AuthStore) DeleteSession(ctx context.Context, tokenHash string) error {
 	return nil
 }
 
+// DeleteUserSessions deletes all sessions of a user.
+func (s *AuthStore) DeleteUserSessions(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	return nil
+}
+
 // DeleteExpiredSessions removes all sessions that have expired before the given time.
 func (s *AuthStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
 	result, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < ?`, now)
@@ -174,9 +247,10 @@ func (s *AuthStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (i
 func (s *AuthStore) CreateAPIKey(ctx context.Context, rec *APIKeyRecord) error {
 	now := time.Now()
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO api_keys (id, name, prefix, key_hash, expires_at, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, rec.ID, rec.Name, rec.Prefix, rec.KeyHash, toNullTime(rec.ExpiresAt), now)
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, expires_at, created_at, project, scopes, templates, allowed_cidrs)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.UserID, rec.Name, rec.Prefix, rec.KeyHash, toNullTime(rec.ExpiresAt), now, rec.Project,
+		marshalStringList(rec.Scopes), marshalStringList(rec.Templates), marshalStringList(rec.AllowedCIDRs))
 	if err != nil {
 		return fmt.Errorf("failed to create api key: %w", err)
 	}
@@ -184,21 +258,16 @@ func (s *AuthStore) CreateAPIKey(ctx context.Context, rec *APIKeyRecord) error {
 	return nil
 }
 
-// GetAPIKeyByHash returns the API key with the given key hash, or nil if not found.
-func (s *AuthStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKeyRecord, error) {
-	row := s.db.QueryRowContext(ctx, `
-		SELECT id, name, prefix, key_hash, expires_at, last_used_at, created_at
-		FROM api_keys WHERE key_hash = ?
-	`, keyHash)
+const apiKeyColumns = `id, COALESCE(user_id, ''), name, prefix, key_hash, expires_at, last_used_at, created_at, project,
+	scopes, templates, allowed_cidrs`
 
+func scanAPIKey(scanner interface{ Scan(dest ...any) error }) (*APIKeyRecord, error) {
 	var rec APIKeyRecord
 	var expiresAt, lastUsedAt sql.NullTime
-	err := row.Scan(&rec.ID, &rec.Name, &rec.Prefix, &rec.KeyHash, &expiresAt, &lastUsedAt, &rec.CreatedAt)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to query api key by hash: %w", err)
+	var scopes, templates, allowedCIDRs string
+	if err := scanner.Scan(&rec.ID, &rec.UserID, &rec.Name, &rec.Prefix, &rec.KeyHash, &expiresAt, &lastUsedAt, &rec.CreatedAt, &rec.Project,
+		&scopes, &templates, &allowedCIDRs); err != nil {
+		return nil, err
 	}
 	if expiresAt.Valid {
 		rec.ExpiresAt = &expiresAt.Time
@@ -206,15 +275,41 @@ func (s *AuthStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKe
 	if lastUsedAt.Valid {
 		rec.LastUsedAt = &lastUsedAt.Time
 	}
+	rec.Scopes, rec.Templates, rec.AllowedCIDRs = unmarshalStringList(scopes), unmarshalStringList(templates), unmarshalStringList(allowedCIDRs)
 	return &rec, nil
 }
 
-// ListAPIKeys returns all API keys (without hash values).
-func (s *AuthStore) ListAPIKeys(ctx context.Context) ([]APIKeyRecord, error) {
+// GetAPIKeyByHash returns the API key with the given key hash, or nil if not found.
+func (s *AuthStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKeyRecord, error) {
+	rec, err := scanAPIKey(s.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key by hash: %w", err)
+	}
+	return rec, nil
+}
+
+// GetAPIKeyByID returns the API key with the given ID, or nil if not found.
+func (s *AuthStore) GetAPIKeyByID(ctx context.Context, id string) (*APIKeyRecord, error) {
+	rec, err := scanAPIKey(s.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key by id: %w", err)
+	}
+	return rec, nil
+}
+
+// ListAPIKeys returns the API keys of a user (without hash values).
+func (s *AuthStore) ListAPIKeys(ctx context.Context, userID string) ([]APIKeyRecord, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, name, prefix, expires_at, last_used_at, created_at
-		FROM api_keys ORDER BY created_at DESC
-	`)
+		SELECT id, user_id, name, prefix, expires_at, last_used_at, created_at, project,
+			scopes, templates, allowed_cidrs
+		FROM api_keys WHERE user_id = ? ORDER BY created_at DESC
+	`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list api keys: %w", err)
 	}
@@ -224,9 +319,12 @@ func (s *AuthStore) ListAPIKeys(ctx context.Context) ([]APIKeyRecord, error) {
 	for rows.Next() {
 		var rec APIKeyRecord
 		var expiresAt, lastUsedAt sql.NullTime
-		if err := rows.Scan(&rec.ID, &rec.Name, &rec.Prefix, &expiresAt, &lastUsedAt, &rec.CreatedAt); err != nil {
+		var scopes, templates, allowedCIDRs string
+		if err := rows.Scan(&rec.ID, &rec.UserID, &rec.Name, &rec.Prefix, &expiresAt, &lastUsedAt, &rec.CreatedAt, &rec.Project,
+			&scopes, &templates, &allowedCIDRs); err != nil {
 			return nil, fmt.Errorf("failed to scan api key: %w", err)
 		}
+		rec.Scopes, rec.Templates, rec.AllowedCIDRs = unmarshalStringList(scopes), unmarshalStringList(templates), unmarshalStringList(allowedCIDRs)
 		if expiresAt.Valid {
 			rec.ExpiresAt = &expiresAt.Time
 		}
@@ -238,9 +336,9 @@ func (s *AuthStore) ListAPIKeys(ctx context.Context) ([]APIKeyRecord, error) {
 	return keys, rows.Err()
 }
 
-// DeleteAPIKey deletes the API key with the given ID.
-func (s *AuthStore) DeleteAPIKey(ctx context.Context, id string) error {
-	result, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
+// DeleteAPIKey deletes the API key with the given ID owned by a user.
+func (s *AuthStore) DeleteAPIKey(ctx context.Context, id, userID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete api key: %w", err)
 	}
@@ -262,3 +360,21 @@ func (s *AuthStore) UpdateAPIKeyLastUsed(ctx context.Context, id string, now tim
 	}
 	return nil
 }
+
+// marshalStringList stores a list as a JSON array, and an empty list as "".
+func marshalStringList(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+func unmarshalStringList(data string) []string {
+	if data == "" {
+		return nil
+	}
+	var items []string
+	_ = json.Unmarshal([]byte(data), &items)
+	return items
+}
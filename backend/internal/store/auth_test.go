@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthStoreUsersAndAPIKeys(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewAuthStore()
+
+	for _, u := range []*UserRecord{
+		{ID: "u-admin", Username: "admin", PasswordHash: "x", Role: "admin"},
+		{ID: "u-dev", Username: "dev", PasswordHash: "x", Role: "developer"},
+	} {
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser(%s) error = %v", u.Username, err)
+		}
+	}
+	for _, k := range []*APIKeyRecord{
+		{ID: "k-admin", UserID: "u-admin", Name: "admin", Prefix: "aaaa", KeyHash: "h1"},
+		{ID: "k-dev", UserID: "u-dev", Name: "dev", Prefix: "bbbb", KeyHash: "h2"},
+	} {
+		if err := s.CreateAPIKey(ctx, k); err != nil {
+			t.Fatalf("CreateAPIKey(%s) error = %v", k.ID, err)
+		}
+	}
+
+	keys, err := s.ListAPIKeys(ctx, "u-dev")
+	if err != nil || len(keys) != 1 || keys[0].ID != "k-dev" || keys[0].UserID != "u-dev" {
+		t.Fatalf("ListAPIKeys(u-dev) = %+v, %v", keys, err)
+	}
+	if err := s.DeleteAPIKey(ctx, "k-admin", "u-dev"); err == nil {
+		t.Fatal("DeleteAPIKey() of another user's key succeeded")
+	}
+
+	count, err := s.ActiveAdminCount(ctx)
+	if err != nil || count != 1 {
+		t.Fatalf("ActiveAdminCount() = %d, %v; want 1", count, err)
+	}
+	dev, err := s.GetUserByUsername(ctx, "dev")
+	if err != nil || dev == nil {
+		t.Fatalf("GetUserByUsername(dev) = %+v, %v", dev, err)
+	}
+	dev.Role = "admin"
+	dev.Disabled = true
+	if err := s.UpdateUser(ctx, dev); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if got, _ := s.GetUserByID(ctx, "u-dev"); got == nil || got.Role != "admin" || !got.Disabled {
+		t.Fatalf("GetUserByID(u-dev) after update = %+v", got)
+	}
+	if count, _ := s.ActiveAdminCount(ctx); count != 1 {
+		t.Fatalf("ActiveAdminCount() with a disabled admin = %d, want 1", count)
+	}
+
+	// Deleting a user removes their keys
+	if err := s.DeleteUser(ctx, "u-dev"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if key, err := s.GetAPIKeyByHash(ctx, "h2"); err != nil || key != nil {
+		t.Fatalf("GetAPIKeyByHash() after DeleteUser = %+v, %v; want nil", key, err)
+	}
+	users, err := s.ListUsers(ctx)
+	if err != nil || len(users) != 1 || users[0].Username != "admin" {
+		t.Fatalf("ListUsers() = %+v, %v", users, err)
+	}
+}
+
+func TestInitDBMigratesSingleAdminAuth(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "liteboxd.db")
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE admin_users (id TEXT PRIMARY KEY, username TEXT NOT NULL UNIQUE, password_hash TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE api_keys (id TEXT PRIMARY KEY, name TEXT NOT NULL, prefix TEXT NOT NULL, key_hash TEXT NOT NULL,
+			expires_at TIMESTAMP, last_used_at TIMESTAMP, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO admin_users (id, username, password_hash) VALUES ('u-1', 'admin', 'x')`,
+		`INSERT INTO api_keys (id, name, prefix, key_hash) VALUES ('k-1', 'ci', 'aaaa', 'h1')`,
+	} {
+		if _, err := legacy.Exec(stmt); err != nil {
+			t.Fatalf("seed legacy db: %v", err)
+		}
+	}
+	_ = legacy.Close()
+
+	if err := InitDB(dbPath); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	t.Cleanup(func() { _ = CloseDB() })
+
+	ctx := context.Background()
+	s := NewAuthStore()
+	admin, err := s.GetUserByID(ctx, "u-1")
+	if err != nil || admin == nil || admin.Role != "admin" || admin.Disabled {
+		t.Fatalf("migrated admin = %+v, %v", admin, err)
+	}
+	key, err := s.GetAPIKeyByHash(ctx, "h1")
+	if err != nil || key == nil || key.UserID != "u-1" {
+		t.Fatalf("migrated api key = %+v, %v", key, err)
+	}
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SandboxNetworkEventRecord is a persisted egress audit event of a sandbox.
+type SandboxNetworkEventRecord struct {
+	ID            int64
+	SandboxID     string
+	Kind          string
+	Domain        string
+	DestinationIP string
+	Port          int
+	Protocol      string
+	Verdict       string
+	ObservedAt    time.Time
+}
+
+// SandboxNetworkEventFilter narrows ListBySandbox. Zero values do not filter.
+type SandboxNetworkEventFilter struct {
+	Kind  string
+	Since time.Time
+	Limit int
+}
+
+// SandboxNetworkEventStore handles network audit event persistence. Events are purged
+// together with the other historical data by SandboxStore.PurgeHistoricalData.
+type SandboxNetworkEventStore struct {
+	db *sql.DB
+}
+
+// NewSandboxNetworkEventStore creates a new SandboxNetworkEventStore.
+func NewSandboxNetworkEventStore() *SandboxNetworkEventStore {
+	return &SandboxNetworkEventStore{db: DB}
+}
+
+// Append inserts a batch of events in one transaction.
+func (s *SandboxNetworkEventStore) Append(ctx context.Context, records []SandboxNetworkEventRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin network events transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO sandbox_network_events (
+			sandbox_id, kind, domain, destination_ip, port, protocol, verdict, observed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare network event insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, rec := range records {
+		if _, err := stmt.ExecContext(ctx, rec.SandboxID, rec.Kind, rec.Domain, rec.DestinationIP,
+			rec.Port, rec.Protocol, rec.Verdict, rec.ObservedAt); err != nil {
+			return fmt.Errorf("failed to insert network event: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit network events: %w", err)
+	}
+	return nil
+}
+
+// ListBySandbox returns the events of a sandbox, newest first.
+func (s *SandboxNetworkEventStore) ListBySandbox(ctx context.Context, sandboxID string, filter SandboxNetworkEventFilter) ([]SandboxNetworkEventRecord, error) {
+	query := sandboxNetworkEventSelectSQL + " WHERE sandbox_id = ?"
+	args := []any{sandboxID}
+	if filter.Kind != "" {
+		query += " AND kind = ?"
+		args = append(args, filter.Kind)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND observed_at >= ?"
+		args = append(args, filter.Since)
+	}
+	query += " ORDER BY observed_at DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network events: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]SandboxNetworkEventRecord, 0)
+	for rows.Next() {
+		var rec SandboxNetworkEventRecord
+		if err := rows.Scan(
+			&rec.ID, &rec.SandboxID, &rec.Kind, &rec.Domain, &rec.DestinationIP, &rec.Port,
+			&rec.Protocol, &rec.Verdict, &rec.ObservedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan network event: %w", err)
+		}
+		items = append(items, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate network events: %w", err)
+	}
+	return items, nil
+}
+
+const sandboxNetworkEventSelectSQL = `
+SELECT
+	id, sandbox_id, kind, domain, destination_ip, port, protocol, verdict, observed_at
+FROM sandbox_network_events`
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSandboxNetworkEventStoreListAndPurge(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	events := NewSandboxNetworkEventStore()
+	now := time.Now().UTC()
+
+	if err := events.Append(ctx, []SandboxNetworkEventRecord{
+		{SandboxID: "sbx-1", Kind: "dns", Domain: "github.com", Verdict: "allowed", ObservedAt: now.Add(-10 * 24 * time.Hour)},
+		{SandboxID: "sbx-1", Kind: "dns", Domain: "pypi.org", Verdict: "allowed", ObservedAt: now.Add(-time.Minute)},
+		{SandboxID: "sbx-1", Kind: "connection", DestinationIP: "140.82.112.3", Port: 443, Protocol: "TCP", Verdict: "denied", ObservedAt: now},
+		{SandboxID: "sbx-2", Kind: "dns", Domain: "example.com", Verdict: "allowed", ObservedAt: now},
+	}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	got, err := events.ListBySandbox(ctx, "sbx-1", SandboxNetworkEventFilter{})
+	if err != nil {
+		t.Fatalf("ListBySandbox() error = %v", err)
+	}
+	if len(got) != 3 || got[0].Kind != "connection" || got[0].Port != 443 || got[2].Domain != "github.com" {
+		t.Fatalf("ListBySandbox() = %+v, want sbx-1 events newest first", got)
+	}
+	got, err = events.ListBySandbox(ctx, "sbx-1", SandboxNetworkEventFilter{Kind: "dns", Since: now.Add(-time.Hour), Limit: 5})
+	if err != nil {
+		t.Fatalf("ListBySandbox(filter) error = %v", err)
+	}
+	if len(got) != 1 || got[0].Domain != "pypi.org" {
+		t.Fatalf("ListBySandbox(filter) = %+v, want only pypi.org", got)
+	}
+
+	res, err := NewSandboxStore().PurgeHistoricalData(ctx, now.Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeHistoricalData() error = %v", err)
+	}
+	if res.DeletedNetworkEvents != 1 {
+		t.Fatalf("DeletedNetworkEvents = %d, want 1", res.DeletedNetworkEvents)
+	}
+	got, _ = events.ListBySandbox(ctx, "sbx-1", SandboxNetworkEventFilter{})
+	if len(got) != 2 {
+		t.Fatalf("events after purge = %+v, want 2", got)
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	PoolMemberWarming = "warming"
+	PoolMemberReady   = "ready"
+	PoolMemberFailed  = "failed"
+	PoolMemberClaimed = "claimed"
+)
+
+// SandboxPoolMemberRecord is a pre-created sandbox waiting in the warm pool of a
+// template. Its ID is the sandbox ID it gets when claimed.
+type SandboxPoolMemberRecord struct {
+	ID              string
+	TemplateName    string
+	TemplateVersion int
+	Status          string
+	StatusReason    string
+	CreatedAt       time.Time
+	ReadyAt         *time.Time
+	UpdatedAt       time.Time
+}
+
+// SandboxPoolStore handles warm pool persistence.
+type SandboxPoolStore struct {
+	db *sql.DB
+}
+
+// NewSandboxPoolStore creates a new SandboxPoolStore.
+func NewSandboxPoolStore() *SandboxPoolStore {
+	return &SandboxPoolStore{db: DB}
+}
+
+// Create inserts a new pool member.
+func (s *SandboxPoolStore) Create(ctx context.Context, rec *SandboxPoolMemberRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sandbox_pool_members (
+			id, template_name, template_version, status, status_reason, created_at, ready_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.TemplateName, rec.TemplateVersion, rec.Status, rec.StatusReason, rec.CreatedAt, toNullTime(rec.ReadyAt), rec.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox pool member: %w", err)
+	}
+	return nil
+}
+
+// List returns the pool members of a template, oldest first. An empty templateName
+// returns the members of all pools.
+func (s *SandboxPoolStore) List(ctx context.Context, templateName string) ([]SandboxPoolMemberRecord, error) {
+	query := sandboxPoolMemberSelectSQL
+	var args []any
+	if templateName != "" {
+		query += " WHERE template_name = ?"
+		args = append(args, templateName)
+	}
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY created_at ASC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandbox pool members: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]SandboxPoolMemberRecord, 0)
+	for rows.Next() {
+		rec, err := scanSandboxPoolMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox pool member: %w", err)
+		}
+		items = append(items, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandbox pool members: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateStatus records the outcome of warming a pool member. Only warming members are
+// updated, so a member claimed or removed meanwhile is left alone.
+func (s *SandboxPoolStore) UpdateStatus(ctx context.Context, id, status, reason string, now time.Time) (bool, error) {
+	var readyAt *time.Time
+	if status == PoolMemberReady {
+		readyAt = &now
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sandbox_pool_members
+		SET status = ?, status_reason = ?, ready_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, status, reason, toNullTime(readyAt), now, id, PoolMemberWarming)
+	if err != nil {
+		return false, fmt.Errorf("failed to update sandbox pool member status: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Claim atomically marks the longest-ready member of a template version as claimed
+// and returns it, or nil when the pool has no ready member. The claimer deletes the
+// member once the sandbox record exists.
+func (s *SandboxPoolStore) Claim(ctx context.Context, templateName string, templateVersion int, now time.Time) (*SandboxPoolMemberRecord, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE sandbox_pool_members
+		SET status = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM sandbox_pool_members
+			WHERE template_name = ? AND template_version = ? AND status = ?
+			ORDER BY ready_at ASC
+			LIMIT 1
+		)
+		RETURNING id, template_name, template_version, status, status_reason, created_at, ready_at, updated_at
+	`, PoolMemberClaimed, now, templateName, templateVersion, PoolMemberReady)
+	rec, err := scanSandboxPoolMember(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim sandbox pool member: %w", err)
+	}
+	return rec, nil
+}
+
+// Delete removes a pool member. It reports whether the member existed.
+func (s *SandboxPoolStore) Delete(ctx context.Context, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sandbox_pool_members WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete sandbox pool member: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+const sandboxPoolMemberSelectSQL = `
+SELECT
+	id, template_name, template_version, status, status_reason, created_at, ready_at, updated_at
+FROM sandbox_pool_members`
+
+func scanSandboxPoolMember(scanner interface{ Scan(dest ...any) error }) (*SandboxPoolMemberRecord, error) {
+	var rec SandboxPoolMemberRecord
+	var readyAt sql.NullTime
+	if err := scanner.Scan(
+		&rec.ID, &rec.TemplateName, &rec.TemplateVersion, &rec.Status, &rec.StatusReason, &rec.CreatedAt, &readyAt, &rec.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if readyAt.Valid {
+		t := readyAt.Time
+		rec.ReadyAt = &t
+	}
+	return &rec, nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSandboxPoolStoreClaim(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	pool := NewSandboxPoolStore()
+	now := time.Now().UTC()
+
+	members := []SandboxPoolMemberRecord{
+		{ID: "warm-1", TemplateName: "python", TemplateVersion: 2},
+		{ID: "warm-2", TemplateName: "python", TemplateVersion: 2},
+		{ID: "warm-3", TemplateName: "python", TemplateVersion: 1},
+		{ID: "warm-4", TemplateName: "python", TemplateVersion: 2},
+	}
+	for i := range members {
+		members[i].Status = PoolMemberWarming
+		members[i].CreatedAt = now.Add(time.Duration(i) * time.Second)
+		members[i].UpdatedAt = now
+		if err := pool.Create(ctx, &members[i]); err != nil {
+			t.Fatalf("Create(%s) error = %v", members[i].ID, err)
+		}
+	}
+
+	// warm-2 becomes ready first, warm-4 is never ready.
+	for i, id := range []string{"warm-2", "warm-1", "warm-3"} {
+		updated, err := pool.UpdateStatus(ctx, id, PoolMemberReady, "", now.Add(time.Duration(i)*time.Second))
+		if err != nil || !updated {
+			t.Fatalf("UpdateStatus(%s) = %v, %v", id, updated, err)
+		}
+	}
+	if updated, err := pool.UpdateStatus(ctx, "warm-2", PoolMemberFailed, "late", now); err != nil || updated {
+		t.Fatalf("UpdateStatus() on ready member = %v, %v; want false", updated, err)
+	}
+
+	for _, want := range []string{"warm-2", "warm-1"} {
+		got, err := pool.Claim(ctx, "python", 2, now)
+		if err != nil {
+			t.Fatalf("Claim() error = %v", err)
+		}
+		if got == nil || got.ID != want || got.Status != PoolMemberClaimed {
+			t.Fatalf("Claim() = %+v, want %s claimed", got, want)
+		}
+	}
+	got, err := pool.Claim(ctx, "python", 2, now)
+	if err != nil || got != nil {
+		t.Fatalf("Claim() with only warming members = %+v, %v; want nil", got, err)
+	}
+
+	left, err := pool.List(ctx, "python")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(left) != 4 || left[1].Status != PoolMemberClaimed || left[2].Status != PoolMemberReady || left[3].Status != PoolMemberWarming {
+		t.Fatalf("List() after claims = %+v", left)
+	}
+	if removed, err := pool.Delete(ctx, "warm-3"); err != nil || !removed {
+		t.Fatalf("Delete() = %v, %v", removed, err)
+	}
+	if removed, err := pool.Delete(ctx, "warm-3"); err != nil || removed {
+		t.Fatalf("Delete() twice = %v, %v; want false", removed, err)
+	}
+}
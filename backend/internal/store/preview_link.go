@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SandboxPreviewLinkRecord is a persisted preview link. Only the hash of its token is
+// stored.
+type SandboxPreviewLinkRecord struct {
+	ID          string
+	SandboxID   string
+	TokenSHA256 string
+	Port        int
+	PathPrefix  string
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// SandboxPreviewLinkStore handles preview link persistence.
+type SandboxPreviewLinkStore struct {
+	db *sql.DB
+}
+
+// NewSandboxPreviewLinkStore creates a new SandboxPreviewLinkStore.
+func NewSandboxPreviewLinkStore() *SandboxPreviewLinkStore {
+	return &SandboxPreviewLinkStore{db: DB}
+}
+
+// Create inserts a new preview link record.
+func (s *SandboxPreviewLinkStore) Create(ctx context.Context, rec *SandboxPreviewLinkRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sandbox_preview_links (
+			id, sandbox_id, token_sha256, port, path_prefix, expires_at, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.SandboxID, rec.TokenSHA256, rec.Port, rec.PathPrefix, rec.ExpiresAt, rec.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox preview link: %w", err)
+	}
+	return nil
+}
+
+// GetByTokenHash returns the preview link with the given token hash, or nil if not found.
+func (s *SandboxPreviewLinkStore) GetByTokenHash(ctx context.Context, tokenHash string) (*SandboxPreviewLinkRecord, error) {
+	row := s.db.QueryRowContext(ctx, sandboxPreviewLinkSelectSQL+" WHERE token_sha256 = ?", tokenHash)
+	rec, err := scanSandboxPreviewLink(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sandbox preview link: %w", err)
+	}
+	return rec, nil
+}
+
+// List returns the preview links of a sandbox that have not expired at now, newest first.
+func (s *SandboxPreviewLinkStore) List(ctx context.Context, sandboxID string, now time.Time) ([]SandboxPreviewLinkRecord, error) {
+	rows, err := s.db.QueryContext(ctx, sandboxPreviewLinkSelectSQL+`
+		WHERE sandbox_id = ? AND expires_at > ?
+		ORDER BY created_at DESC
+	`, sandboxID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandbox preview links: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]SandboxPreviewLinkRecord, 0)
+	for rows.Next() {
+		rec, err := scanSandboxPreviewLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox preview link: %w", err)
+		}
+		items = append(items, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandbox preview links: %w", err)
+	}
+	return items, nil
+}
+
+// Delete removes a preview link of a sandbox. It reports whether the link existed.
+func (s *SandboxPreviewLinkStore) Delete(ctx context.Context, sandboxID, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sandbox_preview_links WHERE id = ? AND sandbox_id = ?", id, sandboxID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete sandbox preview link: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// DeleteExpired removes all preview links that expired before now.
+func (s *SandboxPreviewLinkStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM sandbox_preview_links WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sandbox preview links: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+const sandboxPreviewLinkSelectSQL = `
+SELECT
+	id, sandbox_id, token_sha256, port, path_prefix, expires_at, created_at
+FROM sandbox_preview_links`
+
+func scanSandboxPreviewLink(scanner interface{ Scan(dest ...any) error }) (*SandboxPreviewLinkRecord, error) {
+	var rec SandboxPreviewLinkRecord
+	if err := scanner.Scan(
+		&rec.ID, &rec.SandboxID, &rec.TokenSHA256, &rec.Port, &rec.PathPrefix, &rec.ExpiresAt, &rec.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
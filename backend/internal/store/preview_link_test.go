@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSandboxPreviewLinkStoreFlow(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	sandboxes := NewSandboxStore()
+	links := NewSandboxPreviewLinkStore()
+	now := time.Now().UTC()
+
+	if err := sandboxes.Create(ctx, &SandboxRecord{
+		ID:              "sbx-prev",
+		TemplateName:    "python",
+		Image:           "python:3.11",
+		DesiredState:    DesiredStateActive,
+		LifecycleStatus: "running",
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(time.Hour),
+		UpdatedAt:       now,
+	}); err != nil {
+		t.Fatalf("Create(sandbox) error = %v", err)
+	}
+
+	for i, rec := range []SandboxPreviewLinkRecord{
+		{ID: "prv-1", TokenSHA256: "hash-1", Port: 3000, PathPrefix: "/docs", ExpiresAt: now.Add(time.Hour)},
+		{ID: "prv-2", TokenSHA256: "hash-2", Port: 8080, ExpiresAt: now.Add(time.Hour)},
+		{ID: "prv-old", TokenSHA256: "hash-old", Port: 8080, ExpiresAt: now.Add(-time.Minute)},
+	} {
+		rec.SandboxID = "sbx-prev"
+		rec.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		if err := links.Create(ctx, &rec); err != nil {
+			t.Fatalf("Create(%s) error = %v", rec.ID, err)
+		}
+	}
+
+	got, err := links.GetByTokenHash(ctx, "hash-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetByTokenHash() = %v, %v", got, err)
+	}
+	if got.ID != "prv-1" || got.Port != 3000 || got.PathPrefix != "/docs" {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+	if got, err := links.GetByTokenHash(ctx, "missing"); err != nil || got != nil {
+		t.Fatalf("GetByTokenHash(missing) = %v, %v", got, err)
+	}
+
+	list, err := links.List(ctx, "sbx-prev", now)
+	if err != nil || len(list) != 2 || list[0].ID != "prv-2" {
+		t.Fatalf("List() = %+v, %v; want unexpired links newest first", list, err)
+	}
+
+	if deleted, err := links.Delete(ctx, "other", "prv-1"); err != nil || deleted {
+		t.Fatalf("Delete() from another sandbox = %v, %v; want false", deleted, err)
+	}
+	if deleted, err := links.Delete(ctx, "sbx-prev", "prv-1"); err != nil || !deleted {
+		t.Fatalf("Delete() = %v, %v", deleted, err)
+	}
+	if n, err := links.DeleteExpired(ctx, now); err != nil || n != 1 {
+		t.Fatalf("DeleteExpired() = %d, %v; want 1", n, err)
+	}
+	if got, err := links.GetByTokenHash(ctx, "hash-old"); err != nil || got != nil {
+		t.Fatalf("expired link still present: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SandboxProcessRecord is the persisted state of a background process started in a sandbox.
+type SandboxProcessRecord struct {
+	ID          string
+	SandboxID   string
+	CommandJSON string
+	WorkDir     string
+	PID         int
+	PodUID      string
+	Status      string
+	ExitCode    *int
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	UpdatedAt   time.Time
+}
+
+// Command decodes the stored command line.
+func (r *SandboxProcessRecord) Command() []string {
+	var command []string
+	if r.CommandJSON == "" {
+		return command
+	}
+	_ = json.Unmarshal([]byte(r.CommandJSON), &command)
+	return command
+}
+
+// SandboxProcessStore handles background process persistence.
+type SandboxProcessStore struct {
+	db *sql.DB
+}
+
+// NewSandboxProcessStore creates a new SandboxProcessStore.
+func NewSandboxProcessStore() *SandboxProcessStore {
+	return &SandboxProcessStore{db: DB}
+}
+
+// Create inserts a new process record.
+func (s *SandboxProcessStore) Create(ctx context.Context, rec *SandboxProcessRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sandbox_processes (
+			id, sandbox_id, command_json, work_dir, pid, pod_uid, status, exit_code,
+			started_at, finished_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.SandboxID, rec.CommandJSON, rec.WorkDir, rec.PID, rec.PodUID, rec.Status,
+		toNullInt(rec.ExitCode), rec.StartedAt, toNullTime(rec.FinishedAt), rec.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox process: %w", err)
+	}
+	return nil
+}
+
+// Get returns a process of the given sandbox, or nil if not found.
+func (s *SandboxProcessStore) Get(ctx context.Context, sandboxID, id string) (*SandboxProcessRecord, error) {
+	row := s.db.QueryRowContext(ctx, sandboxProcessSelectSQL+" WHERE sandbox_id = ? AND id = ?", sandboxID, id)
+	rec, err := scanSandboxProcess(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sandbox process: %w", err)
+	}
+	return rec, nil
+}
+
+// ListBySandbox returns all processes of a sandbox, newest first.
+func (s *SandboxProcessStore) ListBySandbox(ctx context.Context, sandboxID string) ([]SandboxProcessRecord, error) {
+	rows, err := s.db.QueryContext(ctx, sandboxProcessSelectSQL+" WHERE sandbox_id = ? ORDER BY started_at DESC", sandboxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandbox processes: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]SandboxProcessRecord, 0)
+	for rows.Next() {
+		rec, err := scanSandboxProcess(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox process: %w", err)
+		}
+		items = append(items, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandbox processes: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateState records the observed status of a process. Only running processes are
+// updated, so a terminal status is never overwritten by a late observation.
+func (s *SandboxProcessStore) UpdateState(ctx context.Context, id, status string, exitCode *int, finishedAt *time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sandbox_processes
+		SET status = ?, exit_code = ?, finished_at = ?, updated_at = ?
+		WHERE id = ? AND status = 'running'
+	`, status, toNullInt(exitCode), toNullTime(finishedAt), time.Now().UTC(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to update sandbox process state: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+const sandboxProcessSelectSQL = `
+SELECT
+	id, sandbox_id, command_json, work_dir, pid, pod_uid, status, exit_code,
+	started_at, finished_at, updated_at
+FROM sandbox_processes`
+
+func scanSandboxProcess(scanner interface{ Scan(dest ...any) error }) (*SandboxProcessRecord, error) {
+	var rec SandboxProcessRecord
+	var exitCode sql.NullInt64
+	var finishedAt sql.NullTime
+	if err := scanner.Scan(
+		&rec.ID, &rec.SandboxID, &rec.CommandJSON, &rec.WorkDir, &rec.PID, &rec.PodUID, &rec.Status, &exitCode,
+		&rec.StartedAt, &finishedAt, &rec.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if exitCode.Valid {
+		v := int(exitCode.Int64)
+		rec.ExitCode = &v
+	}
+	if finishedAt.Valid {
+		t := finishedAt.Time
+		rec.FinishedAt = &t
+	}
+	return &rec, nil
+}
+
+func toNullInt(v *int) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*v), Valid: true}
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSandboxProcessStoreFlow(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	sandboxes := NewSandboxStore()
+	processes := NewSandboxProcessStore()
+	now := time.Now().UTC()
+
+	if err := sandboxes.Create(ctx, &SandboxRecord{
+		ID:              "sbx-proc",
+		TemplateName:    "python",
+		Image:           "python:3.11",
+		DesiredState:    DesiredStateActive,
+		LifecycleStatus: "running",
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(time.Hour),
+		UpdatedAt:       now,
+	}); err != nil {
+		t.Fatalf("Create(sandbox) error = %v", err)
+	}
+
+	rec := &SandboxProcessRecord{
+		ID:          "proc-1",
+		SandboxID:   "sbx-proc",
+		CommandJSON: `["npm","run","dev"]`,
+		WorkDir:     "/workspace",
+		PID:         17,
+		PodUID:      "uid-1",
+		Status:      "running",
+		StartedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := processes.Create(ctx, rec); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := processes.Get(ctx, "sbx-proc", "proc-1")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if got.PID != 17 || got.ExitCode != nil || got.FinishedAt != nil || len(got.Command()) != 3 {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+	if other, err := processes.Get(ctx, "other", "proc-1"); err != nil || other != nil {
+		t.Fatalf("Get() with other sandbox = %v, %v; want nil, nil", other, err)
+	}
+
+	code := 3
+	updated, err := processes.UpdateState(ctx, "proc-1", "exited", &code, &now)
+	if err != nil || !updated {
+		t.Fatalf("UpdateState() = %v, %v", updated, err)
+	}
+	// Terminal states are never overwritten.
+	updated, err = processes.UpdateState(ctx, "proc-1", "lost", nil, &now)
+	if err != nil || updated {
+		t.Fatalf("UpdateState() on finished process = %v, %v; want false", updated, err)
+	}
+
+	items, err := processes.ListBySandbox(ctx, "sbx-proc")
+	if err != nil {
+		t.Fatalf("ListBySandbox() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Status != "exited" || items[0].ExitCode == nil || *items[0].ExitCode != 3 {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProjectRecord is a persisted project. Each project has its own Kubernetes namespace.
+type ProjectRecord struct {
+	ID          string
+	Name        string
+	Namespace   string
+	Description string
+	CreatedAt   time.Time
+}
+
+// ProjectStore handles project persistence.
+type ProjectStore struct {
+	db *sql.DB
+}
+
+// NewProjectStore creates a new ProjectStore.
+func NewProjectStore() *ProjectStore {
+	return &ProjectStore{db: DB}
+}
+
+// Create inserts a new project record.
+func (s *ProjectStore) Create(ctx context.Context, rec *ProjectRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO projects (id, name, namespace, description, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, rec.ID, rec.Name, rec.Namespace, rec.Description, rec.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("project %q already exists", rec.Name)
+		}
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+	return nil
+}
+
+// GetByName returns the project with the given name, or nil if not found.
+func (s *ProjectStore) GetByName(ctx context.Context, name string) (*ProjectRecord, error) {
+	row := s.db.QueryRowContext(ctx, projectSelectSQL+" WHERE name = ?", name)
+	rec, err := scanProject(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	return rec, nil
+}
+
+// List returns all projects ordered by name.
+func (s *ProjectStore) List(ctx context.Context) ([]ProjectRecord, error) {
+	rows, err := s.db.QueryContext(ctx, projectSelectSQL+" ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]ProjectRecord, 0)
+	for rows.Next() {
+		rec, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		items = append(items, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate projects: %w", err)
+	}
+	return items, nil
+}
+
+// Delete removes a project. It reports whether the project existed.
+func (s *ProjectStore) Delete(ctx context.Context, name string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM projects WHERE name = ?", name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete project: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+const projectSelectSQL = `
+SELECT
+	id, name, namespace, description, created_at
+FROM projects`
+
+func scanProject(scanner interface{ Scan(dest ...any) error }) (*ProjectRecord, error) {
+	var rec ProjectRecord
+	if err := scanner.Scan(&rec.ID, &rec.Name, &rec.Namespace, &rec.Description, &rec.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProjectStoreCreateListAndDelete(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewProjectStore()
+
+	now := time.Now().UTC()
+	for _, name := range []string{"team-b", "team-a"} {
+		if err := s.Create(ctx, &ProjectRecord{
+			ID:        "p-" + name,
+			Name:      name,
+			Namespace: "liteboxd-" + name,
+			CreatedAt: now,
+		}); err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+	}
+	if err := s.Create(ctx, &ProjectRecord{ID: "p-dup", Name: "team-a", Namespace: "other", CreatedAt: now}); err == nil {
+		t.Fatal("Create() with a duplicate name succeeded")
+	}
+
+	got, err := s.GetByName(ctx, "team-a")
+	if err != nil || got == nil {
+		t.Fatalf("GetByName() = %v, %v", got, err)
+	}
+	if got.Namespace != "liteboxd-team-a" {
+		t.Fatalf("Namespace = %q, want liteboxd-team-a", got.Namespace)
+	}
+
+	items, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(items) != 2 || items[0].Name != "team-a" || items[1].Name != "team-b" {
+		t.Fatalf("List() = %+v, want team-a and team-b", items)
+	}
+
+	deleted, err := s.Delete(ctx, "team-a")
+	if err != nil || !deleted {
+		t.Fatalf("Delete() = %v, %v", deleted, err)
+	}
+	if got, err := s.GetByName(ctx, "team-a"); err != nil || got != nil {
+		t.Fatalf("GetByName() after delete = %v, %v", got, err)
+	}
+	if deleted, err := s.Delete(ctx, "team-a"); err != nil || deleted {
+		t.Fatalf("second Delete() = %v, %v", deleted, err)
+	}
+}
+
+func TestSandboxStoreProjectScopeAndCount(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewSandboxStore()
+
+	now := time.Now().UTC()
+	for _, rec := range []*SandboxRecord{
+		{ID: "sbx-default", ClusterNamespace: "liteboxd"},
+		{ID: "sbx-team", ClusterNamespace: "liteboxd-team-a", Project: "team-a"},
+	} {
+		rec.Image = "python:3.11"
+		rec.EnvJSON = `{}`
+		rec.DesiredState = DesiredStateActive
+		rec.LifecycleStatus = "running"
+		rec.CreatedAt, rec.ExpiresAt, rec.UpdatedAt = now, now.Add(time.Hour), now
+		if err := s.Create(ctx, rec); err != nil {
+			t.Fatalf("Create(%s) error = %v", rec.ID, err)
+		}
+	}
+
+	items, err := s.ListForUser(ctx, true, SandboxScope{Project: "team-a"})
+	if err != nil {
+		t.Fatalf("ListForUser() error = %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "sbx-team" {
+		t.Fatalf("ListForUser(team-a) = %+v, want sbx-team", items)
+	}
+
+	ns, err := s.GetClusterNamespace(ctx, "sbx-team")
+	if err != nil || ns != "liteboxd-team-a" {
+		t.Fatalf("GetClusterNamespace() = %q, %v", ns, err)
+	}
+	if ns, err := s.GetClusterNamespace(ctx, "missing"); err != nil || ns != "" {
+		t.Fatalf("GetClusterNamespace(missing) = %q, %v", ns, err)
+	}
+
+	count, err := s.CountActiveInProject(ctx, "team-a")
+	if err != nil || count != 1 {
+		t.Fatalf("CountActiveInProject() = %d, %v", count, err)
+	}
+}
@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+// QuotaRecord is a persisted quota on the sandboxes of a user, API key or project.
+// Zero limits are unlimited.
+type QuotaRecord struct {
+	Scope        string
+	Subject      string
+	MaxSandboxes int
+	MaxCPU       string
+	MaxMemory    string
+	MaxStorage   string
+	MaxTTL       int
+	UpdatedAt    time.Time
+}
+
+// SandboxResources is what an active sandbox counts towards quotas.
+type SandboxResources struct {
+	ID                 string
+	CPU                string
+	Memory             string
+	LifecycleStatus    string
+	PersistenceEnabled bool
+	PersistenceSize    string
+}
+
+// QuotaReservationRecord holds resources admitted by quotas for a sandbox that its own
+// row doesn't count yet: one being created, started or resumed. Empty subject fields
+// don't count towards any quota.
+type QuotaReservationRecord struct {
+	ID            string
+	OwnerID       string
+	OwnerAPIKeyID string
+	Project       string
+	CPU           string
+	Memory        string
+	Storage       string
+	CreatedAt     time.Time
+}
+
+// ResourceLister lists the resources counting towards the quotas of a subject.
+type ResourceLister func(ctx context.Context, scope model.QuotaScope, subject string) ([]SandboxResources, error)
+
+// QuotaStore handles quota persistence.
+type QuotaStore struct {
+	db *sql.DB
+}
+
+// NewQuotaStore creates a new QuotaStore.
+func NewQuotaStore() *QuotaStore {
+	return &QuotaStore{db: DB}
+}
+
+// Upsert creates or replaces the quota of a scope and subject.
+func (s *QuotaStore) Upsert(ctx context.Context, rec *QuotaRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO quotas (scope, subject, max_sandboxes, max_cpu, max_memory, max_storage, max_ttl, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(scope, subject) DO UPDATE SET
+			max_sandboxes = excluded.max_sandboxes,
+			max_cpu = excluded.max_cpu,
+			max_memory = excluded.max_memory,
+			max_storage = excluded.max_storage,
+			max_ttl = excluded.max_ttl,
+			updated_at = excluded.updated_at
+	`, rec.Scope, rec.Subject, rec.MaxSandboxes, rec.MaxCPU, rec.MaxMemory, rec.MaxStorage, rec.MaxTTL, rec.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save quota: %w", err)
+	}
+	return nil
+}
+
+// Get returns the quota of a scope and subject, or nil if there is none.
+func (s *QuotaStore) Get(ctx context.Context, scope, subject string) (*QuotaRecord, error) {
+	row := s.db.QueryRowContext(ctx, quotaSelectSQL+" WHERE scope = ? AND subject = ?", scope, subject)
+	rec, err := scanQuota(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota: %w", err)
+	}
+	return rec, nil
+}
+
+// List returns all quotas ordered by scope and subject.
+func (s *QuotaStore) List(ctx context.Context) ([]QuotaRecord, error) {
+	rows, err := s.db.QueryContext(ctx, quotaSelectSQL+" ORDER BY scope, subject")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quotas: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]QuotaRecord, 0)
+	for rows.Next() {
+		rec, err := scanQuota(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quota: %w", err)
+		}
+		items = append(items, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate quotas: %w", err)
+	}
+	return items, nil
+}
+
+// Delete removes the quota of a scope and subject. It reports whether it existed.
+func (s *QuotaStore) Delete(ctx context.Context, scope, subject string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM quotas WHERE scope = ? AND subject = ?", scope, subject)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete quota: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Reserve runs check with the resources counted in a transaction and stores rec if it
+// passes, so that the check and the reservation see the same usage.
+func (s *QuotaStore) Reserve(ctx context.Context, rec *QuotaReservationRecord, check func(list ResourceLister) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin quota reservation: %w", err)
+	}
+	defer tx.Rollback()
+
+	list := func(ctx context.Context, scope model.QuotaScope, subject string) ([]SandboxResources, error) {
+		return listActiveResources(ctx, tx, scope, subject)
+	}
+	if err := check(list); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO quota_reservations (id, owner_id, owner_api_key_id, project, cpu, memory, storage, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.OwnerID, rec.OwnerAPIKeyID, rec.Project, rec.CPU, rec.Memory, rec.Storage, rec.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create quota reservation: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit quota reservation: %w", err)
+	}
+	return nil
+}
+
+// DeleteReservation removes a quota reservation.
+func (s *QuotaStore) DeleteReservation(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM quota_reservations WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete quota reservation: %w", err)
+	}
+	return nil
+}
+
+// DeleteAllReservations removes every quota reservation. Reservations left by a
+// previous run of the server belong to requests that no longer exist.
+func (s *QuotaStore) DeleteAllReservations(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM quota_reservations"); err != nil {
+		return fmt.Errorf("failed to delete quota reservations: %w", err)
+	}
+	return nil
+}
+
+const quotaSelectSQL = `
+SELECT
+	scope, subject, max_sandboxes, max_cpu, max_memory, max_storage, max_ttl, updated_at
+FROM quotas`
+
+func scanQuota(scanner interface{ Scan(dest ...any) error }) (*QuotaRecord, error) {
+	var rec QuotaRecord
+	if err := scanner.Scan(&rec.Scope, &rec.Subject, &rec.MaxSandboxes, &rec.MaxCPU, &rec.MaxMemory,
+		&rec.MaxStorage, &rec.MaxTTL, &rec.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// quotaColumns maps quota scopes to the sandbox and reservation column holding their
+// subject.
+var quotaColumns = map[model.QuotaScope]string{
+	model.QuotaScopeUser:    "owner_id",
+	model.QuotaScopeAPIKey:  "owner_api_key_id",
+	model.QuotaScopeProject: "project",
+}
+
+// ListActiveResources returns the resources of the active sandboxes of a quota subject,
+// a user ID, API key ID or project name depending on scope, along with its
+// reservations. Reservations are reported as running sandboxes.
+func (s *SandboxStore) ListActiveResources(ctx context.Context, scope model.QuotaScope, subject string) ([]SandboxResources, error) {
+	return listActiveResources(ctx, s.db, scope, subject)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func listActiveResources(ctx context.Context, q queryer, scope model.QuotaScope, subject string) ([]SandboxResources, error) {
+	column, ok := quotaColumns[scope]
+	if !ok {
+		return nil, fmt.Errorf("unknown quota scope %q", scope)
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, cpu, memory, lifecycle_status, persistence_enabled, persistence_size
+		FROM sandboxes
+		WHERE `+column+` = ? AND desired_state = ? AND lifecycle_status <> ?
+		UNION ALL
+		SELECT id, cpu, memory, 'running', storage <> '', storage
+		FROM quota_reservations
+		WHERE `+column+` = ?
+	`, subject, DesiredStateActive, "deleted", subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandbox resources: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]SandboxResources, 0)
+	for rows.Next() {
+		var item SandboxResources
+		if err := rows.Scan(&item.ID, &item.CPU, &item.Memory, &item.LifecycleStatus,
+			&item.PersistenceEnabled, &item.PersistenceSize); err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox resources: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandbox resources: %w", err)
+	}
+	return items, nil
+}
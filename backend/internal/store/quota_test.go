@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
+)
+
+func TestQuotaStoreUpsertListAndDelete(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewQuotaStore()
+
+	now := time.Now().UTC()
+	if err := s.Upsert(ctx, &QuotaRecord{Scope: "user", Subject: "u-alice", MaxSandboxes: 2, UpdatedAt: now}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err := s.Upsert(ctx, &QuotaRecord{Scope: "user", Subject: "u-alice", MaxCPU: "4", UpdatedAt: now}); err != nil {
+		t.Fatalf("second Upsert() error = %v", err)
+	}
+	got, err := s.Get(ctx, "user", "u-alice")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if got.MaxSandboxes != 0 || got.MaxCPU != "4" {
+		t.Fatalf("Get() = %+v, want the limits of the second Upsert", got)
+	}
+	if got, err := s.Get(ctx, "project", "u-alice"); err != nil || got != nil {
+		t.Fatalf("Get() of another scope = %v, %v", got, err)
+	}
+
+	if err := s.Upsert(ctx, &QuotaRecord{Scope: "api_key", Subject: "*", MaxTTL: 3600, UpdatedAt: now}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	items, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(items) != 2 || items[0].Scope != "api_key" || items[1].Scope != "user" {
+		t.Fatalf("List() = %+v, want api_key then user", items)
+	}
+
+	if deleted, err := s.Delete(ctx, "user", "u-alice"); err != nil || !deleted {
+		t.Fatalf("Delete() = %v, %v", deleted, err)
+	}
+	if deleted, err := s.Delete(ctx, "user", "u-alice"); err != nil || deleted {
+		t.Fatalf("second Delete() = %v, %v", deleted, err)
+	}
+}
+
+func TestSandboxStoreListActiveResources(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewSandboxStore()
+
+	now := time.Now().UTC()
+	for _, rec := range []*SandboxRecord{
+		{ID: "sbx-1", OwnerID: "u-alice", OwnerAPIKeyID: "k-1", DesiredState: DesiredStateActive, LifecycleStatus: "running"},
+		{ID: "sbx-2", OwnerID: "u-alice", Project: "team-a", DesiredState: DesiredStateActive, LifecycleStatus: "stopped",
+			PersistenceEnabled: true, PersistenceSize: "5Gi"},
+		{ID: "sbx-3", OwnerID: "u-alice", DesiredState: DesiredStateDeleted, LifecycleStatus: "terminating"},
+		{ID: "sbx-4", OwnerID: "u-bob", DesiredState: DesiredStateActive, LifecycleStatus: "running"},
+	} {
+		rec.Image = "python:3.11"
+		rec.CPU, rec.Memory = "500m", "512Mi"
+		rec.EnvJSON = `{}`
+		rec.ClusterNamespace = "liteboxd-sandbox"
+		rec.CreatedAt, rec.ExpiresAt, rec.UpdatedAt = now, now.Add(time.Hour), now
+		if err := s.Create(ctx, rec); err != nil {
+			t.Fatalf("Create(%s) error = %v", rec.ID, err)
+		}
+	}
+
+	tests := []struct {
+		scope   model.QuotaScope
+		subject string
+		want    int
+	}{
+		{model.QuotaScopeUser, "u-alice", 2},
+		{model.QuotaScopeAPIKey, "k-1", 1},
+		{model.QuotaScopeProject, "team-a", 1},
+		{model.QuotaScopeUser, "u-carol", 0},
+	}
+	for _, tt := range tests {
+		items, err := s.ListActiveResources(ctx, tt.scope, tt.subject)
+		if err != nil {
+			t.Fatalf("ListActiveResources(%s, %s) error = %v", tt.scope, tt.subject, err)
+		}
+		if len(items) != tt.want {
+			t.Errorf("ListActiveResources(%s, %s) = %d items, want %d", tt.scope, tt.subject, len(items), tt.want)
+		}
+	}
+
+	items, _ := s.ListActiveResources(ctx, model.QuotaScopeProject, "team-a")
+	if len(items) == 1 && (!items[0].PersistenceEnabled || items[0].PersistenceSize != "5Gi" || items[0].LifecycleStatus != "stopped") {
+		t.Fatalf("ListActiveResources() = %+v, want the stopped persistent sandbox", items[0])
+	}
+	if _, err := s.ListActiveResources(ctx, "team", "a"); err == nil {
+		t.Fatal("ListActiveResources() with an unknown scope succeeded")
+	}
+}
+
+func TestQuotaStoreReservations(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewQuotaStore()
+	sandboxes := NewSandboxStore()
+
+	rec := &QuotaReservationRecord{ID: "r-1", OwnerID: "u-alice", Project: "team-a", CPU: "1", Memory: "1Gi", Storage: "5Gi", CreatedAt: time.Now().UTC()}
+	var seen int
+	err := s.Reserve(ctx, rec, func(list ResourceLister) error {
+		items, err := list(ctx, model.QuotaScopeUser, "u-alice")
+		seen = len(items)
+		return err
+	})
+	if err != nil || seen != 0 {
+		t.Fatalf("Reserve() = %v, saw %d items; want nil, 0", err, seen)
+	}
+	items, err := sandboxes.ListActiveResources(ctx, model.QuotaScopeProject, "team-a")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("ListActiveResources() = %+v, %v; want the reservation", items, err)
+	}
+	if got := items[0]; got.ID != "r-1" || got.CPU != "1" || got.LifecycleStatus != "running" || !got.PersistenceEnabled || got.PersistenceSize != "5Gi" {
+		t.Fatalf("reservation = %+v", got)
+	}
+	if items, _ := sandboxes.ListActiveResources(ctx, model.QuotaScopeAPIKey, "k-1"); len(items) != 0 {
+		t.Fatalf("ListActiveResources(api key) = %+v, want none", items)
+	}
+
+	// A failed check stores nothing
+	rejected := &QuotaReservationRecord{ID: "r-2", OwnerID: "u-alice", CreatedAt: time.Now().UTC()}
+	if err := s.Reserve(ctx, rejected, func(ResourceLister) error { return errors.New("over quota") }); err == nil {
+		t.Fatal("Reserve() with a failing check succeeded")
+	}
+	if items, _ := sandboxes.ListActiveResources(ctx, model.QuotaScopeUser, "u-alice"); len(items) != 1 {
+		t.Fatalf("ListActiveResources() = %+v, want only r-1", items)
+	}
+
+	if err := s.DeleteReservation(ctx, "r-1"); err != nil {
+		t.Fatalf("DeleteReservation() error = %v", err)
+	}
+	if items, _ := sandboxes.ListActiveResources(ctx, model.QuotaScopeUser, "u-alice"); len(items) != 0 {
+		t.Fatalf("ListActiveResources() after delete = %+v, want none", items)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/fslongjin/liteboxd/backend/internal/model"
 )
 
 const (
@@ -65,6 +67,28 @@ type SandboxRecord struct {
 	UpdatedAt             time.Time
 	DeletedAt             *time.Time
 	StoppedAt             *time.Time
+	TTLMode               string
+	FromPool              bool
+	NetworkJSON           string
+	NetworkGroup          string
+	GroupPortsJSON        string
+	EgressBandwidth       string
+	IngressBandwidth      string
+
+	// PreviousAccessTokenSHA256 is the hash of the token replaced by the last rotation,
+	// which stays valid until PreviousAccessTokenExpiresAt.
+	PreviousAccessTokenSHA256    string
+	PreviousAccessTokenExpiresAt *time.Time
+
+	// OwnerID and OwnerName identify the user that created the sandbox, and
+	// OwnerAPIKeyID the API key it was created with. Sandboxes created before
+	// ownership was recorded have no owner and are only visible to admins.
+	OwnerID       string
+	OwnerName     string
+	OwnerAPIKeyID string
+	// Project is the project the sandbox belongs to, empty for sandboxes in the
+	// default namespace
+	Project string
 }
 
 func (r *SandboxRecord) EnvMap() map[string]string {
@@ -81,6 +105,29 @@ func (r *SandboxRecord) EnvMap() map[string]string {
 	return env
 }
 
+// NetworkOverride returns the per-sandbox network override, or nil when the sandbox
+// follows its template's network configuration.
+func (r *SandboxRecord) NetworkOverride() *model.NetworkSpec {
+	if r.NetworkJSON == "" {
+		return nil
+	}
+	var network model.NetworkSpec
+	if err := json.Unmarshal([]byte(r.NetworkJSON), &network); err != nil {
+		return nil
+	}
+	return &network
+}
+
+// GroupPorts returns the ports the sandbox accepts from its network group.
+func (r *SandboxRecord) GroupPorts() []model.PortRule {
+	var ports []model.PortRule
+	if r.GroupPortsJSON == "" {
+		return ports
+	}
+	_ = json.Unmarshal([]byte(r.GroupPortsJSON), &ports)
+	return ports
+}
+
 // ReconcileRunRecord stores one reconcile run.
 type ReconcileRunRecord struct {
 	ID          string
@@ -116,8 +163,29 @@ type SandboxMetadataQuery struct {
 	DeletedFrom     *time.Time
 	DeletedTo       *time.Time
 	DeletionPhase   string
-	Page            int
-	PageSize        int
+	Owner           string
+	Project         string
+	// Templates limits the list to sandboxes of these templates when not empty
+	Templates []string
+	Page      int
+	PageSize  int
+}
+
+// SandboxScope limits the sandboxes listed to those of one owner or project, and to
+// those of some templates. Empty fields don't limit the list.
+type SandboxScope struct {
+	OwnerID   string
+	Project   string
+	Templates []string
+}
+
+// templatesCondition returns a condition matching rows of one of templates.
+func templatesCondition(templates []string) (string, []any) {
+	args := make([]any, len(templates))
+	for i, name := range templates {
+		args[i] = name
+	}
+	return "template_name IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(templates)), ", ") + ")", args
 }
 
 type SandboxStatusHistoryRecord struct {
@@ -150,8 +218,10 @@ func (s *SandboxStore) Create(ctx context.Context, rec *SandboxRecord) error {
 			persistence_enabled, persistence_mode, persistence_size, storage_class_name, volume_claim_name, volume_reclaim_policy,
 			runtime_kind, runtime_name,
 			deletion_phase, deletion_started_at, deletion_last_attempt_at, deletion_next_retry_at, deletion_attempts, deletion_force_level, deletion_last_error,
-			created_at, expires_at, updated_at, deleted_at, stopped_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			created_at, expires_at, updated_at, deleted_at, stopped_at, ttl_mode, from_pool, network_json, network_group, group_ports_json,
+			egress_bandwidth, ingress_bandwidth, previous_access_token_sha256, previous_access_token_expires_at,
+			owner_id, owner_name, owner_api_key_id, project
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, rec.ID, rec.TemplateName, rec.TemplateVersion, rec.Image, rec.CPU, rec.Memory, rec.TTL, rec.EnvJSON,
 		rec.DesiredState, rec.LifecycleStatus, rec.StatusReason,
 		rec.ClusterNamespace, rec.PodName, rec.PodUID, rec.PodPhase, rec.PodIP, toNullTime(rec.LastSeenAt),
@@ -159,7 +229,9 @@ func (s *SandboxStore) Create(ctx context.Context, rec *SandboxRecord) error {
 		rec.PersistenceEnabled, rec.PersistenceMode, rec.PersistenceSize, rec.StorageClassName, rec.VolumeClaimName, rec.VolumeReclaimPolicy,
 		rec.RuntimeKind, rec.RuntimeName,
 		rec.DeletionPhase, toNullTime(rec.DeletionStartedAt), toNullTime(rec.DeletionLastAttemptAt), toNullTime(rec.DeletionNextRetryAt), rec.DeletionAttempts, rec.DeletionForceLevel, rec.DeletionLastError,
-		rec.CreatedAt, rec.ExpiresAt, rec.UpdatedAt, toNullTime(rec.DeletedAt), toNullTime(rec.StoppedAt),
+		rec.CreatedAt, rec.ExpiresAt, rec.UpdatedAt, toNullTime(rec.DeletedAt), toNullTime(rec.StoppedAt), rec.TTLMode, rec.FromPool, rec.NetworkJSON, rec.NetworkGroup, rec.GroupPortsJSON,
+		rec.EgressBandwidth, rec.IngressBandwidth, rec.PreviousAccessTokenSHA256, toNullTime(rec.PreviousAccessTokenExpiresAt),
+		rec.OwnerID, rec.OwnerName, rec.OwnerAPIKeyID, rec.Project,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create sandbox record: %w", err)
@@ -179,6 +251,59 @@ func (s *SandboxStore) GetByID(ctx context.Context, id string) (*SandboxRecord,
 	return rec, nil
 }
 
+// GetClusterNamespace returns the namespace a sandbox runs in, or "" if the sandbox
+// is unknown.
+func (s *SandboxStore) GetClusterNamespace(ctx context.Context, id string) (string, error) {
+	var namespace string
+	err := s.db.QueryRowContext(ctx, `SELECT cluster_namespace FROM sandboxes WHERE id = ?`, id).Scan(&namespace)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get sandbox namespace: %w", err)
+	}
+	return namespace, nil
+}
+
+// ListNetworkGroupOwners returns the owners of the sandboxes of a network group in a
+// namespace that are not deleted yet.
+func (s *SandboxStore) ListNetworkGroupOwners(ctx context.Context, namespace, group string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT owner_id FROM sandboxes
+		WHERE cluster_namespace = ? AND network_group = ? AND lifecycle_status <> ?
+	`, namespace, group, "deleted")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network group owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := make([]string, 0)
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			return nil, fmt.Errorf("failed to scan network group owner: %w", err)
+		}
+		owners = append(owners, owner)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate network group owners: %w", err)
+	}
+	return owners, nil
+}
+
+// CountActiveInProject returns the number of sandboxes of a project that are not
+// deleted yet, including those still being torn down.
+func (s *SandboxStore) CountActiveInProject(ctx context.Context, project string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(1) FROM sandboxes WHERE project = ? AND lifecycle_status <> ?
+	`, project, "deleted").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count project sandboxes: %w", err)
+	}
+	return count, nil
+}
+
 func (s *SandboxStore) ListActive(ctx context.Context) ([]SandboxRecord, error) {
 	rows, err := s.db.QueryContext(ctx, sandboxSelectSQL+`
          WHERE desired_state = ? AND lifecycle_status <> ?
@@ -193,13 +318,27 @@ func (s *SandboxStore) ListActive(ctx context.Context) ([]SandboxRecord, error)
 
 // ListForUser returns items visible to end users. When includeTerminating is true,
 // it includes sandboxes under deletion (desired=deleted, lifecycle=terminating).
-func (s *SandboxStore) ListForUser(ctx context.Context, includeTerminating bool) ([]SandboxRecord, error) {
-	where := `WHERE (desired_state = ? AND lifecycle_status <> ?)`
+func (s *SandboxStore) ListForUser(ctx context.Context, includeTerminating bool, scope SandboxScope) ([]SandboxRecord, error) {
+	where := `WHERE ((desired_state = ? AND lifecycle_status <> ?)`
 	args := []any{DesiredStateActive, "deleted"}
 	if includeTerminating {
 		where += ` OR (desired_state = ? AND lifecycle_status = ?)`
 		args = append(args, DesiredStateDeleted, "terminating")
 	}
+	where += `)`
+	if scope.OwnerID != "" {
+		where += ` AND owner_id = ?`
+		args = append(args, scope.OwnerID)
+	}
+	if scope.Project != "" {
+		where += ` AND project = ?`
+		args = append(args, scope.Project)
+	}
+	if len(scope.Templates) > 0 {
+		cond, condArgs := templatesCondition(scope.Templates)
+		where += ` AND ` + cond
+		args = append(args, condArgs...)
+	}
 	query := sandboxSelectSQL + " " + where + " ORDER BY created_at DESC"
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -220,6 +359,34 @@ func (s *SandboxStore) ListForReconcile(ctx context.Context) ([]SandboxRecord, e
 	return scanSandboxRows(rows)
 }
 
+// CountByLifecycleStatus returns the number of sandboxes in each lifecycle status,
+// leaving out deleted ones.
+func (s *SandboxStore) CountByLifecycleStatus(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT lifecycle_status, COUNT(*) FROM sandboxes
+		WHERE lifecycle_status <> ?
+		GROUP BY lifecycle_status
+	`, "deleted")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sandboxes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox count: %w", err)
+		}
+		counts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandbox counts: %w", err)
+	}
+	return counts, nil
+}
+
 func (s *SandboxStore) ListMetadata(ctx context.Context, query SandboxMetadataQuery) ([]SandboxRecord, int, error) {
 	if query.Page <= 0 {
 		query.Page = 1
@@ -269,6 +436,19 @@ func (s *SandboxStore) ListMetadata(ctx context.Context, query SandboxMetadataQu
 		where = append(where, "deletion_phase = ?")
 		args = append(args, query.DeletionPhase)
 	}
+	if query.Owner != "" {
+		where = append(where, "(owner_id = ? OR owner_name = ?)")
+		args = append(args, query.Owner, query.Owner)
+	}
+	if query.Project != "" {
+		where = append(where, "project = ?")
+		args = append(args, query.Project)
+	}
+	if len(query.Templates) > 0 {
+		cond, condArgs := templatesCondition(query.Templates)
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
 
 	whereSQL := ""
 	if len(where) > 0 {
@@ -302,9 +482,9 @@ func (s *SandboxStore) ListExpiredActive(ctx context.Context, now time.Time) ([]
 		 WHERE desired_state = ?
 		   AND ttl > 0
 		   AND expires_at <= ?
-		   AND lifecycle_status NOT IN (?, ?, ?)
+		   AND lifecycle_status NOT IN (?, ?, ?, ?)
 		 ORDER BY expires_at ASC
-	`, DesiredStateActive, now, "deleted", "terminating", "stopped")
+	`, DesiredStateActive, now, "deleted", "terminating", "stopped", "paused")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list expired sandboxes: %w", err)
 	}
@@ -452,6 +632,141 @@ func (s *SandboxStore) SetStarted(ctx context.Context, id string, newExpiresAt,
 	return nil
 }
 
+// SetPaused marks a sandbox paused and freezes its TTL from now on. stopped_at records
+// when the TTL clock stopped, as for stopped persistent sandboxes.
+func (s *SandboxStore) SetPaused(ctx context.Context, id, reason string, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE sandboxes
+		SET lifecycle_status = ?, status_reason = ?, stopped_at = ?,
+		    pod_phase = '', pod_ip = '', pod_uid = '', updated_at = ?
+		WHERE id = ?
+	`, "paused", reason, now, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to set paused: %w", err)
+	}
+	return nil
+}
+
+// SetUnpaused leaves the paused state, restarting the TTL clock with the given expiry.
+func (s *SandboxStore) SetUnpaused(ctx context.Context, id, status, reason string, newExpiresAt, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE sandboxes
+		SET lifecycle_status = ?, status_reason = ?, stopped_at = NULL, expires_at = ?, updated_at = ?
+		WHERE id = ? AND lifecycle_status = ?
+	`, status, reason, newExpiresAt, now, id, "paused")
+	if err != nil {
+		return fmt.Errorf("failed to set unpaused: %w", err)
+	}
+	return nil
+}
+
+// SetExpiresAt replaces the expiry of an active sandbox.
+func (s *SandboxStore) SetExpiresAt(ctx context.Context, id string, expiresAt, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE sandboxes
+		SET expires_at = ?, updated_at = ?
+		WHERE id = ? AND desired_state = ?
+	`, expiresAt, now, id, DesiredStateActive)
+	if err != nil {
+		return fmt.Errorf("failed to set expires_at: %w", err)
+	}
+	return nil
+}
+
+// RotateAccessToken replaces the access token of an active sandbox. The hash of the
+// replaced token is kept as the previous token until previousExpiresAt; a nil
+// previousExpiresAt drops it at once.
+func (s *SandboxStore) RotateAccessToken(ctx context.Context, id, ciphertext, nonce, keyID, tokenHash string, previousExpiresAt *time.Time, now time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sandboxes
+		SET previous_access_token_sha256 = CASE WHEN ? THEN access_token_sha256 ELSE '' END,
+			previous_access_token_expires_at = ?,
+			access_token_ciphertext = ?, access_token_nonce = ?, access_token_key_id = ?, access_token_sha256 = ?,
+			updated_at = ?
+		WHERE id = ? AND desired_state = ?
+	`, previousExpiresAt != nil, toNullTime(previousExpiresAt), ciphertext, nonce, keyID, tokenHash, now, id, DesiredStateActive)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate access token: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListAccessTokensNotUnderKey returns the sandboxes whose access token is not encrypted
+// under keyID.
+func (s *SandboxStore) ListAccessTokensNotUnderKey(ctx context.Context, keyID string) ([]SandboxRecord, error) {
+	rows, err := s.db.QueryContext(ctx, sandboxSelectSQL+" WHERE access_token_key_id != ?", keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandboxes by token key: %w", err)
+	}
+	defer rows.Close()
+	return scanSandboxRows(rows)
+}
+
+// ReencryptAccessToken stores the access token of a sandbox encrypted under a new key.
+// The row is only updated while the token is still encrypted under oldKeyID, so a
+// concurrent rotation is never overwritten.
+func (s *SandboxStore) ReencryptAccessToken(ctx context.Context, id, oldKeyID, ciphertext, nonce, keyID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sandboxes
+		SET access_token_ciphertext = ?, access_token_nonce = ?, access_token_key_id = ?
+		WHERE id = ? AND access_token_key_id = ?
+	`, ciphertext, nonce, keyID, id, oldKeyID)
+	if err != nil {
+		return false, fmt.Errorf("failed to re-encrypt access token: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// SetNetwork replaces the per-sandbox network override. An empty networkJSON makes the
+// sandbox follow its template again.
+func (s *SandboxStore) SetNetwork(ctx context.Context, id, networkJSON string, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE sandboxes
+		SET network_json = ?, updated_at = ?
+		WHERE id = ? AND desired_state = ?
+	`, networkJSON, now, id, DesiredStateActive)
+	if err != nil {
+		return fmt.Errorf("failed to set network: %w", err)
+	}
+	return nil
+}
+
+// TouchIdleTTL pushes the expiry of a sandbox in idle TTL mode to now + ttl. To keep
+// busy sandboxes from writing on every request, the expiry only moves once it would
+// advance by at least idleTTLTouchStep. Stopped and paused sandboxes are left alone,
+// since their TTL clock is frozen.
+func (s *SandboxStore) TouchIdleTTL(ctx context.Context, rec *SandboxRecord, now time.Time) error {
+	if model.TTLMode(rec.TTLMode) != model.TTLModeIdle || rec.TTL <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(time.Duration(rec.TTL) * time.Second)
+	if expiresAt.Sub(rec.ExpiresAt) < idleTTLTouchStep(rec.TTL) {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE sandboxes
+		SET expires_at = ?, updated_at = ?
+		WHERE id = ? AND desired_state = ? AND expires_at < ?
+		  AND lifecycle_status NOT IN (?, ?, ?, ?)
+	`, expiresAt, now, rec.ID, DesiredStateActive, expiresAt, "deleted", "terminating", "stopped", "paused")
+	if err != nil {
+		return fmt.Errorf("failed to touch idle ttl: %w", err)
+	}
+	rec.ExpiresAt = expiresAt
+	return nil
+}
+
+// idleTTLTouchStep is a tenth of the TTL, capped at one minute.
+func idleTTLTouchStep(ttl int) time.Duration {
+	step := time.Duration(ttl) * time.Second / 10
+	if step > time.Minute {
+		step = time.Minute
+	}
+	return step
+}
+
 func (s *SandboxStore) EnsureStoppedAt(ctx context.Context, id string, now time.Time) error {
 	_, err := s.db.ExecContext(ctx, `
 		UPDATE sandboxes
@@ -676,6 +991,7 @@ type PurgeHistoryResult struct {
 	DeletedStatusHistory  int64
 	DeletedReconcileRuns  int64
 	DeletedReconcileItems int64
+	DeletedNetworkEvents  int64
 }
 
 // PurgeHistoricalData deletes historical records older than cutoff.
@@ -719,7 +1035,17 @@ func (s *SandboxStore) PurgeHistoricalData(ctx context.Context, cutoff time.Time
 	}
 	result.DeletedStatusHistory, _ = res.RowsAffected()
 
-	// 4) Purge deleted sandboxes only.
+	// 4) Purge network audit events.
+	res, err = tx.ExecContext(ctx, `
+		DELETE FROM sandbox_network_events
+		WHERE observed_at < ?
+	`, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge network events: %w", err)
+	}
+	result.DeletedNetworkEvents, _ = res.RowsAffected()
+
+	// 5) Purge deleted sandboxes only.
 	res, err = tx.ExecContext(ctx, `
 		DELETE FROM sandboxes
 		WHERE lifecycle_status = 'deleted'
@@ -747,7 +1073,9 @@ SELECT
 	persistence_enabled, persistence_mode, persistence_size, storage_class_name, volume_claim_name, volume_reclaim_policy,
 	runtime_kind, runtime_name,
 	deletion_phase, deletion_started_at, deletion_last_attempt_at, deletion_next_retry_at, deletion_attempts, deletion_force_level, deletion_last_error,
-	created_at, expires_at, updated_at, deleted_at, stopped_at
+	created_at, expires_at, updated_at, deleted_at, stopped_at, ttl_mode, from_pool, network_json, network_group, group_ports_json,
+	egress_bandwidth, ingress_bandwidth, previous_access_token_sha256, previous_access_token_expires_at,
+	owner_id, owner_name, owner_api_key_id, project
 FROM sandboxes`
 
 func scanSandbox(row interface{ Scan(dest ...any) error }) (*SandboxRecord, error) {
@@ -758,6 +1086,7 @@ func scanSandbox(row interface{ Scan(dest ...any) error }) (*SandboxRecord, erro
 	var deletionStartedAt sql.NullTime
 	var deletionLastAttemptAt sql.NullTime
 	var deletionNextRetryAt sql.NullTime
+	var previousAccessTokenExpiresAt sql.NullTime
 	if err := row.Scan(
 		&rec.ID, &rec.TemplateName, &rec.TemplateVersion, &rec.Image, &rec.CPU, &rec.Memory, &rec.TTL, &rec.EnvJSON,
 		&rec.DesiredState, &rec.LifecycleStatus, &rec.StatusReason,
@@ -766,7 +1095,9 @@ func scanSandbox(row interface{ Scan(dest ...any) error }) (*SandboxRecord, erro
 		&rec.PersistenceEnabled, &rec.PersistenceMode, &rec.PersistenceSize, &rec.StorageClassName, &rec.VolumeClaimName, &rec.VolumeReclaimPolicy,
 		&rec.RuntimeKind, &rec.RuntimeName,
 		&rec.DeletionPhase, &deletionStartedAt, &deletionLastAttemptAt, &deletionNextRetryAt, &rec.DeletionAttempts, &rec.DeletionForceLevel, &rec.DeletionLastError,
-		&rec.CreatedAt, &rec.ExpiresAt, &rec.UpdatedAt, &deletedAt, &stoppedAt,
+		&rec.CreatedAt, &rec.ExpiresAt, &rec.UpdatedAt, &deletedAt, &stoppedAt, &rec.TTLMode, &rec.FromPool, &rec.NetworkJSON, &rec.NetworkGroup, &rec.GroupPortsJSON,
+		&rec.EgressBandwidth, &rec.IngressBandwidth, &rec.PreviousAccessTokenSHA256, &previousAccessTokenExpiresAt,
+		&rec.OwnerID, &rec.OwnerName, &rec.OwnerAPIKeyID, &rec.Project,
 	); err != nil {
 		return nil, err
 	}
@@ -794,6 +1125,10 @@ func scanSandbox(row interface{ Scan(dest ...any) error }) (*SandboxRecord, erro
 		t := deletionNextRetryAt.Time
 		rec.DeletionNextRetryAt = &t
 	}
+	if previousAccessTokenExpiresAt.Valid {
+		t := previousAccessTokenExpiresAt.Time
+		rec.PreviousAccessTokenExpiresAt = &t
+	}
 	return &rec, nil
 }
 
@@ -529,6 +529,69 @@ func TestSandboxStoreListMetadata(t *testing.T) {
 	}
 }
 
+func TestSandboxStoreListByOwner(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewSandboxStore()
+	now := time.Now().UTC()
+
+	for _, owner := range []struct{ id, userID, username string }{
+		{"own-1", "u-alice", "alice"},
+		{"own-2", "u-bob", "bob"},
+		{"own-3", "", ""},
+	} {
+		rec := &SandboxRecord{
+			ID:                    owner.id,
+			TemplateName:          "python",
+			TemplateVersion:       1,
+			Image:                 "python:3.11",
+			CPU:                   "500m",
+			Memory:                "512Mi",
+			TTL:                   3600,
+			EnvJSON:               `{}`,
+			DesiredState:          DesiredStateActive,
+			LifecycleStatus:       "running",
+			ClusterNamespace:      "liteboxd-sandbox",
+			PodName:               "sandbox-" + owner.id,
+			AccessTokenCiphertext: "cipher",
+			AccessTokenNonce:      "nonce",
+			AccessTokenKeyID:      "v1",
+			AccessTokenSHA256:     "hash",
+			AccessURL:             "http://gateway/" + owner.id,
+			CreatedAt:             now,
+			ExpiresAt:             now.Add(time.Hour),
+			UpdatedAt:             now,
+			OwnerID:               owner.userID,
+			OwnerName:             owner.username,
+		}
+		if err := s.Create(ctx, rec); err != nil {
+			t.Fatalf("Create %s error = %v", owner.id, err)
+		}
+	}
+
+	got, err := s.GetByID(ctx, "own-1")
+	if err != nil || got == nil || got.OwnerID != "u-alice" || got.OwnerName != "alice" {
+		t.Fatalf("GetByID(own-1) = %+v, %v", got, err)
+	}
+
+	all, err := s.ListForUser(ctx, false, SandboxScope{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("ListForUser() without owner = %d items, %v; want 3", len(all), err)
+	}
+	owned, err := s.ListForUser(ctx, false, SandboxScope{OwnerID: "u-bob"})
+	if err != nil || len(owned) != 1 || owned[0].ID != "own-2" {
+		t.Fatalf("ListForUser(u-bob) = %+v, %v", owned, err)
+	}
+
+	// Owner matches the user ID or the username
+	for _, owner := range []string{"u-alice", "alice"} {
+		items, total, err := s.ListMetadata(ctx, SandboxMetadataQuery{Owner: owner, Page: 1, PageSize: 20})
+		if err != nil || total != 1 || len(items) != 1 || items[0].ID != "own-1" {
+			t.Fatalf("ListMetadata(owner=%s) = %+v, %d, %v", owner, items, total, err)
+		}
+	}
+}
+
 func TestSandboxStoreListExpiredActiveSkipsTTLZero(t *testing.T) {
 	initTestDB(t)
 	ctx := context.Background()
@@ -748,6 +811,9 @@ func TestSandboxStoreListExpiredActiveExcludesStopped(t *testing.T) {
 	if err := s.Create(ctx, mk("exp-stopped", "stopped")); err != nil {
 		t.Fatalf("Create stopped error = %v", err)
 	}
+	if err := s.Create(ctx, mk("exp-paused", "paused")); err != nil {
+		t.Fatalf("Create paused error = %v", err)
+	}
 
 	expired, err := s.ListExpiredActive(ctx, now)
 	if err != nil {
@@ -900,3 +966,73 @@ func TestSandboxStoreListStatusHistory(t *testing.T) {
 		t.Fatalf("ListStatusHistory before_id unexpected: %+v", paged)
 	}
 }
+
+func TestSandboxStoreTouchIdleTTL(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	s := NewSandboxStore()
+	now := time.Now().UTC()
+
+	mk := func(id, lifecycle, mode string) *SandboxRecord {
+		return &SandboxRecord{
+			ID:                    id,
+			TemplateName:          "python",
+			TemplateVersion:       1,
+			Image:                 "python:3.11",
+			CPU:                   "500m",
+			Memory:                "512Mi",
+			TTL:                   3600,
+			TTLMode:               mode,
+			EnvJSON:               `{}`,
+			DesiredState:          DesiredStateActive,
+			LifecycleStatus:       lifecycle,
+			ClusterNamespace:      "liteboxd-sandbox",
+			PodName:               "sandbox-" + id,
+			AccessTokenCiphertext: "cipher",
+			AccessTokenNonce:      "nonce",
+			AccessTokenKeyID:      "v1",
+			AccessTokenSHA256:     "hash",
+			AccessURL:             "http://gateway/" + id,
+			CreatedAt:             now.Add(-time.Hour),
+			ExpiresAt:             now.Add(time.Minute),
+			UpdatedAt:             now.Add(-time.Hour),
+		}
+	}
+	for _, rec := range []*SandboxRecord{
+		mk("idle-running", "running", "idle"),
+		mk("idle-paused", "paused", "idle"),
+		mk("fixed-running", "running", "fixed"),
+	} {
+		if err := s.Create(ctx, rec); err != nil {
+			t.Fatalf("Create(%s) error = %v", rec.ID, err)
+		}
+		if err := s.TouchIdleTTL(ctx, rec, now); err != nil {
+			t.Fatalf("TouchIdleTTL(%s) error = %v", rec.ID, err)
+		}
+	}
+
+	want := map[string]time.Time{
+		"idle-running":  now.Add(time.Hour),
+		"idle-paused":   now.Add(time.Minute),
+		"fixed-running": now.Add(time.Minute),
+	}
+	for id, expires := range want {
+		got, err := s.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%s) error = %v", id, err)
+		}
+		if got.ExpiresAt.Sub(expires).Abs() > time.Second {
+			t.Errorf("%s ExpiresAt = %v, want %v", id, got.ExpiresAt, expires)
+		}
+	}
+
+	// Activity within the throttle step does not write again
+	rec, _ := s.GetByID(ctx, "idle-running")
+	if err := s.TouchIdleTTL(ctx, rec, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("TouchIdleTTL() error = %v", err)
+	}
+	got, _ := s.GetByID(ctx, "idle-running")
+	if got.ExpiresAt.Sub(now.Add(time.Hour)).Abs() > time.Second {
+		t.Fatalf("ExpiresAt moved within the throttle step: %v", got.ExpiresAt)
+	}
+}
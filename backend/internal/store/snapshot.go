@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SandboxSnapshotRecord is the persisted state of a sandbox snapshot.
+type SandboxSnapshotRecord struct {
+	ID                 string
+	SandboxID          string
+	Name               string
+	TemplateName       string
+	TemplateVersion    int
+	Kind               string
+	Status             string
+	StatusReason       string
+	StorageClassName   string
+	VolumeSize         string
+	VolumeSnapshotName string
+	SizeBytes          int64
+	CreatedAt          time.Time
+	ReadyAt            *time.Time
+	UpdatedAt          time.Time
+	// OwnerID and Project are copied from the source sandbox
+	OwnerID string
+	Project string
+}
+
+// SandboxSnapshotStore handles snapshot persistence.
+type SandboxSnapshotStore struct {
+	db *sql.DB
+}
+
+// NewSandboxSnapshotStore creates a new SandboxSnapshotStore.
+func NewSandboxSnapshotStore() *SandboxSnapshotStore {
+	return &SandboxSnapshotStore{db: DB}
+}
+
+// Create inserts a new snapshot record.
+func (s *SandboxSnapshotStore) Create(ctx context.Context, rec *SandboxSnapshotRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sandbox_snapshots (
+			id, sandbox_id, name, template_name, template_version, kind, status, status_reason,
+			storage_class_name, volume_size, volume_snapshot_name, size_bytes,
+			created_at, ready_at, updated_at, owner_id, project
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.ID, rec.SandboxID, rec.Name, rec.TemplateName, rec.TemplateVersion, rec.Kind, rec.Status, rec.StatusReason,
+		rec.StorageClassName, rec.VolumeSize, rec.VolumeSnapshotName, rec.SizeBytes,
+		rec.CreatedAt, toNullTime(rec.ReadyAt), rec.UpdatedAt, rec.OwnerID, rec.Project)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox snapshot: %w", err)
+	}
+	return nil
+}
+
+// Get returns a snapshot by ID, or nil if not found.
+func (s *SandboxSnapshotStore) Get(ctx context.Context, id string) (*SandboxSnapshotRecord, error) {
+	row := s.db.QueryRowContext(ctx, sandboxSnapshotSelectSQL+" WHERE id = ?", id)
+	rec, err := scanSandboxSnapshot(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sandbox snapshot: %w", err)
+	}
+	return rec, nil
+}
+
+// List returns snapshots, newest first. An empty sandboxID returns the snapshots of
+// all sandboxes. The scope limits the snapshots to those of one owner or project and
+// of some templates.
+func (s *SandboxSnapshotStore) List(ctx context.Context, sandboxID string, scope SandboxScope) ([]SandboxSnapshotRecord, error) {
+	var conds []string
+	var args []any
+	if sandboxID != "" {
+		conds = append(conds, "sandbox_id = ?")
+		args = append(args, sandboxID)
+	}
+	if scope.OwnerID != "" {
+		conds = append(conds, "owner_id = ?")
+		args = append(args, scope.OwnerID)
+	}
+	if scope.Project != "" {
+		conds = append(conds, "project = ?")
+		args = append(args, scope.Project)
+	}
+	if len(scope.Templates) > 0 {
+		cond, condArgs := templatesCondition(scope.Templates)
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	query := sandboxSnapshotSelectSQL
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY created_at DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandbox snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]SandboxSnapshotRecord, 0)
+	for rows.Next() {
+		rec, err := scanSandboxSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox snapshot: %w", err)
+		}
+		items = append(items, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandbox snapshots: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateStatus records the outcome of a pending snapshot. Only pending snapshots are
+// updated, so a terminal status is never overwritten by a late observation.
+func (s *SandboxSnapshotStore) UpdateStatus(ctx context.Context, id, status, reason string, sizeBytes int64, readyAt *time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sandbox_snapshots
+		SET status = ?, status_reason = ?, size_bytes = ?, ready_at = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, status, reason, sizeBytes, toNullTime(readyAt), time.Now().UTC(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to update sandbox snapshot status: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Delete removes a snapshot record.
+func (s *SandboxSnapshotStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sandbox_snapshots WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete sandbox snapshot: %w", err)
+	}
+	return nil
+}
+
+const sandboxSnapshotSelectSQL = `
+SELECT
+	id, sandbox_id, name, template_name, template_version, kind, status, status_reason,
+	storage_class_name, volume_size, volume_snapshot_name, size_bytes,
+	created_at, ready_at, updated_at, owner_id, project
+FROM sandbox_snapshots`
+
+func scanSandboxSnapshot(scanner interface{ Scan(dest ...any) error }) (*SandboxSnapshotRecord, error) {
+	var rec SandboxSnapshotRecord
+	var readyAt sql.NullTime
+	if err := scanner.Scan(
+		&rec.ID, &rec.SandboxID, &rec.Name, &rec.TemplateName, &rec.TemplateVersion, &rec.Kind, &rec.Status, &rec.StatusReason,
+		&rec.StorageClassName, &rec.VolumeSize, &rec.VolumeSnapshotName, &rec.SizeBytes,
+		&rec.CreatedAt, &readyAt, &rec.UpdatedAt, &rec.OwnerID, &rec.Project,
+	); err != nil {
+		return nil, err
+	}
+	if readyAt.Valid {
+		t := readyAt.Time
+		rec.ReadyAt = &t
+	}
+	return &rec, nil
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSandboxSnapshotStoreFlow(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	snapshots := NewSandboxSnapshotStore()
+	now := time.Now().UTC()
+
+	for i, id := range []string{"snap-1", "snap-2"} {
+		if err := snapshots.Create(ctx, &SandboxSnapshotRecord{
+			ID:              id,
+			SandboxID:       "sbx-" + id,
+			TemplateName:    "python",
+			TemplateVersion: 2,
+			Kind:            "archive",
+			Status:          "pending",
+			OwnerID:         "u-" + id,
+			Project:         "team-a",
+			CreatedAt:       now.Add(time.Duration(i) * time.Second),
+			UpdatedAt:       now,
+		}); err != nil {
+			t.Fatalf("Create(%s) error = %v", id, err)
+		}
+	}
+
+	updated, err := snapshots.UpdateStatus(ctx, "snap-1", "ready", "", 1024, &now)
+	if err != nil || !updated {
+		t.Fatalf("UpdateStatus() = %v, %v", updated, err)
+	}
+	// Terminal states are never overwritten.
+	updated, err = snapshots.UpdateStatus(ctx, "snap-1", "failed", "late", 0, nil)
+	if err != nil || updated {
+		t.Fatalf("UpdateStatus() on ready snapshot = %v, %v; want false", updated, err)
+	}
+
+	got, err := snapshots.Get(ctx, "snap-1")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if got.Status != "ready" || got.SizeBytes != 1024 || got.ReadyAt == nil || got.TemplateVersion != 2 {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+
+	all, err := snapshots.List(ctx, "", SandboxScope{})
+	if err != nil || len(all) != 2 || all[0].ID != "snap-2" {
+		t.Fatalf("List() = %+v, %v; want newest first", all, err)
+	}
+	filtered, err := snapshots.List(ctx, "sbx-snap-1", SandboxScope{})
+	if err != nil || len(filtered) != 1 || filtered[0].ID != "snap-1" {
+		t.Fatalf("List(sandbox) = %+v, %v", filtered, err)
+	}
+	owned, err := snapshots.List(ctx, "", SandboxScope{OwnerID: "u-snap-2", Project: "team-a"})
+	if err != nil || len(owned) != 1 || owned[0].ID != "snap-2" {
+		t.Fatalf("List(owner) = %+v, %v", owned, err)
+	}
+	if other, err := snapshots.List(ctx, "", SandboxScope{Project: "team-b"}); err != nil || len(other) != 0 {
+		t.Fatalf("List(project) = %+v, %v; want none", other, err)
+	}
+
+	if err := snapshots.Delete(ctx, "snap-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := snapshots.Get(ctx, "snap-1"); err != nil || got != nil {
+		t.Fatalf("Get() after delete = %v, %v; want nil, nil", got, err)
+	}
+}
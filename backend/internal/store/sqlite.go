@@ -60,12 +60,19 @@ func createTables() error {
 			is_public BOOLEAN DEFAULT 1,
 			latest_version INTEGER DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			project TEXT NOT NULL DEFAULT ''
 		)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to create templates table: %w", err)
 	}
+	// Templates without a project are global
+	if err := ensureColumns("templates", map[string]string{
+		"project": "TEXT NOT NULL DEFAULT ''",
+	}); err != nil {
+		return err
+	}
 
 	// Create template_versions table
 	_, err = DB.Exec(`
@@ -192,6 +199,8 @@ func createTables() error {
 		"CREATE INDEX IF NOT EXISTS idx_sandboxes_last_seen_at ON sandboxes(last_seen_at)",
 		"CREATE INDEX IF NOT EXISTS idx_sandboxes_template_name ON sandboxes(template_name)",
 		"CREATE INDEX IF NOT EXISTS idx_sandboxes_access_token_sha256 ON sandboxes(access_token_sha256)",
+		"CREATE INDEX IF NOT EXISTS idx_sandboxes_owner_id ON sandboxes(owner_id)",
+		"CREATE INDEX IF NOT EXISTS idx_sandboxes_project ON sandboxes(project)",
 	}
 	for _, idx := range sandboxIndexes {
 		if _, err := DB.Exec(idx); err != nil {
@@ -256,12 +265,209 @@ func createTables() error {
 		return fmt.Errorf("failed to create sandbox reconcile items index: %w", err)
 	}
 
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS sandbox_processes (
+			id TEXT PRIMARY KEY,
+			sandbox_id TEXT NOT NULL,
+			command_json TEXT NOT NULL DEFAULT '[]',
+			work_dir TEXT NOT NULL DEFAULT '',
+			pid INTEGER NOT NULL DEFAULT 0,
+			pod_uid TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			exit_code INTEGER,
+			started_at TIMESTAMP NOT NULL,
+			finished_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (sandbox_id) REFERENCES sandboxes(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox_processes table: %w", err)
+	}
+	if _, err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_sandbox_processes_sid_started ON sandbox_processes(sandbox_id, started_at DESC)"); err != nil {
+		return fmt.Errorf("failed to create sandbox processes index: %w", err)
+	}
+
+	// Snapshots are kept after their sandbox is deleted, so there is no foreign key.
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS sandbox_snapshots (
+			id TEXT PRIMARY KEY,
+			sandbox_id TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			template_name TEXT NOT NULL,
+			template_version INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			status TEXT NOT NULL,
+			status_reason TEXT NOT NULL DEFAULT '',
+			storage_class_name TEXT NOT NULL DEFAULT '',
+			volume_size TEXT NOT NULL DEFAULT '',
+			volume_snapshot_name TEXT NOT NULL DEFAULT '',
+			size_bytes INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			ready_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL,
+			owner_id TEXT NOT NULL DEFAULT '',
+			project TEXT NOT NULL DEFAULT ''
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox_snapshots table: %w", err)
+	}
+	if err := ensureColumns("sandbox_snapshots", map[string]string{
+		"owner_id": "TEXT NOT NULL DEFAULT ''",
+		"project":  "TEXT NOT NULL DEFAULT ''",
+	}); err != nil {
+		return err
+	}
+	// Snapshots taken before they recorded an owner belong to the owner of their sandbox
+	if _, err := DB.Exec(`
+		UPDATE sandbox_snapshots SET
+			owner_id = COALESCE((SELECT owner_id FROM sandboxes WHERE sandboxes.id = sandbox_snapshots.sandbox_id), ''),
+			project = COALESCE((SELECT project FROM sandboxes WHERE sandboxes.id = sandbox_snapshots.sandbox_id), '')
+		WHERE owner_id = ''
+	`); err != nil {
+		return fmt.Errorf("failed to backfill snapshot owners: %w", err)
+	}
+	if _, err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_sandbox_snapshots_sid_created ON sandbox_snapshots(sandbox_id, created_at DESC)"); err != nil {
+		return fmt.Errorf("failed to create sandbox snapshots index: %w", err)
+	}
+
+	// Pool members become sandboxes when claimed, so there is no foreign key.
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS sandbox_pool_members (
+			id TEXT PRIMARY KEY,
+			template_name TEXT NOT NULL,
+			template_version INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			status_reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			ready_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox_pool_members table: %w", err)
+	}
+	if _, err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_sandbox_pool_members_template_status ON sandbox_pool_members(template_name, status)"); err != nil {
+		return fmt.Errorf("failed to create sandbox pool members index: %w", err)
+	}
+
+	// Network events are audit records kept until retention purges them, even after
+	// their sandbox is deleted, so there is no foreign key.
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS sandbox_network_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sandbox_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			domain TEXT NOT NULL DEFAULT '',
+			destination_ip TEXT NOT NULL DEFAULT '',
+			port INTEGER NOT NULL DEFAULT 0,
+			protocol TEXT NOT NULL DEFAULT '',
+			verdict TEXT NOT NULL,
+			observed_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox_network_events table: %w", err)
+	}
+	if _, err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_sandbox_network_events_sid_observed ON sandbox_network_events(sandbox_id, observed_at DESC)"); err != nil {
+		return fmt.Errorf("failed to create sandbox network events index: %w", err)
+	}
+	if _, err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_sandbox_network_events_observed ON sandbox_network_events(observed_at)"); err != nil {
+		return fmt.Errorf("failed to create sandbox network events index: %w", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS sandbox_preview_links (
+			id TEXT PRIMARY KEY,
+			sandbox_id TEXT NOT NULL,
+			token_sha256 TEXT NOT NULL UNIQUE,
+			port INTEGER NOT NULL,
+			path_prefix TEXT NOT NULL DEFAULT '',
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (sandbox_id) REFERENCES sandboxes(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox_preview_links table: %w", err)
+	}
+	if _, err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_sandbox_preview_links_sid_created ON sandbox_preview_links(sandbox_id, created_at DESC)"); err != nil {
+		return fmt.Errorf("failed to create sandbox preview links index: %w", err)
+	}
+
+	// Create sandbox_traffic table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS sandbox_traffic (
+			sandbox_id TEXT PRIMARY KEY,
+			bytes_in INTEGER NOT NULL DEFAULT 0,
+			bytes_out INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (sandbox_id) REFERENCES sandboxes(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox_traffic table: %w", err)
+	}
+
+	// Create projects table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS projects (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			namespace TEXT NOT NULL UNIQUE,
+			description TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create projects table: %w", err)
+	}
+
+	// Create quotas table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS quotas (
+			scope TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			max_sandboxes INTEGER NOT NULL DEFAULT 0,
+			max_cpu TEXT NOT NULL DEFAULT '',
+			max_memory TEXT NOT NULL DEFAULT '',
+			max_storage TEXT NOT NULL DEFAULT '',
+			max_ttl INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (scope, subject)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create quotas table: %w", err)
+	}
+
+	// Quota reservations count sandboxes that were admitted but aren't counted by their
+	// own row yet
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS quota_reservations (
+			id TEXT PRIMARY KEY,
+			owner_id TEXT NOT NULL DEFAULT '',
+			owner_api_key_id TEXT NOT NULL DEFAULT '',
+			project TEXT NOT NULL DEFAULT '',
+			cpu TEXT NOT NULL DEFAULT '',
+			memory TEXT NOT NULL DEFAULT '',
+			storage TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create quota_reservations table: %w", err)
+	}
+
 	// Create admin_users table
 	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS admin_users (
 			id TEXT PRIMARY KEY,
 			username TEXT NOT NULL UNIQUE,
 			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL DEFAULT 'admin',
+			disabled BOOLEAN NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
@@ -269,6 +475,13 @@ func createTables() error {
 	if err != nil {
 		return fmt.Errorf("failed to create admin_users table: %w", err)
 	}
+	// Users created before roles existed were all administrators
+	if err := ensureColumns("admin_users", map[string]string{
+		"role":     "TEXT NOT NULL DEFAULT 'admin'",
+		"disabled": "BOOLEAN NOT NULL DEFAULT 0",
+	}); err != nil {
+		return err
+	}
 
 	// Create sessions table
 	_, err = DB.Exec(`
@@ -296,12 +509,34 @@ func createTables() error {
 			key_hash TEXT NOT NULL,
 			expires_at TIMESTAMP,
 			last_used_at TIMESTAMP,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			user_id TEXT REFERENCES admin_users(id) ON DELETE CASCADE,
+			project TEXT NOT NULL DEFAULT '',
+			scopes TEXT NOT NULL DEFAULT '',
+			templates TEXT NOT NULL DEFAULT '',
+			allowed_cidrs TEXT NOT NULL DEFAULT ''
 		)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to create api_keys table: %w", err)
 	}
+	if err := ensureColumns("api_keys", map[string]string{
+		"user_id":       "TEXT REFERENCES admin_users(id) ON DELETE CASCADE",
+		"project":       "TEXT NOT NULL DEFAULT ''",
+		"scopes":        "TEXT NOT NULL DEFAULT ''",
+		"templates":     "TEXT NOT NULL DEFAULT ''",
+		"allowed_cidrs": "TEXT NOT NULL DEFAULT ''",
+	}); err != nil {
+		return err
+	}
+	// Keys created before they were bound to users belong to the first admin
+	if _, err := DB.Exec(`
+		UPDATE api_keys SET user_id = (
+			SELECT id FROM admin_users WHERE role = 'admin' ORDER BY created_at LIMIT 1
+		) WHERE user_id IS NULL
+	`); err != nil {
+		return fmt.Errorf("failed to assign api keys to admin: %w", err)
+	}
 	// Drop existing non-unique index to replace with UNIQUE index (idempotent migration)
 	if _, err := DB.Exec("DROP INDEX IF EXISTS idx_api_keys_key_hash"); err != nil {
 		return fmt.Errorf("failed to drop old api_keys index: %w", err)
@@ -309,6 +544,7 @@ func createTables() error {
 	apiKeyIndexes := []string{
 		"CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash)",
 		"CREATE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys(prefix)",
+		"CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id)",
 	}
 	for _, idx := range apiKeyIndexes {
 		if _, err := DB.Exec(idx); err != nil {
@@ -337,12 +573,31 @@ func ensureSandboxColumns() error {
 		"deletion_attempts":        "INTEGER NOT NULL DEFAULT 0",
 		"deletion_force_level":     "INTEGER NOT NULL DEFAULT 0",
 		"deletion_last_error":      "TEXT NOT NULL DEFAULT ''",
+		"ttl_mode":                 "TEXT NOT NULL DEFAULT ''",
+		"from_pool":                "BOOLEAN NOT NULL DEFAULT 0",
+		"network_json":             "TEXT NOT NULL DEFAULT ''",
+		"network_group":            "TEXT NOT NULL DEFAULT ''",
+		"group_ports_json":         "TEXT NOT NULL DEFAULT ''",
+		"egress_bandwidth":         "TEXT NOT NULL DEFAULT ''",
+		"ingress_bandwidth":        "TEXT NOT NULL DEFAULT ''",
+
+		"previous_access_token_sha256":     "TEXT NOT NULL DEFAULT ''",
+		"previous_access_token_expires_at": "TIMESTAMP",
+		"owner_id":                         "TEXT NOT NULL DEFAULT ''",
+		"owner_name":                       "TEXT NOT NULL DEFAULT ''",
+		"owner_api_key_id":                 "TEXT NOT NULL DEFAULT ''",
+		"project":                          "TEXT NOT NULL DEFAULT ''",
 	}
 
+	return ensureColumns("sandboxes", columns)
+}
+
+// ensureColumns adds the columns missing from an existing table.
+func ensureColumns(table string, columns map[string]string) error {
 	existing := map[string]struct{}{}
-	rows, err := DB.Query(`PRAGMA table_info(sandboxes)`)
+	rows, err := DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return fmt.Errorf("failed to inspect sandboxes table schema: %w", err)
+		return fmt.Errorf("failed to inspect %s table schema: %w", table, err)
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -352,7 +607,7 @@ func ensureSandboxColumns() error {
 		var dfltValue sql.NullString
 		var pk int
 		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
-			return fmt.Errorf("failed to scan sandboxes schema row: %w", err)
+			return fmt.Errorf("failed to scan %s schema row: %w", table, err)
 		}
 		existing[name] = struct{}{}
 	}
@@ -360,9 +615,9 @@ func ensureSandboxColumns() error {
 		if _, ok := existing[name]; ok {
 			continue
 		}
-		stmt := fmt.Sprintf("ALTER TABLE sandboxes ADD COLUMN %s %s", name, ddl)
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, ddl)
 		if _, err := DB.Exec(stmt); err != nil {
-			return fmt.Errorf("failed to add sandboxes.%s: %w", name, err)
+			return fmt.Errorf("failed to add %s.%s: %w", table, name, err)
 		}
 	}
 	return nil
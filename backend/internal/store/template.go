@@ -57,16 +57,17 @@ func (s *TemplateStore) Create(ctx context.Context, req *model.CreateTemplateReq
 		Author:        "",
 		IsPublic:      isPublic,
 		LatestVersion: 1,
+		Project:       req.Project,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
 
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO templates (id, name, display_name, description, tags, author, is_public, latest_version, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO templates (id, name, display_name, description, tags, author, is_public, latest_version, project, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, template.ID, template.Name, template.DisplayName, template.Description,
 		template.MarshalTags(), template.Author, template.IsPublic,
-		template.LatestVersion, template.CreatedAt, template.UpdatedAt)
+		template.LatestVersion, template.Project, template.CreatedAt, template.UpdatedAt)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			return nil, fmt.Errorf("template with name '%s' already exists", req.Name)
@@ -108,12 +109,12 @@ func (s *TemplateStore) Get(ctx context.Context, name string) (*model.Template,
 	var tagsJSON string
 
 	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, display_name, description, tags, author, is_public, latest_version, created_at, updated_at
+		SELECT id, name, display_name, description, tags, author, is_public, latest_version, project, created_at, updated_at
 		FROM templates WHERE name = ?
 	`, name).Scan(
 		&template.ID, &template.Name, &template.DisplayName, &template.Description,
 		&tagsJSON, &template.Author, &template.IsPublic,
-		&template.LatestVersion, &template.CreatedAt, &template.UpdatedAt,
+		&template.LatestVersion, &template.Project, &template.CreatedAt, &template.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -144,12 +145,12 @@ func (s *TemplateStore) GetByID(ctx context.Context, id string) (*model.Template
 	var tagsJSON string
 
 	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, display_name, description, tags, author, is_public, latest_version, created_at, updated_at
+		SELECT id, name, display_name, description, tags, author, is_public, latest_version, project, created_at, updated_at
 		FROM templates WHERE id = ?
 	`, id).Scan(
 		&template.ID, &template.Name, &template.DisplayName, &template.Description,
 		&tagsJSON, &template.Author, &template.IsPublic,
-		&template.LatestVersion, &template.CreatedAt, &template.UpdatedAt,
+		&template.LatestVersion, &template.Project, &template.CreatedAt, &template.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -188,6 +189,16 @@ func (s *TemplateStore) List(ctx context.Context, opts model.TemplateListOptions
 		searchPattern := "%" + opts.Search + "%"
 		args = append(args, searchPattern, searchPattern, searchPattern)
 	}
+	if opts.Project != "" {
+		conditions = append(conditions, "(project = '' OR project = ?)")
+		args = append(args, opts.Project)
+	}
+	if len(opts.Names) > 0 {
+		conditions = append(conditions, "name IN (?"+strings.Repeat(", ?", len(opts.Names)-1)+")")
+		for _, name := range opts.Names {
+			args = append(args, name)
+		}
+	}
 
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -204,7 +215,7 @@ func (s *TemplateStore) List(ctx context.Context, opts model.TemplateListOptions
 	// Query items
 	offset := (opts.Page - 1) * opts.PageSize
 	query := fmt.Sprintf(`
-		SELECT id, name, display_name, description, tags, author, is_public, latest_version, created_at, updated_at
+		SELECT id, name, display_name, description, tags, author, is_public, latest_version, project, created_at, updated_at
 		FROM templates %s
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
@@ -224,7 +235,7 @@ func (s *TemplateStore) List(ctx context.Context, opts model.TemplateListOptions
 		if err := rows.Scan(
 			&t.ID, &t.Name, &t.DisplayName, &t.Description,
 			&tagsJSON, &t.Author, &t.IsPublic,
-			&t.LatestVersion, &t.CreatedAt, &t.UpdatedAt,
+			&t.LatestVersion, &t.Project, &t.CreatedAt, &t.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan template: %w", err)
 		}
@@ -232,13 +243,21 @@ func (s *TemplateStore) List(ctx context.Context, opts model.TemplateListOptions
 			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
 		}
 
-		// Load spec for each template
-		version, err := s.GetVersion(ctx, t.ID, t.LatestVersion)
+		items = append(items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate templates: %w", err)
+	}
+	// Close before loading specs: a nested read can wait on a pending write that is in
+	// turn waiting for these rows to be released.
+	rows.Close()
+
+	// Load spec for each template
+	for i := range items {
+		version, err := s.GetVersion(ctx, items[i].ID, items[i].LatestVersion)
 		if err == nil && version != nil {
-			t.Spec = &version.Spec
+			items[i].Spec = &version.Spec
 		}
-
-		items = append(items, t)
 	}
 
 	if items == nil {
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SandboxTrafficRecord holds the gateway traffic counters of a sandbox. BytesIn is
+// sent to the sandbox by clients, BytesOut is sent back by the sandbox.
+type SandboxTrafficRecord struct {
+	SandboxID string
+	BytesIn   int64
+	BytesOut  int64
+	UpdatedAt time.Time
+}
+
+// SandboxTrafficStore handles sandbox traffic counter persistence.
+type SandboxTrafficStore struct {
+	db *sql.DB
+}
+
+// NewSandboxTrafficStore creates a new SandboxTrafficStore.
+func NewSandboxTrafficStore() *SandboxTrafficStore {
+	return &SandboxTrafficStore{db: DB}
+}
+
+// Add adds to the traffic counters of a sandbox. Traffic of sandboxes whose metadata
+// has already been removed is dropped.
+func (s *SandboxTrafficStore) Add(ctx context.Context, sandboxID string, bytesIn, bytesOut int64, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sandbox_traffic (sandbox_id, bytes_in, bytes_out, updated_at)
+		SELECT ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM sandboxes WHERE id = ?)
+		ON CONFLICT(sandbox_id) DO UPDATE SET
+			bytes_in = bytes_in + excluded.bytes_in,
+			bytes_out = bytes_out + excluded.bytes_out,
+			updated_at = excluded.updated_at
+	`, sandboxID, bytesIn, bytesOut, now, sandboxID)
+	if err != nil {
+		return fmt.Errorf("failed to add sandbox traffic: %w", err)
+	}
+	return nil
+}
+
+// Get returns the traffic counters of a sandbox, or nil if it has had no traffic.
+func (s *SandboxTrafficStore) Get(ctx context.Context, sandboxID string) (*SandboxTrafficRecord, error) {
+	var rec SandboxTrafficRecord
+	err := s.db.QueryRowContext(ctx, `
+		SELECT sandbox_id, bytes_in, bytes_out, updated_at FROM sandbox_traffic WHERE sandbox_id = ?
+	`, sandboxID).Scan(&rec.SandboxID, &rec.BytesIn, &rec.BytesOut, &rec.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sandbox traffic: %w", err)
+	}
+	return &rec, nil
+}
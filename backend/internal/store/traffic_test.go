@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSandboxTrafficStoreAccumulates(t *testing.T) {
+	initTestDB(t)
+	ctx := context.Background()
+	sandboxes := NewSandboxStore()
+	traffic := NewSandboxTrafficStore()
+	now := time.Now().UTC()
+
+	if err := sandboxes.Create(ctx, &SandboxRecord{
+		ID:              "sbx-traffic",
+		TemplateName:    "python",
+		Image:           "python:3.11",
+		DesiredState:    DesiredStateActive,
+		LifecycleStatus: "running",
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(time.Hour),
+		UpdatedAt:       now,
+	}); err != nil {
+		t.Fatalf("Create(sandbox) error = %v", err)
+	}
+
+	if got, err := traffic.Get(ctx, "sbx-traffic"); err != nil || got != nil {
+		t.Fatalf("Get() before traffic = %+v, %v; want nil", got, err)
+	}
+	if err := traffic.Add(ctx, "sbx-traffic", 100, 2000, now); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := traffic.Add(ctx, "sbx-traffic", 50, 500, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	got, err := traffic.Get(ctx, "sbx-traffic")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+	if got.BytesIn != 150 || got.BytesOut != 2500 || !got.UpdatedAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected counters: %+v", got)
+	}
+
+	// Traffic of unknown sandboxes is dropped rather than failing
+	if err := traffic.Add(ctx, "missing", 1, 1, now); err != nil {
+		t.Fatalf("Add(missing) error = %v", err)
+	}
+	if got, err := traffic.Get(ctx, "missing"); err != nil || got != nil {
+		t.Fatalf("Get(missing) = %+v, %v; want nil", got, err)
+	}
+
+	counts, err := sandboxes.CountByLifecycleStatus(ctx)
+	if err != nil || counts["running"] != 1 || len(counts) != 1 {
+		t.Fatalf("CountByLifecycleStatus() = %v, %v", counts, err)
+	}
+}
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+type FileType string
+
+const (
+	FileTypeFile      FileType = "file"
+	FileTypeDirectory FileType = "directory"
+	FileTypeSymlink   FileType = "symlink"
+	FileTypeOther     FileType = "other"
+)
+
+// FileInfo describes a filesystem entry inside a sandbox. Symlinks are not followed.
+type FileInfo struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Type       FileType  `json:"type"`
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"` // permission bits in octal, e.g. "0755"
+	ModifiedAt time.Time `json:"modified_at"`
+	LinkTarget string    `json:"link_target,omitempty"`
+}
+
+type FileListResponse struct {
+	Path  string     `json:"path"`
+	Items []FileInfo `json:"items"`
+}
+
+// MkdirRequest creates a directory and any missing parents
+type MkdirRequest struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// MoveRequest renames a file or directory. An existing destination file is only
+// replaced when Overwrite is set; existing directories are never replaced.
+type MoveRequest struct {
+	Source      string `json:"source" binding:"required"`
+	Destination string `json:"destination" binding:"required"`
+	Overwrite   bool   `json:"overwrite,omitempty"`
+}
+
+// FSEventType is the kind of a filesystem watch event
+type FSEventType string
+
+const (
+	FSEventReady  FSEventType = "ready" // initial scan finished; changes are reported from here on
+	FSEventCreate FSEventType = "create"
+	FSEventModify FSEventType = "modify"
+	FSEventDelete FSEventType = "delete"
+	FSEventError  FSEventType = "error" // the watch failed and the stream ends
+)
+
+// FSEvent is a single event emitted by the filesystem watch endpoint. For delete
+// events, FileType is the type the path had before it was removed.
+type FSEvent struct {
+	Type       FSEventType `json:"type"`
+	Path       string      `json:"path,omitempty"`
+	FileType   FileType    `json:"file_type,omitempty"`
+	Size       int64       `json:"size,omitempty"`
+	ModifiedAt *time.Time  `json:"modified_at,omitempty"`
+	Message    string      `json:"message,omitempty"` // error message (error)
+}
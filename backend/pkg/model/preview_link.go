@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// PreviewTokenParam is the query parameter that carries a preview link token. The
+// gateway moves the token into a cookie of the same name on first use.
+const PreviewTokenParam = "liteboxd_preview_token"
+
+// PreviewLink grants access to one port of a sandbox through the gateway without the
+// sandbox access token, so the port can be opened in a browser or shared.
+type PreviewLink struct {
+	ID         string `json:"id"`
+	SandboxID  string `json:"sandbox_id"`
+	Port       int    `json:"port"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+	// Token and URL are only returned when the link is created
+	Token     string    `json:"token,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatePreviewLinkRequest mints a preview link. PathPrefix limits the link to a path
+// and the paths below it. TTL is the lifetime of the link in seconds.
+type CreatePreviewLinkRequest struct {
+	Port       int    `json:"port" binding:"required"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+	TTL        int    `json:"ttl,omitempty"`
+}
+
+type PreviewLinkListResponse struct {
+	Items []PreviewLink `json:"items"`
+}
@@ -15,6 +15,7 @@ type Template struct {
 	Author        string    `json:"author"`
 	IsPublic      bool      `json:"isPublic"`
 	LatestVersion int       `json:"latestVersion"`
+	Project       string    `json:"project,omitempty"`
 	CreatedAt     time.Time `json:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt"`
 
@@ -40,6 +41,16 @@ const (
 	PersistenceDefaultSize       = "1Gi"
 )
 
+// TTLMode decides what the TTL of a sandbox counts from.
+type TTLMode string
+
+const (
+	// TTLModeFixed expires the sandbox TTL seconds after creation. This is the default.
+	TTLModeFixed TTLMode = "fixed"
+	// TTLModeIdle expires the sandbox TTL seconds after its last gateway request or exec.
+	TTLModeIdle TTLMode = "idle"
+)
+
 // TemplateSpec defines the specification of a template
 type TemplateSpec struct {
 	Image          string            `json:"image" yaml:"image"`
@@ -47,6 +58,7 @@ type TemplateSpec struct {
 	Args           []string          `json:"args,omitempty" yaml:"args,omitempty"`       // Override container args; empty = use image default
 	Resources      ResourceSpec      `json:"resources" yaml:"resources"`
 	TTL            int               `json:"ttl" yaml:"ttl"`
+	TTLMode        TTLMode           `json:"ttlMode,omitempty" yaml:"ttlMode,omitempty"`
 	Env            map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
 	StartupScript  string            `json:"startupScript,omitempty" yaml:"startupScript,omitempty"`
 	StartupTimeout int               `json:"startupTimeout,omitempty" yaml:"startupTimeout,omitempty"`
@@ -54,6 +66,7 @@ type TemplateSpec struct {
 	ReadinessProbe *ProbeSpec        `json:"readinessProbe,omitempty" yaml:"readinessProbe,omitempty"`
 	Network        *NetworkSpec      `json:"network,omitempty" yaml:"network,omitempty"`
 	Persistence    *PersistenceSpec  `json:"persistence,omitempty" yaml:"persistence,omitempty"`
+	PoolSize       int               `json:"poolSize,omitempty" yaml:"poolSize,omitempty"` // Ready sandboxes kept warm for fast creation; 0 disables the pool
 }
 
 // ResourceSpec defines resource limits
@@ -155,6 +168,8 @@ type CreateTemplateRequest struct {
 	IsPublic    *bool        `json:"isPublic"`
 	Spec        TemplateSpec `json:"spec" binding:"required"`
 	AutoPrepull bool         `json:"autoPrepull"`
+	// Project makes the template usable only by sandboxes of that project
+	Project string `json:"project,omitempty"`
 }
 
 // UpdateTemplateRequest is the request body for updating a template
@@ -193,6 +208,10 @@ type TemplateListOptions struct {
 	Search   string
 	Page     int
 	PageSize int
+	// Project limits the list to global templates and those of the project
+	Project string
+	// Names limits the list to the named templates, when set
+	Names []string
 }
 
 // RollbackResponse is the response for rollback operation
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// SandboxTraffic is the traffic the gateway has relayed for a sandbox. The counters are
+// flushed by the gateway periodically, so they may lag behind by a few seconds.
+type SandboxTraffic struct {
+	SandboxID string     `json:"sandbox_id"`
+	BytesIn   int64      `json:"bytes_in"`             // Bytes sent to the sandbox by clients
+	BytesOut  int64      `json:"bytes_out"`            // Bytes sent back by the sandbox
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // Time of the last flush with traffic; unset without traffic
+}
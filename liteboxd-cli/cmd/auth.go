@@ -27,12 +27,29 @@ var authLoginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login and store an API key",
 	Long: `Login to a LiteBoxd server with username and password.
-This will create an API key and store it in your config file for subsequent CLI commands.`,
+This will create an API key and store it in your config file for subsequent CLI commands.
+
+By default the API key may do everything your user may. --scope limits it to some
+operations, --template to some templates and --allow-cidr to some source addresses:
+  sandbox:read     list and inspect sandboxes, read files and logs
+  sandbox:create   create sandboxes
+  sandbox:write    change, stop, start and delete sandboxes, write files
+  sandbox:exec     run commands and processes in sandboxes
+  template:read    list and inspect templates, pools and image prepulls
+  template:write   change templates, pools and image prepulls`,
 	Example: `  # Login with interactive prompts
   liteboxd auth login
 
   # Login to a specific server
-  liteboxd auth login --api-server https://api.example.com/api/v1`,
+  liteboxd auth login --api-server https://api.example.com/api/v1
+
+  # Store an API key that can only reach the sandboxes and templates of a project
+  liteboxd auth login --project team-a
+
+  # Store an API key for CI that can only create sandboxes from two templates and
+  # exec in them, from the CI network
+  liteboxd auth login --scope sandbox:create --scope sandbox:exec \
+    --template python --template node --allow-cidr 10.20.0.0/16`,
 	RunE: runAuthLogin,
 }
 
@@ -50,8 +67,19 @@ var authLogoutCmd = &cobra.Command{
 	RunE:    runAuthLogout,
 }
 
+var (
+	authProjectFlag    string
+	authScopeFlags     []string
+	authTemplateFlags  []string
+	authAllowCIDRFlags []string
+)
+
 func init() {
 	rootCmd.AddCommand(authCmd)
+	authLoginCmd.Flags().StringVar(&authProjectFlag, "project", "", "Scope the API key to a project")
+	authLoginCmd.Flags().StringSliceVar(&authScopeFlags, "scope", nil, "Limit the API key to a scope (repeatable)")
+	authLoginCmd.Flags().StringSliceVar(&authTemplateFlags, "template", nil, "Limit the API key to a template (repeatable)")
+	authLoginCmd.Flags().StringSliceVar(&authAllowCIDRFlags, "allow-cidr", nil, "Only accept the API key from a CIDR or IP address (repeatable)")
 	authCmd.AddCommand(authLoginCmd)
 	authCmd.AddCommand(authStatusCmd)
 	authCmd.AddCommand(authLogoutCmd)
@@ -134,8 +162,12 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	}
 	keyName := fmt.Sprintf("cli-%s-%d", hostname, time.Now().Unix())
 
-	createBody, _ := json.Marshal(map[string]string{
-		"name": keyName,
+	createBody, _ := json.Marshal(map[string]any{
+		"name":          keyName,
+		"project":       authProjectFlag,
+		"scopes":        authScopeFlags,
+		"templates":     authTemplateFlags,
+		"allowed_cidrs": authAllowCIDRFlags,
 	})
 
 	createReq, err := http.NewRequest("POST", baseURL+"/auth/api-keys", bytes.NewReader(createBody))
@@ -159,10 +191,13 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	var keyResp struct {
-		ID     string `json:"id"`
-		Name   string `json:"name"`
-		Key    string `json:"key"`
-		Prefix string `json:"prefix"`
+		ID           string   `json:"id"`
+		Name         string   `json:"name"`
+		Key          string   `json:"key"`
+		Prefix       string   `json:"prefix"`
+		Scopes       []string `json:"scopes"`
+		Templates    []string `json:"templates"`
+		AllowedCIDRs []string `json:"allowed_cidrs"`
 	}
 	if err := json.NewDecoder(createResp.Body).Decode(&keyResp); err != nil {
 		return fmt.Errorf("failed to parse API key response: %w", err)
@@ -191,6 +226,10 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	fmt.Printf("API key created and saved to config (%s)\n", config.GetConfigPath())
 	fmt.Printf("  Name: %s\n", keyResp.Name)
 	fmt.Printf("  Prefix: lbxk_%s...\n", keyResp.Prefix)
+	if authProjectFlag != "" {
+		fmt.Printf("  Project: %s\n", authProjectFlag)
+	}
+	printAPIKeyRestrictions(keyResp.Scopes, keyResp.Templates, keyResp.AllowedCIDRs)
 	fmt.Println("\nYou can now use other CLI commands (e.g., liteboxd sandbox list).")
 	return nil
 }
@@ -221,13 +260,26 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 		fmt.Println("Token is invalid or expired. Run 'liteboxd auth login' to re-authenticate.")
 		return nil
 	}
+	if resp.StatusCode == http.StatusForbidden {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		fmt.Printf("Token is not accepted here: %s\n", errResp.Error)
+		return nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("unexpected response (HTTP %d)", resp.StatusCode)
 	}
 
 	var meResp struct {
-		AuthMethod string `json:"auth_method"`
-		Username   string `json:"username"`
+		AuthMethod   string   `json:"auth_method"`
+		Username     string   `json:"username"`
+		Role         string   `json:"role"`
+		Project      string   `json:"project"`
+		Scopes       []string `json:"scopes"`
+		Templates    []string `json:"templates"`
+		AllowedCIDRs []string `json:"allowed_cidrs"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&meResp); err != nil {
 		return fmt.Errorf("failed to parse response: %w", err)
@@ -237,10 +289,36 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 	if meResp.Username != "" {
 		fmt.Printf("  Username: %s\n", meResp.Username)
 	}
+	if meResp.Role != "" {
+		fmt.Printf("  Role: %s\n", meResp.Role)
+	}
+	if meResp.Project != "" {
+		fmt.Printf("  Project: %s\n", meResp.Project)
+	}
+	if meResp.AuthMethod == "api_key" {
+		if len(meResp.Scopes) == 0 {
+			fmt.Println("  Scopes: all")
+		}
+		printAPIKeyRestrictions(meResp.Scopes, meResp.Templates, meResp.AllowedCIDRs)
+	}
 	fmt.Printf("  Server: %s\n", baseURL)
 	return nil
 }
 
+// printAPIKeyRestrictions prints the scopes, template allowlist and source address
+// allowlist of an API key, skipping those that aren't set.
+func printAPIKeyRestrictions(scopes, templates, allowedCIDRs []string) {
+	if len(scopes) > 0 {
+		fmt.Printf("  Scopes: %s\n", strings.Join(scopes, ", "))
+	}
+	if len(templates) > 0 {
+		fmt.Printf("  Templates: %s\n", strings.Join(templates, ", "))
+	}
+	if len(allowedCIDRs) > 0 {
+		fmt.Printf("  Allowed CIDRs: %s\n", strings.Join(allowedCIDRs, ", "))
+	}
+}
+
 func runAuthLogout(cmd *cobra.Command, args []string) error {
 	token := viper.GetString("token")
 	if token == "" {
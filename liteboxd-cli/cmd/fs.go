@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/fslongjin/liteboxd/liteboxd-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var sandboxLsCmd = &cobra.Command{
+	Use:   "ls <id> [path]",
+	Short: "List a directory in a sandbox",
+	Args:  cobra.RangeArgs(1, 2),
+	Example: `  # List the root directory
+  liteboxd sandbox ls <id>
+
+  # List a directory as JSON
+  liteboxd sandbox ls <id> /workspace -o json`,
+	RunE: runSandboxLs,
+}
+
+func init() {
+	sandboxCmd.AddCommand(sandboxLsCmd)
+}
+
+func runSandboxLs(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	dir := "/"
+	if len(args) == 2 {
+		dir = args[1]
+	}
+
+	items, err := client.Sandbox.ListDir(ctx, args[0], dir)
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"type", "mode", "size", "modified_at", "name", "link_target"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), items)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var sandboxPortForwardCmd = &cobra.Command{
+	Use:   "port-forward <id> [local:]remote",
+	Short: "Forward a local port to a sandbox port",
+	Long: `Forward connections on a local port to a port of a sandbox, tunneled as raw TCP
+through the gateway. This works for any TCP protocol, such as databases, SSH or gRPC.
+
+Without a local port the remote port is used; with an empty local port (":5432") a
+free port is picked. Runs until interrupted.`,
+	Args: cobra.ExactArgs(2),
+	Example: `  # Reach a PostgreSQL server in the sandbox at localhost:5432
+  liteboxd sandbox port-forward <sandbox-id> 5432
+
+  # Forward local port 15432 to sandbox port 5432
+  liteboxd sandbox port-forward <sandbox-id> 15432:5432`,
+	RunE: runSandboxPortForward,
+}
+
+var portForwardAddressFlag string
+
+func init() {
+	sandboxPortForwardCmd.Flags().StringVar(&portForwardAddressFlag, "address", "127.0.0.1", "Local address to listen on")
+	sandboxCmd.AddCommand(sandboxPortForwardCmd)
+}
+
+func runSandboxPortForward(cmd *cobra.Command, args []string) error {
+	localPort, remotePort, err := parsePortMapping(args[1])
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(portForwardAddressFlag, localPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	defer listener.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Forwarding %s -> %d\n", listener.Addr(), remotePort)
+	client := getAPIClient()
+	return client.Sandbox.PortForward(ctx, args[0], listener, remotePort, func(err error) {
+		fmt.Fprintf(os.Stderr, "Error forwarding connection: %v\n", err)
+	})
+}
+
+// parsePortMapping parses a [local:]remote port mapping. The local port is returned as
+// a string, so that an empty one lets the system pick a free port.
+func parsePortMapping(mapping string) (string, int, error) {
+	local, remote, found := strings.Cut(mapping, ":")
+	if !found {
+		remote = mapping
+		local = mapping
+	}
+	remotePort, err := strconv.Atoi(remote)
+	if err != nil || remotePort < 1 || remotePort > 65535 {
+		return "", 0, fmt.Errorf("invalid remote port %q", remote)
+	}
+	if local != "" {
+		if p, err := strconv.Atoi(local); err != nil || p < 1 || p > 65535 {
+			return "", 0, fmt.Errorf("invalid local port %q", local)
+		}
+	}
+	if local == "" {
+		local = "0"
+	}
+	return local, remotePort, nil
+}
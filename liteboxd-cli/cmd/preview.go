@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fslongjin/liteboxd/liteboxd-cli/internal/output"
+	liteboxd "github.com/fslongjin/liteboxd/sdk/go"
+	"github.com/spf13/cobra"
+)
+
+var sandboxPreviewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Manage sandbox preview links",
+	Long: `Preview links open a port of a sandbox through the gateway without the access token,
+so it can be viewed in a browser or shared. Links may be limited to a path prefix and
+expire after their TTL; revoke a link to stop it working early.`,
+}
+
+var (
+	previewPortFlag int
+	previewPathFlag string
+	previewTTLFlag  time.Duration
+)
+
+var sandboxPreviewCreateCmd = &cobra.Command{
+	Use:   "create <id>",
+	Short: "Create a preview link",
+	Args:  cobra.ExactArgs(1),
+	Example: `  # Share port 3000 for a day
+  liteboxd sandbox preview create <id> --port 3000 --ttl 24h
+
+  # Only share the docs under /docs
+  liteboxd sandbox preview create <id> --port 8080 --path /docs`,
+	RunE: runSandboxPreviewCreate,
+}
+
+var sandboxPreviewListCmd = &cobra.Command{
+	Use:   "list <id>",
+	Short: "List the active preview links of a sandbox",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSandboxPreviewList,
+}
+
+var sandboxPreviewRevokeCmd = &cobra.Command{
+	Use:   "revoke <id> <link-id>",
+	Short: "Revoke a preview link",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runSandboxPreviewRevoke,
+}
+
+func init() {
+	sandboxCmd.AddCommand(sandboxPreviewCmd)
+
+	sandboxPreviewCreateCmd.Flags().IntVar(&previewPortFlag, "port", 0, "Sandbox port to share (required)")
+	sandboxPreviewCreateCmd.Flags().StringVar(&previewPathFlag, "path", "", "Limit the link to this path prefix")
+	sandboxPreviewCreateCmd.Flags().DurationVar(&previewTTLFlag, "ttl", 0, "Link lifetime (e.g. 30m, 24h; default 1h, max 168h)")
+	sandboxPreviewCreateCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "Only print the link URL")
+	_ = sandboxPreviewCreateCmd.MarkFlagRequired("port")
+	sandboxPreviewCmd.AddCommand(sandboxPreviewCreateCmd)
+
+	sandboxPreviewCmd.AddCommand(sandboxPreviewListCmd)
+	sandboxPreviewCmd.AddCommand(sandboxPreviewRevokeCmd)
+}
+
+func runSandboxPreviewCreate(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	link, err := client.Sandbox.CreatePreviewLink(ctx, args[0], &liteboxd.CreatePreviewLinkRequest{
+		Port:       previewPortFlag,
+		PathPrefix: previewPathFlag,
+		TTL:        int(previewTTLFlag.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	if quietFlag {
+		fmt.Println(link.URL)
+		return nil
+	}
+	fmt.Printf("Created preview link: %s\n", link.ID)
+	fmt.Printf("URL: %s\n", link.URL)
+	fmt.Printf("Expires at: %s\n", link.ExpiresAt.Local().Format(time.RFC3339))
+	return nil
+}
+
+func runSandboxPreviewList(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	links, err := client.Sandbox.ListPreviewLinks(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"id", "port", "path_prefix", "expires_at", "created_at"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), links)
+}
+
+func runSandboxPreviewRevoke(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	if err := client.Sandbox.RevokePreviewLink(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked preview link: %s\n", args[1])
+	return nil
+}
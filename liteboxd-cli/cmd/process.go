@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fslongjin/liteboxd/liteboxd-cli/internal/output"
+	liteboxd "github.com/fslongjin/liteboxd/sdk/go"
+	"github.com/spf13/cobra"
+)
+
+var sandboxProcessCmd = &cobra.Command{
+	Use:     "process",
+	Aliases: []string{"proc"},
+	Short:   "Manage background processes in a sandbox",
+}
+
+var (
+	processWorkDirFlag string
+	processEnvFlag     []string
+	processFollowFlag  bool
+)
+
+var sandboxProcessStartCmd = &cobra.Command{
+	Use:   "start <id> -- <command> [args...]",
+	Short: "Start a command in the background",
+	Args:  cobra.MinimumNArgs(2),
+	Example: `  # Start a dev server that keeps running after the CLI exits
+  liteboxd sandbox process start <id> --workdir /workspace -- npm run dev`,
+	RunE: runSandboxProcessStart,
+}
+
+var sandboxProcessListCmd = &cobra.Command{
+	Use:   "list <id>",
+	Short: "List background processes",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSandboxProcessList,
+}
+
+var sandboxProcessGetCmd = &cobra.Command{
+	Use:   "get <id> <process-id>",
+	Short: "Get background process details",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runSandboxProcessGet,
+}
+
+var sandboxProcessLogsCmd = &cobra.Command{
+	Use:   "logs <id> <process-id>",
+	Short: "Print background process output",
+	Args:  cobra.ExactArgs(2),
+	Example: `  # Print output captured so far
+  liteboxd sandbox process logs <id> <process-id>
+
+  # Follow output until the process exits
+  liteboxd sandbox process logs -f <id> <process-id>`,
+	RunE: runSandboxProcessLogs,
+}
+
+var sandboxProcessSignalCmd = &cobra.Command{
+	Use:   "signal <id> <process-id> <signal>",
+	Short: "Send a signal (TERM, INT, HUP, ...) to a background process",
+	Args:  cobra.ExactArgs(3),
+	RunE:  runSandboxProcessSignal,
+}
+
+var sandboxProcessKillCmd = &cobra.Command{
+	Use:   "kill <id> <process-id>",
+	Short: "Kill a background process",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runSandboxProcessKill,
+}
+
+func init() {
+	sandboxCmd.AddCommand(sandboxProcessCmd)
+
+	sandboxProcessStartCmd.Flags().StringVar(&processWorkDirFlag, "workdir", "", "Working directory for the command")
+	sandboxProcessStartCmd.Flags().StringSliceVar(&processEnvFlag, "env", nil, "Environment variables (KEY=VALUE)")
+	sandboxProcessCmd.AddCommand(sandboxProcessStartCmd)
+
+	sandboxProcessCmd.AddCommand(sandboxProcessListCmd)
+	sandboxProcessCmd.AddCommand(sandboxProcessGetCmd)
+
+	sandboxProcessLogsCmd.Flags().BoolVarP(&processFollowFlag, "follow", "f", false, "Follow output until the process exits")
+	sandboxProcessCmd.AddCommand(sandboxProcessLogsCmd)
+
+	sandboxProcessCmd.AddCommand(sandboxProcessSignalCmd)
+	sandboxProcessCmd.AddCommand(sandboxProcessKillCmd)
+}
+
+func runSandboxProcessStart(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	req := &liteboxd.StartProcessRequest{
+		Command: args[1:],
+		WorkDir: processWorkDirFlag,
+	}
+	if len(processEnvFlag) > 0 {
+		req.Env = parseEnvVars(processEnvFlag)
+	}
+
+	proc, err := client.Sandbox.StartProcess(ctx, args[0], req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Started process: %s (pid %d)\n", proc.ID, proc.PID)
+	return nil
+}
+
+func runSandboxProcessList(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	procs, err := client.Sandbox.ListProcesses(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"id", "pid", "status", "exit_code", "command", "started_at"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), procs)
+}
+
+func runSandboxProcessGet(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	proc, err := client.Sandbox.GetProcess(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	formatter := output.NewFormatter(output.ParseFormat(outputFormat))
+	return formatter.Write(cmd.OutOrStdout(), proc)
+}
+
+func runSandboxProcessLogs(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+
+	if processFollowFlag {
+		exitCode, err := client.Sandbox.FollowProcessOutput(context.Background(), args[0], args[1], os.Stdout, os.Stderr)
+		if err != nil {
+			return err
+		}
+		if exitCode >= 0 {
+			fmt.Fprintf(os.Stderr, "process exited with code %d\n", exitCode)
+		} else {
+			fmt.Fprintln(os.Stderr, "process terminated without exit code")
+		}
+		return nil
+	}
+
+	ctx, _ := getContext()
+	out, err := client.Sandbox.GetProcessOutput(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stderr, out.Stderr)
+	fmt.Print(out.Stdout)
+	return nil
+}
+
+func runSandboxProcessSignal(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	if err := client.Sandbox.SignalProcess(ctx, args[0], args[1], args[2]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Sent %s to process %s\n", args[2], args[1])
+	return nil
+}
+
+func runSandboxProcessKill(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	if err := client.Sandbox.KillProcess(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Killed process: %s\n", args[1])
+	return nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fslongjin/liteboxd/liteboxd-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage projects",
+	Long: `Create and remove projects. Each project runs its sandboxes in its own
+Kubernetes namespace, named after the sandbox namespace and the project.
+
+Creating and deleting projects requires the admin role. Use --project on
+'sandbox create' to create a sandbox in a project, and on 'auth login' to
+store an API key that can only reach one project.`,
+}
+
+var projectListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List projects",
+	Example: `  liteboxd project list`,
+	RunE:    runProjectList,
+}
+
+var projectGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Show a project",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProjectGet,
+}
+
+var projectCreateCmd = &cobra.Command{
+	Use:     "create <name>",
+	Short:   "Create a project and its namespace",
+	Args:    cobra.ExactArgs(1),
+	Example: `  liteboxd project create team-a --description "Data team"`,
+	RunE:    runProjectCreate,
+}
+
+var projectDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a project and its namespace",
+	Long: `Delete a project and its namespace. Projects that still have sandboxes
+can't be deleted; delete the sandboxes first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProjectDelete,
+}
+
+var projectDescriptionFlag string
+
+func init() {
+	rootCmd.AddCommand(projectCmd)
+
+	projectListCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	projectCmd.AddCommand(projectListCmd)
+
+	projectGetCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	projectCmd.AddCommand(projectGetCmd)
+
+	projectCreateCmd.Flags().StringVar(&projectDescriptionFlag, "description", "", "Description of the project")
+	projectCmd.AddCommand(projectCreateCmd)
+
+	projectCmd.AddCommand(projectDeleteCmd)
+}
+
+func runProjectList(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	projects, err := client.Project.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"name", "namespace", "description", "created_at"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), projects)
+}
+
+func runProjectGet(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	project, err := client.Project.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	formatter := output.NewFormatter(output.ParseFormat(outputFormat))
+	return formatter.Write(cmd.OutOrStdout(), project)
+}
+
+func runProjectCreate(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	project, err := client.Project.Create(ctx, args[0], projectDescriptionFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created project %s (namespace %s)\n", project.Name, project.Namespace)
+	return nil
+}
+
+func runProjectDelete(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	if err := client.Project.Delete(ctx, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted project %s\n", args[0])
+	return nil
+}
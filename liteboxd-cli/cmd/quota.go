@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fslongjin/liteboxd/liteboxd-cli/internal/output"
+	liteboxd "github.com/fslongjin/liteboxd/sdk/go"
+	"github.com/spf13/cobra"
+)
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Show and manage quotas",
+	Long: `Show the quotas that apply to you, and manage the quotas of users, API keys
+and projects. Managing quotas requires the admin role.
+
+A sandbox counts towards the quotas of the user and API key that created it and of
+its project. Scopes and subjects:
+  user      a user ID or username
+  api_key   an API key ID
+  project   a project name
+
+The subject "*" sets the default quota of a scope, for subjects without their own.
+
+Limits:
+  --max-sandboxes  sandboxes running at once (stopped and paused ones don't count)
+  --max-cpu        summed CPU limits of running sandboxes
+  --max-memory     summed memory limits of running sandboxes
+  --max-storage    summed size of persistent volumes
+  --max-ttl        TTL of sandboxes in seconds; sandboxes that never expire are refused
+
+Requests that exceed a quota fail with HTTP 429 (too many sandboxes) or 403.`,
+}
+
+var quotaShowCmd = &cobra.Command{
+	Use:     "show",
+	Short:   "Show the quotas that apply to you and their usage",
+	Example: `  liteboxd quota show`,
+	RunE:    runQuotaShow,
+}
+
+var quotaListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List quotas",
+	Example: `  liteboxd quota list`,
+	RunE:    runQuotaList,
+}
+
+var quotaGetCmd = &cobra.Command{
+	Use:   "get <scope> <subject>",
+	Short: "Show the quota of a subject and its usage",
+	Args:  cobra.ExactArgs(2),
+	Example: `  liteboxd quota get user alice
+  liteboxd quota get project team-a`,
+	RunE: runQuotaGet,
+}
+
+var quotaSetCmd = &cobra.Command{
+	Use:   "set <scope> <subject>",
+	Short: "Set the quota of a subject",
+	Long: `Set the quota of a subject, replacing its previous limits. Limits that are not
+set are unlimited.`,
+	Args: cobra.ExactArgs(2),
+	Example: `  # Let alice run 5 sandboxes with 4 CPUs and 8Gi of memory in total
+  liteboxd quota set user alice --max-sandboxes 5 --max-cpu 4 --max-memory 8Gi
+
+  # Limit every API key without its own quota to 10 sandboxes
+  liteboxd quota set api_key '*' --max-sandboxes 10
+
+  # Limit the persistent storage and TTL of a project
+  liteboxd quota set project team-a --max-storage 100Gi --max-ttl 86400`,
+	RunE: runQuotaSet,
+}
+
+var quotaDeleteCmd = &cobra.Command{
+	Use:   "delete <scope> <subject>",
+	Short: "Remove the quota of a subject",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runQuotaDelete,
+}
+
+var quotaLimitsFlag liteboxd.QuotaLimits
+
+func init() {
+	rootCmd.AddCommand(quotaCmd)
+
+	quotaShowCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	quotaCmd.AddCommand(quotaShowCmd)
+
+	quotaListCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	quotaCmd.AddCommand(quotaListCmd)
+
+	quotaGetCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	quotaCmd.AddCommand(quotaGetCmd)
+
+	quotaSetCmd.Flags().IntVar(&quotaLimitsFlag.MaxSandboxes, "max-sandboxes", 0, "Sandboxes running at once")
+	quotaSetCmd.Flags().StringVar(&quotaLimitsFlag.MaxCPU, "max-cpu", "", "Summed CPU limits of running sandboxes (e.g. 4, 2500m)")
+	quotaSetCmd.Flags().StringVar(&quotaLimitsFlag.MaxMemory, "max-memory", "", "Summed memory limits of running sandboxes (e.g. 8Gi)")
+	quotaSetCmd.Flags().StringVar(&quotaLimitsFlag.MaxStorage, "max-storage", "", "Summed size of persistent volumes (e.g. 100Gi)")
+	quotaSetCmd.Flags().IntVar(&quotaLimitsFlag.MaxTTL, "max-ttl", 0, "TTL of sandboxes in seconds")
+	quotaCmd.AddCommand(quotaSetCmd)
+
+	quotaCmd.AddCommand(quotaDeleteCmd)
+}
+
+// quotaStatusFormatter formats quota statuses, showing usage next to the limits.
+func quotaStatusFormatter() output.Formatter {
+	format := output.ParseFormat(outputFormat)
+	if format != output.FormatTable {
+		return output.NewFormatter(format)
+	}
+	return output.NewTableFormatterWithLabels(
+		[]string{"scope", "subject", "usage.sandboxes", "limits.max_sandboxes", "usage.cpu", "limits.max_cpu",
+			"usage.memory", "limits.max_memory", "usage.storage", "limits.max_storage", "limits.max_ttl"},
+		map[string]string{
+			"scope": "SCOPE", "subject": "SUBJECT",
+			"usage.sandboxes": "SANDBOXES", "limits.max_sandboxes": "MAX",
+			"usage.cpu": "CPU", "limits.max_cpu": "MAX",
+			"usage.memory": "MEMORY", "limits.max_memory": "MAX",
+			"usage.storage": "STORAGE", "limits.max_storage": "MAX",
+			"limits.max_ttl": "MAX TTL",
+		},
+	)
+}
+
+func runQuotaShow(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	items, err := client.Quota.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return quotaStatusFormatter().Write(cmd.OutOrStdout(), items)
+}
+
+func runQuotaList(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	quotas, err := client.Quota.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"scope", "subject", "max_sandboxes", "max_cpu", "max_memory", "max_storage", "max_ttl", "updated_at"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), quotas)
+}
+
+func runQuotaGet(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	status, err := client.Quota.Get(ctx, liteboxd.QuotaScope(args[0]), args[1])
+	if err != nil {
+		return err
+	}
+	return quotaStatusFormatter().Write(cmd.OutOrStdout(), []liteboxd.QuotaStatus{*status})
+}
+
+func runQuotaSet(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	quota, err := client.Quota.Set(ctx, liteboxd.QuotaScope(args[0]), args[1], &quotaLimitsFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Set quota of %s %s\n", quota.Scope, quota.Subject)
+	return nil
+}
+
+func runQuotaDelete(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	if err := client.Quota.Delete(ctx, liteboxd.QuotaScope(args[0]), args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted quota of %s %s\n", args[0], args[1])
+	return nil
+}
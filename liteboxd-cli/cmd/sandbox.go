@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -26,10 +28,17 @@ var sandboxCmd = &cobra.Command{
 var (
 	templateFlag        string
 	templateVersionFlag int
+	fromSnapshotFlag    string
 	cpuFlag             string
 	memoryFlag          string
 	ttlFlag             int
+	ttlModeFlag         string
 	envFlag             []string
+	internetFlag        bool
+	allowDomainFlag     []string
+	networkGroupFlag    string
+	groupPortFlag       []string
+	projectFlag         string
 	waitFlag            bool
 	quietFlag           bool
 )
@@ -40,15 +49,26 @@ var sandboxCreateCmd = &cobra.Command{
 	Long: `Create a new sandbox from a template.
 
 All sandboxes must be created from a template. Use --cpu, --memory, --ttl, and --env
-to override template values.`,
+to override template values, and --internet or --allow-domain to override its network
+configuration. With --from-snapshot the sandbox starts from a snapshot's
+root filesystem and uses the snapshot's template unless --template is given.`,
 	Example: `  # Create from template with defaults
   liteboxd sandbox create --template python-data-science
 
   # Create with overrides
   liteboxd sandbox create --template python-ds --ttl 7200 --env DEBUG=true
 
+  # Create without internet access
+  liteboxd sandbox create --template python-ds --internet=false
+
   # Create and wait for ready
-  liteboxd sandbox create --template nodejs --wait`,
+  liteboxd sandbox create --template nodejs --wait
+
+  # Restore a snapshot into a new sandbox
+  liteboxd sandbox create --from-snapshot snap-1a2b3c4d
+
+  # Create in the namespace of a project
+  liteboxd sandbox create --template python-ds --project team-a`,
 	RunE: runSandboxCreate,
 }
 
@@ -111,11 +131,123 @@ var sandboxStartCmd = &cobra.Command{
 	RunE: runSandboxStart,
 }
 
+var sandboxPauseCmd = &cobra.Command{
+	Use:   "pause <id>",
+	Short: "Pause a sandbox without persistence",
+	Long: `Pause a sandbox without persistence.
+
+The sandbox filesystem is checkpointed to the server and its pod is released. The TTL
+does not run down while paused. Running processes are not kept: resume reruns the
+template startup script. Use stop and start for persistence-enabled sandboxes.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  # Pause a running sandbox
+  liteboxd sandbox pause <sandbox-id>`,
+	RunE: runSandboxPause,
+}
+
+var sandboxExtendCmd = &cobra.Command{
+	Use:   "extend <id>",
+	Short: "Set a new expiry for a sandbox",
+	Long: `Set a new expiry for a sandbox, either a TTL from now or an absolute time.
+
+The server bounds how far ahead the expiry may be set. Stopped and paused sandboxes
+count the TTL from when they stopped, since their TTL does not run down meanwhile.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  # Keep a sandbox for another hour
+  liteboxd sandbox extend <sandbox-id> --ttl 1h
+
+  # Expire at a given time
+  liteboxd sandbox extend <sandbox-id> --expires-at 2026-01-02T15:04:05Z`,
+	RunE: runSandboxExtend,
+}
+
+var (
+	extendTTLFlag       time.Duration
+	extendExpiresAtFlag string
+)
+
+var sandboxRotateTokenCmd = &cobra.Command{
+	Use:   "rotate-token <id>",
+	Short: "Replace the access token of a sandbox",
+	Long: `Replace the access token of a sandbox, for example after it has leaked.
+
+The old token is rejected by the gateway at once, or after --grace so that clients
+using it can switch over.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  # Rotate and keep the old token working for 5 minutes
+  liteboxd sandbox rotate-token <sandbox-id> --grace 5m`,
+	RunE: runSandboxRotateToken,
+}
+
+var rotateGraceFlag time.Duration
+
+var sandboxNetworkCmd = &cobra.Command{
+	Use:   "network <id>",
+	Short: "Change the egress of a sandbox",
+	Long: `Change the egress of a running sandbox.
+
+Unless the server allows widening, the new configuration may only disable internet
+access or narrow the template's allowed domains. --reset makes the sandbox follow its
+template's network configuration again.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  # Cut a sandbox off from the internet
+  liteboxd sandbox network <sandbox-id> --internet=false
+
+  # Only allow a subset of the template's domains
+  liteboxd sandbox network <sandbox-id> --allow-domain pypi.org --allow-domain files.pythonhosted.org
+
+  # Go back to the template's network configuration
+  liteboxd sandbox network <sandbox-id> --reset`,
+	RunE: runSandboxNetwork,
+}
+
+var networkResetFlag bool
+
+var sandboxNetworkEventsCmd = &cobra.Command{
+	Use:   "events <id>",
+	Short: "List DNS queries and outbound connections of a sandbox",
+	Args:  cobra.ExactArgs(1),
+	Example: `  # Show the latest egress events
+  liteboxd sandbox network events <sandbox-id>
+
+  # Show DNS queries of the last hour
+  liteboxd sandbox network events <sandbox-id> --kind dns --since 1h`,
+	RunE: runSandboxNetworkEvents,
+}
+
+var (
+	networkEventsKindFlag  string
+	networkEventsSinceFlag time.Duration
+	networkEventsLimitFlag int
+)
+
+var sandboxTrafficCmd = &cobra.Command{
+	Use:   "traffic <id>",
+	Short: "Show the bytes the gateway relayed for a sandbox",
+	Args:  cobra.ExactArgs(1),
+	Example: `  # Show traffic counters
+  liteboxd sandbox traffic <sandbox-id>
+
+  # Output as JSON
+  liteboxd sandbox traffic <sandbox-id> -o json`,
+	RunE: runSandboxTraffic,
+}
+
+var sandboxResumeCmd = &cobra.Command{
+	Use:   "resume <id>",
+	Short: "Resume a paused sandbox",
+	Args:  cobra.ExactArgs(1),
+	Example: `  # Resume a paused sandbox
+  liteboxd sandbox resume <sandbox-id>`,
+	RunE: runSandboxResume,
+}
+
 var (
 	execTimeout     int
 	exitCodeFlag    bool
 	execInteractive bool // -i flag
 	execTTY         bool // -t flag
+	execStream      bool // --stream flag
 )
 
 var sandboxExecCmd = &cobra.Command{
@@ -132,7 +264,13 @@ var sandboxExecCmd = &cobra.Command{
   liteboxd sandbox exec -it <id>
 
   # Long-running command
-  liteboxd sandbox exec <id> --timeout 300 -- npm test`,
+  liteboxd sandbox exec <id> --timeout 300 -- npm test
+
+  # Stream output while the command runs
+  liteboxd sandbox exec <id> --stream --timeout 1800 -- make test
+
+  # Stream with local stdin piped to the command
+  cat data.csv | liteboxd sandbox exec <id> --stream -i -- python process.py`,
 	RunE: runSandboxExec,
 }
 
@@ -153,23 +291,34 @@ var sandboxLogsCmd = &cobra.Command{
 	RunE: runSandboxLogs,
 }
 
+var transferRecursive bool // -r flag for upload/download
+
 var sandboxUploadCmd = &cobra.Command{
-	Use:     "upload <id> <local-path> <remote-path>",
-	Short:   "Upload file to sandbox",
-	Args:    cobra.ExactArgs(3),
-	Example: `  liteboxd sandbox upload <id> ./main.py /workspace/main.py`,
-	RunE:    runSandboxUpload,
+	Use:   "upload <id> <local-path> <remote-path>",
+	Short: "Upload file or directory to sandbox",
+	Args:  cobra.ExactArgs(3),
+	Example: `  liteboxd sandbox upload <id> ./main.py /workspace/main.py
+
+  # Upload the contents of a directory (file modes are preserved)
+  liteboxd sandbox upload -r <id> ./project /workspace/project`,
+	RunE: runSandboxUpload,
 }
 
 var sandboxDownloadCmd = &cobra.Command{
 	Use:   "download <id> <remote-path> [local-path]",
-	Short: "Download file from sandbox",
+	Short: "Download file or directory from sandbox",
 	Args:  cobra.RangeArgs(2, 3),
 	Example: `  # Download to stdout
   liteboxd sandbox download <id> /workspace/output.txt
 
   # Download to file
-  liteboxd sandbox download <id> /workspace/output.txt ./output.txt`,
+  liteboxd sandbox download <id> /workspace/output.txt ./output.txt
+
+  # Download the contents of a directory into ./results
+  liteboxd sandbox download -r <id> /workspace/results ./results
+
+  # Write a directory as tar.gz to stdout
+  liteboxd sandbox download -r <id> /workspace/results > results.tar.gz`,
 	RunE: runSandboxDownload,
 }
 
@@ -190,15 +339,21 @@ func init() {
 	rootCmd.AddCommand(sandboxCmd)
 
 	// Create command
-	sandboxCreateCmd.Flags().StringVar(&templateFlag, "template", "", "Template name (required)")
+	sandboxCreateCmd.Flags().StringVar(&templateFlag, "template", "", "Template name (required unless --from-snapshot is set)")
 	sandboxCreateCmd.Flags().IntVar(&templateVersionFlag, "template-version", 0, "Template version (default latest)")
+	sandboxCreateCmd.Flags().StringVar(&fromSnapshotFlag, "from-snapshot", "", "Start from the root filesystem of a snapshot")
 	sandboxCreateCmd.Flags().StringVar(&cpuFlag, "cpu", "", "Override CPU limit")
 	sandboxCreateCmd.Flags().StringVar(&memoryFlag, "memory", "", "Override memory limit")
 	sandboxCreateCmd.Flags().IntVar(&ttlFlag, "ttl", 0, "Override TTL in seconds")
+	sandboxCreateCmd.Flags().StringVar(&ttlModeFlag, "ttl-mode", "", "Override TTL mode (fixed, idle)")
 	sandboxCreateCmd.Flags().StringSliceVar(&envFlag, "env", nil, "Environment variables (KEY=VALUE)")
+	sandboxCreateCmd.Flags().BoolVar(&internetFlag, "internet", false, "Override internet access")
+	sandboxCreateCmd.Flags().StringSliceVar(&allowDomainFlag, "allow-domain", nil, "Only allow egress to these domains (implies --internet)")
+	sandboxCreateCmd.Flags().StringVar(&networkGroupFlag, "network-group", "", "Join a network group whose members can reach each other at peer-<id>")
+	sandboxCreateCmd.Flags().StringSliceVar(&groupPortFlag, "group-port", nil, "Port the network group may connect to, as PORT or PORT/PROTOCOL (tcp, udp, any)")
+	sandboxCreateCmd.Flags().StringVar(&projectFlag, "project", "", "Create the sandbox in a project (default: the project of a scoped API key)")
 	sandboxCreateCmd.Flags().BoolVar(&waitFlag, "wait", false, "Wait for sandbox to be ready")
 	sandboxCreateCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "Only print sandbox ID")
-	sandboxCreateCmd.MarkFlagRequired("template")
 	sandboxCmd.AddCommand(sandboxCreateCmd)
 
 	// List command
@@ -223,12 +378,46 @@ func init() {
 	// Start command
 	sandboxCmd.AddCommand(sandboxStartCmd)
 
+	// Pause and resume commands
+	sandboxCmd.AddCommand(sandboxPauseCmd)
+	sandboxCmd.AddCommand(sandboxResumeCmd)
+
+	// Extend command
+	sandboxExtendCmd.Flags().DurationVar(&extendTTLFlag, "ttl", 0, "Expire this long from now (e.g. 30m, 2h)")
+	sandboxExtendCmd.Flags().StringVar(&extendExpiresAtFlag, "expires-at", "", "Expire at this time (RFC3339)")
+	sandboxExtendCmd.MarkFlagsMutuallyExclusive("ttl", "expires-at")
+	sandboxExtendCmd.MarkFlagsOneRequired("ttl", "expires-at")
+	sandboxCmd.AddCommand(sandboxExtendCmd)
+
+	sandboxRotateTokenCmd.Flags().DurationVar(&rotateGraceFlag, "grace", 0, "Keep the old token valid this long (at most 24h)")
+	sandboxRotateTokenCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "Only print the new token")
+	sandboxCmd.AddCommand(sandboxRotateTokenCmd)
+
+	// Network command
+	sandboxNetworkCmd.Flags().BoolVar(&internetFlag, "internet", false, "Allow internet access")
+	sandboxNetworkCmd.Flags().StringSliceVar(&allowDomainFlag, "allow-domain", nil, "Only allow egress to these domains (implies --internet)")
+	sandboxNetworkCmd.Flags().BoolVar(&networkResetFlag, "reset", false, "Follow the template's network configuration again")
+	sandboxNetworkCmd.MarkFlagsMutuallyExclusive("reset", "internet")
+	sandboxNetworkCmd.MarkFlagsMutuallyExclusive("reset", "allow-domain")
+	sandboxNetworkCmd.MarkFlagsOneRequired("reset", "internet", "allow-domain")
+	sandboxNetworkEventsCmd.Flags().StringVar(&networkEventsKindFlag, "kind", "", "Only show events of this kind (dns or connection)")
+	sandboxNetworkEventsCmd.Flags().DurationVar(&networkEventsSinceFlag, "since", 0, "Only show events newer than this (e.g. 30m, 24h)")
+	sandboxNetworkEventsCmd.Flags().IntVar(&networkEventsLimitFlag, "limit", 0, "Maximum number of events (server default 100)")
+	sandboxNetworkEventsCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	sandboxNetworkCmd.AddCommand(sandboxNetworkEventsCmd)
+	sandboxCmd.AddCommand(sandboxNetworkCmd)
+
+	// Traffic command
+	sandboxTrafficCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	sandboxCmd.AddCommand(sandboxTrafficCmd)
+
 	// Exec command
 	sandboxExecCmd.Flags().IntVar(&execTimeout, "timeout", 30, "Execution timeout in seconds")
 	sandboxExecCmd.Flags().BoolVar(&quietFlag, "quiet", false, "Only print stdout")
 	sandboxExecCmd.Flags().BoolVar(&exitCodeFlag, "exit-code", false, "Print exit code")
 	sandboxExecCmd.Flags().BoolVarP(&execInteractive, "stdin", "i", false, "Pass stdin to the container")
 	sandboxExecCmd.Flags().BoolVarP(&execTTY, "tty", "t", false, "Allocate a pseudo-TTY")
+	sandboxExecCmd.Flags().BoolVar(&execStream, "stream", false, "Stream output as it is produced (non-TTY)")
 	sandboxCmd.AddCommand(sandboxExecCmd)
 
 	// Logs command
@@ -236,7 +425,9 @@ func init() {
 	sandboxLogsCmd.Flags().BoolVar(&logsEventsFlag, "events", false, "Show Pod events")
 	sandboxCmd.AddCommand(sandboxLogsCmd)
 
+	sandboxUploadCmd.Flags().BoolVarP(&transferRecursive, "recursive", "r", false, "Upload a directory")
 	sandboxCmd.AddCommand(sandboxUploadCmd)
+	sandboxDownloadCmd.Flags().BoolVarP(&transferRecursive, "recursive", "r", false, "Download a directory")
 	sandboxCmd.AddCommand(sandboxDownloadCmd)
 
 	// Wait command
@@ -247,13 +438,20 @@ func init() {
 }
 
 func runSandboxCreate(cmd *cobra.Command, args []string) error {
+	if templateFlag == "" && fromSnapshotFlag == "" {
+		return fmt.Errorf("required flag \"template\" not set")
+	}
 	client := getAPIClient()
 	ctx, _ := getContext()
 
 	// Build overrides
 	var overrides *liteboxd.SandboxOverrides
 	ttlChanged := cmd.Flags().Changed("ttl")
-	if cpuFlag != "" || memoryFlag != "" || ttlChanged || len(envFlag) > 0 {
+	network, err := networkFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if cpuFlag != "" || memoryFlag != "" || ttlChanged || ttlModeFlag != "" || len(envFlag) > 0 || network != nil {
 		overrides = &liteboxd.SandboxOverrides{}
 		if cpuFlag != "" {
 			overrides.CPU = cpuFlag
@@ -265,19 +463,30 @@ func runSandboxCreate(cmd *cobra.Command, args []string) error {
 			ttl := ttlFlag
 			overrides.TTL = &ttl
 		}
+		if ttlModeFlag != "" {
+			overrides.TTLMode = liteboxd.TTLMode(ttlModeFlag)
+		}
 		if len(envFlag) > 0 {
 			overrides.Env = parseEnvVars(envFlag)
 		}
+		overrides.Network = network
 	}
 
-	// Create sandbox
-	var sandbox *liteboxd.Sandbox
-	var err error
-	if templateVersionFlag > 0 {
-		sandbox, err = client.Sandbox.CreateWithVersion(ctx, templateFlag, templateVersionFlag, overrides)
-	} else {
-		sandbox, err = client.Sandbox.Create(ctx, templateFlag, overrides)
+	groupPorts, err := parsePortRules(groupPortFlag)
+	if err != nil {
+		return err
 	}
+
+	// Create sandbox
+	sandbox, err := client.Sandbox.CreateWithRequest(ctx, &liteboxd.CreateSandboxRequest{
+		Template:        templateFlag,
+		TemplateVersion: templateVersionFlag,
+		FromSnapshot:    fromSnapshotFlag,
+		Overrides:       overrides,
+		NetworkGroup:    networkGroupFlag,
+		GroupPorts:      groupPorts,
+		Project:         projectFlag,
+	})
 	if err != nil {
 		return err
 	}
@@ -288,6 +497,9 @@ func runSandboxCreate(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Created sandbox: %s\n", sandbox.ID)
 		fmt.Printf("Status: %s\n", sandbox.Status)
 		fmt.Printf("Expires: %s\n", sandbox.ExpiresAt.Format(time.RFC3339))
+		if sandbox.PeerHost != "" {
+			fmt.Printf("Peer host: %s (network group %s)\n", sandbox.PeerHost, sandbox.NetworkGroup)
+		}
 	}
 
 	// Wait for ready if requested
@@ -314,7 +526,7 @@ func runSandboxList(cmd *cobra.Command, args []string) error {
 	var formatter output.Formatter
 	if format == output.FormatTable {
 		// Show only basic fields in list view
-		formatter = output.NewTableFormatter([]string{"id", "image", "status", "createdAt", "expiresAt"})
+		formatter = output.NewTableFormatter([]string{"id", "image", "status", "owner.username", "createdAt", "expiresAt"})
 	} else {
 		formatter = output.NewFormatter(format)
 	}
@@ -400,10 +612,201 @@ func runSandboxStart(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runSandboxPause(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	id := args[0]
+	if err := client.Sandbox.Pause(ctx, id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pause requested: %s\n", id)
+	return nil
+}
+
+func runSandboxExtend(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	id := args[0]
+	var sandbox *liteboxd.Sandbox
+	var err error
+	if extendExpiresAtFlag != "" {
+		expiresAt, parseErr := time.Parse(time.RFC3339, extendExpiresAtFlag)
+		if parseErr != nil {
+			return fmt.Errorf("invalid --expires-at: %w", parseErr)
+		}
+		sandbox, err = client.Sandbox.SetExpiresAt(ctx, id, expiresAt)
+	} else {
+		if extendTTLFlag < time.Second {
+			return fmt.Errorf("--ttl must be at least 1s")
+		}
+		sandbox, err = client.Sandbox.ExtendTTL(ctx, id, extendTTLFlag)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sandbox %s now expires at %s\n", id, sandbox.ExpiresAt.Local().Format(time.RFC3339))
+	return nil
+}
+
+func runSandboxRotateToken(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	sandbox, err := client.Sandbox.RotateToken(ctx, args[0], rotateGraceFlag)
+	if err != nil {
+		return err
+	}
+	if quietFlag {
+		fmt.Println(sandbox.AccessToken)
+		return nil
+	}
+	fmt.Printf("New access token: %s\n", sandbox.AccessToken)
+	if sandbox.PreviousAccessTokenExpiresAt != nil {
+		fmt.Printf("Old token accepted until: %s\n", sandbox.PreviousAccessTokenExpiresAt.Local().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func runSandboxNetwork(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	id := args[0]
+	var sandbox *liteboxd.Sandbox
+	var err error
+	if networkResetFlag {
+		sandbox, err = client.Sandbox.ResetNetwork(ctx, id)
+	} else {
+		network, flagErr := networkFromFlags(cmd)
+		if flagErr != nil {
+			return flagErr
+		}
+		sandbox, err = client.Sandbox.UpdateNetwork(ctx, id, &liteboxd.UpdateSandboxNetworkRequest{NetworkSpec: *network})
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case sandbox.Network == nil:
+		fmt.Printf("Sandbox %s follows its template's network configuration\n", id)
+	case !sandbox.Network.AllowInternetAccess:
+		fmt.Printf("Sandbox %s has no internet access\n", id)
+	case len(sandbox.Network.AllowedDomains) > 0:
+		fmt.Printf("Sandbox %s may reach: %s\n", id, strings.Join(sandbox.Network.AllowedDomains, ", "))
+	default:
+		fmt.Printf("Sandbox %s has internet access\n", id)
+	}
+	return nil
+}
+
+func runSandboxNetworkEvents(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	query := &liteboxd.NetworkEventQuery{
+		Kind:  liteboxd.NetworkEventKind(networkEventsKindFlag),
+		Limit: networkEventsLimitFlag,
+	}
+	if networkEventsSinceFlag > 0 {
+		since := time.Now().Add(-networkEventsSinceFlag)
+		query.Since = &since
+	}
+	events, err := client.Sandbox.ListNetworkEvents(ctx, args[0], query)
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"observed_at", "kind", "verdict", "domain", "destination_ip", "port", "protocol"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), events)
+}
+
+func runSandboxTraffic(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	traffic, err := client.Sandbox.GetTraffic(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"sandbox_id", "bytes_in", "bytes_out", "updated_at"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), traffic)
+}
+
+// parsePortRules parses PORT or PORT/PROTOCOL values.
+func parsePortRules(values []string) ([]liteboxd.PortRule, error) {
+	var rules []liteboxd.PortRule
+	for _, value := range values {
+		portStr, protocol, _ := strings.Cut(value, "/")
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		rules = append(rules, liteboxd.PortRule{Port: port, Protocol: strings.ToUpper(protocol)})
+	}
+	return rules, nil
+}
+
+// networkFromFlags builds a network configuration from --internet and --allow-domain,
+// or returns nil when neither is set.
+func networkFromFlags(cmd *cobra.Command) (*liteboxd.NetworkSpec, error) {
+	internetChanged := cmd.Flags().Changed("internet")
+	if !internetChanged && len(allowDomainFlag) == 0 {
+		return nil, nil
+	}
+	if internetChanged && !internetFlag && len(allowDomainFlag) > 0 {
+		return nil, fmt.Errorf("--allow-domain cannot be combined with --internet=false")
+	}
+	return &liteboxd.NetworkSpec{
+		AllowInternetAccess: internetFlag || len(allowDomainFlag) > 0,
+		AllowedDomains:      allowDomainFlag,
+	}, nil
+}
+
+func runSandboxResume(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	id := args[0]
+	if err := client.Sandbox.Resume(ctx, id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Resume requested: %s\n", id)
+	return nil
+}
+
 func runSandboxExec(cmd *cobra.Command, args []string) error {
 	id := args[0]
 	cmdArgs := args[1:]
 
+	// --stream uses the NDJSON streaming endpoint; -i forwards local stdin
+	if execStream {
+		if execTTY {
+			return fmt.Errorf("--stream cannot be combined with -t")
+		}
+		return runStreamExec(cmd, id, cmdArgs)
+	}
+
 	// If -i or -t, use interactive mode
 	if execInteractive || execTTY {
 		return runInteractiveExec(id, cmdArgs)
@@ -438,6 +841,36 @@ func runNonInteractiveExec(cmd *cobra.Command, id string, cmdArgs []string) erro
 	return nil
 }
 
+func runStreamExec(cmd *cobra.Command, id string, cmdArgs []string) error {
+	client := getAPIClient()
+
+	var stdin io.Reader
+	if execInteractive {
+		stdin = os.Stdin
+	}
+
+	var stderr io.Writer = os.Stderr
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	if quiet {
+		stderr = nil
+	}
+
+	exitCode, err := client.Sandbox.ExecStream(context.Background(), id, cmdArgs, execTimeout, stdin, os.Stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	if exitCodeFlag {
+		os.Exit(exitCode)
+	}
+
+	if exitCode != 0 {
+		return fmt.Errorf("command exited with code %d", exitCode)
+	}
+
+	return nil
+}
+
 func runInteractiveExec(id string, command []string) error {
 	if len(command) == 0 {
 		command = []string{"sh"} // default shell
@@ -545,13 +978,25 @@ func runSandboxUpload(cmd *cobra.Command, args []string) error {
 	localPath := args[1]
 	remotePath := args[2]
 
-	// Read file
-	content, err := os.ReadFile(localPath)
+	if transferRecursive {
+		if err := client.Sandbox.UploadDirectory(ctx, id, localPath, remotePath); err != nil {
+			return err
+		}
+		fmt.Printf("Uploaded directory: %s -> %s\n", localPath, remotePath)
+		return nil
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	if err := client.Sandbox.UploadFile(ctx, id, remotePath, content, ""); err != nil {
+	if err := client.Sandbox.UploadFileFromReader(ctx, id, remotePath, f, info.Size()); err != nil {
 		return err
 	}
 
@@ -571,21 +1016,37 @@ func runSandboxDownload(cmd *cobra.Command, args []string) error {
 		localPath = args[2]
 	}
 
-	content, err := client.Sandbox.DownloadFile(ctx, id, remotePath)
-	if err != nil {
-		return err
+	if transferRecursive {
+		if localPath == "" {
+			// Write the tar.gz archive to stdout
+			return client.Sandbox.DownloadArchive(ctx, id, remotePath, os.Stdout)
+		}
+		if err := client.Sandbox.DownloadDirectory(ctx, id, remotePath, localPath); err != nil {
+			return err
+		}
+		fmt.Printf("Downloaded directory: %s -> %s\n", remotePath, localPath)
+		return nil
 	}
 
 	if localPath == "" {
 		// Write to stdout
-		fmt.Print(string(content))
-	} else {
-		if err := os.WriteFile(localPath, content, 0644); err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
-		}
-		fmt.Printf("Downloaded: %s -> %s\n", remotePath, localPath)
+		_, err := client.Sandbox.DownloadFileToWriter(ctx, id, remotePath, os.Stdout)
+		return err
 	}
 
+	f, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	_, err = client.Sandbox.DownloadFileToWriter(ctx, id, remotePath, f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(localPath)
+		return err
+	}
+	fmt.Printf("Downloaded: %s -> %s\n", remotePath, localPath)
 	return nil
 }
 
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fslongjin/liteboxd/liteboxd-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var sandboxSnapshotCmd = &cobra.Command{
+	Use:     "snapshot",
+	Aliases: []string{"snap"},
+	Short:   "Manage sandbox snapshots",
+	Long: `Capture the root filesystem of persistence-enabled sandboxes and restore it into new
+sandboxes with 'liteboxd sandbox create --from-snapshot <snapshot-id>'.`,
+}
+
+var (
+	snapshotNameFlag string
+	snapshotWaitFlag bool
+)
+
+var sandboxSnapshotCreateCmd = &cobra.Command{
+	Use:   "create <id>",
+	Short: "Snapshot a sandbox",
+	Args:  cobra.ExactArgs(1),
+	Example: `  # Snapshot a sandbox and wait until it can be restored
+  liteboxd sandbox snapshot create <id> --name before-upgrade --wait`,
+	RunE: runSandboxSnapshotCreate,
+}
+
+var sandboxSnapshotListCmd = &cobra.Command{
+	Use:   "list [id]",
+	Short: "List snapshots of a sandbox, or of all sandboxes",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runSandboxSnapshotList,
+}
+
+var sandboxSnapshotGetCmd = &cobra.Command{
+	Use:   "get <snapshot-id>",
+	Short: "Get snapshot details",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSandboxSnapshotGet,
+}
+
+var sandboxSnapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <snapshot-id>",
+	Short: "Delete a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSandboxSnapshotDelete,
+}
+
+func init() {
+	sandboxCmd.AddCommand(sandboxSnapshotCmd)
+
+	sandboxSnapshotCreateCmd.Flags().StringVar(&snapshotNameFlag, "name", "", "Snapshot name")
+	sandboxSnapshotCreateCmd.Flags().BoolVar(&snapshotWaitFlag, "wait", false, "Wait for the snapshot to be ready")
+	sandboxSnapshotCreateCmd.Flags().DurationVar(&waitTimeoutFlag, "timeout", 10*time.Minute, "Max wait time")
+	sandboxSnapshotCreateCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "Only print snapshot ID")
+	sandboxSnapshotCmd.AddCommand(sandboxSnapshotCreateCmd)
+
+	sandboxSnapshotCmd.AddCommand(sandboxSnapshotListCmd)
+	sandboxSnapshotCmd.AddCommand(sandboxSnapshotGetCmd)
+	sandboxSnapshotCmd.AddCommand(sandboxSnapshotDeleteCmd)
+}
+
+func runSandboxSnapshotCreate(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	snapshot, err := client.Sandbox.CreateSnapshot(ctx, args[0], snapshotNameFlag)
+	if err != nil {
+		return err
+	}
+	if quietFlag {
+		fmt.Println(snapshot.ID)
+	} else {
+		fmt.Printf("Created snapshot: %s (%s)\n", snapshot.ID, snapshot.Kind)
+	}
+
+	if snapshotWaitFlag {
+		snapshot, err = client.Sandbox.WaitForSnapshot(ctx, snapshot.ID, 2*time.Second, waitTimeoutFlag)
+		if err != nil {
+			return err
+		}
+		if !quietFlag {
+			fmt.Printf("Snapshot %s is ready\n", snapshot.ID)
+		}
+	}
+	return nil
+}
+
+func runSandboxSnapshotList(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	sandboxID := ""
+	if len(args) == 1 {
+		sandboxID = args[0]
+	}
+	snapshots, err := client.Sandbox.ListSnapshots(ctx, sandboxID)
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"id", "sandbox_id", "name", "kind", "status", "template", "created_at"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), snapshots)
+}
+
+func runSandboxSnapshotGet(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	snapshot, err := client.Sandbox.GetSnapshot(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	formatter := output.NewFormatter(output.ParseFormat(outputFormat))
+	return formatter.Write(cmd.OutOrStdout(), snapshot)
+}
+
+func runSandboxSnapshotDelete(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	if err := client.Sandbox.DeleteSnapshot(ctx, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted snapshot: %s\n", args[0])
+	return nil
+}
@@ -30,6 +30,7 @@ var (
 	templateTTL         int
 	templateFile        string
 	templateChangelog   string
+	templateProject     string
 )
 
 var templateCreateCmd = &cobra.Command{
@@ -42,7 +43,10 @@ You can provide template configuration via --file (YAML) or using individual fla
   liteboxd template create --name python-ds --file template.yaml
 
   # Create using flags
-  liteboxd template create --name node-basic --image node:20-alpine --cpu 500m`,
+  liteboxd template create --name node-basic --image node:20-alpine --cpu 500m
+
+  # Create a template only the sandboxes of a project can use
+  liteboxd template create --name team-python --image python:3.11 --project team-a`,
 	RunE: runTemplateCreate,
 }
 
@@ -130,6 +134,7 @@ func init() {
 	templateCreateCmd.Flags().StringVar(&templateCPU, "cpu", "", "CPU limit")
 	templateCreateCmd.Flags().StringVar(&templateMemory, "memory", "", "Memory limit")
 	templateCreateCmd.Flags().IntVar(&templateTTL, "ttl", 0, "Default TTL in seconds")
+	templateCreateCmd.Flags().StringVar(&templateProject, "project", "", "Make the template usable only in this project")
 	templateCreateCmd.MarkFlagRequired("name")
 	templateCmd.AddCommand(templateCreateCmd)
 
@@ -139,6 +144,7 @@ func init() {
 	templateListCmd.Flags().String("search", "", "Search in name/description")
 	templateListCmd.Flags().Int("page", 1, "Page number")
 	templateListCmd.Flags().Int("page-size", 20, "Items per page")
+	templateListCmd.Flags().String("project", "", "Only list global templates and those of this project")
 	templateCmd.AddCommand(templateListCmd)
 
 	// Get command
@@ -193,6 +199,7 @@ func runTemplateCreate(cmd *cobra.Command, args []string) error {
 	req.DisplayName = templateDisplayName
 	req.Description = templateDescription
 	req.Tags = templateTags
+	req.Project = templateProject
 
 	// Build spec from flags
 	req.Spec = liteboxd.TemplateSpec{
@@ -226,12 +233,14 @@ func runTemplateList(cmd *cobra.Command, args []string) error {
 	search, _ := cmd.Flags().GetString("search")
 	page, _ := cmd.Flags().GetInt("page")
 	pageSize, _ := cmd.Flags().GetInt("page-size")
+	project, _ := cmd.Flags().GetString("project")
 
 	opts := &liteboxd.TemplateListOptions{
 		Tag:      tag,
 		Search:   search,
 		Page:     page,
 		PageSize: pageSize,
+		Project:  project,
 	}
 
 	resp, err := client.Template.List(ctx, opts)
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fslongjin/liteboxd/liteboxd-cli/internal/output"
+	liteboxd "github.com/fslongjin/liteboxd/sdk/go"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+	Long: `Create, update and remove the users of a LiteBoxd server. Requires the admin role.
+
+Roles:
+  admin      everything, including managing users
+  developer  create, change and exec into sandboxes and templates
+  viewer     read-only access
+
+API keys act with the role of the user that created them.`,
+}
+
+var userListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List users",
+	Example: `  liteboxd user list`,
+	RunE:    runUserList,
+}
+
+var userGetCmd = &cobra.Command{
+	Use:   "get <user>",
+	Short: "Show a user by ID or username",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUserGet,
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create <username>",
+	Short: "Create a user",
+	Args:  cobra.ExactArgs(1),
+	Example: `  # Create a developer, prompting for the password
+  liteboxd user create alice
+
+  # Create a read-only user
+  liteboxd user create bob --role viewer`,
+	RunE: runUserCreate,
+}
+
+var userUpdateCmd = &cobra.Command{
+	Use:   "update <user>",
+	Short: "Change the role or password of a user",
+	Args:  cobra.ExactArgs(1),
+	Example: `  # Make alice an admin
+  liteboxd user update alice --role admin
+
+  # Set a new password for bob
+  liteboxd user update bob --reset-password`,
+	RunE: runUserUpdate,
+}
+
+var userDisableCmd = &cobra.Command{
+	Use:   "disable <user>",
+	Short: "Disable a user, ending their sessions and rejecting their API keys",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUserSetDisabled(true),
+}
+
+var userEnableCmd = &cobra.Command{
+	Use:   "enable <user>",
+	Short: "Enable a disabled user",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUserSetDisabled(false),
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete <user>",
+	Short: "Delete a user and their API keys",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUserDelete,
+}
+
+var (
+	userCreateRoleFlag    string
+	userUpdateRoleFlag    string
+	userPasswordFlag      string
+	userResetPasswordFlag bool
+)
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+
+	userListCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	userCmd.AddCommand(userListCmd)
+
+	userGetCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json, yaml)")
+	userCmd.AddCommand(userGetCmd)
+
+	userCreateCmd.Flags().StringVar(&userCreateRoleFlag, "role", "developer", "Role of the user (admin, developer, viewer)")
+	userCreateCmd.Flags().StringVar(&userPasswordFlag, "password", "", "Password (prompted for when not set)")
+	userCmd.AddCommand(userCreateCmd)
+
+	userUpdateCmd.Flags().StringVar(&userUpdateRoleFlag, "role", "", "New role (admin, developer, viewer)")
+	userUpdateCmd.Flags().BoolVar(&userResetPasswordFlag, "reset-password", false, "Prompt for a new password")
+	userUpdateCmd.MarkFlagsOneRequired("role", "reset-password")
+	userCmd.AddCommand(userUpdateCmd)
+
+	userCmd.AddCommand(userDisableCmd)
+	userCmd.AddCommand(userEnableCmd)
+	userCmd.AddCommand(userDeleteCmd)
+}
+
+// promptPassword reads a password twice without echoing it.
+func promptPassword() (string, error) {
+	fmt.Print("Password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	fmt.Print("Confirm password: ")
+	confirm, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if string(password) != string(confirm) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return string(password), nil
+}
+
+func runUserList(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	users, err := client.User.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	format := output.ParseFormat(outputFormat)
+	var formatter output.Formatter
+	if format == output.FormatTable {
+		formatter = output.NewTableFormatter([]string{"username", "role", "disabled", "id", "created_at"})
+	} else {
+		formatter = output.NewFormatter(format)
+	}
+
+	return formatter.Write(cmd.OutOrStdout(), users)
+}
+
+func runUserGet(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	user, err := client.User.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	formatter := output.NewFormatter(output.ParseFormat(outputFormat))
+	return formatter.Write(cmd.OutOrStdout(), user)
+}
+
+func runUserCreate(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	password := userPasswordFlag
+	if password == "" {
+		var err error
+		if password, err = promptPassword(); err != nil {
+			return err
+		}
+	}
+
+	user, err := client.User.Create(ctx, args[0], password, liteboxd.Role(userCreateRoleFlag))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created user %s (%s)\n", user.Username, user.Role)
+	return nil
+}
+
+func runUserUpdate(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	req := &liteboxd.UpdateUserRequest{}
+	if userUpdateRoleFlag != "" {
+		role := liteboxd.Role(userUpdateRoleFlag)
+		req.Role = &role
+	}
+	if userResetPasswordFlag {
+		password, err := promptPassword()
+		if err != nil {
+			return err
+		}
+		req.Password = &password
+	}
+
+	user, err := client.User.Update(ctx, args[0], req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated user %s (%s)\n", user.Username, user.Role)
+	return nil
+}
+
+func runUserSetDisabled(disabled bool) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		client := getAPIClient()
+		ctx, _ := getContext()
+
+		var err error
+		var user *liteboxd.User
+		if disabled {
+			user, err = client.User.Disable(ctx, args[0])
+		} else {
+			user, err = client.User.Enable(ctx, args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		if user.Disabled {
+			fmt.Printf("Disabled user %s\n", user.Username)
+		} else {
+			fmt.Printf("Enabled user %s\n", user.Username)
+		}
+		return nil
+	}
+}
+
+func runUserDelete(cmd *cobra.Command, args []string) error {
+	client := getAPIClient()
+	ctx, _ := getContext()
+
+	if err := client.User.Delete(ctx, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted user %s\n", args[0])
+	return nil
+}
@@ -30,6 +30,9 @@ type Client struct {
 	Template     *TemplateService
 	Prepull      *PrepullService
 	ImportExport *ImportExportService
+	User         *UserService
+	Project      *ProjectService
+	Quota        *QuotaService
 }
 
 // NewClient creates a new LiteBoxd API client.
@@ -58,6 +61,9 @@ func NewClient(baseURL string, opts ...Option) *Client {
 	c.Template = &TemplateService{client: c}
 	c.Prepull = &PrepullService{client: c}
 	c.ImportExport = &ImportExportService{client: c}
+	c.User = &UserService{client: c}
+	c.Project = &ProjectService{client: c}
+	c.Quota = &QuotaService{client: c}
 
 	return c
 }
@@ -213,3 +219,49 @@ func (c *Client) doText(ctx context.Context, method, requestPath string, queryPa
 func (c *Client) buildPath(segments ...string) string {
 	return path.Join(segments...)
 }
+
+// doStreamRequest performs a request whose response body is consumed incrementally.
+// The client-level timeout is not applied since it would cut off long-lived streams;
+// callers bound the request through ctx instead.
+func (c *Client) doStreamRequest(ctx context.Context, method, requestPath string, bodyReader io.Reader, contentType string, query url.Values) (*http.Response, error) {
+	req, err := c.newStreamRequest(ctx, method, requestPath, bodyReader, contentType, query)
+	if err != nil {
+		return nil, err
+	}
+	return c.doStream(req)
+}
+
+// newStreamRequest builds a request for doStream, for callers that need to set extra
+// headers or a content length first.
+func (c *Client) newStreamRequest(ctx context.Context, method, requestPath string, bodyReader io.Reader, contentType string, query url.Values) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path = c.baseURL.Path + "/" + requestPath
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+	return req, nil
+}
+
+// doStream executes a request without the client-level timeout.
+func (c *Client) doStream(req *http.Request) (*http.Response, error) {
+	streamClient := *c.httpClient
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+
+	return resp, nil
+}
@@ -23,13 +23,24 @@ var (
 
 	// ErrUnauthorized is returned when authentication fails.
 	ErrUnauthorized = &APIError{StatusCode: 401, Message: "unauthorized"}
+
+	// ErrForbidden is returned when the role of the caller doesn't allow the operation.
+	ErrForbidden = &APIError{StatusCode: 403, Message: "forbidden"}
 )
 
+// ErrorCodeQuotaExceeded is the code of errors returned when a request would exceed a
+// quota.
+const ErrorCodeQuotaExceeded = "QUOTA_EXCEEDED"
+
 // APIError represents an error response from the API.
 type APIError struct {
 	StatusCode int
 	Message    string
 	Err        error
+	// Code identifies the error when the API reports one, such as ErrorCodeQuotaExceeded
+	Code string
+	// Quota describes the exceeded quota of QUOTA_EXCEEDED errors
+	Quota *QuotaViolation
 }
 
 // Error returns the error message.
@@ -56,7 +67,9 @@ func (e *APIError) Unwrap() error {
 
 // errorResponse represents an error response from the API.
 type errorResponse struct {
-	Error string `json:"error"`
+	Error string          `json:"error"`
+	Code  string          `json:"code,omitempty"`
+	Quota *QuotaViolation `json:"quota,omitempty"`
 }
 
 // handleErrorResponse handles an error response from the API.
@@ -77,6 +90,8 @@ func handleErrorResponse(resp *http.Response) error {
 		return &APIError{
 			StatusCode: resp.StatusCode,
 			Message:    errResp.Error,
+			Code:       errResp.Code,
+			Quota:      errResp.Quota,
 		}
 	}
 
@@ -101,3 +116,16 @@ func IsConflict(err error) bool {
 func IsBadRequest(err error) bool {
 	return errors.Is(err, ErrBadRequest)
 }
+
+// IsForbidden checks if an error is a forbidden error.
+func IsForbidden(err error) bool {
+	return errors.Is(err, ErrForbidden)
+}
+
+// IsQuotaExceeded checks if an error was returned because the request would exceed a
+// quota: 429 for too many running sandboxes, 403 for any other limit. The exceeded
+// quota is in the Quota field of the APIError.
+func IsQuotaExceeded(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == ErrorCodeQuotaExceeded
+}
@@ -0,0 +1,360 @@
+package liteboxd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// UploadFileFromReader streams r to filePath inside the sandbox without buffering it
+// in memory. size is the number of bytes r will yield, or -1 if unknown; a known size
+// lets the server reject files over its transfer limit before any data is sent.
+func (s *SandboxService) UploadFileFromReader(ctx context.Context, id, filePath string, r io.Reader, size int64) error {
+	query := url.Values{}
+	query.Set("path", filePath)
+
+	req, err := s.client.newStreamRequest(ctx, "POST", s.client.buildPath("sandboxes", id, "files"), r, "application/octet-stream", query)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+
+	resp, err := s.client.doStream(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return handleErrorResponse(resp)
+	}
+
+	var result map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return nil
+}
+
+// DownloadFileToWriter streams a file from the sandbox to w and returns the number of
+// bytes written.
+func (s *SandboxService) DownloadFileToWriter(ctx context.Context, id, filePath string, w io.Writer) (int64, error) {
+	return s.DownloadFileRange(ctx, id, filePath, 0, -1, w)
+}
+
+// DownloadFileRange streams length bytes of a file starting at offset to w and returns
+// the number of bytes written. A negative length reads to the end of the file.
+func (s *SandboxService) DownloadFileRange(ctx context.Context, id, filePath string, offset, length int64, w io.Writer) (int64, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("invalid offset %d", offset)
+	}
+	if length == 0 {
+		return 0, nil
+	}
+
+	query := url.Values{}
+	query.Set("path", filePath)
+
+	req, err := s.client.newStreamRequest(ctx, "GET", s.client.buildPath("sandboxes", id, "files"), nil, "", query)
+	if err != nil {
+		return 0, err
+	}
+	ranged := offset > 0 || length > 0
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := s.client.doStream(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return 0, nil
+	}
+	if resp.StatusCode >= 400 {
+		return 0, handleErrorResponse(resp)
+	}
+	if ranged && resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("server ignored the requested range (status %d)", resp.StatusCode)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to read file: %w", err)
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return n, fmt.Errorf("failed to read file: got %d of %d bytes", n, resp.ContentLength)
+	}
+	return n, nil
+}
+
+// UploadArchive extracts a tar, tar.gz or zip archive into destDir inside the sandbox,
+// creating destDir if needed and preserving file modes. format is one of the
+// ArchiveFormat* constants; an empty format lets the server detect it.
+func (s *SandboxService) UploadArchive(ctx context.Context, id, destDir string, archive io.Reader, format string) error {
+	query := url.Values{}
+	query.Set("path", destDir)
+	query.Set("extract", "true")
+	if format != "" {
+		query.Set("format", format)
+	}
+
+	resp, err := s.client.doStreamRequest(ctx, "POST", s.client.buildPath("sandboxes", id, "files"), archive, "application/octet-stream", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return handleErrorResponse(resp)
+	}
+
+	var result map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return nil
+}
+
+// UploadDirectory uploads the contents of localDir into remoteDir inside the sandbox.
+// The directory is streamed as a tar.gz archive, so it is never held in memory.
+func (s *SandboxService) UploadDirectory(ctx context.Context, id, localDir, remoteDir string) error {
+	info, err := os.Stat(localDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", localDir)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTarGz(pw, localDir))
+	}()
+	defer pr.Close()
+
+	return s.UploadArchive(ctx, id, remoteDir, pr, ArchiveFormatTarGz)
+}
+
+// DownloadArchive writes the contents of remotePath as a tar.gz archive to w.
+// Archive entries are relative to remotePath.
+func (s *SandboxService) DownloadArchive(ctx context.Context, id, remotePath string, w io.Writer) error {
+	query := url.Values{}
+	query.Set("path", remotePath)
+	query.Set("archive", "true")
+
+	resp, err := s.client.doStreamRequest(ctx, "GET", s.client.buildPath("sandboxes", id, "files"), nil, "", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return handleErrorResponse(resp)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	return nil
+}
+
+// DownloadDirectory downloads the contents of remotePath into localDir, creating
+// localDir if needed.
+func (s *SandboxService) DownloadDirectory(ctx context.Context, id, remotePath, localDir string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.DownloadArchive(ctx, id, remotePath, pw))
+	}()
+	defer pr.Close()
+
+	return extractTarGz(pr, localDir)
+}
+
+// writeTarGz writes the contents of dir to w as a tar.gz archive with entries
+// relative to dir.
+func writeTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// extractTarGz extracts a tar.gz stream into dest. Entries escaping dest are rejected.
+// Links are created after all files so an archive cannot redirect later entries
+// through a link it just created, and directory modes are applied last so read-only
+// directories can still be populated.
+func extractTarGz(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+
+	type deferredEntry struct {
+		target string
+		hdr    *tar.Header
+	}
+	var links, dirs []deferredEntry
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		rel, err := archiveEntryPath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if rel == "" {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			dirs = append(dirs, deferredEntry{target: target, hdr: hdr})
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fs.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(target, fs.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			links = append(links, deferredEntry{target: target, hdr: hdr})
+		}
+	}
+
+	for _, l := range links {
+		if err := os.MkdirAll(filepath.Dir(l.target), 0o755); err != nil {
+			return err
+		}
+		_ = os.Remove(l.target)
+		if l.hdr.Typeflag == tar.TypeSymlink {
+			if err := os.Symlink(l.hdr.Linkname, l.target); err != nil {
+				return err
+			}
+			continue
+		}
+		rel, err := archiveEntryPath(l.hdr.Linkname)
+		if err != nil || rel == "" {
+			return fmt.Errorf("invalid hard link %q in archive", l.hdr.Linkname)
+		}
+		if err := os.Link(filepath.Join(dest, filepath.FromSlash(rel)), l.target); err != nil {
+			return err
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].target, fs.FileMode(dirs[i].hdr.Mode).Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveEntryPath returns an archive entry name relative to the extraction root,
+// or "" for the root itself.
+func archiveEntryPath(name string) (string, error) {
+	rel := path.Clean(strings.TrimLeft(name, "/"))
+	if rel == "." {
+		return "", nil
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("archive entry %q escapes the destination", name)
+	}
+	return rel, nil
+}
@@ -0,0 +1,135 @@
+package liteboxd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListDir lists the entries of a directory inside the sandbox, including hidden ones.
+// dir must be an absolute path. Symlinks are reported as-is, not followed.
+func (s *SandboxService) ListDir(ctx context.Context, id, dir string) ([]FileInfo, error) {
+	var result FileListResponse
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "fs"), nil, &result, map[string]string{"path": dir})
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// Stat returns information about a single path inside the sandbox.
+func (s *SandboxService) Stat(ctx context.Context, id, path string) (*FileInfo, error) {
+	var result FileInfo
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "fs", "stat"), nil, &result, map[string]string{"path": path})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Mkdir creates a directory and any missing parents, like mkdir -p.
+func (s *SandboxService) Mkdir(ctx context.Context, id, path string) error {
+	req := &MkdirRequest{Path: path}
+	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "fs", "mkdir"), req, nil)
+}
+
+// Move renames a file or directory. An existing destination file is replaced only when
+// overwrite is true; an existing destination directory is never replaced.
+func (s *SandboxService) Move(ctx context.Context, id, source, destination string, overwrite bool) error {
+	req := &MoveRequest{Source: source, Destination: destination, Overwrite: overwrite}
+	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "fs", "move"), req, nil)
+}
+
+// Remove deletes a file, symlink or directory. Non-empty directories are only removed
+// when recursive is true.
+func (s *SandboxService) Remove(ctx context.Context, id, path string, recursive bool) error {
+	query := map[string]string{"path": path}
+	if recursive {
+		query["recursive"] = "true"
+	}
+	return s.client.doEmptyResponse(ctx, "DELETE", s.client.buildPath("sandboxes", id, "fs"), nil, query)
+}
+
+// Watch streams create, modify and delete events for path and everything below it
+// until ctx is done. The sandbox is rescanned every interval (one second when zero),
+// so several changes to a path between two scans arrive as one event. The first event
+// is FSEventReady, sent once the initial scan completes. If the watch fails after that,
+// an FSEventError event is sent. The channel is closed when the stream ends.
+func (s *SandboxService) Watch(ctx context.Context, id, path string, interval time.Duration) (<-chan FSEvent, error) {
+	query := url.Values{}
+	query.Set("path", path)
+	if seconds := int(interval / time.Second); seconds > 0 {
+		query.Set("interval", strconv.Itoa(seconds))
+	}
+
+	resp, err := s.client.doStreamRequest(ctx, "GET", s.client.buildPath("sandboxes", id, "fs", "watch"), nil, "", query)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, handleErrorResponse(resp)
+	}
+
+	events := make(chan FSEvent, 64)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		send := func(event FSEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if err := readSSEEvents(resp.Body, func(data []byte) bool {
+			var event FSEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return send(FSEvent{Type: FSEventError, Message: fmt.Sprintf("failed to decode event: %v", err)})
+			}
+			return send(event)
+		}); err != nil && ctx.Err() == nil {
+			send(FSEvent{Type: FSEventError, Message: fmt.Sprintf("watch stream failed: %v", err)})
+		}
+	}()
+	return events, nil
+}
+
+// readSSEEvents calls handle with the data of each server-sent event in r until r ends
+// or handle returns false. Comments and event names are ignored.
+func readSSEEvents(r io.Reader, handle func(data []byte) bool) error {
+	reader := bufio.NewReader(r)
+	var data []byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if !handle(data) {
+					return nil
+				}
+				data = nil
+			}
+		case strings.HasPrefix(line, "data:"):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+}
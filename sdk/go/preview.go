@@ -0,0 +1,29 @@
+package liteboxd
+
+import "context"
+
+// CreatePreviewLink mints a link that opens a port of a sandbox through the gateway
+// without the access token, for use in a browser or to share. The returned URL and
+// Token are only available here.
+func (s *SandboxService) CreatePreviewLink(ctx context.Context, id string, req *CreatePreviewLinkRequest) (*PreviewLink, error) {
+	var result PreviewLink
+	err := s.client.doJSON(ctx, "POST", s.client.buildPath("sandboxes", id, "preview-links"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListPreviewLinks lists the unexpired preview links of a sandbox, newest first.
+func (s *SandboxService) ListPreviewLinks(ctx context.Context, id string) ([]PreviewLink, error) {
+	var result PreviewLinkListResponse
+	if err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "preview-links"), nil, &result, nil); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// RevokePreviewLink revokes a preview link. The gateway rejects its token from then on.
+func (s *SandboxService) RevokePreviewLink(ctx context.Context, id, linkID string) error {
+	return s.client.doEmptyResponse(ctx, "DELETE", s.client.buildPath("sandboxes", id, "preview-links", linkID), nil, nil)
+}
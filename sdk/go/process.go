@@ -0,0 +1,78 @@
+package liteboxd
+
+import (
+	"context"
+	"io"
+	"net/url"
+)
+
+// StartProcess starts a command in the background. The process keeps running after
+// the call returns; use the returned ID to inspect, signal or read its output.
+func (s *SandboxService) StartProcess(ctx context.Context, id string, req *StartProcessRequest) (*SandboxProcess, error) {
+	var result SandboxProcess
+	err := s.client.doJSON(ctx, "POST", s.client.buildPath("sandboxes", id, "processes"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListProcesses lists background processes of a sandbox, newest first.
+func (s *SandboxService) ListProcesses(ctx context.Context, id string) ([]SandboxProcess, error) {
+	var result ProcessListResponse
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "processes"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// GetProcess retrieves a background process with its current status.
+func (s *SandboxService) GetProcess(ctx context.Context, id, processID string) (*SandboxProcess, error) {
+	var result SandboxProcess
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "processes", processID), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetProcessOutput returns the output a background process has produced so far.
+func (s *SandboxService) GetProcessOutput(ctx context.Context, id, processID string) (*ProcessOutputResponse, error) {
+	var result ProcessOutputResponse
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "processes", processID, "output"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FollowProcessOutput copies a background process's output to stdout and stderr,
+// starting from the beginning, until the process finishes. Returns the exit code,
+// or -1 if the process ended without reporting one (killed or lost).
+func (s *SandboxService) FollowProcessOutput(ctx context.Context, id, processID string, stdout, stderr io.Writer) (int, error) {
+	query := url.Values{}
+	query.Set("follow", "true")
+	resp, err := s.client.doStreamRequest(ctx, "GET", s.client.buildPath("sandboxes", id, "processes", processID, "output"), nil, "", query)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return 0, handleErrorResponse(resp)
+	}
+
+	return copyExecStream(resp.Body, stdout, stderr)
+}
+
+// SignalProcess sends a signal such as "TERM", "INT" or "HUP" to a background process.
+func (s *SandboxService) SignalProcess(ctx context.Context, id, processID, signal string) error {
+	req := &SignalProcessRequest{Signal: signal}
+	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "processes", processID, "signal"), req, nil)
+}
+
+// KillProcess terminates a background process with SIGKILL.
+func (s *SandboxService) KillProcess(ctx context.Context, id, processID string) error {
+	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "processes", processID, "kill"), nil, nil)
+}
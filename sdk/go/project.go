@@ -0,0 +1,49 @@
+package liteboxd
+
+import "context"
+
+// ProjectService handles projects. Creating and deleting projects requires the admin
+// role.
+type ProjectService struct {
+	client *Client
+}
+
+// Create creates a project and the Kubernetes namespace its sandboxes run in.
+func (p *ProjectService) Create(ctx context.Context, name, description string) (*Project, error) {
+	req := &CreateProjectRequest{
+		Name:        name,
+		Description: description,
+	}
+	var result Project
+	err := p.client.doJSON(ctx, "POST", p.client.buildPath("projects"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// List retrieves all projects.
+func (p *ProjectService) List(ctx context.Context) ([]Project, error) {
+	var result ProjectListResponse
+	err := p.client.doJSON(ctx, "GET", p.client.buildPath("projects"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// Get retrieves a project by name.
+func (p *ProjectService) Get(ctx context.Context, name string) (*Project, error) {
+	var result Project
+	err := p.client.doJSON(ctx, "GET", p.client.buildPath("projects", name), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Delete deletes a project and its namespace. Projects that still have sandboxes
+// can't be deleted.
+func (p *ProjectService) Delete(ctx context.Context, name string) error {
+	return p.client.doEmptyResponse(ctx, "DELETE", p.client.buildPath("projects", name), nil, nil)
+}
@@ -0,0 +1,58 @@
+package liteboxd
+
+import "context"
+
+// QuotaService handles quotas on the sandboxes of users, API keys and projects.
+// Everyone can see the quotas that apply to them; managing quotas requires the admin
+// role.
+type QuotaService struct {
+	client *Client
+}
+
+// Status retrieves the quotas that apply to the caller, those of its user, API key
+// and project, along with their usage.
+func (q *QuotaService) Status(ctx context.Context) ([]QuotaStatus, error) {
+	var result QuotaStatusResponse
+	err := q.client.doJSON(ctx, "GET", q.client.buildPath("quotas"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// List retrieves all quotas.
+func (q *QuotaService) List(ctx context.Context) ([]Quota, error) {
+	var result QuotaListResponse
+	err := q.client.doJSON(ctx, "GET", q.client.buildPath("quotas", "limits"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// Get retrieves the quota of a subject along with its usage. Subjects are user IDs or
+// usernames, API key IDs or project names, depending on scope; QuotaSubjectDefault
+// is the default quota of the scope.
+func (q *QuotaService) Get(ctx context.Context, scope QuotaScope, subject string) (*QuotaStatus, error) {
+	var result QuotaStatus
+	err := q.client.doJSON(ctx, "GET", q.client.buildPath("quotas", "limits", string(scope), subject), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Set creates or replaces the quota of a subject. Zero limits are unlimited.
+func (q *QuotaService) Set(ctx context.Context, scope QuotaScope, subject string, limits *QuotaLimits) (*Quota, error) {
+	var result Quota
+	err := q.client.doJSON(ctx, "PUT", q.client.buildPath("quotas", "limits", string(scope), subject), limits, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Delete removes the quota of a subject.
+func (q *QuotaService) Delete(ctx context.Context, scope QuotaScope, subject string) error {
+	return q.client.doEmptyResponse(ctx, "DELETE", q.client.buildPath("quotas", "limits", string(scope), subject), nil, nil)
+}
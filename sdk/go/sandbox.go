@@ -3,11 +3,14 @@ package liteboxd
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -51,6 +54,17 @@ func (s *SandboxService) CreateWithVersion(ctx context.Context, template string,
 	return &result, nil
 }
 
+// CreateWithRequest creates a sandbox from a full create request, for options such as
+// NetworkGroup or Project that the other Create methods do not take.
+func (s *SandboxService) CreateWithRequest(ctx context.Context, req *CreateSandboxRequest) (*Sandbox, error) {
+	var result Sandbox
+	err := s.client.doJSON(ctx, "POST", s.client.buildPath("sandboxes"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // List retrieves all sandboxes.
 func (s *SandboxService) List(ctx context.Context) ([]Sandbox, error) {
 	var result SandboxListResponse
@@ -76,6 +90,88 @@ func (s *SandboxService) Delete(ctx context.Context, id string) error {
 	return s.client.doEmptyResponse(ctx, "DELETE", s.client.buildPath("sandboxes", id), nil, nil)
 }
 
+// ExtendTTL makes a sandbox expire ttl from now, bounded by the server's max TTL.
+// Stopped and paused sandboxes count ttl from when they stopped.
+func (s *SandboxService) ExtendTTL(ctx context.Context, id string, ttl time.Duration) (*Sandbox, error) {
+	return s.setTTL(ctx, id, &ExtendTTLRequest{TTL: int(ttl / time.Second)})
+}
+
+// SetExpiresAt makes a sandbox expire at the given time, bounded by the server's max TTL.
+func (s *SandboxService) SetExpiresAt(ctx context.Context, id string, expiresAt time.Time) (*Sandbox, error) {
+	return s.setTTL(ctx, id, &ExtendTTLRequest{ExpiresAt: &expiresAt})
+}
+
+// RotateToken replaces the access token of a sandbox and returns the sandbox with the
+// new token. The replaced token keeps working at the gateway for gracePeriod (at most
+// 24h), or stops working at once when gracePeriod is zero.
+func (s *SandboxService) RotateToken(ctx context.Context, id string, gracePeriod time.Duration) (*Sandbox, error) {
+	var result Sandbox
+	req := &RotateAccessTokenRequest{GracePeriod: int(gracePeriod / time.Second)}
+	err := s.client.doJSON(ctx, "POST", s.client.buildPath("sandboxes", id, "token", "rotate"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *SandboxService) setTTL(ctx context.Context, id string, req *ExtendTTLRequest) (*Sandbox, error) {
+	var result Sandbox
+	err := s.client.doJSON(ctx, "POST", s.client.buildPath("sandboxes", id, "ttl"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateNetwork changes the egress of a sandbox at runtime. Unless the server allows
+// widening, the new configuration may only narrow the template's.
+func (s *SandboxService) UpdateNetwork(ctx context.Context, id string, req *UpdateSandboxNetworkRequest) (*Sandbox, error) {
+	var result Sandbox
+	err := s.client.doJSON(ctx, "PUT", s.client.buildPath("sandboxes", id, "network"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ResetNetwork drops the network override of a sandbox so it follows its template again.
+func (s *SandboxService) ResetNetwork(ctx context.Context, id string) (*Sandbox, error) {
+	return s.UpdateNetwork(ctx, id, &UpdateSandboxNetworkRequest{Reset: true})
+}
+
+// GetTraffic returns the bytes the gateway has relayed to and from a sandbox.
+func (s *SandboxService) GetTraffic(ctx context.Context, id string) (*SandboxTraffic, error) {
+	var result SandboxTraffic
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "traffic"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListNetworkEvents returns the recorded DNS queries and outbound connections of a
+// sandbox, newest first. query may be nil.
+func (s *SandboxService) ListNetworkEvents(ctx context.Context, id string, query *NetworkEventQuery) ([]SandboxNetworkEvent, error) {
+	queryParams := make(map[string]string)
+	if query != nil {
+		if query.Kind != "" {
+			queryParams["kind"] = string(query.Kind)
+		}
+		if query.Since != nil {
+			queryParams["since"] = query.Since.Format(time.RFC3339)
+		}
+		if query.Limit > 0 {
+			queryParams["limit"] = strconv.Itoa(query.Limit)
+		}
+	}
+	var result NetworkEventListResponse
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("sandboxes", id, "network", "events"), nil, &result, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
 // Restart restarts a persistence-enabled sandbox.
 func (s *SandboxService) Restart(ctx context.Context, id string) error {
 	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "restart"), nil, nil)
@@ -91,6 +187,17 @@ func (s *SandboxService) Start(ctx context.Context, id string) error {
 	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "start"), nil, nil)
 }
 
+// Pause checkpoints the filesystem of a sandbox without persistence and releases its pod.
+// The TTL does not run down while the sandbox is paused. Running processes are not kept.
+func (s *SandboxService) Pause(ctx context.Context, id string) error {
+	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "pause"), nil, nil)
+}
+
+// Resume recreates a paused sandbox from its checkpoint and reruns the startup script.
+func (s *SandboxService) Resume(ctx context.Context, id string) error {
+	return s.client.doEmptyResponse(ctx, "POST", s.client.buildPath("sandboxes", id, "resume"), nil, nil)
+}
+
 // Execute runs a command in the sandbox.
 func (s *SandboxService) Execute(ctx context.Context, id string, command []string, timeout int) (*ExecResponse, error) {
 	req := &ExecRequest{
@@ -105,6 +212,81 @@ func (s *SandboxService) Execute(ctx context.Context, id string, command []strin
 	return &result, nil
 }
 
+// ExecStream runs a command in the sandbox and copies its output to stdout and stderr
+// as it is produced, instead of buffering it until the command finishes.
+// stdin is optional; when non-nil it is streamed to the command's standard input.
+// Returns the command's exit code once the stream completes.
+func (s *SandboxService) ExecStream(ctx context.Context, id string, command []string, timeout int, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	query := url.Values{}
+	for _, arg := range command {
+		query.Add("command", arg)
+	}
+	if timeout > 0 {
+		query.Set("timeout", strconv.Itoa(timeout))
+	}
+
+	contentType := ""
+	if stdin != nil {
+		contentType = "application/octet-stream"
+	}
+	resp, err := s.client.doStreamRequest(ctx, "POST", s.client.buildPath("sandboxes", id, "exec", "stream"), stdin, contentType, query)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return 0, handleErrorResponse(resp)
+	}
+
+	return copyExecStream(resp.Body, stdout, stderr)
+}
+
+// copyExecStream decodes NDJSON exec events from r into stdout/stderr and returns the
+// exit code carried by the final exit event.
+func copyExecStream(r io.Reader, stdout, stderr io.Writer) (int, error) {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	decoder := json.NewDecoder(r)
+	for {
+		var event ExecStreamEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return 0, fmt.Errorf("exec stream ended without exit status")
+			}
+			return 0, fmt.Errorf("failed to decode exec stream: %w", err)
+		}
+
+		switch event.Type {
+		case ExecStreamEventStdout, ExecStreamEventStderr:
+			data := []byte(event.Data)
+			if event.Encoding == ExecStreamEncodingBase64 {
+				decoded, err := base64.StdEncoding.DecodeString(event.Data)
+				if err != nil {
+					return 0, fmt.Errorf("failed to decode exec stream data: %w", err)
+				}
+				data = decoded
+			}
+			w := stdout
+			if event.Type == ExecStreamEventStderr {
+				w = stderr
+			}
+			if _, err := w.Write(data); err != nil {
+				return 0, err
+			}
+		case ExecStreamEventExit:
+			return event.ExitCode, nil
+		case ExecStreamEventError:
+			return 0, fmt.Errorf("exec stream failed: %s", event.Message)
+		}
+	}
+}
+
 // GetLogs retrieves container logs and Pod events.
 func (s *SandboxService) GetLogs(ctx context.Context, id string) (*LogsResponse, error) {
 	var result LogsResponse
@@ -0,0 +1,102 @@
+package liteboxd
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CreateSnapshot starts a snapshot of a persistence-enabled sandbox. The snapshot is
+// pending until it is ready to restore from; see WaitForSnapshot.
+func (s *SandboxService) CreateSnapshot(ctx context.Context, id, name string) (*SandboxSnapshot, error) {
+	var result SandboxSnapshot
+	err := s.client.doJSON(ctx, "POST", s.client.buildPath("sandboxes", id, "snapshots"), &CreateSnapshotRequest{Name: name}, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListSnapshots lists snapshots taken from a sandbox, newest first. An empty id lists
+// the snapshots of all sandboxes, including deleted ones.
+func (s *SandboxService) ListSnapshots(ctx context.Context, id string) ([]SandboxSnapshot, error) {
+	path := s.client.buildPath("snapshots")
+	if id != "" {
+		path = s.client.buildPath("sandboxes", id, "snapshots")
+	}
+	var result SnapshotListResponse
+	if err := s.client.doJSON(ctx, "GET", path, nil, &result, nil); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// GetSnapshot retrieves a snapshot with its current status.
+func (s *SandboxService) GetSnapshot(ctx context.Context, snapshotID string) (*SandboxSnapshot, error) {
+	var result SandboxSnapshot
+	err := s.client.doJSON(ctx, "GET", s.client.buildPath("snapshots", snapshotID), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteSnapshot removes a snapshot and the data backing it.
+func (s *SandboxService) DeleteSnapshot(ctx context.Context, snapshotID string) error {
+	return s.client.doEmptyResponse(ctx, "DELETE", s.client.buildPath("snapshots", snapshotID), nil, nil)
+}
+
+// WaitForSnapshot waits until the snapshot is ready to restore from.
+// pollInterval is the time between checks (default 2s).
+// timeout is the maximum wait time (default 10m).
+func (s *SandboxService) WaitForSnapshot(ctx context.Context, snapshotID string, pollInterval, timeout time.Duration) (*SandboxSnapshot, error) {
+	if pollInterval == 0 {
+		pollInterval = 2 * time.Second
+	}
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		snapshot, err := s.GetSnapshot(ctx, snapshotID)
+		if err != nil {
+			return nil, err
+		}
+		switch snapshot.Status {
+		case SnapshotStatusReady:
+			return snapshot, nil
+		case SnapshotStatusFailed:
+			return nil, fmt.Errorf("snapshot failed: %s", snapshot.StatusReason)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for snapshot to be ready")
+		case <-ticker.C:
+		}
+	}
+}
+
+// CreateFromSnapshot creates a sandbox whose root filesystem starts from a ready
+// snapshot. An empty template uses the template and version the snapshot was taken
+// from; the template must have persistence enabled.
+func (s *SandboxService) CreateFromSnapshot(ctx context.Context, snapshotID, template string, version int, overrides *SandboxOverrides) (*Sandbox, error) {
+	req := &CreateSandboxRequest{
+		Template:        template,
+		TemplateVersion: version,
+		FromSnapshot:    snapshotID,
+		Overrides:       overrides,
+	}
+	var result Sandbox
+	err := s.client.doJSON(ctx, "POST", s.client.buildPath("sandboxes"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
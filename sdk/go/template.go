@@ -36,6 +36,9 @@ func (t *TemplateService) List(ctx context.Context, opts *TemplateListOptions) (
 		if opts.PageSize > 0 {
 			queryParams["pageSize"] = strconv.Itoa(opts.PageSize)
 		}
+		if opts.Project != "" {
+			queryParams["project"] = opts.Project
+		}
 	}
 	var result TemplateListResponse
 	err := t.client.doJSON(ctx, "GET", t.client.buildPath("templates"), nil, &result, queryParams)
@@ -65,6 +68,26 @@ func (t *TemplateService) Update(ctx context.Context, name string, req *UpdateTe
 	return &result, nil
 }
 
+// ListPools reports the warm pools of all templates that have one.
+func (t *TemplateService) ListPools(ctx context.Context) (*SandboxPoolListResponse, error) {
+	var result SandboxPoolListResponse
+	err := t.client.doJSON(ctx, "GET", t.client.buildPath("pools"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetPool reports the warm pool of a template.
+func (t *TemplateService) GetPool(ctx context.Context, name string) (*SandboxPoolStatus, error) {
+	var result SandboxPoolStatus
+	err := t.client.doJSON(ctx, "GET", t.client.buildPath("pools", name), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // Delete removes a template.
 func (t *TemplateService) Delete(ctx context.Context, name string) error {
 	return t.client.doEmptyResponse(ctx, "DELETE", t.client.buildPath("templates", name), nil, nil)
@@ -0,0 +1,174 @@
+package liteboxd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DialPort opens a TCP connection to a port of a sandbox, tunneled over a WebSocket
+// through the gateway and authorized with the sandbox access token.
+func (s *SandboxService) DialPort(ctx context.Context, id string, port int) (net.Conn, error) {
+	sandbox, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return dialTunnel(ctx, sandbox, port)
+}
+
+// PortForward forwards every connection accepted on listener to remotePort of a
+// sandbox until ctx is done or the listener fails. Errors of single connections are
+// passed to onError, which may be nil. It returns nil once ctx is done.
+func (s *SandboxService) PortForward(ctx context.Context, id string, listener net.Listener, remotePort int, onError func(error)) error {
+	sandbox, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sandbox.TunnelURL == "" || sandbox.AccessToken == "" {
+		return fmt.Errorf("sandbox %s has no tunnel url or access token", id)
+	}
+
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer local.Close()
+			remote, err := dialTunnel(ctx, sandbox, remotePort)
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				return
+			}
+			defer remote.Close()
+			pipeConns(ctx, local, remote)
+		}()
+	}
+}
+
+// dialTunnel opens a tunnel to port of sandbox.
+func dialTunnel(ctx context.Context, sandbox *Sandbox, port int) (net.Conn, error) {
+	if sandbox.TunnelURL == "" {
+		return nil, fmt.Errorf("sandbox %s has no tunnel url", sandbox.ID)
+	}
+	tunnelURL := strings.ReplaceAll(sandbox.TunnelURL, "{port}", strconv.Itoa(port))
+
+	header := http.Header{}
+	header.Set("X-Access-Token", sandbox.AccessToken)
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, tunnelURL, header)
+	if err != nil {
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			defer resp.Body.Close()
+			return nil, handleErrorResponse(resp)
+		}
+		return nil, fmt.Errorf("failed to connect tunnel: %w", err)
+	}
+	return &tunnelConn{ws: ws}, nil
+}
+
+// pipeConns copies data between two connections until either side ends or ctx is
+// done.
+func pipeConns(ctx context.Context, a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst, src net.Conn) {
+		_, _ = io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// tunnelConn adapts a tunnel WebSocket to net.Conn. Every Write is sent as one binary
+// message.
+type tunnelConn struct {
+	ws      *websocket.Conn
+	reader  io.Reader
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+}
+
+func (c *tunnelConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	for {
+		if c.reader == nil {
+			messageType, reader, err := c.ws.NextReader()
+			if err != nil {
+				var closeErr *websocket.CloseError
+				if errors.As(err, &closeErr) && closeErr.Code == websocket.CloseNormalClosure {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			c.reader = reader
+		}
+		n, err := c.reader.Read(p)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *tunnelConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.ws.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *tunnelConn) Close() error {
+	c.writeMu.Lock()
+	_ = c.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+	return c.ws.Close()
+}
+
+func (c *tunnelConn) LocalAddr() net.Addr  { return c.ws.LocalAddr() }
+func (c *tunnelConn) RemoteAddr() net.Addr { return c.ws.RemoteAddr() }
+
+func (c *tunnelConn) SetDeadline(t time.Time) error {
+	if err := c.ws.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.ws.SetWriteDeadline(t)
+}
+
+func (c *tunnelConn) SetReadDeadline(t time.Time) error  { return c.ws.SetReadDeadline(t) }
+func (c *tunnelConn) SetWriteDeadline(t time.Time) error { return c.ws.SetWriteDeadline(t) }
@@ -10,9 +10,50 @@ type CreateSandboxRequest = model.CreateSandboxRequest
 type SandboxListResponse = model.SandboxListResponse
 type ExecRequest = model.ExecRequest
 type ExecResponse = model.ExecResponse
+type ExecStreamEvent = model.ExecStreamEvent
 type LogsResponse = model.LogsResponse
 type WSMessage = model.WSMessage
 type ExecInteractiveRequest = model.ExecInteractiveRequest
+type ExtendTTLRequest = model.ExtendTTLRequest
+type RotateAccessTokenRequest = model.RotateAccessTokenRequest
+type TTLMode = model.TTLMode
+type UpdateSandboxNetworkRequest = model.UpdateSandboxNetworkRequest
+type SandboxNetworkEvent = model.SandboxNetworkEvent
+type NetworkEventKind = model.NetworkEventKind
+type NetworkEventVerdict = model.NetworkEventVerdict
+type NetworkEventQuery = model.NetworkEventQuery
+type NetworkEventListResponse = model.NetworkEventListResponse
+type SandboxTraffic = model.SandboxTraffic
+type SandboxOwner = model.SandboxOwner
+
+// Process types
+type SandboxProcess = model.SandboxProcess
+type ProcessStatus = model.ProcessStatus
+type StartProcessRequest = model.StartProcessRequest
+type ProcessListResponse = model.ProcessListResponse
+type SignalProcessRequest = model.SignalProcessRequest
+type ProcessOutputResponse = model.ProcessOutputResponse
+
+// Filesystem types
+type FileInfo = model.FileInfo
+type FileType = model.FileType
+type FileListResponse = model.FileListResponse
+type MkdirRequest = model.MkdirRequest
+type MoveRequest = model.MoveRequest
+type FSEvent = model.FSEvent
+type FSEventType = model.FSEventType
+
+// Snapshot types
+type SandboxSnapshot = model.SandboxSnapshot
+type SnapshotKind = model.SnapshotKind
+type SnapshotStatus = model.SnapshotStatus
+type CreateSnapshotRequest = model.CreateSnapshotRequest
+type SnapshotListResponse = model.SnapshotListResponse
+
+// Preview link types
+type PreviewLink = model.PreviewLink
+type CreatePreviewLinkRequest = model.CreatePreviewLinkRequest
+type PreviewLinkListResponse = model.PreviewLinkListResponse
 
 // Template types
 type Template = model.Template
@@ -20,7 +61,14 @@ type TemplateSpec = model.TemplateSpec
 type ResourceSpec = model.ResourceSpec
 type FileSpec = model.FileSpec
 type ProbeSpec = model.ProbeSpec
+type NetworkSpec = model.NetworkSpec
+type PortRule = model.PortRule
+type DomainRule = model.DomainRule
+type CIDRRule = model.CIDRRule
+type BandwidthSpec = model.BandwidthSpec
 type TemplateVersion = model.TemplateVersion
+type SandboxPoolStatus = model.SandboxPoolStatus
+type SandboxPoolListResponse = model.SandboxPoolListResponse
 type CreateTemplateRequest = model.CreateTemplateRequest
 type UpdateTemplateRequest = model.UpdateTemplateRequest
 type RollbackRequest = model.RollbackRequest
@@ -44,6 +92,28 @@ type TemplateYAML = model.TemplateYAML
 type TemplateYAMLMetadata = model.TemplateYAMLMetadata
 type TemplateListYAML = model.TemplateListYAML
 
+// User types
+type Role = model.Role
+type User = model.User
+type CreateUserRequest = model.CreateUserRequest
+type UpdateUserRequest = model.UpdateUserRequest
+type UserListResponse = model.UserListResponse
+
+// Project types
+type Project = model.Project
+type CreateProjectRequest = model.CreateProjectRequest
+type ProjectListResponse = model.ProjectListResponse
+
+// Quota types
+type QuotaScope = model.QuotaScope
+type QuotaLimits = model.QuotaLimits
+type Quota = model.Quota
+type QuotaListResponse = model.QuotaListResponse
+type QuotaUsage = model.QuotaUsage
+type QuotaStatus = model.QuotaStatus
+type QuotaStatusResponse = model.QuotaStatusResponse
+type QuotaViolation = model.QuotaViolation
+
 // Constants
 const (
 	SandboxStatusPending     = model.SandboxStatusPending
@@ -52,8 +122,51 @@ const (
 	SandboxStatusFailed      = model.SandboxStatusFailed
 	SandboxStatusTerminating = model.SandboxStatusTerminating
 	SandboxStatusStopped     = model.SandboxStatusStopped
+	SandboxStatusPaused      = model.SandboxStatusPaused
 	SandboxStatusUnknown     = model.SandboxStatusUnknown
 
+	TTLModeFixed = model.TTLModeFixed
+	TTLModeIdle  = model.TTLModeIdle
+
+	NetworkEventDNS        = model.NetworkEventDNS
+	NetworkEventConnection = model.NetworkEventConnection
+	NetworkVerdictAllowed  = model.NetworkVerdictAllowed
+	NetworkVerdictDenied   = model.NetworkVerdictDenied
+
+	ProcessStatusRunning = model.ProcessStatusRunning
+	ProcessStatusExited  = model.ProcessStatusExited
+	ProcessStatusKilled  = model.ProcessStatusKilled
+	ProcessStatusLost    = model.ProcessStatusLost
+
+	FileTypeFile      = model.FileTypeFile
+	FileTypeDirectory = model.FileTypeDirectory
+	FileTypeSymlink   = model.FileTypeSymlink
+	FileTypeOther     = model.FileTypeOther
+
+	FSEventReady  = model.FSEventReady
+	FSEventCreate = model.FSEventCreate
+	FSEventModify = model.FSEventModify
+	FSEventDelete = model.FSEventDelete
+	FSEventError  = model.FSEventError
+
+	SnapshotKindVolumeSnapshot = model.SnapshotKindVolumeSnapshot
+	SnapshotKindArchive        = model.SnapshotKindArchive
+
+	SnapshotStatusPending = model.SnapshotStatusPending
+	SnapshotStatusReady   = model.SnapshotStatusReady
+	SnapshotStatusFailed  = model.SnapshotStatusFailed
+
+	ExecStreamEventStdout = model.ExecStreamEventStdout
+	ExecStreamEventStderr = model.ExecStreamEventStderr
+	ExecStreamEventExit   = model.ExecStreamEventExit
+	ExecStreamEventError  = model.ExecStreamEventError
+
+	ExecStreamEncodingBase64 = model.ExecStreamEncodingBase64
+
+	ArchiveFormatTar   = model.ArchiveFormatTar
+	ArchiveFormatTarGz = model.ArchiveFormatTarGz
+	ArchiveFormatZip   = model.ArchiveFormatZip
+
 	PrepullStatusPending   = model.PrepullStatusPending
 	PrepullStatusPulling   = model.PrepullStatusPulling
 	PrepullStatusCompleted = model.PrepullStatusCompleted
@@ -62,4 +175,13 @@ const (
 	ImportStrategyCreateOnly     = model.ImportStrategyCreateOnly
 	ImportStrategyUpdateOnly     = model.ImportStrategyUpdateOnly
 	ImportStrategyCreateOrUpdate = model.ImportStrategyCreateOrUpdate
+
+	RoleAdmin     = model.RoleAdmin
+	RoleDeveloper = model.RoleDeveloper
+	RoleViewer    = model.RoleViewer
+
+	QuotaScopeUser      = model.QuotaScopeUser
+	QuotaScopeAPIKey    = model.QuotaScopeAPIKey
+	QuotaScopeProject   = model.QuotaScopeProject
+	QuotaSubjectDefault = model.QuotaSubjectDefault
 )
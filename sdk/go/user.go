@@ -0,0 +1,71 @@
+package liteboxd
+
+import "context"
+
+// UserService handles user management. All of its operations require the admin role.
+type UserService struct {
+	client *Client
+}
+
+// Create creates a user. An empty role creates a developer.
+func (u *UserService) Create(ctx context.Context, username, password string, role Role) (*User, error) {
+	req := &CreateUserRequest{
+		Username: username,
+		Password: password,
+		Role:     role,
+	}
+	var result User
+	err := u.client.doJSON(ctx, "POST", u.client.buildPath("users"), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// List retrieves all users.
+func (u *UserService) List(ctx context.Context) ([]User, error) {
+	var result UserListResponse
+	err := u.client.doJSON(ctx, "GET", u.client.buildPath("users"), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// Get retrieves a user by ID or username.
+func (u *UserService) Get(ctx context.Context, user string) (*User, error) {
+	var result User
+	err := u.client.doJSON(ctx, "GET", u.client.buildPath("users", user), nil, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Update changes the role, disabled flag or password of a user, identified by ID or
+// username. Fields left nil are kept.
+func (u *UserService) Update(ctx context.Context, user string, req *UpdateUserRequest) (*User, error) {
+	var result User
+	err := u.client.doJSON(ctx, "PATCH", u.client.buildPath("users", user), req, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Disable disables a user, ending their sessions and rejecting their API keys.
+func (u *UserService) Disable(ctx context.Context, user string) (*User, error) {
+	disabled := true
+	return u.Update(ctx, user, &UpdateUserRequest{Disabled: &disabled})
+}
+
+// Enable enables a disabled user.
+func (u *UserService) Enable(ctx context.Context, user string) (*User, error) {
+	disabled := false
+	return u.Update(ctx, user, &UpdateUserRequest{Disabled: &disabled})
+}
+
+// Delete deletes a user along with their API keys.
+func (u *UserService) Delete(ctx context.Context, user string) error {
+	return u.client.doEmptyResponse(ctx, "DELETE", u.client.buildPath("users", user), nil, nil)
+}